@@ -0,0 +1,140 @@
+// Command catalogue นำเข้าและส่งออกสถานที่ท่องเที่ยวเป็น CSV หรือ GeoJSON (จับคู่จังหวัดด้วยชื่อ)
+//
+//	go run ./cmd/catalogue import attractions.csv          # dry run แสดง diff
+//	go run ./cmd/catalogue import -apply attractions.csv   # บันทึก
+//	go run ./cmd/catalogue export -format geojson -o attractions.geojson
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: catalogue import [-apply] [-format csv|geojson] <file>")
+	fmt.Fprintln(os.Stderr, "       catalogue export [-format csv|geojson] [-province-id N] [-o file]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "import":
+		runImport(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func initDB() {
+	config.Init()
+	if err := config.DB.AutoMigrate(&models.Province{}, &models.AttractionCategory{}, &models.TouristAttraction{}); err != nil {
+		log.Fatalf("Migration error: %v", err)
+	}
+}
+
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	apply := fs.Bool("apply", false, "บันทึกการเปลี่ยนแปลง (ไม่ส่ง = dry run)")
+	format := fs.String("format", "", "csv หรือ geojson (ไม่ส่ง = ดูจากนามสกุลไฟล์)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		usage()
+	}
+	path := fs.Arg(0)
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", path, err)
+	}
+	defer f.Close()
+	file, err := services.ParseAttractionFile(*format, f)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", path, err)
+	}
+
+	initDB()
+	result, err := services.NewAttractionImportService(config.DB).Import(file, *apply)
+	if result != nil {
+		for _, row := range result.Rows {
+			switch row.Action {
+			case "unchanged":
+				continue
+			case "invalid":
+				fmt.Printf("line %d  invalid    %s (%s): %s\n", row.Line, row.Name, row.Province, row.Error)
+			default:
+				fmt.Printf("line %d  %-9s  %s (%s)\n", row.Line, row.Action, row.Name, row.Province)
+			}
+			for _, change := range row.Changes {
+				fmt.Printf("          %s: %q -> %q\n", change.Field, change.From, change.To)
+			}
+		}
+		if len(result.NewCategories) > 0 {
+			fmt.Printf("new categories: %s\n", strings.Join(result.NewCategories, ", "))
+		}
+		if len(result.IgnoredColumns) > 0 {
+			fmt.Printf("ignored columns: %s\n", strings.Join(result.IgnoredColumns, ", "))
+		}
+		log.Printf("%d new, %d changed, %d unchanged, %d invalid", result.New, result.Changed, result.Unchanged, result.Invalid)
+	}
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+	if result.Applied {
+		log.Println("Import applied")
+	} else {
+		log.Println("Dry run only, re-run with -apply to save")
+	}
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	format := fs.String("format", "csv", "csv หรือ geojson")
+	provinceID := fs.Uint("province-id", 0, "เฉพาะจังหวัด (0 = ทั้งหมด)")
+	output := fs.String("o", "", "ไฟล์ปลายทาง (ไม่ส่ง = stdout)")
+	fs.Parse(args)
+	if *format != "csv" && *format != "geojson" {
+		usage()
+	}
+
+	initDB()
+	attractions, err := services.NewAttractionImportService(config.DB).Export(*provinceID)
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", *output, err)
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "geojson" {
+		err = services.WriteAttractionsGeoJSON(w, attractions)
+	} else {
+		err = services.WriteAttractionsCSV(w, attractions)
+	}
+	if err != nil {
+		log.Fatalf("Export failed: %v", err)
+	}
+	log.Printf("Exported %d attraction(s)", len(attractions))
+}
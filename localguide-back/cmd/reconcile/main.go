@@ -0,0 +1,31 @@
+// Command reconcile เทียบ TripPayment กับ Stripe หนึ่งรอบแล้วพิมพ์สรุป (ใช้ตรวจเองหรือรันจาก cron)
+//
+//	go run ./cmd/reconcile
+package main
+
+import (
+	"log"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+)
+
+func main() {
+	config.Init()
+
+	if err := config.DB.AutoMigrate(&models.ReconciliationIssue{}); err != nil {
+		log.Fatalf("Migration error: %v", err)
+	}
+
+	summary, err := services.NewReconciliationService(config.DB, services.NewStripeService()).RunOnce(time.Now())
+	if err != nil {
+		log.Fatalf("Reconciliation failed: %v", err)
+	}
+
+	log.Printf("Checked %d payment(s): %d open issue(s), %d resolved", summary.Checked, summary.Open, summary.Resolved)
+	if summary.Open > 0 {
+		log.Println("See GET /api/admin/reconciliation for details")
+	}
+}
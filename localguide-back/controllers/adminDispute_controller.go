@@ -1,22 +1,28 @@
 package controllers
 
 import (
+	"errors"
 	"localguide-back/config"
 	"localguide-back/models"
 	"localguide-back/services"
+	"log"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
+// errDisputeAlreadyDecided - booking เปลี่ยนสถานะไปแล้วระหว่างตัดสิน (มีคำตัดสินอื่น commit ก่อน)
+var errDisputeAlreadyDecided = errors.New("dispute already decided")
+
 // AdminResolveNoShowDispute - Admin ตัดสินกรณีมีการ dispute no-show report
 func AdminResolveNoShowDispute(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Booking ID must be a positive integer",
+			"error":   "booking_id_invalid",
 		})
 	}
 
@@ -28,7 +34,7 @@ func AdminResolveNoShowDispute(c *fiber.Ctx) error {
 
 	if err := c.BodyParser(&requestData); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid request data",
+			"error":   "invalid_request_data",
 		})
 	}
 
@@ -37,7 +43,7 @@ func AdminResolveNoShowDispute(c *fiber.Ctx) error {
 	var user models.User
 	if err := config.DB.First(&user, userID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get user info",
+			"error":   "user_info_get_failed",
 		})
 	}
 
@@ -45,13 +51,13 @@ func AdminResolveNoShowDispute(c *fiber.Ctx) error {
 	var role models.Role
 	if err := config.DB.First(&role, user.RoleID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get user role",
+			"error":   "user_role_get_failed",
 		})
 	}
 
 	if role.Name != "admin" {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error":   "Only admin can resolve disputes",
+			"error":   "dispute_admin_only",
 		})
 	}
 
@@ -59,11 +65,11 @@ func AdminResolveNoShowDispute(c *fiber.Ctx) error {
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error":   "Booking not found",
+				"error":   "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get booking",
+			"error":   "booking_get_failed",
 		})
 	}
 
@@ -75,7 +81,7 @@ func AdminResolveNoShowDispute(c *fiber.Ctx) error {
 		err2 := config.DB.Where("trip_booking_id = ? AND report_type = ? AND status = ?", bookingID, "user_no_show", "pending").First(&originalReport).Error
 		if err2 != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error":   "No active dispute to resolve",
+				"error":   "no_active_dispute",
 				"status":  booking.Status,
 			})
 		}
@@ -84,205 +90,64 @@ func AdminResolveNoShowDispute(c *fiber.Ctx) error {
 	// Validate decision
 	if requestData.Decision != "guide_wins" && requestData.Decision != "user_wins" && requestData.Decision != "split_cost" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid decision. Must be 'guide_wins', 'user_wins', or 'split_cost'",
+			"error": "dispute_decision_invalid",
 		})
 	}
 
-	// Get payment
-	var payment models.TripPayment
-	if err := config.DB.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Payment not found",
+	// เหตุผลของคำตัดสินแสดงให้ทั้งสองฝ่ายเห็น
+	requestData.Reason = strings.TrimSpace(requestData.Reason)
+	if requestData.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "rationale_required",
 		})
 	}
 
+	// เงินแบ่งจากยอดที่ยังค้างในระบบ (หักรายการจ่าย/คืนที่ตั้งไว้แล้ว รวมที่ยังรอใน outbox) ไม่ถาม Stripe
+	// booking, เงิน, คำตัดสินและรีพอร์ตบันทึกใน transaction เดียว การคืนเงินกับ Stripe ทำโดย PaymentOutboxWorker หลัง commit
 	now := time.Now()
-	stripeService := services.NewStripeService()
-
-	// Helper: updates all related reports to resolved
-	resolveReports := func(notes string) {
-		config.DB.Model(&models.TripReport{}).
-			Where("trip_booking_id = ? AND status = ?", bookingID, "pending").
-			Updates(map[string]interface{}{
-				"status":      "resolved",
-				"resolved_at": &now,
-				"admin_notes": notes,
-			})
-	}
-
-	switch requestData.Decision {
-	case "guide_wins":
-		// 50/50 split
-		// Check refundable amount for second payment before refunding
-		remaining, err := stripeService.GetRefundableAmountCents(payment.StripePaymentIntentID)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to check refundable amount: " + err.Error()})
-		}
-		amountToRefund := int64(payment.SecondPayment * 100)
-		if remaining <= 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payment already fully refunded"})
-		}
-		if amountToRefund > remaining {
-			amountToRefund = remaining
-		}
-
-		// Process 50% release + refund via helper (keeps logic consistent)
-		if amountToRefund <= 0 {
-			// If nothing left to refund, still release guide portion and mark reports
-			err = config.DB.Transaction(func(tx *gorm.DB) error {
-				booking.Status = "no_show_confirmed"
-				booking.CancellationReason = "admin_decision_guide_wins"
-				if err := tx.Save(&booking).Error; err != nil { return err }
-				guideRelease := models.PaymentRelease{
-					TripPaymentID: payment.ID,
-					ReleaseType:   "first_payment",
-					Amount:        payment.FirstPayment,
-					RecipientType: "guide",
-					RecipientID:   booking.GuideID,
-					Reason:        "admin_decision_guide_wins",
-					ScheduledAt:   now,
-					ProcessedAt:   &now,
-					Status:        "processed",
-				}
-				if err := tx.Create(&guideRelease).Error; err != nil { return err }
-				payment.Status = "partially_refunded"
-				payment.RefundedAt = &now
-				if payment.RefundAmount == 0 { payment.RefundAmount = payment.SecondPayment }
-				payment.RefundReason = "admin_decision_guide_wins"
-				return tx.Save(&payment).Error
-			})
-			if err != nil {
-				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update records: " + err.Error()})
-			}
-			resolveReports(requestData.Reason)
-			return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Guide wins processed without additional refund (already refunded)", "booking": booking})
-		}
-
-		// Normal path uses existing helper
-		resp := processNoShowPayment(c, &booking, &payment, stripeService, now, "admin_decision_guide_wins")
-		if resp != nil { return resp }
-		resolveReports(requestData.Reason)
-		return nil
-
-	case "user_wins":
-		// Full refund to user
-		remaining, err := stripeService.GetRefundableAmountCents(payment.StripePaymentIntentID)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to check refundable amount: " + err.Error()})
-		}
-		amountToRefund := int64(payment.TotalAmount * 100)
-		if remaining <= 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payment already fully refunded"})
-		}
-		if amountToRefund > remaining { amountToRefund = remaining }
-
-		booking.Status = "cancelled"
-		booking.CancelledAt = &now
-		booking.CancellationReason = "admin_decision_user_wins"
-		if err := config.DB.Save(&booking).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update booking"})
-		}
-
-		stripeRefund, err := stripeService.RefundPayment(payment.StripePaymentIntentID, amountToRefund, "requested_by_customer")
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to process Stripe refund: " + err.Error()})
-		}
-
-		userRefund := models.PaymentRelease{
-			TripPaymentID:  payment.ID,
-			ReleaseType:    "refund",
-			Amount:         float64(amountToRefund) / 100.0,
-			RecipientType:  "user",
-			RecipientID:    booking.UserID,
-			Reason:         "admin_decision_user_wins",
-			ScheduledAt:    now,
-			ProcessedAt:    &now,
-			Status:         "processed",
-			TransactionRef: stripeRefund.ID,
-			Notes:          "Full refund by admin decision",
-		}
-		if err := config.DB.Create(&userRefund).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user refund record"})
-		}
-
-		payment.Status = "refunded"
-		payment.RefundedAt = &now
-		payment.RefundAmount = float64(amountToRefund) / 100.0
-		payment.RefundReason = "admin_decision_user_wins"
-		if err := config.DB.Save(&payment).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update payment status"})
-		}
-
-		resolveReports(requestData.Reason)
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Admin decision: User wins. Refund processed.", "booking": booking, "user_refund": userRefund, "decision": requestData.Decision})
-
-	case "split_cost":
-		remaining, err := stripeService.GetRefundableAmountCents(payment.StripePaymentIntentID)
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to check refundable amount: " + err.Error()})
-		}
-		guideAmount := payment.TotalAmount * 0.25
-		userRefundAmount := payment.TotalAmount * 0.75
-		amountToRefund := int64(userRefundAmount * 100)
-		if remaining <= 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Payment already fully refunded"})
-		}
-		if amountToRefund > remaining { amountToRefund = remaining }
-
-		booking.Status = "no_show_split"
-		booking.CancellationReason = "admin_decision_split_cost"
-		if err := config.DB.Save(&booking).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update booking"})
-		}
-
-		guideRelease := models.PaymentRelease{
-			TripPaymentID: payment.ID,
-			ReleaseType:   "first_payment",
-			Amount:        guideAmount,
-			RecipientType: "guide",
-			RecipientID:   booking.GuideID,
-			Reason:        "admin_decision_split_cost",
-			ScheduledAt:   now,
-			ProcessedAt:   &now,
-			Status:        "processed",
-		}
-		if err := config.DB.Create(&guideRelease).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create guide payment release"})
-		}
-
-		stripeRefund, err := stripeService.RefundPayment(payment.StripePaymentIntentID, amountToRefund, "requested_by_customer")
-		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Failed to process Stripe refund: " + err.Error()})
-		}
-
-		userRefund := models.PaymentRelease{
-			TripPaymentID:  payment.ID,
-			ReleaseType:    "refund",
-			Amount:         float64(amountToRefund) / 100.0,
-			RecipientType:  "user",
-			RecipientID:    booking.UserID,
-			Reason:         "admin_decision_split_cost",
-			ScheduledAt:    now,
-			ProcessedAt:    &now,
-			Status:         "processed",
-			TransactionRef: stripeRefund.ID,
-			Notes:          "75% refund by admin decision (split cost)",
-		}
-		if err := config.DB.Create(&userRefund).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user refund record"})
-		}
-
-		payment.Status = "partially_refunded"
-		payment.RefundedAt = &now
-		payment.RefundAmount = float64(amountToRefund) / 100.0
-		payment.RefundReason = "admin_decision_split_cost"
-		if err := config.DB.Save(&payment).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update payment status"})
-		}
-
-		resolveReports(requestData.Reason)
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Admin decision: Split cost processed.", "booking": booking, "guide_release": guideRelease, "user_refund": userRefund, "decision": requestData.Decision})
+	reason := "admin_decision_" + requestData.Decision
+	var settlement *services.CaseSettlement
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// booking ต้องยังอยู่สถานะเดิม กันการตัดสินซ้ำ/พร้อมกัน (หรือพร้อมกับ job ปิด dispute) ตั้งรายการเงินซ้ำ
+		claimed := tx.Model(&models.TripBooking{}).
+			Where("id = ? AND status = ?", booking.ID, booking.Status).
+			Update("cancellation_reason", reason)
+		if claimed.Error != nil {
+			return claimed.Error
+		}
+		if claimed.RowsAffected == 0 {
+			return errDisputeAlreadyDecided
+		}
+
+		var err error
+		if settlement, err = services.SettleNoShowDecision(tx, &booking, requestData.Decision, reason, now); err != nil {
+			return err
+		}
+		return services.RecordNoShowDecision(tx, booking.ID, requestData.Decision, "admin", requestData.Reason, &userID, now)
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, errDisputeAlreadyDecided):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "no_active_dispute"})
+		case errors.Is(err, services.ErrNothingToSettle):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment_fully_refunded"})
+		case errors.Is(err, services.ErrPaymentNotCollected), errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment_settle_failed", "details": err.Error()})
+		}
+		log.Printf("failed to resolve no-show dispute for booking #%d: %v", booking.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "records_update_failed"})
 	}
-
-	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid decision. Must be 'guide_wins', 'user_wins', or 'split_cost'"})
-}
\ No newline at end of file
+	config.DB.First(&booking, booking.ID)
+
+	data := map[string]interface{}{"BookingID": booking.ID, "Decision": requestData.Decision, "Rationale": requestData.Reason}
+	notify(booking.UserID, "dispute_resolved", data, "trip_booking", booking.ID)
+	notify(bookingCounterpartUserID(config.DB, &booking, "user"), "dispute_resolved", data, "trip_booking", booking.ID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":       "Admin decision recorded. Any refund is being processed.",
+		"booking":       booking,
+		"guide_release": settlement.GuideRelease,
+		"user_refund":   settlement.UserRefund,
+		"decision":      requestData.Decision,
+	})
+}
@@ -3,9 +3,13 @@ package controllers
 import (
 	"localguide-back/config"
 	"localguide-back/models"
+	"localguide-back/services"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 func ApproveGuide(c *fiber.Ctx) error {
@@ -18,7 +22,7 @@ func ApproveGuide(c *fiber.Ctx) error {
 	
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+			"error": "invalid_request_body",
 		})
 	}
 
@@ -26,7 +30,7 @@ func ApproveGuide(c *fiber.Ctx) error {
 	var verification models.GuideVertification
 	if err := config.DB.First(&verification, verificationID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Guide verification not found",
+			"error": "guide_verification_not_found",
 		})
 	}
 
@@ -37,7 +41,7 @@ func ApproveGuide(c *fiber.Ctx) error {
 	// เปลี่ยนแปลงสถานะของ GuideVertification 
 	if err := tx.Model(&verification).Update("status", req.Status).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update guide status",
+			"error": "guide_status_update_failed",
 		})
 	}
 
@@ -57,14 +61,14 @@ func ApproveGuide(c *fiber.Ctx) error {
 		var existingGuide models.Guide
 		if err := tx.Where("user_id = ?", verification.UserID).First(&existingGuide).Error; err == nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Guide already exists",
+				"error": "guide_exists",
 			})
 		}
 
 		// สร้าง Guide ใหม่
 		if err := tx.Create(&newGuide).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to create guide",
+				"error": "guide_create_failed",
 			})
 		}
 
@@ -76,7 +80,7 @@ func ApproveGuide(c *fiber.Ctx) error {
 			}
 			if err := tx.Create(&guideCert).Error; err != nil {
 				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-					"error": "Failed to create guide certification",
+					"error": "guide_certification_create_failed",
 				})
 			}
 		}
@@ -85,14 +89,14 @@ func ApproveGuide(c *fiber.Ctx) error {
 		// อัปเดต GuideID ใน GuideVertification
 		if err := tx.Model(&verification).Update("guide_id", newGuide.ID).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to update guide ID in verification",
+				"error": "verification_guide_update_failed",
 			})
 		}
 
 		// อัปเดต role ของ user เป็น guide (role_id = 2)
 		if err := tx.Model(&models.User{}).Where("id = ?", verification.UserID).Update("role_id", 2).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to update user role",
+				"error": "user_role_update_failed",
 			})
 		}
 
@@ -103,7 +107,7 @@ func ApproveGuide(c *fiber.Ctx) error {
 				// เชื่อมโยง guide กับ language (many2many)
 				if err := tx.Model(&newGuide).Association("Language").Append(&lang); err != nil {
 					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-						"error": "Failed to associate guide with language",
+						"error": "guide_language_link_failed",
 					})
 				}
 			}
@@ -116,7 +120,7 @@ func ApproveGuide(c *fiber.Ctx) error {
 				// เชื่อมโยง guide กับ tourist attraction (many2many)
 				if err := tx.Model(&newGuide).Association("TouristAttraction").Append(&attraction); err != nil {
 					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-						"error": "Failed to associate guide with tourist attraction",
+						"error": "guide_attraction_link_failed",
 					})
 				}
 			}
@@ -128,7 +132,7 @@ func ApproveGuide(c *fiber.Ctx) error {
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to commit transaction",
+			"error": "transaction_commit_failed",
 		})
 	}
 
@@ -149,7 +153,7 @@ func GetAllGuides(c *fiber.Ctx) error {
 		Preload("Certification").
 		Find(&guides).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve guides",
+			"error": "guides_get_failed",
 		})
 	}
 	return c.JSON(fiber.Map{
@@ -169,7 +173,7 @@ func GetPendingVerifications(c *fiber.Ctx) error {
 		Preload("Language").
 		Find(&verifications).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve verification requests",
+			"error": "verification_requests_get_failed",
 		})
 	}
 
@@ -178,67 +182,202 @@ func GetPendingVerifications(c *fiber.Ctx) error {
 	})
 }
 
-// GetAllTripReports returns all trip reports for admin
+// GetAllTripReports - คิวเคสของ admin กรองได้ด้วย ?status=open|pending,investigating &severity= &type= &booking_id=
+// &assigned_to=me|unassigned|<user id> &min_age_hours= &max_age_hours= &overdue=true เรียงตาม ?sort=sla (ค่าเริ่มต้น)|newest|oldest
 func GetAllTripReports(c *fiber.Ctx) error {
-	var reports []models.TripReport
+	now := time.Now()
+	query := config.DB.Model(&models.TripReport{})
+
+	if status := c.Query("status"); status == "open" {
+		query = query.Where("status IN ?", services.OpenReportStatuses)
+	} else if status != "" {
+		statuses := queryList(status)
+		for _, s := range statuses {
+			if !reportStatuses[s] {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "report_status_filter_invalid",
+				})
+			}
+		}
+		query = query.Where("status IN ?", statuses)
+	}
+	if severity := c.Query("severity"); severity != "" {
+		severities := queryList(severity)
+		for _, s := range severities {
+			if reportSeverityLevels[s] == 0 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "severity_invalid",
+				})
+			}
+		}
+		query = query.Where("severity IN ?", severities)
+	}
+	if reportType := c.Query("type"); reportType != "" {
+		query = query.Where("report_type IN ?", queryList(reportType))
+	}
+	if bookingID := c.QueryInt("booking_id"); bookingID > 0 {
+		query = query.Where("trip_booking_id = ?", bookingID)
+	}
+	switch assigned := c.Query("assigned_to"); assigned {
+	case "":
+	case "unassigned":
+		query = query.Where("assigned_to IS NULL")
+	case "me":
+		query = query.Where("assigned_to = ?", c.Locals("user_id"))
+	default:
+		id, err := strconv.Atoi(assigned)
+		if err != nil || id <= 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "assigned_to_invalid",
+			})
+		}
+		query = query.Where("assigned_to = ?", id)
+	}
+	if hours := c.QueryInt("min_age_hours"); hours > 0 {
+		query = query.Where("created_at <= ?", now.Add(-time.Duration(hours)*time.Hour))
+	}
+	if hours := c.QueryInt("max_age_hours"); hours > 0 {
+		query = query.Where("created_at >= ?", now.Add(-time.Duration(hours)*time.Hour))
+	}
+	if c.QueryBool("overdue") {
+		cond, args := services.OverdueReportCondition(now)
+		query = query.Where(cond, args...)
+	}
 
-	if err := config.DB.
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "trip_reports_get_failed",
+		})
+	}
+
+	switch c.Query("sort", "sla") {
+	case "sla":
+		// เคสที่ร้ายแรงกว่าและรอนานกว่าอยู่บนสุด
+		query = query.Order("CASE severity WHEN 'critical' THEN 4 WHEN 'high' THEN 3 WHEN 'medium' THEN 2 ELSE 1 END DESC").Order("created_at ASC")
+	case "newest":
+		query = query.Order("created_at DESC")
+	case "oldest":
+		query = query.Order("created_at ASC")
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "report_sort_invalid",
+		})
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	var reports []models.TripReport
+	if err := query.
 		Preload("TripBooking.User").
 		Preload("TripBooking.Guide.User").
 		Preload("Reporter").
 		Preload("ReportedUser").
+		Offset((page - 1) * limit).Limit(limit).
 		Find(&reports).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve trip reports",
+			"error": "trip_reports_get_failed",
+		})
+	}
+
+	items := make([]fiber.Map, 0, len(reports))
+	for i := range reports {
+		items = append(items, fiber.Map{
+			"report": reports[i],
+			"sla":    services.CaseSLA(&reports[i], now),
 		})
 	}
 
 	return c.JSON(fiber.Map{
-		"reports": reports,
+		"reports": items,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
 	})
 }
 
 // HandleTripReport allows admin to handle trip reports
+// admin_notes คือคำตอบที่ผู้รีพอร์ตเห็น บันทึกภายในใช้ POST /admin/trip-reports/:id/notes
 func HandleTripReport(c *fiber.Ctx) error {
 	reportID := c.Params("id")
 	
 	var req struct {
-		Status      string `json:"status"`      // investigating, resolved, dismissed
+		Status      string `json:"status"`      // pending, investigating, resolved, dismissed
 		AdminNotes  string `json:"admin_notes"`
 		Actions     string `json:"actions"`     // JSON string of actions taken
 	}
 
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+			"error": "invalid_request_body",
+		})
+	}
+	if !reportStatuses[req.Status] {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "report_status_invalid",
 		})
 	}
 
 	var report models.TripReport
 	if err := config.DB.First(&report, reportID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Trip report not found",
+			"error": "trip_report_not_found",
 		})
 	}
 
 	// Update report status and admin notes
+	now := time.Now()
 	updates := map[string]interface{}{
-		"status":      req.Status,
-		"admin_notes": req.AdminNotes,
-		"actions":     req.Actions,
+		"status": req.Status,
+	}
+	if req.AdminNotes != "" {
+		updates["admin_notes"] = req.AdminNotes
+	}
+	if req.Actions != "" {
+		updates["actions"] = req.Actions
 	}
 
-	if req.Status == "resolved" {
-		now := time.Now()
-		updates["resolved_at"] = &now
+	// admin คนแรกที่เปิดดูเคสถือเป็นผู้รับเรื่อง (นับ SLA การตอบรับ)
+	if adminID, ok := c.Locals("user_id").(uint); ok && report.ReviewedAt == nil && req.Status != "pending" {
+		updates["reviewed_by"] = adminID
+		updates["reviewed_at"] = &now
+		if report.AssignedTo == nil {
+			updates["assigned_to"] = adminID
+			updates["assigned_at"] = &now
+		}
+	}
+
+	if req.Status == "resolved" || req.Status == "dismissed" {
+		if report.ResolvedAt == nil {
+			updates["resolved_at"] = &now
+		}
+	} else {
+		updates["resolved_at"] = nil
 	}
 
+	previousStatus := report.Status
 	if err := config.DB.Model(&report).Updates(updates).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update trip report",
+			"error": "trip_report_update_failed",
 		})
 	}
 
+	// แจ้งผู้รีพอร์ตเมื่อสถานะเปลี่ยน
+	if req.Status != previousStatus {
+		notify(report.ReporterID, "report_updated", map[string]interface{}{
+			"BookingID": report.TripBookingID,
+			"Title":     report.Title,
+			"Status":    req.Status,
+		}, "trip_report", report.ID)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Trip report updated successfully",
 		"report":  report,
@@ -255,7 +394,7 @@ func GetAllPayments(c *fiber.Ctx) error {
 		Preload("TripBooking.Guide").
 		Find(&payments).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve payments",
+			"error": "payments_get_failed",
 		})
 	}
 
@@ -271,21 +410,40 @@ func ManualReleasePayment(c *fiber.Ctx) error {
 	var req struct {
 		ReleaseType   string  `json:"release_type"` // first_payment, second_payment, refund
 		Amount        float64 `json:"amount"`
-		RecipientType string  `json:"recipient_type"` // guide, user
-		RecipientID   uint    `json:"recipient_id"`
+		RecipientType string  `json:"recipient_type"` // guide, user (ผู้รับคือไกด์/ผู้จองของ booking นี้)
 		Reason        string  `json:"reason"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+			"error": "invalid_request_body",
 		})
 	}
 
 	var payment models.TripPayment
 	if err := config.DB.First(&payment, paymentID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Payment not found",
+			"error": "payment_not_found",
+		})
+	}
+
+	// RecipientID ของ release ไกด์คือ Guide.ID ของ user คือ User.ID เอาจาก booking ไม่รับจาก request
+	var booking models.TripBooking
+	if err := config.DB.First(&booking, payment.TripBookingID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "booking_not_found",
+		})
+	}
+
+	var recipientID uint
+	switch req.RecipientType {
+	case "guide":
+		recipientID = booking.GuideID
+	case "user":
+		recipientID = booking.UserID
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "recipient_type_invalid",
 		})
 	}
 
@@ -296,7 +454,7 @@ func ManualReleasePayment(c *fiber.Ctx) error {
 		ReleaseType:   req.ReleaseType,
 		Amount:        req.Amount,
 		RecipientType: req.RecipientType,
-		RecipientID:   req.RecipientID,
+		RecipientID:   recipientID,
 		Reason:        req.Reason,
 		ScheduledAt:   now,
 		ProcessedAt:   &now,
@@ -304,9 +462,19 @@ func ManualReleasePayment(c *fiber.Ctx) error {
 		Notes:         "Manual release by admin",
 	}
 
-	if err := config.DB.Create(&release).Error; err != nil {
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	// จ่ายไกด์ผ่าน outbox (โอนเข้า Stripe Connect ถ้ามีบัญชี) ส่วนคืนเงิน user admin ทำเองนอกระบบ
+	var err error
+	if req.RecipientType == "guide" {
+		_, err = services.QueueGuidePayout(tx, &payment, &release)
+	} else {
+		err = tx.Create(&release).Error
+	}
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create payment release",
+			"error": "payment_release_create_failed",
 		})
 	}
 
@@ -326,15 +494,170 @@ func ManualReleasePayment(c *fiber.Ctx) error {
 	}
 
 	payment.Status = newStatus
-	if err := config.DB.Save(&payment).Error; err != nil {
+	if err := tx.Save(&payment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "payment_status_update_failed",
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update payment status",
+			"error": "payment_status_update_failed",
 		})
 	}
 
+	if req.ReleaseType == "refund" && req.RecipientType == "user" {
+		issueInvoices(booking.ID)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Payment released successfully",
 		"release": release,
 		"payment": payment,
 	})
 }
+
+// SetPaymentPayoutFreeze - admin ระงับ/ยกเลิกการระงับการจ่ายไกด์ของ payment (เช่น ระหว่างสอบสวนรีพอร์ตความปลอดภัย)
+// ยกเลิกแล้วรายการจ่ายที่ค้างไว้จะถูกจ่ายต่อ
+func SetPaymentPayoutFreeze(c *fiber.Ctx) error {
+	var req struct {
+		Frozen bool   `json:"frozen"`
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+
+	var payment models.TripPayment
+	if err := config.DB.First(&payment, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "payment_not_found",
+		})
+	}
+	if payment.PayoutsFrozen == req.Frozen {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":          "payout_freeze_unchanged",
+			"payouts_frozen": payment.PayoutsFrozen,
+		})
+	}
+	if req.Frozen && strings.TrimSpace(req.Reason) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "reason_required",
+		})
+	}
+
+	now := time.Now()
+	var released int64
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if req.Frozen {
+			return services.FreezePayouts(tx, payment.ID, strings.TrimSpace(req.Reason), now)
+		}
+		var err error
+		released, err = services.UnfreezePayouts(tx, payment.ID, now)
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "payout_freeze_update_failed",
+		})
+	}
+
+	config.DB.First(&payment, payment.ID)
+	return c.JSON(fiber.Map{
+		"message":          "Payout freeze updated",
+		"payment":          payment,
+		"released_payouts": released,
+	})
+}
+
+// GetPaymentOutbox - คำสั่งคืนเงิน/โอนเงินที่รอทำกับ Stripe (?status=pending|succeeded|failed)
+func GetPaymentOutbox(c *fiber.Ctx) error {
+	query := config.DB.Preload("PaymentRelease").Order("id DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var entries []models.PaymentOutbox
+	if err := query.Limit(200).Find(&entries).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "payment_outbox_get_failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"outbox": entries,
+	})
+}
+
+// RetryPaymentOutbox - ให้ worker ลองคำสั่งที่ล้มเหลวใหม่ (ใช้ idempotency key เดิม)
+func RetryPaymentOutbox(c *fiber.Ctx) error {
+	var entry models.PaymentOutbox
+	if err := config.DB.First(&entry, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "outbox_entry_not_found",
+		})
+	}
+
+	if entry.Status != "failed" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "outbox_entry_not_failed",
+			"status": entry.Status,
+		})
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&entry).Updates(map[string]interface{}{
+			"status":          "pending",
+			"attempts":        0,
+			"next_attempt_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.PaymentRelease{}).Where("id = ?", entry.PaymentReleaseID).Update("status", "pending").Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "outbox_retry_failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Outbox entry queued for retry",
+		"outbox":  entry,
+	})
+}
+
+// SetGuideStripeAccount - ผูกบัญชี Stripe Connect ของไกด์ เพื่อโอนเงินผ่าน Stripe
+func SetGuideStripeAccount(c *fiber.Ctx) error {
+	var req struct {
+		StripeAccountID string `json:"stripe_account_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+	if req.StripeAccountID != "" && !strings.HasPrefix(req.StripeAccountID, "acct_") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "stripe_account_invalid",
+		})
+	}
+
+	result := config.DB.Model(&models.Guide{}).Where("id = ?", c.Params("id")).Update("stripe_account_id", req.StripeAccountID)
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "guide_update_failed",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "guide_not_found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Guide Stripe account updated",
+	})
+}
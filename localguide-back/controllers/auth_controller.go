@@ -22,33 +22,33 @@ func Register(c *fiber.Ctx) error {
 	}
 
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
 	}
 
 	if !isValidEmail(req.Email) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email format"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email_invalid"})
 	}
 
 	if utf8.RuneCountInString(req.Password) < 8 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password must be at least 8 characters"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "password_too_short"})
 	}
 
 	if utf8.RuneCountInString(req.FirstName) == 0 || utf8.RuneCountInString(req.LastName) == 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "First name and last name are required"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "name_required"})
 	}
 
 	if !isValidPhone(req.Phone) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid phone number"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "phone_invalid"})
 	}
 
 	var existing models.AuthUser
 	if err := config.DB.Where("email = ?", req.Email).First(&existing).Error; err == nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Email already exists"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email_taken"})
 	}
 
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Password hash failed"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "password_hash_failed"})
 	}
 
 	tx := config.DB.Begin()
@@ -58,7 +58,7 @@ func Register(c *fiber.Ctx) error {
 	}
 	if err := tx.Create(&authUser).Error; err != nil {
 		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create auth user"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "auth_user_create_failed"})
 	}
 
 	// สมัคร user อย่างเดียว (role = 1, กำหนดชื่อ-สกุล-เบอร์)
@@ -76,11 +76,11 @@ func Register(c *fiber.Ctx) error {
 
 	if err := tx.Create(&user).Error; err != nil {
 		tx.Rollback()
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_create_failed"})
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Transaction failed"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "transaction_failed"})
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
@@ -91,7 +91,7 @@ func Register(c *fiber.Ctx) error {
 
 	tokenString, err := token.SignedString(config.JWTSecret)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Token creation failed"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_creation_failed"})
 	}
 
 	userResponse := fiber.Map{
@@ -112,19 +112,19 @@ func Register(c *fiber.Ctx) error {
 func Login(c *fiber.Ctx) error {
 	var req models.AuthUser
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
 	}
 
 	var auth models.AuthUser
 	if err := config.DB.Where("email = ?", req.Email).First(&auth).Error; err != nil ||
 		bcrypt.CompareHashAndPassword([]byte(auth.Password), []byte(req.Password)) != nil {
 		time.Sleep(500 * time.Millisecond)
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Invalid credentials"})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "credentials_invalid"})
 	}
 
 	var user models.User
 	if err := config.DB.Where("auth_user_id = ?", auth.ID).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "User not found"})
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "user_not_found"})
 	}
 
 	// ไม่ต้องเช็ค guide อีกต่อไป
@@ -136,7 +136,7 @@ func Login(c *fiber.Ctx) error {
 
 	tokenString, err := token.SignedString(config.JWTSecret)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Token generation failed"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_generation_failed"})
 	}
 
 	return c.JSON(fiber.Map{
@@ -152,17 +152,17 @@ func Login(c *fiber.Ctx) error {
 func Me(c *fiber.Ctx) error {
     userID := c.Locals("user_id") 
     if userID == nil {
-        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Unauthorized"})
+        return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "unauthorized"})
     }
     
     var user models.User
     if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
     }
     
     var authUser models.AuthUser
     if err := config.DB.Where("id = ?", user.AuthUserID).First(&authUser).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "AuthUser not found"})
+        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "auth_user_not_found"})
     }
     
     return c.JSON(fiber.Map{
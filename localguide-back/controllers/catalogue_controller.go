@@ -0,0 +1,620 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	maxCatalogueNameLength        = 100
+	maxCatalogueDescriptionLength = 2000
+)
+
+// catalogueLabels - ชื่อที่ใช้ในข้อความตอบกลับของแต่ละประเภท
+var catalogueLabels = map[string]string{
+	"province":   "Province",
+	"attraction": "Tourist attraction",
+	"language":   "Language",
+	"category":   "Attraction category",
+}
+
+// catalogueCodes - คำนำหน้ารหัสข้อความ error ของแต่ละประเภท (เช่น province_not_found)
+var catalogueCodes = map[string]string{
+	"province":   "province",
+	"attraction": "tourist_attraction",
+	"language":   "language",
+	"category":   "attraction_category",
+}
+
+// catalogueID - อ่าน :id ของ route คืน 0 เมื่อส่ง response error ไปแล้ว
+func catalogueID(c *fiber.Ctx, kind string) (uint, error) {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return 0, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": catalogueCodes[kind] + "_id_invalid",
+		})
+	}
+	return uint(id), nil
+}
+
+// catalogueName - ตัดช่องว่างและตรวจความยาวชื่อ คืนรหัสข้อความ error (ว่าง = ผ่าน)
+func catalogueName(kind, name string) (string, string) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", catalogueCodes[kind] + "_name_required"
+	}
+	if utf8.RuneCountInString(name) > maxCatalogueNameLength {
+		return "", catalogueCodes[kind] + "_name_too_long"
+	}
+	return name, ""
+}
+
+// catalogueNameTaken - มีรายการอื่นใช้ชื่อนี้อยู่หรือไม่ (ไม่สนตัวพิมพ์เล็ก/ใหญ่; ใช้ q.Unscoped() ถ้าต้องนับรายการที่ถูกลบด้วย)
+func catalogueNameTaken(q *gorm.DB, name string, exceptID uint) bool {
+	var count int64
+	q.Where("LOWER(name) = LOWER(?) AND id <> ?", name, exceptID).Count(&count)
+	return count > 0
+}
+
+func catalogueNotFound(c *fiber.Ctx, kind string) error {
+	return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+		"error": catalogueCodes[kind] + "_not_found",
+	})
+}
+
+// getCatalogueImpact - จำนวนข้อมูลที่อ้างถึงรายการ (ใช้ตรวจก่อนลบ)
+func getCatalogueImpact(c *fiber.Ctx, kind string) error {
+	id, err := catalogueID(c, kind)
+	if id == 0 {
+		return err
+	}
+	impact, err := services.NewCatalogueService(config.DB).Impact(kind, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return catalogueNotFound(c, kind)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "references_check_failed",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"impact":    impact,
+		"deletable": !impact.Referenced(),
+	})
+}
+
+// deleteCatalogueEntry - soft delete รายการที่ไม่มีไกด์/ใบสมัคร/ข้อมูลอื่นอ้างถึง
+func deleteCatalogueEntry(c *fiber.Ctx, kind string) error {
+	id, err := catalogueID(c, kind)
+	if id == 0 {
+		return err
+	}
+	impact, err := services.NewCatalogueService(config.DB).Delete(kind, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return catalogueNotFound(c, kind)
+	}
+	if errors.Is(err, services.ErrCatalogueInUse) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error":  catalogueCodes[kind] + "_in_use",
+			"impact": impact,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": catalogueCodes[kind] + "_delete_failed",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": catalogueLabels[kind] + " deleted successfully",
+	})
+}
+
+// mergeCatalogueEntry - ย้ายการอ้างถึงทั้งหมดไปที่ target_id แล้วลบรายการนี้
+func mergeCatalogueEntry(c *fiber.Ctx, kind string) error {
+	id, err := catalogueID(c, kind)
+	if id == 0 {
+		return err
+	}
+	var input struct {
+		TargetID uint `json:"target_id"`
+	}
+	if err := c.BodyParser(&input); err != nil || input.TargetID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "target_id_required",
+		})
+	}
+
+	moved, err := services.NewCatalogueService(config.DB).Merge(kind, id, input.TargetID)
+	if errors.Is(err, services.ErrCatalogueMergeSelf) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "catalogue_merge_self",
+		})
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return catalogueNotFound(c, kind)
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": catalogueCodes[kind] + "_merge_failed",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message":   catalogueLabels[kind] + " merged successfully",
+		"target_id": input.TargetID,
+		"moved":     moved,
+	})
+}
+
+// GetAttractionCategories - ประเภทสถานที่ท่องเที่ยวทั้งหมด
+func GetAttractionCategories(c *fiber.Ctx) error {
+	var categories []models.AttractionCategory
+	if err := config.DB.Order("name").Find(&categories).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "attraction_categories_get_failed",
+		})
+	}
+	localizeContent(c, &categories)
+	return c.JSON(fiber.Map{
+		"categories": categories,
+	})
+}
+
+// AdminCreateProvince - เพิ่มจังหวัด
+func AdminCreateProvince(c *fiber.Ctx) error {
+	var input struct {
+		Name   string `json:"name"`
+		Region string `json:"region"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	province := models.Province{}
+	if msg := applyProvinceInput(&province, &input.Name, &input.Region); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	// ชื่อจังหวัดเป็น unique ใน DB รวมรายการที่ถูกลบ
+	if catalogueNameTaken(config.DB.Unscoped().Model(&models.Province{}), province.Name, 0) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "province_name_taken"})
+	}
+	if err := config.DB.Create(&province).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "province_create_failed"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Province created successfully",
+		"province": province,
+	})
+}
+
+// AdminUpdateProvince - แก้ไขชื่อ/ภาคของจังหวัด (ส่งเฉพาะ field ที่ต้องการแก้)
+func AdminUpdateProvince(c *fiber.Ctx) error {
+	id, err := catalogueID(c, "province")
+	if id == 0 {
+		return err
+	}
+	var input struct {
+		Name   *string `json:"name"`
+		Region *string `json:"region"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	var province models.Province
+	if err := config.DB.First(&province, id).Error; err != nil {
+		return catalogueNotFound(c, "province")
+	}
+	if msg := applyProvinceInput(&province, input.Name, input.Region); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if catalogueNameTaken(config.DB.Unscoped().Model(&models.Province{}), province.Name, province.ID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "province_name_taken"})
+	}
+	if err := config.DB.Save(&province).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "province_update_failed"})
+	}
+	return c.JSON(fiber.Map{
+		"message":  "Province updated successfully",
+		"province": province,
+	})
+}
+
+func applyProvinceInput(province *models.Province, name, region *string) string {
+	if name != nil {
+		n, msg := catalogueName("province", *name)
+		if msg != "" {
+			return msg
+		}
+		province.Name = n
+	}
+	if region != nil {
+		r := strings.TrimSpace(*region)
+		if r == "" || utf8.RuneCountInString(r) > maxCatalogueNameLength {
+			return "region_invalid"
+		}
+		province.Region = r
+	}
+	return ""
+}
+
+// AdminDeleteProvince - ลบจังหวัดที่ไม่มีไกด์ ใบสมัคร สถานที่ท่องเที่ยว หรือโพสต์ทริปอ้างถึง
+func AdminDeleteProvince(c *fiber.Ctx) error { return deleteCatalogueEntry(c, "province") }
+
+// AdminMergeProvince - รวมจังหวัดเข้ากับ target_id
+func AdminMergeProvince(c *fiber.Ctx) error { return mergeCatalogueEntry(c, "province") }
+
+// AdminGetProvinceImpact - ข้อมูลที่อ้างถึงจังหวัด
+func AdminGetProvinceImpact(c *fiber.Ctx) error { return getCatalogueImpact(c, "province") }
+
+// attractionInput - ข้อมูลสถานที่ท่องเที่ยวจาก admin (nil = ไม่แก้ field นั้น)
+type attractionInput struct {
+	Name          *string  `json:"name"`
+	Description   *string  `json:"description"`
+	ProvinceID    *uint    `json:"province_id"`
+	CategoryID    *uint    `json:"category_id"`
+	District      *string  `json:"district"`
+	City          *string  `json:"city"`
+	Rating        *float64 `json:"rating"`
+	Latitude      *float64 `json:"latitude"` // ส่งคู่กับ longitude
+	Longitude     *float64 `json:"longitude"`
+	ClearLocation bool     `json:"clear_location"` // true = ลบพิกัดเดิม
+}
+
+// apply ตรวจและกำหนดค่าให้ attraction คืนสถานะและรหัสข้อความ error (0 = ผ่าน)
+func (in *attractionInput) apply(attraction *models.TouristAttraction) (int, string) {
+	if in.Name != nil {
+		name, msg := catalogueName("attraction", *in.Name)
+		if msg != "" {
+			return fiber.StatusBadRequest, msg
+		}
+		attraction.Name = name
+	}
+	if in.Description != nil {
+		d := strings.TrimSpace(*in.Description)
+		if utf8.RuneCountInString(d) > maxCatalogueDescriptionLength {
+			return fiber.StatusBadRequest, "description_too_long"
+		}
+		attraction.Description = d
+	}
+	for _, f := range []struct {
+		value *string
+		dst   *string
+		code  string
+	}{{in.District, &attraction.District, "district_too_long"}, {in.City, &attraction.City, "city_too_long"}} {
+		if f.value == nil {
+			continue
+		}
+		v := strings.TrimSpace(*f.value)
+		if utf8.RuneCountInString(v) > maxCatalogueNameLength {
+			return fiber.StatusBadRequest, f.code
+		}
+		*f.dst = v
+	}
+	if in.Rating != nil {
+		if *in.Rating < 0 || *in.Rating > 5 {
+			return fiber.StatusBadRequest, "rating_invalid"
+		}
+		attraction.Rating = *in.Rating
+	}
+	if in.ClearLocation {
+		attraction.Latitude, attraction.Longitude = nil, nil
+	} else if in.Latitude != nil || in.Longitude != nil {
+		if in.Latitude == nil || in.Longitude == nil {
+			return fiber.StatusBadRequest, "coordinates_not_paired"
+		}
+		if !validCoordinates(*in.Latitude, *in.Longitude) {
+			return fiber.StatusBadRequest, "coordinates_invalid"
+		}
+		attraction.Latitude, attraction.Longitude = in.Latitude, in.Longitude
+	}
+	if in.ProvinceID != nil {
+		var province models.Province
+		if err := config.DB.First(&province, *in.ProvinceID).Error; err != nil {
+			return fiber.StatusBadRequest, "province_not_found"
+		}
+		attraction.ProvinceID = province.ID
+	}
+	if in.CategoryID != nil {
+		var category models.AttractionCategory
+		if err := config.DB.First(&category, *in.CategoryID).Error; err != nil {
+			return fiber.StatusBadRequest, "attraction_category_not_found"
+		}
+		attraction.CategoryID = &category.ID
+		attraction.Category = category.Name
+	}
+	if attraction.ProvinceID == 0 {
+		return fiber.StatusBadRequest, "province_id_required"
+	}
+	if attraction.CategoryID == nil {
+		return fiber.StatusBadRequest, "category_id_required"
+	}
+
+	var duplicates int64
+	config.DB.Model(&models.TouristAttraction{}).
+		Where("LOWER(name) = LOWER(?) AND province_id = ? AND id <> ?", attraction.Name, attraction.ProvinceID, attraction.ID).
+		Count(&duplicates)
+	if duplicates > 0 {
+		return fiber.StatusConflict, "attraction_name_taken"
+	}
+	return 0, ""
+}
+
+// AdminCreateAttraction - เพิ่มสถานที่ท่องเที่ยว (อัปโหลดรูปผ่าน PUT /admin/attractions/:id/image)
+func AdminCreateAttraction(c *fiber.Ctx) error {
+	var input attractionInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	if input.Name == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "tourist_attraction_name_required"})
+	}
+	var attraction models.TouristAttraction
+	if status, msg := input.apply(&attraction); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err := config.DB.Create(&attraction).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "tourist_attraction_create_failed"})
+	}
+	config.DB.Preload("Province").Preload("AttractionCategory").First(&attraction, attraction.ID)
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":    "Tourist attraction created successfully",
+		"attraction": attraction,
+	})
+}
+
+// AdminUpdateAttraction - แก้ไขสถานที่ท่องเที่ยว (ส่งเฉพาะ field ที่ต้องการแก้)
+func AdminUpdateAttraction(c *fiber.Ctx) error {
+	id, err := catalogueID(c, "attraction")
+	if id == 0 {
+		return err
+	}
+	var input attractionInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	var attraction models.TouristAttraction
+	if err := config.DB.First(&attraction, id).Error; err != nil {
+		return catalogueNotFound(c, "attraction")
+	}
+	if status, msg := input.apply(&attraction); status != 0 {
+		return c.Status(status).JSON(fiber.Map{"error": msg})
+	}
+	if err := config.DB.Omit("Province", "AttractionCategory", "Image").Save(&attraction).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "tourist_attraction_update_failed"})
+	}
+	config.DB.Preload("Province").Preload("AttractionCategory").Preload("Image.Variants").First(&attraction, attraction.ID)
+	return c.JSON(fiber.Map{
+		"message":    "Tourist attraction updated successfully",
+		"attraction": attraction,
+	})
+}
+
+// AdminDeleteAttraction - ลบสถานที่ท่องเที่ยวที่ไม่มีไกด์หรือใบสมัครอ้างถึง
+func AdminDeleteAttraction(c *fiber.Ctx) error { return deleteCatalogueEntry(c, "attraction") }
+
+// AdminMergeAttraction - รวมสถานที่ท่องเที่ยว (เช่นรายการซ้ำ) เข้ากับ target_id
+func AdminMergeAttraction(c *fiber.Ctx) error { return mergeCatalogueEntry(c, "attraction") }
+
+// AdminGetAttractionImpact - ข้อมูลที่อ้างถึงสถานที่ท่องเที่ยว
+func AdminGetAttractionImpact(c *fiber.Ctx) error { return getCatalogueImpact(c, "attraction") }
+
+// AdminCreateLanguage - เพิ่มภาษา
+func AdminCreateLanguage(c *fiber.Ctx) error {
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	name, msg := catalogueName("language", input.Name)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if catalogueNameTaken(config.DB.Model(&models.Language{}), name, 0) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "language_name_taken"})
+	}
+	language := models.Language{Name: name}
+	if err := config.DB.Create(&language).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "language_create_failed"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Language created successfully",
+		"language": language,
+	})
+}
+
+// AdminUpdateLanguage - แก้ชื่อภาษา
+func AdminUpdateLanguage(c *fiber.Ctx) error {
+	id, err := catalogueID(c, "language")
+	if id == 0 {
+		return err
+	}
+	var input struct {
+		Name string `json:"name"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	var language models.Language
+	if err := config.DB.First(&language, id).Error; err != nil {
+		return catalogueNotFound(c, "language")
+	}
+	name, msg := catalogueName("language", input.Name)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if catalogueNameTaken(config.DB.Model(&models.Language{}), name, language.ID) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "language_name_taken"})
+	}
+	language.Name = name
+	if err := config.DB.Save(&language).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "language_update_failed"})
+	}
+	return c.JSON(fiber.Map{
+		"message":  "Language updated successfully",
+		"language": language,
+	})
+}
+
+// AdminDeleteLanguage - ลบภาษาที่ไม่มีไกด์หรือใบสมัครอ้างถึง
+func AdminDeleteLanguage(c *fiber.Ctx) error { return deleteCatalogueEntry(c, "language") }
+
+// AdminMergeLanguage - รวมภาษาเข้ากับ target_id
+func AdminMergeLanguage(c *fiber.Ctx) error { return mergeCatalogueEntry(c, "language") }
+
+// AdminGetLanguageImpact - ข้อมูลที่อ้างถึงภาษา
+func AdminGetLanguageImpact(c *fiber.Ctx) error { return getCatalogueImpact(c, "language") }
+
+// AdminCreateAttractionCategory - เพิ่มประเภทสถานที่ท่องเที่ยว
+func AdminCreateAttractionCategory(c *fiber.Ctx) error {
+	var input struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	name, msg := catalogueName("category", input.Name)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	if catalogueNameTaken(config.DB.Model(&models.AttractionCategory{}), name, 0) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "attraction_category_name_taken"})
+	}
+	category := models.AttractionCategory{Name: name, Description: strings.TrimSpace(input.Description)}
+	if err := config.DB.Create(&category).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "attraction_category_create_failed"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Attraction category created successfully",
+		"category": category,
+	})
+}
+
+// AdminUpdateAttractionCategory - แก้ไขประเภท (ชื่อใหม่ถูกคัดลอกไปที่ Category ของสถานที่ท่องเที่ยวด้วย)
+func AdminUpdateAttractionCategory(c *fiber.Ctx) error {
+	id, err := catalogueID(c, "category")
+	if id == 0 {
+		return err
+	}
+	var input struct {
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	var category models.AttractionCategory
+	if err := config.DB.First(&category, id).Error; err != nil {
+		return catalogueNotFound(c, "category")
+	}
+	if input.Name != nil {
+		name, msg := catalogueName("category", *input.Name)
+		if msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		if catalogueNameTaken(config.DB.Model(&models.AttractionCategory{}), name, category.ID) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "attraction_category_name_taken"})
+		}
+		category.Name = name
+	}
+	if input.Description != nil {
+		category.Description = strings.TrimSpace(*input.Description)
+	}
+
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&category).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.TouristAttraction{}).Where("category_id = ?", category.ID).
+			Update("category", category.Name).Error
+	}); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "attraction_category_update_failed"})
+	}
+	return c.JSON(fiber.Map{
+		"message":  "Attraction category updated successfully",
+		"category": category,
+	})
+}
+
+// AdminDeleteAttractionCategory - ลบประเภทที่ไม่มีสถานที่ท่องเที่ยวใช้อยู่
+func AdminDeleteAttractionCategory(c *fiber.Ctx) error { return deleteCatalogueEntry(c, "category") }
+
+// AdminMergeAttractionCategory - รวมประเภทเข้ากับ target_id
+func AdminMergeAttractionCategory(c *fiber.Ctx) error { return mergeCatalogueEntry(c, "category") }
+
+// AdminGetAttractionCategoryImpact - จำนวนสถานที่ท่องเที่ยวที่ใช้ประเภทนี้
+func AdminGetAttractionCategoryImpact(c *fiber.Ctx) error { return getCatalogueImpact(c, "category") }
+
+// AdminImportAttractions - นำเข้าสถานที่ท่องเที่ยวจาก CSV หรือ GeoJSON (form field: file)
+// จับคู่จังหวัดและประเภทด้วยชื่อ ค่าเริ่มต้นเป็น dry run คืน diff (new/changed/unchanged/invalid)
+// ส่ง ?apply=true เพื่อบันทึก (ต้องไม่มีแถวที่ไม่ถูกต้อง) ?format=csv|geojson (ไม่ส่ง = ดูจากนามสกุลไฟล์)
+func AdminImportAttractions(c *fiber.Ctx) error {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file_required"})
+	}
+	format := c.Query("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+	}
+	f, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "file_read_failed"})
+	}
+	defer f.Close()
+
+	file, err := services.ParseAttractionFile(format, f)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	result, err := services.NewAttractionImportService(config.DB).Import(file, c.QueryBool("apply"))
+	if errors.Is(err, services.ErrAttractionImportInvalid) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":  "import_has_invalid_rows",
+			"result": result,
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "attraction_import_failed"})
+	}
+	return c.JSON(fiber.Map{"result": result})
+}
+
+// AdminExportAttractions - ส่งออกสถานที่ท่องเที่ยว ?format=csv (ค่าเริ่มต้น) | geojson &province_id=
+// ไฟล์ที่ได้แก้ใน spreadsheet แล้วนำเข้ากลับได้
+func AdminExportAttractions(c *fiber.Ctx) error {
+	format := c.Query("format", "csv")
+	if format != "csv" && format != "geojson" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "export_format_invalid"})
+	}
+	attractions, err := services.NewAttractionImportService(config.DB).Export(uint(c.QueryInt("province_id", 0)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "attraction_export_failed"})
+	}
+
+	var buf bytes.Buffer
+	filename := "attractions-" + time.Now().Format("20060102")
+	if format == "geojson" {
+		err = services.WriteAttractionsGeoJSON(&buf, attractions)
+		c.Set(fiber.HeaderContentType, "application/geo+json")
+		filename += ".geojson"
+	} else {
+		err = services.WriteAttractionsCSV(&buf, attractions)
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		filename += ".csv"
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "attraction_export_failed"})
+	}
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	return c.Send(buf.Bytes())
+}
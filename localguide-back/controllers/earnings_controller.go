@@ -0,0 +1,134 @@
+package controllers
+
+import (
+	"fmt"
+	"localguide-back/config"
+	"localguide-back/services"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseEarningsDate - รับวันที่แบบ YYYY-MM-DD ตามเวลาท้องถิ่น (ว่าง = ไม่จำกัด)
+func parseEarningsDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// GetMyEarnings - รายได้ของไกด์: ยอดรอจ่าย/จ่ายแล้ว/คืนเงิน แยกตาม booking และเดือน
+// ?from=YYYY-MM-DD&to=YYYY-MM-DD (to รวมวันนั้นด้วย) จำกัดช่วงของรายการ ยอด totals เป็นยอดสะสมถึงปัจจุบันเสมอ
+func GetMyEarnings(c *fiber.Ctx) error {
+	guide, resp := currentGuide(c)
+	if guide == nil {
+		return resp
+	}
+
+	from, err := parseEarningsDate(c.Query("from"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from_date_invalid"})
+	}
+	to, err := parseEarningsDate(c.Query("to"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to_date_invalid"})
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	ledger, err := services.BuildGuideLedger(config.DB, guide.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "earnings_get_failed"})
+	}
+	entries := services.FilterLedger(ledger, from, to)
+
+	// แยกตาม booking (ยอดสะสมของ booking ที่มีรายการในช่วงนี้)
+	byBookingEntries := map[uint][]services.LedgerEntry{}
+	for _, e := range ledger {
+		byBookingEntries[e.TripBookingID] = append(byBookingEntries[e.TripBookingID], e)
+	}
+	inRange := map[uint]bool{}
+	for _, e := range entries {
+		inRange[e.TripBookingID] = true
+	}
+	byBooking := make([]fiber.Map, 0, len(inRange))
+	for bookingID := range inRange {
+		byBooking = append(byBooking, fiber.Map{
+			"trip_booking_id": bookingID,
+			"totals":          services.SummarizeLedger(byBookingEntries[bookingID]),
+		})
+	}
+	sort.Slice(byBooking, func(i, j int) bool {
+		return byBooking[i]["trip_booking_id"].(uint) > byBooking[j]["trip_booking_id"].(uint)
+	})
+
+	// แยกตามเดือน (ยอดเคลื่อนไหวในเดือนนั้น)
+	byMonthEntries := map[string][]services.LedgerEntry{}
+	for _, e := range entries {
+		month := e.Date.In(time.Local).Format("2006-01")
+		byMonthEntries[month] = append(byMonthEntries[month], e)
+	}
+	byPeriod := make([]fiber.Map, 0, len(byMonthEntries))
+	for month, monthEntries := range byMonthEntries {
+		byPeriod = append(byPeriod, fiber.Map{
+			"period": month,
+			"totals": services.SummarizeLedger(monthEntries),
+		})
+	}
+	sort.Slice(byPeriod, func(i, j int) bool {
+		return byPeriod[i]["period"].(string) > byPeriod[j]["period"].(string)
+	})
+
+	return c.JSON(fiber.Map{
+		"guide_id":      guide.ID,
+		"totals":        services.SummarizeLedger(ledger),
+		"period_totals": services.SummarizeLedger(entries),
+		"by_booking":    byBooking,
+		"by_period":     byPeriod,
+		"entries":       entries,
+	})
+}
+
+// GetMyEarningsStatement - statement รายเดือน (:month = YYYY-MM) ?format=json|csv|pdf
+func GetMyEarningsStatement(c *fiber.Ctx) error {
+	guide, resp := currentGuide(c)
+	if guide == nil {
+		return resp
+	}
+
+	month, err := time.ParseInLocation("2006-01", c.Params("month"), time.Local)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "month_invalid"})
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "csv" && format != "pdf" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "report_format_invalid"})
+	}
+
+	ledger, err := services.BuildGuideLedger(config.DB, guide.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "earnings_get_failed"})
+	}
+	statement := services.BuildEarningsStatement(guide.ID, ledger, month)
+
+	filename := fmt.Sprintf("earnings-%s", statement.Month)
+	switch format {
+	case "csv":
+		body, err := statement.CSV()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "statement_build_failed"})
+		}
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`.csv"`)
+		return c.Send(body)
+	case "pdf":
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`.pdf"`)
+		return c.Send(statement.PDF())
+	}
+
+	return c.JSON(statement)
+}
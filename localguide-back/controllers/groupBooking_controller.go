@@ -0,0 +1,938 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+func newInviteToken() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// loadOrganizerBooking - โหลด booking และตรวจสอบว่าผู้เรียกเป็นผู้จอง
+func loadOrganizerBooking(c *fiber.Ctx, db *gorm.DB) (*models.TripBooking, error) {
+	bookingID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || bookingID <= 0 {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "booking_id_invalid",
+		})
+	}
+
+	var booking models.TripBooking
+	if err := db.First(&booking, bookingID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "booking_not_found"})
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "booking_get_failed"})
+	}
+
+	if booking.UserID != c.Locals("user_id").(uint) {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "organiser_only",
+		})
+	}
+
+	return &booking, nil
+}
+
+// ensureOrganizerParticipant - สร้างแถวผู้เข้าร่วมของผู้จองถ้ายังไม่มี
+func ensureOrganizerParticipant(tx *gorm.DB, booking *models.TripBooking) (*models.TripBookingParticipant, error) {
+	var organizer models.TripBookingParticipant
+	err := tx.Where("trip_booking_id = ? AND is_organizer = ?", booking.ID, true).First(&organizer).Error
+	if err == nil {
+		return &organizer, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	var user models.User
+	if err := tx.First(&user, booking.UserID).Error; err != nil {
+		return nil, err
+	}
+	var authUser models.AuthUser
+	if err := tx.First(&authUser, user.AuthUserID).Error; err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	userID := booking.UserID
+	organizer = models.TripBookingParticipant{
+		TripBookingID: booking.ID,
+		UserID:        &userID,
+		Email:         authUser.Email,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Phone:         user.Phone,
+		IsOrganizer:   true,
+		InviteToken:   newInviteToken(),
+		InviteStatus:  "accepted",
+		InvitedAt:     now,
+		RespondedAt:   &now,
+		ShareStatus:   "none",
+	}
+	if err := tx.Create(&organizer).Error; err != nil {
+		return nil, err
+	}
+	return &organizer, nil
+}
+
+// bookingGroupSize - จำนวนคนสูงสุดตาม TripRequire ของ booking
+func bookingGroupSize(db *gorm.DB, booking *models.TripBooking) int {
+	var offer models.TripOffer
+	if err := db.Select("id", "trip_require_id").First(&offer, booking.TripOfferID).Error; err != nil {
+		return 1
+	}
+	var tripRequire models.TripRequire
+	if err := db.Select("id", "group_size").First(&tripRequire, offer.TripRequireID).Error; err != nil || tripRequire.GroupSize < 1 {
+		return 1
+	}
+	return tripRequire.GroupSize
+}
+
+// canViewBookingParticipants - ผู้จอง ไกด์ และผู้ร่วมเดินทางที่รับคำเชิญแล้ว
+func canViewBookingParticipants(db *gorm.DB, booking *models.TripBooking, userID uint) string {
+	if role := bookingParticipantRole(db, booking, userID); role != "" {
+		return role
+	}
+	var count int64
+	db.Model(&models.TripBookingParticipant{}).
+		Where("trip_booking_id = ? AND user_id = ? AND invite_status = ?", booking.ID, userID, "accepted").
+		Count(&count)
+	if count > 0 {
+		return "participant"
+	}
+	return ""
+}
+
+// sendBookingInviteEmail - เข้าคิวอีเมลคำเชิญ คืน false ถ้าไม่ได้ตั้งค่า SMTP (เช่นตอน dev/test)
+func sendBookingInviteEmail(email, token string, booking *models.TripBooking) (bool, error) {
+	inviteURL := services.FrontendURL("/booking-invites/" + token)
+	body := fmt.Sprintf(`
+        <h2>คุณได้รับคำเชิญร่วมทริป</h2>
+        <p>คุณได้รับเชิญให้ร่วมเดินทางใน booking #%d วันที่ %s</p>
+        <a href="%s">ดูคำเชิญ</a>
+    `, booking.ID, booking.StartDate.Format("2006-01-02"), inviteURL)
+
+	return services.NewNotificationService(config.DB).QueueEmail(email, "คำเชิญร่วมทริป - LocalGuide", body)
+}
+
+// refreshSplitPayment - ถ้าทุกส่วนแบ่งจ่ายครบ (หรือผู้จองจ่ายแทนแล้ว) ให้ payment และ booking เป็น paid
+func refreshSplitPayment(tx *gorm.DB, bookingID uint, stripeStatus string) error {
+	var pending int64
+	if err := tx.Model(&models.TripBookingParticipant{}).
+		Where("trip_booking_id = ? AND share_status = ?", bookingID, "pending").
+		Count(&pending).Error; err != nil {
+		return err
+	}
+	if pending > 0 {
+		return nil
+	}
+
+	var payment models.TripPayment
+	if err := tx.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
+		return fmt.Errorf("payment record not found: %w", err)
+	}
+	if payment.Status == "paid" {
+		return nil
+	}
+
+	now := time.Now()
+	payment.Status = "paid"
+	payment.StripeStatus = stripeStatus
+	payment.PaidAt = &now
+	if err := tx.Save(&payment).Error; err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if err := tx.Model(&models.TripBooking{}).Where("id = ?", bookingID).Updates(map[string]interface{}{
+		"payment_status": "paid",
+		"status":         "paid",
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update booking: %w", err)
+	}
+
+	var booking models.TripBooking
+	if err := tx.First(&booking, bookingID).Error; err != nil {
+		return fmt.Errorf("booking not found: %w", err)
+	}
+	if _, err := services.IssueInvoices(tx, booking.ID, now); err != nil {
+		return err
+	}
+	return notifyBookingPaid(tx, &booking, payment.TotalAmount)
+}
+
+// SharePaymentIntents - ใช้ปรับยอด/ยกเลิก PaymentIntent ที่ผู้จองสร้างไว้จ่ายแทน (เปลี่ยนเป็นตัวจำลองได้ตอนทดสอบ)
+var SharePaymentIntents services.PaymentIntentAdjuster = services.NewStripeService()
+
+// remainingShareAmount - ยอดส่วนแบ่งที่ยังค้างจ่ายของ booking
+func remainingShareAmount(tx *gorm.DB, bookingID uint) (float64, error) {
+	var remaining float64
+	if err := tx.Model(&models.TripBookingParticipant{}).
+		Where("trip_booking_id = ? AND share_status = ?", bookingID, "pending").
+		Select("COALESCE(SUM(share_amount), 0)").
+		Scan(&remaining).Error; err != nil {
+		return 0, fmt.Errorf("failed to sum remaining shares: %w", err)
+	}
+	return roundAmount(remaining), nil
+}
+
+// settleParticipantShare - บันทึกว่าส่วนแบ่งนี้จ่ายแล้ว (เรียกได้ทั้งจาก confirm และ webhook ซ้ำได้)
+// paidCents คือยอดของ PaymentIntent (0 = ไม่ทราบ ใช้ส่วนแบ่ง หรือยอดค้างทั้งหมดกรณีจ่ายแทน)
+func settleParticipantShare(tx *gorm.DB, participant *models.TripBookingParticipant, stripeStatus string, paidCents int64) error {
+	if participant.ShareStatus == "paid" {
+		return nil
+	}
+
+	paid := participant.ShareAmount
+	var overpaid float64
+	if participant.CoversRemainder {
+		// ยอดจ่ายแทนถูกกำหนดตอนสร้าง PaymentIntent ถ้ามีคนจ่ายส่วนของตัวเองไประหว่างนั้น คืนส่วนที่เกินให้ผู้จอง
+		remaining, err := remainingShareAmount(tx, participant.TripBookingID)
+		if err != nil {
+			return err
+		}
+		paid = remaining
+		if paidCents > 0 && float64(paidCents)/100 > remaining {
+			overpaid = roundAmount(float64(paidCents)/100 - remaining)
+		}
+	} else if paidCents > 0 {
+		paid = float64(paidCents) / 100
+	}
+
+	now := time.Now()
+	participant.ShareStatus = "paid"
+	participant.PaidAmount = paid
+	participant.PaidAt = &now
+	if err := tx.Save(participant).Error; err != nil {
+		return fmt.Errorf("failed to update participant share: %w", err)
+	}
+
+	// ผู้จองจ่ายส่วนที่เหลือแทน -> ส่วนแบ่งที่ยังค้างของคนอื่นถือว่าจ่ายแล้ว
+	if participant.CoversRemainder {
+		if err := tx.Model(&models.TripBookingParticipant{}).
+			Where("trip_booking_id = ? AND id <> ? AND share_status = ?", participant.TripBookingID, participant.ID, "pending").
+			Update("share_status", "covered").Error; err != nil {
+			return fmt.Errorf("failed to cover remaining shares: %w", err)
+		}
+	} else if err := adjustCoverPayment(tx, participant.TripBookingID); err != nil {
+		return err
+	}
+
+	if overpaid > 0 {
+		var payment models.TripPayment
+		if err := tx.Where("trip_booking_id = ?", participant.TripBookingID).First(&payment).Error; err != nil {
+			return fmt.Errorf("payment record not found: %w", err)
+		}
+		var recipientID uint
+		if participant.UserID != nil {
+			recipientID = *participant.UserID
+		}
+		release := models.PaymentRelease{
+			TripPaymentID: payment.ID,
+			Amount:        overpaid,
+			RecipientType: "user",
+			RecipientID:   recipientID,
+			Reason:        "cover_remainder_overpaid",
+			ScheduledAt:   now,
+			Notes:         "Remainder was paid by another participant before the covering payment completed",
+		}
+		if _, err := services.QueueOverpaymentRefund(tx, &payment, participant, &release); err != nil {
+			return err
+		}
+	}
+
+	return refreshSplitPayment(tx, participant.TripBookingID, stripeStatus)
+}
+
+// adjustCoverPayment - มีคนจ่ายส่วนแบ่งของตัวเองขณะที่ผู้จองสร้าง PaymentIntent จ่ายแทนค้างไว้
+// ปรับยอด PaymentIntent นั้นเป็นยอดค้างใหม่ (ไม่เหลือยอดค้าง = ยกเลิก) Stripe ปรับไม่ได้ (เช่น กำลังตัดเงิน) ให้คืนส่วนเกินตอน settle แทน
+func adjustCoverPayment(tx *gorm.DB, bookingID uint) error {
+	var cover models.TripBookingParticipant
+	err := tx.Where("trip_booking_id = ? AND covers_remainder = ? AND share_status <> ? AND stripe_payment_intent_id <> ''", bookingID, true, "paid").
+		First(&cover).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get covering payment: %w", err)
+	}
+
+	remaining, err := remainingShareAmount(tx, bookingID)
+	if err != nil {
+		return err
+	}
+	if remaining > 0 {
+		if err := SharePaymentIntents.UpdatePaymentIntentAmount(cover.StripePaymentIntentID, int64(math.Round(remaining*100))); err != nil {
+			log.Printf("failed to adjust covering payment of booking #%d: %v", bookingID, err)
+		}
+		return nil
+	}
+
+	if err := SharePaymentIntents.CancelPaymentIntent(cover.StripePaymentIntentID); err != nil {
+		log.Printf("failed to cancel covering payment of booking #%d: %v", bookingID, err)
+		return nil
+	}
+	return tx.Model(&cover).Updates(map[string]interface{}{
+		"covers_remainder":         false,
+		"stripe_payment_intent_id": "",
+		"stripe_client_secret":     "",
+	}).Error
+}
+
+var (
+	errOrganizerSharePaid     = errors.New("organiser_share_already_paid")
+	errSharePaymentInProgress = errors.New("share_payment_in_progress")
+)
+
+// reassignShareToOrganizer - ย้ายส่วนแบ่งที่ยังไม่จ่ายของผู้ร่วมเดินทางไปให้ผู้จอง (ตอนปฏิเสธคำเชิญหรือถูกนำออก)
+// PaymentIntent ของส่วนแบ่งเดิมและของผู้จอง (ที่ไม่ใช่จ่ายแทน) ต้องยกเลิกได้ก่อน ไม่งั้นอาจถูกตัดเงินยอดเก่า
+// ผู้จองที่จ่ายส่วนของตัวเองไปแล้วรับเพิ่มไม่ได้ (PaymentIntent เดิมใช้คืนเงินตาม PaidAmount)
+func reassignShareToOrganizer(tx *gorm.DB, participant *models.TripBookingParticipant) error {
+	if participant.ShareStatus != "pending" {
+		return nil
+	}
+
+	var organizer models.TripBookingParticipant
+	if err := tx.Where("trip_booking_id = ? AND is_organizer = ?", participant.TripBookingID, true).First(&organizer).Error; err != nil {
+		return fmt.Errorf("failed to get organiser participant: %w", err)
+	}
+	if organizer.ShareStatus != "pending" && organizer.ShareStatus != "none" {
+		return errOrganizerSharePaid
+	}
+
+	if participant.StripePaymentIntentID != "" {
+		if err := SharePaymentIntents.CancelPaymentIntent(participant.StripePaymentIntentID); err != nil {
+			log.Printf("failed to cancel share payment of participant #%d: %v", participant.ID, err)
+			return errSharePaymentInProgress
+		}
+	}
+
+	organizerUpdates := map[string]interface{}{
+		"share_amount": roundAmount(organizer.ShareAmount + participant.ShareAmount),
+		"share_status": "pending",
+	}
+	// PaymentIntent จ่ายแทนใช้ยอดค้างรวมซึ่งไม่เปลี่ยน ส่วน PaymentIntent ส่วนของผู้จองเองต้องสร้างใหม่ตามยอดใหม่
+	if organizer.StripePaymentIntentID != "" && !organizer.CoversRemainder {
+		if err := SharePaymentIntents.CancelPaymentIntent(organizer.StripePaymentIntentID); err != nil {
+			log.Printf("failed to cancel share payment of organiser #%d: %v", organizer.ID, err)
+			return errSharePaymentInProgress
+		}
+		organizerUpdates["stripe_payment_intent_id"] = ""
+		organizerUpdates["stripe_client_secret"] = ""
+	}
+	if err := tx.Model(&organizer).Updates(organizerUpdates).Error; err != nil {
+		return fmt.Errorf("failed to update organiser share: %w", err)
+	}
+
+	participant.ShareAmount = 0
+	participant.ShareStatus = "none"
+	participant.StripePaymentIntentID = ""
+	participant.StripeClientSecret = ""
+	return nil
+}
+
+// shareReassignErrorStatus / shareReassignErrorCode - แปลง error จาก reassignShareToOrganizer เป็น response
+func shareReassignErrorStatus(err error) int {
+	if errors.Is(err, errOrganizerSharePaid) || errors.Is(err, errSharePaymentInProgress) {
+		return fiber.StatusConflict
+	}
+	return fiber.StatusInternalServerError
+}
+
+func shareReassignErrorCode(err error) string {
+	if errors.Is(err, errOrganizerSharePaid) || errors.Is(err, errSharePaymentInProgress) {
+		return err.Error()
+	}
+	return "share_reassign_failed"
+}
+
+// InviteBookingParticipant - ผู้จองเชิญผู้ร่วมเดินทางทางอีเมล
+func InviteBookingParticipant(c *fiber.Ctx) error {
+	var req struct {
+		Email     string `json:"email"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Phone     string `json:"phone"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Email == "" || !strings.Contains(req.Email, "@") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "email_required"})
+	}
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	booking, resp := loadOrganizerBooking(c, tx)
+	if booking == nil {
+		return resp
+	}
+
+	if booking.Status != "pending_payment" && booking.Status != "paid" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "participant_invite_closed",
+		})
+	}
+
+	organizer, err := ensureOrganizerParticipant(tx, booking)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "organiser_participant_create_failed"})
+	}
+	if organizer.Email == req.Email {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "already_participant"})
+	}
+
+	var existing int64
+	tx.Model(&models.TripBookingParticipant{}).
+		Where("trip_booking_id = ? AND email = ? AND invite_status <> ?", booking.ID, req.Email, "declined").
+		Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "email_already_invited"})
+	}
+
+	var active int64
+	tx.Model(&models.TripBookingParticipant{}).
+		Where("trip_booking_id = ? AND invite_status <> ?", booking.ID, "declined").
+		Count(&active)
+	groupSize := bookingGroupSize(tx, booking)
+	if int(active) >= groupSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "group_size_limit_reached",
+			"group_size": groupSize,
+		})
+	}
+
+	participant := models.TripBookingParticipant{
+		TripBookingID: booking.ID,
+		Email:         req.Email,
+		FirstName:     req.FirstName,
+		LastName:      req.LastName,
+		Phone:         req.Phone,
+		InviteToken:   newInviteToken(),
+		InviteStatus:  "invited",
+		InvitedAt:     time.Now(),
+		ShareStatus:   "none",
+	}
+	if err := tx.Create(&participant).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "participant_invite_failed"})
+	}
+
+	// ถ้าอีเมลนี้มีบัญชีในระบบ แจ้งเตือนในแอปด้วย
+	var authUser models.AuthUser
+	if err := tx.Where("email = ?", req.Email).First(&authUser).Error; err == nil {
+		var invitee models.User
+		if err := tx.Where("auth_user_id = ?", authUser.ID).First(&invitee).Error; err == nil {
+			data := map[string]interface{}{"BookingID": booking.ID}
+			if _, err := services.NewNotificationService(tx).Send(invitee.ID, "booking_invite", data, "trip_booking", booking.ID); err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "participant_notify_failed"})
+			}
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "participant_invite_failed"})
+	}
+
+	// เข้าคิวอีเมลหลัง commit ไม่สำเร็จไม่ถือว่าเชิญไม่สำเร็จ
+	emailSent, err := sendBookingInviteEmail(req.Email, participant.InviteToken, booking)
+	if err != nil {
+		log.Printf("failed to queue invite email for booking #%d: %v", booking.ID, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":     "Participant invited",
+		"participant": participant,
+		"email_sent":  emailSent,
+	})
+}
+
+// GetBookingParticipants - รายชื่อผู้ร่วมเดินทาง (ผู้จองและไกด์เห็นข้อมูลติดต่อ)
+func GetBookingParticipants(c *fiber.Ctx) error {
+	bookingID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || bookingID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "booking_id_invalid"})
+	}
+
+	var booking models.TripBooking
+	if err := config.DB.First(&booking, bookingID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "booking_not_found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "booking_get_failed"})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	role := canViewBookingParticipants(config.DB, &booking, userID)
+	if role == "" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "not_participant"})
+	}
+
+	var participants []models.TripBookingParticipant
+	if err := config.DB.Where("trip_booking_id = ?", booking.ID).Order("is_organizer DESC, id ASC").Find(&participants).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "participants_get_failed"})
+	}
+
+	// ผู้ร่วมเดินทางด้วยกันไม่เห็นข้อมูลติดต่อของคนอื่น
+	if role == "participant" {
+		for i := range participants {
+			if participants[i].UserID == nil || *participants[i].UserID != userID {
+				participants[i].Email = ""
+				participants[i].Phone = ""
+			}
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"participants":  participants,
+		"group_size":    bookingGroupSize(config.DB, &booking),
+		"split_payment": booking.SplitPayment,
+	})
+}
+
+// RemoveBookingParticipant - ผู้จองยกเลิกคำเชิญ ส่วนแบ่งที่ยังไม่จ่ายย้ายไปให้ผู้จอง (ลบไม่ได้ถ้าจ่ายแล้ว)
+func RemoveBookingParticipant(c *fiber.Ctx) error {
+	booking, resp := loadOrganizerBooking(c, config.DB)
+	if booking == nil {
+		return resp
+	}
+
+	participantID, err := strconv.Atoi(c.Params("participantId"))
+	if err != nil || participantID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "participant_id_invalid"})
+	}
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	var participant models.TripBookingParticipant
+	if err := tx.Where("id = ? AND trip_booking_id = ?", participantID, booking.ID).First(&participant).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "participant_not_found"})
+	}
+
+	if participant.IsOrganizer {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "organiser_not_removable"})
+	}
+	if participant.ShareStatus != "none" && participant.ShareStatus != "pending" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "participant_share_exists"})
+	}
+
+	if err := reassignShareToOrganizer(tx, &participant); err != nil {
+		return c.Status(shareReassignErrorStatus(err)).JSON(fiber.Map{"error": shareReassignErrorCode(err)})
+	}
+
+	if err := tx.Delete(&participant).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "participant_remove_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "participant_remove_failed"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Participant removed"})
+}
+
+func respondBookingInvite(c *fiber.Ctx, accept bool) error {
+	token := c.Params("token")
+	userID := c.Locals("user_id").(uint)
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	var participant models.TripBookingParticipant
+	if err := tx.Where("invite_token = ?", token).First(&participant).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "invitation_not_found"})
+	}
+	if participant.InviteStatus != "invited" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invitation_already_answered"})
+	}
+
+	var booking models.TripBooking
+	if err := tx.First(&booking, participant.TripBookingID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "booking_not_found"})
+	}
+	if booking.UserID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "already_organiser"})
+	}
+
+	// คำเชิญผูกกับอีเมลที่ถูกเชิญ คนอื่นที่ได้ลิงก์ไปใช้ token แทนไม่ได้
+	var user models.User
+	if err := tx.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_info_get_failed"})
+	}
+	var authUser models.AuthUser
+	if err := tx.First(&authUser, user.AuthUserID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_email_get_failed"})
+	}
+	if strings.ToLower(authUser.Email) != participant.Email {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "invitation_email_mismatch"})
+	}
+
+	now := time.Now()
+	participant.RespondedAt = &now
+	if accept {
+		participant.InviteStatus = "accepted"
+		participant.UserID = &userID
+		if participant.FirstName == "" {
+			participant.FirstName = user.FirstName
+			participant.LastName = user.LastName
+			if participant.Phone == "" {
+				participant.Phone = user.Phone
+			}
+		}
+	} else {
+		// ปฏิเสธแล้วส่วนแบ่งที่ยังไม่จ่ายให้ผู้จองรับผิดชอบ
+		if err := reassignShareToOrganizer(tx, &participant); err != nil {
+			return c.Status(shareReassignErrorStatus(err)).JSON(fiber.Map{"error": shareReassignErrorCode(err)})
+		}
+		participant.InviteStatus = "declined"
+	}
+
+	if err := tx.Save(&participant).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "invitation_update_failed"})
+	}
+
+	notifType := "booking_invite_accepted"
+	if !accept {
+		notifType = "booking_invite_declined"
+	}
+	data := map[string]interface{}{"BookingID": booking.ID, "Email": participant.Email}
+	if _, err := services.NewNotificationService(tx).Send(booking.UserID, notifType, data, "trip_booking", booking.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "organiser_notify_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "invitation_update_failed"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":     "Invitation " + participant.InviteStatus,
+		"participant": participant,
+	})
+}
+
+// GetMyBookingInvites - คำเชิญที่ส่งมาที่อีเมลของผู้ใช้ที่ login อยู่และยังไม่ได้ตอบ
+func GetMyBookingInvites(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_info_get_failed"})
+	}
+	var authUser models.AuthUser
+	if err := config.DB.First(&authUser, user.AuthUserID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_email_get_failed"})
+	}
+
+	var participants []models.TripBookingParticipant
+	if err := config.DB.Where("email = ? AND invite_status = ?", strings.ToLower(authUser.Email), "invited").
+		Order("invited_at DESC").Find(&participants).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "invitations_get_failed"})
+	}
+
+	invites := make([]fiber.Map, 0, len(participants))
+	for _, p := range participants {
+		invites = append(invites, fiber.Map{
+			"token":           p.InviteToken,
+			"trip_booking_id": p.TripBookingID,
+			"invited_at":      p.InvitedAt,
+			"share_amount":    p.ShareAmount,
+		})
+	}
+
+	return c.JSON(fiber.Map{"invites": invites})
+}
+
+// AcceptBookingInvite - ผู้ได้รับเชิญยอมรับคำเชิญ (ผูกกับบัญชีที่ login อยู่)
+func AcceptBookingInvite(c *fiber.Ctx) error {
+	return respondBookingInvite(c, true)
+}
+
+// DeclineBookingInvite - ผู้ได้รับเชิญปฏิเสธคำเชิญ
+func DeclineBookingInvite(c *fiber.Ctx) error {
+	return respondBookingInvite(c, false)
+}
+
+// SetupSplitPayment - ผู้จองแบ่ง TotalAmount ให้ผู้ร่วมเดินทางแต่ละคนจ่ายเอง
+// ไม่ส่ง shares มา = แบ่งเท่ากันทุกคน (เศษให้ผู้จอง)
+func SetupSplitPayment(c *fiber.Ctx) error {
+	var req struct {
+		Shares []struct {
+			ParticipantID uint    `json:"participant_id"`
+			Amount        float64 `json:"amount"`
+		} `json:"shares"`
+	}
+	_ = c.BodyParser(&req)
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	booking, resp := loadOrganizerBooking(c, tx)
+	if booking == nil {
+		return resp
+	}
+
+	if booking.Status != "pending_payment" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "split_payment_locked"})
+	}
+	if booking.SplitPayment {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "split_payment_exists"})
+	}
+
+	var existingPayments int64
+	tx.Model(&models.TripPayment{}).Where("trip_booking_id = ?", booking.ID).Count(&existingPayments)
+	if existingPayments > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment_already_started"})
+	}
+
+	organizer, err := ensureOrganizerParticipant(tx, booking)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "organiser_participant_create_failed"})
+	}
+
+	var participants []models.TripBookingParticipant
+	if err := tx.Where("trip_booking_id = ? AND invite_status <> ?", booking.ID, "declined").Order("id ASC").Find(&participants).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "participants_get_failed"})
+	}
+	if len(participants) < 2 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "split_requires_co_traveller"})
+	}
+
+	shares := make(map[uint]float64)
+	if len(req.Shares) == 0 {
+		each := math.Floor(booking.TotalAmount/float64(len(participants))*100) / 100
+		for _, p := range participants {
+			shares[p.ID] = each
+		}
+		shares[organizer.ID] = roundAmount(booking.TotalAmount - each*float64(len(participants)-1))
+	} else {
+		valid := make(map[uint]bool)
+		for _, p := range participants {
+			valid[p.ID] = true
+		}
+		sum := 0.0
+		for _, s := range req.Shares {
+			if !valid[s.ParticipantID] {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "share_participant_unknown", "participant_id": s.ParticipantID})
+			}
+			if s.Amount < 0 {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "share_amount_negative"})
+			}
+			shares[s.ParticipantID] = roundAmount(s.Amount)
+			sum += roundAmount(s.Amount)
+		}
+		if math.Abs(sum-booking.TotalAmount) >= 0.01 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":        "shares_total_mismatch",
+				"total_amount": booking.TotalAmount,
+				"shares_total": roundAmount(sum),
+			})
+		}
+	}
+
+	for i := range participants {
+		amount := shares[participants[i].ID]
+		status := "none"
+		if amount > 0 {
+			status = "pending"
+		}
+		if err := tx.Model(&participants[i]).Updates(map[string]interface{}{
+			"share_amount": amount,
+			"share_status": status,
+		}).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "shares_save_failed"})
+		}
+	}
+
+	// payment รวมของ booking ใช้สำหรับแบ่งจ่ายให้ไกด์ตามขั้นตอนเหมือนเดิม
+	// ไม่มี PaymentIntent ของตัวเอง (คืนเงินและ reconcile ผ่าน PaymentIntent ของผู้ร่วมเดินทาง)
+	now := time.Now()
+	paymentNumber, err := services.NextDocumentNumber(tx, "PAY", now)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "payment_record_create_failed"})
+	}
+	firstPayment := roundAmount(booking.TotalAmount * 0.5)
+	payment := models.TripPayment{
+		TripBookingID: booking.ID,
+		PaymentNumber: paymentNumber,
+		TransactionID: "GROUP-" + strconv.Itoa(int(booking.ID)),
+		TotalAmount:   booking.TotalAmount,
+		FirstPayment:  firstPayment,
+		SecondPayment: roundAmount(booking.TotalAmount - firstPayment),
+		PaymentMethod: "stripe_split",
+		Status:        "pending",
+	}
+	if err := tx.Create(&payment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "payment_record_create_failed"})
+	}
+
+	if err := tx.Model(booking).Update("split_payment", true).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "booking_update_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "split_payment_setup_failed"})
+	}
+
+	config.DB.Where("trip_booking_id = ? AND invite_status <> ?", booking.ID, "declined").Order("id ASC").Find(&participants)
+
+	return c.JSON(fiber.Map{
+		"message":      "Split payment set up",
+		"payment":      payment,
+		"participants": participants,
+	})
+}
+
+// CreateSharePayment - ผู้ร่วมเดินทางสร้าง PaymentIntent สำหรับส่วนแบ่งของตัวเอง
+// ผู้จองส่ง cover_remainder = true เพื่อจ่ายส่วนที่ยังค้างของทุกคนในครั้งเดียว
+func CreateSharePayment(c *fiber.Ctx) error {
+	bookingID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || bookingID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "booking_id_invalid"})
+	}
+
+	var req struct {
+		CoverRemainder bool `json:"cover_remainder"`
+	}
+	_ = c.BodyParser(&req)
+
+	userID := c.Locals("user_id").(uint)
+
+	var booking models.TripBooking
+	if err := config.DB.First(&booking, bookingID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "booking_not_found"})
+	}
+	if !booking.SplitPayment {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "booking_not_split"})
+	}
+	if booking.PaymentStatus == "paid" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "payment_already_completed"})
+	}
+
+	var participant models.TripBookingParticipant
+	if err := config.DB.Where("trip_booking_id = ? AND user_id = ? AND invite_status = ?", booking.ID, userID, "accepted").First(&participant).Error; err != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "not_participant"})
+	}
+
+	amount := participant.ShareAmount
+	if req.CoverRemainder {
+		if !participant.IsOrganizer {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "organiser_only_cover_shares"})
+		}
+		var remaining float64
+		config.DB.Model(&models.TripBookingParticipant{}).
+			Where("trip_booking_id = ? AND share_status = ?", booking.ID, "pending").
+			Select("COALESCE(SUM(share_amount), 0)").
+			Scan(&remaining)
+		amount = roundAmount(remaining)
+	} else if participant.ShareStatus != "pending" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no_outstanding_share"})
+	}
+
+	if amount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "nothing_to_pay"})
+	}
+
+	var authUser models.AuthUser
+	var user models.User
+	if err := config.DB.First(&user, userID).Error; err == nil {
+		config.DB.First(&authUser, user.AuthUserID)
+	}
+
+	stripeService := services.NewStripeService()
+	paymentIntent, err := stripeService.CreateSharePaymentIntent(&booking, &participant, amount, authUser.Email)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "payment_intent_create_failed",
+			"details": err.Error(),
+		})
+	}
+
+	if err := config.DB.Model(&participant).Updates(map[string]interface{}{
+		"stripe_payment_intent_id": paymentIntent.ID,
+		"stripe_client_secret":     paymentIntent.ClientSecret,
+		"covers_remainder":         req.CoverRemainder,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "payment_intent_save_failed"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"participant":       participant,
+		"client_secret":     paymentIntent.ClientSecret,
+		"payment_intent_id": paymentIntent.ID,
+		"amount":            amount,
+		"message":           "Payment intent created. Complete payment on client side.",
+	})
+}
+
+// ConfirmSharePayment - ยืนยันการจ่ายส่วนแบ่งหลังจาก Stripe ชำระเงินสำเร็จ
+func ConfirmSharePayment(c *fiber.Ctx) error {
+	bookingID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || bookingID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "booking_id_invalid"})
+	}
+
+	var req struct {
+		PaymentIntentID string `json:"payment_intent_id"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.PaymentIntentID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_data"})
+	}
+
+	userID := c.Locals("user_id").(uint)
+
+	var participant models.TripBookingParticipant
+	if err := config.DB.Where("trip_booking_id = ? AND user_id = ? AND stripe_payment_intent_id = ?", bookingID, userID, req.PaymentIntentID).First(&participant).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "share_payment_not_found"})
+	}
+
+	stripeService := services.NewStripeService()
+	paymentIntent, err := stripeService.ConfirmPayment(req.PaymentIntentID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "payment_not_completed",
+			"details": err.Error(),
+		})
+	}
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	if err := settleParticipantShare(tx, &participant, string(paymentIntent.Status), paymentIntent.Amount); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "share_payment_record_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "share_payment_record_failed"})
+	}
+
+	var booking models.TripBooking
+	config.DB.First(&booking, bookingID)
+
+	return c.JSON(fiber.Map{
+		"message":     "Share payment confirmed",
+		"participant": participant,
+		"booking":     booking,
+	})
+}
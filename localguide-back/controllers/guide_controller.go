@@ -4,28 +4,77 @@ import (
 	"fmt"
 	"localguide-back/config"
 	"localguide-back/models"
+	"localguide-back/services"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm/clause"
 )
 
+// GetGuides - รายชื่อไกด์ ?sort=score (ค่าเริ่มต้น, คะแนนถ่วง Bayesian) | rating | reviews | newest, ?province_id=
+// ?lat=&lng=&within_km= เฉพาะไกด์ที่ดูแลสถานที่ท่องเที่ยวในรัศมี within_km กม. จากพิกัด
 func GetGuides(c *fiber.Ctx) error {
 	var guides []models.Guide
 
-	result := config.DB.
+	// ไกด์ที่ยังไม่มีรีวิวได้คะแนนเท่าค่าเฉลี่ยตั้งต้น
+	mean, _ := services.RatingPrior()
+	query := config.DB.Select("guides.*").
+		Joins("LEFT JOIN guide_rating_summaries rs ON rs.guide_id = guides.id")
+	switch c.Query("sort", "score") {
+	case "score":
+		query = query.Order(clause.OrderBy{Expression: clause.Expr{SQL: "COALESCE(rs.bayesian_score, ?) DESC, COALESCE(rs.review_count, 0) DESC, guides.id ASC", Vars: []interface{}{mean}}})
+	case "rating":
+		query = query.Order("COALESCE(rs.average_rating, 0) DESC, COALESCE(rs.review_count, 0) DESC, guides.id ASC")
+	case "reviews":
+		query = query.Order("COALESCE(rs.review_count, 0) DESC, guides.id ASC")
+	case "newest":
+		query = query.Order("guides.created_at DESC")
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "guide_sort_invalid",
+		})
+	}
+	if provinceID := c.QueryInt("province_id", 0); provinceID > 0 {
+		query = query.Where("guides.province_id = ?", provinceID)
+	}
+	if c.Query("within_km") != "" {
+		lat, lng, radiusKm, msg := parseNearQuery(c, "lat", "lng", "within_km")
+		if msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		nearby, err := attractionsWithin(config.DB.Select("id", "latitude", "longitude"), lat, lng, radiusKm)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "guides_get_failed",
+			})
+		}
+		if len(nearby) == 0 {
+			return c.JSON(fiber.Map{"guides": []models.Guide{}})
+		}
+		attractionIDs := make([]uint, len(nearby))
+		for i, a := range nearby {
+			attractionIDs[i] = a.ID
+		}
+		query = query.Where("guides.id IN (?)", config.DB.Table("guide_attractions").
+			Select("guide_id").Where("tourist_attraction_id IN ?", attractionIDs))
+	}
+
+	result := query.
 		Preload("User").
 		Preload("Province").
 		Preload("Language").
 		Preload("TouristAttraction").
 		Preload("Certification").
+		Preload("RatingSummary").
 		Find(&guides)
 
 	if result.Error != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve guides",
+			"error": "guides_get_failed",
 		})
 	}
 
+	localizeContent(c, &guides)
 	return c.JSON(fiber.Map{
 		"guides": guides,
 	})
@@ -41,14 +90,16 @@ func GetGuideByID(c *fiber.Ctx) error {
 		Preload("Province").
 		Preload("Language").
 		Preload("TouristAttraction").
+		Preload("RatingSummary").
 		First(&guide, id)
 
 	if result.Error != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Guide not found",
+			"error": "guide_not_found",
 		})
 	}
 
+	localizeContent(c, &guide)
 	return c.JSON(fiber.Map{
 		"guide": guide,
 	})
@@ -60,7 +111,7 @@ func CreateGuide(c *fiber.Ctx) error {
     userID := c.Locals("user_id")
     if userID == nil {
         return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-            "error": "Unauthorized",
+            "error": "unauthorized",
         })
     }
 
@@ -71,11 +122,12 @@ func CreateGuide(c *fiber.Ctx) error {
         LanguageIDs         []uint   `json:"languageIds"`
         AttractionIDs       []uint   `json:"attractionIds"`
         CertificationNumber string   `json:"certificationNumber"`
+        LicenceDocumentURL  string   `json:"licenceDocumentUrl"` // ไม่บังคับ อัปโหลดด้วย purpose licence_document
     }
 
     if err := c.BodyParser(&req); err != nil {
         return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Invalid request body",
+            "error": "invalid_request_body",
         })
     }
 
@@ -83,16 +135,26 @@ func CreateGuide(c *fiber.Ctx) error {
     if req.Bio == "" || req.Description == "" || 
        req.ProvinceID == 0 || len(req.LanguageIDs) == 0 || req.CertificationNumber == "" {
         return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "Missing required fields (bio, description, provinceId, languageIds, certificationNumber)",
+            "error": "guide_fields_required",
         })
     }
 
+    // สำเนาใบอนุญาตต้องเป็นไฟล์ที่ผู้สมัครอัปโหลดเอง
+    if req.LicenceDocumentURL != "" {
+        upload, err := uploadService().FindByURL(req.LicenceDocumentURL)
+        if err != nil || upload.OwnerID != userID.(uint) || upload.Purpose != "licence_document" {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+                "error": "licence_document_invalid",
+            })
+        }
+    }
+
     // ตรวจสอบว่ามีคำขอที่รออยู่แล้วหรือไม่
     var existingVerification models.GuideVertification
     if err := config.DB.Where("user_id = ? AND status = ?", userID, "pending").
         First(&existingVerification).Error; err == nil {
         return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "You already have a pending guide application",
+            "error": "guide_application_pending",
         })
     }
 
@@ -100,7 +162,7 @@ func CreateGuide(c *fiber.Ctx) error {
     var existingGuide models.Guide
     if err := config.DB.Where("user_id = ?", userID).First(&existingGuide).Error; err == nil {
         return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-            "error": "You are already a guide",
+            "error": "already_guide",
         })
     }
 
@@ -115,6 +177,7 @@ func CreateGuide(c *fiber.Ctx) error {
         Description:         req.Description,
         ProvinceID:          req.ProvinceID,
         CertificationData:   req.CertificationNumber,
+        LicenceDocument:     req.LicenceDocumentURL,
         Status:              "pending",
         VerificationDate:    time.Now(),
         GuideID:             nil,
@@ -124,7 +187,7 @@ func CreateGuide(c *fiber.Ctx) error {
     if err := tx.Create(&verification).Error; err != nil {
         fmt.Printf("Error creating verification: %v\n", err)
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to create guide application",
+            "error": "guide_application_create_failed",
         })
     }
 
@@ -156,7 +219,7 @@ func CreateGuide(c *fiber.Ctx) error {
 
     if err := tx.Commit().Error; err != nil {
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-            "error": "Failed to commit transaction",
+            "error": "transaction_commit_failed",
         })
     }
 
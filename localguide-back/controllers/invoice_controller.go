@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// issueInvoices - ออกใบกำกับภาษี/ใบลดหนี้ที่ยังขาดนอก transaction ออกไม่สำเร็จไม่ทำให้ request ล้มเหลว (GET invoice จะออกให้อีกครั้ง)
+func issueInvoices(bookingID uint) {
+	if _, err := services.IssueInvoices(config.DB, bookingID, time.Now()); err != nil {
+		log.Printf("failed to issue invoices for booking #%d: %v", bookingID, err)
+	}
+}
+
+// GetTripBookingInvoice - ใบกำกับภาษี/ใบเสร็จของ booking (user หรือไกด์ของ booking)
+// ?format=pdf (ค่าเริ่มต้น) หรือ json (รายการเอกสารทั้งหมด), ?number= เลือกใบลดหนี้
+func GetTripBookingInvoice(c *fiber.Ctx) error {
+	booking, _, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+
+	documents, err := services.IssueInvoices(config.DB, booking.ID, time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "invoice_issue_failed"})
+	}
+	if len(documents) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "booking_not_paid"})
+	}
+
+	if c.Query("format", "pdf") == "json" {
+		return c.JSON(fiber.Map{"documents": documents})
+	}
+
+	number := c.Query("number", documents[0].Number)
+	var doc *models.Invoice
+	var related []models.Invoice
+	for i := range documents {
+		if documents[i].Number == number {
+			doc = &documents[i]
+		}
+	}
+	if doc == nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "document_not_found"})
+	}
+	for _, d := range documents {
+		if doc.DocumentType == "invoice" && d.DocumentType == "credit_note" {
+			related = append(related, d)
+		} else if doc.InvoiceID != nil && d.ID == *doc.InvoiceID {
+			related = append(related, d)
+		}
+	}
+
+	c.Set(fiber.HeaderContentType, "application/pdf")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+strings.ToLower(doc.Number)+`.pdf"`)
+	return c.Send(services.RenderInvoicePDF(doc, related))
+}
+
+// UpdateTripBookingBilling - ผู้จองกำหนดชื่อ/เลขผู้เสียภาษี/ที่อยู่ของผู้ซื้อ (เช่น บริษัทที่เบิกจ่าย) ต้องทำก่อนออกใบกำกับภาษี
+func UpdateTripBookingBilling(c *fiber.Ctx) error {
+	booking, role, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+	if role != "user" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "traveller_only_billing"})
+	}
+
+	var req struct {
+		BillingName    string `json:"billing_name"`
+		BillingTaxID   string `json:"billing_tax_id"`
+		BillingAddress string `json:"billing_address"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	req.BillingTaxID = strings.TrimSpace(req.BillingTaxID)
+	if req.BillingTaxID != "" && (req.BillingName == "" || req.BillingAddress == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "company_billing_incomplete"})
+	}
+
+	var issued int64
+	config.DB.Model(&models.Invoice{}).Where("trip_booking_id = ?", booking.ID).Count(&issued)
+	if issued > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "invoice_already_issued"})
+	}
+
+	if err := config.DB.Model(booking).Updates(map[string]interface{}{
+		"billing_name":    strings.TrimSpace(req.BillingName),
+		"billing_tax_id":  req.BillingTaxID,
+		"billing_address": strings.TrimSpace(req.BillingAddress),
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "billing_update_failed"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Billing details updated",
+		"billing": fiber.Map{
+			"billing_name":    booking.BillingName,
+			"billing_tax_id":  booking.BillingTaxID,
+			"billing_address": booking.BillingAddress,
+		},
+	})
+}
@@ -0,0 +1,464 @@
+package controllers
+
+import (
+	"encoding/json"
+	"localguide-back/config"
+	"localguide-back/middleware"
+	"localguide-back/models"
+	"localguide-back/services"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const (
+	maxMessageLength      = 4000
+	maxMessageAttachments = 5
+	defaultMessagePage    = 50
+)
+
+// messageHub - กระจายข้อความใหม่ให้คนที่เปิด WebSocket ของห้องอยู่
+var messageHub = services.NewMessageHub()
+
+type messageAttachmentInput struct {
+	URL      string `json:"url"`
+	FileName string `json:"file_name"`
+}
+
+type messageInput struct {
+	Body        string                   `json:"body"`
+	Attachments []messageAttachmentInput `json:"attachments"`
+}
+
+func (in *messageInput) validate(userID, conversationID uint) string {
+	in.Body = strings.TrimSpace(in.Body)
+	if in.Body == "" && len(in.Attachments) == 0 {
+		return "message_empty"
+	}
+	if len([]rune(in.Body)) > maxMessageLength {
+		return "message_too_long"
+	}
+	if len(in.Attachments) > maxMessageAttachments {
+		return "message_too_many_attachments"
+	}
+	for _, a := range in.Attachments {
+		// ไฟล์แนบต้องอัปโหลดผ่าน /uploads ของระบบก่อน
+		if !isUploadedURL(a.URL) {
+			return "message_attachment_not_uploaded"
+		}
+		if !canAttachURL(a.URL, userID, conversationID, 0) {
+			return "message_attachment_forbidden"
+		}
+	}
+	return ""
+}
+
+// loadConversation - โหลดห้องและตรวจสอบว่าผู้เรียกเป็น user หรือไกด์ของห้อง
+func loadConversation(c *fiber.Ctx) (*models.Conversation, error) {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "conversation_id_invalid"})
+	}
+
+	var conversation models.Conversation
+	if err := config.DB.First(&conversation, id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "conversation_not_found"})
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "conversation_get_failed"})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	if conversation.UserID != userID && conversation.GuideUserID != userID {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "not_in_conversation"})
+	}
+
+	return &conversation, nil
+}
+
+// conversationUnlocked - จ่ายเงินจองแล้วหรือยัง (ก่อนจ่ายจะปิดเบอร์โทร/อีเมลในข้อความ)
+func conversationUnlocked(db *gorm.DB, conversation *models.Conversation) bool {
+	query := db.Model(&models.TripBooking{})
+	if conversation.TripBookingID != nil {
+		query = query.Where("id = ?", *conversation.TripBookingID)
+	} else if conversation.TripOfferID != nil {
+		query = query.Where("trip_offer_id = ?", *conversation.TripOfferID)
+	} else {
+		return false
+	}
+
+	var count int64
+	query.Where("payment_status NOT IN ?", []string{"pending", "failed", ""}).Count(&count)
+	return count > 0
+}
+
+func messageView(message *models.Message, unlocked bool) fiber.Map {
+	body := message.Body
+	if !unlocked {
+		body = services.MaskContactDetails(body)
+	}
+
+	attachments := make([]fiber.Map, 0, len(message.Attachments))
+	for _, a := range message.Attachments {
+		attachments = append(attachments, fiber.Map{
+			"id":        a.ID,
+			"url":       a.URL,
+			"file_name": a.FileName,
+		})
+	}
+
+	return fiber.Map{
+		"id":              message.ID,
+		"conversation_id": message.ConversationID,
+		"sender_id":       message.SenderID,
+		"body":            body,
+		"attachments":     attachments,
+		"read_at":         message.ReadAt,
+		"created_at":      message.CreatedAt,
+	}
+}
+
+// postMessage - บันทึกข้อความ แจ้งอีกฝ่าย และส่งให้คนที่เปิด WebSocket อยู่
+func postMessage(conversation *models.Conversation, senderID uint, in messageInput) (fiber.Map, error) {
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	message := models.Message{
+		ConversationID: conversation.ID,
+		SenderID:       senderID,
+		Body:           in.Body,
+	}
+	for _, a := range in.Attachments {
+		message.Attachments = append(message.Attachments, models.MessageAttachment{URL: a.URL, FileName: a.FileName})
+	}
+	if err := tx.Create(&message).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(conversation).Update("last_message_at", message.CreatedAt).Error; err != nil {
+		return nil, err
+	}
+
+	recipient := conversation.GuideUserID
+	if senderID == conversation.GuideUserID {
+		recipient = conversation.UserID
+	}
+	if _, err := services.NewNotificationService(tx).Send(recipient, "new_message", nil, "conversation", conversation.ID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	view := messageView(&message, conversationUnlocked(config.DB, conversation))
+	messageHub.Publish(conversation.ID, services.MessageEvent{Type: "message", Payload: view})
+	return view, nil
+}
+
+// markConversationRead - อ่านข้อความของอีกฝ่ายทั้งหมดในห้อง (read receipt)
+func markConversationRead(conversation *models.Conversation, readerID uint) (int64, error) {
+	now := time.Now()
+	result := config.DB.Model(&models.Message{}).
+		Where("conversation_id = ? AND sender_id <> ? AND read_at IS NULL", conversation.ID, readerID).
+		Update("read_at", &now)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		messageHub.Publish(conversation.ID, services.MessageEvent{
+			Type:    "read",
+			Payload: fiber.Map{"reader_id": readerID, "read_at": now},
+		})
+	}
+	return result.RowsAffected, nil
+}
+
+// StartConversation - เปิด (หรือดึง) ห้องสนทนาของ offer หรือ booking
+func StartConversation(c *fiber.Ctx) error {
+	var req struct {
+		TripOfferID   uint `json:"trip_offer_id"`
+		TripBookingID uint `json:"trip_booking_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	if (req.TripOfferID == 0) == (req.TripBookingID == 0) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_or_booking_required"})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	conversation := models.Conversation{}
+	var existing *gorm.DB
+
+	if req.TripBookingID != 0 {
+		var booking models.TripBooking
+		if err := config.DB.First(&booking, req.TripBookingID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "booking_not_found"})
+		}
+		var offer models.TripOffer
+		config.DB.Select("id", "trip_require_id").First(&offer, booking.TripOfferID)
+
+		conversation.TripBookingID = &booking.ID
+		if offer.TripRequireID != 0 {
+			conversation.TripRequireID = &offer.TripRequireID
+		}
+		conversation.UserID = booking.UserID
+		conversation.GuideID = booking.GuideID
+		existing = config.DB.Where("trip_booking_id = ?", booking.ID)
+	} else {
+		var offer models.TripOffer
+		if err := config.DB.First(&offer, req.TripOfferID).Error; err != nil || offer.Status == "draft" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "offer_not_found"})
+		}
+		var tripRequire models.TripRequire
+		if err := config.DB.First(&tripRequire, offer.TripRequireID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "trip_requirement_not_found"})
+		}
+
+		conversation.TripOfferID = &offer.ID
+		conversation.TripRequireID = &tripRequire.ID
+		conversation.UserID = tripRequire.UserID
+		conversation.GuideID = offer.GuideID
+		existing = config.DB.Where("trip_offer_id = ?", offer.ID)
+	}
+
+	var guide models.Guide
+	if err := config.DB.Select("id", "user_id").First(&guide, conversation.GuideID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "guide_not_found"})
+	}
+	conversation.GuideUserID = guide.UserID
+
+	if userID != conversation.UserID && userID != conversation.GuideUserID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "not_in_conversation"})
+	}
+
+	var found models.Conversation
+	if err := existing.First(&found).Error; err == nil {
+		return c.JSON(fiber.Map{"conversation": found})
+	}
+
+	if err := config.DB.Create(&conversation).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "conversation_create_failed"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"conversation": conversation})
+}
+
+// GetConversations - ห้องสนทนาทั้งหมดของผู้ใช้ พร้อมจำนวนข้อความที่ยังไม่อ่าน
+func GetConversations(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var conversations []models.Conversation
+	if err := config.DB.Where("user_id = ? OR guide_user_id = ?", userID, userID).
+		Order("last_message_at DESC, id DESC").
+		Find(&conversations).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "conversations_get_failed"})
+	}
+
+	result := make([]fiber.Map, 0, len(conversations))
+	for i := range conversations {
+		conversation := &conversations[i]
+
+		var unread int64
+		config.DB.Model(&models.Message{}).
+			Where("conversation_id = ? AND sender_id <> ? AND read_at IS NULL", conversation.ID, userID).
+			Count(&unread)
+
+		item := fiber.Map{
+			"id":              conversation.ID,
+			"trip_require_id": conversation.TripRequireID,
+			"trip_offer_id":   conversation.TripOfferID,
+			"trip_booking_id": conversation.TripBookingID,
+			"user_id":         conversation.UserID,
+			"guide_id":        conversation.GuideID,
+			"last_message_at": conversation.LastMessageAt,
+			"unread_count":    unread,
+		}
+
+		var last models.Message
+		if err := config.DB.Preload("Attachments").Where("conversation_id = ?", conversation.ID).Order("id DESC").First(&last).Error; err == nil {
+			item["last_message"] = messageView(&last, conversationUnlocked(config.DB, conversation))
+		}
+
+		result = append(result, item)
+	}
+
+	return c.JSON(fiber.Map{"conversations": result})
+}
+
+// GetMessages - ดึงข้อความ (polling) ส่ง after_id เพื่อดึงเฉพาะข้อความใหม่ หรือ before_id เพื่อย้อนหลัง
+func GetMessages(c *fiber.Ctx) error {
+	conversation, resp := loadConversation(c)
+	if conversation == nil {
+		return resp
+	}
+
+	limit := c.QueryInt("limit", defaultMessagePage)
+	if limit <= 0 || limit > 200 {
+		limit = defaultMessagePage
+	}
+
+	query := config.DB.Preload("Attachments").Where("conversation_id = ?", conversation.ID)
+	if afterID := c.QueryInt("after_id", 0); afterID > 0 {
+		query = query.Where("id > ?", afterID).Order("id ASC")
+	} else {
+		if beforeID := c.QueryInt("before_id", 0); beforeID > 0 {
+			query = query.Where("id < ?", beforeID)
+		}
+		query = query.Order("id DESC")
+	}
+
+	var messages []models.Message
+	if err := query.Limit(limit).Find(&messages).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "messages_get_failed"})
+	}
+
+	// เรียงจากเก่าไปใหม่เสมอ
+	if c.QueryInt("after_id", 0) <= 0 {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	unlocked := conversationUnlocked(config.DB, conversation)
+	views := make([]fiber.Map, 0, len(messages))
+	for i := range messages {
+		views = append(views, messageView(&messages[i], unlocked))
+	}
+
+	return c.JSON(fiber.Map{
+		"messages":         views,
+		"contacts_visible": unlocked,
+	})
+}
+
+// SendMessage - ส่งข้อความในห้อง
+func SendMessage(c *fiber.Ctx) error {
+	conversation, resp := loadConversation(c)
+	if conversation == nil {
+		return resp
+	}
+
+	var in messageInput
+	if err := c.BodyParser(&in); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	userID := c.Locals("user_id").(uint)
+	if msg := in.validate(userID, conversation.ID); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	view, err := postMessage(conversation, userID, in)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "message_send_failed"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{"message": view})
+}
+
+// MarkConversationRead - อ่านข้อความในห้องแล้ว
+func MarkConversationRead(c *fiber.Ctx) error {
+	conversation, resp := loadConversation(c)
+	if conversation == nil {
+		return resp
+	}
+
+	count, err := markConversationRead(conversation, c.Locals("user_id").(uint))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "messages_mark_read_failed"})
+	}
+
+	return c.JSON(fiber.Map{"marked_read": count})
+}
+
+// ConversationSocketUpgrade - ตรวจสิทธิ์ก่อนเปิด WebSocket ของห้อง
+func ConversationSocketUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	conversation, resp := loadConversation(c)
+	if conversation == nil {
+		return resp
+	}
+
+	c.Locals("conversation", conversation)
+	middleware.RequestLanguage(c) // เก็บภาษาไว้ใน Locals("lang") WebSocket ไม่ผ่าน middleware.Localize จึงแปลข้อความ error เอง
+	return c.Next()
+}
+
+// ConversationSocket - stream ข้อความใหม่และ read receipt ของห้อง
+// client ส่ง {"type":"message","body":"...","attachments":[...]} หรือ {"type":"read"} ได้
+func ConversationSocket(conn *websocket.Conn) {
+	conversation := conn.Locals("conversation").(*models.Conversation)
+	userID := conn.Locals("user_id").(uint)
+	lang, _ := conn.Locals("lang").(string)
+
+	// goroutine อ่านและ loop ส่ง event เขียนพร้อมกันไม่ได้ (websocket ห้ามเขียนซ้อน) ทุกการเขียนต้องผ่าน writeJSON
+	var writeMu sync.Mutex
+	writeJSON := func(v interface{}) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteJSON(v)
+	}
+	socketError := func(code string) {
+		writeJSON(fiber.Map{"type": "error", "error": services.Message(code, lang), "code": code})
+	}
+
+	events, unsubscribe := messageHub.Subscribe(conversation.ID)
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			var frame struct {
+				Type string `json:"type"`
+				messageInput
+			}
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := json.Unmarshal(data, &frame); err != nil {
+				socketError("frame_invalid")
+				continue
+			}
+
+			switch frame.Type {
+			case "message":
+				in := frame.messageInput
+				if msg := in.validate(userID, conversation.ID); msg != "" {
+					socketError(msg)
+					continue
+				}
+				if _, err := postMessage(conversation, userID, in); err != nil {
+					socketError("message_send_failed")
+				}
+			case "read":
+				markConversationRead(conversation, userID)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := writeJSON(event); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
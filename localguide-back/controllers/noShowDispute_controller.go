@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const maxDisputeStatementsPerParty = 10
+
+func loadNoShowDispute(bookingID uint) (*models.NoShowDispute, error) {
+	var dispute models.NoShowDispute
+	err := config.DB.Preload("Statements", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at ASC")
+	}).Where("trip_booking_id = ?", bookingID).First(&dispute).Error
+	if err != nil {
+		return nil, err
+	}
+	return &dispute, nil
+}
+
+// noShowDisputeView - ไทม์ไลน์ที่ทั้งสองฝ่ายและ admin เห็นเหมือนกัน
+func noShowDisputeView(d *models.NoShowDispute) fiber.Map {
+	statements := make([]fiber.Map, 0, len(d.Statements))
+	for _, s := range d.Statements {
+		statements = append(statements, fiber.Map{
+			"id":         s.ID,
+			"party":      s.Party,
+			"statement":  s.Body,
+			"evidence":   parseEvidence(s.Evidence),
+			"created_at": s.CreatedAt,
+		})
+	}
+
+	var decision fiber.Map
+	if d.DecidedAt != nil {
+		decision = fiber.Map{
+			"decision":   d.Decision,
+			"source":     d.DecisionSource,
+			"rationale":  d.Rationale,
+			"decided_at": d.DecidedAt,
+		}
+	}
+
+	return fiber.Map{
+		"id":              d.ID,
+		"trip_booking_id": d.TripBookingID,
+		"stage":           d.Stage,
+		"respond_by":      d.RespondBy,
+		"disputed_at":     d.DisputedAt,
+		"evidence_due_at": d.EvidenceDueAt,
+		"statements":      statements,
+		"decision":        decision,
+	}
+}
+
+// GetNoShowDispute - ไทม์ไลน์ dispute no-show ของ booking สำหรับ user/ไกด์ของ booking
+func GetNoShowDispute(c *fiber.Ctx) error {
+	booking, _, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+
+	dispute, err := loadNoShowDispute(booking.ID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "no_show_dispute_not_found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"dispute": noShowDisputeView(dispute),
+	})
+}
+
+// AdminGetNoShowDispute - ไทม์ไลน์ dispute สำหรับ admin
+func AdminGetNoShowDispute(c *fiber.Ctx) error {
+	bookingID, err := c.ParamsInt("id")
+	if err != nil || bookingID <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "booking_id_invalid",
+		})
+	}
+
+	dispute, err := loadNoShowDispute(uint(bookingID))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "no_show_dispute_not_found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"dispute": noShowDisputeView(dispute),
+	})
+}
+
+// AddDisputeStatement - user หรือไกด์ส่งคำชี้แจงพร้อมหลักฐานเพิ่มเติม ได้จนถึง evidence_due_at
+func AddDisputeStatement(c *fiber.Ctx) error {
+	booking, role, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Statement string   `json:"statement"`
+		Evidence  []string `json:"evidence"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+	req.Statement = strings.TrimSpace(req.Statement)
+	if req.Statement == "" || utf8.RuneCountInString(req.Statement) > 5000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "statement_length_invalid",
+		})
+	}
+	if msg := validateEvidence(req.Evidence, userID, booking.ID); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
+		})
+	}
+
+	var dispute models.NoShowDispute
+	if err := config.DB.Where("trip_booking_id = ?", booking.ID).First(&dispute).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "no_show_dispute_not_found",
+		})
+	}
+	now := time.Now()
+	if dispute.Stage != "evidence" || dispute.EvidenceDueAt == nil || now.After(*dispute.EvidenceDueAt) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":           "dispute_closed",
+			"stage":           dispute.Stage,
+			"evidence_due_at": dispute.EvidenceDueAt,
+		})
+	}
+
+	party := "traveller"
+	if role == "guide" {
+		party = "guide"
+	}
+	var count int64
+	config.DB.Model(&models.DisputeStatement{}).Where("no_show_dispute_id = ? AND party = ?", dispute.ID, party).Count(&count)
+	if count >= maxDisputeStatementsPerParty {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "statement_limit_reached",
+		})
+	}
+
+	statement := models.DisputeStatement{
+		NoShowDisputeID: dispute.ID,
+		AuthorID:        userID,
+		Party:           party,
+		Body:            req.Statement,
+		Evidence:        evidenceJSON(req.Evidence),
+	}
+	if err := config.DB.Create(&statement).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "statement_add_failed",
+		})
+	}
+
+	notify(bookingCounterpartUserID(config.DB, booking, role), "dispute_statement_added", map[string]interface{}{
+		"BookingID": booking.ID,
+		"Deadline":  dispute.EvidenceDueAt.Format(services.DisputeDeadlineLayout),
+	}, "trip_booking", booking.ID)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":         "Statement added",
+		"evidence_due_at": dispute.EvidenceDueAt,
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"localguide-back/models"
 	"localguide-back/services"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -16,7 +17,7 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Booking ID must be a positive integer",
+			"error": "booking_id_invalid",
 		})
 	}
 
@@ -24,11 +25,11 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Booking not found",
+				"error": "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get booking",
+			"error": "booking_get_failed",
 		})
 	}
 
@@ -36,7 +37,7 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	if booking.UserID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You can only confirm no-show for your own bookings",
+			"error": "no_show_confirm_own_only",
 		})
 	}
 
@@ -44,20 +45,12 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 	// ยอมให้ผู้ใช้ยืนยันการไม่มาได้เฉพาะเมื่อไกด์ได้รีพอร์ตแล้วเท่านั้น
 	if booking.Status != "user_no_show_reported" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":  "Invalid booking status for no-show confirmation",
+			"error":  "no_show_confirm_status_invalid",
 			"status": booking.Status,
 			"message": "User can only confirm no-show after guide reports it",
 		})
 	}
 
-	// ป้องกันการยืนยันซ้ำหรือการดำเนินการกับ booking ที่ถูกตัดสินแล้ว
-	if booking.Status == "user_no_show_confirmed" || booking.Status == "user_no_show_disputed" || booking.Status == "guide_no_show_confirmed" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "No-show already handled",
-			"status": booking.Status,
-		})
-	}
-
 	// 🔧 แก้ไข: เทียบแค่วัน ไม่สนเวลา
 	now := time.Now()
 	startYear, startMonth, startDay := booking.StartDate.Date()
@@ -67,7 +60,7 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 
 	if nowDateOnly.Before(startDateOnly) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot confirm no-show before trip start date",
+			"error": "no_show_confirm_before_start",
 		})
 	}
 
@@ -75,14 +68,14 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 	var payment models.TripPayment
 	if err := config.DB.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Payment not found",
+			"error": "payment_not_found",
 		})
 	}
 
 	// ป้องกันการคืนเงินซ้ำ หาก payment ถูกคืนบางส่วนหรือคืนเต็มไปแล้ว
 	if payment.Status == "partially_refunded" || payment.Status == "refunded" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Payment already refunded or partially refunded",
+			"error": "payment_already_refunded",
 			"payment_status": payment.Status,
 		})
 	}
@@ -91,6 +84,27 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 	guideAmount := payment.TotalAmount * 0.5
 	userRefundAmount := payment.TotalAmount * 0.5
 
+	// บันทึกทุกอย่างใน transaction เดียว การคืนเงินกับ Stripe ทำโดย PaymentOutboxWorker หลัง commit
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	// เปลี่ยนสถานะแบบมีเงื่อนไขก่อนตั้งรายการเงิน การยืนยันซ้ำหรือพร้อมกันจะได้ 0 แถวและไม่จ่าย/คืนเงินซ้ำ
+	claimed := tx.Model(&models.TripBooking{}).
+		Where("id = ? AND status = ?", booking.ID, "user_no_show_reported").
+		Updates(map[string]interface{}{"status": "user_no_show_confirmed", "no_show_at": &now})
+	if claimed.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "booking_update_failed",
+		})
+	}
+	if claimed.RowsAffected == 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "no_show_already_handled",
+		})
+	}
+	booking.Status = "user_no_show_confirmed"
+	booking.NoShowAt = &now
+
 	// Release 50% payment to guide
 	guideRelease := models.PaymentRelease{
 		TripPaymentID: payment.ID,
@@ -101,44 +115,28 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 		Reason:        "user_confirmed_no_show",
 		ScheduledAt:   now,
 		ProcessedAt:   &now,
-		Status:        "processed",
 		Notes:         "Guide compensation for user no-show (50%)",
 	}
-
-	if err := config.DB.Create(&guideRelease).Error; err != nil {
+	if _, err := services.QueueGuidePayout(tx, &payment, &guideRelease); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create guide payment release",
-		})
-	}
-
-	// Refund 50% to user via Stripe
-	stripeService := services.NewStripeService()
-	refundAmountCents := int64(userRefundAmount * 100)
-
-	stripeRefund, err := stripeService.RefundPayment(payment.StripePaymentIntentID, refundAmountCents, "requested_by_customer")
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process Stripe refund: " + err.Error(),
+			"error": "guide_payment_release_create_failed",
 		})
 	}
 
+	// Refund 50% to user (queued)
 	userRefund := models.PaymentRelease{
-		TripPaymentID:  payment.ID,
-		ReleaseType:    "refund",
-		Amount:         userRefundAmount,
-		RecipientType:  "user",
-		RecipientID:    booking.UserID,
-		Reason:         "user_confirmed_no_show",
-		ScheduledAt:    now,
-		ProcessedAt:    &now,
-		Status:         "processed",
-		TransactionRef: stripeRefund.ID,
-		Notes:          "50% refund - user confirmed no-show",
-	}
-
-	if err := config.DB.Create(&userRefund).Error; err != nil {
+		TripPaymentID: payment.ID,
+		ReleaseType:   "refund",
+		Amount:        userRefundAmount,
+		RecipientType: "user",
+		RecipientID:   booking.UserID,
+		Reason:        "user_confirmed_no_show",
+		ScheduledAt:   now,
+		Notes:         "50% refund - user confirmed no-show",
+	}
+	if _, err := services.QueueRefund(tx, &payment, &userRefund); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create user refund record",
+			"error": "user_refund_record_create_failed",
 		})
 	}
 
@@ -147,23 +145,26 @@ func ConfirmUserNoShow(c *fiber.Ctx) error {
 	payment.RefundedAt = &now
 	payment.RefundAmount = userRefundAmount
 	payment.RefundReason = "user_confirmed_no_show"
-	if err := config.DB.Save(&payment).Error; err != nil {
+	if err := tx.Save(&payment).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update payment status",
+			"error": "payment_status_update_failed",
 		})
 	}
 
-	// Update booking status
-	booking.Status = "user_no_show_confirmed"
-	booking.NoShowAt = &now
-	if err := config.DB.Save(&booking).Error; err != nil {
+	if err := services.RecordNoShowDecision(tx, booking.ID, "guide_wins", "traveller_confirmed", "The traveller confirmed the no-show.", nil, now); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "dispute_decision_record_failed",
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update booking",
+			"error": "no_show_confirm_failed",
 		})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message":       "No-show confirmed: Guide receives 50%, User refunded 50%",
+		"message":       "No-show confirmed: Guide receives 50%, 50% refund to user is being processed",
 		"booking":       booking,
 		"guide_release": guideRelease,
 		"user_refund":   userRefund,
@@ -175,7 +176,7 @@ func ReportUserNoShow(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Booking ID must be a positive integer",
+			"error": "booking_id_invalid",
 		})
 	}
 
@@ -192,11 +193,11 @@ func ReportUserNoShow(c *fiber.Ctx) error {
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Booking not found",
+				"error": "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get booking",
+			"error": "booking_get_failed",
 		})
 	}
 
@@ -207,20 +208,20 @@ func ReportUserNoShow(c *fiber.Ctx) error {
 	var guide models.Guide
 	if err := config.DB.Where("user_id = ?", userID).First(&guide).Error; err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Guide profile not found",
+			"error": "guide_profile_not_found",
 		})
 	}
 	
 	if booking.GuideID != guide.ID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You can only report no-show for your own bookings",
+			"error": "no_show_own_only",
 		})
 	}
 
 	// Validate status
 	if booking.Status != "paid" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid booking status for no-show report",
+			"error": "no_show_status_invalid",
 		})
 	}
 
@@ -233,7 +234,7 @@ func ReportUserNoShow(c *fiber.Ctx) error {
 
 	if nowDateOnly.Before(startDateOnly) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot report no-show before trip start date",
+			"error": "no_show_before_start",
 		})
 	}
 
@@ -242,7 +243,7 @@ func ReportUserNoShow(c *fiber.Ctx) error {
 	booking.NoShowAt = &now
 	if err := config.DB.Save(&booking).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update booking",
+			"error": "booking_update_failed",
 		})
 	}
 
@@ -269,39 +270,89 @@ func ReportUserNoShow(c *fiber.Ctx) error {
 		Actions:        "",
 	}
 
-	if err := config.DB.Create(&report).Error; err != nil {
+	// เปิดไทม์ไลน์ dispute โดยมีรีพอร์ตของไกด์เป็นคำชี้แจงแรก
+	dispute := models.NoShowDispute{
+		TripBookingID: booking.ID,
+		Stage:         "awaiting_traveller",
+		RespondBy:     now.Add(services.NoShowResponseWindow()),
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+		dispute.NoShowReportID = report.ID
+		if err := tx.Create(&dispute).Error; err != nil {
+			return err
+		}
+		var evidence []string
+		if requestData.Evidence != "" {
+			evidence = []string{requestData.Evidence}
+		}
+		return tx.Create(&models.DisputeStatement{
+			NoShowDisputeID: dispute.ID,
+			AuthorID:        userID,
+			Party:           "guide",
+			Body:            description,
+			Evidence:        evidenceJSON(evidence),
+		}).Error
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create no-show report",
+			"error": "no_show_report_create_failed",
 		})
 	}
 
+	notify(booking.UserID, "user_no_show_reported", map[string]interface{}{"BookingID": booking.ID}, "trip_booking", booking.ID)
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "User no-show reported. User has 24 hours to respond, or admin will review.",
-		"booking": booking,
-		"report":  report,
-		"status":  "reported",
-		"note":    "User can confirm or dispute this report within 24 hours",
+		"message":    "User no-show reported. If the user does not respond by the deadline, the no-show is confirmed.",
+		"booking":    booking,
+		"report":     report,
+		"status":     "reported",
+		"respond_by": dispute.RespondBy,
+		"note":       "User can confirm or dispute this report before respond_by",
 	})
 }
 
-// DisputeNoShowReport - User คัดค้านการรีพอร์ต no-show จากไกด์
+// DisputeNoShowReport - User คัดค้านการรีพอร์ต no-show จากไกด์ ภายในเวลาที่กำหนด
+// หลังคัดค้าน ทั้งสองฝ่ายส่งคำชี้แจงเพิ่มได้จนถึง evidence_due_at (POST /trip-bookings/:id/dispute/statements)
 func DisputeNoShowReport(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Booking ID must be a positive integer",
+			"error":   "booking_id_invalid",
 		})
 	}
 
 	var requestData struct {
-		Reason      string `json:"reason"`
-		Description string `json:"description"`
-		Evidence    string `json:"evidence"` // URL ของหลักฐาน เช่น รูปภาพ
+		Reason        string   `json:"reason"`
+		Description   string   `json:"description"`
+		Evidence      string   `json:"evidence"`       // URL ของหลักฐาน เช่น รูปภาพ
+		EvidenceFiles []string `json:"evidence_files"` // หลักฐานหลายไฟล์ (อัปโหลดผ่าน /upload)
 	}
 
 	if err := c.BodyParser(&requestData); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid request data",
+			"error":   "invalid_request_data",
+		})
+	}
+
+	statement := strings.TrimSpace(requestData.Description)
+	if statement == "" {
+		statement = strings.TrimSpace(requestData.Reason)
+	}
+	if statement == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "dispute_reason_required",
+		})
+	}
+	evidence := requestData.EvidenceFiles
+	if requestData.Evidence != "" {
+		evidence = append([]string{requestData.Evidence}, evidence...)
+	}
+	if msg := validateEvidence(evidence, c.Locals("user_id").(uint), uint(bookingID)); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": msg,
 		})
 	}
 
@@ -309,11 +360,11 @@ func DisputeNoShowReport(c *fiber.Ctx) error {
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error":   "Booking not found",
+				"error":   "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get booking",
+			"error":   "booking_get_failed",
 		})
 	}
 
@@ -321,21 +372,23 @@ func DisputeNoShowReport(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	if booking.UserID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error":   "You can only dispute reports for your own bookings",
+			"error":   "dispute_own_only",
 		})
 	}
 
 	if booking.Status != "user_no_show_reported" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "No active no-show report to dispute",
+			"error":   "no_active_no_show_report",
 		})
 	}
 
-	// Update booking status to disputed (รอ admin ตัดสิน)
-	booking.Status = "user_no_show_disputed"
-	if err := config.DB.Save(&booking).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update booking",
+	now := time.Now()
+	var dispute models.NoShowDispute
+	hasDispute := config.DB.Where("trip_booking_id = ?", booking.ID).First(&dispute).Error == nil
+	if hasDispute && now.After(dispute.RespondBy) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":      "dispute_deadline_passed",
+			"respond_by": dispute.RespondBy,
 		})
 	}
 
@@ -343,30 +396,67 @@ func DisputeNoShowReport(c *fiber.Ctx) error {
 	report := models.TripReport{
 		TripBookingID:  uint(bookingID),
 		ReporterID:     userID,
-		ReportedUserID: booking.GuideID,
+		ReportedUserID: bookingCounterpartUserID(config.DB, &booking, "user"),
 		ReportType:     "dispute_no_show",
 		Title:          "User disputes no-show report",
-		Description:    requestData.Description,
-		Evidence:       requestData.Evidence,
+		Description:    statement,
+		Evidence:       evidenceJSON(evidence),
 		Severity:       "medium",
 		Status:         "pending",
 		AdminNotes:     "",
 		Actions:        "",
 	}
 
-	if err := config.DB.Create(&report).Error; err != nil {
+	evidenceDueAt := now.Add(services.NoShowEvidenceWindow())
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Update booking status to disputed (รอคำชี้แจงจากไกด์และ admin ตัดสิน)
+		booking.Status = "user_no_show_disputed"
+		if err := tx.Save(&booking).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&report).Error; err != nil {
+			return err
+		}
+
+		// รีพอร์ตก่อนมีไทม์ไลน์ยังไม่มี dispute
+		if !hasDispute {
+			var original models.TripReport
+			tx.Where("trip_booking_id = ? AND report_type = ?", booking.ID, "user_no_show").Order("id DESC").First(&original)
+			dispute = models.NoShowDispute{TripBookingID: booking.ID, NoShowReportID: original.ID, RespondBy: now}
+		}
+		dispute.Stage = "evidence"
+		dispute.DisputeReportID = &report.ID
+		dispute.DisputedAt = &now
+		dispute.EvidenceDueAt = &evidenceDueAt
+		if err := tx.Save(&dispute).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.DisputeStatement{
+			NoShowDisputeID: dispute.ID,
+			AuthorID:        userID,
+			Party:           "traveller",
+			Body:            statement,
+			Evidence:        evidenceJSON(evidence),
+		}).Error
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to create dispute report",
+			"error":   "dispute_report_create_failed",
 		})
 	}
 
-	// TODO: ส่ง notification ให้ admin สำหรับการตัดสิน
+	// admin จะได้รับแจ้งเมื่อปิดรับหลักฐาน (NoShowDisputeService) หรือดูได้จากคิวเคส
+	notify(report.ReportedUserID, "no_show_disputed", map[string]interface{}{
+		"BookingID": booking.ID,
+		"Deadline":  evidenceDueAt.Format(services.DisputeDeadlineLayout),
+	}, "trip_booking", booking.ID)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "No-show report disputed successfully. Admin will review the case.",
-		"booking": booking,
-		"report":  report,
-		"status":  "disputed",
+		"message":         "No-show report disputed successfully. Both parties can add statements until the evidence deadline.",
+		"booking":         booking,
+		"report":          report,
+		"status":          "disputed",
+		"evidence_due_at": evidenceDueAt,
 	})
 }
 
@@ -375,7 +465,7 @@ func ReportGuideNoShow(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Booking ID must be a positive integer",
+			"error": "booking_id_invalid",
 		})
 	}
 
@@ -397,11 +487,11 @@ func ReportGuideNoShow(c *fiber.Ctx) error {
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Booking not found",
+				"error": "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get booking",
+			"error": "booking_get_failed",
 		})
 	}
 
@@ -409,14 +499,14 @@ func ReportGuideNoShow(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	if booking.UserID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You can only report guide no-show for your own bookings",
+			"error": "guide_no_show_own_only",
 		})
 	}
 
 	// Validate status
 	if booking.Status != "paid" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid booking status for guide no-show report",
+			"error":   "guide_no_show_status_invalid",
 			"status":  booking.Status,
 			"message": "Booking must be in 'paid' status to report guide no-show",
 		})
@@ -431,19 +521,30 @@ func ReportGuideNoShow(c *fiber.Ctx) error {
 
 	if nowDateOnly.Before(startDateOnly) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":      "Cannot report guide no-show before trip start date",
+			"error":      "guide_no_show_before_start",
 			"start_date": booking.StartDate.Format("2006-01-02"),
 			"now":        now.Format("2006-01-02"),
 		})
 	}
 
+	var payment models.TripPayment
+	if err := config.DB.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "payment_not_found",
+		})
+	}
+
+	// บันทึกทุกอย่างใน transaction เดียว การคืนเงินกับ Stripe ทำโดย PaymentOutboxWorker หลัง commit
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
 	// อัปเดต booking status
 	booking.Status = "guide_no_show_confirmed"
 	booking.NoShowAt = &now
 	booking.CancellationReason = "guide_no_show"
-	if err := config.DB.Save(&booking).Error; err != nil {
+	if err := tx.Save(&booking).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update booking",
+			"error": "booking_update_failed",
 		})
 	}
 
@@ -470,47 +571,26 @@ func ReportGuideNoShow(c *fiber.Ctx) error {
 		Actions:        "",
 	}
 
-	if err := config.DB.Create(&report).Error; err != nil {
+	if err := tx.Create(&report).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create guide no-show report",
-		})
-	}
-
-	// คืนเงินเต็มจำนวนให้ลูกค้าทันที
-	var payment models.TripPayment
-	if err := config.DB.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Payment not found",
-		})
-	}
-
-	stripeService := services.NewStripeService()
-	refundAmountCents := int64(payment.TotalAmount * 100)
-	
-	stripeRefund, err := stripeService.RefundPayment(payment.StripePaymentIntentID, refundAmountCents, "requested_by_customer")
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to process Stripe refund: " + err.Error(),
+			"error": "guide_no_show_report_create_failed",
 		})
 	}
 
+	// คืนเงินเต็มจำนวนให้ลูกค้า (เข้าคิว)
 	userRefund := models.PaymentRelease{
-		TripPaymentID:  payment.ID,
-		ReleaseType:    "refund",
-		Amount:         payment.TotalAmount,
-		RecipientType:  "user",
-		RecipientID:    booking.UserID,
-		Reason:         "guide_no_show",
-		ScheduledAt:    now,
-		ProcessedAt:    &now,
-		Status:         "processed",
-		TransactionRef: stripeRefund.ID,
-		Notes:          "Full refund - guide no-show",
+		TripPaymentID: payment.ID,
+		ReleaseType:   "refund",
+		Amount:        payment.TotalAmount,
+		RecipientType: "user",
+		RecipientID:   booking.UserID,
+		Reason:        "guide_no_show",
+		ScheduledAt:   now,
+		Notes:         "Full refund - guide no-show",
 	}
-	
-	if err := config.DB.Create(&userRefund).Error; err != nil {
+	if _, err := services.QueueRefund(tx, &payment, &userRefund); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create refund record",
+			"error": "refund_record_create_failed",
 		})
 	}
 
@@ -519,95 +599,25 @@ func ReportGuideNoShow(c *fiber.Ctx) error {
 	payment.RefundedAt = &now
 	payment.RefundAmount = payment.TotalAmount
 	payment.RefundReason = "guide_no_show"
-	if err := config.DB.Save(&payment).Error; err != nil {
+	if err := tx.Save(&payment).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update payment status",
+			"error": "payment_status_update_failed",
 		})
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message":     "Guide no-show reported. Full refund issued immediately.",
-		"booking":     booking,
-		"report":      report,
-		"user_refund": userRefund,
-		"status":      "refunded",
-	})
-}
-
-// Helper function สำหรับประมวลผล no-show payment (50%-50%)
-func processNoShowPayment(c *fiber.Ctx, booking *models.TripBooking, payment *models.TripPayment, stripeService *services.StripeService, now time.Time, reason string) error {
-	booking.Status = "no_show_confirmed"
-	booking.CancellationReason = reason
-	if err := config.DB.Save(booking).Error; err != nil {
+	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update booking",
+			"error": "guide_no_show_report_failed",
 		})
 	}
 
-	// Release 50% payment to guide
-	guideRelease := models.PaymentRelease{
-		TripPaymentID: payment.ID,
-		ReleaseType:   "first_payment",
-		Amount:        payment.FirstPayment,
-		RecipientType: "guide",
-		RecipientID:   booking.GuideID,
-		Reason:        reason,
-		ScheduledAt:   now,
-		ProcessedAt:   &now,
-		Status:        "processed",
-	}
-
-	if err := config.DB.Create(&guideRelease).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to create guide payment release",
-		})
-	}
-
-	// Refund remaining 50% to user via Stripe
-	refundAmount := int64(payment.SecondPayment * 100) // Convert to cents
-	stripeRefund, err := stripeService.RefundPayment(payment.StripePaymentIntentID, refundAmount, "requested_by_customer")
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to process Stripe refund: " + err.Error(),
-		})
-	}
-
-	userRefund := models.PaymentRelease{
-		TripPaymentID:  payment.ID,
-		ReleaseType:    "refund",
-		Amount:         payment.SecondPayment,
-		RecipientType:  "user",
-		RecipientID:    booking.UserID,
-		Reason:         reason,
-		ScheduledAt:    now,
-		ProcessedAt:    &now,
-		Status:         "processed",
-		TransactionRef: stripeRefund.ID,
-		Notes:          "50% refund - no-show confirmed",
-	}
-
-	if err := config.DB.Create(&userRefund).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to create user refund record",
-		})
-	}
-
-	// Update payment status
-	payment.Status = "partially_refunded"
-	payment.RefundedAt = &now
-	payment.RefundAmount = payment.SecondPayment
-	payment.RefundReason = reason
-	if err := config.DB.Save(payment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update payment status",
-		})
-	}
+	notify(bookingCounterpartUserID(config.DB, &booking, "user"), "guide_no_show_reported", map[string]interface{}{"BookingID": booking.ID}, "trip_booking", booking.ID)
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message":       "No-show confirmed: 50% paid to guide, 50% refunded to user",
-		"booking":       booking,
-		"guide_release": guideRelease,
-		"user_refund":   userRefund,
-		"decision":      "guide_wins",
+		"message":     "Guide no-show reported. Full refund is being processed.",
+		"booking":     booking,
+		"report":      report,
+		"user_refund": userRefund,
+		"status":      "refunded",
 	})
 }
\ No newline at end of file
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var notificationChannels = map[string]bool{"in_app": true, "email": true}
+
+// notify - แจ้งเตือนนอก transaction ส่งไม่สำเร็จไม่ทำให้ request ล้มเหลว
+func notify(userID uint, notifType string, data map[string]interface{}, relatedType string, relatedID uint) {
+	if userID == 0 {
+		return
+	}
+	if _, err := services.NewNotificationService(config.DB).Send(userID, notifType, data, relatedType, relatedID); err != nil {
+		log.Printf("failed to send %s notification to user #%d: %v", notifType, userID, err)
+	}
+}
+
+// notifyAdmins - แจ้งเตือน admin ทุกคน (role 3) นอก transaction
+func notifyAdmins(notifType string, data map[string]interface{}, relatedType string, relatedID uint) {
+	var adminIDs []uint
+	if err := config.DB.Model(&models.User{}).Where("role_id = ?", 3).Pluck("id", &adminIDs).Error; err != nil {
+		log.Printf("failed to find admins for %s notification: %v", notifType, err)
+		return
+	}
+	for _, id := range adminIDs {
+		notify(id, notifType, data, relatedType, relatedID)
+	}
+}
+
+// notifyBookingPaid - แจ้ง user ว่าชำระเงินสำเร็จ และแจ้งไกด์ว่าได้รับการจองแล้ว
+func notifyBookingPaid(db *gorm.DB, booking *models.TripBooking, amount float64) error {
+	notifier := services.NewNotificationService(db)
+	if _, err := notifier.Send(booking.UserID, "payment_succeeded", map[string]interface{}{
+		"BookingID": booking.ID,
+		"Amount":    amount,
+	}, "trip_booking", booking.ID); err != nil {
+		return err
+	}
+
+	if guideUserID := bookingCounterpartUserID(db, booking, "user"); guideUserID != 0 {
+		if _, err := notifier.Send(guideUserID, "booking_paid", map[string]interface{}{
+			"BookingID": booking.ID,
+			"StartDate": booking.StartDate.Format("2006-01-02"),
+		}, "trip_booking", booking.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetNotifications - feed การแจ้งเตือนของผู้ใช้ (ใหม่สุดก่อน) ส่ง ?unread=true เพื่อดูเฉพาะที่ยังไม่อ่าน
+func GetNotifications(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := config.DB.Model(&models.Notification{}).Where("user_id = ?", userID)
+	if c.QueryBool("unread") {
+		query = query.Where("read_at IS NULL")
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * limit).Limit(limit).Find(&notifications).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "notifications_get_failed"})
+	}
+
+	var unread int64
+	config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread)
+
+	return c.JSON(fiber.Map{
+		"notifications": notifications,
+		"unread_count":  unread,
+		"total":         total,
+		"page":          page,
+		"limit":         limit,
+	})
+}
+
+// GetUnreadNotificationCount - จำนวนการแจ้งเตือนที่ยังไม่อ่าน (ใช้แสดง badge)
+func GetUnreadNotificationCount(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var unread int64
+	if err := config.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "notification_count_failed"})
+	}
+
+	return c.JSON(fiber.Map{"unread_count": unread})
+}
+
+// MarkNotificationRead - อ่านการแจ้งเตือน 1 รายการ
+func MarkNotificationRead(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "notification_id_invalid"})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	var notification models.Notification
+	if err := config.DB.Where("id = ? AND user_id = ?", id, userID).First(&notification).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "notification_not_found"})
+	}
+
+	if notification.ReadAt == nil {
+		now := time.Now()
+		notification.ReadAt = &now
+		if err := config.DB.Model(&notification).Update("read_at", &now).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "notification_update_failed"})
+		}
+	}
+
+	return c.JSON(fiber.Map{"notification": notification})
+}
+
+// MarkAllNotificationsRead - อ่านการแจ้งเตือนทั้งหมด
+func MarkAllNotificationsRead(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	result := config.DB.Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "notifications_update_failed"})
+	}
+
+	return c.JSON(fiber.Map{"marked_read": result.RowsAffected})
+}
+
+// GetNotificationPreferences - ภาษาและช่องทางที่ผู้ใช้ปิด/เปิดไว้ (ไม่มีในรายการ = เปิด)
+func GetNotificationPreferences(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	var user models.User
+	if err := config.DB.Select("id", "language").First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
+	}
+
+	var prefs []models.NotificationPreference
+	if err := config.DB.Where("user_id = ?", userID).Order("type, channel").Find(&prefs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "preferences_get_failed"})
+	}
+
+	items := make([]fiber.Map, 0, len(prefs))
+	for _, p := range prefs {
+		items = append(items, fiber.Map{"type": p.Type, "channel": p.Channel, "enabled": p.Enabled})
+	}
+
+	return c.JSON(fiber.Map{
+		"language":    user.Language,
+		"preferences": items,
+	})
+}
+
+// UpdateNotificationPreferences - ตั้งค่าภาษาและเปิด/ปิดช่องทาง (type "*" = ทุกประเภท)
+func UpdateNotificationPreferences(c *fiber.Ctx) error {
+	var req struct {
+		Language    string `json:"language"`
+		Preferences []struct {
+			Type    string `json:"type"`
+			Channel string `json:"channel"`
+			Enabled bool   `json:"enabled"`
+		} `json:"preferences"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	if req.Language != "" && !services.IsSupportedLanguage(req.Language) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "language_invalid"})
+	}
+	for _, p := range req.Preferences {
+		if p.Type == "" || !notificationChannels[p.Channel] {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "preference_invalid"})
+		}
+	}
+
+	userID := c.Locals("user_id").(uint)
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	if req.Language != "" {
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("language", req.Language).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "language_update_failed"})
+		}
+	}
+
+	for _, p := range req.Preferences {
+		var pref models.NotificationPreference
+		err := tx.Where("user_id = ? AND type = ? AND channel = ?", userID, p.Type, p.Channel).First(&pref).Error
+		if err == gorm.ErrRecordNotFound {
+			pref = models.NotificationPreference{UserID: userID, Type: p.Type, Channel: p.Channel, Enabled: p.Enabled}
+			err = tx.Create(&pref).Error
+		} else if err == nil {
+			err = tx.Model(&pref).Update("enabled", p.Enabled).Error
+		}
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "preferences_update_failed"})
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "preferences_update_failed"})
+	}
+
+	return GetNotificationPreferences(c)
+}
@@ -34,20 +34,20 @@ func GoogleLogin(c *fiber.Ctx) error {
 func GoogleCallback(c *fiber.Ctx) error {
     code := c.Query("code")
     if code == "" {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No code provided"})
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "oauth_code_missing"})
     }
 
     // แลกเปลี่ยน code เป็น access token
     token, err := getGoogleOauthConfig().Exchange(context.Background(), code)
     if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to exchange token"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_exchange_failed"})
     }
 
     // ดึงข้อมูล user จาก Google API
     client := getGoogleOauthConfig().Client(context.Background(), token)
     resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
     if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get user info"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_info_get_failed"})
     }
     defer resp.Body.Close()
 
@@ -61,7 +61,7 @@ func GoogleCallback(c *fiber.Ctx) error {
     }
 
     if err := json.NewDecoder(resp.Body).Decode(&googleUser); err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to parse user info"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_info_parse_failed"})
     }
 
     // ตรวจสอบว่า user มีอยู่แล้วหรือไม่
@@ -78,7 +78,7 @@ func GoogleCallback(c *fiber.Ctx) error {
             Password: "", // OAuth ไม่ต้องมีรหัสผ่าน
         }
         if err := tx.Create(&authUser).Error; err != nil {
-            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create auth user"})
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "auth_user_create_failed"})
         }
 
         user = models.User{
@@ -89,17 +89,17 @@ func GoogleCallback(c *fiber.Ctx) error {
             Avatar:      googleUser.Picture,
         }
         if err := tx.Create(&user).Error; err != nil {
-            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create user"})
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_create_failed"})
         }
     } else {
         // หา user ที่มีอยู่แล้ว
         if err := tx.Where("auth_user_id = ?", authUser.ID).First(&user).Error; err != nil {
-            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "User not found"})
+            return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_not_found"})
         }
     }
 
     if err := tx.Commit().Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "transaction_commit_failed"})
     }
 
     // สร้าง JWT token
@@ -111,7 +111,7 @@ func GoogleCallback(c *fiber.Ctx) error {
 
     tokenString, err := jwtToken.SignedString(config.JWTSecret)
     if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Token creation failed"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_creation_failed"})
     }
 
     // Redirect กลับไป frontend พร้อม token
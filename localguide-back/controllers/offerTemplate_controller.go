@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"localguide-back/config"
+	"localguide-back/models"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// currentGuide - หา Guide ของผู้ใช้ที่ login อยู่
+func currentGuide(c *fiber.Ctx) (*models.Guide, error) {
+	userID := c.Locals("user_id").(uint)
+	var guide models.Guide
+	if err := config.DB.Where("user_id = ?", userID).First(&guide).Error; err != nil {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "guide_profile_not_found",
+		})
+	}
+	return &guide, nil
+}
+
+// loadGuideTemplate - โหลดแม่แบบและตรวจสอบว่าเป็นของไกด์คนนี้
+func loadGuideTemplate(c *fiber.Ctx, guide *models.Guide) (*models.TripOfferTemplate, error) {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "template_id_invalid"})
+	}
+
+	var template models.TripOfferTemplate
+	if err := config.DB.Where("id = ? AND guide_id = ?", id, guide.ID).First(&template).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "template_not_found"})
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "template_get_failed"})
+	}
+	return &template, nil
+}
+
+type offerTemplateRequest struct {
+	Name             string  `json:"name"`
+	Title            string  `json:"title"`
+	Description      string  `json:"description"`
+	Itinerary        string  `json:"itinerary"`
+	IncludedServices string  `json:"included_services"`
+	ExcludedServices string  `json:"excluded_services"`
+	OfferNotes       string  `json:"offer_notes"`
+	DefaultPrice     float64 `json:"default_price"`
+	PriceBreakdown   string  `json:"price_breakdown"`
+}
+
+func (r *offerTemplateRequest) validate() string {
+	if r.Name == "" || r.Title == "" || r.Description == "" {
+		return "template_fields_required"
+	}
+	if r.DefaultPrice < 0 {
+		return "template_price_negative"
+	}
+	return ""
+}
+
+func (r *offerTemplateRequest) apply(template *models.TripOfferTemplate) {
+	template.Name = r.Name
+	template.Title = r.Title
+	template.Description = r.Description
+	template.Itinerary = r.Itinerary
+	template.IncludedServices = r.IncludedServices
+	template.ExcludedServices = r.ExcludedServices
+	template.OfferNotes = r.OfferNotes
+	template.DefaultPrice = r.DefaultPrice
+	template.PriceBreakdown = r.PriceBreakdown
+}
+
+// GetOfferTemplates - ดูแม่แบบ offer ทั้งหมดของไกด์
+func GetOfferTemplates(c *fiber.Ctx) error {
+	guide, resp := currentGuide(c)
+	if guide == nil {
+		return resp
+	}
+
+	var templates []models.TripOfferTemplate
+	if err := config.DB.Where("guide_id = ?", guide.ID).Order("updated_at DESC").Find(&templates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "templates_get_failed"})
+	}
+
+	return c.JSON(fiber.Map{"templates": templates})
+}
+
+// GetOfferTemplateByID - ดูแม่แบบรายการเดียว
+func GetOfferTemplateByID(c *fiber.Ctx) error {
+	guide, resp := currentGuide(c)
+	if guide == nil {
+		return resp
+	}
+
+	template, resp := loadGuideTemplate(c, guide)
+	if template == nil {
+		return resp
+	}
+
+	return c.JSON(fiber.Map{"template": template})
+}
+
+// CreateOfferTemplate - ไกด์สร้างแม่แบบ offer
+func CreateOfferTemplate(c *fiber.Ctx) error {
+	guide, resp := currentGuide(c)
+	if guide == nil {
+		return resp
+	}
+
+	var req offerTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	if msg := req.validate(); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	template := models.TripOfferTemplate{GuideID: guide.ID}
+	req.apply(&template)
+
+	if err := config.DB.Create(&template).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "template_create_failed"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":  "Template created successfully",
+		"template": template,
+	})
+}
+
+// UpdateOfferTemplate - ไกด์แก้ไขแม่แบบของตัวเอง
+func UpdateOfferTemplate(c *fiber.Ctx) error {
+	guide, resp := currentGuide(c)
+	if guide == nil {
+		return resp
+	}
+
+	template, resp := loadGuideTemplate(c, guide)
+	if template == nil {
+		return resp
+	}
+
+	var req offerTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	if msg := req.validate(); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	req.apply(template)
+	if err := config.DB.Save(template).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "template_update_failed"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Template updated successfully",
+		"template": template,
+	})
+}
+
+// DeleteOfferTemplate - ลบแม่แบบ (soft delete) offer ที่สร้างไปแล้วไม่ได้รับผลกระทบ
+func DeleteOfferTemplate(c *fiber.Ctx) error {
+	guide, resp := currentGuide(c)
+	if guide == nil {
+		return resp
+	}
+
+	template, resp := loadGuideTemplate(c, guide)
+	if template == nil {
+		return resp
+	}
+
+	if err := config.DB.Delete(template).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "template_delete_failed"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Template deleted successfully"})
+}
+
+// InstantiateOfferTemplate - สร้าง offer จากแม่แบบสำหรับ TripRequire ที่ระบุ
+// offer ที่ได้เป็น draft เสมอ เว้นแต่ส่ง send = true (จะตรวจช่วงราคาก่อนส่ง)
+func InstantiateOfferTemplate(c *fiber.Ctx) error {
+	guide, resp := currentGuide(c)
+	if guide == nil {
+		return resp
+	}
+
+	template, resp := loadGuideTemplate(c, guide)
+	if template == nil {
+		return resp
+	}
+
+	var req struct {
+		TripRequireID uint     `json:"trip_require_id"`
+		TotalPrice    *float64 `json:"total_price"` // ถ้าไม่ส่งมาจะใช้ราคาตั้งต้นของแม่แบบ
+		OfferNotes    *string  `json:"offer_notes"`
+		Send          bool     `json:"send"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	if req.TripRequireID == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "trip_require_id_required"})
+	}
+
+	in := offerInput{
+		Title:            template.Title,
+		Description:      template.Description,
+		Itinerary:        template.Itinerary,
+		IncludedServices: template.IncludedServices,
+		ExcludedServices: template.ExcludedServices,
+		OfferNotes:       template.OfferNotes,
+		TotalPrice:       template.DefaultPrice,
+		PriceBreakdown:   template.PriceBreakdown,
+	}
+	if req.TotalPrice != nil {
+		in.TotalPrice = *req.TotalPrice
+	}
+	if req.OfferNotes != nil {
+		in.OfferNotes = *req.OfferNotes
+	}
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	tripRequire, resp := loadOfferableTripRequire(c, tx, guide.ID, req.TripRequireID)
+	if tripRequire == nil {
+		return resp
+	}
+
+	if req.Send && !offerPriceInRange(tripRequire, in.TotalPrice) {
+		return priceOutOfRangeResponse(c, tripRequire)
+	}
+
+	offer, quotation, err := createDraftOffer(tx, guide.ID, tripRequire, in)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_from_template_failed"})
+	}
+
+	if req.Send {
+		if err := markOfferSent(tx, offer, quotation, tripRequire); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_send_failed"})
+		}
+	}
+
+	now := time.Now()
+	if err := tx.Model(template).Update("last_used_at", &now).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "template_update_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_from_template_failed"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":   "Offer created from template",
+		"offer":     offer,
+		"quotation": quotation,
+	})
+}
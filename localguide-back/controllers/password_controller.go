@@ -3,15 +3,15 @@ package controllers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"localguide-back/config"
 	"localguide-back/models"
-	"os"
+	"localguide-back/services"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"golang.org/x/crypto/bcrypt"
-	"gopkg.in/gomail.v2"
 )
 
 func ForgotPassword(c *fiber.Ctx) error {
@@ -20,7 +20,7 @@ func ForgotPassword(c *fiber.Ctx) error {
     }
 
     if err := c.BodyParser(&req); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
     }
 
     // ตรวจสอบว่า email มีอยู่ในระบบหรือไม่
@@ -44,12 +44,12 @@ func ForgotPassword(c *fiber.Ctx) error {
     }
 
     if err := config.DB.Create(&passwordReset).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create reset token"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "reset_token_create_failed"})
     }
 
     // ส่งอีเมล
     if err := sendResetEmail(req.Email, token); err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to send email"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "email_send_failed"})
     }
 
     return c.JSON(fiber.Map{"message": "If email exists, reset link has been sent"})
@@ -62,20 +62,20 @@ func ResetPassword(c *fiber.Ctx) error {
     }
 
     if err := c.BodyParser(&req); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
     }
 
     // ตรวจสอบ token
     var passwordReset models.PasswordReset
     if err := config.DB.Where("token = ? AND used = false AND expires_at > ?", 
         req.Token, time.Now()).First(&passwordReset).Error; err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired token"})
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "token_invalid_or_expired"})
     }
 
     // Hash รหัสผ่านใหม่
     hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
     if err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Password hash failed"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "password_hash_failed"})
     }
 
     tx := config.DB.Begin()
@@ -84,43 +84,38 @@ func ResetPassword(c *fiber.Ctx) error {
     // อัปเดตรหัสผ่าน
     if err := tx.Model(&models.AuthUser{}).Where("email = ?", passwordReset.Email).
         Update("password", string(hashedPassword)).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update password"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "password_update_failed"})
     }
 
     // ทำเครื่องหมายว่า token ถูกใช้แล้ว
     if err := tx.Model(&passwordReset).Update("used", true).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to mark token as used"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "token_mark_used_failed"})
     }
 
     if err := tx.Commit().Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to commit transaction"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "transaction_commit_failed"})
     }
 
     return c.JSON(fiber.Map{"message": "Password reset successfully"})
 }
 
+// sendResetEmail - เข้าคิวอีเมลรีเซ็ตรหัสผ่าน (ส่งจริงโดย NotificationDispatcher)
 func sendResetEmail(email, token string) error {
-    m := gomail.NewMessage()
-    m.SetHeader("From", os.Getenv("SMTP_FROM"))
-    m.SetHeader("To", email)
-    m.SetHeader("Subject", "รีเซ็ตรหัสผ่าน - LocalGuide")
-    
-    resetURL := fmt.Sprintf("http://localhost:3000/auth/reset-password?token=%s", token)
+    resetURL := services.FrontendURL("/auth/reset-password?token=" + token)
     body := fmt.Sprintf(`
         <h2>รีเซ็ตรหัสผ่าน</h2>
         <p>คุณได้ขอรีเซ็ตรหัสผ่าน กรุณาคลิกลิงก์ด้านล่าง:</p>
         <a href="%s">รีเซ็ตรหัสผ่าน</a>
         <p>ลิงก์นี้จะหมดอายุใน 1 ชั่วโมง</p>
     `, resetURL)
-    
-    m.SetBody("text/html", body)
-
-    d := gomail.NewDialer(
-        os.Getenv("SMTP_HOST"),
-        465, // SMTP port
-        os.Getenv("SMTP_USER"),
-        os.Getenv("SMTP_PASS"),
-    )
-
-    return d.DialAndSend(m)
-}
\ No newline at end of file
+
+    queued, err := services.NewNotificationService(config.DB).QueueEmail(email, "รีเซ็ตรหัสผ่าน - LocalGuide", body)
+    if err != nil {
+        return err
+    }
+    // ไม่ได้ตั้งค่า SMTP ลิงก์รีเซ็ตจะไม่ถึงผู้ใช้ ถือว่าส่งไม่สำเร็จ
+    if !queued {
+        return errors.New("SMTP is not configured")
+    }
+    return nil
+}
@@ -13,16 +13,17 @@ func GetProvinces(c *fiber.Ctx) error {
 	var provinces []models.Province
 	if err := config.DB.Find(&provinces).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve provinces",
+			"error": "provinces_get_failed",
 			"details": err.Error(),
 		})
 	}
 
 	if len(provinces) == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "No provinces found",
+			"error": "provinces_not_found",
 		})
 	}
+	localizeContent(c, &provinces)
 	return c.JSON(fiber.Map{
 		"provinces": provinces,
 	})
@@ -32,13 +33,13 @@ func GetProvinceAttractions(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid province ID",
+			"error": "province_id_invalid",
 		})
 	}
 
 	if id <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Province ID must be greater than 0",
+			"error": "province_id_not_positive",
 		})
 	}
 
@@ -46,30 +47,32 @@ func GetProvinceAttractions(c *fiber.Ctx) error {
 	if err := config.DB.First(&province, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Province not found",
+				"error": "province_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve province",
+			"error": "province_get_failed",
 			"details": err.Error(),
 		})
 	}
 
 	// Get tourist attractions for this province
 	var attractions []models.TouristAttraction
-	if err := config.DB.Where("province_id = ?", province.ID).Find(&attractions).Error; err != nil {
+	if err := config.DB.Preload("Image.Variants").Where("province_id = ?", province.ID).Find(&attractions).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve attractions",
+			"error": "attractions_get_failed",
 			"details": err.Error(),
 		})
 	}
 
 	if len(attractions) == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "No attractions found for this province",
+			"error": "province_has_no_attractions",
 		})
 	}
 
+	localizeContent(c, &province)
+	localizeContent(c, &attractions)
 	return c.JSON(fiber.Map{
 		"province":    province.Name,
 		"attractions": attractions,
@@ -0,0 +1,232 @@
+package controllers
+
+import (
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stripe/stripe-go/v76"
+	"gorm.io/gorm"
+)
+
+// ReconciliationLookup - แหล่งข้อมูล Stripe ที่ใช้ตรวจและ resync (เปลี่ยนเป็นตัวจำลองได้ตอนทดสอบ)
+var ReconciliationLookup services.PaymentLookup = services.NewStripeService()
+
+// GetReconciliationIssues - รายการที่ข้อมูลการชำระเงินไม่ตรงกับ Stripe (?status=open|resolved|resynced|ignored|all, ?type=)
+func GetReconciliationIssues(c *fiber.Ctx) error {
+	query := config.DB.Order("detected_at DESC, id DESC")
+	if status := c.Query("status", "open"); status != "all" {
+		query = query.Where("status = ?", status)
+	}
+	if issueType := c.Query("type"); issueType != "" {
+		query = query.Where("issue_type = ?", issueType)
+	}
+
+	var issues []models.ReconciliationIssue
+	if err := query.Limit(200).Find(&issues).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "reconciliation_issues_get_failed",
+		})
+	}
+
+	var open int64
+	config.DB.Model(&models.ReconciliationIssue{}).Where("status = ?", "open").Count(&open)
+
+	return c.JSON(fiber.Map{
+		"issues":     issues,
+		"open_count": open,
+	})
+}
+
+// RunReconciliation - สั่ง reconcile ทันทีโดยไม่ต้องรอรอบถัดไป
+func RunReconciliation(c *fiber.Ctx) error {
+	summary, err := services.NewReconciliationService(config.DB, ReconciliationLookup).RunOnce(time.Now())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "reconciliation_run_failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Reconciliation completed",
+		"summary": summary,
+	})
+}
+
+// resyncPaymentSuccess - บันทึกการชำระเงินที่พลาด webhook ไป payment แยกจ่ายบันทึกทีละส่วนแบ่งที่ Stripe ชำระแล้ว
+func resyncPaymentSuccess(payment *models.TripPayment) error {
+	if payment.PaymentMethod != "stripe_split" {
+		return handlePaymentSuccess(&stripe.PaymentIntent{ID: payment.StripePaymentIntentID, Status: stripe.PaymentIntentStatusSucceeded})
+	}
+
+	var participants []models.TripBookingParticipant
+	if err := config.DB.Where("trip_booking_id = ? AND share_status = ? AND stripe_payment_intent_id <> ''", payment.TripBookingID, "pending").
+		Order("id ASC").Find(&participants).Error; err != nil {
+		return err
+	}
+	for _, p := range participants {
+		snapshot, err := ReconciliationLookup.GetPaymentSnapshot(p.StripePaymentIntentID)
+		if err != nil {
+			return err
+		}
+		if snapshot.Status != "succeeded" {
+			continue
+		}
+		if err := handlePaymentSuccess(&stripe.PaymentIntent{ID: p.StripePaymentIntentID, Status: stripe.PaymentIntentStatusSucceeded, Amount: snapshot.CapturedCents}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResyncReconciliationIssue - แก้ข้อมูลในระบบให้ตรงกับ Stripe (เฉพาะ issue ที่ Resyncable)
+// status_mismatch: บันทึกว่าชำระเงินแล้วเหมือนได้รับ webhook, unrecorded_refund: บันทึก refund ที่ขาดไป
+func ResyncReconciliationIssue(c *fiber.Ctx) error {
+	var issue models.ReconciliationIssue
+	if err := config.DB.First(&issue, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reconciliation_issue_not_found",
+		})
+	}
+
+	if issue.Status != "open" || !issue.Resyncable {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "issue_not_resyncable_open",
+			"status": issue.Status,
+		})
+	}
+
+	var payment models.TripPayment
+	if err := config.DB.First(&payment, issue.TripPaymentID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "payment_not_found",
+		})
+	}
+
+	// ดึงข้อมูลล่าสุดจาก Stripe ก่อนแก้ ไม่เชื่อค่าที่บันทึกไว้ตอนตรวจ
+	snapshot, err := services.PaymentSnapshotFor(config.DB, ReconciliationLookup, &payment)
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "stripe_payment_get_failed",
+		})
+	}
+
+	now := time.Now()
+	switch issue.IssueType {
+	case "status_mismatch":
+		if snapshot.Status != "succeeded" {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":         "payment_not_succeeded",
+				"stripe_status": snapshot.Status,
+			})
+		}
+		if err := resyncPaymentSuccess(&payment); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "payment_update_failed",
+			})
+		}
+
+	case "unrecorded_refund":
+		var refund *services.RefundSnapshot
+		for i := range snapshot.Refunds {
+			if snapshot.Refunds[i].ID == issue.ExternalRef {
+				refund = &snapshot.Refunds[i]
+			}
+		}
+		if refund == nil || refund.Status == "failed" || refund.Status == "canceled" {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "stripe_refund_missing",
+			})
+		}
+
+		var booking models.TripBooking
+		if err := config.DB.First(&booking, payment.TripBookingID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "booking_not_found",
+			})
+		}
+
+		err := config.DB.Transaction(func(tx *gorm.DB) error {
+			var existing int64
+			if err := tx.Model(&models.PaymentRelease{}).Where("transaction_ref = ?", refund.ID).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing > 0 {
+				return nil
+			}
+			_, err := services.RecordStripeRefund(tx, &payment, &booking, *refund, now)
+			return err
+		})
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "refund_record_failed",
+			})
+		}
+		issueInvoices(booking.ID)
+
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "issue_not_resyncable",
+		})
+	}
+
+	// ตรวจ payment ใหม่ issue ที่แก้แล้วจะถูกปิด แล้วบันทึกว่า admin เป็นคน resync
+	config.DB.First(&payment, payment.ID)
+	if _, _, err := services.NewReconciliationService(config.DB, ReconciliationLookup).ReconcilePayment(&payment, now); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "payment_recheck_failed",
+		})
+	}
+
+	adminID := c.Locals("user_id").(uint)
+	if err := config.DB.Model(&issue).Updates(map[string]interface{}{
+		"status":      "resynced",
+		"resolved_at": &now,
+		"resolved_by": adminID,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "issue_update_failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Payment resynced from Stripe",
+		"issue":   issue,
+	})
+}
+
+// IgnoreReconciliationIssue - admin ตรวจแล้วว่าไม่ต้องแก้ (เช่น คืนเงินนอก Stripe)
+func IgnoreReconciliationIssue(c *fiber.Ctx) error {
+	var issue models.ReconciliationIssue
+	if err := config.DB.First(&issue, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reconciliation_issue_not_found",
+		})
+	}
+
+	if issue.Status != "open" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "issue_not_open",
+			"status": issue.Status,
+		})
+	}
+
+	now := time.Now()
+	adminID := c.Locals("user_id").(uint)
+	if err := config.DB.Model(&issue).Updates(map[string]interface{}{
+		"status":      "ignored",
+		"resolved_at": &now,
+		"resolved_by": adminID,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "issue_update_failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Issue ignored",
+		"issue":   issue,
+	})
+}
@@ -0,0 +1,470 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+var reportStatuses = map[string]bool{"pending": true, "investigating": true, "resolved": true, "dismissed": true}
+
+// queryList - แยกค่าใน query string ที่คั่นด้วย comma
+func queryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// appendReportAction - เพิ่มการดำเนินการลงใน TripReport.Actions (JSON array) โดยเก็บค่าเดิมที่ไม่ใช่ JSON ไว้
+func appendReportAction(actions, action string) string {
+	var list []string
+	if actions != "" {
+		if err := json.Unmarshal([]byte(actions), &list); err != nil {
+			list = []string{actions}
+		}
+	}
+	raw, _ := json.Marshal(append(list, action))
+	return string(raw)
+}
+
+func loadTripReport(c *fiber.Ctx) (*models.TripReport, error) {
+	var report models.TripReport
+	if err := config.DB.First(&report, c.Params("id")).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "trip_report_not_found",
+		})
+	}
+	return &report, nil
+}
+
+func isOpenReport(report *models.TripReport) bool {
+	return report.Status == "pending" || report.Status == "investigating"
+}
+
+// linkedReportIDs - id ของรีพอร์ตที่เชื่อมกับรีพอร์ตนี้
+func linkedReportIDs(db *gorm.DB, reportID uint) []uint {
+	var links []models.TripReportLink
+	db.Where("trip_report_id = ? OR related_report_id = ?", reportID, reportID).Find(&links)
+	ids := make([]uint, 0, len(links))
+	for _, l := range links {
+		if l.TripReportID == reportID {
+			ids = append(ids, l.RelatedReportID)
+		} else {
+			ids = append(ids, l.TripReportID)
+		}
+	}
+	return ids
+}
+
+// GetTripReportCase - รายละเอียดเคส: รีพอร์ต, SLA, บันทึกภายใน, รีพอร์ตที่เชื่อมไว้/อื่นๆ ใน booking เดียวกัน และเงินที่ยังค้างในระบบ
+func GetTripReportCase(c *fiber.Ctx) error {
+	var report models.TripReport
+	if err := config.DB.
+		Preload("TripBooking.User").
+		Preload("TripBooking.Guide.User").
+		Preload("Reporter").
+		Preload("ReportedUser").
+		First(&report, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "trip_report_not_found",
+		})
+	}
+
+	var notes []models.TripReportNote
+	if err := config.DB.Preload("Author").Where("trip_report_id = ?", report.ID).
+		Order("created_at ASC").Find(&notes).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "case_notes_get_failed",
+		})
+	}
+
+	linked := map[uint]bool{}
+	for _, id := range linkedReportIDs(config.DB, report.ID) {
+		linked[id] = true
+	}
+	var bookingReports []models.TripReport
+	config.DB.Where("trip_booking_id = ? AND id <> ?", report.TripBookingID, report.ID).
+		Order("created_at ASC").Find(&bookingReports)
+	linkedReports := []models.TripReport{}
+	otherReports := []models.TripReport{}
+	for _, r := range bookingReports {
+		if linked[r.ID] {
+			linkedReports = append(linkedReports, r)
+		} else {
+			otherReports = append(otherReports, r)
+		}
+	}
+
+	response := fiber.Map{
+		"report":          report,
+		"sla":             services.CaseSLA(&report, time.Now()),
+		"notes":           notes,
+		"linked_reports":  linkedReports,
+		"booking_reports": otherReports,
+	}
+
+	var payment models.TripPayment
+	if err := config.DB.Where("trip_booking_id = ?", report.TripBookingID).First(&payment).Error; err == nil {
+		escrow, _ := services.PaymentEscrow(config.DB, &payment)
+		response["payment"] = payment
+		response["escrow"] = escrow
+	}
+
+	return c.JSON(response)
+}
+
+// AssignTripReport - มอบหมายเคสให้ admin ({"admin_id": null} = ยกเลิกการมอบหมาย)
+func AssignTripReport(c *fiber.Ctx) error {
+	report, err := loadTripReport(c)
+	if report == nil {
+		return err
+	}
+
+	var req struct {
+		AdminID *uint `json:"admin_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+
+	updates := map[string]interface{}{"assigned_to": nil, "assigned_at": nil}
+	if req.AdminID != nil {
+		var admin models.User
+		if err := config.DB.Where("id = ? AND role_id = ?", *req.AdminID, 3).First(&admin).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "assignee_not_admin",
+			})
+		}
+		now := time.Now()
+		updates = map[string]interface{}{"assigned_to": admin.ID, "assigned_at": &now}
+	}
+
+	if err := config.DB.Model(report).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "trip_report_assign_failed",
+		})
+	}
+
+	if req.AdminID != nil && *req.AdminID != c.Locals("user_id").(uint) {
+		notify(*req.AdminID, "report_assigned", map[string]interface{}{
+			"ReportID":  report.ID,
+			"BookingID": report.TripBookingID,
+			"Title":     report.Title,
+			"Severity":  report.Severity,
+		}, "trip_report", report.ID)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Trip report assignment updated",
+		"report":  report,
+	})
+}
+
+// AddTripReportNote - เพิ่มบันทึกภายในของเคส
+func AddTripReportNote(c *fiber.Ctx) error {
+	report, err := loadTripReport(c)
+	if report == nil {
+		return err
+	}
+
+	var req struct {
+		Body string `json:"body"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" || utf8.RuneCountInString(req.Body) > 5000 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "note_length_invalid",
+		})
+	}
+
+	note := models.TripReportNote{
+		TripReportID: report.ID,
+		AuthorID:     c.Locals("user_id").(uint),
+		Body:         req.Body,
+	}
+	if err := config.DB.Create(&note).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "note_add_failed",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"note": note,
+	})
+}
+
+// LinkTripReports - เชื่อมรีพอร์ตที่เกี่ยวข้องกันใน booking เดียวกัน (ปิดเคสแล้วรีพอร์ตที่เชื่อมไว้จะปิดไปด้วย)
+func LinkTripReports(c *fiber.Ctx) error {
+	report, err := loadTripReport(c)
+	if report == nil {
+		return err
+	}
+
+	var req struct {
+		RelatedReportID uint `json:"related_report_id"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+	if req.RelatedReportID == report.ID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "report_link_self",
+		})
+	}
+
+	var related models.TripReport
+	if err := config.DB.First(&related, req.RelatedReportID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "related_report_not_found",
+		})
+	}
+	if related.TripBookingID != report.TripBookingID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "report_link_other_booking",
+		})
+	}
+
+	link := models.TripReportLink{
+		TripReportID:    report.ID,
+		RelatedReportID: related.ID,
+		CreatedBy:       c.Locals("user_id").(uint),
+	}
+	if link.TripReportID > link.RelatedReportID {
+		link.TripReportID, link.RelatedReportID = link.RelatedReportID, link.TripReportID
+	}
+	var existing int64
+	config.DB.Model(&models.TripReportLink{}).
+		Where("trip_report_id = ? AND related_report_id = ?", link.TripReportID, link.RelatedReportID).
+		Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "reports_already_linked",
+		})
+	}
+	if err := config.DB.Create(&link).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "reports_link_failed",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"link": link,
+	})
+}
+
+// UnlinkTripReports - ยกเลิกการเชื่อมรีพอร์ต
+func UnlinkTripReports(c *fiber.Ctx) error {
+	reportID, err := c.ParamsInt("id")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "report_id_invalid",
+		})
+	}
+	relatedID, err := c.ParamsInt("relatedId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "related_report_id_invalid",
+		})
+	}
+	if reportID > relatedID {
+		reportID, relatedID = relatedID, reportID
+	}
+
+	result := config.DB.Where("trip_report_id = ? AND related_report_id = ?", reportID, relatedID).
+		Delete(&models.TripReportLink{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "reports_unlink_failed",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "reports_not_linked",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Reports unlinked",
+	})
+}
+
+// ResolveTripReportCase - ปิดเคสพร้อมจัดการเงินของ booking ในขั้นตอนเดียว
+// outcome: none, refund (คืน user ทั้งหมด), release (จ่ายไกด์ทั้งหมด), split (จ่ายไกด์ guide_percent% ที่เหลือคืน user)
+// รีพอร์ตที่เชื่อมไว้และยังไม่ปิดจะถูกปิดไปพร้อมกัน
+func ResolveTripReportCase(c *fiber.Ctx) error {
+	report, err := loadTripReport(c)
+	if report == nil {
+		return err
+	}
+	adminID := c.Locals("user_id").(uint)
+
+	var req struct {
+		Outcome         string   `json:"outcome"`
+		GuidePercent    *float64 `json:"guide_percent"` // split เท่านั้น ค่าเริ่มต้น 25 เหมือน split_cost ของ dispute no-show
+		Status          string   `json:"status"`        // resolved (ค่าเริ่มต้น), dismissed
+		Reason          string   `json:"reason"`        // บันทึกภายใน
+		AdminNotes      string   `json:"admin_notes"`   // คำตอบที่ผู้รีพอร์ตเห็น
+		UnfreezePayouts bool     `json:"unfreeze_payouts"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+
+	if req.Outcome == "" {
+		req.Outcome = "none"
+	}
+	if req.Outcome != "none" && req.Outcome != "refund" && req.Outcome != "release" && req.Outcome != "split" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "outcome_invalid",
+		})
+	}
+	guideShare := 0.25
+	if req.GuidePercent != nil {
+		if *req.GuidePercent < 0 || *req.GuidePercent > 100 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "guide_percent_invalid",
+			})
+		}
+		guideShare = *req.GuidePercent / 100
+	}
+	if req.Status == "" {
+		req.Status = "resolved"
+	}
+	if req.Status != "resolved" && req.Status != "dismissed" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "report_close_status_invalid",
+		})
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "resolution_reason_required",
+		})
+	}
+	if !isOpenReport(report) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "trip_report_closed",
+			"status": report.Status,
+		})
+	}
+
+	var booking models.TripBooking
+	if err := config.DB.First(&booking, report.TripBookingID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "booking_not_found",
+		})
+	}
+
+	now := time.Now()
+	var settlement *services.CaseSettlement
+	var closed []models.TripReport
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if req.UnfreezePayouts {
+			var payment models.TripPayment
+			if err := tx.Where("trip_booking_id = ? AND payouts_frozen = ?", booking.ID, true).First(&payment).Error; err == nil {
+				if _, err := services.UnfreezePayouts(tx, payment.ID, now); err != nil {
+					return err
+				}
+			}
+		}
+
+		if req.Outcome != "none" {
+			var err error
+			if settlement, err = services.SettleCase(tx, &booking, req.Outcome, guideShare, req.Reason, now); err != nil {
+				return err
+			}
+		}
+
+		ids := append([]uint{report.ID}, linkedReportIDs(tx, report.ID)...)
+		if err := tx.Where("id IN ? AND status IN ?", ids, services.OpenReportStatuses).Find(&closed).Error; err != nil {
+			return err
+		}
+		for i := range closed {
+			r := &closed[i]
+			updates := map[string]interface{}{
+				"status":      req.Status,
+				"resolved_at": &now,
+				"actions":     appendReportAction(r.Actions, "case_"+req.Outcome),
+			}
+			if r.ReviewedAt == nil {
+				updates["reviewed_by"] = adminID
+				updates["reviewed_at"] = &now
+			}
+			if r.AssignedTo == nil {
+				updates["assigned_to"] = adminID
+				updates["assigned_at"] = &now
+			}
+			if req.AdminNotes != "" {
+				updates["admin_notes"] = req.AdminNotes
+			}
+			if err := tx.Model(r).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Create(&models.TripReportNote{
+			TripReportID: report.ID,
+			AuthorID:     adminID,
+			Body:         "Case " + req.Status + " (" + req.Outcome + "): " + req.Reason,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, services.ErrNothingToSettle) || errors.Is(err, services.ErrPaymentNotCollected) || errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error":   "payment_settle_failed",
+				"details": err.Error(),
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "case_resolve_failed",
+			"details": err.Error(),
+		})
+	}
+
+	for _, r := range closed {
+		notify(r.ReporterID, "report_updated", map[string]interface{}{
+			"BookingID": r.TripBookingID,
+			"Title":     r.Title,
+			"Status":    req.Status,
+		}, "trip_report", r.ID)
+	}
+	if settlement != nil {
+		data := map[string]interface{}{"BookingID": booking.ID, "Decision": req.Outcome}
+		notify(booking.UserID, "dispute_resolved", data, "trip_booking", booking.ID)
+		notify(bookingCounterpartUserID(config.DB, &booking, "user"), "dispute_resolved", data, "trip_booking", booking.ID)
+	}
+
+	closedIDs := make([]uint, 0, len(closed))
+	for _, r := range closed {
+		closedIDs = append(closedIDs, r.ID)
+	}
+	return c.JSON(fiber.Map{
+		"message":        "Case resolved",
+		"outcome":        req.Outcome,
+		"closed_reports": closedIDs,
+		"settlement":     settlement,
+	})
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"localguide-back/config"
+	"localguide-back/services"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// parseReportFilter - ?from=YYYY-MM-DD&to=YYYY-MM-DD (รวมวัน to) &period=day|week|month &group_by=province|guide &province_id= &guide_id=
+func parseReportFilter(c *fiber.Ctx) (*services.ReportFilter, error) {
+	from, err := parseEarningsDate(c.Query("from"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "from_date_invalid"})
+	}
+	to, err := parseEarningsDate(c.Query("to"))
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to_date_invalid"})
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	period := c.Query("period", "month")
+	if period != "day" && period != "week" && period != "month" {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "period_invalid"})
+	}
+	groupBy := c.Query("group_by")
+	if groupBy != "" && groupBy != "province" && groupBy != "guide" {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "group_by_invalid"})
+	}
+
+	return &services.ReportFilter{
+		From:       from,
+		To:         to,
+		Period:     period,
+		GroupBy:    groupBy,
+		ProvinceID: uint(c.QueryInt("province_id", 0)),
+		GuideID:    uint(c.QueryInt("guide_id", 0)),
+	}, nil
+}
+
+func formatReportAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// GetFinancialReport - GMV, คืนเงิน, จ่ายไกด์ และรายได้แพลตฟอร์ม ตามช่วงเวลา (แยกจังหวัด/ไกด์ได้) ?format=csv ส่งออกทุกแถว
+func GetFinancialReport(c *fiber.Ctx) error {
+	filter, err := parseReportFilter(c)
+	if filter == nil {
+		return err
+	}
+
+	rows, err := services.NewFinancialReportService(config.DB).Report(*filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "financial_report_failed"})
+	}
+
+	if c.Query("format") == "csv" {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"period", "group_id", "group_name", "bookings", "gmv", "refunds", "guide_payouts", "platform_revenue"})
+		for _, r := range rows {
+			w.Write([]string{
+				r.Period,
+				strconv.Itoa(int(r.GroupID)),
+				r.GroupName,
+				strconv.FormatInt(r.Bookings, 10),
+				formatReportAmount(r.GMV),
+				formatReportAmount(r.Refunds),
+				formatReportAmount(r.GuidePayouts),
+				formatReportAmount(r.PlatformRevenue),
+			})
+		}
+		w.Flush()
+
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="financial-report-`+filter.Period+`-`+time.Now().Format("20060102")+`.csv"`)
+		return c.Send(buf.Bytes())
+	}
+
+	page := c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	start := (page - 1) * limit
+	if start > len(rows) {
+		start = len(rows)
+	}
+	end := start + limit
+	if end > len(rows) {
+		end = len(rows)
+	}
+
+	return c.JSON(fiber.Map{
+		"rows":     rows[start:end],
+		"total":    len(rows),
+		"page":     page,
+		"limit":    limit,
+		"period":   filter.Period,
+		"group_by": filter.GroupBy,
+	})
+}
+
+// GetFinancialSummary - ยอดรวมของช่วงเวลา + escrow ที่ยังค้าง + อัตรา dispute/no-show
+func GetFinancialSummary(c *fiber.Ctx) error {
+	filter, err := parseReportFilter(c)
+	if filter == nil {
+		return err
+	}
+
+	summary, err := services.NewFinancialReportService(config.DB).Summary(*filter)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "financial_summary_failed"})
+	}
+
+	if c.Query("format") == "csv" {
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write([]string{"metric", "value"})
+		w.Write([]string{"bookings", strconv.FormatInt(summary.Bookings, 10)})
+		w.Write([]string{"gmv", formatReportAmount(summary.GMV)})
+		w.Write([]string{"refunds", formatReportAmount(summary.Refunds)})
+		w.Write([]string{"guide_payouts", formatReportAmount(summary.GuidePayouts)})
+		w.Write([]string{"platform_revenue", formatReportAmount(summary.PlatformRevenue)})
+		w.Write([]string{"outstanding_escrow", formatReportAmount(summary.OutstandingEscrow)})
+		w.Write([]string{"disputed_bookings", strconv.FormatInt(summary.DisputedBookings, 10)})
+		w.Write([]string{"no_show_bookings", strconv.FormatInt(summary.NoShowBookings, 10)})
+		w.Write([]string{"dispute_rate", strconv.FormatFloat(summary.DisputeRate, 'f', 4, 64)})
+		w.Write([]string{"no_show_rate", strconv.FormatFloat(summary.NoShowRate, 'f', 4, 64)})
+		w.Flush()
+
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="financial-summary-`+time.Now().Format("20060102")+`.csv"`)
+		return c.Send(buf.Bytes())
+	}
+
+	return c.JSON(fiber.Map{"summary": summary})
+}
@@ -3,8 +3,14 @@ package controllers
 import (
 	"localguide-back/config"
 	"localguide-back/models"
+	"localguide-back/services"
+	"log"
+	"math"
+	"sort"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
 )
 
 // GetLanguages returns all available languages
@@ -13,33 +19,172 @@ func GetLanguages(c *fiber.Ctx) error {
 
 	if err := config.DB.Find(&languages).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve languages",
+			"error": "languages_get_failed",
 		})
 	}
 
+	localizeContent(c, &languages)
 	return c.JSON(fiber.Map{
 		"languages": languages,
 	})
 }
 
-// GetTouristAttractions returns all tourist attractions or filtered by province
+// GetTouristAttractions returns all tourist attractions or filtered by province and/or category
 func GetTouristAttractions(c *fiber.Ctx) error {
 	var attractions []models.TouristAttraction
 	provinceID := c.Query("province_id")
+	categoryID := c.Query("category_id")
 
-	query := config.DB.Preload("Province")
+	query := config.DB.Preload("Province").Preload("AttractionCategory").Preload("Image.Variants")
 
 	if provinceID != "" {
 		query = query.Where("province_id = ?", provinceID)
 	}
+	if categoryID != "" {
+		query = query.Where("category_id = ?", categoryID)
+	}
 
 	if err := query.Find(&attractions).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve tourist attractions",
+			"error": "tourist_attractions_get_failed",
 		})
 	}
 
+	localizeContent(c, &attractions)
 	return c.JSON(fiber.Map{
 		"attractions": attractions,
 	})
 }
+
+const (
+	defaultNearbyRadiusKm = 10.0
+	maxNearbyRadiusKm     = 200.0
+	defaultNearbyLimit    = 50
+	maxNearbyLimit        = 200
+)
+
+// nearbyAttraction - สถานที่ท่องเที่ยวพร้อมระยะเส้นตรงจากจุดที่ค้นหา
+type nearbyAttraction struct {
+	models.TouristAttraction
+	DistanceKm float64 `json:"distance_km"`
+}
+
+// parseNearQuery อ่านพิกัดและรัศมี (กิโลเมตร) จาก query คืนรหัสข้อความ error ("" = ผ่าน)
+func parseNearQuery(c *fiber.Ctx, latKey, lngKey, radiusKey string) (lat, lng, radiusKm float64, msg string) {
+	lat, errLat := strconv.ParseFloat(c.Query(latKey), 64)
+	lng, errLng := strconv.ParseFloat(c.Query(lngKey), 64)
+	if errLat != nil || errLng != nil {
+		return 0, 0, 0, "near_coordinates_required"
+	}
+	if !validCoordinates(lat, lng) {
+		return 0, 0, 0, "coordinates_invalid"
+	}
+	radiusKm = defaultNearbyRadiusKm
+	if v := c.Query(radiusKey); v != "" {
+		r, err := strconv.ParseFloat(v, 64)
+		if err != nil || r <= 0 || r > maxNearbyRadiusKm || math.IsNaN(r) {
+			return 0, 0, 0, "near_radius_out_of_range"
+		}
+		radiusKm = r
+	}
+	return lat, lng, radiusKm, ""
+}
+
+// attractionsWithin - สถานที่ท่องเที่ยวที่มีพิกัดและอยู่ในรัศมี เรียงจากใกล้ไปไกล
+// กรองด้วยกรอบพิกัดใน SQL ก่อน แล้วคำนวณระยะจริงด้วย haversine
+func attractionsWithin(query *gorm.DB, lat, lng, radiusKm float64) ([]nearbyAttraction, error) {
+	minLat, maxLat, minLng, maxLng := services.BoundingBox(lat, lng, radiusKm)
+	var candidates []models.TouristAttraction
+	if err := query.
+		Where("latitude IS NOT NULL AND longitude IS NOT NULL").
+		Where("latitude BETWEEN ? AND ? AND longitude BETWEEN ? AND ?", minLat, maxLat, minLng, maxLng).
+		Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	nearby := make([]nearbyAttraction, 0, len(candidates))
+	for _, a := range candidates {
+		d := services.DistanceKm(lat, lng, *a.Latitude, *a.Longitude)
+		if d <= radiusKm {
+			nearby = append(nearby, nearbyAttraction{TouristAttraction: a, DistanceKm: math.Round(d*100) / 100})
+		}
+	}
+	sort.SliceStable(nearby, func(i, j int) bool { return nearby[i].DistanceKm < nearby[j].DistanceKm })
+	return nearby, nil
+}
+
+// GetNearbyAttractions - สถานที่ท่องเที่ยวใกล้พิกัด ?lat=&lng=&radius= (กม., ค่าเริ่มต้น 10 สูงสุด 200)
+// &category_id= &limit= (ค่าเริ่มต้น 50) เรียงจากใกล้ไปไกล พร้อม distance_km
+func GetNearbyAttractions(c *fiber.Ctx) error {
+	lat, lng, radiusKm, msg := parseNearQuery(c, "lat", "lng", "radius")
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+	limit := c.QueryInt("limit", defaultNearbyLimit)
+	if limit <= 0 || limit > maxNearbyLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "limit_invalid"})
+	}
+
+	query := config.DB.Preload("Province").Preload("AttractionCategory").Preload("Image.Variants")
+	if categoryID := c.Query("category_id"); categoryID != "" {
+		query = query.Where("category_id = ?", categoryID)
+	}
+	attractions, err := attractionsWithin(query, lat, lng, radiusKm)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "tourist_attractions_get_failed",
+		})
+	}
+	if len(attractions) > limit {
+		attractions = attractions[:limit]
+	}
+
+	localizeContent(c, &attractions)
+	return c.JSON(fiber.Map{
+		"attractions": attractions,
+		"radius_km":   radiusKm,
+	})
+}
+
+// UploadAttractionImage - Admin อัปโหลดรูปหลักของสถานที่ท่องเที่ยว (form field: image) แทนที่รูปเดิม
+// รูปถูกลบ metadata และสร้างรูปย่อ thumbnail/medium/large
+func UploadAttractionImage(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "attraction_id_invalid",
+		})
+	}
+
+	var attraction models.TouristAttraction
+	if err := config.DB.First(&attraction, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "tourist_attraction_not_found",
+		})
+	}
+
+	upload, err := saveUpload(c, "image", "attraction_image")
+	if upload == nil {
+		return err
+	}
+
+	previousURL := attraction.ImageURL
+	attraction.ImageURL = upload.URL
+	attraction.ImageUploadID = &upload.ID
+	if err := config.DB.Model(&attraction).Select("image_url", "image_upload_id").Updates(&attraction).Error; err != nil {
+		uploadService().Delete(upload)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "attraction_image_update_failed",
+		})
+	}
+	if previousURL != "" && previousURL != upload.URL {
+		if err := uploadService().DeleteByURL(previousURL); err != nil {
+			log.Printf("failed to delete previous image of attraction #%d: %v", attraction.ID, err)
+		}
+	}
+
+	config.DB.Preload("Province").Preload("Image.Variants").First(&attraction, attraction.ID)
+	return c.JSON(fiber.Map{
+		"message":    "Attraction image uploaded successfully",
+		"attraction": attraction,
+	})
+}
@@ -0,0 +1,158 @@
+package controllers
+
+import (
+	"localguide-back/config"
+	"localguide-back/models"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// GetReviewModerationQueue - รีวิวที่รอ admin ตรวจ (?status=flagged (ค่าเริ่มต้น) | hidden | all)
+func GetReviewModerationQueue(c *fiber.Ctx) error {
+	query := preloadReviewImages(config.DB).Preload("User").Preload("Guide.User").
+		Order("flag_count DESC, updated_at DESC")
+	switch c.Query("status", "flagged") {
+	case "flagged":
+		query = query.Where("flag_count > 0")
+	case "hidden":
+		query = query.Where("is_hidden = ?", true)
+	case "all":
+		query = query.Where("flag_count > 0 OR is_hidden = ?", true)
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "review_status_filter_invalid",
+		})
+	}
+
+	var reviews []models.TripReview
+	if err := query.Limit(200).Find(&reviews).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "reviews_get_failed",
+		})
+	}
+
+	ids := make([]uint, 0, len(reviews))
+	for _, r := range reviews {
+		ids = append(ids, r.ID)
+	}
+	var flags []models.ReviewFlag
+	if len(ids) > 0 {
+		config.DB.Preload("Reporter").Where("trip_review_id IN ?", ids).Order("created_at ASC").Find(&flags)
+	}
+	flagsByReview := map[uint][]models.ReviewFlag{}
+	for _, f := range flags {
+		flagsByReview[f.TripReviewID] = append(flagsByReview[f.TripReviewID], f)
+	}
+
+	items := make([]fiber.Map, 0, len(reviews))
+	for _, r := range reviews {
+		items = append(items, fiber.Map{
+			"review": r,
+			"flags":  flagsByReview[r.ID],
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"reviews": items,
+	})
+}
+
+// moderateReview - ซ่อน/คืนรีวิว ปิดการแจ้งที่ค้าง และคำนวณคะแนนไกด์ใหม่ใน transaction เดียวกัน
+func moderateReview(c *fiber.Ctx, hide bool) error {
+	var input struct {
+		Reason string `json:"reason"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+	input.Reason = strings.TrimSpace(input.Reason)
+	if input.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "reason_required",
+		})
+	}
+
+	var review models.TripReview
+	if err := config.DB.First(&review, c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "review_not_found",
+		})
+	}
+	if hide && review.IsHidden {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "review_already_hidden",
+		})
+	}
+	if !hide && !review.IsHidden && review.FlagCount == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "review_not_hidden_or_flagged",
+		})
+	}
+
+	// ซ่อน = การแจ้งถูกต้อง (upheld), คืน/คงไว้ = ยกเลิกการแจ้ง (dismissed)
+	flagStatus := "dismissed"
+	if hide {
+		flagStatus = "upheld"
+	}
+	now := time.Now()
+	adminID := c.Locals("user_id").(uint)
+	wasHidden := review.IsHidden
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ReviewFlag{}).
+			Where("trip_review_id = ? AND status = ?", review.ID, "pending").
+			Updates(map[string]interface{}{
+				"status":      flagStatus,
+				"resolved_by": adminID,
+				"resolved_at": &now,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&review).Updates(map[string]interface{}{
+			"is_hidden":     hide,
+			"hidden_reason": input.Reason,
+			"hidden_at":     &now,
+			"moderated_by":  adminID,
+			"flag_count":    0,
+		}).Error; err != nil {
+			return err
+		}
+		return updateGuideRating(tx, review.GuideID)
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "review_moderate_failed",
+		})
+	}
+
+	config.DB.First(&review, review.ID)
+	if hide != wasHidden {
+		notifType := "review_restored"
+		if hide {
+			notifType = "review_hidden"
+		}
+		notify(review.UserID, notifType, map[string]interface{}{
+			"BookingID": review.TripBookingID,
+			"Reason":    input.Reason,
+		}, "trip_review", review.ID)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Review moderated",
+		"review":  review,
+	})
+}
+
+// HideReview - admin ซ่อนรีวิวพร้อมเหตุผล (ไม่แสดงและไม่นับคะแนนไกด์)
+func HideReview(c *fiber.Ctx) error {
+	return moderateReview(c, true)
+}
+
+// RestoreReview - admin คืนรีวิวที่ถูกซ่อน หรือยกเลิกการแจ้งของรีวิวที่ยังแสดงอยู่
+func RestoreReview(c *fiber.Ctx) error {
+	return moderateReview(c, false)
+}
@@ -1,33 +1,147 @@
 package controllers
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"localguide-back/config"
 	"localguide-back/models"
+	"localguide-back/services"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+const (
+	maxReviewCommentLength = 2000
+	maxReviewImages        = 5
+	maxReviewCaptionLength = 300
+)
+
+// reviewImageInput - รูปประกอบรีวิวที่ client ส่งมา
+type reviewImageInput struct {
+	URL     string `json:"url"`
+	Caption string `json:"caption"`
+}
+
+// parseReviewImages - images รับได้ทั้ง array ของ {url, caption}, array ของ URL
+// และข้อความ JSON array ของ URL (รูปแบบเดิมก่อนมีตาราง trip_review_images)
+func parseReviewImages(raw json.RawMessage) ([]reviewImageInput, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		var legacy string
+		if err := json.Unmarshal(raw, &legacy); err != nil {
+			return nil, err
+		}
+		raw = bytes.TrimSpace([]byte(legacy))
+		if len(raw) == 0 {
+			return nil, nil
+		}
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+	images := make([]reviewImageInput, 0, len(items))
+	for _, item := range items {
+		var image reviewImageInput
+		if err := json.Unmarshal(item, &image.URL); err != nil {
+			if err := json.Unmarshal(item, &image); err != nil {
+				return nil, err
+			}
+		}
+		images = append(images, image)
+	}
+	return images, nil
+}
+
+// normalizeReviewContent - ตัดช่องว่างและตรวจความยาวของ comment และตรวจรูปประกอบ
+// รูปต้องเป็นไฟล์ที่อัปโหลดผ่าน /uploads หรือ http/https ไม่เกิน maxReviewImages รูป
+// ไฟล์ที่มีใน uploads ต้องเป็น review_image ของผู้เขียนรีวิวเอง (ผูก UploadID เพื่อให้ได้รูปย่อ)
+func normalizeReviewContent(userID uint, comment string, rawImages json.RawMessage) (string, []models.TripReviewImage, error) {
+	comment = strings.TrimSpace(comment)
+	if utf8.RuneCountInString(comment) > maxReviewCommentLength {
+		return "", nil, errors.New("review_comment_too_long")
+	}
+
+	inputs, err := parseReviewImages(rawImages)
+	if err != nil {
+		return "", nil, errors.New("review_images_invalid")
+	}
+	if len(inputs) > maxReviewImages {
+		return "", nil, errors.New("review_images_too_many")
+	}
+	svc := uploadService()
+	images := make([]models.TripReviewImage, 0, len(inputs))
+	for i, in := range inputs {
+		url := strings.TrimSpace(in.URL)
+		if !isAttachmentURL(url) {
+			return "", nil, errors.New("review_image_url_invalid")
+		}
+		caption := strings.TrimSpace(in.Caption)
+		if utf8.RuneCountInString(caption) > maxReviewCaptionLength {
+			return "", nil, errors.New("review_caption_too_long")
+		}
+		image := models.TripReviewImage{URL: url, Caption: caption, Position: i}
+		if upload, err := svc.FindByURL(url); err == nil {
+			if upload.OwnerID != userID || upload.Purpose != "review_image" {
+				return "", nil, errors.New("review_image_not_owned")
+			}
+			image.UploadID = &upload.ID
+		}
+		images = append(images, image)
+	}
+	return comment, images, nil
+}
+
+// preloadReviewImages - โหลดรูปประกอบรีวิวตามลำดับพร้อมรูปย่อ
+func preloadReviewImages(q *gorm.DB) *gorm.DB {
+	return q.Preload("Images", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position")
+	}).Preload("Images.Upload.Variants")
+}
+
+// replaceReviewImages - แทนที่รูปประกอบรีวิวทั้งหมดด้วยรายการใหม่
+func replaceReviewImages(tx *gorm.DB, reviewID uint, images []models.TripReviewImage) error {
+	if err := tx.Unscoped().Where("trip_review_id = ?", reviewID).Delete(&models.TripReviewImage{}).Error; err != nil {
+		return err
+	}
+	if len(images) == 0 {
+		return nil
+	}
+	for i := range images {
+		images[i].TripReviewID = reviewID
+	}
+	return tx.Create(&images).Error
+}
+
 // CreateReview - User สร้างรีวิวให้กับไกด์หลังทริปเสร็จ
 func CreateReview(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
 	var input struct {
-		TripBookingID       uint    `json:"trip_booking_id"`
-		Rating              float64 `json:"rating"`
-		Comment             string  `json:"comment"`
-		ServiceRating       float64 `json:"service_rating"`
-		KnowledgeRating     float64 `json:"knowledge_rating"`
-		CommunicationRating float64 `json:"communication_rating"`
-		PunctualityRating   float64 `json:"punctuality_rating"`
-		Images              string  `json:"images"`
-		IsAnonymous         bool    `json:"is_anonymous"`
+		TripBookingID       uint            `json:"trip_booking_id"`
+		Rating              float64         `json:"rating"`
+		Comment             string          `json:"comment"`
+		ServiceRating       float64         `json:"service_rating"`
+		KnowledgeRating     float64         `json:"knowledge_rating"`
+		CommunicationRating float64         `json:"communication_rating"`
+		PunctualityRating   float64         `json:"punctuality_rating"`
+		Images              json.RawMessage `json:"images"`
+		IsAnonymous         bool            `json:"is_anonymous"`
 	}
 
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+			"error": "invalid_request_body",
 		})
 	}
 
@@ -38,7 +152,14 @@ func CreateReview(c *fiber.Ctx) error {
 		input.CommunicationRating < 1 || input.CommunicationRating > 5 ||
 		input.PunctualityRating < 1 || input.PunctualityRating > 5 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Ratings must be between 1 and 5",
+			"error": "ratings_invalid",
+		})
+	}
+
+	comment, images, err := normalizeReviewContent(userID, input.Comment, input.Images)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
@@ -46,20 +167,20 @@ func CreateReview(c *fiber.Ctx) error {
 	var booking models.TripBooking
 	if err := config.DB.Preload("TripOffer.Guide").First(&booking, input.TripBookingID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Booking not found",
+			"error": "booking_not_found",
 		})
 	}
 
 	if booking.UserID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You are not authorized to review this booking",
+			"error": "review_forbidden",
 		})
 	}
 
 	// ตรวจสอบว่าทริปต้องเสร็จสิ้นแล้ว
 	if booking.Status != "trip_completed" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Can only review completed trips",
+			"error": "review_trip_not_completed",
 		})
 	}
 
@@ -67,7 +188,7 @@ func CreateReview(c *fiber.Ctx) error {
 	var existingReview models.TripReview
 	if err := config.DB.Where("trip_booking_id = ?", input.TripBookingID).First(&existingReview).Error; err == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "You have already reviewed this trip",
+			"error": "already_reviewed",
 		})
 	}
 
@@ -77,27 +198,38 @@ func CreateReview(c *fiber.Ctx) error {
 		UserID:              userID,
 		GuideID:             booking.TripOffer.GuideID,
 		Rating:              input.Rating,
-		Comment:             input.Comment,
+		Comment:             comment,
 		ServiceRating:       input.ServiceRating,
 		KnowledgeRating:     input.KnowledgeRating,
 		CommunicationRating: input.CommunicationRating,
 		PunctualityRating:   input.PunctualityRating,
-		Images:              input.Images,
+		Images:              images,
 		IsAnonymous:         input.IsAnonymous,
 		IsVerified:          true,
 	}
 
-	if err := config.DB.Create(&review).Error; err != nil {
+	// สร้างรีวิวและคำนวณคะแนนรวมของไกด์ใน transaction เดียวกัน
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&review).Error; err != nil {
+			return err
+		}
+		return updateGuideRating(tx, review.GuideID)
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create review",
+			"error": "review_create_failed",
 		})
 	}
 
-	// อัพเดทคะแนนเฉลี่ยของไกด์
-	updateGuideRating(booking.TripOffer.GuideID)
+	var guide models.Guide
+	if err := config.DB.Select("id", "user_id").First(&guide, review.GuideID).Error; err == nil {
+		notify(guide.UserID, "review_posted", map[string]interface{}{
+			"BookingID": booking.ID,
+			"Rating":    review.Rating,
+		}, "trip_review", review.ID)
+	}
 
 	// โหลด review พร้อม relations
-	config.DB.Preload("User").Preload("Guide.User").Preload("TripBooking").First(&review, review.ID)
+	preloadReviewImages(config.DB).Preload("User").Preload("Guide.User").Preload("TripBooking").First(&review, review.ID)
 
 	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
 		"message": "Review created successfully",
@@ -109,17 +241,25 @@ func CreateReview(c *fiber.Ctx) error {
 func GetGuideReviews(c *fiber.Ctx) error {
 	guideID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || guideID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid guide ID"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "guide_id_invalid"})
+	}
+
+	// แสดงเฉพาะรีวิวที่ไม่ถูกซ่อน ?verified_only=true แสดงเฉพาะรีวิวจากการจองจริง
+	visible := func() *gorm.DB {
+		q := config.DB.Model(&models.TripReview{}).Where("guide_id = ? AND is_hidden = ?", guideID, false)
+		if c.QueryBool("verified_only") {
+			q = q.Where("is_verified = ?", true)
+		}
+		return q
 	}
 
 	var reviews []models.TripReview
-	query := config.DB.Preload("User").Preload("TripBooking").
-		Where("guide_id = ?", guideID).
+	query := preloadReviewImages(visible()).Preload("User").Preload("TripBooking").
 		Order("created_at DESC")
 
 	if err := query.Find(&reviews).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch reviews",
+			"error": "reviews_fetch_failed",
 		})
 	}
 
@@ -138,39 +278,53 @@ func GetGuideReviews(c *fiber.Ctx) error {
 		OneStar             int64
 	}
 
-	config.DB.Model(&models.TripReview{}).Where("guide_id = ?", guideID).Count(&stats.TotalReviews)
+	var total int64
+	visible().Count(&total)
 	
-	if stats.TotalReviews > 0 {
-		config.DB.Model(&models.TripReview{}).Where("guide_id = ?", guideID).
+	if total > 0 {
+		// Scan เขียนทับทั้ง struct จึงกำหนด TotalReviews หลัง Scan
+		visible().
 			Select("AVG(rating) as average_rating, AVG(service_rating) as average_service, " +
 				"AVG(knowledge_rating) as average_knowledge, AVG(communication_rating) as average_communication, " +
 				"AVG(punctuality_rating) as average_punctuality").
 			Scan(&stats)
+		stats.TotalReviews = total
 		
-		config.DB.Model(&models.TripReview{}).Where("guide_id = ? AND rating >= 4.5", guideID).Count(&stats.FiveStars)
-		config.DB.Model(&models.TripReview{}).Where("guide_id = ? AND rating >= 3.5 AND rating < 4.5", guideID).Count(&stats.FourStars)
-		config.DB.Model(&models.TripReview{}).Where("guide_id = ? AND rating >= 2.5 AND rating < 3.5", guideID).Count(&stats.ThreeStars)
-		config.DB.Model(&models.TripReview{}).Where("guide_id = ? AND rating >= 1.5 AND rating < 2.5", guideID).Count(&stats.TwoStars)
-		config.DB.Model(&models.TripReview{}).Where("guide_id = ? AND rating < 1.5", guideID).Count(&stats.OneStar)
+		visible().Where("rating >= 4.5").Count(&stats.FiveStars)
+		visible().Where("rating >= 3.5 AND rating < 4.5").Count(&stats.FourStars)
+		visible().Where("rating >= 2.5 AND rating < 3.5").Count(&stats.ThreeStars)
+		visible().Where("rating >= 1.5 AND rating < 2.5").Count(&stats.TwoStars)
+		visible().Where("rating < 1.5").Count(&stats.OneStar)
 	}
 
 	return c.JSON(fiber.Map{
 		"reviews": reviews,
 		"stats":   stats,
+		"summary": guideRatingSummary(uint(guideID)),
 	})
 }
 
+// guideRatingSummary - คะแนนรวมที่บันทึกไว้ของไกด์ (ยังไม่มีรีวิว = คะแนนตั้งต้น)
+func guideRatingSummary(guideID uint) models.GuideRatingSummary {
+	var summary models.GuideRatingSummary
+	if err := config.DB.Where("guide_id = ?", guideID).Limit(1).Find(&summary).Error; err != nil || summary.GuideID == 0 {
+		mean, _ := services.RatingPrior()
+		return models.GuideRatingSummary{GuideID: guideID, BayesianScore: mean}
+	}
+	return summary
+}
+
 // GetMyReviews - User ดูรีวิวที่เขียนเอง
 func GetMyReviews(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
 	var reviews []models.TripReview
-	if err := config.DB.Preload("Guide.User").Preload("TripBooking").
+	if err := preloadReviewImages(config.DB).Preload("Guide.User").Preload("TripBooking").
 		Where("user_id = ?", userID).
 		Order("created_at DESC").
 		Find(&reviews).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch reviews",
+			"error": "reviews_fetch_failed",
 		})
 	}
 
@@ -184,35 +338,35 @@ func UpdateReview(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	reviewID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || reviewID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid review ID"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "review_id_invalid"})
 	}
 
 	var input struct {
-		Rating              float64 `json:"rating"`
-		Comment             string  `json:"comment"`
-		ServiceRating       float64 `json:"service_rating"`
-		KnowledgeRating     float64 `json:"knowledge_rating"`
-		CommunicationRating float64 `json:"communication_rating"`
-		PunctualityRating   float64 `json:"punctuality_rating"`
-		Images              string  `json:"images"`
+		Rating              float64         `json:"rating"`
+		Comment             string          `json:"comment"`
+		ServiceRating       float64         `json:"service_rating"`
+		KnowledgeRating     float64         `json:"knowledge_rating"`
+		CommunicationRating float64         `json:"communication_rating"`
+		PunctualityRating   float64         `json:"punctuality_rating"`
+		Images              json.RawMessage `json:"images"`
 	}
 
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+			"error": "invalid_request_body",
 		})
 	}
 
 	var review models.TripReview
 	if err := config.DB.First(&review, reviewID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Review not found",
+			"error": "review_not_found",
 		})
 	}
 
 	if review.UserID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You can only edit your own reviews",
+			"error": "review_edit_own_only",
 		})
 	}
 
@@ -223,28 +377,39 @@ func UpdateReview(c *fiber.Ctx) error {
 		input.CommunicationRating < 1 || input.CommunicationRating > 5 ||
 		input.PunctualityRating < 1 || input.PunctualityRating > 5 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Ratings must be between 1 and 5",
+			"error": "ratings_invalid",
+		})
+	}
+
+	comment, images, err := normalizeReviewContent(userID, input.Comment, input.Images)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
 	review.Rating = input.Rating
-	review.Comment = input.Comment
+	review.Comment = comment
 	review.ServiceRating = input.ServiceRating
 	review.KnowledgeRating = input.KnowledgeRating
 	review.CommunicationRating = input.CommunicationRating
 	review.PunctualityRating = input.PunctualityRating
-	review.Images = input.Images
 
-	if err := config.DB.Save(&review).Error; err != nil {
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&review).Error; err != nil {
+			return err
+		}
+		if err := replaceReviewImages(tx, review.ID, images); err != nil {
+			return err
+		}
+		return updateGuideRating(tx, review.GuideID)
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update review",
+			"error": "review_update_failed",
 		})
 	}
 
-	// อัพเดทคะแนนเฉลี่ยของไกด์
-	updateGuideRating(review.GuideID)
-
-	config.DB.Preload("User").Preload("Guide.User").Preload("TripBooking").First(&review, review.ID)
+	preloadReviewImages(config.DB).Preload("User").Preload("Guide.User").Preload("TripBooking").First(&review, review.ID)
 
 	return c.JSON(fiber.Map{
 		"message": "Review updated successfully",
@@ -257,33 +422,33 @@ func DeleteReview(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	reviewID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || reviewID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid review ID"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "review_id_invalid"})
 	}
 
 	var review models.TripReview
 	if err := config.DB.First(&review, reviewID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Review not found",
+			"error": "review_not_found",
 		})
 	}
 
 	if review.UserID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You can only delete your own reviews",
+			"error": "review_delete_own_only",
 		})
 	}
 
-	guideID := review.GuideID
-
-	if err := config.DB.Delete(&review).Error; err != nil {
+	if err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&review).Error; err != nil {
+			return err
+		}
+		return updateGuideRating(tx, review.GuideID)
+	}); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete review",
+			"error": "review_delete_failed",
 		})
 	}
 
-	// อัพเดทคะแนนเฉลี่ยของไกด์
-	updateGuideRating(guideID)
-
 	return c.JSON(fiber.Map{
 		"message": "Review deleted successfully",
 	})
@@ -294,7 +459,7 @@ func GuideRespondToReview(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	reviewID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || reviewID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid review ID"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "review_id_invalid"})
 	}
 
 	var input struct {
@@ -303,7 +468,7 @@ func GuideRespondToReview(c *fiber.Ctx) error {
 
 	if err := c.BodyParser(&input); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
+			"error": "invalid_request_body",
 		})
 	}
 
@@ -311,21 +476,21 @@ func GuideRespondToReview(c *fiber.Ctx) error {
 	var guide models.Guide
 	if err := config.DB.Where("user_id = ?", userID).First(&guide).Error; err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only guides can respond to reviews",
+			"error": "guide_only_respond_reviews",
 		})
 	}
 
 	var review models.TripReview
 	if err := config.DB.First(&review, reviewID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Review not found",
+			"error": "review_not_found",
 		})
 	}
 
 	// ตรวจสอบว่ารีวิวนี้เป็นของไกด์คนนี้
 	if review.GuideID != guide.ID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You can only respond to your own reviews",
+			"error": "review_respond_own_only",
 		})
 	}
 
@@ -335,11 +500,11 @@ func GuideRespondToReview(c *fiber.Ctx) error {
 
 	if err := config.DB.Save(&review).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to save response",
+			"error": "response_save_failed",
 		})
 	}
 
-	config.DB.Preload("User").Preload("Guide.User").Preload("TripBooking").First(&review, review.ID)
+	preloadReviewImages(config.DB).Preload("User").Preload("Guide.User").Preload("TripBooking").First(&review, review.ID)
 
 	return c.JSON(fiber.Map{
 		"message": "Response saved successfully",
@@ -347,46 +512,168 @@ func GuideRespondToReview(c *fiber.Ctx) error {
 	})
 }
 
-// MarkReviewHelpful - ผู้ใช้กด helpful บนรีวิว
-func MarkReviewHelpful(c *fiber.Ctx) error {
+// loadVisibleReview - โหลดรีวิวที่ยังแสดงอยู่ (รีวิวที่ถูกซ่อนถือว่าไม่พบ)
+func loadVisibleReview(c *fiber.Ctx) (*models.TripReview, error) {
 	reviewID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || reviewID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid review ID"})
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "review_id_invalid"})
 	}
 
 	var review models.TripReview
-	if err := config.DB.First(&review, reviewID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Review not found",
+	if err := config.DB.Where("is_hidden = ?", false).First(&review, reviewID).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "review_not_found",
 		})
 	}
+	return &review, nil
+}
 
-	review.HelpfulCount++
-	if err := config.DB.Save(&review).Error; err != nil {
+// syncHelpfulCount - นับ HelpfulCount ใหม่จากจำนวน vote จริง
+func syncHelpfulCount(tx *gorm.DB, reviewID uint) (int, error) {
+	var count int64
+	if err := tx.Model(&models.ReviewHelpfulVote{}).Where("trip_review_id = ?", reviewID).Count(&count).Error; err != nil {
+		return 0, err
+	}
+	if err := tx.Model(&models.TripReview{}).Where("id = ?", reviewID).Update("helpful_count", count).Error; err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// MarkReviewHelpful - ผู้ใช้กด helpful บนรีวิว (หนึ่งครั้งต่อคน กดซ้ำไม่เพิ่ม)
+func MarkReviewHelpful(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	review, err := loadVisibleReview(c)
+	if review == nil {
+		return err
+	}
+
+	if review.UserID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "review_helpful_own",
+		})
+	}
+
+	var count int
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		vote := models.ReviewHelpfulVote{TripReviewID: review.ID, UserID: userID}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&vote).Error; err != nil {
+			return err
+		}
+		count, err = syncHelpfulCount(tx, review.ID)
+		return err
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update review",
+			"error": "review_update_failed",
 		})
 	}
 
 	return c.JSON(fiber.Map{
 		"message":       "Marked as helpful",
-		"helpful_count": review.HelpfulCount,
+		"helpful_count": count,
+		"voted":         true,
+	})
+}
+
+// UnmarkReviewHelpful - ยกเลิก helpful ที่เคยกด
+func UnmarkReviewHelpful(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	review, err := loadVisibleReview(c)
+	if review == nil {
+		return err
+	}
+
+	var count int
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("trip_review_id = ? AND user_id = ?", review.ID, userID).Delete(&models.ReviewHelpfulVote{}).Error; err != nil {
+			return err
+		}
+		count, err = syncHelpfulCount(tx, review.ID)
+		return err
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "review_update_failed",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":       "Helpful vote removed",
+		"helpful_count": count,
+		"voted":         false,
 	})
 }
 
-// Helper function to update guide's average rating
-func updateGuideRating(guideID uint) error {
-	var avgRating float64
-	if err := config.DB.Model(&models.TripReview{}).
-		Where("guide_id = ?", guideID).
-		Select("COALESCE(AVG(rating), 0)").
-		Scan(&avgRating).Error; err != nil {
+// FlagReview - ผู้ใช้/ไกด์แจ้งรีวิวที่ไม่เหมาะสมให้ admin ตรวจ
+func FlagReview(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	review, err := loadVisibleReview(c)
+	if review == nil {
 		return err
 	}
 
-	return config.DB.Model(&models.Guide{}).
-		Where("id = ?", guideID).
-		Update("rating", avgRating).Error
+	var input struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request_body",
+		})
+	}
+
+	switch input.Reason {
+	case "spam", "offensive", "fake", "personal_info", "off_topic", "other":
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "flag_reason_invalid",
+		})
+	}
+	if review.UserID == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "review_flag_own",
+		})
+	}
+
+	var existing int64
+	config.DB.Model(&models.ReviewFlag{}).Where("trip_review_id = ? AND reporter_id = ?", review.ID, userID).Count(&existing)
+	if existing > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "review_already_flagged",
+		})
+	}
+
+	flag := models.ReviewFlag{
+		TripReviewID: review.ID,
+		ReporterID:   userID,
+		Reason:       input.Reason,
+		Details:      strings.TrimSpace(input.Details),
+		Status:       "pending",
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&flag).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.TripReview{}).Where("id = ?", review.ID).
+			Update("flag_count", gorm.Expr("flag_count + 1")).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "review_flag_failed",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Review flagged for moderation",
+		"flag":    flag,
+	})
+}
+
+// Helper function to update guide's average rating และคะแนนรวม (เฉพาะรีวิวที่ยังแสดงอยู่)
+func updateGuideRating(tx *gorm.DB, guideID uint) error {
+	_, err := services.RecomputeGuideRating(tx, guideID)
+	return err
 }
 
 // GetReviewableBookings - ดู bookings ที่ยังไม่ได้รีวิว
@@ -402,7 +689,7 @@ func GetReviewableBookings(c *fiber.Ctx) error {
 		Where("trip_bookings.user_id = ? AND trip_bookings.status = ? AND trip_reviews.id IS NULL", userID, "completed").
 		Find(&bookings).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch bookings",
+			"error": "bookings_fetch_failed",
 		})
 	}
 
@@ -3,6 +3,7 @@ package controllers
 import (
 	"encoding/json"
 	"fmt"
+	"log"
 	"localguide-back/config"
 	"localguide-back/models"
 	"os"
@@ -23,7 +24,7 @@ func StripeWebhook(c *fiber.Ctx) error {
 	event, err := webhook.ConstructEvent(payload, sig, endpointSecret)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid signature",
+			"error": "signature_invalid",
 		})
 	}
 
@@ -33,14 +34,14 @@ func StripeWebhook(c *fiber.Ctx) error {
 		var paymentIntent stripe.PaymentIntent
 		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Failed to parse payment intent",
+				"error": "payment_intent_parse_failed",
 			})
 		}
 		
 		// อัปเดตการชำระเงินในฐานข้อมูล
 		if err := handlePaymentSuccess(&paymentIntent); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to process payment success",
+				"error": "payment_success_process_failed",
 			})
 		}
 
@@ -48,14 +49,14 @@ func StripeWebhook(c *fiber.Ctx) error {
 		var paymentIntent stripe.PaymentIntent
 		if err := json.Unmarshal(event.Data.Raw, &paymentIntent); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Failed to parse payment intent",
+				"error": "payment_intent_parse_failed",
 			})
 		}
 		
 		// จัดการเมื่อการชำระเงินล้มเหลว
 		if err := handlePaymentFailed(&paymentIntent); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to process payment failure",
+				"error": "payment_failure_process_failed",
 			})
 		}
 
@@ -70,6 +71,17 @@ func StripeWebhook(c *fiber.Ctx) error {
 
 // handlePaymentSuccess - จัดการเมื่อการชำระเงินสำเร็จ
 func handlePaymentSuccess(paymentIntent *stripe.PaymentIntent) error {
+	// PaymentIntent ของส่วนแบ่ง (กรณีแยกจ่าย)
+	var participant models.TripBookingParticipant
+	if err := config.DB.Where("stripe_payment_intent_id = ?", paymentIntent.ID).First(&participant).Error; err == nil {
+		tx := config.DB.Begin()
+		defer tx.Rollback()
+		if err := settleParticipantShare(tx, &participant, string(paymentIntent.Status), paymentIntent.Amount); err != nil {
+			return err
+		}
+		return tx.Commit().Error
+	}
+
 	// ค้นหา payment record จาก PaymentIntent ID
 	var payment models.TripPayment
 	if err := config.DB.Where("stripe_payment_intent_id = ?", paymentIntent.ID).First(&payment).Error; err != nil {
@@ -92,17 +104,32 @@ func handlePaymentSuccess(paymentIntent *stripe.PaymentIntent) error {
 		return fmt.Errorf("booking not found: %w", err)
 	}
 
+	wasPaid := booking.PaymentStatus == "paid"
 	booking.PaymentStatus = "paid"
 	booking.Status = "paid"
 	if err := config.DB.Save(&booking).Error; err != nil {
 		return fmt.Errorf("failed to update booking: %w", err)
 	}
 
+	// webhook อาจมาซ้ำ หรือ confirm ไปแล้วจากหน้าเว็บ แจ้งแค่ครั้งแรก
+	if !wasPaid {
+		issueInvoices(booking.ID)
+		if err := notifyBookingPaid(config.DB, &booking, payment.TotalAmount); err != nil {
+			log.Printf("failed to notify booking #%d paid: %v", booking.ID, err)
+		}
+	}
+
 	return nil
 }
 
 // handlePaymentFailed - จัดการเมื่อการชำระเงินล้มเหลว
 func handlePaymentFailed(paymentIntent *stripe.PaymentIntent) error {
+	// ส่วนแบ่งที่จ่ายไม่สำเร็จยังคงเป็น pending ผู้ร่วมเดินทางสร้าง PaymentIntent ใหม่ได้
+	var participant models.TripBookingParticipant
+	if err := config.DB.Where("stripe_payment_intent_id = ?", paymentIntent.ID).First(&participant).Error; err == nil {
+		return nil
+	}
+
 	// ค้นหา payment record
 	var payment models.TripPayment
 	if err := config.DB.Where("stripe_payment_intent_id = ?", paymentIntent.ID).First(&payment).Error; err != nil {
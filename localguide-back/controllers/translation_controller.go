@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"errors"
+	"localguide-back/config"
+	"localguide-back/middleware"
+	"localguide-back/services"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// localizeContent - แทนข้อความใน v ด้วยคำแปลตามภาษาของ request (?lang, ภาษาของผู้ใช้, Accept-Language)
+// แปลไม่สำเร็จแสดงค่าเดิม
+func localizeContent(c *fiber.Ctx, v interface{}) {
+	lang := middleware.RequestLanguage(c)
+	if err := services.NewTranslationService(config.DB).Localize(lang, v); err != nil {
+		log.Printf("localize %s response failed: %v", c.Path(), err)
+	}
+}
+
+type translationInput struct {
+	Language string            `json:"language"`
+	Fields   map[string]string `json:"fields"` // field → คำแปล (ค่าว่าง = ลบคำแปล)
+}
+
+// saveTranslations - บันทึกคำแปลแล้วตอบคำแปลทั้งหมดของรายการ
+func saveTranslations(c *fiber.Ctx, entityType string, id uint, input translationInput) error {
+	svc := services.NewTranslationService(config.DB)
+	err := svc.Set(entityType, id, input.Language, input.Fields)
+	switch {
+	case errors.Is(err, services.ErrUnsupportedLanguage):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "language_invalid"})
+	case errors.Is(err, services.ErrUnknownTranslationField):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "translation_field_invalid",
+			"fields": services.TranslationFields(entityType),
+		})
+	case errors.Is(err, services.ErrTranslationTooLong):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "translation_too_long"})
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "translation_target_not_found"})
+	case err != nil:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "translations_update_failed"})
+	}
+
+	translations, err := svc.Get(entityType, id)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "translations_get_failed"})
+	}
+	return c.JSON(fiber.Map{
+		"message":      "Translations updated",
+		"translations": translations,
+	})
+}
+
+// translationTarget - อ่าน :type และ :id ของ route คืน id 0 เมื่อส่ง response error ไปแล้ว
+func translationTarget(c *fiber.Ctx) (string, uint, error) {
+	entityType := c.Params("type")
+	if services.TranslationFields(entityType) == nil {
+		return "", 0, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "translation_type_invalid"})
+	}
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return "", 0, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "translation_id_invalid"})
+	}
+	return entityType, uint(id), nil
+}
+
+// AdminGetTranslations - Admin ดูคำแปลของ province, attraction, category, language, role หรือ guide
+func AdminGetTranslations(c *fiber.Ctx) error {
+	entityType, id, err := translationTarget(c)
+	if id == 0 {
+		return err
+	}
+	translations, err := services.NewTranslationService(config.DB).Get(entityType, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "translation_target_not_found"})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "translations_get_failed"})
+	}
+	return c.JSON(fiber.Map{
+		"fields":       services.TranslationFields(entityType),
+		"translations": translations,
+	})
+}
+
+// AdminSetTranslations - Admin บันทึกคำแปลภาษาเดียว body: {"language": "en", "fields": {"name": "..."}}
+func AdminSetTranslations(c *fiber.Ctx) error {
+	entityType, id, err := translationTarget(c)
+	if id == 0 {
+		return err
+	}
+	var input translationInput
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	return saveTranslations(c, entityType, id, input)
+}
+
+// GetMyGuideTranslations - ไกด์ดูคำแปล bio/description ของโปรไฟล์ตัวเอง
+func GetMyGuideTranslations(c *fiber.Ctx) error {
+	guide, err := currentGuide(c)
+	if guide == nil {
+		return err
+	}
+	translations, err := services.NewTranslationService(config.DB).Get("guide", guide.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "translations_get_failed"})
+	}
+	return c.JSON(fiber.Map{
+		"translations": translations,
+	})
+}
+
+// SetMyGuideTranslations - ไกด์บันทึก bio/description ภาษาอื่น body: {"language": "en", "bio": "...", "description": "..."}
+func SetMyGuideTranslations(c *fiber.Ctx) error {
+	guide, err := currentGuide(c)
+	if guide == nil {
+		return err
+	}
+	var input struct {
+		Language    string  `json:"language"`
+		Bio         *string `json:"bio"`
+		Description *string `json:"description"`
+	}
+	if err := c.BodyParser(&input); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	fields := map[string]string{}
+	if input.Bio != nil {
+		fields["bio"] = *input.Bio
+	}
+	if input.Description != nil {
+		fields["description"] = *input.Description
+	}
+	return saveTranslations(c, "guide", guide.ID, translationInput{Language: input.Language, Fields: fields})
+}
@@ -4,6 +4,8 @@ import (
 	"localguide-back/config"
 	"localguide-back/models"
 	"localguide-back/services"
+	"log"
+	"math"
 	"strconv"
 	"time"
 
@@ -16,7 +18,7 @@ func CreateTripPayment(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Booking ID must be a positive integer",
+			"error":   "booking_id_invalid",
 		})
 	}
 
@@ -24,11 +26,11 @@ func CreateTripPayment(c *fiber.Ctx) error {
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error":   "Booking not found",
+				"error":   "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get booking",
+			"error":   "booking_get_failed",
 		})
 	}
 
@@ -36,21 +38,28 @@ func CreateTripPayment(c *fiber.Ctx) error {
 	var user models.User
 	if err := config.DB.First(&user, booking.UserID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get user details",
+			"error":   "user_details_get_failed",
 		})
 	}
 
 	var authUser models.AuthUser
 	if err := config.DB.First(&authUser, user.AuthUserID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get user email",
+			"error":   "user_email_get_failed",
 		})
 	}
 
 	// Check if payment already exists
 	if booking.PaymentStatus == "paid" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Payment already completed",
+			"error":   "payment_already_completed",
+		})
+	}
+
+	// booking ที่แยกจ่ายต้องจ่ายผ่านส่วนแบ่งของแต่ละคน
+	if booking.SplitPayment {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":   "pay_your_share",
 		})
 	}
 
@@ -59,29 +68,37 @@ func CreateTripPayment(c *fiber.Ctx) error {
 	paymentIntent, err := stripeService.CreatePaymentIntent(&booking, authUser.Email)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to create payment intent: " + err.Error(),
+			"error":   "payment_intent_create_failed",
+			"details": err.Error(),
 		})
 	}
 
-	// Create payment record
-	now := time.Now()
+	// Create payment record (ปัดงวดแรกเป็นสตางค์ งวดที่สองคือส่วนที่เหลือ ยอดรวมจึงตรงกับ TotalAmount)
+	firstPayment := math.Round(booking.TotalAmount*0.5*100) / 100
 	payment := models.TripPayment{
 		TripBookingID:         uint(bookingID),
-		PaymentNumber:         "PAY" + strconv.Itoa(int(now.Unix())),
 		TransactionID:         paymentIntent.ID,
 		StripePaymentIntentID: paymentIntent.ID,
 		StripeClientSecret:    paymentIntent.ClientSecret,
 		StripeStatus:         string(paymentIntent.Status),
 		TotalAmount:          booking.TotalAmount,
-		FirstPayment:         booking.TotalAmount * 0.5,  // 50% for first release
-		SecondPayment:        booking.TotalAmount * 0.5,  // 50% for second release
+		FirstPayment:         firstPayment,  // 50% for first release
+		SecondPayment:        math.Round((booking.TotalAmount-firstPayment)*100) / 100,  // 50% for second release
 		PaymentMethod:        "stripe_card",
 		Status:               "pending", // รอการชำระเงินจาก user
 	}
 
-	if err := config.DB.Create(&payment).Error; err != nil {
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		number, err := services.NextDocumentNumber(tx, "PAY", time.Now())
+		if err != nil {
+			return err
+		}
+		payment.PaymentNumber = number
+		return tx.Create(&payment).Error
+	})
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to create payment record",
+			"error":   "payment_record_create_failed",
 		})
 	}
 
@@ -99,7 +116,7 @@ func GetTripPayment(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Booking ID must be a positive integer",
+			"error":   "booking_id_invalid",
 		})
 	}
 
@@ -107,11 +124,11 @@ func GetTripPayment(c *fiber.Ctx) error {
 	if err := config.DB.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error":   "Payment not found",
+				"error":   "payment_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get payment",
+			"error":   "payment_get_failed",
 		})
 	}
 
@@ -128,7 +145,7 @@ func GetTripBookings(c *fiber.Ctx) error {
 	var user models.User
 	if err := config.DB.Preload("Role").First(&user, userID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get user info",
+			"error": "user_info_get_failed",
 		})
 	}
 
@@ -159,7 +176,7 @@ func GetTripBookings(c *fiber.Ctx) error {
 
 	if err := query.Order("created_at DESC").Find(&bookings).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get bookings",
+			"error": "bookings_get_failed",
 		})
 	}
 
@@ -193,6 +210,13 @@ func GetTripBookings(c *fiber.Ctx) error {
 			"user_id":          booking.UserID,
 			"guide_id":         booking.GuideID,
 			"start_date":       booking.StartDate,
+			"end_date":         bookingEndDate(&booking),
+			"days":             bookingDays(&booking),
+			"daily_payout":     booking.DailyPayout,
+			"early_completion_requested_by": booking.EarlyCompletionRequestedBy,
+			"early_completion_requested_at": booking.EarlyCompletionRequestedAt,
+			"early_completion_reason":       booking.EarlyCompletionReason,
+			"early_completion_agreed_at":    booking.EarlyCompletionAgreedAt,
 			"total_amount":     booking.TotalAmount,
 			"status":           booking.Status,
 			"payment_status":   booking.PaymentStatus,
@@ -256,7 +280,7 @@ func GetTripBookingByID(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil || id <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Booking ID must be a positive integer",
+			"error": "booking_id_invalid",
 		})
 	}
 
@@ -265,16 +289,19 @@ func GetTripBookingByID(c *fiber.Ctx) error {
 	var booking models.TripBooking
 	if err := config.DB.Preload("TripOffer.TripRequire.Province").
 		Preload("TripOffer.Guide.User").
+		Preload("TripOffer.Stops", func(db *gorm.DB) *gorm.DB {
+			return db.Order("day, position")
+		}).
 		Preload("User").
 		Preload("Guide.User").
 		First(&booking, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Booking not found",
+				"error": "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get booking",
+			"error": "booking_get_failed",
 		})
 	}
 
@@ -293,9 +320,15 @@ func GetTripBookingByID(c *fiber.Ctx) error {
 		}
 	}
 	
+	// ผู้ร่วมเดินทางที่รับคำเชิญแล้วดู booking ได้
+	isCoTraveller := false
 	if !isOwner && !isGuideOwner {
+		isCoTraveller = canViewBookingParticipants(config.DB, &booking, userID) == "participant"
+	}
+
+	if !isOwner && !isGuideOwner && !isCoTraveller {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You can only view your own bookings",
+			"error": "booking_view_own_only",
 		})
 	}
 
@@ -314,7 +347,7 @@ func GetTripBookingByID(c *fiber.Ctx) error {
 
 	// Get trip reviews
 	var reviews []models.TripReview
-	config.DB.Where("trip_booking_id = ?", booking.ID).Find(&reviews)
+	preloadReviewImages(config.DB).Where("trip_booking_id = ?", booking.ID).Find(&reviews)
 
 	// Check if the current user has reviewed this booking
 	hasReview := false
@@ -335,6 +368,13 @@ func GetTripBookingByID(c *fiber.Ctx) error {
 		"user_id":          booking.UserID,
 		"guide_id":         booking.GuideID,
 		"start_date":       booking.StartDate,
+		"end_date":         bookingEndDate(&booking),
+		"days":             bookingDays(&booking),
+		"daily_payout":     booking.DailyPayout,
+		"early_completion_requested_by": booking.EarlyCompletionRequestedBy,
+		"early_completion_requested_at": booking.EarlyCompletionRequestedAt,
+		"early_completion_reason":       booking.EarlyCompletionReason,
+		"early_completion_agreed_at":    booking.EarlyCompletionAgreedAt,
 		"total_amount":     booking.TotalAmount,
 		"status":           booking.Status,
 		"payment_status":   booking.PaymentStatus,
@@ -360,6 +400,8 @@ func GetTripBookingByID(c *fiber.Ctx) error {
 		"offer_title":           booking.TripOffer.Title,
 		"offer_description":     booking.TripOffer.Description,
 		"offer_itinerary":       booking.TripOffer.Itinerary,
+		"offer_stops":           booking.TripOffer.Stops,
+		"offer_estimated_distance_km": booking.TripOffer.EstimatedDistanceKm,
 		"offer_included_services": booking.TripOffer.IncludedServices,
 		"offer_excluded_services": booking.TripOffer.ExcludedServices,
 
@@ -400,6 +442,20 @@ func GetTripBookingByID(c *fiber.Ctx) error {
 	// Add reports
 	enrichedBooking["reports"] = reports
 
+	// Add participants
+	var participants []models.TripBookingParticipant
+	config.DB.Where("trip_booking_id = ? AND invite_status <> ?", booking.ID, "declined").Order("is_organizer DESC, id ASC").Find(&participants)
+	enrichedBooking["participants"] = participants
+	enrichedBooking["split_payment"] = booking.SplitPayment
+
+	// Add meeting point and start check-in evidence
+	var startCheckIns []models.TripStartCheckIn
+	config.DB.Where("trip_booking_id = ?", booking.ID).Order("created_at ASC").Find(&startCheckIns)
+	enrichedBooking["meeting_latitude"] = booking.MeetingLatitude
+	enrichedBooking["meeting_longitude"] = booking.MeetingLongitude
+	enrichedBooking["meeting_note"] = booking.MeetingNote
+	enrichedBooking["start_check_ins"] = startCheckIns
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"booking": enrichedBooking,
 	})
@@ -410,7 +466,7 @@ func ConfirmTripPayment(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Booking ID must be a positive integer",
+			"error":   "booking_id_invalid",
 		})
 	}
 
@@ -420,7 +476,7 @@ func ConfirmTripPayment(c *fiber.Ctx) error {
 
 	if err := c.BodyParser(&requestData); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Invalid request data",
+			"error":   "invalid_request_data",
 		})
 	}
 
@@ -429,7 +485,8 @@ func ConfirmTripPayment(c *fiber.Ctx) error {
 	paymentIntent, err := stripeService.ConfirmPayment(requestData.PaymentIntentID)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Payment not completed: " + err.Error(),
+			"error":   "payment_not_completed",
+			"details": err.Error(),
 		})
 	}
 
@@ -437,7 +494,7 @@ func ConfirmTripPayment(c *fiber.Ctx) error {
 	var payment models.TripPayment
 	if err := config.DB.Where("trip_booking_id = ? AND stripe_payment_intent_id = ?", bookingID, requestData.PaymentIntentID).First(&payment).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error":   "Payment record not found",
+			"error":   "payment_record_not_found",
 		})
 	}
 
@@ -449,7 +506,7 @@ func ConfirmTripPayment(c *fiber.Ctx) error {
 
 	if err := config.DB.Save(&payment).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update payment status",
+			"error":   "payment_status_update_failed",
 		})
 	}
 
@@ -457,18 +514,26 @@ func ConfirmTripPayment(c *fiber.Ctx) error {
 	var booking models.TripBooking
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get booking",
+			"error":   "booking_get_failed",
 		})
 	}
 
+	wasPaid := booking.PaymentStatus == "paid"
 	booking.PaymentStatus = "paid"
 	booking.Status = "paid"
 	if err := config.DB.Save(&booking).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update booking status",
+			"error":   "booking_status_update_failed",
 		})
 	}
 
+	if !wasPaid {
+		issueInvoices(booking.ID)
+		if err := notifyBookingPaid(config.DB, &booking, payment.TotalAmount); err != nil {
+			log.Printf("failed to notify booking #%d paid: %v", booking.ID, err)
+		}
+	}
+
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
 		"message": "Payment confirmed successfully",
 		"payment": payment,
@@ -0,0 +1,354 @@
+package controllers
+
+import (
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// dateOnly - ตัดเวลาออก เหลือแค่วันที่ (ใช้ location ของค่าที่ส่งเข้ามา)
+func dateOnly(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// bookingDays - จำนวนวันของทริป (booking เก่าที่ไม่มีค่า ให้ถือว่าเป็นทริปวันเดียว)
+func bookingDays(booking *models.TripBooking) int {
+	if booking.Days < 1 {
+		return 1
+	}
+	return booking.Days
+}
+
+// bookingEndDate - วันสุดท้ายของทริป
+func bookingEndDate(booking *models.TripBooking) time.Time {
+	if booking.EndDate != nil {
+		return *booking.EndDate
+	}
+	return booking.StartDate.AddDate(0, 0, bookingDays(booking)-1)
+}
+
+// hasTripEndDateArrived - ถึงวันสุดท้ายของทริปแล้วหรือยัง
+func hasTripEndDateArrived(booking *models.TripBooking, now time.Time) bool {
+	end := bookingEndDate(booking)
+	return !dateOnly(now.In(end.Location())).Before(dateOnly(end))
+}
+
+// tripDayNumber - วันนี้เป็นวันที่เท่าไหร่ของทริป (เริ่มที่ 1)
+func tripDayNumber(booking *models.TripBooking, now time.Time) int {
+	start := dateOnly(booking.StartDate)
+	today := dateOnly(now.In(booking.StartDate.Location()))
+	return int(math.Round(today.Sub(start).Hours()/24)) + 1
+}
+
+// bookingParticipantRole - คืนค่า "user" หรือ "guide" ถ้า userID เป็นผู้เกี่ยวข้องกับ booking นี้
+func bookingParticipantRole(db *gorm.DB, booking *models.TripBooking, userID uint) string {
+	if booking.UserID == userID {
+		return "user"
+	}
+	var guide models.Guide
+	if err := db.Select("id", "user_id").First(&guide, booking.GuideID).Error; err == nil && guide.UserID == userID {
+		return "guide"
+	}
+	return ""
+}
+
+// bookingCounterpartUserID - user ID ของอีกฝ่ายใน booking
+func bookingCounterpartUserID(db *gorm.DB, booking *models.TripBooking, role string) uint {
+	if role == "guide" {
+		return booking.UserID
+	}
+	var guide models.Guide
+	if err := db.Select("id", "user_id").First(&guide, booking.GuideID).Error; err != nil {
+		return 0
+	}
+	return guide.UserID
+}
+
+// releasedDailyAmount - ยอดที่จ่ายรายวันให้ไกด์ไปแล้ว (รวมที่ค้างไว้เพราะระงับการจ่าย)
+func releasedDailyAmount(db *gorm.DB, paymentID uint) float64 {
+	var total float64
+	db.Model(&models.PaymentRelease{}).
+		Where("trip_payment_id = ? AND release_type = ? AND status IN ?", paymentID, "daily_payment", []string{"processed", "pending"}).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total)
+	return total
+}
+
+func loadBookingForParticipant(c *fiber.Ctx) (*models.TripBooking, string, error) {
+	bookingID, err := strconv.Atoi(c.Params("id"))
+	if err != nil || bookingID <= 0 {
+		return nil, "", c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "booking_id_invalid",
+		})
+	}
+
+	var booking models.TripBooking
+	if err := config.DB.First(&booking, bookingID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, "", c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "booking_not_found",
+			})
+		}
+		return nil, "", c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "booking_get_failed",
+		})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	role := bookingParticipantRole(config.DB, &booking, userID)
+	if role == "" {
+		return nil, "", c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "not_participant",
+		})
+	}
+
+	return &booking, role, nil
+}
+
+// CheckInTripDay - user หรือไกด์เช็คอินวันของทริป เมื่อครบทั้งสองฝ่ายถือว่าวันนั้นเสร็จ
+// ถ้า booking เลือกจ่ายรายวัน จะจ่ายงวดที่สองตามสัดส่วนของวันนั้นให้ไกด์
+func CheckInTripDay(c *fiber.Ctx) error {
+	booking, role, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+
+	var req struct {
+		DayNumber int    `json:"day_number"` // ไม่ส่งมา = วันนี้
+		Notes     string `json:"notes"`
+	}
+	_ = c.BodyParser(&req)
+
+	if booking.Status != "trip_started" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "trip_not_started_check_in",
+		})
+	}
+
+	now := time.Now()
+	today := tripDayNumber(booking, now)
+	day := req.DayNumber
+	if day == 0 {
+		day = today
+	}
+	if day < 1 || day > bookingDays(booking) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "day_outside_trip",
+			"days":  bookingDays(booking),
+		})
+	}
+	if day > today {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "check_in_future_day",
+		})
+	}
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	var checkIn models.TripDayCheckIn
+	if err := tx.Where("trip_booking_id = ? AND day_number = ?", booking.ID, day).First(&checkIn).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "check_in_get_failed"})
+		}
+		checkIn = models.TripDayCheckIn{
+			TripBookingID: booking.ID,
+			DayNumber:     day,
+			TripDate:      dateOnly(booking.StartDate).AddDate(0, 0, day-1),
+		}
+	}
+
+	if role == "user" {
+		if checkIn.UserCheckedInAt != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "already_checked_in"})
+		}
+		checkIn.UserCheckedInAt = &now
+	} else {
+		if checkIn.GuideCheckedInAt != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "already_checked_in"})
+		}
+		checkIn.GuideCheckedInAt = &now
+	}
+	if req.Notes != "" {
+		checkIn.Notes = req.Notes
+	}
+
+	var release *models.PaymentRelease
+	if checkIn.UserCheckedInAt != nil && checkIn.GuideCheckedInAt != nil {
+		checkIn.CompletedAt = &now
+
+		if booking.DailyPayout {
+			var payment models.TripPayment
+			if err := tx.Where("trip_booking_id = ?", booking.ID).First(&payment).Error; err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "payment_not_found"})
+			}
+
+			// วันสุดท้ายให้ ConfirmTripComplete จ่ายส่วนที่เหลือ
+			if day < bookingDays(booking) {
+				dailyRelease := models.PaymentRelease{
+					TripPaymentID: payment.ID,
+					ReleaseType:   "daily_payment",
+					Amount:        math.Round(payment.SecondPayment/float64(bookingDays(booking))*100) / 100,
+					RecipientType: "guide",
+					RecipientID:   booking.GuideID,
+					Reason:        "trip_day_completed",
+					ScheduledAt:   now,
+					Notes:         "Daily payout for day " + strconv.Itoa(day),
+				}
+				if _, err := services.QueueGuidePayout(tx, &payment, &dailyRelease); err != nil {
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "payment_release_create_failed"})
+				}
+				checkIn.PaymentReleaseID = &dailyRelease.ID
+				release = &dailyRelease
+			}
+		}
+	}
+
+	if err := tx.Save(&checkIn).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "check_in_save_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "check_in_save_failed"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":  "Checked in for day " + strconv.Itoa(day),
+		"check_in": checkIn,
+		"release":  release,
+	})
+}
+
+// GetTripDayCheckIns - ดูการเช็คอินรายวันของ booking
+func GetTripDayCheckIns(c *fiber.Ctx) error {
+	booking, _, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+
+	var checkIns []models.TripDayCheckIn
+	if err := config.DB.Where("trip_booking_id = ?", booking.ID).Order("day_number ASC").Find(&checkIns).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "check_ins_get_failed"})
+	}
+
+	return c.JSON(fiber.Map{
+		"check_ins":  checkIns,
+		"days":       bookingDays(booking),
+		"start_date": booking.StartDate,
+		"end_date":   bookingEndDate(booking),
+	})
+}
+
+// RequestEarlyCompletion - ฝ่ายใดฝ่ายหนึ่งขอจบทริปก่อนวันสุดท้าย (ต้องให้อีกฝ่ายยอมรับ)
+func RequestEarlyCompletion(c *fiber.Ctx) error {
+	booking, role, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.BodyParser(&req)
+
+	if booking.Status != "trip_started" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "trip_not_started_early_completion",
+		})
+	}
+
+	now := time.Now()
+	if hasTripEndDateArrived(booking, now) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "early_completion_not_needed",
+		})
+	}
+
+	userID := c.Locals("user_id").(uint)
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	if err := tx.Model(booking).Updates(map[string]interface{}{
+		"early_completion_requested_by": userID,
+		"early_completion_requested_at": &now,
+		"early_completion_reason":       req.Reason,
+		"early_completion_agreed_at":    nil,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "booking_update_failed"})
+	}
+
+	counterpart := bookingCounterpartUserID(tx, booking, role)
+	if counterpart != 0 {
+		data := map[string]interface{}{"BookingID": booking.ID, "Reason": req.Reason}
+		if _, err := services.NewNotificationService(tx).Send(counterpart, "early_completion_requested", data, "trip_booking", booking.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "other_party_notify_failed"})
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "booking_update_failed"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Early completion requested. Waiting for the other party to agree.",
+		"booking": booking,
+	})
+}
+
+// AgreeEarlyCompletion - อีกฝ่ายยอมรับการจบทริปก่อนกำหนด หลังจากนั้น user ยืนยันจบทริปได้
+func AgreeEarlyCompletion(c *fiber.Ctx) error {
+	booking, _, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+
+	if booking.Status != "trip_started" || booking.EarlyCompletionRequestedBy == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "no_early_completion_request",
+		})
+	}
+
+	userID := c.Locals("user_id").(uint)
+	if *booking.EarlyCompletionRequestedBy == userID {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "early_completion_needs_agreement",
+		})
+	}
+
+	if booking.EarlyCompletionAgreedAt != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "early_completion_already_agreed",
+		})
+	}
+
+	now := time.Now()
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	if err := tx.Model(booking).Update("early_completion_agreed_at", &now).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "booking_update_failed"})
+	}
+
+	data := map[string]interface{}{"BookingID": booking.ID}
+	if _, err := services.NewNotificationService(tx).Send(*booking.EarlyCompletionRequestedBy, "early_completion_agreed", data, "trip_booking", booking.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "other_party_notify_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "booking_update_failed"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Early completion agreed. The traveller can now confirm trip completion.",
+		"booking": booking,
+	})
+}
@@ -3,14 +3,145 @@ package controllers
 import (
 	"localguide-back/config"
 	"localguide-back/models"
+	"localguide-back/services"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gofiber/fiber/v2"
 	"gorm.io/gorm"
 )
 
+// offerInput - ข้อมูลของ offer ที่ใช้ร่วมกันระหว่างการสร้างเองและการสร้างจาก template
+type offerInput struct {
+	Title            string
+	Description      string
+	Itinerary        string
+	IncludedServices string
+	ExcludedServices string
+	OfferNotes       string
+	TotalPrice       float64
+	PriceBreakdown   string
+}
+
+func offerPriceInRange(tripRequire *models.TripRequire, price float64) bool {
+	return price >= tripRequire.MinPrice && price <= tripRequire.MaxPrice
+}
+
+func priceOutOfRangeResponse(c *fiber.Ctx, tripRequire *models.TripRequire) error {
+	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+		"error": "price_out_of_range",
+		"requested_range": map[string]float64{
+			"min": tripRequire.MinPrice,
+			"max": tripRequire.MaxPrice,
+		},
+	})
+}
+
+func isTripRequireAcceptingOffers(tripRequire *models.TripRequire) bool {
+	// ให้ TripRequire รับ offer ได้ทั้ง open และ in_review
+	return tripRequire.Status != "assigned" && tripRequire.Status != "completed" && tripRequire.Status != "cancelled"
+}
+
+// loadOfferableTripRequire - ตรวจสอบว่า TripRequire ยังเปิดรับ offer และไกด์ยังไม่มี offer ที่ยังไม่ถอน
+func loadOfferableTripRequire(c *fiber.Ctx, tx *gorm.DB, guideID, tripRequireID uint) (*models.TripRequire, error) {
+	var tripRequire models.TripRequire
+	if err := tx.First(&tripRequire, tripRequireID).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "trip_requirement_not_found",
+		})
+	}
+
+	if !isTripRequireAcceptingOffers(&tripRequire) {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "trip_requirement_closed",
+		})
+	}
+
+	// ตรวจสอบว่า Guide มี offer ที่ยังไม่ถอนอยู่แล้วหรือยัง (ถอนแล้วเสนอใหม่ได้)
+	var existingOffer models.TripOffer
+	if err := tx.Where("trip_require_id = ? AND guide_id = ? AND status <> ?", tripRequireID, guideID, "withdrawn").First(&existingOffer).Error; err == nil {
+		return nil, c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "offer_already_made",
+		})
+	}
+
+	return &tripRequire, nil
+}
+
+// createDraftOffer - สร้าง TripOffer และ TripOfferQuotation ในสถานะ draft
+func createDraftOffer(tx *gorm.DB, guideID uint, tripRequire *models.TripRequire, in offerInput) (*models.TripOffer, *models.TripOfferQuotation, error) {
+	offer := models.TripOffer{
+		TripRequireID:    tripRequire.ID,
+		GuideID:          guideID,
+		Title:            in.Title,
+		Description:      in.Description,
+		Itinerary:        in.Itinerary,
+		IncludedServices: in.IncludedServices,
+		ExcludedServices: in.ExcludedServices,
+		Status:           "draft",
+		OfferNotes:       in.OfferNotes,
+	}
+	if err := tx.Create(&offer).Error; err != nil {
+		return nil, nil, err
+	}
+
+	quotationNumber, err := services.NextDocumentNumber(tx, "QT", time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	quotation := models.TripOfferQuotation{
+		TripOfferID:     offer.ID,
+		Version:         1,
+		TotalPrice:      in.TotalPrice,
+		PriceBreakdown:  in.PriceBreakdown,
+		QuotationNumber: quotationNumber,
+		Status:          "draft",
+	}
+	if err := tx.Create(&quotation).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return &offer, &quotation, nil
+}
+
+// markOfferSent - ส่ง offer ให้ user: อัปเดตสถานะ offer/quotation, TripRequire และแจ้ง user
+func markOfferSent(tx *gorm.DB, offer *models.TripOffer, quotation *models.TripOfferQuotation, tripRequire *models.TripRequire) error {
+	now := time.Now()
+	if err := tx.Model(offer).Updates(map[string]interface{}{
+		"status":  "sent",
+		"sent_at": &now,
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Model(quotation).Updates(map[string]interface{}{
+		"status":  "sent",
+		"sent_at": &now,
+	}).Error; err != nil {
+		return err
+	}
+
+	// เปลี่ยนสถานะ TripRequire เป็น in_review ถ้ายังเป็น open
+	if tripRequire.Status == "open" {
+		if err := tx.Model(tripRequire).Update("status", "in_review").Error; err != nil {
+			return err
+		}
+	}
+
+	_, err := services.NewNotificationService(tx).Send(tripRequire.UserID, "offer_received", map[string]interface{}{
+		"OfferTitle": offer.Title,
+		"TripTitle":  tripRequire.Title,
+	}, "trip_offer", offer.ID)
+	return err
+}
+
 // CreateTripOffer - Guide สร้าง offer สำหรับ TripRequire
+// ส่ง save_as_draft = true เพื่อบันทึกเป็นร่างไว้ก่อน (ยังไม่ตรวจช่วงราคาจนกว่าจะส่ง)
 func CreateTripOffer(c *fiber.Ctx) error {
 	var req struct {
 		TripRequireID    uint    `json:"trip_require_id" validate:"required"`
@@ -25,11 +156,13 @@ func CreateTripOffer(c *fiber.Ctx) error {
 		PaymentTerms     string  `json:"payment_terms"`
 		OfferNotes       string  `json:"offer_notes"`
 		ValidDays        int     `json:"valid_days" validate:"min=1,max=30"` // วันที่ offer หมดอายุ
+		SaveAsDraft      bool    `json:"save_as_draft"`
+		Stops            []offerStopInput `json:"stops"` // จุดแวะตามกำหนดการ (ไม่บังคับ)
 	}
 
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to parse request body",
+			"error": "request_body_parse_failed",
 			"details": err.Error(),
 		})
 	}
@@ -39,91 +172,122 @@ func CreateTripOffer(c *fiber.Ctx) error {
 	var guide models.Guide
 	if err := config.DB.Where("user_id = ?", userID).First(&guide).Error; err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only guides can create offers",
+			"error": "guide_only_create_offers",
 		})
 	}
 
-	// ตรวจสอบว่า TripRequire มีอยู่และยังเปิดรับ offer อยู่
-	var tripRequire models.TripRequire
-	if err := config.DB.First(&tripRequire, req.TripRequireID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Trip requirement not found",
-		})
+	stops, distanceKm, msg := buildOfferStops(config.DB, req.Stops)
+	if msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
 	}
 
-	// ให้ TripRequire รับ offer ได้ทั้ง open และ in_review
-	if tripRequire.Status == "assigned" || tripRequire.Status == "completed" || tripRequire.Status == "cancelled" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Trip requirement is no longer accepting offers",
-		})
-	}
+	tx := config.DB.Begin()
+	defer tx.Rollback()
 
-	// ตรวจสอบว่า Guide เคย offer ไปแล้วหรือยัง
-	var existingOffer models.TripOffer
-	if err := config.DB.Where("trip_require_id = ? AND guide_id = ?", req.TripRequireID, guide.ID).First(&existingOffer).Error; err == nil {
-		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
-			"error": "You have already made an offer for this trip requirement",
-		})
+	// ตรวจสอบว่า TripRequire มีอยู่และยังเปิดรับ offer อยู่
+	tripRequire, resp := loadOfferableTripRequire(c, tx, guide.ID, req.TripRequireID)
+	if tripRequire == nil {
+		return resp
 	}
 
 	// ตรวจสอบว่าราคาอยู่ในช่วงที่ user ต้องการ
-	if req.TotalPrice < tripRequire.MinPrice || req.TotalPrice > tripRequire.MaxPrice {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Price is outside the requested range",
-			"requested_range": map[string]float64{
-				"min": tripRequire.MinPrice,
-				"max": tripRequire.MaxPrice,
-			},
-		})
+	if !req.SaveAsDraft && !offerPriceInRange(tripRequire, req.TotalPrice) {
+		return priceOutOfRangeResponse(c, tripRequire)
 	}
 
-	// สร้าง TripOffer
-	now := time.Now()
-	offer := models.TripOffer{
-		TripRequireID:    req.TripRequireID,
-		GuideID:          guide.ID,
+	offer, quotation, err := createDraftOffer(tx, guide.ID, tripRequire, offerInput{
 		Title:            req.Title,
 		Description:      req.Description,
 		Itinerary:        req.Itinerary,
 		IncludedServices: req.IncludedServices,
 		ExcludedServices: req.ExcludedServices,
-		Status:           "sent",
 		OfferNotes:       req.OfferNotes,
-		SentAt:           &now,
+		TotalPrice:       req.TotalPrice,
+		PriceBreakdown:   req.PriceBreakdown,
+	})
+	if err == nil {
+		err = replaceOfferStops(tx, offer, stops, distanceKm)
 	}
-
-	if err := config.DB.Create(&offer).Error; err != nil {
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create offer",
+			"error":   "offer_create_failed",
 			"details": err.Error(),
 		})
 	}
 
-	// สร้าง TripOfferQuotation
-	quotation := models.TripOfferQuotation{
-		TripOfferID:     offer.ID,
-		Version:         1,
-		TotalPrice:      req.TotalPrice,
-		PriceBreakdown:  req.PriceBreakdown,
-		QuotationNumber: "QT" + strconv.Itoa(int(offer.ID)) + "-" + strconv.Itoa(int(now.Unix())),
-		Status:          "sent",
-		SentAt:          &now,
+	message := "Offer saved as draft"
+	if !req.SaveAsDraft {
+		if err := markOfferSent(tx, offer, quotation, tripRequire); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error":   "offer_send_failed",
+				"details": err.Error(),
+			})
+		}
+		message = "Offer created successfully"
 	}
 
-	if err := config.DB.Create(&quotation).Error; err != nil {
+	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create quotation",
-			"details": err.Error(),
+			"error": "offer_create_failed",
 		})
 	}
 
-	// ไม่ต้องเปลี่ยนสถานะ TripRequire เป็น in_review ถ้ายังเป็น open
-	if tripRequire.Status == "open" {
-		config.DB.Model(&tripRequire).Update("status", "in_review")
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":   message,
+		"offer":     offer,
+		"quotation": quotation,
+	})
+}
+
+// SendTripOffer - ไกด์ส่ง offer ที่บันทึกเป็นร่างไว้ให้ user (ตรวจช่วงราคาตอนส่ง)
+func SendTripOffer(c *fiber.Ctx) error {
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_id_invalid"})
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message":   "Offer created successfully",
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	offer, resp := loadGuideOffer(c, tx, id)
+	if offer == nil {
+		return resp
+	}
+
+	if offer.Status != "draft" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "offer_not_draft",
+			"status": offer.Status,
+		})
+	}
+
+	var tripRequire models.TripRequire
+	if err := tx.First(&tripRequire, offer.TripRequireID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "trip_requirement_not_found"})
+	}
+	if !isTripRequireAcceptingOffers(&tripRequire) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "trip_requirement_closed"})
+	}
+
+	var quotation models.TripOfferQuotation
+	if err := tx.Where("trip_offer_id = ?", offer.ID).Order("version DESC").First(&quotation).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "quotation_get_failed"})
+	}
+
+	if !offerPriceInRange(&tripRequire, quotation.TotalPrice) {
+		return priceOutOfRangeResponse(c, &tripRequire)
+	}
+
+	if err := markOfferSent(tx, offer, &quotation, &tripRequire); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_send_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_send_failed"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":   "Offer sent successfully",
 		"offer":     offer,
 		"quotation": quotation,
 	})
@@ -133,22 +297,22 @@ func CreateTripOffer(c *fiber.Ctx) error {
 func GetTripOffers(c *fiber.Ctx) error {
     tripRequireID, err := strconv.Atoi(c.Params("id"))
     if err != nil  {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid require ID"})
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "require_id_invalid"})
     }
 	if tripRequireID <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Require ID must be greater than 0"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "require_id_not_positive"})
 	}
     
     var offers []models.TripOffer
-    if err := config.DB.
+    if err := preloadOfferStops(config.DB).
         Preload("Guide.User").
         Preload("Guide.Province").
         Preload("Guide.Language").
         Preload("TripOfferQuotation").
-        Where("trip_require_id = ?", tripRequireID).
+        Where("trip_require_id = ? AND status <> ?", tripRequireID, "draft").
         Order("created_at DESC").
         Find(&offers).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get offers"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offers_get_failed"})
     }
     
     return c.Status(fiber.StatusOK).JSON(fiber.Map{"offers": offers})
@@ -158,13 +322,13 @@ func GetTripOffers(c *fiber.Ctx) error {
 func GetTripOfferByID(c *fiber.Ctx) error {
     id, err := strconv.Atoi(c.Params("id"))
     if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid offer ID"})
+        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_id_invalid"})
     }
 	if id <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Offer ID must be greater than 0"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_id_not_positive"})
 	}
     var offer models.TripOffer
-    if err := config.DB.
+    if err := preloadOfferStops(config.DB).
         Preload("TripRequire.User").
         Preload("TripRequire.Province").
         Preload("Guide.User").
@@ -173,49 +337,286 @@ func GetTripOfferByID(c *fiber.Ctx) error {
         Preload("TripOfferQuotation").
         First(&offer, id).Error; err != nil {
         if err == gorm.ErrRecordNotFound {
-            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Offer not found"})
+            return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "offer_not_found"})
         }
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get offer"})
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_get_failed"})
     }
+	// draft เห็นได้เฉพาะไกด์เจ้าของ offer
+	if offer.Status == "draft" {
+		userID, _ := c.Locals("user_id").(uint)
+		if offer.Guide.UserID != userID {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "offer_not_found"})
+		}
+	}
     return c.Status(fiber.StatusOK).JSON(fiber.Map{"data": offer})
 }
 
-// แก้ไข TripOffer
+// editableOfferStatuses - สถานะของ offer ที่ไกด์ยังแก้ไขหรือถอนได้
+var editableOfferStatuses = []string{"draft", "sent", "negotiating"}
+
+// liveOfferStatuses - สถานะของ offer ที่ยังรอ user ตัดสินใจ
+var liveOfferStatuses = []string{"sent", "negotiating"}
+
+func isOfferEditable(status string) bool {
+	for _, s := range editableOfferStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// loadGuideOffer - โหลด offer และตรวจสอบว่าเป็นของไกด์ที่ login อยู่
+func loadGuideOffer(c *fiber.Ctx, tx *gorm.DB, offerID int) (*models.TripOffer, error) {
+	userID := c.Locals("user_id").(uint)
+	var guide models.Guide
+	if err := tx.Where("user_id = ?", userID).First(&guide).Error; err != nil {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "guide_only_manage_offers"})
+	}
+
+	var offer models.TripOffer
+	if err := tx.First(&offer, offerID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "offer_not_found"})
+		}
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_get_failed"})
+	}
+
+	if offer.GuideID != guide.ID {
+		return nil, c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "offer_manage_own_only"})
+	}
+
+	return &offer, nil
+}
+
+// UpdateTripOffer - ไกด์แก้ไข offer ของตัวเอง (เฉพาะ field ที่อนุญาต)
+// ถ้ามีการแก้ราคา จะสร้าง TripOfferQuotation เวอร์ชันใหม่
 func UpdateTripOffer(c *fiber.Ctx) error {
-    id, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid offer ID"})
-    }
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_id_invalid"})
+	}
 	if id <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Offer ID must be greater than 0"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_id_not_positive"})
 	}
-    var offer models.TripOffer
-    if err := config.DB.First(&offer, id).Error; err != nil {
-        return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Offer not found"})
-    }
-    if err := c.BodyParser(&offer); err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request"})
-    }
-    if err := config.DB.Save(&offer).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update offer"})
-    }
-    return c.Status(fiber.StatusOK).JSON(fiber.Map{"offer": offer})
+
+	// ใช้ pointer เพื่อแยก field ที่ไม่ได้ส่งมา ออกจาก field ที่ส่งมาเป็นค่าว่าง
+	var req struct {
+		Title            *string  `json:"title"`
+		Description      *string  `json:"description"`
+		Itinerary        *string  `json:"itinerary"`
+		IncludedServices *string  `json:"included_services"`
+		ExcludedServices *string  `json:"excluded_services"`
+		OfferNotes       *string  `json:"offer_notes"`
+		TotalPrice       *float64 `json:"total_price"`
+		PriceBreakdown   *string  `json:"price_breakdown"`
+		QuotationNotes   *string  `json:"quotation_notes"`
+		Stops            *[]offerStopInput `json:"stops"` // ส่งมา = แทนที่จุดแวะทั้งหมด
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request"})
+	}
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	offer, resp := loadGuideOffer(c, tx, id)
+	if offer == nil {
+		return resp
+	}
+
+	if !isOfferEditable(offer.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "offer_not_editable",
+			"status": offer.Status,
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		if *req.Title == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "title_empty"})
+		}
+		updates["title"] = *req.Title
+	}
+	if req.Description != nil {
+		if *req.Description == "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "description_empty"})
+		}
+		updates["description"] = *req.Description
+	}
+	if req.Itinerary != nil {
+		updates["itinerary"] = *req.Itinerary
+	}
+	if req.IncludedServices != nil {
+		updates["included_services"] = *req.IncludedServices
+	}
+	if req.ExcludedServices != nil {
+		updates["excluded_services"] = *req.ExcludedServices
+	}
+	if req.OfferNotes != nil {
+		updates["offer_notes"] = *req.OfferNotes
+	}
+
+	if len(updates) > 0 {
+		if err := tx.Model(offer).Updates(updates).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_update_failed"})
+		}
+	}
+	if req.Stops != nil {
+		stops, distanceKm, msg := buildOfferStops(tx, *req.Stops)
+		if msg != "" {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+		}
+		if err := replaceOfferStops(tx, offer, stops, distanceKm); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_stops_update_failed"})
+		}
+	}
+
+	// แก้ราคา -> ออกใบเสนอราคาเวอร์ชันใหม่ แทนการแก้ของเดิม
+	var quotation *models.TripOfferQuotation
+	if req.TotalPrice != nil || req.PriceBreakdown != nil {
+		var latest models.TripOfferQuotation
+		if err := tx.Where("trip_offer_id = ?", offer.ID).Order("version DESC").First(&latest).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "quotation_get_failed"})
+		}
+
+		newQuotation := models.TripOfferQuotation{
+			TripOfferID:    offer.ID,
+			Version:        latest.Version + 1,
+			TotalPrice:     latest.TotalPrice,
+			PriceBreakdown: latest.PriceBreakdown,
+			Status:         latest.Status,
+			SentAt:         latest.SentAt,
+		}
+		if req.TotalPrice != nil {
+			var tripRequire models.TripRequire
+			if err := tx.First(&tripRequire, offer.TripRequireID).Error; err != nil {
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "trip_requirement_get_failed"})
+			}
+			// draft จะถูกตรวจช่วงราคาตอนส่ง
+			if offer.Status != "draft" && !offerPriceInRange(&tripRequire, *req.TotalPrice) {
+				return priceOutOfRangeResponse(c, &tripRequire)
+			}
+			newQuotation.TotalPrice = *req.TotalPrice
+		}
+		if req.PriceBreakdown != nil {
+			newQuotation.PriceBreakdown = *req.PriceBreakdown
+		}
+		if req.QuotationNotes != nil {
+			newQuotation.Notes = *req.QuotationNotes
+		}
+		now := time.Now()
+		if newQuotation.Status == "sent" {
+			newQuotation.SentAt = &now
+		}
+		quotationNumber, err := services.NextDocumentNumber(tx, "QT", now)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "quotation_create_failed"})
+		}
+		newQuotation.QuotationNumber = quotationNumber
+
+		if err := tx.Create(&newQuotation).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "quotation_create_failed"})
+		}
+		if err := tx.Model(&latest).Update("status", "superseded").Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "quotation_supersede_failed"})
+		}
+		quotation = &newQuotation
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_update_failed"})
+	}
+
+	preloadOfferStops(config.DB.Preload("TripOfferQuotation")).First(offer, offer.ID)
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":   "Offer updated successfully",
+		"offer":     offer,
+		"quotation": quotation,
+	})
 }
 
-// ลบ TripOffer (ถอนข้อเสนอ)
+// WithdrawTripOffer - ไกด์ถอนข้อเสนอ (เปลี่ยนสถานะเป็น withdrawn ไม่ได้ลบจริง)
 func WithdrawTripOffer(c *fiber.Ctx) error {
-    id, err := strconv.Atoi(c.Params("id"))
-    if err != nil {
-        return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid offer ID"})
-    }
+	id, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_id_invalid"})
+	}
 
 	if id <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Offer ID must be greater than 0"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_id_not_positive"})
 	}
-    if err := config.DB.Delete(&models.TripOffer{}, id).Error; err != nil {
-        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete offer"})
-    }
-    return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Offer withdrawn successfully"})
+
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	_ = c.BodyParser(&body)
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	offer, resp := loadGuideOffer(c, tx, id)
+	if offer == nil {
+		return resp
+	}
+
+	if !isOfferEditable(offer.Status) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "offer_not_withdrawable",
+			"status": offer.Status,
+		})
+	}
+
+	wasLive := offer.Status != "draft"
+	now := time.Now()
+	if err := tx.Model(offer).Updates(map[string]interface{}{
+		"status":            "withdrawn",
+		"withdrawn_at":      &now,
+		"withdrawal_reason": body.Reason,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_withdraw_failed"})
+	}
+
+	var tripRequire models.TripRequire
+	if err := tx.First(&tripRequire, offer.TripRequireID).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "trip_requirement_get_failed"})
+	}
+
+	// ถ้าเป็น offer สุดท้ายที่ยังรอการตัดสินใจ ให้ TripRequire กลับไปเป็น open
+	var liveOffers int64
+	if err := tx.Model(&models.TripOffer{}).
+		Where("trip_require_id = ? AND status IN ?", tripRequire.ID, liveOfferStatuses).
+		Count(&liveOffers).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_count_failed"})
+	}
+	if liveOffers == 0 && tripRequire.Status == "in_review" {
+		if err := tx.Model(&tripRequire).Update("status", "open").Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "trip_requirement_status_update_failed"})
+		}
+	}
+
+	// แจ้ง user เจ้าของ TripRequire (draft ยังไม่เคยส่งถึง user จึงไม่ต้องแจ้ง)
+	if wasLive {
+		if _, err := services.NewNotificationService(tx).Send(tripRequire.UserID, "offer_withdrawn", map[string]interface{}{
+			"OfferTitle": offer.Title,
+			"TripTitle":  tripRequire.Title,
+			"Reason":     body.Reason,
+		}, "trip_offer", offer.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "traveller_notify_failed"})
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_withdraw_failed"})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":             "Offer withdrawn successfully",
+		"offer":               offer,
+		"trip_require_status": tripRequire.Status,
+	})
 }
 
 // Accept TripOffer (User เลือก offer 1 คน - คนที่เหลือ reject อัตโนมัติ)
@@ -223,10 +624,16 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 	offerID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || offerID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid offer ID",
+			"error": "offer_id_invalid",
 		})
 	}
 
+	// Optional body: ทริปหลายวันเลือกจ่ายงวดที่สองให้ไกด์เป็นรายวันได้
+	var body struct {
+		DailyPayout bool `json:"daily_payout"`
+	}
+	_ = c.BodyParser(&body)
+
 	userID := c.Locals("user_id").(uint)
 
 	// เริ่ม transaction
@@ -237,7 +644,7 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 	var offer models.TripOffer
 	if err := tx.First(&offer, offerID).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Offer not found",
+			"error": "offer_not_found",
 		})
 	}
 
@@ -245,28 +652,28 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 	var tripRequire models.TripRequire
 	if err := tx.First(&tripRequire, offer.TripRequireID).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get trip requirement",
+			"error": "trip_requirement_get_failed",
 		})
 	}
 
 	// ตรวจสอบว่าเป็นเจ้าของ trip require
 	if tripRequire.UserID != userID {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "You can only accept offers for your own trip requirements",
+			"error": "offer_accept_own_trip_only",
 		})
 	}
 
 	// ตรวจสอบสถานะ offer
 	if offer.Status != "sent" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Offer is no longer available for acceptance",
+			"error": "offer_not_available",
 		})
 	}
 
 	// ตรวจสอบสถานะ trip require
 	if tripRequire.Status != "open" && tripRequire.Status != "in_review" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Trip requirement is no longer accepting offers",
+			"error": "trip_requirement_closed",
 		})
 	}
 
@@ -274,7 +681,7 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 	var quotation models.TripOfferQuotation
 	if err := tx.Where("trip_offer_id = ?", offer.ID).Order("version DESC").First(&quotation).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get offer quotation",
+			"error": "quotation_get_failed",
 		})
 	}
 
@@ -286,7 +693,7 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 		"accepted_at": &now,
 	}).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to accept offer",
+			"error": "offer_accept_failed",
 		})
 	}
 
@@ -296,7 +703,7 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 		"accepted_at": &now,
 	}).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to accept quotation",
+			"error": "quotation_accept_failed",
 		})
 	}
 
@@ -305,7 +712,7 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 	if err := tx.Where("trip_require_id = ? AND id != ? AND status IN (?)", offer.TripRequireID, offer.ID, []string{"sent", "negotiating"}).
 		Find(&rejectedOffers).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get other offers",
+			"error": "other_offers_get_failed",
 		})
 	}
 
@@ -317,23 +724,40 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 			"rejection_reason": "auto_selection",
 		}).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to reject other offers",
+			"error": "other_offers_reject_failed",
 		})
 	}
 
 	// อัปเดตสถานะ TripRequire เป็น assigned
 	if err := tx.Model(&tripRequire).Update("status", "assigned").Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update trip requirement status",
+			"error": "trip_requirement_status_update_failed",
 		})
 	}
 
+	days := tripRequire.Days
+	if days < 1 {
+		days = 1
+	}
+	if body.DailyPayout && days < 2 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "daily_payout_multi_day_only",
+		})
+	}
+	var endDate *time.Time
+	if !tripRequire.EndDate.IsZero() {
+		endDate = &tripRequire.EndDate
+	}
+
 	// สร้าง TripBooking
 	booking := models.TripBooking{
 		TripOfferID:     offer.ID,
 		UserID:          userID,
 		GuideID:         offer.GuideID,
 		StartDate:       tripRequire.StartDate,
+		EndDate:         endDate,
+		Days:            days,
+		DailyPayout:     body.DailyPayout,
 		TotalAmount:     quotation.TotalPrice,
 		Status:          "pending_payment",
 		PaymentStatus:   "pending",
@@ -342,14 +766,24 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 
 	if err := tx.Create(&booking).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create booking",
+			"error": "booking_create_failed",
 		})
 	}
 
+	// แจ้งไกด์ว่าข้อเสนอได้รับการยอมรับ
+	if guideUserID := bookingCounterpartUserID(tx, &booking, "user"); guideUserID != 0 {
+		if _, err := services.NewNotificationService(tx).Send(guideUserID, "offer_accepted", map[string]interface{}{
+			"OfferTitle": offer.Title,
+			"BookingID":  booking.ID,
+		}, "trip_booking", booking.ID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "guide_notify_failed"})
+		}
+	}
+
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to complete offer acceptance",
+			"error": "offer_acceptance_failed",
 		})
 	}
 
@@ -364,7 +798,7 @@ func AcceptTripOffer(c *fiber.Ctx) error {
 func RejectTripOffer(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil || id <= 0 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid offer ID"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_id_invalid"})
 	}
 
 	// Optional body with reason
@@ -382,25 +816,25 @@ func RejectTripOffer(c *fiber.Ctx) error {
 	var offer models.TripOffer
 	if err := tx.Preload("TripRequire").First(&offer, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Offer not found"})
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "offer_not_found"})
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get offer"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_get_failed"})
 	}
 
 	// Load the related TripRequire to validate ownership
 	var tripRequire models.TripRequire
 	if err := tx.First(&tripRequire, offer.TripRequireID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get trip requirement"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "trip_requirement_get_failed"})
 	}
 
 	// Only the owner of the TripRequire can reject a guide's offer
 	if tripRequire.UserID != userID {
-		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "You can only reject offers for your own trip requirements"})
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "offer_reject_own_trip_only"})
 	}
 
 	// Only reject if in a rejectable state
 	if offer.Status != "sent" && offer.Status != "negotiating" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Only pending offers can be rejected"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "offer_not_pending"})
 	}
 
 	now := time.Now()
@@ -411,7 +845,7 @@ func RejectTripOffer(c *fiber.Ctx) error {
 	}
 
 	if err := tx.Model(&offer).Updates(updates).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reject offer"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "offer_reject_failed"})
 	}
 
 	// Update latest quotation status (if exists)
@@ -424,7 +858,7 @@ func RejectTripOffer(c *fiber.Ctx) error {
 	}
 
 	if err := tx.Commit().Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to complete rejection"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "rejection_failed"})
 	}
 
 	return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -441,13 +875,13 @@ func GetGuideOffers(c *fiber.Ctx) error {
 	var guide models.Guide
 	if err := config.DB.Where("user_id = ?", userID).First(&guide).Error; err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only guides can view their offers",
+			"error": "guide_only_view_offers",
 		})
 	}
 	
 	// ดึงข้อเสนอทั้งหมดของ guide
 	var offers []models.TripOffer
-	if err := config.DB.
+	if err := preloadOfferStops(config.DB).
 		Preload("TripRequire.User").
 		Preload("TripRequire.Province").
 		Preload("TripOfferQuotation").
@@ -458,7 +892,7 @@ func GetGuideOffers(c *fiber.Ctx) error {
 		Order("created_at DESC").
 		Find(&offers).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get offers",
+			"error": "offers_get_failed",
 		})
 	}
 	
@@ -466,3 +900,114 @@ func GetGuideOffers(c *fiber.Ctx) error {
 		"offers": offers,
 	})
 }
+
+const (
+	maxOfferStops    = 50
+	maxOfferStopDays = 30
+)
+
+// offerStopInput - จุดแวะในกำหนดการจาก request ระบุ attraction_id หรือ name (พิกัดไม่บังคับ)
+type offerStopInput struct {
+	Day          int      `json:"day"`
+	AttractionID *uint    `json:"attraction_id"`
+	Name         string   `json:"name"`
+	Latitude     *float64 `json:"latitude"`
+	Longitude    *float64 `json:"longitude"`
+	Note         string   `json:"note"`
+}
+
+// buildOfferStops ตรวจจุดแวะ เรียงตามวัน (คงลำดับเดิมภายในวันเดียวกัน) และคำนวณระยะระหว่างจุดที่ต่อกัน
+// จุดที่อ้างถึงสถานที่ท่องเที่ยวใช้ชื่อและพิกัดของสถานที่ถ้าไม่ได้ส่งมา คืนรหัสข้อความ error ("" = ผ่าน)
+func buildOfferStops(db *gorm.DB, inputs []offerStopInput) ([]models.TripOfferStop, float64, string) {
+	if len(inputs) > maxOfferStops {
+		return nil, 0, "itinerary_too_many_stops"
+	}
+	stops := make([]models.TripOfferStop, 0, len(inputs))
+	for _, in := range inputs {
+		day := in.Day
+		if day == 0 {
+			day = 1
+		}
+		if day < 1 || day > maxOfferStopDays {
+			return nil, 0, "stop_day_invalid"
+		}
+		if (in.Latitude == nil) != (in.Longitude == nil) {
+			return nil, 0, "stop_coordinates_incomplete"
+		}
+		if in.Latitude != nil && !validCoordinates(*in.Latitude, *in.Longitude) {
+			return nil, 0, "stop_coordinates_invalid"
+		}
+		stop := models.TripOfferStop{
+			Day:       day,
+			Name:      strings.TrimSpace(in.Name),
+			Latitude:  in.Latitude,
+			Longitude: in.Longitude,
+			Note:      strings.TrimSpace(in.Note),
+		}
+		if in.AttractionID != nil {
+			var attraction models.TouristAttraction
+			if err := db.First(&attraction, *in.AttractionID).Error; err != nil {
+				return nil, 0, "tourist_attraction_not_found"
+			}
+			stop.TouristAttractionID = &attraction.ID
+			if stop.Name == "" {
+				stop.Name = attraction.Name
+			}
+			if stop.Latitude == nil {
+				stop.Latitude, stop.Longitude = attraction.Latitude, attraction.Longitude
+			}
+		}
+		if stop.Name == "" {
+			return nil, 0, "stop_name_required"
+		}
+		if utf8.RuneCountInString(stop.Name) > 200 {
+			return nil, 0, "stop_name_too_long"
+		}
+		stops = append(stops, stop)
+	}
+
+	sort.SliceStable(stops, func(i, j int) bool { return stops[i].Day < stops[j].Day })
+	points := make([]services.RoutePoint, len(stops))
+	for i := range stops {
+		if i > 0 && stops[i].Day == stops[i-1].Day {
+			stops[i].Position = stops[i-1].Position + 1
+		}
+		points[i] = services.RoutePoint{Day: stops[i].Day, Latitude: stops[i].Latitude, Longitude: stops[i].Longitude}
+	}
+	var total float64
+	for i, leg := range services.EstimateRouteLegs(points) {
+		if leg == nil {
+			continue
+		}
+		distance, minutes := leg.DistanceKm, leg.TravelMinutes
+		stops[i].DistanceFromPreviousKm = &distance
+		stops[i].TravelMinutesFromPrevious = &minutes
+		total += distance
+	}
+	return stops, math.Round(total*10) / 10, ""
+}
+
+// replaceOfferStops - แทนที่จุดแวะทั้งหมดของ offer และบันทึกระยะทางรวม
+func replaceOfferStops(tx *gorm.DB, offer *models.TripOffer, stops []models.TripOfferStop, totalKm float64) error {
+	if err := tx.Unscoped().Where("trip_offer_id = ?", offer.ID).Delete(&models.TripOfferStop{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(offer).Update("estimated_distance_km", totalKm).Error; err != nil {
+		return err
+	}
+	offer.Stops = stops
+	if len(stops) == 0 {
+		return nil
+	}
+	for i := range stops {
+		stops[i].TripOfferID = offer.ID
+	}
+	return tx.Create(&stops).Error
+}
+
+// preloadOfferStops - โหลดจุดแวะของ offer ตามลำดับวันและตำแหน่ง
+func preloadOfferStops(q *gorm.DB) *gorm.DB {
+	return q.Preload("Stops", func(db *gorm.DB) *gorm.DB {
+		return db.Order("day, position")
+	})
+}
@@ -0,0 +1,243 @@
+package controllers
+
+import (
+	"encoding/json"
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+const maxReportEvidence = 10
+
+// ประเภทรีพอร์ตที่ผู้ร่วมทริปส่งเองได้ (no-show มี flow ของตัวเองใน noShow_controller.go)
+// ค่าคือ severity ขั้นต่ำ ผู้รีพอร์ตเลือกสูงกว่านี้ได้แต่ต่ำกว่าไม่ได้
+var incidentReportTypes = map[string]string{
+	"safety_issue":           "high",
+	"inappropriate_behavior": "medium",
+	"payment_issue":          "medium",
+	"other":                  "low",
+}
+
+var reportSeverityLevels = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// isAttachmentURL - ไฟล์ที่อัปโหลดผ่านระบบ หรือ URL http/https
+func isAttachmentURL(u string) bool {
+	return isUploadedURL(u) || strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://")
+}
+
+// validateEvidence - ตรวจรายการหลักฐานที่ userID แนบใน booking bookingID คืนรหัสข้อความ error (ว่าง = ผ่าน)
+func validateEvidence(urls []string, userID, bookingID uint) string {
+	if len(urls) > maxReportEvidence {
+		return "evidence_too_many"
+	}
+	for _, u := range urls {
+		if !isAttachmentURL(u) {
+			return "evidence_url_invalid"
+		}
+		if !canAttachURL(u, userID, 0, bookingID) {
+			return "evidence_url_forbidden"
+		}
+	}
+	return ""
+}
+
+// evidenceJSON - เก็บรายการหลักฐานเป็น JSON array (ไม่มีหลักฐาน = ว่าง)
+func evidenceJSON(urls []string) string {
+	if len(urls) == 0 {
+		return ""
+	}
+	raw, _ := json.Marshal(urls)
+	return string(raw)
+}
+
+// parseEvidence - อ่านหลักฐานที่เก็บไว้ รองรับข้อมูลเดิมที่เป็น URL เดียว
+func parseEvidence(stored string) []string {
+	evidence := []string{}
+	if stored != "" {
+		if err := json.Unmarshal([]byte(stored), &evidence); err != nil {
+			evidence = []string{stored}
+		}
+	}
+	return evidence
+}
+
+// triageReportSeverity - ใช้ severity ที่สูงกว่าระหว่างที่ผู้รีพอร์ตเลือกกับขั้นต่ำของประเภท
+func triageReportSeverity(reportType, requested string) string {
+	severity := incidentReportTypes[reportType]
+	if reportSeverityLevels[requested] > reportSeverityLevels[severity] {
+		severity = requested
+	}
+	return severity
+}
+
+// criticalReportFreezesPayouts - ระงับการจ่ายไกด์เมื่อลูกค้ารีพอร์ตปัญหาความปลอดภัยระดับ critical (ปิดได้ด้วย CRITICAL_REPORT_FREEZE_PAYOUTS=false)
+func criticalReportFreezesPayouts() bool {
+	return os.Getenv("CRITICAL_REPORT_FREEZE_PAYOUTS") != "false"
+}
+
+// CreateTripReport - user หรือไกด์ของ booking รีพอร์ตปัญหาความปลอดภัย/พฤติกรรม/การชำระเงิน
+// critical จะแจ้ง admin ทันที และถ้าเป็น safety_issue จากลูกค้าจะระงับการจ่ายไกด์ที่ยังไม่ได้จ่าย
+func CreateTripReport(c *fiber.Ctx) error {
+	booking, role, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+	userID := c.Locals("user_id").(uint)
+
+	var req struct {
+		ReportType  string   `json:"report_type"`
+		Title       string   `json:"title"`
+		Description string   `json:"description"`
+		Severity    string   `json:"severity"`
+		Evidence    []string `json:"evidence"` // URL ของหลักฐาน (อัปโหลดผ่าน /upload)
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+
+	if _, ok := incidentReportTypes[req.ReportType]; !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "report_type_invalid",
+		})
+	}
+	if req.Severity != "" && reportSeverityLevels[req.Severity] == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "severity_invalid"})
+	}
+	req.Title = strings.TrimSpace(req.Title)
+	req.Description = strings.TrimSpace(req.Description)
+	if req.Title == "" || req.Description == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "title_description_required"})
+	}
+	if utf8.RuneCountInString(req.Title) > 200 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "title_too_long"})
+	}
+	if msg := validateEvidence(req.Evidence, userID, booking.ID); msg != "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": msg})
+	}
+
+	// รีพอร์ตประเภทเดียวกันที่ยังรอตรวจอยู่ ไม่ต้องสร้างซ้ำ
+	var open int64
+	config.DB.Model(&models.TripReport{}).
+		Where("trip_booking_id = ? AND reporter_id = ? AND report_type = ? AND status IN ?", booking.ID, userID, req.ReportType, []string{"pending", "investigating"}).
+		Count(&open)
+	if open > 0 {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "trip_report_duplicate"})
+	}
+
+	severity := triageReportSeverity(req.ReportType, req.Severity)
+	freeze := severity == "critical" && req.ReportType == "safety_issue" && role == "user" && criticalReportFreezesPayouts()
+
+	report := models.TripReport{
+		TripBookingID:  booking.ID,
+		ReporterID:     userID,
+		ReportedUserID: bookingCounterpartUserID(config.DB, booking, role),
+		ReportType:     req.ReportType,
+		Title:          req.Title,
+		Description:    req.Description,
+		Evidence:       evidenceJSON(req.Evidence),
+		Severity:       severity,
+		Status:         "pending",
+	}
+
+	payoutsFrozen := false
+	now := time.Now()
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if freeze {
+			var payment models.TripPayment
+			if err := tx.Where("trip_booking_id = ?", booking.ID).First(&payment).Error; err == nil && !payment.PayoutsFrozen {
+				if err := services.FreezePayouts(tx, payment.ID, "critical safety report", now); err != nil {
+					return err
+				}
+				payoutsFrozen = true
+				report.Actions = `["payouts_frozen"]`
+			}
+		}
+		return tx.Create(&report).Error
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "report_create_failed"})
+	}
+
+	if severity == "critical" {
+		notifyAdmins("report_critical", map[string]interface{}{
+			"BookingID":     booking.ID,
+			"ReportType":    report.ReportType,
+			"Title":         report.Title,
+			"PayoutsFrozen": payoutsFrozen,
+		}, "trip_report", report.ID)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message":        "Report submitted. Our team will review it.",
+		"report":         reporterReportView(report),
+		"payouts_frozen": payoutsFrozen,
+	})
+}
+
+// reporterReportView - ข้อมูลรีพอร์ตที่ผู้รีพอร์ตเห็นได้ (สถานะและคำตอบจาก admin)
+func reporterReportView(r models.TripReport) fiber.Map {
+	return fiber.Map{
+		"id":              r.ID,
+		"trip_booking_id": r.TripBookingID,
+		"report_type":     r.ReportType,
+		"title":           r.Title,
+		"description":     r.Description,
+		"evidence":        parseEvidence(r.Evidence),
+		"severity":        r.Severity,
+		"status":          r.Status,
+		"admin_response":  r.AdminNotes,
+		"reviewed_at":     r.ReviewedAt,
+		"resolved_at":     r.ResolvedAt,
+		"created_at":      r.CreatedAt,
+		"updated_at":      r.UpdatedAt,
+	}
+}
+
+// GetMyTripReports - รีพอร์ตทั้งหมดที่ผู้ใช้ส่ง พร้อมสถานะและคำตอบจาก admin (?status=)
+func GetMyTripReports(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+
+	query := config.DB.Where("reporter_id = ?", userID).Order("created_at DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var reports []models.TripReport
+	if err := query.Find(&reports).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "reports_get_failed"})
+	}
+
+	views := make([]fiber.Map, 0, len(reports))
+	for _, r := range reports {
+		views = append(views, reporterReportView(r))
+	}
+	return c.JSON(fiber.Map{"reports": views})
+}
+
+// GetTripBookingReports - รีพอร์ตของ booking ที่ผู้ใช้คนนี้เป็นผู้ส่ง
+func GetTripBookingReports(c *fiber.Ctx) error {
+	booking, _, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+	userID := c.Locals("user_id").(uint)
+
+	var reports []models.TripReport
+	if err := config.DB.Where("trip_booking_id = ? AND reporter_id = ?", booking.ID, userID).
+		Order("created_at DESC").Find(&reports).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "reports_get_failed"})
+	}
+
+	views := make([]fiber.Map, 0, len(reports))
+	for _, r := range reports {
+		views = append(views, reporterReportView(r))
+	}
+	return c.JSON(fiber.Map{"reports": views})
+}
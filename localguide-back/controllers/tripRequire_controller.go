@@ -28,7 +28,7 @@ func CreateTripRequire(c *fiber.Ctx) error {
 
 	if err := c.BodyParser(&req); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to parse request body",
+			"error": "request_body_parse_failed",
 			"details": err.Error(),
 		})
 	}
@@ -36,7 +36,7 @@ func CreateTripRequire(c *fiber.Ctx) error {
 	// ตรวจสอบว่า MaxPrice >= MinPrice
 	if req.MaxPrice < req.MinPrice {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Max price must be greater than or equal to min price",
+			"error": "price_range_invalid",
 		})
 	}
 
@@ -47,7 +47,7 @@ func CreateTripRequire(c *fiber.Ctx) error {
 	var province models.Province
 	if err := config.DB.First(&province, req.ProvinceID).Error; err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Province not found",
+			"error": "province_not_found",
 		})
 	}
 
@@ -55,21 +55,21 @@ func CreateTripRequire(c *fiber.Ctx) error {
 	startDate, err := time.Parse("02/01/2006", req.StartDate)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid start_date format (use DD/MM/YYYY)",
+			"error": "start_date_format_invalid",
 		})
 	}
 
 	endDate, err := time.Parse("02/01/2006", req.EndDate)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid end_date format (use DD/MM/YYYY)",
+			"error": "end_date_format_invalid",
 		})
 	}
 
 	// ตรวจสอบว่า EndDate >= StartDate
 	if endDate.Before(startDate) {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "End date must be after start date",
+			"error": "end_before_start",
 		})
 	}
 
@@ -78,7 +78,7 @@ func CreateTripRequire(c *fiber.Ctx) error {
 		expiry, err := time.Parse("02/01/2006", req.ExpiresAt)
 		if err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid expires_at format (use DD/MM/YYYY)",
+				"error": "expires_at_format_invalid",
 			})
 		}
 		expiresAt = &expiry
@@ -103,7 +103,7 @@ func CreateTripRequire(c *fiber.Ctx) error {
 
 	if err := config.DB.Create(&tripRequire).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create trip requirement",
+			"error": "trip_requirement_create_failed",
 			"details": err.Error(),
 		})
 	}
@@ -121,7 +121,7 @@ func GetTripRequires(c *fiber.Ctx) error {
 	var tripRequires []models.TripRequire
 	if err := config.DB.Where("user_id = ?", userID).Order("created_at DESC").Find(&tripRequires).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve trip requirements",
+			"error": "trip_requirements_get_failed",
 			"details": err.Error(),
 		})
 	}
@@ -137,7 +137,7 @@ func GetTripRequires(c *fiber.Ctx) error {
 	for _, tr := range tripRequires {
 		// นับจำนวน offers
 		var offerCount int64
-		config.DB.Model(&models.TripOffer{}).Where("trip_require_id = ?", tr.ID).Count(&offerCount)
+		config.DB.Model(&models.TripOffer{}).Where("trip_require_id = ? AND status <> ?", tr.ID, "draft").Count(&offerCount)
 
 		// ดึงชื่อ province
 		var province models.Province
@@ -165,7 +165,7 @@ func BrowseTripRequires(c *fiber.Ctx) error {
 	var guide models.Guide
 	if err := config.DB.Where("user_id = ?", userID).First(&guide).Error; err != nil {
 		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-			"error": "Only guides can browse trip requirements",
+			"error": "guide_only_browse_trips",
 		})
 	}
 
@@ -209,7 +209,7 @@ func BrowseTripRequires(c *fiber.Ctx) error {
 	var tripRequires []models.TripRequire
 	if err := query.Order("created_at DESC").Find(&tripRequires).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve trip requirements",
+			"error": "trip_requirements_get_failed",
 			"details": err.Error(),
 		})
 	}
@@ -227,11 +227,11 @@ func BrowseTripRequires(c *fiber.Ctx) error {
 	for _, tr := range tripRequires {
 		// นับจำนวน offers
 		var offerCount int64
-		config.DB.Model(&models.TripOffer{}).Where("trip_require_id = ?", tr.ID).Count(&offerCount)
+		config.DB.Model(&models.TripOffer{}).Where("trip_require_id = ? AND status <> ?", tr.ID, "draft").Count(&offerCount)
 
-		// ตรวจสอบว่า guide คนนี้ offer แล้วหรือยัง
+		// ตรวจสอบว่า guide คนนี้มี offer ที่ยังไม่ถอนหรือยัง (ถอนแล้วเสนอใหม่ได้)
 		var existingOffer models.TripOffer
-		hasOffered := config.DB.Where("trip_require_id = ? AND guide_id = ?", tr.ID, guide.ID).First(&existingOffer).Error == nil
+		hasOffered := config.DB.Where("trip_require_id = ? AND guide_id = ? AND status <> ?", tr.ID, guide.ID, "withdrawn").First(&existingOffer).Error == nil
 
 		// ดึงชื่อ province
 		var province models.Province
@@ -265,7 +265,7 @@ func GetTripRequiresByTripID(c *fiber.Ctx) error {
 	tripID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || tripID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid trip ID",
+			"error": "trip_id_invalid",
 			"details": err.Error(),
 		})
 	}
@@ -274,18 +274,18 @@ func GetTripRequiresByTripID(c *fiber.Ctx) error {
 	if err := config.DB.Where("trip_id = ?", tripID).First(&tripRequire).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Trip requirement not found",
+				"error": "trip_requirement_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve trip requirement",
+			"error": "trip_requirement_retrieve_failed",
 			"details": err.Error(),
 		})
 	}
 
 	if tripRequire.ID == 0 {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "No trip requirements found for this trip ID",
+			"error": "trip_requirements_not_found",
 		})
 	}
 
@@ -298,7 +298,7 @@ func GetTripRequireByID(c *fiber.Ctx) error {
 	id, err := strconv.Atoi(c.Params("id"))
 	if err != nil || id <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid trip require ID",
+			"error": "trip_require_id_invalid",
 			"details": err.Error(),
 		})
 	}
@@ -307,11 +307,11 @@ func GetTripRequireByID(c *fiber.Ctx) error {
 	if err := config.DB.Preload("Province").Preload("User").First(&tripRequire, id).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Trip requirement not found",
+				"error": "trip_requirement_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to retrieve trip requirement",
+			"error": "trip_requirement_retrieve_failed",
 			"details": err.Error(),
 		})
 	}
@@ -326,7 +326,7 @@ func UpdateTripRequire(c *fiber.Ctx) error {
 	tripID ,err := strconv.Atoi(c.Params("id"))
 	if err != nil || tripID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid trip ID",
+			"error": "trip_id_invalid",
 			"details": err.Error(),
 		})
 	}
@@ -334,14 +334,14 @@ func UpdateTripRequire(c *fiber.Ctx) error {
 	var tripRequire models.TripRequire
 	if err := c.BodyParser(&tripRequire); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Failed to parse request body",
+			"error": "request_body_parse_failed",
 			"details": err.Error(),
 		})
 	}
 
 	if err := config.DB.Model(&models.TripRequire{}).Where("id = ?", tripID).Updates(tripRequire).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update trip requirement",
+			"error": "trip_requirement_update_failed",
 			"details": err.Error(),
 		})
 	}
@@ -359,7 +359,7 @@ func DeleteTripRequire(c *fiber.Ctx) error {
 	tripID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || tripID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid trip ID",
+			"error": "trip_id_invalid",
 			"details": err.Error(),
 		})
 	}
@@ -370,11 +370,11 @@ func DeleteTripRequire(c *fiber.Ctx) error {
 	if err := config.DB.Where("id = ? AND user_id = ?", tripID, userID).First(&tripRequire).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error": "Trip requirement not found or you don't have permission",
+				"error": "trip_requirement_not_found_or_forbidden",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to find trip requirement",
+			"error": "trip_requirement_find_failed",
 			"details": err.Error(),
 		})
 	}
@@ -382,14 +382,15 @@ func DeleteTripRequire(c *fiber.Ctx) error {
 	// ตรวจสอบสถานะก่อนลบ - ไม่สามารถลบได้ถ้ามี offer ที่ accepted แล้ว
 	if tripRequire.Status == "assigned" || tripRequire.Status == "completed" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Cannot delete trip requirement with status: " + tripRequire.Status,
+			"error":  "trip_requirement_not_deletable",
+			"status": tripRequire.Status,
 		})
 	}
 
 	// ลบ trip require (soft delete)
 	if err := config.DB.Delete(&tripRequire).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to delete trip requirement",
+			"error": "trip_requirement_delete_failed",
 			"details": err.Error(),
 		})
 	}
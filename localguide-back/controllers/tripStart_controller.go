@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"math/big"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultMeetingRadiusMeters = 300
+	maxFailedStartCheckIns     = 5
+	failedStartCheckInWindow   = 15 * time.Minute
+)
+
+func newStartCode() string {
+	n, _ := rand.Int(rand.Reader, big.NewInt(1000000))
+	return fmt.Sprintf("%06d", n.Int64())
+}
+
+// meetingRadiusMeters - ระยะที่ยอมให้ไกด์อยู่ห่างจากจุดนัดพบ (MEETING_POINT_RADIUS_METERS)
+func meetingRadiusMeters() float64 {
+	if v, err := strconv.ParseFloat(os.Getenv("MEETING_POINT_RADIUS_METERS"), 64); err == nil && v > 0 {
+		return v
+	}
+	return defaultMeetingRadiusMeters
+}
+
+func validCoordinates(lat, lng float64) bool {
+	return lat >= -90 && lat <= 90 && lng >= -180 && lng <= 180
+}
+
+// GetTripStartCode - user ดูรหัสเริ่มทริปเพื่อบอกไกด์ตอนพบกัน (สร้างครั้งแรกที่เรียก)
+func GetTripStartCode(c *fiber.Ctx) error {
+	booking, role, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+	if role != "user" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "traveller_only_start_code"})
+	}
+
+	if booking.Status != "paid" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "start_code_unavailable",
+			"status": booking.Status,
+		})
+	}
+
+	if booking.StartCode == "" {
+		booking.StartCode = newStartCode()
+		if err := config.DB.Model(booking).Update("start_code", booking.StartCode).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "start_code_generate_failed"})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"start_code":        booking.StartCode,
+		"meeting_latitude":  booking.MeetingLatitude,
+		"meeting_longitude": booking.MeetingLongitude,
+		"meeting_note":      booking.MeetingNote,
+	})
+}
+
+// SetMeetingPoint - user กำหนดจุดนัดพบ ใช้เทียบกับตำแหน่งของไกด์ตอนเช็คอิน
+func SetMeetingPoint(c *fiber.Ctx) error {
+	booking, role, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+	if role != "user" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "traveller_only_meeting_point"})
+	}
+
+	var req struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Note      string  `json:"note"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
+	}
+	if !validCoordinates(req.Latitude, req.Longitude) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "coordinates_invalid"})
+	}
+
+	if booking.Status != "pending_payment" && booking.Status != "paid" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "meeting_point_locked"})
+	}
+
+	if err := config.DB.Model(booking).Updates(map[string]interface{}{
+		"meeting_latitude":  req.Latitude,
+		"meeting_longitude": req.Longitude,
+		"meeting_note":      req.Note,
+	}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "meeting_point_update_failed"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":           "Meeting point updated",
+		"meeting_latitude":  req.Latitude,
+		"meeting_longitude": req.Longitude,
+		"meeting_note":      req.Note,
+	})
+}
+
+// GuideCheckIn - ไกด์กรอกรหัสเริ่มทริปจาก user (และส่งพิกัดได้) -> เริ่มทริปและจ่ายงวดแรก
+// ทุกครั้งที่เช็คอินจะถูกบันทึกเป็นหลักฐาน รวมถึงครั้งที่ไม่ผ่าน
+func GuideCheckIn(c *fiber.Ctx) error {
+	booking, role, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+	if role != "guide" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": "guide_only_check_in"})
+	}
+
+	var req struct {
+		Code           string   `json:"code"`
+		Latitude       *float64 `json:"latitude"`
+		Longitude      *float64 `json:"longitude"`
+		AccuracyMeters *float64 `json:"accuracy_meters"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "start_code_required"})
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "coordinates_incomplete"})
+	}
+	if req.Latitude != nil && !validCoordinates(*req.Latitude, *req.Longitude) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "coordinates_invalid"})
+	}
+
+	if booking.Status != "paid" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":  "booking_not_paid_or_started",
+			"status": booking.Status,
+		})
+	}
+
+	now := time.Now()
+	if dateOnly(now.In(booking.StartDate.Location())).Before(dateOnly(booking.StartDate)) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "check_in_before_start"})
+	}
+
+	var failed int64
+	config.DB.Model(&models.TripStartCheckIn{}).
+		Where("trip_booking_id = ? AND status = ? AND created_at > ?", booking.ID, "rejected", now.Add(-failedStartCheckInWindow)).
+		Count(&failed)
+	if failed >= maxFailedStartCheckIns {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{"error": "check_in_rate_limited"})
+	}
+
+	checkIn := models.TripStartCheckIn{
+		TripBookingID:  booking.ID,
+		GuideID:        booking.GuideID,
+		CodeValid:      booking.StartCode != "" && subtle.ConstantTimeCompare([]byte(booking.StartCode), []byte(req.Code)) == 1,
+		Latitude:       req.Latitude,
+		Longitude:      req.Longitude,
+		AccuracyMeters: req.AccuracyMeters,
+		Status:         "accepted",
+		IPAddress:      c.IP(),
+		UserAgent:      c.Get("User-Agent"),
+	}
+
+	if req.Latitude != nil && booking.MeetingLatitude != nil && booking.MeetingLongitude != nil {
+		distance := services.DistanceMeters(*booking.MeetingLatitude, *booking.MeetingLongitude, *req.Latitude, *req.Longitude)
+		within := distance <= meetingRadiusMeters()
+		checkIn.DistanceMeters = &distance
+		checkIn.WithinRadius = &within
+	}
+
+	if !checkIn.CodeValid {
+		checkIn.Status = "rejected"
+		checkIn.FailureReason = "invalid_code"
+	} else if checkIn.WithinRadius != nil && !*checkIn.WithinRadius {
+		checkIn.Status = "rejected"
+		checkIn.FailureReason = "outside_meeting_radius"
+	}
+
+	if checkIn.Status == "rejected" {
+		if err := config.DB.Create(&checkIn).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "check_in_record_failed"})
+		}
+		msg := "start_code_invalid"
+		if checkIn.FailureReason == "outside_meeting_radius" {
+			msg = "meeting_point_too_far"
+		}
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":           msg,
+			"distance_meters": checkIn.DistanceMeters,
+		})
+	}
+
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	if err := tx.Create(&checkIn).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "check_in_record_failed"})
+	}
+
+	release, err := startTripAndReleaseFirstPayment(tx, booking, "guide_check_in", now)
+	if err != nil {
+		return c.Status(startTripErrorStatus(err)).JSON(fiber.Map{"error": err.Error()})
+	}
+	booking.StartCodeUsedAt = &now
+	if err := tx.Model(booking).Update("start_code_used_at", &now).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "booking_update_failed"})
+	}
+
+	data := map[string]interface{}{"BookingID": booking.ID}
+	if _, err := services.NewNotificationService(tx).Send(booking.UserID, "guide_checked_in", data, "trip_booking", booking.ID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "traveller_notify_failed"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "check_in_failed"})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  "Check-in verified, trip started and 50% payment released to guide",
+		"booking":  booking,
+		"check_in": checkIn,
+		"release":  release,
+	})
+}
+
+// GetTripStartCheckIns - ประวัติการเช็คอินเริ่มทริป (ใช้เป็นหลักฐานตอนมีข้อพิพาท)
+func GetTripStartCheckIns(c *fiber.Ctx) error {
+	booking, _, err := loadBookingForParticipant(c)
+	if booking == nil {
+		return err
+	}
+
+	var checkIns []models.TripStartCheckIn
+	if err := config.DB.Where("trip_booking_id = ?", booking.ID).Order("created_at ASC").Find(&checkIns).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "check_ins_get_failed"})
+	}
+
+	return c.JSON(fiber.Map{"check_ins": checkIns})
+}
@@ -1,8 +1,12 @@
 package controllers
 
 import (
+	"errors"
 	"localguide-back/config"
 	"localguide-back/models"
+	"localguide-back/services"
+	"log"
+	"math"
 	"strconv"
 	"time"
 
@@ -15,7 +19,7 @@ func ConfirmGuideArrival(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Booking ID must be a positive integer",
+			"error":   "booking_id_invalid",
 		})
 	}
 
@@ -23,36 +27,90 @@ func ConfirmGuideArrival(c *fiber.Ctx) error {
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error":   "Booking not found",
+				"error":   "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get booking",
+			"error":   "booking_get_failed",
+		})
+	}
+
+	// เฉพาะ user เจ้าของ booking เท่านั้นที่ยืนยันว่าไกด์มาได้ (ไกด์ใช้ guide-check-in แทน)
+	userID := c.Locals("user_id").(uint)
+	if booking.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "traveller_only_confirm_arrival",
 		})
 	}
 
 	if booking.Status != "paid" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Booking must be paid before confirming guide arrival",
+			"error":   "booking_payment_required_for_arrival",
 		})
 	}
 
-	// Update booking status
-	now := time.Now()
-	booking.Status = "trip_started"
-	booking.TripStartedAt = &now
-	if err := config.DB.Save(&booking).Error; err != nil {
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	release, err := startTripAndReleaseFirstPayment(tx, &booking, "trip_started", time.Now())
+	if err != nil {
+		return c.Status(startTripErrorStatus(err)).JSON(fiber.Map{
+			"error":   err.Error(),
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update booking",
+			"error":   "booking_update_failed",
 		})
 	}
 
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message": "Guide arrival confirmed, 50% payment released to guide",
+		"booking": booking,
+		"release": release,
+	})
+}
+
+// error ของ startTripAndReleaseFirstPayment ข้อความเป็นรหัสใน services/messages.go ส่งกลับเป็น "error" ได้ตรงๆ
+var (
+	errTripAlreadyStarted        = errors.New("trip_already_started")
+	errBookingUpdateFailed       = errors.New("booking_update_failed")
+	errPaymentNotFound           = errors.New("payment_not_found")
+	errPaymentReleaseFailed      = errors.New("payment_release_create_failed")
+	errPaymentStatusUpdateFailed = errors.New("payment_status_update_failed")
+)
+
+// startTripErrorStatus - HTTP status ของ error จาก startTripAndReleaseFirstPayment
+func startTripErrorStatus(err error) int {
+	if err == errTripAlreadyStarted {
+		return fiber.StatusConflict
+	}
+	return fiber.StatusInternalServerError
+}
+
+// startTripAndReleaseFirstPayment - เปลี่ยน booking เป็น trip_started และจ่ายงวดแรก (50%) ให้ไกด์
+// เปลี่ยนสถานะแบบมีเงื่อนไข (ต้องยังเป็น paid) ไกด์เช็คอินพร้อมกับลูกค้ายืนยันว่าไกด์มาจะจ่ายงวดแรกครั้งเดียว
+// อีกฝั่งได้ errTripAlreadyStarted
+func startTripAndReleaseFirstPayment(tx *gorm.DB, booking *models.TripBooking, reason string, now time.Time) (*models.PaymentRelease, error) {
+	// Update booking status
+	res := tx.Model(&models.TripBooking{}).
+		Where("id = ? AND status = ?", booking.ID, "paid").
+		Updates(map[string]interface{}{"status": "trip_started", "trip_started_at": &now})
+	if res.Error != nil {
+		log.Printf("booking #%d: failed to start trip: %v", booking.ID, res.Error)
+		return nil, errBookingUpdateFailed
+	}
+	if res.RowsAffected == 0 {
+		return nil, errTripAlreadyStarted
+	}
+	booking.Status = "trip_started"
+	booking.TripStartedAt = &now
+
 	// Get payment to calculate first release
 	var payment models.TripPayment
-	if err := config.DB.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Payment not found",
-		})
+	if err := tx.Where("trip_booking_id = ?", booking.ID).First(&payment).Error; err != nil {
+		return nil, errPaymentNotFound
 	}
 
 	// Create payment release record for guide (50%)
@@ -62,32 +120,23 @@ func ConfirmGuideArrival(c *fiber.Ctx) error {
 		Amount:        payment.FirstPayment,
 		RecipientType: "guide",
 		RecipientID:   booking.GuideID,
-		Reason:        "trip_started",
+		Reason:        reason,
 		ScheduledAt:   now,
-		ProcessedAt:   &now,
-		Status:        "processed",
 	}
-	
-	if err := config.DB.Create(&release).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to create payment release",
-		})
+	if _, err := services.QueueGuidePayout(tx, &payment, &release); err != nil {
+		log.Printf("booking #%d: failed to queue first payment: %v", booking.ID, err)
+		return nil, errPaymentReleaseFailed
 	}
 
 	// Update payment status
 	payment.Status = "first_released"
 	payment.FirstReleasedAt = &now
-	if err := config.DB.Save(&payment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update payment status",
-		})
+	if err := tx.Save(&payment).Error; err != nil {
+		log.Printf("booking #%d: failed to update payment: %v", booking.ID, err)
+		return nil, errPaymentStatusUpdateFailed
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Guide arrival confirmed, 50% payment released to guide",
-		"booking": booking,
-		"release": release,
-	})
+	return &release, nil
 }
 
 // ConfirmTripComplete - User ยืนยันทริปเสร็จ -> ไกด์ได้เงินเต็ม
@@ -95,7 +144,7 @@ func ConfirmTripComplete(c *fiber.Ctx) error {
 	bookingID, err := strconv.Atoi(c.Params("id"))
 	if err != nil || bookingID <= 0 {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Booking ID must be a positive integer",
+			"error":   "booking_id_invalid",
 		})
 	}
 
@@ -103,63 +152,90 @@ func ConfirmTripComplete(c *fiber.Ctx) error {
 	if err := config.DB.First(&booking, bookingID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-				"error":   "Booking not found",
+				"error":   "booking_not_found",
 			})
 		}
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to get booking",
+			"error":   "booking_get_failed",
+		})
+	}
+
+	// เฉพาะ user เจ้าของ booking เท่านั้นที่ยืนยันจบทริปได้
+	userID := c.Locals("user_id").(uint)
+	if booking.UserID != userID {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error":   "traveller_only_confirm_completion",
 		})
 	}
 
 	if booking.Status != "trip_started" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error":   "Trip must be started before completion",
+			"error":   "trip_not_started_completion",
 		})
 	}
 
-	// Update booking status
+	// งวดที่สองจ่ายได้เมื่อถึงวันสุดท้ายของทริป หรือทั้งสองฝ่ายตกลงจบทริปก่อนกำหนดแล้ว
 	now := time.Now()
+	if !hasTripEndDateArrived(&booking, now) && booking.EarlyCompletionAgreedAt == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error":    "trip_end_not_reached",
+			"end_date": bookingEndDate(&booking),
+		})
+	}
+
+	// เปลี่ยนสถานะและจ่ายงวดที่สองใน tx เดียวกัน
+	tx := config.DB.Begin()
+	defer tx.Rollback()
+
+	// Update booking status
 	booking.Status = "trip_completed"
 	booking.TripCompletedAt = &now
-	if err := config.DB.Save(&booking).Error; err != nil {
+	if err := tx.Save(&booking).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update booking",
+			"error":   "booking_update_failed",
 		})
 	}
 
 	// Get payment for second release
 	var payment models.TripPayment
-	if err := config.DB.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
+	if err := tx.Where("trip_booking_id = ?", bookingID).First(&payment).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Payment not found",
+			"error":   "payment_not_found",
 		})
 	}
 
-	// Release remaining 50% payment to guide
+	// Release remaining 50% payment to guide (หักส่วนที่จ่ายรายวันไปแล้ว)
+	amount := payment.SecondPayment
+	if booking.DailyPayout {
+		amount = math.Round((payment.SecondPayment-releasedDailyAmount(tx, payment.ID))*100) / 100
+	}
 	release := models.PaymentRelease{
 		TripPaymentID: payment.ID,
 		ReleaseType:   "second_payment",
-		Amount:        payment.SecondPayment,
+		Amount:        amount,
 		RecipientType: "guide",
 		RecipientID:   booking.GuideID,
 		Reason:        "trip_completed",
 		ScheduledAt:   now,
-		ProcessedAt:   &now,
-		Status:        "processed",
 	}
-	
-	if err := config.DB.Create(&release).Error; err != nil {
+	if _, err := services.QueueGuidePayout(tx, &payment, &release); err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to create payment release",
+			"error":   "payment_release_create_failed",
 		})
 	}
 
 	// Update payment status
 	payment.Status = "fully_released"
 	payment.SecondReleasedAt = &now
-	if err := config.DB.Save(&payment).Error; err != nil {
+	if err := tx.Save(&payment).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error":   "payment_status_update_failed",
+		})
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error":   "Failed to update payment status",
+			"error":   "booking_update_failed",
 		})
 	}
 
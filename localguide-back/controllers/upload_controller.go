@@ -1,50 +1,261 @@
 package controllers
 
 import (
-	"fmt"
+	"errors"
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"log"
 	"os"
-	"path/filepath"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+// FileStorage - ที่เก็บไฟล์อัปโหลด main ตั้งค่าจาก env (STORAGE_BACKEND) ตอนทดสอบเปลี่ยนเป็น temp dir หรือ S3 ปลอมได้
+var FileStorage services.Storage = services.NewLocalStorage("./uploads", "/uploads")
+
+func uploadService() *services.UploadService {
+	svc := services.NewUploadService(config.DB, FileStorage)
+	svc.SigningKey = fileSigningKey()
+	return svc
+}
+
+// fileSigningKey - กุญแจลงชื่อ signed URL (FILE_URL_SECRET ถ้าไม่ตั้งใช้ JWT_SECRET)
+func fileSigningKey() []byte {
+	if secret := os.Getenv("FILE_URL_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+	return config.JWTSecret
+}
+
+// isUploadedURL - URL ของไฟล์ที่อัปโหลดผ่านระบบ
+func isUploadedURL(u string) bool {
+	return uploadService().IsStoredURL(u)
+}
+
+// canAttachURL - ผู้ใช้แนบไฟล์นี้ได้หรือไม่ (ดู UploadService.CanAttach) ตรวจไม่สำเร็จถือว่าแนบไม่ได้
+func canAttachURL(u string, userID, conversationID, bookingID uint) bool {
+	ok, err := uploadService().CanAttach(u, userID, conversationID, bookingID)
+	if err != nil {
+		log.Printf("attachment check for user #%d failed: %v", userID, err)
+	}
+	return ok
+}
+
+// uploadErrorCodes - error ของ UploadService ที่เป็นความผิดของ client กับรหัสข้อความที่ตอบกลับ
+var uploadErrorCodes = []struct {
+	err  error
+	code string
+}{
+	{services.ErrUnknownUploadPurpose, "upload_purpose_unknown"},
+	{services.ErrUploadEmpty, "upload_empty"},
+	{services.ErrUploadTooLarge, "upload_too_large"},
+	{services.ErrUploadTypeNotAllowed, "upload_type_not_allowed"},
+	{services.ErrInvalidImage, "upload_image_invalid"},
+}
+
+// saveUpload - อ่านไฟล์จาก form field แล้วบันทึกตาม policy ของ purpose
+// คืน nil เมื่อส่ง response error ไปแล้ว
+func saveUpload(c *fiber.Ctx, field, purpose string) (*models.Upload, error) {
+	userID := c.Locals("user_id").(uint)
+
+	fileHeader, err := c.FormFile(field)
+	if err != nil {
+		return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "file_upload_missing",
+			"field": field,
+		})
+	}
+	f, err := fileHeader.Open()
+	if err != nil {
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "uploaded_file_open_failed",
+		})
+	}
+	defer f.Close()
+
+	upload, err := uploadService().Save(userID, purpose, fileHeader.Filename, f, fileHeader.Size)
+	if err != nil {
+		for _, e := range uploadErrorCodes {
+			if errors.Is(err, e.err) {
+				return nil, c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error":   e.code,
+					"details": err.Error(),
+				})
+			}
+		}
+		log.Printf("upload by user #%d failed: %v", userID, err)
+		return nil, c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "file_save_failed",
+		})
+	}
+	return upload, nil
+}
+
 // UploadFile - อัปโหลดไฟล์และคืน URL
+// form: file, purpose (avatar, review_image, evidence, licence_document, message_attachment; ไม่ระบุ = evidence)
 func UploadFile(c *fiber.Ctx) error {
-	// Get file from form
-	file, err := c.FormFile("file")
+	purpose := c.FormValue("purpose", "evidence")
+
+	upload, err := saveUpload(c, "file", purpose)
+	if upload == nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"id":           upload.ID,
+		"url":          upload.URL,
+		"path":         upload.URL,
+		"purpose":      upload.Purpose,
+		"content_type": upload.ContentType,
+		"size":         upload.Size,
+	})
+}
+
+// sendUpload - ส่งเนื้อไฟล์จาก storage (ห้าม cache เพราะเป็นไฟล์ส่วนตัว)
+func sendUpload(c *fiber.Ctx, svc *services.UploadService, upload *models.Upload) error {
+	body, err := svc.Open(upload)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "No file uploaded",
+		if errors.Is(err, services.ErrStorageNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "file_not_found",
+			})
+		}
+		log.Printf("failed to open upload #%d: %v", upload.ID, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "file_read_failed",
 		})
 	}
+	c.Set(fiber.HeaderContentType, upload.ContentType)
+	c.Set(fiber.HeaderCacheControl, "private, no-store")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	return c.SendStream(body, int(upload.Size))
+}
 
-	// Generate unique filename
-	timestamp := time.Now().Unix()
-	ext := filepath.Ext(file.Filename)
-	filename := fmt.Sprintf("%d%s", timestamp, ext)
+func logFileAccess(c *fiber.Ctx, svc *services.UploadService, upload *models.Upload, userID uint, action string) {
+	if err := svc.LogFileAccess(upload, userID, action, c.IP(), c.Get(fiber.HeaderUserAgent)); err != nil {
+		log.Printf("failed to log %s of upload #%d by user #%d: %v", action, upload.ID, userID, err)
+	}
+}
 
-	// Ensure uploads directory exists
-	uploadDir := "./uploads"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
+// GetUploadAccess - ตรวจสิทธิ์แล้วคืน URL สำหรับเปิดไฟล์ (ไฟล์ส่วนตัวได้ signed URL อายุสั้น)
+// query: url = URL ของไฟล์ที่เก็บไว้ในรีพอร์ต/หลักฐาน/ใบสมัครไกด์
+func GetUploadAccess(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	svc := uploadService()
+
+	upload, err := svc.FindByURL(c.Query("url"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "file_not_found",
+		})
+	}
+	allowed, err := svc.CanAccess(upload, userID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create upload directory",
+			"error": "file_access_check_failed",
+		})
+	}
+	if !allowed {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "file_access_denied",
+		})
+	}
+	if !upload.Private {
+		return c.JSON(fiber.Map{
+			"url": upload.URL,
+		})
+	}
+
+	expiresAt := time.Now().Add(services.SignedFileURLTTL())
+	logFileAccess(c, svc, upload, userID, "sign_url")
+	return c.JSON(fiber.Map{
+		"url":        svc.SignedURL(upload, userID, expiresAt),
+		"expires_at": expiresAt,
+	})
+}
+
+// DownloadUpload - ดาวน์โหลดไฟล์ผ่าน token (สำหรับ client ที่ส่ง Authorization header ได้)
+func DownloadUpload(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(uint)
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "upload_id_invalid",
 		})
 	}
+	svc := uploadService()
 
-	// Save file
-	filePath := filepath.Join(uploadDir, filename)
-	if err := c.SaveFile(file, filePath); err != nil {
+	var upload models.Upload
+	if err := config.DB.First(&upload, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "file_not_found",
+		})
+	}
+	allowed, err := svc.CanAccess(&upload, userID)
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to save file",
+			"error": "file_access_check_failed",
+		})
+	}
+	if !allowed {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "file_access_denied",
 		})
 	}
 
-	// Return URL path
-	url := fmt.Sprintf("/uploads/%s", filename)
-	
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"url":  url,
-		"path": url,
+	logFileAccess(c, svc, &upload, userID, "download")
+	return sendUpload(c, svc, &upload)
+}
+
+// ServeSignedFile - เปิดไฟล์ส่วนตัวด้วย signed URL จาก GetUploadAccess (ไม่ต้องใช้ token เพื่อใช้ใน <img> ได้)
+func ServeSignedFile(c *fiber.Ctx) error {
+	key := c.Params("*")
+	expires, _ := strconv.ParseInt(c.Query("expires"), 10, 64)
+	uid, _ := strconv.ParseUint(c.Query("uid"), 10, 64)
+
+	svc := uploadService()
+	if !svc.VerifySignedURL(key, uint(uid), expires, c.Query("sig"), time.Now()) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "file_link_invalid",
+		})
+	}
+	upload, err := svc.FindPrivateByKey(key)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "file_not_found",
+		})
+	}
+
+	logFileAccess(c, svc, upload, uint(uid), "signed_download")
+	return sendUpload(c, svc, upload)
+}
+
+// AdminGetFileAccessLog - ประวัติการเข้าถึงไฟล์ (บันทึกเฉพาะเอกสารยืนยันตัวตน)
+func AdminGetFileAccessLog(c *fiber.Ctx) error {
+	id, err := c.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "upload_id_invalid",
+		})
+	}
+
+	var upload models.Upload
+	if err := config.DB.First(&upload, id).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "file_not_found",
+		})
+	}
+	var logs []models.FileAccessLog
+	if err := config.DB.Where("upload_id = ?", upload.ID).Order("created_at DESC").Find(&logs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "access_log_get_failed",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"upload": upload,
+		"logs":   logs,
 	})
 }
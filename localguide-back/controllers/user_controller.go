@@ -1,14 +1,9 @@
 package controllers
 
 import (
-	"fmt"
-	"io"
 	"localguide-back/config"
 	"localguide-back/models"
-	"net/http"
-	"os"
-	"path/filepath"
-	"strings"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -23,9 +18,10 @@ func GetUserProfile(c *fiber.Ctx) error {
 		Preload("AuthUser").
 		Preload("Role").
 		First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
 	}
 
+	localizeContent(c, &user)
 	return c.JSON(fiber.Map{"user": user})
 }
 
@@ -44,12 +40,12 @@ func UpdateUserProfile(c *fiber.Ctx) error {
 	}
 
 	if err := c.BodyParser(&req); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
 	}
 
 	var user models.User
 	if err := config.DB.First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
 	}
 
 	// Update fields
@@ -68,7 +64,7 @@ func UpdateUserProfile(c *fiber.Ctx) error {
 	}
 
 	if err := config.DB.Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update profile"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "profile_update_failed"})
 	}
 
 	// Reload user with relations
@@ -76,7 +72,7 @@ func UpdateUserProfile(c *fiber.Ctx) error {
 		Preload("AuthUser").
 		Preload("Role").
 		First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to reload user data"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_reload_failed"})
 	}
 
 	return c.JSON(fiber.Map{
@@ -93,7 +89,7 @@ func GetUserByID(c *fiber.Ctx) error {
 		Preload("AuthUser").
 		Preload("Role").
 		First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
 	}
 
 	return c.JSON(user)
@@ -104,124 +100,75 @@ func EditUser(c *fiber.Ctx) error {
 	var user models.User
 
 	if err := config.DB.First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
 	}
 
 	if err := c.BodyParser(&user); err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "invalid_request_body"})
 	}
 
 	if err := config.DB.Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update user"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "user_update_failed"})
 	}
 
 	return c.JSON(user)
 }
 
-// UploadProfileAvatar - อัพโหลดรูปโปรไฟล์ของผู้ใช้ที่ล็อกอิน
+// UploadProfileAvatar - อัพโหลดรูปโปรไฟล์ของผู้ใช้ที่ล็อกอิน (แทนที่รูปเดิม)
+// รูปถูกลบ metadata ย่อขนาด และมีรูปย่อใน avatar_variants (thumbnail, medium, large)
 func UploadProfileAvatar(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 
-	fileHeader, err := c.FormFile("avatar")
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No avatar file uploaded (field name: avatar)"})
-	}
-
-	// ขนาดสูงสุด 5MB
-	const maxSize = 5 * 1024 * 1024
-	if fileHeader.Size > maxSize {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "File too large. Max 5MB"})
-	}
-
-	// ตรวจสอบประเภทไฟล์โดยอ่าน header
-	f, err := fileHeader.Open()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to open uploaded file"})
-	}
-	defer f.Close()
-
-	buf := make([]byte, 512)
-	n, _ := f.Read(buf)
-	contentType := http.DetectContentType(buf[:n])
-	if !strings.HasPrefix(contentType, "image/") {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid file type. Only images are allowed"})
-	}
-
-	// สร้างโฟลเดอร์ถ้ายังไม่มี
-	uploadDir := "./uploads/avatars"
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create upload directory"})
-	}
-
-	// ตั้งชื่อไฟล์ให้ไม่ชนกัน
-	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
-	if ext == "" {
-		ext = ".jpg"
-	}
-	filename := fmt.Sprintf("avatar_%d_%d%s", userID, time.Now().Unix(), ext)
-	savePath := filepath.Join(uploadDir, filename)
-
-	// ใช้ c.SaveFile เพื่อบันทึกไฟล์
-	if err := c.SaveFile(fileHeader, savePath); err != nil {
-		// บางครั้ง c.SaveFile อาจล้มเหลวถ้าเราอ่านไฟล์ไปแล้ว; fallback write manually
-		// รีโอเพ่นและคัดลอก
-		f2, _ := fileHeader.Open()
-		defer func() {
-			if f2 != nil {
-				f2.Close()
-			}
-		}()
-		out, err2 := os.Create(savePath)
-		if err2 != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save file"})
-		}
-		defer out.Close()
-		if _, err3 := io.Copy(out, f2); err3 != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to write file"})
-		}
-	}
-
-	// บันทึก URL/เส้นทางลงฐานข้อมูล (ให้เข้าถึงได้จาก /uploads/...)
-	avatarURL := "/uploads/avatars/" + filename
-
 	var user models.User
 	if err := config.DB.First(&user, userID).Error; err != nil {
-		// ถ้าไม่พบผู้ใช้ ลบไฟล์ที่เพิ่งอัพขึ้น
-		os.Remove(savePath)
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
 	}
 
-	user.Avatar = avatarURL
+	upload, err := saveUpload(c, "avatar", "avatar")
+	if upload == nil {
+		return err
+	}
+
+	previous := user.Avatar
+	user.Avatar = upload.URL
 	if err := config.DB.Save(&user).Error; err != nil {
 		// ลบไฟล์ถ้า DB update ล้มเหลว
-		os.Remove(savePath)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update user avatar"})
+		uploadService().Delete(upload)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "avatar_update_failed"})
+	}
+	if previous != "" {
+		if err := uploadService().DeleteByURL(previous); err != nil {
+			log.Printf("failed to delete previous avatar of user #%d: %v", userID, err)
+		}
 	}
 
 	// Return updated user profile (selectively) และ URL
 	return c.JSON(fiber.Map{
 		"message":    "Avatar uploaded successfully",
-		"avatar_url": avatarURL,
-		"user":       user,
+		"avatar_url":      upload.URL,
+		"avatar_width":    upload.Width,
+		"avatar_height":   upload.Height,
+		"avatar_variants": upload.Variants,
+		"user":            user,
 	})
 }
 
-// DeleteProfileAvatar - ลบรูปโปรไฟล์ของผู้ใช้ (และไฟล์บน disk)
+// DeleteProfileAvatar - ลบรูปโปรไฟล์ของผู้ใช้ (และไฟล์ใน storage)
 func DeleteProfileAvatar(c *fiber.Ctx) error {
 	userID := c.Locals("user_id").(uint)
 	var user models.User
 	if err := config.DB.First(&user, userID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "user_not_found"})
 	}
 	if user.Avatar == "" {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "No avatar to delete"})
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no_avatar"})
+	}
+	if err := uploadService().DeleteByURL(user.Avatar); err != nil {
+		log.Printf("failed to delete avatar of user #%d: %v", userID, err)
 	}
-	// ลบไฟล์
-	path := "." + user.Avatar // avatar stored as /uploads/avatars/...
-	_ = os.Remove(path)
 	user.Avatar = ""
 	if err := config.DB.Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to clear avatar field"})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "avatar_clear_failed"})
 	}
 	return c.JSON(fiber.Map{"message": "Avatar deleted"})
 }
@@ -3,19 +3,26 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"localguide-back/config"
 	"localguide-back/controllers"
 	"localguide-back/middleware"
 	"localguide-back/migrations"
 	"localguide-back/models"
+	"localguide-back/services"
 
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
 func main() {
 	config.Init()
+
+	if err := migrations.DropTripPaymentIntentUnique(config.DB); err != nil {
+		log.Printf("Migration error: %v", err)
+	}
 	
 	if err := config.DB.AutoMigrate(
 		&models.AuthUser{}, 
@@ -23,45 +30,128 @@ func main() {
         &models.User{}, 
         &models.Province{}, 
         &models.Guide{},
+        &models.GuideRatingSummary{},
 		&models.Language{}, 
         &models.TouristAttraction{},
+        &models.AttractionCategory{},
 		&models.GuideCertification{}, 
         &models.GuideVertification{}, 
 		&models.PasswordReset{}, 
 		&models.TripRequire{}, 
         &models.TripOffer{}, 
         &models.TripOfferQuotation{}, 
+        &models.TripOfferStop{},
+        &models.TripOfferTemplate{},
         &models.TripBooking{}, 
+        &models.TripDayCheckIn{},
+        &models.TripBookingParticipant{},
+        &models.TripStartCheckIn{},
 		&models.TripPayment{}, 
         &models.TripReview{}, 
-        &models.TripReport{}, 
+        &models.TripReviewImage{},
+        &models.ReviewHelpfulVote{},
+        &models.ReviewFlag{},
+        &models.TripReport{},
+        &models.TripReportNote{},
+        &models.TripReportLink{},
+        &models.NoShowDispute{},
+        &models.DisputeStatement{},
         &models.PaymentRelease{},
+        &models.PaymentOutbox{},
+        &models.ReconciliationIssue{},
+        &models.DocumentSequence{},
+        &models.Invoice{},
+        &models.Notification{},
+        &models.NotificationPreference{},
+        &models.NotificationOutbox{},
+        &models.Conversation{},
+        &models.Message{},
+        &models.MessageAttachment{},
+        &models.Upload{},
+        &models.ImageVariant{},
+        &models.FileAccessLog{},
+        &models.Translation{},
 	); err != nil {
 		log.Printf("Migration error: %v", err)
 	} else {
 		log.Println("All tables migrated successfully")
 	}
 
+	if err := migrations.DropPaymentReleaseRecipientFK(config.DB); err != nil {
+		log.Printf("Migration error: %v", err)
+	}
+	if err := migrations.RelaxPaymentOutboxReleaseIndex(config.DB); err != nil {
+		log.Printf("Migration error: %v", err)
+	}
+	if err := migrations.ClearSplitPaymentRefs(config.DB); err != nil {
+		log.Printf("Migration error: %v", err)
+	}
+	if err := migrations.MigrateReviewImages(config.DB); err != nil {
+		log.Printf("Migration error: %v", err)
+	}
+	if err := services.BackfillGuideRatings(config.DB); err != nil {
+		log.Printf("Migration error: %v", err)
+	}
+
 	// Seed data
 	migrations.SeedRoles(config.DB)
 	migrations.SeedLanguages(config.DB)
 	migrations.SeedProvinces(config.DB)
-	migrations.SeedTouristAttractions(config.DB)
+	if err := migrations.SeedTouristAttractions(config.DB); err != nil {
+		log.Printf("Migration error: %v", err)
+	}
+	if err := migrations.MigrateAttractionCategories(config.DB); err != nil {
+		log.Printf("Migration error: %v", err)
+	}
 	migrations.SeedUsers(config.DB)                   
 	migrations.SeedGuides(config.DB)
 
-	app := fiber.New()
+	// Background jobs - จบทริปอัตโนมัติเมื่อ user ไม่ยืนยันหลังจบทริป ทำคำสั่งคืนเงิน/โอนเงินกับ Stripe และส่ง outbox การแจ้งเตือน
+	services.NewTripCompletionService(config.DB).Start(time.Hour)
+	services.NewPaymentOutboxWorker(config.DB, services.NewStripeService()).Start(time.Minute)
+	services.NewReconciliationService(config.DB, services.NewStripeService()).Start(6 * time.Hour)
+	services.NewNoShowDisputeService(config.DB).Start(15 * time.Minute)
+	services.NewNotificationDispatcher(config.DB, services.NewEmailChannel(services.NewSMTPSenderFromEnv())).Start(time.Minute)
+
+	// ที่เก็บไฟล์อัปโหลด (local disk หรือ S3) และ job ลบไฟล์ที่ไม่มีข้อมูลใดอ้างถึง
+	controllers.FileStorage = services.NewStorageFromEnv()
+	if local, ok := controllers.FileStorage.(*services.LocalStorage); ok {
+		if n, err := local.MovePrivateFiles(); err != nil {
+			log.Printf("Migration error: %v", err)
+		} else if n > 0 {
+			log.Printf("Moved %d private file(s) out of %s", n, local.Dir)
+		}
+	}
+	uploads := services.NewUploadService(config.DB, controllers.FileStorage)
+	// หลักฐานและเอกสารยืนยันตัวตนที่อัปโหลดก่อนมีไฟล์ส่วนตัว ย้ายไปไว้ใต้ private/
+	if n, err := uploads.MigratePrivateUploads(); err != nil {
+		log.Printf("Migration error: %v", err)
+	} else if n > 0 {
+		log.Printf("Moved %d upload(s) to private storage", n)
+	}
+	uploads.Start(6 * time.Hour)
+
+	// ขนาด body สูงสุดต้องพอสำหรับไฟล์หลักฐาน (ดู uploadPolicies)
+	app := fiber.New(fiber.Config{BodyLimit: 25 << 20})
 	
 	// Serve uploads static files
-	app.Static("/uploads", "./uploads")
+	uploadDir := "./uploads"
+	if local, ok := controllers.FileStorage.(*services.LocalStorage); ok {
+		uploadDir = local.Dir
+	}
+	// ไฟล์ส่วนตัว (หลักฐาน, เอกสารยืนยันตัวตน) อยู่นอก uploadDir (LocalStorage.PrivateDir) เปิดได้ผ่าน /api/files ด้วย signed URL เท่านั้น
+	app.Static("/uploads", uploadDir)
 	
 	// CORS configuration
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders: "Origin, Content-Type, Accept, Accept-Language, Authorization",
 	}))
 
+	// ข้อความ error ตามภาษา (?lang, ภาษาของผู้ใช้ หรือ Accept-Language)
+	app.Use(middleware.Localize())
+
 	// API routes group
 	api := app.Group("/api")
 	
@@ -76,6 +166,8 @@ func main() {
     api.Get("/provinces/:id/attractions", controllers.GetProvinceAttractions)
     api.Get("/languages", controllers.GetLanguages)
     api.Get("/attractions", controllers.GetTouristAttractions)
+    api.Get("/attractions/nearby", controllers.GetNearbyAttractions) // สถานที่ใกล้พิกัด ?lat=&lng=&radius= (กม.)
+    api.Get("/attraction-categories", controllers.GetAttractionCategories)
     api.Get("/guides", controllers.GetGuides)
     api.Get("/guides/:id", controllers.GetGuideByID)
     
@@ -98,6 +190,15 @@ func main() {
     api.Get("/trip-offers/:id", middleware.AuthRequired(), controllers.GetTripOfferByID)
     api.Put("/trip-offers/:id", middleware.AuthRequired(), controllers.UpdateTripOffer) // สำหรับแก้ไข
     api.Delete("/trip-offers/:id", middleware.AuthRequired(), controllers.WithdrawTripOffer)
+    api.Put("/trip-offers/:id/send", middleware.AuthRequired(), controllers.SendTripOffer) // ส่ง offer ที่เป็น draft
+
+    // Offer templates (แม่แบบ offer ของ guide)
+    api.Get("/offer-templates", middleware.AuthRequired(), controllers.GetOfferTemplates)
+    api.Post("/offer-templates", middleware.AuthRequired(), controllers.CreateOfferTemplate)
+    api.Get("/offer-templates/:id", middleware.AuthRequired(), controllers.GetOfferTemplateByID)
+    api.Put("/offer-templates/:id", middleware.AuthRequired(), controllers.UpdateOfferTemplate)
+    api.Delete("/offer-templates/:id", middleware.AuthRequired(), controllers.DeleteOfferTemplate)
+    api.Post("/offer-templates/:id/instantiate", middleware.AuthRequired(), controllers.InstantiateOfferTemplate) // สร้าง draft offer จากแม่แบบ
     
     // 3. Accept/Reject offer
     api.Put("/trip-offers/:id/accept", middleware.AuthRequired(), controllers.AcceptTripOffer)
@@ -107,6 +208,17 @@ func main() {
     api.Post("/trip-bookings/:id/payment", middleware.AuthRequired(), controllers.CreateTripPayment)
     api.Post("/trip-bookings/:id/payment/confirm", middleware.AuthRequired(), controllers.ConfirmTripPayment)
     api.Get("/trip-bookings/:id/payment", middleware.AuthRequired(), controllers.GetTripPayment)
+
+    // Group bookings - ผู้ร่วมเดินทางและการแยกจ่าย
+    api.Post("/trip-bookings/:id/participants", middleware.AuthRequired(), controllers.InviteBookingParticipant) // ผู้จองเชิญผู้ร่วมเดินทางทางอีเมล
+    api.Get("/trip-bookings/:id/participants", middleware.AuthRequired(), controllers.GetBookingParticipants)
+    api.Delete("/trip-bookings/:id/participants/:participantId", middleware.AuthRequired(), controllers.RemoveBookingParticipant)
+    api.Get("/booking-invites", middleware.AuthRequired(), controllers.GetMyBookingInvites) // คำเชิญที่ยังไม่ได้ตอบ
+    api.Post("/booking-invites/:token/accept", middleware.AuthRequired(), controllers.AcceptBookingInvite)
+    api.Post("/booking-invites/:token/decline", middleware.AuthRequired(), controllers.DeclineBookingInvite)
+    api.Post("/trip-bookings/:id/split-payment", middleware.AuthRequired(), controllers.SetupSplitPayment) // แบ่งยอดให้แต่ละคนจ่ายเอง
+    api.Post("/trip-bookings/:id/shares/pay", middleware.AuthRequired(), controllers.CreateSharePayment) // สร้าง PaymentIntent สำหรับส่วนแบ่งของตัวเอง
+    api.Post("/trip-bookings/:id/shares/confirm", middleware.AuthRequired(), controllers.ConfirmSharePayment)
     
     // Stripe webhook (ไม่ต้องใช้ auth เพราะมาจาก Stripe server)
     api.Post("/stripe/webhook", controllers.StripeWebhook)
@@ -114,14 +226,29 @@ func main() {
     // 5. Trip booking management
     api.Get("/trip-bookings", middleware.AuthRequired(), controllers.GetTripBookings) // ดู bookings ของตัวเอง
     api.Get("/trip-bookings/:id", middleware.AuthRequired(), controllers.GetTripBookingByID)
+    api.Get("/trip-bookings/:id/invoice", middleware.AuthRequired(), controllers.GetTripBookingInvoice) // ใบกำกับภาษี/ใบเสร็จ PDF (?number= ใบลดหนี้, ?format=json)
+    api.Put("/trip-bookings/:id/billing", middleware.AuthRequired(), controllers.UpdateTripBookingBilling) // ข้อมูลผู้ซื้อในใบกำกับภาษี (ก่อนชำระเงิน)
     
     // 6. Trip status management
     api.Put("/trip-bookings/:id/confirm-guide-arrival", middleware.AuthRequired(), controllers.ConfirmGuideArrival) // User ยืนยันไกด์มา -> ไกด์ได้เงิน 50%
+    api.Get("/trip-bookings/:id/start-code", middleware.AuthRequired(), controllers.GetTripStartCode) // User ดูรหัสเริ่มทริปเพื่อบอกไกด์
+    api.Put("/trip-bookings/:id/meeting-point", middleware.AuthRequired(), controllers.SetMeetingPoint) // User กำหนดจุดนัดพบ
+    api.Post("/trip-bookings/:id/guide-check-in", middleware.AuthRequired(), controllers.GuideCheckIn) // Guide กรอกรหัส (+GPS) -> เริ่มทริป ไกด์ได้เงิน 50%
+    api.Get("/trip-bookings/:id/start-check-ins", middleware.AuthRequired(), controllers.GetTripStartCheckIns)
     api.Put("/trip-bookings/:id/confirm-trip-complete", middleware.AuthRequired(), controllers.ConfirmTripComplete) // User ยืนยันทริปเสร็จ -> ไกด์ได้เงินเต็ม
+    api.Post("/trip-bookings/:id/check-ins", middleware.AuthRequired(), controllers.CheckInTripDay) // User/Guide เช็คอินรายวัน (ทริปหลายวัน)
+    api.Get("/trip-bookings/:id/check-ins", middleware.AuthRequired(), controllers.GetTripDayCheckIns)
+    api.Put("/trip-bookings/:id/request-early-completion", middleware.AuthRequired(), controllers.RequestEarlyCompletion) // ขอจบทริปก่อนวันสุดท้าย
+    api.Put("/trip-bookings/:id/agree-early-completion", middleware.AuthRequired(), controllers.AgreeEarlyCompletion) // อีกฝ่ายยอมรับการจบทริปก่อนกำหนด
     api.Put("/trip-bookings/:id/report-user-no-show", middleware.AuthRequired(), controllers.ReportUserNoShow) // Guide รีพอร์ต user ไม่มา -> ไกด์ได้ 50% + คืนเงินส่วนที่เหลือให้ user
     api.Put("/trip-bookings/:id/confirm-user-no-show", middleware.AuthRequired(), controllers.ConfirmUserNoShow) // User ยืนยันตัวเองไม่มา -> ไกด์ได้ 50% + คืนเงิน 50%
     api.Put("/trip-bookings/:id/report-guide-no-show", middleware.AuthRequired(), controllers.ReportGuideNoShow) // User รีพอร์ตไกด์ไม่มา
     api.Put("/trip-bookings/:id/dispute-no-show", middleware.AuthRequired(), controllers.DisputeNoShowReport) // User โต้แย้งการรีพอร์ต no-show
+    api.Get("/trip-bookings/:id/dispute", middleware.AuthRequired(), controllers.GetNoShowDispute) // ไทม์ไลน์ dispute no-show (คำชี้แจง, กำหนดเวลา, คำตัดสิน)
+    api.Post("/trip-bookings/:id/dispute/statements", middleware.AuthRequired(), controllers.AddDisputeStatement) // ส่งคำชี้แจง/หลักฐานเพิ่ม
+    api.Post("/trip-bookings/:id/reports", middleware.AuthRequired(), controllers.CreateTripReport) // User/Guide รีพอร์ตปัญหาความปลอดภัย/พฤติกรรม/การชำระเงิน
+    api.Get("/trip-bookings/:id/reports", middleware.AuthRequired(), controllers.GetTripBookingReports) // รีพอร์ตที่ตัวเองส่งใน booking นี้
+    api.Get("/trip-reports/mine", middleware.AuthRequired(), controllers.GetMyTripReports) // สถานะรีพอร์ตและคำตอบจาก admin
 
     // 7. Review system routes (for guides only)
     api.Post("/reviews", middleware.AuthRequired(), controllers.CreateReview) // User สร้างรีวิวให้ไกด์
@@ -130,13 +257,35 @@ func main() {
     api.Put("/reviews/:id", middleware.AuthRequired(), controllers.UpdateReview) // แก้ไขรีวิว (เฉพาะเจ้าของ)
     api.Delete("/reviews/:id", middleware.AuthRequired(), controllers.DeleteReview) // ลบรีวิว (เฉพาะเจ้าของ)
     api.Post("/reviews/:id/response", middleware.AuthRequired(), controllers.GuideRespondToReview) // ไกด์ตอบกลับรีวิว
-    api.Post("/reviews/:id/helpful", middleware.AuthRequired(), controllers.MarkReviewHelpful) // ทำเครื่องหมายรีวิวว่าเป็นประโยชน์
+    api.Post("/reviews/:id/helpful", middleware.AuthRequired(), controllers.MarkReviewHelpful) // ทำเครื่องหมายรีวิวว่าเป็นประโยชน์ (หนึ่งครั้งต่อคน)
+    api.Delete("/reviews/:id/helpful", middleware.AuthRequired(), controllers.UnmarkReviewHelpful) // ยกเลิก helpful
+    api.Post("/reviews/:id/flag", middleware.AuthRequired(), controllers.FlagReview) // แจ้งรีวิวไม่เหมาะสมให้ admin ตรวจ
     api.Get("/trip-bookings/reviewable", middleware.AuthRequired(), controllers.GetReviewableBookings) // ดู bookings ที่รีวิวได้
 
+    // 9. Notifications
+    api.Get("/notifications", middleware.AuthRequired(), controllers.GetNotifications) // feed การแจ้งเตือน (?unread=true)
+    api.Get("/notifications/unread-count", middleware.AuthRequired(), controllers.GetUnreadNotificationCount)
+    api.Put("/notifications/read-all", middleware.AuthRequired(), controllers.MarkAllNotificationsRead)
+    api.Put("/notifications/:id/read", middleware.AuthRequired(), controllers.MarkNotificationRead)
+    api.Get("/notifications/preferences", middleware.AuthRequired(), controllers.GetNotificationPreferences)
+    api.Put("/notifications/preferences", middleware.AuthRequired(), controllers.UpdateNotificationPreferences) // ภาษา + เปิด/ปิดช่องทาง
+
+    // 8. Messaging (traveller <-> guide)
+    api.Post("/conversations", middleware.AuthRequired(), controllers.StartConversation) // เปิดห้องสนทนาของ offer หรือ booking
+    api.Get("/conversations", middleware.AuthRequired(), controllers.GetConversations) // ห้องของฉัน + จำนวนที่ยังไม่อ่าน
+    api.Get("/conversations/:id/messages", middleware.AuthRequired(), controllers.GetMessages) // polling (?after_id=)
+    api.Post("/conversations/:id/messages", middleware.AuthRequired(), controllers.SendMessage)
+    api.Put("/conversations/:id/read", middleware.AuthRequired(), controllers.MarkConversationRead) // read receipt
+    api.Get("/ws/conversations/:id", middleware.WebSocketAuth(), controllers.ConversationSocketUpgrade, websocket.New(controllers.ConversationSocket)) // WebSocket stream (?token=)
+
     // User profile routes
     api.Get("/users/profile", middleware.AuthRequired(), controllers.GetUserProfile)
     api.Put("/users/profile", middleware.AuthRequired(), controllers.UpdateUserProfile)
     api.Post("/guides", middleware.AuthRequired(), controllers.CreateGuide)
+    api.Get("/guides/me/earnings", middleware.AuthRequired(), controllers.GetMyEarnings) // รายได้ของไกด์ (?from=&to=)
+    api.Get("/guides/me/earnings/statements/:month", middleware.AuthRequired(), controllers.GetMyEarningsStatement) // statement รายเดือน (?format=csv|pdf)
+    api.Get("/guides/me/translations", middleware.AuthRequired(), controllers.GetMyGuideTranslations)
+    api.Put("/guides/me/translations", middleware.AuthRequired(), controllers.SetMyGuideTranslations) // bio/description ภาษาอื่น
     api.Get("/users/:id", middleware.AuthRequired(), middleware.OwnerOrAdminRequired(), controllers.GetUserByID)
     api.Put("/users/:id", middleware.AuthRequired(), middleware.OwnerOrAdminRequired(), controllers.EditUser)
     api.Get("/me", middleware.AuthRequired(), controllers.Me)
@@ -147,17 +296,67 @@ func main() {
 
     // Upload endpoint for evidence files
     api.Post("/uploads", middleware.AuthRequired(), controllers.UploadFile)
+    api.Get("/uploads/access", middleware.AuthRequired(), controllers.GetUploadAccess) // ขอ signed URL ของไฟล์ส่วนตัว (?url=)
+    api.Get("/uploads/:id/download", middleware.AuthRequired(), controllers.DownloadUpload)
+    api.Get("/files/*", controllers.ServeSignedFile) // ไฟล์ส่วนตัวผ่าน signed URL (ไม่ต้องใช้ token)
 
     // Admin routes
     admin := api.Group("/admin", middleware.AuthRequired(), middleware.AdminRequired())
     admin.Get("/guides", controllers.GetAllGuides)
     admin.Get("/verifications", controllers.GetPendingVerifications)
     admin.Put("/verifications/:id/status", controllers.ApproveGuide)
-    admin.Get("/trip-reports", controllers.GetAllTripReports)
+    admin.Get("/trip-reports", controllers.GetAllTripReports) // คิวเคส (?status=&severity=&type=&assigned_to=&min_age_hours=&overdue=true)
+    admin.Get("/trip-reports/:id", controllers.GetTripReportCase) // รายละเอียดเคส + SLA + บันทึกภายใน + รีพอร์ตที่เกี่ยวข้อง
     admin.Put("/trip-reports/:id", controllers.HandleTripReport)
+    admin.Put("/trip-reports/:id/assign", controllers.AssignTripReport) // มอบหมายเคสให้ admin
+    admin.Post("/trip-reports/:id/notes", controllers.AddTripReportNote) // บันทึกภายใน
+    admin.Post("/trip-reports/:id/links", controllers.LinkTripReports) // เชื่อมรีพอร์ตใน booking เดียวกัน
+    admin.Delete("/trip-reports/:id/links/:relatedId", controllers.UnlinkTripReports)
+    admin.Post("/trip-reports/:id/resolve", controllers.ResolveTripReportCase) // ปิดเคส + คืนเงิน/แบ่ง/จ่ายไกด์
+    admin.Get("/reviews/moderation", controllers.GetReviewModerationQueue) // รีวิวที่ถูกแจ้ง/ถูกซ่อน (?status=flagged|hidden|all)
+    admin.Put("/reviews/:id/hide", controllers.HideReview) // ซ่อนรีวิวพร้อมเหตุผล
+    admin.Put("/reviews/:id/restore", controllers.RestoreReview) // คืนรีวิว/ยกเลิกการแจ้ง
     admin.Get("/payments", controllers.GetAllPayments)
     admin.Put("/payments/:id/release", controllers.ManualReleasePayment)
-    admin.Put("/trip-bookings/:id/resolve-dispute", controllers.AdminResolveNoShowDispute) // Admin ตัดสินกรณี dispute
+    admin.Put("/payments/:id/payout-freeze", controllers.SetPaymentPayoutFreeze) // ระงับ/ยกเลิกการระงับการจ่ายไกด์
+    admin.Get("/reports/financial", controllers.GetFinancialReport) // GMV/คืนเงิน/จ่ายไกด์/รายได้ ตามวัน/สัปดาห์/เดือน (?group_by=province|guide&format=csv)
+    admin.Get("/reports/financial/summary", controllers.GetFinancialSummary) // ยอดรวม + escrow + อัตรา dispute/no-show
+    admin.Get("/payment-outbox", controllers.GetPaymentOutbox) // คำสั่งคืนเงิน/โอนเงินกับ Stripe (?status=failed)
+    admin.Put("/payment-outbox/:id/retry", controllers.RetryPaymentOutbox)
+    admin.Get("/reconciliation", controllers.GetReconciliationIssues) // ข้อมูลการชำระเงินที่ไม่ตรงกับ Stripe (?status=open)
+    admin.Post("/reconciliation/run", controllers.RunReconciliation)
+    admin.Post("/reconciliation/:id/resync", controllers.ResyncReconciliationIssue)
+    admin.Put("/reconciliation/:id/ignore", controllers.IgnoreReconciliationIssue)
+    admin.Put("/guides/:id/stripe-account", controllers.SetGuideStripeAccount) // บัญชี Stripe Connect สำหรับโอนเงินให้ไกด์
+    admin.Get("/trip-bookings/:id/dispute", controllers.AdminGetNoShowDispute) // ไทม์ไลน์ dispute no-show
+    admin.Put("/trip-bookings/:id/resolve-dispute", controllers.AdminResolveNoShowDispute) // Admin ตัดสินกรณี dispute (ต้องมี reason)
+    admin.Get("/uploads/:id/access-log", controllers.AdminGetFileAccessLog) // ประวัติการเปิดเอกสารยืนยันตัวตน
+    // Catalogue: ลบได้เฉพาะรายการที่ไม่มีข้อมูลอ้างถึง (ดู /impact) ไม่เช่นนั้นใช้ /merge
+    admin.Post("/provinces", controllers.AdminCreateProvince)
+    admin.Put("/provinces/:id", controllers.AdminUpdateProvince)
+    admin.Delete("/provinces/:id", controllers.AdminDeleteProvince)
+    admin.Get("/provinces/:id/impact", controllers.AdminGetProvinceImpact) // ไกด์/ใบสมัคร/สถานที่/โพสต์ทริปที่อ้างถึง
+    admin.Post("/provinces/:id/merge", controllers.AdminMergeProvince) // ย้ายการอ้างถึงไปที่ target_id แล้วลบ
+    admin.Post("/attractions", controllers.AdminCreateAttraction)
+    admin.Post("/attractions/import", controllers.AdminImportAttractions) // นำเข้า CSV/GeoJSON (dry run เว้นแต่ ?apply=true)
+    admin.Get("/attractions/export", controllers.AdminExportAttractions)  // ส่งออก ?format=csv|geojson
+    admin.Put("/attractions/:id", controllers.AdminUpdateAttraction)
+    admin.Delete("/attractions/:id", controllers.AdminDeleteAttraction)
+    admin.Get("/attractions/:id/impact", controllers.AdminGetAttractionImpact)
+    admin.Post("/attractions/:id/merge", controllers.AdminMergeAttraction)
+    admin.Put("/attractions/:id/image", controllers.UploadAttractionImage) // รูปหลักของสถานที่ท่องเที่ยว (สร้างรูปย่อ)
+    admin.Post("/attraction-categories", controllers.AdminCreateAttractionCategory)
+    admin.Put("/attraction-categories/:id", controllers.AdminUpdateAttractionCategory)
+    admin.Delete("/attraction-categories/:id", controllers.AdminDeleteAttractionCategory)
+    admin.Get("/attraction-categories/:id/impact", controllers.AdminGetAttractionCategoryImpact)
+    admin.Post("/attraction-categories/:id/merge", controllers.AdminMergeAttractionCategory)
+    admin.Post("/languages", controllers.AdminCreateLanguage)
+    admin.Put("/languages/:id", controllers.AdminUpdateLanguage)
+    admin.Delete("/languages/:id", controllers.AdminDeleteLanguage)
+    admin.Get("/languages/:id/impact", controllers.AdminGetLanguageImpact)
+    admin.Post("/languages/:id/merge", controllers.AdminMergeLanguage)
+    admin.Get("/translations/:type/:id", controllers.AdminGetTranslations) // คำแปลของ province, attraction, category, language, role, guide
+    admin.Put("/translations/:type/:id", controllers.AdminSetTranslations) // {"language": "en", "fields": {"name": "..."}}
     
     // Google Auth routes
     api.Get("/auth/google/login", controllers.GoogleLogin)
@@ -23,7 +23,7 @@ func AuthRequired() fiber.Handler {
 		authHeader := c.Get("Authorization")
 		if authHeader == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Authorization header required",
+				"error": "authorization_header_required",
 			})
 		}
 
@@ -31,42 +31,72 @@ func AuthRequired() fiber.Handler {
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 		if tokenString == authHeader {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token format",
+				"error": "token_format_invalid",
 			})
 		}
 
-		// Parse และ validate JWT token
-		token, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
-			return config.JWTSecret, nil 
-		})
-
-		if err != nil || !token.Valid {
+		userID, roleID, errMsg := parseToken(tokenString)
+		if errMsg != "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token",
+				"error": errMsg,
 			})
 		}
 
-		// ดึงข้อมูล claims
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+		// เก็บข้อมูล user ใน context
+		c.Locals("user_id", userID)
+		c.Locals("role_id", roleID)
+
+		return c.Next()
+	}
+}
+
+// parseToken - ตรวจสอบ JWT และดึง user_id, role_id (คืนรหัสข้อความ error ถ้าไม่ผ่าน)
+func parseToken(tokenString string) (uint, uint, string) {
+	token, err := jwt.ParseWithClaims(tokenString, jwt.MapClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return config.JWTSecret, nil
+	})
+
+	if err != nil || !token.Valid {
+		return 0, 0, "token_invalid"
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, 0, "token_claims_invalid"
+	}
+
+	userIDFloat, userIDExists := claims["user_id"].(float64)
+	roleIDFloat, roleIDExists := claims["role_id"].(float64)
+	if !userIDExists || !roleIDExists {
+		return 0, 0, "token_missing_claims"
+	}
+
+	return uint(userIDFloat), uint(roleIDFloat), ""
+}
+
+// WebSocketAuth middleware - เหมือน AuthRequired แต่รับ token จาก query (?token=) ได้
+// เพราะ browser ส่ง Authorization header ตอนเปิด WebSocket ไม่ได้
+func WebSocketAuth() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenString := c.Query("token")
+		if tokenString == "" {
+			tokenString = strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+		}
+		if tokenString == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid token claims",
+				"error": "token_required",
 			})
 		}
 
-		// ดึง user_id และ role_id จาก claims
-		userIDFloat, userIDExists := claims["user_id"].(float64)
-		roleIDFloat, roleIDExists := claims["role_id"].(float64)
-		
-		if !userIDExists || !roleIDExists {
+		userID, roleID, errMsg := parseToken(tokenString)
+		if errMsg != "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Missing user or role information in token",
+				"error": errMsg,
 			})
 		}
 
-		// เก็บข้อมูล user ใน context
-		c.Locals("user_id", uint(userIDFloat))
-		c.Locals("role_id", uint(roleIDFloat))
+		c.Locals("user_id", userID)
+		c.Locals("role_id", roleID)
 
 		return c.Next()
 	}
@@ -78,14 +108,14 @@ func AdminRequired() fiber.Handler {
 		roleID := c.Locals("role_id")
 		if roleID == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "User role not found",
+				"error": "user_role_not_found",
 			})
 		}
 
 		role, ok := roleID.(uint)
 		if !ok || role != 3 { // 3 = Admin role
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Admin access required",
+				"error": "admin_required",
 			})
 		}
 
@@ -99,14 +129,14 @@ func GuideRequired() fiber.Handler {
 		roleID := c.Locals("role_id")
 		if roleID == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "User role not found",
+				"error": "user_role_not_found",
 			})
 		}
 
 		role, ok := roleID.(uint)
 		if !ok || role != 2 { // 2 = Guide role
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Guide access required",
+				"error": "guide_required",
 			})
 		}
 
@@ -120,14 +150,14 @@ func UserRequired() fiber.Handler {
 		roleID := c.Locals("role_id")
 		if roleID == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "User role not found",
+				"error": "user_role_not_found",
 			})
 		}
 
 		role, ok := roleID.(uint)
 		if !ok || (role != 1 && role != 2 && role != 3) { // 1 = User, 2 = Guide, 3 = Admin
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "User access required",
+				"error": "user_required",
 			})
 		}
 
@@ -143,7 +173,7 @@ func OwnerOrAdminRequired() fiber.Handler {
 		
 		if userID == nil || roleID == nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "User information not found",
+				"error": "user_info_not_found",
 			})
 		}
 
@@ -152,7 +182,7 @@ func OwnerOrAdminRequired() fiber.Handler {
 		
 		if !ok1 || !ok2 {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Invalid user information",
+				"error": "user_info_invalid",
 			})
 		}
 
@@ -165,14 +195,14 @@ func OwnerOrAdminRequired() fiber.Handler {
 		paramUserID := c.Params("id")
 		if paramUserID == "" {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "User ID parameter required",
+				"error": "user_id_required",
 			})
 		}
 
 		// เปรียบเทียบ ID โดยตรง (convert uint to string)
 		if fmt.Sprintf("%d", currentUserID) != paramUserID {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Access denied: You can only access your own data",
+				"error": "own_data_only",
 			})
 		}
 
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"localguide-back/config"
+	"localguide-back/models"
+	"localguide-back/services"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestLanguage - ภาษาของ response สำหรับ request นี้ (en, th)
+// ลำดับ: ?lang → ภาษาที่ผู้ใช้ที่ login ตั้งไว้ → Accept-Language → services.DefaultLanguage
+// เรียกหลัง AuthRequired เพื่อให้ใช้ภาษาของผู้ใช้ได้ ผลลัพธ์เก็บใน Locals("lang")
+func RequestLanguage(c *fiber.Ctx) string {
+	if lang, ok := c.Locals("lang").(string); ok {
+		return lang
+	}
+	lang := resolveLanguage(c)
+	c.Locals("lang", lang)
+	return lang
+}
+
+func resolveLanguage(c *fiber.Ctx) string {
+	if lang := strings.ToLower(strings.TrimSpace(c.Query("lang"))); services.IsSupportedLanguage(lang) {
+		return lang
+	}
+	if userID, ok := c.Locals("user_id").(uint); ok && config.DB != nil {
+		var lang string
+		config.DB.Model(&models.User{}).Where("id = ?", userID).Select("language").Scan(&lang)
+		if services.IsSupportedLanguage(lang) {
+			return lang
+		}
+	}
+	if lang := parseAcceptLanguage(c.Get(fiber.HeaderAcceptLanguage)); lang != "" {
+		return lang
+	}
+	return services.DefaultLanguage
+}
+
+// parseAcceptLanguage - เลือกภาษาที่รองรับที่มี q สูงสุด (en-US นับเป็น en) คืน "" ถ้าไม่มี
+func parseAcceptLanguage(header string) string {
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		tag := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexByte(tag, '-'); i >= 0 {
+			tag = tag[:i]
+		}
+		if !services.IsSupportedLanguage(tag) {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			candidates = append(candidates, candidate{tag, q})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+// Localize middleware - แปลง response error ที่เป็นรหัสใน message catalogue เป็นข้อความตามภาษาของ request
+// {"error": "booking_not_found"} → {"error": "ไม่พบการจอง", "code": "booking_not_found"}
+// ข้อความที่ไม่อยู่ใน catalogue (เช่น error จาก service) ส่งต่อตามเดิม และตั้ง Content-Language ทุก response
+func Localize() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+		lang := RequestLanguage(c)
+		c.Set(fiber.HeaderContentLanguage, lang)
+
+		if c.Response().StatusCode() < fiber.StatusBadRequest ||
+			!strings.HasPrefix(string(c.Response().Header.ContentType()), fiber.MIMEApplicationJSON) {
+			return nil
+		}
+		dec := json.NewDecoder(bytes.NewReader(c.Response().Body()))
+		dec.UseNumber()
+		var body map[string]interface{}
+		if dec.Decode(&body) != nil {
+			return nil
+		}
+		code, ok := body["error"].(string)
+		if !ok || !services.HasMessage(code) {
+			return nil
+		}
+		body["error"] = services.Message(code, lang)
+		body["code"] = code
+		out, err := json.Marshal(body)
+		if err != nil {
+			return nil
+		}
+		c.Response().SetBodyRaw(out)
+		return nil
+	}
+}
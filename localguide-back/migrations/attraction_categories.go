@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"localguide-back/models"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MigrateAttractionCategories สร้างประเภทใน attraction_categories จากข้อความ Category เดิมของสถานที่ท่องเที่ยว
+// แล้วผูก CategoryID (เฉพาะรายการที่ยังไม่มี CategoryID เรียกซ้ำได้)
+func MigrateAttractionCategories(db *gorm.DB) error {
+	var names []string
+	if err := db.Model(&models.TouristAttraction{}).
+		Where("category_id IS NULL AND category <> ''").
+		Distinct().Pluck("category", &names).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range names {
+			trimmed := strings.TrimSpace(name)
+			if trimmed == "" {
+				continue
+			}
+			var category models.AttractionCategory
+			if err := tx.Where("LOWER(name) = LOWER(?)", trimmed).
+				Attrs(models.AttractionCategory{Name: trimmed}).
+				FirstOrCreate(&category).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.TouristAttraction{}).
+				Where("category_id IS NULL AND category = ?", name).
+				Updates(map[string]interface{}{"category_id": category.ID, "category": category.Name}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
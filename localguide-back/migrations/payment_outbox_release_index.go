@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"localguide-back/models"
+
+	"gorm.io/gorm"
+)
+
+// RelaxPaymentOutboxReleaseIndex เปลี่ยน unique index เดิมของ payment_outboxes.payment_release_id เป็น index ธรรมดา
+// คืนเงิน booking แยกจ่ายแบ่งเป็นหลายคำสั่ง (หนึ่งคำสั่งต่อ PaymentIntent ของผู้ร่วมเดินทาง) ภายใต้ release เดียว
+// AutoMigrate ไม่แก้ index ที่ชื่อเดิมมีอยู่แล้ว จึงต้องลบแล้วสร้างใหม่เอง
+func RelaxPaymentOutboxReleaseIndex(db *gorm.DB) error {
+	const name = "idx_payment_outboxes_payment_release_id"
+	indexes, err := db.Migrator().GetIndexes(&models.PaymentOutbox{})
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if index.Name() != name {
+			continue
+		}
+		if unique, ok := index.Unique(); !ok || !unique {
+			return nil
+		}
+		if err := db.Migrator().DropIndex(&models.PaymentOutbox{}, name); err != nil {
+			return err
+		}
+		return db.Migrator().CreateIndex(&models.PaymentOutbox{}, "PaymentReleaseID")
+	}
+	return nil
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import (
+	"localguide-back/models"
+
+	"gorm.io/gorm"
+)
+
+// DropPaymentReleaseRecipientFK ลบ foreign key เดิมของ payment_releases.recipient_id ที่ชี้ไปที่ users
+// RecipientID ของ release ไกด์เป็น Guide.ID จึงอ้างอิงตารางเดียวไม่ได้ (ใช้ RecipientType บอกว่าเป็นตารางไหน)
+func DropPaymentReleaseRecipientFK(db *gorm.DB) error {
+	const name = "fk_payment_releases_recipient"
+	if !db.Migrator().HasConstraint(&models.PaymentRelease{}, name) {
+		return nil
+	}
+	return db.Migrator().DropConstraint(&models.PaymentRelease{}, name)
+}
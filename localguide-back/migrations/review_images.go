@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"encoding/json"
+	"localguide-back/models"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// MigrateReviewImages ย้ายรูปรีวิวจากคอลัมน์ trip_reviews.images (ข้อความ JSON array ของ URL)
+// ไปเป็นแถวใน trip_review_images ตามลำดับเดิม แล้วลบคอลัมน์เก่า
+// ข้อมูลเดิมที่ไม่ใช่ JSON อ่านเป็น URL เดียวหรือหลาย URL คั่นด้วย comma ถ้ายังมีรีวิวที่อ่านไม่ได้จะเก็บคอลัมน์ไว้
+// รีวิวที่มีแถวรูปแล้วจะข้าม จึงเรียกซ้ำได้
+// ต้องเรียกหลัง AutoMigrate(&models.TripReviewImage{})
+func MigrateReviewImages(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.TripReview{}, "images") {
+		return nil
+	}
+
+	var rows []struct {
+		ID     uint
+		Images string
+	}
+	if err := db.Table("trip_reviews").Select("id, images").
+		Where("images IS NOT NULL AND images <> '' AND images <> '[]'").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	skipped := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, row := range rows {
+			var migrated int64
+			if err := tx.Model(&models.TripReviewImage{}).Where("trip_review_id = ?", row.ID).Count(&migrated).Error; err != nil {
+				return err
+			}
+			if migrated > 0 {
+				continue
+			}
+			urls, ok := legacyReviewImageURLs(row.Images)
+			if !ok {
+				log.Printf("review #%d: skipping unreadable images %q", row.ID, row.Images)
+				skipped++
+				continue
+			}
+			var images []models.TripReviewImage
+			for _, u := range urls {
+				u = strings.TrimSpace(u)
+				if u == "" {
+					continue
+				}
+				image := models.TripReviewImage{TripReviewID: row.ID, URL: u, Position: len(images)}
+				var upload models.Upload
+				if err := tx.Select("id").Where("url = ?", u).Limit(1).Find(&upload).Error; err == nil && upload.ID != 0 {
+					image.UploadID = &upload.ID
+				}
+				images = append(images, image)
+			}
+			if len(images) == 0 {
+				continue
+			}
+			if err := tx.Create(&images).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if skipped > 0 {
+		log.Printf("keeping trip_reviews.images: %d review(s) could not be migrated", skipped)
+		return nil
+	}
+	return db.Migrator().DropColumn(&models.TripReview{}, "images")
+}
+
+// legacyReviewImageURLs อ่านค่า images เดิม: JSON array หรือ URL เดียว/คั่นด้วย comma
+// ค่าที่ขึ้นต้นด้วย [ แต่ไม่ใช่ JSON ที่ถูกต้องถือว่าอ่านไม่ได้
+func legacyReviewImageURLs(stored string) ([]string, bool) {
+	var urls []string
+	if err := json.Unmarshal([]byte(stored), &urls); err == nil {
+		return urls, true
+	}
+	if strings.HasPrefix(strings.TrimSpace(stored), "[") {
+		return nil, false
+	}
+	return strings.Split(stored, ","), true
+}
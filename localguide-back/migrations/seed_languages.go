@@ -6,7 +6,11 @@ import (
 	"gorm.io/gorm"
 )
 
+// SeedLanguages ใส่ภาษาตั้งต้นเมื่อยังไม่มีข้อมูล หลังจากนั้นจัดการผ่าน admin API
 func SeedLanguages(db *gorm.DB) {
+	if catalogueSeeded(db, &models.Language{}) {
+		return
+	}
 	languages := []models.Language{
 		{Name: "Thai"},
 		{Name: "English"},
@@ -24,3 +28,10 @@ func SeedLanguages(db *gorm.DB) {
 		db.FirstOrCreate(&language, models.Language{Name: language.Name})
 	}
 }
+
+// catalogueSeeded - ตารางมีข้อมูลแล้วหรือไม่ (นับรวมรายการที่ถูกลบ)
+func catalogueSeeded(db *gorm.DB, model interface{}) bool {
+	var count int64
+	db.Unscoped().Model(model).Count(&count)
+	return count > 0
+}
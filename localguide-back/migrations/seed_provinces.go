@@ -6,7 +6,12 @@ import (
 	"gorm.io/gorm"
 )
 
+// SeedProvinces ใส่จังหวัดตั้งต้นเมื่อยังไม่มีข้อมูล หลังจากนั้นจัดการผ่าน admin API
+// (ไม่ seed ซ้ำ เพื่อไม่ให้รายการที่ admin ลบ/รวม/แก้ชื่อแล้วกลับมา)
 func SeedProvinces(db *gorm.DB) error {
+    if catalogueSeeded(db, &models.Province{}) {
+        return nil
+    }
     provinces := []models.Province{
         {Name: "เชียงราย", Region: "ภาคเหนือ"},
         {Name: "น่าน", Region: "ภาคเหนือ"},
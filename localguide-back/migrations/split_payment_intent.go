@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"localguide-back/models"
+
+	"gorm.io/gorm"
+)
+
+// DropTripPaymentIntentUnique ลบ unique constraint เดิมของ trip_payments.stripe_payment_intent_id
+// ให้ AutoMigrate สร้าง unique index ที่ยกเว้นค่าว่างแทน (payment แยกจ่ายไม่มี PaymentIntent ของตัวเอง)
+// ต้องเรียกก่อน AutoMigrate
+func DropTripPaymentIntentUnique(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.TripPayment{}) {
+		return nil
+	}
+	for _, name := range []string{"uni_trip_payments_stripe_payment_intent_id", "trip_payments_stripe_payment_intent_id_key"} {
+		if !db.Migrator().HasConstraint(&models.TripPayment{}, name) {
+			continue
+		}
+		if err := db.Migrator().DropConstraint(&models.TripPayment{}, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClearSplitPaymentRefs ล้าง ref ปลอม GROUP-<id> ที่เคยใส่ไว้ใน stripe_payment_intent_id ของ payment แยกจ่าย
+// (TransactionID ยังเก็บ GROUP-<id> ไว้เหมือนเดิม)
+func ClearSplitPaymentRefs(db *gorm.DB) error {
+	return db.Model(&models.TripPayment{}).
+		Where("payment_method = ? AND stripe_payment_intent_id LIKE ?", "stripe_split", "GROUP-%").
+		Update("stripe_payment_intent_id", "").Error
+}
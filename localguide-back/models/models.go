@@ -26,6 +26,7 @@ type User struct {
 	Phone       string `gorm:"not null;default:''"` 
 	Sex         string `gorm:"not null;default:''"` 
 	Avatar      string 
+	Language    string `gorm:"not null;default:'th'"` // ภาษาที่ใช้ส่งการแจ้งเตือน และภาษาของ response เมื่อไม่ได้ส่ง ?lang (th, en)
 }
 
 type Guide struct {
@@ -36,11 +37,31 @@ type Guide struct {
 	Description       string              `gorm:"not null"` 
 	Available         bool                
 	Rating            float64             
+	StripeAccountID   string              `json:"-"` // บัญชี Stripe Connect สำหรับโอนเงินให้ไกด์ (ว่าง = จ่ายนอกระบบ)
 	ProvinceID        uint                `gorm:"not null"` // เพิ่ม ProvinceID
 	Province          Province            `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:ProvinceID"`
 	Language          []Language          `gorm:"many2many:guide_languages"`
 	TouristAttraction []TouristAttraction `gorm:"many2many:guide_attractions"`
 	Certification 	  []GuideCertification `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:GuideID"`
+	RatingSummary     *GuideRatingSummary `gorm:"foreignKey:GuideID"`
+}
+
+// GuideRatingSummary - คะแนนรวมของไกด์จากรีวิวที่แสดงอยู่ คำนวณใหม่ทุกครั้งที่สร้าง/แก้/ลบ/ซ่อนรีวิว
+type GuideRatingSummary struct {
+	GuideID              uint      `gorm:"primaryKey;autoIncrement:false"`
+	ReviewCount          int       `gorm:"default:0"`
+	AverageRating        float64   `gorm:"default:0"`
+	AverageService       float64   `gorm:"default:0"`
+	AverageKnowledge     float64   `gorm:"default:0"`
+	AverageCommunication float64   `gorm:"default:0"`
+	AveragePunctuality   float64   `gorm:"default:0"`
+	FiveStars            int       `gorm:"default:0"`
+	FourStars            int       `gorm:"default:0"`
+	ThreeStars           int       `gorm:"default:0"`
+	TwoStars             int       `gorm:"default:0"`
+	OneStar              int       `gorm:"default:0"`
+	BayesianScore        float64   `gorm:"default:0;index"` // คะแนนที่ถ่วงด้วยค่าเฉลี่ยตั้งต้น ใช้เรียงลำดับไกด์
+	UpdatedAt            time.Time
 }
 
 type GuideCertification struct {
@@ -68,9 +89,22 @@ type TouristAttraction struct {
 	Province    Province `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:ProvinceID"`
 	District    string   
 	City        string   
-	Category    string   `gorm:"not null"` // ประเภท เช่น "วัด", "น้ำตก", "ชายหาด"
+	Category    string   `gorm:"not null"` // ชื่อประเภท (สำเนาจาก AttractionCategory ให้ client เดิมใช้ได้)
+	CategoryID  *uint    `gorm:"index"`
+	AttractionCategory *AttractionCategory `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:CategoryID"`
 	Rating      float64  
-	ImageURL    string   
+	ImageURL    string   // URL รูปหลัก (รูปที่อัปโหลดผ่านระบบมีรูปย่อใน Image.Variants)
+	ImageUploadID *uint  `gorm:"index"`
+	Image       *Upload  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:ImageUploadID"`
+	Latitude    *float64 `gorm:"index:idx_attraction_location"` // พิกัด (nil = ยังไม่ระบุ ไม่แสดงในการค้นหาตามระยะ)
+	Longitude   *float64 `gorm:"index:idx_attraction_location"`
+}
+
+// AttractionCategory - ประเภทสถานที่ท่องเที่ยว เช่น "วัด", "น้ำตก", "ชายหาด" (admin จัดการได้)
+type AttractionCategory struct {
+	gorm.Model
+	Name        string   `gorm:"not null;index"`
+	Description string
 }
 
 type Province struct {
@@ -79,6 +113,17 @@ type Province struct {
 	Region             string              `gorm:"not null"`
 }
 
+// Translation - คำแปลของข้อความ catalogue และโปรไฟล์ไกด์ ต่อภาษา
+// ค่าที่บันทึกในตารางหลักเป็นค่าเริ่มต้น ภาษาที่มีคำแปลจะแสดงคำแปลแทน
+type Translation struct {
+	gorm.Model
+	EntityType string `gorm:"not null;uniqueIndex:idx_translation"` // province, attraction, category, language, role, guide
+	EntityID   uint   `gorm:"not null;uniqueIndex:idx_translation"`
+	Field      string `gorm:"not null;uniqueIndex:idx_translation"` // name, description, bio
+	Language   string `gorm:"not null;uniqueIndex:idx_translation"` // th, en
+	Value      string `gorm:"type:text;not null"`
+}
+
 type GuideVertification struct {
 	gorm.Model
 	UserID            uint      `gorm:"not null"` 
@@ -99,6 +144,7 @@ type GuideVertification struct {
 	Language         []Language `gorm:"many2many:guide_verification_languages"`
 	Attraction       []TouristAttraction `gorm:"many2many:guide_verification_attractions"`
 	CertificationData string
+	LicenceDocument   string // URL ของสำเนาใบอนุญาตมัคคุเทศก์ (อัปโหลดด้วย purpose licence_document)
 }
 type PasswordReset struct {
 	gorm.Model
@@ -154,8 +200,29 @@ type TripOffer struct {
 	AcceptedAt       *time.Time  // วันที่ user accept
 	RejectedAt       *time.Time  // วันที่ reject (auto หรือ manual)
 	RejectionReason  string      `gorm:"type:text"` // เหตุผลการ reject (auto_selection, manual_reject, expired, counter_offered)
+	WithdrawnAt      *time.Time  // วันที่ไกด์ถอนข้อเสนอ
+	WithdrawalReason string      `gorm:"type:text"` // เหตุผลที่ไกด์ถอนข้อเสนอ
 	TripOfferNegotiation []TripOfferNegotiation `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:TripOfferID"`
 	TripOfferQuotation []TripOfferQuotation `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:TripOfferID"`
+	Stops            []TripOfferStop `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripOfferID"` // จุดแวะตามกำหนดการ
+	EstimatedDistanceKm float64  // ระยะเดินทางรวมโดยประมาณระหว่างจุดแวะ (คำนวณจากพิกัด)
+}
+
+// TripOfferStop - จุดแวะตามกำหนดการของ offer เรียงตาม Day, Position
+// ระยะจากจุดก่อนหน้าคำนวณจากพิกัดตอนบันทึก (nil = จุดแรกของวัน หรือไม่มีพิกัด)
+type TripOfferStop struct {
+	gorm.Model
+	TripOfferID         uint               `gorm:"not null;index"`
+	Day                 int                `gorm:"not null;default:1"`
+	Position            int                `gorm:"not null"`
+	TouristAttractionID *uint              `gorm:"index"`
+	TouristAttraction   *TouristAttraction `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:TouristAttractionID"`
+	Name                string             `gorm:"not null"`
+	Latitude            *float64
+	Longitude           *float64
+	Note                string             `gorm:"type:text"`
+	DistanceFromPreviousKm    *float64 // ระยะทางโดยประมาณจากจุดก่อนหน้า
+	TravelMinutesFromPrevious *int     // เวลาเดินทางโดยประมาณจากจุดก่อนหน้า
 }
 
 // TripOfferQuotation - ใบเสนอราคา
@@ -167,7 +234,7 @@ type TripOfferQuotation struct {
 	TotalPrice       float64     `gorm:"not null"`   // ราคารวมที่เสนอ
 	PriceBreakdown   string      `gorm:"type:text"` // รายละเอียดราคา (ใช้ text แทน json)
 	QuotationNumber  string      // เลขที่ใบเสนอราคา (optional)
-	Status           string      `gorm:"default:'draft'"` // draft, sent, accepted, rejected, expired
+	Status           string      `gorm:"default:'draft'"` // draft, sent, accepted, rejected, expired, superseded
 	SentAt           *time.Time  // วันที่ส่งใบเสนอราคา
 	AcceptedAt       *time.Time  // วันที่ยอมรับ
 	RejectedAt       *time.Time  // วันที่ปฏิเสธ
@@ -192,6 +259,23 @@ type TripOfferNegotiation struct {
 	Notes            string      // หมายเหตุภายใน
 }
 
+// TripOfferTemplate - แม่แบบ offer ของไกด์ ใช้สร้าง offer ใหม่ได้กับทุก TripRequire
+type TripOfferTemplate struct {
+	gorm.Model
+	GuideID          uint        `gorm:"not null;index"`
+	Guide            Guide       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:GuideID"`
+	Name             string      `gorm:"not null"` // ชื่อแม่แบบ (ไกด์ใช้เรียกเอง)
+	Title            string      `gorm:"not null"`
+	Description      string      `gorm:"type:text;not null"`
+	Itinerary        string      `gorm:"type:text"`
+	IncludedServices string      `gorm:"type:text"`
+	ExcludedServices string      `gorm:"type:text"`
+	OfferNotes       string      `gorm:"type:text"`
+	DefaultPrice     float64     `gorm:"default:0"` // ราคาตั้งต้น (ตรวจช่วงราคาตอนส่ง offer)
+	PriceBreakdown   string      `gorm:"type:text"`
+	LastUsedAt       *time.Time  // วันที่ใช้แม่แบบล่าสุด
+}
+
 // TripBooking - การจองหลังจาก user accept offer
 type TripBooking struct {
 	gorm.Model
@@ -202,6 +286,8 @@ type TripBooking struct {
 	GuideID          uint        `gorm:"not null"`
 	Guide            Guide       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:GuideID"`
 	StartDate        time.Time   `gorm:"not null"`
+	EndDate          *time.Time  // วันสุดท้ายของทริป (booking เก่าที่ไม่มีค่า ให้ถือว่าเป็นทริปวันเดียว)
+	Days             int         `gorm:"default:1"` // จำนวนวันของทริป
 	TotalAmount      float64     `gorm:"not null"`
 	Status           string      `gorm:"default:'pending_payment'"` // pending_payment, paid, trip_started, trip_completed, cancelled, no_show
 	PaymentStatus    string      `gorm:"default:'pending'"` // pending, paid, first_released, fully_released, partially_refunded
@@ -212,6 +298,78 @@ type TripBooking struct {
 	CancellationReason string    // เหตุผลการยกเลิก
 	SpecialRequests  string      // ความต้องการพิเศษ
 	Notes            string      // หมายเหตุ
+	DailyPayout      bool        `gorm:"default:false"` // จ่ายเงินงวดที่สองให้ไกด์ตามสัดส่วนรายวัน (ทริปหลายวัน)
+	EarlyCompletionRequestedBy *uint      // user ID ของฝ่ายที่ขอจบทริปก่อนกำหนด
+	EarlyCompletionRequestedAt *time.Time // วันที่ขอจบทริปก่อนกำหนด
+	EarlyCompletionReason      string     // เหตุผลที่ขอจบก่อนกำหนด
+	EarlyCompletionAgreedAt    *time.Time // วันที่อีกฝ่ายยอมรับ (ทั้งสองฝ่ายตกลงแล้ว)
+	TripDayCheckIn   []TripDayCheckIn `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripBookingID"`
+	SplitPayment     bool        `gorm:"default:false"` // แยกจ่ายตามส่วนแบ่งของผู้ร่วมเดินทางแต่ละคน
+	AutoCompletedAt  *time.Time  // วันที่ระบบจบทริปอัตโนมัติ (user ไม่ได้ยืนยันภายในเวลาที่กำหนด)
+	StartCode        string      `json:"-"` // รหัสเริ่มทริปแบบใช้ครั้งเดียว แสดงให้ user เท่านั้น ไกด์กรอกตอนพบกัน
+	StartCodeUsedAt  *time.Time  // วันที่ไกด์ใช้รหัสเริ่มทริปแล้ว
+	BillingName      string      // ชื่อผู้ซื้อในใบกำกับภาษี (บริษัทที่เบิกจ่าย ว่าง = ชื่อผู้จอง)
+	BillingTaxID     string      // เลขประจำตัวผู้เสียภาษีของผู้ซื้อ (มีค่า = นิติบุคคล แสดงภาษีหัก ณ ที่จ่าย)
+	BillingAddress   string      `gorm:"type:text"` // ที่อยู่ผู้ซื้อในใบกำกับภาษี
+	MeetingLatitude  *float64    // จุดนัดพบ (user กำหนด)
+	MeetingLongitude *float64
+	MeetingNote      string      // รายละเอียดจุดนัดพบ
+	StartCheckIns    []TripStartCheckIn `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripBookingID"`
+	Participants     []TripBookingParticipant `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripBookingID"`
+}
+
+// TripStartCheckIn - หลักฐานการเช็คอินของไกด์ตอนเริ่มทริป (เก็บทุกครั้งรวมที่ไม่ผ่าน ใช้ตอนมีข้อพิพาท)
+type TripStartCheckIn struct {
+	gorm.Model
+	TripBookingID    uint         `gorm:"not null;index"`
+	GuideID          uint         `gorm:"not null"`
+	CodeValid        bool         `gorm:"default:false"` // รหัสที่กรอกถูกต้องหรือไม่
+	Latitude         *float64     // ตำแหน่งของไกด์ตอนเช็คอิน
+	Longitude        *float64
+	AccuracyMeters   *float64     // ความแม่นยำของ GPS จากอุปกรณ์
+	DistanceMeters   *float64     // ระยะห่างจากจุดนัดพบ (ถ้ามีทั้งสองค่า)
+	WithinRadius     *bool
+	Status           string       `gorm:"not null"` // accepted, rejected
+	FailureReason    string       // invalid_code, outside_meeting_radius
+	IPAddress        string
+	UserAgent        string
+}
+
+// TripBookingParticipant - ผู้ร่วมเดินทางใน booking (รวมผู้จองเอง) และส่วนแบ่งค่าใช้จ่ายกรณีแยกจ่าย
+type TripBookingParticipant struct {
+	gorm.Model
+	TripBookingID    uint         `gorm:"not null;index"`
+	UserID           *uint        `gorm:"index"` // ผูกกับผู้ใช้ในระบบเมื่อรับคำเชิญ
+	User             *User        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:UserID"`
+	Email            string       `gorm:"not null"`
+	FirstName        string
+	LastName         string
+	Phone            string
+	IsOrganizer      bool         `gorm:"default:false"` // ผู้จอง (เจ้าของ booking)
+	InviteToken      string       `gorm:"uniqueIndex" json:"-"` // token ในลิงก์คำเชิญ (ไม่ส่งออกใน API)
+	InviteStatus     string       `gorm:"default:'invited'"` // invited, accepted, declined
+	InvitedAt        time.Time
+	RespondedAt      *time.Time
+	ShareAmount      float64      `gorm:"default:0"` // ส่วนแบ่งที่ต้องจ่าย (กรณีแยกจ่าย)
+	ShareStatus      string       `gorm:"default:'none'"` // none, pending, paid, covered
+	StripePaymentIntentID string  `gorm:"index"` // PaymentIntent ของส่วนแบ่งนี้
+	StripeClientSecret    string  `json:"-"` // ส่งให้เฉพาะผู้จ่ายตอนสร้าง PaymentIntent
+	CoversRemainder  bool         `gorm:"default:false"` // ผู้จองจ่ายส่วนที่เหลือแทนคนอื่น
+	PaidAmount       float64      `gorm:"default:0"` // จำนวนที่ตัดจาก PaymentIntent ของส่วนแบ่งนี้จริง (ใช้แบ่งคืนเงิน)
+	PaidAt           *time.Time
+}
+
+// TripDayCheckIn - การเช็คอินรายวันของทริป (ทั้ง user และไกด์ยืนยันว่าวันนั้นมีการเที่ยวจริง)
+type TripDayCheckIn struct {
+	gorm.Model
+	TripBookingID    uint         `gorm:"not null;uniqueIndex:idx_trip_day_check_in"`
+	DayNumber        int          `gorm:"not null;uniqueIndex:idx_trip_day_check_in"` // วันที่เท่าไหร่ของทริป (เริ่มที่ 1)
+	TripDate         time.Time    `gorm:"not null"`
+	UserCheckedInAt  *time.Time   // วันที่ user เช็คอิน
+	GuideCheckedInAt *time.Time   // วันที่ไกด์เช็คอิน
+	CompletedAt      *time.Time   // วันที่ทั้งสองฝ่ายเช็คอินครบ
+	PaymentReleaseID *uint        // PaymentRelease ของวันนี้ (กรณีจ่ายรายวัน)
+	Notes            string
 }
 
 // TripPayment - การชำระเงินแบบใหม่ (User จ่าย 100% แล้วแบ่งจ่ายให้ไกด์ตามขั้นตอน)
@@ -226,7 +384,7 @@ type TripPayment struct {
 	PaymentMethod    string       `gorm:"not null"` // stripe_card, stripe_bank_transfer, etc.
 	TransactionID    string       `gorm:"unique;not null"`
 	// Stripe fields
-	StripePaymentIntentID string  `gorm:"unique"` // Stripe PaymentIntent ID
+	StripePaymentIntentID string  `gorm:"uniqueIndex:idx_trip_payments_stripe_payment_intent_id,where:stripe_payment_intent_id <> ''"` // Stripe PaymentIntent ID (ว่างสำหรับ booking แยกจ่าย ใช้ PaymentIntent ของผู้ร่วมเดินทางแทน)
 	StripeClientSecret    string  // Stripe client secret สำหรับ frontend
 	StripeStatus         string   // Stripe payment status
 	// Original fields
@@ -238,6 +396,9 @@ type TripPayment struct {
 	RefundAmount     float64      `gorm:"default:0"` // จำนวนเงินที่ refund ให้ user
 	RefundReason     string       // เหตุผล refund
 	Notes            string       // หมายเหตุเพิ่มเติม
+	PayoutsFrozen    bool         `gorm:"default:false"` // ระงับการจ่ายไกด์ระหว่างตรวจสอบรีพอร์ตร้ายแรง
+	PayoutsFrozenReason string
+	PayoutsFrozenAt  *time.Time
 }
 
 // TripReview - รีวิวหลังเสร็จการเที่ยว
@@ -255,12 +416,51 @@ type TripReview struct {
 	KnowledgeRating  float64      `gorm:"not null"` // คะแนนความรู้
 	CommunicationRating float64   `gorm:"not null"` // คะแนนการสื่อสาร
 	PunctualityRating float64     `gorm:"not null"` // คะแนนความตรงต่อเวลา
-	Images           string       `gorm:"type:text"` // รูปภาพประกอบรีวิว
+	Images           []TripReviewImage `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripReviewID"` // รูปภาพประกอบรีวิว เรียงตาม Position
 	IsAnonymous      bool         `gorm:"default:false"` // รีวิวแบบไม่ระบุตัวตน
 	IsVerified       bool         `gorm:"default:true"`  // รีวิวที่ verified จากการจองจริง
 	HelpfulCount     int          `gorm:"default:0"`     // จำนวนคนที่กด helpful
 	ResponseFromGuide string      `gorm:"type:text"`     // การตอบกลับจากไกด์
 	RespondedAt      *time.Time   // วันที่ไกด์ตอบกลับ
+	IsHidden         bool         `gorm:"default:false;index"` // admin ซ่อนรีวิว ไม่แสดงและไม่นับคะแนน
+	HiddenReason     string       `gorm:"type:text"`     // เหตุผลที่ซ่อน/คืนรีวิวล่าสุด
+	HiddenAt         *time.Time
+	ModeratedBy      *uint        // Admin ที่ซ่อน/คืนรีวิวล่าสุด
+	FlagCount        int          `gorm:"default:0"`     // จำนวนการแจ้งที่รอ admin ตรวจ
+}
+
+// TripReviewImage - รูปประกอบรีวิว (สูงสุด 5 รูปต่อรีวิว)
+type TripReviewImage struct {
+	gorm.Model
+	TripReviewID     uint         `gorm:"not null;index"`
+	UploadID         *uint        `gorm:"index"` // ว่าง = URL ภายนอก หรือไฟล์ที่อัปโหลดก่อนมีตาราง uploads
+	Upload           *Upload      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;foreignKey:UploadID"`
+	URL              string       `gorm:"not null"`
+	Caption          string       `gorm:"type:text"`
+	Position         int          `gorm:"not null;default:0"` // ลำดับการแสดง เริ่มที่ 0
+}
+
+// ReviewHelpfulVote - การกด helpful หนึ่งครั้งต่อ user ต่อรีวิว (ยกเลิกได้ด้วยการลบ)
+type ReviewHelpfulVote struct {
+	ID               uint         `gorm:"primaryKey"`
+	TripReviewID     uint         `gorm:"not null;uniqueIndex:idx_review_helpful_vote"`
+	TripReview       TripReview   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripReviewID" json:"-"`
+	UserID           uint         `gorm:"not null;uniqueIndex:idx_review_helpful_vote"`
+	CreatedAt        time.Time
+}
+
+// ReviewFlag - ผู้ใช้/ไกด์แจ้งรีวิวให้ admin ตรวจ (หนึ่งครั้งต่อคนต่อรีวิว)
+type ReviewFlag struct {
+	gorm.Model
+	TripReviewID     uint         `gorm:"not null;uniqueIndex:idx_review_flag_reporter"`
+	TripReview       TripReview   `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripReviewID" json:"-"`
+	ReporterID       uint         `gorm:"not null;uniqueIndex:idx_review_flag_reporter"`
+	Reporter         User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:ReporterID"`
+	Reason           string       `gorm:"not null"` // spam, offensive, fake, personal_info, off_topic, other
+	Details          string       `gorm:"type:text"`
+	Status           string       `gorm:"default:'pending';index"` // pending, upheld (รีวิวถูกซ่อน), dismissed
+	ResolvedBy       *uint
+	ResolvedAt       *time.Time
 }
 
 // TripReport - รีพอร์ตปัญหาต่างๆ
@@ -283,6 +483,55 @@ type TripReport struct {
 	ReviewedAt       *time.Time   // วันที่ admin review
 	ResolvedAt       *time.Time   // วันที่แก้ไขเสร็จ
 	Actions          string       `gorm:"type:text"` // การดำเนินการที่ทำ
+	AssignedTo       *uint        `gorm:"index"` // Admin ที่รับผิดชอบเคส
+	AssignedAt       *time.Time
+}
+
+// TripReportNote - บันทึกภายในของ admin ในเคส (ผู้รีพอร์ตไม่เห็น)
+type TripReportNote struct {
+	gorm.Model
+	TripReportID     uint         `gorm:"not null;index"`
+	AuthorID         uint         `gorm:"not null"`
+	Author           User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:AuthorID"`
+	Body             string       `gorm:"type:text;not null"`
+}
+
+// TripReportLink - เชื่อมรีพอร์ตที่เกี่ยวข้องกันใน booking เดียวกัน (เก็บ id น้อยกว่าไว้ที่ TripReportID)
+type TripReportLink struct {
+	ID               uint         `gorm:"primaryKey"`
+	TripReportID     uint         `gorm:"not null;uniqueIndex:idx_trip_report_link"`
+	RelatedReportID  uint         `gorm:"not null;uniqueIndex:idx_trip_report_link;index"`
+	CreatedBy        uint         `gorm:"not null"`
+	CreatedAt        time.Time
+}
+
+// NoShowDispute - ไทม์ไลน์ของกรณีไกด์รีพอร์ตว่า user ไม่มา ตั้งแต่รีพอร์ตจนถึงคำตัดสิน
+type NoShowDispute struct {
+	gorm.Model
+	TripBookingID     uint         `gorm:"not null;uniqueIndex"`
+	TripBooking       TripBooking  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripBookingID"`
+	NoShowReportID    uint         `gorm:"not null"` // TripReport ประเภท user_no_show
+	DisputeReportID   *uint        // TripReport ประเภท dispute_no_show (เมื่อ user คัดค้าน)
+	Stage             string       `gorm:"not null;index;default:'awaiting_traveller'"` // awaiting_traveller, evidence, awaiting_decision, decided
+	RespondBy         time.Time    `gorm:"not null"` // user ต้องยืนยันหรือคัดค้านก่อนเวลานี้
+	DisputedAt        *time.Time   // วันที่ user คัดค้าน
+	EvidenceDueAt     *time.Time   // ปิดรับคำชี้แจง/หลักฐานจากทั้งสองฝ่าย
+	Decision          string       // guide_wins, user_wins, split_cost
+	DecisionSource    string       // admin, traveller_confirmed, traveller_no_response, guide_no_response
+	Rationale         string       `gorm:"type:text"` // เหตุผลของคำตัดสินที่ทั้งสองฝ่ายเห็น
+	DecidedBy         *uint        // Admin ที่ตัดสิน (ว่าง = ตัดสินอัตโนมัติ)
+	DecidedAt         *time.Time
+	Statements        []DisputeStatement `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:NoShowDisputeID"`
+}
+
+// DisputeStatement - คำชี้แจงพร้อมหลักฐานของแต่ละฝ่ายในไทม์ไลน์ dispute
+type DisputeStatement struct {
+	gorm.Model
+	NoShowDisputeID   uint         `gorm:"not null;index"`
+	AuthorID          uint         `gorm:"not null"`
+	Party             string       `gorm:"not null"` // guide, traveller
+	Body              string       `gorm:"type:text;not null"`
+	Evidence          string       `gorm:"type:text"` // JSON array ของ URL หลักฐาน
 }
 
 // PaymentRelease - ตาราง track การจ่ายเงินให้ไกด์แต่ละครั้ง
@@ -290,15 +539,206 @@ type PaymentRelease struct {
 	gorm.Model
 	TripPaymentID    uint         `gorm:"not null"`
 	TripPayment      TripPayment  `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripPaymentID"`
-	ReleaseType      string       `gorm:"not null"` // first_payment, second_payment, refund
+	ReleaseType      string       `gorm:"not null"` // first_payment, daily_payment, second_payment, refund, overpayment_refund
 	Amount           float64      `gorm:"not null"` // จำนวนเงินที่จ่าย/คืน
 	RecipientType    string       `gorm:"not null"` // guide, user
-	RecipientID      uint         `gorm:"not null"` // ID ของผู้รับเงิน
-	Recipient        User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:RecipientID"`
-	Reason           string       `gorm:"not null"` // trip_started, trip_completed, user_no_show
+	RecipientID      uint         `gorm:"not null;index"` // guide = Guide.ID, user = User.ID (ดู RecipientType)
+	Reason           string       `gorm:"not null"` // trip_started, guide_check_in, trip_completed, user_no_show, auto_completed
 	ScheduledAt      time.Time    `gorm:"not null"` // วันที่กำหนดจ่าย
 	ProcessedAt      *time.Time   // วันที่จ่ายจริง
 	Status           string       `gorm:"default:'pending'"` // pending, processed, failed
 	TransactionRef   string       // อ้างอิงธุรกรรมการโอนเงิน
 	Notes            string       // หมายเหตุ
-}
\ No newline at end of file
+}
+
+// PaymentOutbox - คำสั่งที่ต้องทำกับ Stripe (คืนเงิน/โอนเงิน) บันทึกใน transaction เดียวกับข้อมูล booking/payment
+// worker จะทำตามคำสั่งด้วย IdempotencyKey แล้วอัปเดตผลกลับไปที่ PaymentRelease
+type PaymentOutbox struct {
+	gorm.Model
+	TripPaymentID    uint           `gorm:"not null;index"`
+	TripPayment      TripPayment    `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripPaymentID"`
+	PaymentReleaseID uint           `gorm:"not null;index"` // คืนเงิน booking แยกจ่ายมีหลายคำสั่งต่อ release (หนึ่งคำสั่งต่อผู้ร่วมเดินทาง)
+	PaymentRelease   PaymentRelease `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:PaymentReleaseID"`
+	TripBookingParticipantID *uint  `gorm:"index"` // ส่วนแบ่งที่คืนเงิน (เฉพาะ booking แยกจ่าย)
+	Action           string         `gorm:"not null"` // refund, transfer
+	AmountCents      int64          `gorm:"not null"`
+	Target           string         `gorm:"not null"` // refund: PaymentIntent ID, transfer: Stripe account ของไกด์
+	IdempotencyKey   string         `gorm:"not null;uniqueIndex"`
+	Status           string         `gorm:"not null;default:'pending';index"` // pending, succeeded, failed
+	Attempts         int            `gorm:"not null;default:0"`
+	NextAttemptAt    time.Time      `gorm:"not null;index"`
+	LastError        string         `gorm:"type:text"`
+	ExternalRef      string         // refund/transfer ID จาก Stripe
+	ProcessedAt      *time.Time
+}
+
+// DocumentSequence - ตัวนับเลขที่เอกสารแยกตามชุดและปี (เช่น INV-2026) จองเลขใน transaction เดียวกับการสร้างเอกสาร เลขจึงไม่ซ้ำและไม่ขาดช่วง
+type DocumentSequence struct {
+	Name       string    `gorm:"primaryKey"`
+	LastNumber int       `gorm:"not null;default:0"`
+	UpdatedAt  time.Time
+}
+
+// Invoice - ใบกำกับภาษี/ใบเสร็จรับเงิน (invoice) และใบลดหนี้เมื่อคืนเงิน (credit_note) ของ booking
+// บันทึกรายละเอียดผู้ขาย/ไกด์/ผู้ซื้อ ณ วันที่ออกเอกสารไว้ เอกสารที่ออกแล้วจึงไม่เปลี่ยนตามข้อมูลภายหลัง
+type Invoice struct {
+	gorm.Model
+	TripBookingID     uint        `gorm:"not null;index"`
+	TripBooking       TripBooking `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripBookingID"`
+	TripPaymentID     uint        `gorm:"not null;index"`
+	DocumentType      string      `gorm:"not null"` // invoice, credit_note
+	Number            string      `gorm:"uniqueIndex;not null"` // INV-2026-000001, CN-2026-000001
+	InvoiceID         *uint       `gorm:"index"` // ใบลดหนี้อ้างอิงใบกำกับภาษีฉบับไหน
+	PaymentReleaseID  *uint       `gorm:"uniqueIndex"` // refund ที่ทำให้เกิดใบลดหนี้ (หนึ่งใบต่อหนึ่ง refund)
+	IssuedAt          time.Time   `gorm:"not null"`
+	Description       string      `gorm:"not null"`
+	Amount            float64     `gorm:"not null"` // ยอดรวม VAT แล้ว
+	NetAmount         float64     `gorm:"not null"` // ยอดก่อน VAT
+	VATRate           float64     `gorm:"not null;default:0"` // % (0 = ไม่จด VAT)
+	VATAmount         float64     `gorm:"not null;default:0"`
+	WithholdingRate   float64     `gorm:"not null;default:0"` // % ภาษีหัก ณ ที่จ่าย (ผู้ซื้อเป็นนิติบุคคล)
+	WithholdingAmount float64     `gorm:"not null;default:0"`
+	SellerName        string      `gorm:"not null"`
+	SellerTaxID       string
+	SellerAddress     string      `gorm:"type:text"`
+	GuideID           uint        `gorm:"not null"`
+	GuideName         string      `gorm:"not null"` // ไกด์ผู้ให้บริการ
+	BuyerName         string      `gorm:"not null"`
+	BuyerTaxID        string
+	BuyerAddress      string      `gorm:"type:text"`
+}
+
+// ReconciliationIssue - ข้อมูลใน TripPayment/PaymentRelease ไม่ตรงกับ Stripe (พบโดย reconciliation job)
+// เจอซ้ำจะอัปเดต record เดิม ถ้ารอบถัดไปไม่เจอแล้วจะปิดให้อัตโนมัติ
+type ReconciliationIssue struct {
+	gorm.Model
+	TripPaymentID         uint        `gorm:"not null;index"`
+	TripPayment           TripPayment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:TripPaymentID"`
+	StripePaymentIntentID string      `gorm:"not null"`
+	IssueType             string      `gorm:"not null;index"` // status_mismatch, amount_mismatch, refund_total_mismatch, unrecorded_refund, missing_stripe_refund, stripe_error
+	ExternalRef           string      // refund ID ของ Stripe (ถ้ามี)
+	LocalValue            string      // ค่าในระบบ
+	StripeValue           string      // ค่าใน Stripe
+	Details               string      `gorm:"type:text"`
+	Resyncable            bool        `gorm:"not null;default:false"` // แก้ข้อมูลในระบบตาม Stripe ได้อย่างปลอดภัย
+	Status                string      `gorm:"not null;default:'open';index"` // open, resolved, resynced, ignored
+	DetectedAt            time.Time   `gorm:"not null"`
+	LastCheckedAt         time.Time   `gorm:"not null"`
+	ResolvedAt            *time.Time
+	ResolvedBy            *uint       // admin ที่ resync/ignore (ว่าง = ปิดอัตโนมัติ)
+}
+
+// Conversation - ห้องสนทนาระหว่าง user กับไกด์ ผูกกับ TripRequire/TripOffer (ก่อนจอง) หรือ TripBooking
+type Conversation struct {
+	gorm.Model
+	TripRequireID    *uint        `gorm:"index"`
+	TripOfferID      *uint        `gorm:"uniqueIndex"` // ห้องของ offer (หนึ่งห้องต่อ offer)
+	TripBookingID    *uint        `gorm:"uniqueIndex"` // ห้องของ booking (หนึ่งห้องต่อ booking)
+	UserID           uint         `gorm:"not null;index"` // นักท่องเที่ยว
+	User             User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:UserID"`
+	GuideID          uint         `gorm:"not null;index"`
+	Guide            Guide        `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:GuideID"`
+	GuideUserID      uint         `gorm:"not null;index"` // user ID ของไกด์ (ใช้ตรวจสิทธิ์)
+	LastMessageAt    *time.Time
+}
+
+// Message - ข้อความในห้องสนทนา (เก็บข้อความจริง ปิดเบอร์โทร/อีเมลตอนแสดงผลจนกว่าจะจ่ายเงิน)
+type Message struct {
+	gorm.Model
+	ConversationID   uint         `gorm:"not null;index"`
+	Conversation     Conversation `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:ConversationID"`
+	SenderID         uint         `gorm:"not null"`
+	Sender           User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:SenderID"`
+	Body             string       `gorm:"type:text"`
+	ReadAt           *time.Time   // วันที่อีกฝ่ายอ่านแล้ว (read receipt)
+	Attachments      []MessageAttachment `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:MessageID"`
+}
+
+// MessageAttachment - ไฟล์แนบในข้อความ (อัปโหลดผ่าน /uploads ก่อน)
+type MessageAttachment struct {
+	gorm.Model
+	MessageID        uint         `gorm:"not null;index"`
+	URL              string       `gorm:"not null"`
+	FileName         string
+}
+
+// Upload - ไฟล์ที่อัปโหลดผ่านระบบ เก็บเจ้าของและวัตถุประสงค์ (ไฟล์ที่ไม่มีข้อมูลใดอ้างถึงจะถูกลบโดย job)
+type Upload struct {
+	gorm.Model
+	OwnerID          uint         `gorm:"not null;index"`
+	Owner            User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:OwnerID"`
+	Purpose          string       `gorm:"not null;index"` // avatar, review_image, evidence, licence_document, message_attachment
+	Backend          string       `gorm:"not null"` // local, s3
+	Key              string       `gorm:"not null;uniqueIndex"` // path ใน storage เช่น avatars/<random>.png
+	URL              string       `gorm:"not null;index"`
+	ContentType      string       `gorm:"not null"` // ตรวจจากเนื้อไฟล์ ไม่เชื่อ header ของ client
+	Size             int64        `gorm:"not null"`
+	OriginalName     string
+	Private          bool         `gorm:"not null;default:false"` // เปิดได้ผ่าน signed URL หรือ download endpoint ที่ตรวจสิทธิ์เท่านั้น
+	Width            int          // รูปที่ผ่าน image pipeline เท่านั้น
+	Height           int
+	Variants         []ImageVariant `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:UploadID"`
+}
+
+// ImageVariant - รูปย่อที่สร้างจากรูปที่อัปโหลด (thumbnail, medium, large)
+type ImageVariant struct {
+	gorm.Model
+	UploadID         uint         `gorm:"not null;uniqueIndex:idx_image_variant"`
+	Variant          string       `gorm:"not null;uniqueIndex:idx_image_variant"` // thumbnail, medium, large
+	Key              string       `gorm:"not null;uniqueIndex"`
+	URL              string       `gorm:"not null"`
+	Width            int          `gorm:"not null"`
+	Height           int          `gorm:"not null"`
+	Size             int64        `gorm:"not null"`
+}
+
+// FileAccessLog - บันทึกทุกครั้งที่มีการเข้าถึงเอกสารยืนยันตัวตน (เช่นสำเนาใบอนุญาตไกด์)
+type FileAccessLog struct {
+	gorm.Model
+	UploadID         uint         `gorm:"not null;index"`
+	UserID           uint         `gorm:"not null;index"` // ผู้เข้าถึง (signed URL ผูกกับผู้ที่ขอ)
+	Action           string       `gorm:"not null"` // sign_url, download, signed_download
+	IPAddress        string
+	UserAgent        string
+}
+
+// Notification - การแจ้งเตือนภายในระบบสำหรับผู้ใช้
+type Notification struct {
+	gorm.Model
+	UserID           uint         `gorm:"not null;index"` // ผู้รับการแจ้งเตือน
+	User             User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:UserID"`
+	Type             string       `gorm:"not null"` // offer_withdrawn, ...
+	Title            string       `gorm:"not null"`
+	Message          string       `gorm:"type:text"`
+	RelatedType      string       // ประเภทข้อมูลที่เกี่ยวข้อง เช่น trip_offer, trip_booking
+	RelatedID        uint         // ID ของข้อมูลที่เกี่ยวข้อง
+	ReadAt           *time.Time   // วันที่ผู้ใช้อ่านแล้ว
+}
+
+// NotificationPreference - ผู้ใช้เปิด/ปิดช่องทางแจ้งเตือน ต่อประเภท (Type = "*" คือทุกประเภท)
+// ไม่มี record = เปิด
+type NotificationPreference struct {
+	gorm.Model
+	UserID           uint         `gorm:"not null;uniqueIndex:idx_notification_pref"`
+	User             User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;foreignKey:UserID"`
+	Type             string       `gorm:"not null;uniqueIndex:idx_notification_pref"` // ประเภทการแจ้งเตือน หรือ *
+	Channel          string       `gorm:"not null;uniqueIndex:idx_notification_pref"` // in_app, email
+	Enabled          bool         `gorm:"not null"`
+}
+
+// NotificationOutbox - ข้อความที่รอส่งออกไปยังช่องทางภายนอก (email, ...) ส่งไม่สำเร็จจะลองใหม่
+type NotificationOutbox struct {
+	gorm.Model
+	NotificationID   *uint        `gorm:"index"` // ว่างได้ เช่นอีเมลรีเซ็ตรหัสผ่าน
+	UserID           *uint        `gorm:"index"`
+	Channel          string       `gorm:"not null"` // email
+	Recipient        string       `gorm:"not null"` // อีเมลผู้รับ
+	Subject          string
+	Body             string       `gorm:"type:text"`
+	Status           string       `gorm:"not null;default:'pending';index"` // pending, sent, failed
+	Attempts         int          `gorm:"not null;default:0"`
+	MaxAttempts      int          `gorm:"not null;default:5"`
+	NextAttemptAt    time.Time    `gorm:"not null;index"`
+	LastError        string       `gorm:"type:text"`
+	SentAt           *time.Time
+}
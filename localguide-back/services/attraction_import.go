@@ -0,0 +1,549 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"localguide-back/models"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAttractionImportFormat  = errors.New("unsupported import format")
+	ErrAttractionImportInvalid = errors.New("import file has invalid rows")
+)
+
+// AttractionColumns - คอลัมน์ของไฟล์ CSV และ properties ของ GeoJSON (ลำดับเดียวกับไฟล์ export)
+// จับคู่กับข้อมูลเดิมด้วย name + province (ไม่สนตัวพิมพ์เล็ก/ใหญ่)
+var AttractionColumns = []string{"name", "province", "category", "district", "city", "description", "rating", "latitude", "longitude", "image_url"}
+
+const (
+	maxImportNameLength        = 100
+	maxImportDescriptionLength = 2000
+	maxImportRows              = 5000
+	utf8BOM                    = "\ufeff"
+)
+
+// AttractionRecord - หนึ่งแถวในไฟล์นำเข้า Fields มีเฉพาะคอลัมน์ที่อยู่ในไฟล์ (คอลัมน์ที่ไม่มีจะไม่ถูกแก้)
+type AttractionRecord struct {
+	Line   int // บรรทัดใน CSV หรือลำดับ feature ใน GeoJSON
+	Fields map[string]string
+	Error  string // อ่านแถวนี้ไม่ได้ (เช่น geometry ไม่ใช่ Point)
+}
+
+// AttractionFile - ข้อมูลที่อ่านจากไฟล์ พร้อมคอลัมน์ที่ไม่รู้จัก (ไม่ถูกนำเข้า)
+type AttractionFile struct {
+	Records        []AttractionRecord
+	IgnoredColumns []string
+}
+
+func isAttractionColumn(name string) bool {
+	for _, c := range AttractionColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAttractionFile อ่านไฟล์ตาม format: csv หรือ geojson
+func ParseAttractionFile(format string, r io.Reader) (*AttractionFile, error) {
+	switch strings.ToLower(format) {
+	case "csv":
+		return ParseAttractionsCSV(r)
+	case "geojson", "json":
+		return ParseAttractionsGeoJSON(r)
+	}
+	return nil, fmt.Errorf("%w %q (use csv or geojson)", ErrAttractionImportFormat, format)
+}
+
+// ParseAttractionsCSV อ่าน CSV ที่มีหัวตาราง ต้องมีคอลัมน์ name และ province (ข้ามแถวว่าง)
+func ParseAttractionsCSV(r io.Reader) (*AttractionFile, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	// Excel ใส่ BOM หน้าไฟล์ UTF-8
+	data = bytes.TrimPrefix(data, []byte(utf8BOM))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	file := &AttractionFile{}
+	columns := make([]string, len(header))
+	seen := map[string]bool{}
+	for i, h := range header {
+		name := strings.ToLower(strings.TrimSpace(h))
+		if !isAttractionColumn(name) {
+			if name != "" {
+				file.IgnoredColumns = append(file.IgnoredColumns, name)
+			}
+			continue
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("column %q appears more than once", name)
+		}
+		seen[name] = true
+		columns[i] = name
+	}
+	if !seen["name"] || !seen["province"] {
+		return nil, errors.New("columns name and province are required")
+	}
+
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		record := AttractionRecord{Line: line, Fields: map[string]string{}}
+		blank := true
+		for i, column := range columns {
+			if column == "" {
+				continue
+			}
+			value := ""
+			if i < len(row) {
+				value = strings.TrimSpace(row[i])
+			}
+			if value != "" {
+				blank = false
+			}
+			record.Fields[column] = value
+		}
+		if blank {
+			continue
+		}
+		if len(file.Records) == maxImportRows {
+			return nil, fmt.Errorf("file has more than %d rows", maxImportRows)
+		}
+		file.Records = append(file.Records, record)
+	}
+	return file, nil
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *geoJSONGeometry       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// geoJSONGeometry - เก็บ coordinates ไว้ก่อน เพราะรูปแบบขึ้นกับ Type (นำเข้าเฉพาะ Point)
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+// ParseAttractionsGeoJSON อ่าน FeatureCollection ของ Point (พิกัดจาก geometry, geometry = null คือไม่มีพิกัด)
+func ParseAttractionsGeoJSON(r io.Reader) (*AttractionFile, error) {
+	var collection geoJSONFeatureCollection
+	if err := json.NewDecoder(r).Decode(&collection); err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+	if collection.Type != "FeatureCollection" {
+		return nil, errors.New("GeoJSON must be a FeatureCollection")
+	}
+	if len(collection.Features) > maxImportRows {
+		return nil, fmt.Errorf("file has more than %d features", maxImportRows)
+	}
+
+	file := &AttractionFile{}
+	ignored := map[string]bool{}
+	for i, feature := range collection.Features {
+		record := AttractionRecord{Line: i + 1, Fields: map[string]string{"latitude": "", "longitude": ""}}
+		for key, value := range feature.Properties {
+			name := strings.ToLower(strings.TrimSpace(key))
+			if !isAttractionColumn(name) || name == "latitude" || name == "longitude" {
+				ignored[name] = true
+				continue
+			}
+			switch v := value.(type) {
+			case nil:
+				record.Fields[name] = ""
+			case string:
+				record.Fields[name] = strings.TrimSpace(v)
+			case float64:
+				record.Fields[name] = strconv.FormatFloat(v, 'f', -1, 64)
+			default:
+				record.Fields[name] = fmt.Sprint(v)
+			}
+		}
+		if g := feature.Geometry; g != nil {
+			var point []float64
+			if g.Type != "Point" || json.Unmarshal(g.Coordinates, &point) != nil || len(point) < 2 {
+				record.Error = "geometry must be a Point"
+			} else {
+				record.Fields["longitude"] = strconv.FormatFloat(point[0], 'f', -1, 64)
+				record.Fields["latitude"] = strconv.FormatFloat(point[1], 'f', -1, 64)
+			}
+		}
+		file.Records = append(file.Records, record)
+	}
+	for name := range ignored {
+		file.IgnoredColumns = append(file.IgnoredColumns, name)
+	}
+	sort.Strings(file.IgnoredColumns)
+	return file, nil
+}
+
+// AttractionFieldChange - ค่าที่เปลี่ยนของ field หนึ่ง
+type AttractionFieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// AttractionImportRow - ผลของแต่ละแถว Action: new, changed, unchanged, invalid
+type AttractionImportRow struct {
+	Line         int                     `json:"line"`
+	Name         string                  `json:"name"`
+	Province     string                  `json:"province"`
+	Action       string                  `json:"action"`
+	AttractionID uint                    `json:"attraction_id,omitempty"`
+	Changes      []AttractionFieldChange `json:"changes,omitempty"`
+	Error        string                  `json:"error,omitempty"`
+}
+
+// AttractionImportResult - diff ของไฟล์เทียบกับข้อมูลปัจจุบัน (Applied = บันทึกแล้ว)
+type AttractionImportResult struct {
+	Rows           []AttractionImportRow `json:"rows"`
+	New            int                   `json:"new"`
+	Changed        int                   `json:"changed"`
+	Unchanged      int                   `json:"unchanged"`
+	Invalid        int                   `json:"invalid"`
+	NewCategories  []string              `json:"new_categories,omitempty"`
+	IgnoredColumns []string              `json:"ignored_columns,omitempty"`
+	Applied        bool                  `json:"applied"`
+}
+
+func formatCoordinate(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+// attractionFieldValues - ค่าของแต่ละคอลัมน์ในรูปข้อความ ใช้ทั้ง diff และ export
+func attractionFieldValues(a *models.TouristAttraction) map[string]string {
+	return map[string]string{
+		"name":        a.Name,
+		"province":    a.Province.Name,
+		"category":    a.Category,
+		"district":    a.District,
+		"city":        a.City,
+		"description": a.Description,
+		"rating":      strconv.FormatFloat(a.Rating, 'f', -1, 64),
+		"latitude":    formatCoordinate(a.Latitude),
+		"longitude":   formatCoordinate(a.Longitude),
+		"image_url":   a.ImageURL,
+	}
+}
+
+func normalizeCatalogueKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// provinceKey - ชื่อจังหวัดสำหรับจับคู่ ("จังหวัดน่าน" ตรงกับ "น่าน")
+func provinceKey(name string) string {
+	return normalizeCatalogueKey(strings.TrimPrefix(strings.TrimSpace(name), "จังหวัด"))
+}
+
+// applyAttractionRecord ตรวจแถวแล้วกำหนดค่าให้ attraction คืนข้อความ error ("" = ผ่าน)
+func applyAttractionRecord(a *models.TouristAttraction, fields map[string]string) string {
+	for _, f := range []struct {
+		column string
+		dst    *string
+		max    int
+	}{
+		{"name", &a.Name, maxImportNameLength},
+		{"category", &a.Category, maxImportNameLength},
+		{"district", &a.District, maxImportNameLength},
+		{"city", &a.City, maxImportNameLength},
+		{"description", &a.Description, maxImportDescriptionLength},
+		{"image_url", &a.ImageURL, 500},
+	} {
+		value, ok := fields[f.column]
+		if !ok {
+			continue
+		}
+		if utf8.RuneCountInString(value) > f.max {
+			return fmt.Sprintf("%s must be at most %d characters", f.column, f.max)
+		}
+		*f.dst = value
+	}
+	if a.Category == "" {
+		return "category is required"
+	}
+	if value, ok := fields["rating"]; ok {
+		rating := 0.0
+		if value != "" {
+			r, err := strconv.ParseFloat(value, 64)
+			if err != nil || r < 0 || r > 5 {
+				return "rating must be a number between 0 and 5"
+			}
+			rating = r
+		}
+		a.Rating = rating
+	}
+
+	lat, hasLat := fields["latitude"]
+	lng, hasLng := fields["longitude"]
+	if hasLat != hasLng || (lat == "") != (lng == "") {
+		return "latitude and longitude must be provided together"
+	}
+	if hasLat {
+		a.Latitude, a.Longitude = nil, nil
+		if lat != "" {
+			la, errLat := strconv.ParseFloat(lat, 64)
+			lo, errLng := strconv.ParseFloat(lng, 64)
+			if errLat != nil || errLng != nil || la < -90 || la > 90 || lo < -180 || lo > 180 {
+				return "invalid coordinates"
+			}
+			a.Latitude, a.Longitude = &la, &lo
+		}
+	}
+	return ""
+}
+
+// AttractionImportService นำเข้าและส่งออกสถานที่ท่องเที่ยว (จับคู่จังหวัดและประเภทด้วยชื่อ)
+type AttractionImportService struct {
+	db *gorm.DB
+}
+
+func NewAttractionImportService(db *gorm.DB) *AttractionImportService {
+	return &AttractionImportService{db: db}
+}
+
+// errImportDryRun - ใช้ rollback transaction ตอน dry run
+var errImportDryRun = errors.New("dry run")
+
+// Import เทียบไฟล์กับข้อมูลปัจจุบัน ถ้า apply = true และทุกแถวถูกต้องจะสร้าง/แก้ไขในครั้งเดียว
+// (ถ้ามีแถวไม่ถูกต้องจะไม่บันทึกเลย คืน ErrAttractionImportInvalid) นำเข้าไฟล์เดิมซ้ำได้ผลเป็น unchanged
+func (s *AttractionImportService) Import(file *AttractionFile, apply bool) (*AttractionImportResult, error) {
+	result := &AttractionImportResult{Rows: []AttractionImportRow{}, IgnoredColumns: file.IgnoredColumns}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.plan(tx, file, result, apply); err != nil {
+			return err
+		}
+		if !apply {
+			return errImportDryRun
+		}
+		if result.Invalid > 0 {
+			return ErrAttractionImportInvalid
+		}
+		return nil
+	})
+	if errors.Is(err, errImportDryRun) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	result.Applied = true
+	return result, nil
+}
+
+func (s *AttractionImportService) plan(tx *gorm.DB, file *AttractionFile, result *AttractionImportResult, apply bool) error {
+	var provinces []models.Province
+	if err := tx.Find(&provinces).Error; err != nil {
+		return err
+	}
+	provinceByKey := map[string]models.Province{}
+	for _, p := range provinces {
+		provinceByKey[provinceKey(p.Name)] = p
+	}
+
+	var categories []models.AttractionCategory
+	if err := tx.Find(&categories).Error; err != nil {
+		return err
+	}
+	categoryByKey := map[string]*models.AttractionCategory{}
+	for i := range categories {
+		categoryByKey[normalizeCatalogueKey(categories[i].Name)] = &categories[i]
+	}
+
+	var attractions []models.TouristAttraction
+	if err := tx.Preload("Province").Find(&attractions).Error; err != nil {
+		return err
+	}
+	attractionByKey := map[string]*models.TouristAttraction{}
+	for i := range attractions {
+		a := &attractions[i]
+		attractionByKey[fmt.Sprintf("%d|%s", a.ProvinceID, normalizeCatalogueKey(a.Name))] = a
+	}
+
+	inFile := map[string]int{}
+	for _, record := range file.Records {
+		row := AttractionImportRow{Line: record.Line, Name: record.Fields["name"], Province: record.Fields["province"]}
+		invalid := func(msg string) {
+			row.Action, row.Error = "invalid", msg
+			result.Invalid++
+			result.Rows = append(result.Rows, row)
+		}
+		if record.Error != "" {
+			invalid(record.Error)
+			continue
+		}
+		if row.Name == "" {
+			invalid("name is required")
+			continue
+		}
+		province, ok := provinceByKey[provinceKey(row.Province)]
+		if !ok {
+			invalid(fmt.Sprintf("province %q not found", row.Province))
+			continue
+		}
+		key := fmt.Sprintf("%d|%s", province.ID, normalizeCatalogueKey(row.Name))
+		if line, dup := inFile[key]; dup {
+			invalid(fmt.Sprintf("duplicate of line %d", line))
+			continue
+		}
+		inFile[key] = record.Line
+
+		existing := attractionByKey[key]
+		updated := models.TouristAttraction{ProvinceID: province.ID, Province: province}
+		if existing != nil {
+			updated = *existing
+			row.AttractionID = existing.ID
+		}
+		if msg := applyAttractionRecord(&updated, record.Fields); msg != "" {
+			invalid(msg)
+			continue
+		}
+
+		categoryKey := normalizeCatalogueKey(updated.Category)
+		category := categoryByKey[categoryKey]
+		if category == nil {
+			category = &models.AttractionCategory{Name: strings.Join(strings.Fields(updated.Category), " ")}
+			if apply {
+				if err := tx.Create(category).Error; err != nil {
+					return err
+				}
+			}
+			categoryByKey[categoryKey] = category
+			result.NewCategories = append(result.NewCategories, category.Name)
+		}
+		updated.Category = category.Name
+		if category.ID != 0 {
+			updated.CategoryID = &category.ID
+		}
+
+		if existing == nil {
+			row.Action = "new"
+			result.New++
+			if apply {
+				if err := tx.Omit("Province").Create(&updated).Error; err != nil {
+					return err
+				}
+				row.AttractionID = updated.ID
+			}
+			result.Rows = append(result.Rows, row)
+			continue
+		}
+
+		before, after := attractionFieldValues(existing), attractionFieldValues(&updated)
+		for _, column := range AttractionColumns {
+			if before[column] != after[column] {
+				row.Changes = append(row.Changes, AttractionFieldChange{Field: column, From: before[column], To: after[column]})
+			}
+		}
+		if len(row.Changes) == 0 {
+			row.Action = "unchanged"
+			result.Unchanged++
+			result.Rows = append(result.Rows, row)
+			continue
+		}
+		row.Action = "changed"
+		result.Changed++
+		if apply {
+			if err := tx.Model(existing).
+				Select("Name", "Category", "CategoryID", "District", "City", "Description", "Rating", "Latitude", "Longitude", "ImageURL").
+				Updates(&updated).Error; err != nil {
+				return err
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return nil
+}
+
+// Export คืนสถานที่ท่องเที่ยวทั้งหมด (provinceID = 0) หรือเฉพาะจังหวัด เรียงตามจังหวัดและชื่อ
+func (s *AttractionImportService) Export(provinceID uint) ([]models.TouristAttraction, error) {
+	q := s.db.Preload("Province").
+		Joins("JOIN provinces ON provinces.id = tourist_attractions.province_id").
+		Order("provinces.name, tourist_attractions.name")
+	if provinceID > 0 {
+		q = q.Where("tourist_attractions.province_id = ?", provinceID)
+	}
+	var attractions []models.TouristAttraction
+	err := q.Find(&attractions).Error
+	return attractions, err
+}
+
+// WriteAttractionsCSV เขียน CSV ตาม AttractionColumns (มี BOM ให้ Excel อ่านภาษาไทยได้ถูกต้อง)
+func WriteAttractionsCSV(w io.Writer, attractions []models.TouristAttraction) error {
+	if _, err := io.WriteString(w, utf8BOM); err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	cw.Write(AttractionColumns)
+	for i := range attractions {
+		values := attractionFieldValues(&attractions[i])
+		row := make([]string, len(AttractionColumns))
+		for j, column := range AttractionColumns {
+			row[j] = values[column]
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteAttractionsGeoJSON เขียน FeatureCollection (สถานที่ที่ไม่มีพิกัดมี geometry เป็น null)
+func WriteAttractionsGeoJSON(w io.Writer, attractions []models.TouristAttraction) error {
+	collection := geoJSONFeatureCollection{Type: "FeatureCollection", Features: []geoJSONFeature{}}
+	for i := range attractions {
+		a := &attractions[i]
+		values := attractionFieldValues(a)
+		properties := map[string]interface{}{}
+		for _, column := range AttractionColumns {
+			if column != "latitude" && column != "longitude" {
+				properties[column] = values[column]
+			}
+		}
+		properties["rating"] = a.Rating
+		feature := geoJSONFeature{Type: "Feature", Properties: properties}
+		if a.Latitude != nil && a.Longitude != nil {
+			coordinates, _ := json.Marshal([]float64{*a.Longitude, *a.Latitude})
+			feature.Geometry = &geoJSONGeometry{Type: "Point", Coordinates: coordinates}
+		}
+		collection.Features = append(collection.Features, feature)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(collection)
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"localguide-back/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownCatalogueKind = errors.New("unknown catalogue type")
+	ErrCatalogueMergeSelf   = errors.New("cannot merge an entry into itself")
+	ErrCatalogueInUse       = errors.New("entry is still referenced")
+)
+
+// catalogueRef - ข้อมูลที่อ้างถึงรายการใน catalogue
+type catalogueRef struct {
+	Label  string // ชื่อใน CatalogueImpact
+	Table  string
+	Column string
+	Owner  string // ตารางเชื่อม many2many: คอลัมน์อีกฝั่ง (ว่าง = foreign key ในตารางข้อมูลที่ soft delete ได้)
+}
+
+// catalogueRefs - ที่ที่อ้างถึง province, attraction, language และ category
+// (เพิ่มตรงนี้เมื่อมีตารางใหม่ที่อ้างถึงรายการใน catalogue)
+var catalogueRefs = map[string][]catalogueRef{
+	"province": {
+		{Label: "guides", Table: "guides", Column: "province_id"},
+		{Label: "verifications", Table: "guide_vertifications", Column: "province_id"},
+		{Label: "attractions", Table: "tourist_attractions", Column: "province_id"},
+		{Label: "trip_requests", Table: "trip_requires", Column: "province_id"},
+	},
+	"attraction": {
+		{Label: "guides", Table: "guide_attractions", Column: "tourist_attraction_id", Owner: "guide_id"},
+		{Label: "verifications", Table: "guide_verification_attractions", Column: "tourist_attraction_id", Owner: "guide_vertification_id"},
+		{Label: "offer_stops", Table: "trip_offer_stops", Column: "tourist_attraction_id"},
+	},
+	"language": {
+		{Label: "guides", Table: "guide_languages", Column: "language_id", Owner: "guide_id"},
+		{Label: "verifications", Table: "guide_verification_languages", Column: "language_id", Owner: "guide_vertification_id"},
+	},
+	"category": {
+		{Label: "attractions", Table: "tourist_attractions", Column: "category_id"},
+	},
+}
+
+func catalogueModel(kind string) (interface{}, error) {
+	switch kind {
+	case "province":
+		return &models.Province{}, nil
+	case "attraction":
+		return &models.TouristAttraction{}, nil
+	case "language":
+		return &models.Language{}, nil
+	case "category":
+		return &models.AttractionCategory{}, nil
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownCatalogueKind, kind)
+}
+
+// CatalogueImpact - จำนวนข้อมูลที่อ้างถึงรายการ ตาม Label ของ catalogueRef
+type CatalogueImpact map[string]int64
+
+// Referenced - มีข้อมูลใดอ้างถึงหรือไม่
+func (i CatalogueImpact) Referenced() bool {
+	for _, n := range i {
+		if n > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// CatalogueService ลบและรวมรายการใน catalogue (จังหวัด, สถานที่ท่องเที่ยว, ภาษา, ประเภทสถานที่)
+// โดยตรวจข้อมูลที่อ้างถึงก่อน
+type CatalogueService struct {
+	db *gorm.DB
+}
+
+func NewCatalogueService(db *gorm.DB) *CatalogueService {
+	return &CatalogueService{db: db}
+}
+
+// Impact นับข้อมูลที่อ้างถึงรายการ (ไม่นับข้อมูลที่ถูก soft delete)
+// คืน gorm.ErrRecordNotFound ถ้าไม่มีรายการนี้
+func (s *CatalogueService) Impact(kind string, id uint) (CatalogueImpact, error) {
+	model, err := catalogueModel(kind)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.First(model, id).Error; err != nil {
+		return nil, err
+	}
+	return s.impact(s.db, kind, id)
+}
+
+func (s *CatalogueService) impact(db *gorm.DB, kind string, id uint) (CatalogueImpact, error) {
+	impact := CatalogueImpact{}
+	for _, ref := range catalogueRefs[kind] {
+		var count int64
+		if db.Migrator().HasTable(ref.Table) {
+			q := db.Table(ref.Table).Where(ref.Column+" = ?", id)
+			if ref.Owner == "" {
+				q = q.Where("deleted_at IS NULL")
+			}
+			if err := q.Count(&count).Error; err != nil {
+				return nil, fmt.Errorf("failed to count %s: %w", ref.Label, err)
+			}
+		}
+		impact[ref.Label] += count
+	}
+	return impact, nil
+}
+
+// Delete soft delete รายการที่ไม่มีข้อมูลใดอ้างถึง
+// ถ้ายังมีการอ้างถึงคืน ErrCatalogueInUse พร้อม impact (ให้ admin รวมเข้ากับรายการอื่นแทน)
+func (s *CatalogueService) Delete(kind string, id uint) (CatalogueImpact, error) {
+	model, err := catalogueModel(kind)
+	if err != nil {
+		return nil, err
+	}
+	var impact CatalogueImpact
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(model, id).Error; err != nil {
+			return err
+		}
+		impact, err = s.impact(tx, kind, id)
+		if err != nil {
+			return err
+		}
+		if impact.Referenced() {
+			return ErrCatalogueInUse
+		}
+		if err := deleteTranslations(tx, kind, id); err != nil {
+			return err
+		}
+		return tx.Delete(model).Error
+	})
+	return impact, err
+}
+
+// Merge ย้ายทุกการอ้างถึงจาก sourceID ไปที่ targetID แล้ว soft delete รายการต้นทาง
+// ตารางเชื่อมที่ผูกกับทั้งสองรายการอยู่แล้วจะเหลือแถวเดียว คืนจำนวนข้อมูลที่ย้าย
+func (s *CatalogueService) Merge(kind string, sourceID, targetID uint) (CatalogueImpact, error) {
+	if sourceID == targetID {
+		return nil, ErrCatalogueMergeSelf
+	}
+	source, err := catalogueModel(kind)
+	if err != nil {
+		return nil, err
+	}
+	target, _ := catalogueModel(kind)
+
+	moved := CatalogueImpact{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(source, sourceID).Error; err != nil {
+			return err
+		}
+		if err := tx.First(target, targetID).Error; err != nil {
+			return err
+		}
+		for _, ref := range catalogueRefs[kind] {
+			if !tx.Migrator().HasTable(ref.Table) {
+				continue
+			}
+			if ref.Owner != "" {
+				// แถวที่ผูกกับปลายทางอยู่แล้ว ลบทิ้งแทนการย้าย (กัน primary key ซ้ำ)
+				if err := tx.Exec("DELETE FROM "+ref.Table+" WHERE "+ref.Column+" = ? AND "+ref.Owner+" IN (SELECT "+ref.Owner+" FROM "+ref.Table+" WHERE "+ref.Column+" = ?)",
+					sourceID, targetID).Error; err != nil {
+					return fmt.Errorf("failed to merge %s: %w", ref.Label, err)
+				}
+			}
+			// ย้ายทั้งข้อมูลปกติและที่ถูก soft delete เพื่อไม่ให้มีข้อมูลชี้ไปที่รายการที่ถูกลบ
+			result := tx.Table(ref.Table).Where(ref.Column+" = ?", sourceID).Update(ref.Column, targetID)
+			if result.Error != nil {
+				return fmt.Errorf("failed to merge %s: %w", ref.Label, result.Error)
+			}
+			moved[ref.Label] += result.RowsAffected
+		}
+		if category, ok := target.(*models.AttractionCategory); ok {
+			if err := tx.Model(&models.TouristAttraction{}).Where("category_id = ?", category.ID).
+				Update("category", category.Name).Error; err != nil {
+				return err
+			}
+		}
+		if err := deleteTranslations(tx, kind, sourceID); err != nil {
+			return err
+		}
+		return tx.Delete(source).Error
+	})
+	return moved, err
+}
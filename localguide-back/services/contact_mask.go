@@ -0,0 +1,31 @@
+package services
+
+import (
+	"regexp"
+	"unicode"
+)
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s\-().]{6,}\d`)
+)
+
+const minPhoneDigits = 9
+
+// MaskContactDetails ปิดอีเมลและเบอร์โทรในข้อความ กันการนัดจ่ายเงินนอกระบบก่อนจองสำเร็จ
+func MaskContactDetails(text string) string {
+	text = emailPattern.ReplaceAllString(text, "[email hidden]")
+	return phonePattern.ReplaceAllStringFunc(text, func(match string) string {
+		digits := 0
+		for _, r := range match {
+			if unicode.IsDigit(r) {
+				digits++
+			}
+		}
+		// ตัวเลขสั้นๆ เช่น ราคา หรือวันที่ ไม่ต้องปิด
+		if digits < minPhoneDigits {
+			return match
+		}
+		return "[phone hidden]"
+	})
+}
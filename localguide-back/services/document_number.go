@@ -0,0 +1,31 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NextDocumentNumber จองเลขที่เอกสารถัดไปของชุด series ในปีของ now เช่น INV-2026-000001
+// ต้องเรียกภายใน transaction เดียวกับการสร้างเอกสาร: UPDATE ล็อกแถวตัวนับไว้จนจบ transaction
+// ถ้า transaction rollback เลขที่จองไว้ก็คืนด้วย เลขจึงไม่ซ้ำและไม่ขาดช่วง
+func NextDocumentNumber(tx *gorm.DB, series string, now time.Time) (string, error) {
+	name := fmt.Sprintf("%s-%d", series, now.Year())
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.DocumentSequence{Name: name}).Error; err != nil {
+		return "", fmt.Errorf("failed to create document sequence: %w", err)
+	}
+	if err := tx.Model(&models.DocumentSequence{}).Where("name = ?", name).
+		Update("last_number", gorm.Expr("last_number + 1")).Error; err != nil {
+		return "", fmt.Errorf("failed to allocate document number: %w", err)
+	}
+
+	var seq models.DocumentSequence
+	if err := tx.Where("name = ?", name).First(&seq).Error; err != nil {
+		return "", fmt.Errorf("failed to read document sequence: %w", err)
+	}
+	return fmt.Sprintf("%s-%06d", name, seq.LastNumber), nil
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/gomail.v2"
+)
+
+const defaultSMTPPort = 465
+
+// EmailSender ส่งอีเมล 1 ฉบับ (แยกเป็น interface เพื่อเปลี่ยนผู้ให้บริการหรือใช้ตัวปลอมตอนทดสอบ)
+type EmailSender interface {
+	Send(to, subject, htmlBody string) error
+}
+
+// SMTPSender ส่งอีเมลผ่าน SMTP ด้วย gomail
+type SMTPSender struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+// NewSMTPSenderFromEnv อ่านค่า SMTP_HOST, SMTP_PORT (ค่าเริ่มต้น 465), SMTP_USER, SMTP_PASS, SMTP_FROM
+func NewSMTPSenderFromEnv() *SMTPSender {
+	port := defaultSMTPPort
+	if v, err := strconv.Atoi(os.Getenv("SMTP_PORT")); err == nil && v > 0 {
+		port = v
+	}
+	return &SMTPSender{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     port,
+		Username: os.Getenv("SMTP_USER"),
+		Password: os.Getenv("SMTP_PASS"),
+		From:     os.Getenv("SMTP_FROM"),
+	}
+}
+
+// EmailConfigured - ตั้งค่า SMTP แล้วหรือยัง (ตอน dev/test ไม่ตั้งจะไม่ส่งอีเมล)
+func EmailConfigured() bool {
+	return os.Getenv("SMTP_HOST") != ""
+}
+
+func (s *SMTPSender) Send(to, subject, htmlBody string) error {
+	if s.Host == "" {
+		return errors.New("SMTP is not configured")
+	}
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.From)
+	m.SetHeader("To", to)
+	m.SetHeader("Subject", subject)
+	m.SetBody("text/html", htmlBody)
+
+	d := gomail.NewDialer(s.Host, s.Port, s.Username, s.Password)
+	return d.DialAndSend(m)
+}
+
+// FrontendURL ต่อ path เข้ากับ FRONTEND_URL (ค่าเริ่มต้น http://localhost:3000) ใช้สร้างลิงก์ในอีเมล
+func FrontendURL(path string) string {
+	base := os.Getenv("FRONTEND_URL")
+	if base == "" {
+		base = "http://localhost:3000"
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.TrimLeft(path, "/")
+}
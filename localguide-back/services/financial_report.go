@@ -0,0 +1,341 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ประเภท TripReport ที่นับเป็น no-show และที่ต้องให้ admin ตัดสิน (dispute)
+var (
+	noShowReportTypes  = []string{"user_no_show", "guide_no_show"}
+	disputeReportTypes = []string{"dispute_no_show", "guide_no_show", "inappropriate_behavior", "safety_issue", "payment_issue", "other"}
+)
+
+// ReportFilter - เงื่อนไขของรายงานการเงิน (ช่วงเวลาเป็น [From, To) ค่าว่าง = ไม่จำกัด)
+type ReportFilter struct {
+	From       time.Time
+	To         time.Time
+	Period     string // day, week, month
+	GroupBy    string // "", province, guide
+	ProvinceID uint
+	GuideID    uint
+}
+
+// FinancialReportRow - ยอดของหนึ่งช่วงเวลา (และหนึ่งจังหวัด/ไกด์ ถ้าแยกกลุ่ม)
+type FinancialReportRow struct {
+	Period          string  `json:"period"`
+	GroupID         uint    `json:"group_id,omitempty"`
+	GroupName       string  `json:"group_name,omitempty"`
+	Bookings        int64   `json:"bookings"`
+	GMV             float64 `json:"gmv"`
+	Refunds         float64 `json:"refunds"`
+	GuidePayouts    float64 `json:"guide_payouts"`
+	PlatformRevenue float64 `json:"platform_revenue"`
+}
+
+// FinancialSummary - ยอดรวมของช่วงเวลา + เงินที่ระบบถือไว้ (escrow) ณ ปัจจุบัน + อัตรา dispute/no-show
+type FinancialSummary struct {
+	Bookings          int64   `json:"bookings"`
+	GMV               float64 `json:"gmv"`
+	Refunds           float64 `json:"refunds"`
+	GuidePayouts      float64 `json:"guide_payouts"`
+	PlatformRevenue   float64 `json:"platform_revenue"`
+	OutstandingEscrow float64 `json:"outstanding_escrow"`
+	DisputedBookings  int64   `json:"disputed_bookings"`
+	NoShowBookings    int64   `json:"no_show_bookings"`
+	DisputeRate       float64 `json:"dispute_rate"`
+	NoShowRate        float64 `json:"no_show_rate"`
+}
+
+// periodExpr - SQL ที่แปลง column เวลาเป็นช่วง (day = YYYY-MM-DD, week = วันจันทร์ของสัปดาห์, month = YYYY-MM)
+func periodExpr(db *gorm.DB, period, column string) (string, error) {
+	sqlite := db.Dialector.Name() == "sqlite"
+	switch period {
+	case "day":
+		if sqlite {
+			return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s)", column), nil
+		}
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM-DD')", column), nil
+	case "week":
+		if sqlite {
+			return fmt.Sprintf("date(%s, 'weekday 0', '-6 days')", column), nil
+		}
+		return fmt.Sprintf("to_char(date_trunc('week', %s), 'YYYY-MM-DD')", column), nil
+	case "month":
+		if sqlite {
+			return fmt.Sprintf("strftime('%%Y-%%m', %s)", column), nil
+		}
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM')", column), nil
+	}
+	return "", fmt.Errorf("period must be day, week or month")
+}
+
+func groupExpr(groupBy string) (string, error) {
+	switch groupBy {
+	case "":
+		return "", nil
+	case "province":
+		return "r.province_id", nil
+	case "guide":
+		return "b.guide_id", nil
+	}
+	return "", fmt.Errorf("group_by must be province or guide")
+}
+
+// bookingJoins - join จาก trip_payments (tp) ไปยัง booking (b) และ TripRequire (r) เพื่อกรอง/แยกตามจังหวัดและไกด์
+const bookingJoins = ` JOIN trip_bookings b ON b.id = tp.trip_booking_id
+ LEFT JOIN trip_offers o ON o.id = b.trip_offer_id
+ LEFT JOIN trip_requires r ON r.id = o.trip_require_id`
+
+// settledPayments - ยอดรายการจ่ายออกของแต่ละ payment: processed แล้วเท่าไร ยังค้างกี่รายการ และวันที่จ่ายรายการสุดท้าย
+const settledPayments = `(SELECT tp.id, tp.trip_booking_id, tp.total_amount, tp.status, tp.paid_at,
+  COALESCE(SUM(CASE WHEN pr.status = 'processed' THEN pr.amount ELSE 0 END), 0) AS paid_out,
+  SUM(CASE WHEN pr.status IN ('pending', 'failed') THEN 1 ELSE 0 END) AS open_releases,
+  MAX(pr.processed_at) AS settled_at
+ FROM trip_payments tp
+ LEFT JOIN payment_releases pr ON pr.trip_payment_id = tp.id AND pr.deleted_at IS NULL
+ WHERE tp.deleted_at IS NULL
+ GROUP BY tp.id, tp.trip_booking_id, tp.total_amount, tp.status, tp.paid_at) tp`
+
+// payment ที่ปิดยอดแล้ว: ไม่มีรายการค้าง และจ่ายไกด์ครบ/คืนเงินแล้ว ส่วนที่เหลือคือรายได้ของแพลตฟอร์ม
+const settledCondition = `tp.open_releases = 0 AND tp.status IN ('fully_released', 'refunded', 'partially_refunded')`
+
+func collectedStatuses() []string {
+	statuses := make([]string, 0, len(collectedPaymentStatuses))
+	for status := range collectedPaymentStatuses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+	return statuses
+}
+
+// reportWhere - เงื่อนไขจังหวัด/ไกด์/ช่วงเวลา ของ column วันที่ที่กำหนด
+func reportWhere(f ReportFilter, dateColumn string) (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if dateColumn != "" {
+		if !f.From.IsZero() {
+			conds = append(conds, dateColumn+" >= ?")
+			args = append(args, f.From)
+		}
+		if !f.To.IsZero() {
+			conds = append(conds, dateColumn+" < ?")
+			args = append(args, f.To)
+		}
+	}
+	if f.ProvinceID != 0 {
+		conds = append(conds, "r.province_id = ?")
+		args = append(args, f.ProvinceID)
+	}
+	if f.GuideID != 0 {
+		conds = append(conds, "b.guide_id = ?")
+		args = append(args, f.GuideID)
+	}
+	if len(conds) == 0 {
+		return "", nil
+	}
+	return " AND " + strings.Join(conds, " AND "), args
+}
+
+type reportAggregate struct {
+	Period   string
+	GroupID  uint
+	Bookings int64
+	Amount   float64
+	Amount2  float64
+}
+
+func (s *FinancialReportService) aggregate(f ReportFilter, from, dateColumn, where string, whereArgs []interface{}, selects string) ([]reportAggregate, error) {
+	bucket, err := periodExpr(s.db, f.Period, dateColumn)
+	if err != nil {
+		return nil, err
+	}
+	group, err := groupExpr(f.GroupBy)
+	if err != nil {
+		return nil, err
+	}
+
+	groupSelect, groupBy := "0", bucket
+	if group != "" {
+		groupSelect, groupBy = group, bucket+", "+group
+	}
+	extra, extraArgs := reportWhere(f, dateColumn)
+
+	query := fmt.Sprintf("SELECT %s AS period, %s AS group_id, %s FROM %s%s WHERE %s%s GROUP BY %s",
+		bucket, groupSelect, selects, from, bookingJoins, where, extra, groupBy)
+
+	var rows []reportAggregate
+	if err := s.db.Raw(query, append(whereArgs, extraArgs...)...).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// FinancialReportService คำนวณรายงานการเงินด้วย SQL aggregation
+type FinancialReportService struct {
+	db *gorm.DB
+}
+
+func NewFinancialReportService(db *gorm.DB) *FinancialReportService {
+	return &FinancialReportService{db: db}
+}
+
+// Report ยอด GMV/คืนเงิน/จ่ายไกด์/รายได้แพลตฟอร์ม แยกตามช่วงเวลา (และจังหวัด/ไกด์) ใหม่สุดก่อน
+// GMV นับตามวันที่ชำระเงิน คืนเงิน/จ่ายไกด์นับตามวันที่จ่ายจริง รายได้นับตามวันที่ปิดยอดของ payment
+func (s *FinancialReportService) Report(f ReportFilter) ([]FinancialReportRow, error) {
+	gmv, err := s.aggregate(f, "trip_payments tp", "tp.paid_at",
+		"tp.deleted_at IS NULL AND tp.status IN ?", []interface{}{collectedStatuses()},
+		"COUNT(*) AS bookings, COALESCE(SUM(tp.total_amount), 0) AS amount")
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := s.aggregate(f, "payment_releases pr JOIN trip_payments tp ON tp.id = pr.trip_payment_id", "pr.processed_at",
+		"pr.deleted_at IS NULL AND pr.status = 'processed'", nil,
+		"COALESCE(SUM(CASE WHEN pr.recipient_type = 'user' THEN pr.amount ELSE 0 END), 0) AS amount, "+
+			"COALESCE(SUM(CASE WHEN pr.recipient_type = 'guide' THEN pr.amount ELSE 0 END), 0) AS amount2")
+	if err != nil {
+		return nil, err
+	}
+
+	revenue, err := s.aggregate(f, settledPayments, "COALESCE(tp.settled_at, tp.paid_at)",
+		settledCondition, nil,
+		"COALESCE(SUM(tp.total_amount - tp.paid_out), 0) AS amount")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := map[string]*FinancialReportRow{}
+	row := func(a reportAggregate) *FinancialReportRow {
+		key := fmt.Sprintf("%s|%d", a.Period, a.GroupID)
+		if rows[key] == nil {
+			rows[key] = &FinancialReportRow{Period: a.Period, GroupID: a.GroupID}
+		}
+		return rows[key]
+	}
+	for _, a := range gmv {
+		r := row(a)
+		r.Bookings = a.Bookings
+		r.GMV = roundAmount(a.Amount)
+	}
+	for _, a := range releases {
+		r := row(a)
+		r.Refunds = roundAmount(a.Amount)
+		r.GuidePayouts = roundAmount(a.Amount2)
+	}
+	for _, a := range revenue {
+		row(a).PlatformRevenue = roundAmount(a.Amount)
+	}
+
+	out := make([]FinancialReportRow, 0, len(rows))
+	for _, r := range rows {
+		out = append(out, *r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Period != out[j].Period {
+			return out[i].Period > out[j].Period
+		}
+		return out[i].GroupID < out[j].GroupID
+	})
+
+	if err := s.fillGroupNames(f.GroupBy, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func roundAmount(amount float64) float64 {
+	return centsToAmount(toCents(amount))
+}
+
+func (s *FinancialReportService) fillGroupNames(groupBy string, rows []FinancialReportRow) error {
+	if groupBy == "" || len(rows) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(rows))
+	for _, r := range rows {
+		ids = append(ids, r.GroupID)
+	}
+
+	names := map[uint]string{}
+	if groupBy == "province" {
+		var provinces []models.Province
+		if err := s.db.Where("id IN ?", ids).Find(&provinces).Error; err != nil {
+			return err
+		}
+		for _, p := range provinces {
+			names[p.ID] = p.Name
+		}
+	} else {
+		var guides []models.Guide
+		if err := s.db.Preload("User").Where("id IN ?", ids).Find(&guides).Error; err != nil {
+			return err
+		}
+		for _, g := range guides {
+			names[g.ID] = fullName(g.User)
+		}
+	}
+	for i := range rows {
+		rows[i].GroupName = names[rows[i].GroupID]
+	}
+	return nil
+}
+
+// Summary ยอดรวมของช่วงเวลา อัตรา dispute/no-show คิดจาก booking ที่ชำระเงินในช่วงนั้น
+// escrow คือเงินที่ชำระแล้วแต่ยังไม่ได้จ่ายไกด์/คืนเงิน ณ ปัจจุบัน (ไม่ขึ้นกับช่วงเวลา)
+func (s *FinancialReportService) Summary(f ReportFilter) (*FinancialSummary, error) {
+	f.Period, f.GroupBy = "month", ""
+	rows, err := s.Report(f)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &FinancialSummary{}
+	for _, r := range rows {
+		summary.Bookings += r.Bookings
+		summary.GMV += r.GMV
+		summary.Refunds += r.Refunds
+		summary.GuidePayouts += r.GuidePayouts
+		summary.PlatformRevenue += r.PlatformRevenue
+	}
+	summary.GMV = roundAmount(summary.GMV)
+	summary.Refunds = roundAmount(summary.Refunds)
+	summary.GuidePayouts = roundAmount(summary.GuidePayouts)
+	summary.PlatformRevenue = roundAmount(summary.PlatformRevenue)
+
+	escrowWhere, escrowArgs := reportWhere(ReportFilter{ProvinceID: f.ProvinceID, GuideID: f.GuideID}, "")
+	var escrow float64
+	if err := s.db.Raw("SELECT COALESCE(SUM(tp.total_amount - tp.paid_out), 0) FROM "+settledPayments+bookingJoins+
+		" WHERE tp.status IN ? AND NOT ("+settledCondition+")"+escrowWhere,
+		append([]interface{}{collectedStatuses()}, escrowArgs...)...).Scan(&escrow).Error; err != nil {
+		return nil, err
+	}
+	summary.OutstandingEscrow = roundAmount(escrow)
+
+	paidWhere, paidArgs := reportWhere(f, "tp.paid_at")
+	countReports := func(types []string) (int64, error) {
+		var n int64
+		err := s.db.Raw("SELECT COUNT(DISTINCT b.id) FROM trip_payments tp"+bookingJoins+
+			" JOIN trip_reports tr ON tr.trip_booking_id = b.id AND tr.deleted_at IS NULL"+
+			" WHERE tp.deleted_at IS NULL AND tp.status IN ? AND tr.report_type IN ?"+paidWhere,
+			append([]interface{}{collectedStatuses(), types}, paidArgs...)...).Scan(&n).Error
+		return n, err
+	}
+	if summary.DisputedBookings, err = countReports(disputeReportTypes); err != nil {
+		return nil, err
+	}
+	if summary.NoShowBookings, err = countReports(noShowReportTypes); err != nil {
+		return nil, err
+	}
+	if summary.Bookings > 0 {
+		summary.DisputeRate = float64(summary.DisputedBookings) / float64(summary.Bookings)
+		summary.NoShowRate = float64(summary.NoShowBookings) / float64(summary.Bookings)
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,72 @@
+package services
+
+import "math"
+
+const earthRadiusMeters = 6371000.0
+
+// DistanceMeters คำนวณระยะทางบนผิวโลก (haversine) ระหว่างสองพิกัด หน่วยเมตร
+func DistanceMeters(lat1, lon1, lat2, lon2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+
+	dLat := toRad(lat2 - lat1)
+	dLon := toRad(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusMeters * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// ประมาณระยะทางจริงจากระยะเส้นตรง (ไม่ใช้ maps API ภายนอก)
+const (
+	roadDistanceFactor    = 1.3  // ถนนจริงยาวกว่าเส้นตรงโดยเฉลี่ยราว 30%
+	averageTravelSpeedKmh = 40.0 // ความเร็วเฉลี่ยรวมการจราจรในเมือง
+)
+
+// DistanceKm - ระยะเส้นตรงระหว่างสองพิกัด หน่วยกิโลเมตร
+func DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	return DistanceMeters(lat1, lon1, lat2, lon2) / 1000
+}
+
+// BoundingBox คืนกรอบพิกัดที่ครอบวงกลมรัศมี radiusKm รอบจุด ใช้กรองใน SQL ก่อนคำนวณระยะจริง
+func BoundingBox(lat, lon, radiusKm float64) (minLat, maxLat, minLon, maxLon float64) {
+	dLat := radiusKm / (earthRadiusMeters / 1000) * 180 / math.Pi
+	minLat, maxLat = math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+	cosLat := math.Cos(lat * math.Pi / 180)
+	if cosLat < 0.01 || minLat <= -90 || maxLat >= 90 {
+		// ใกล้ขั้วโลก กรอบครอบทุกลองจิจูด
+		return minLat, maxLat, -180, 180
+	}
+	dLon := dLat / cosLat
+	return minLat, maxLat, lon - dLon, lon + dLon
+}
+
+// RoutePoint - จุดแวะบนเส้นทาง (ไม่คิดระยะข้ามวัน และข้ามจุดที่ไม่มีพิกัด)
+type RoutePoint struct {
+	Day       int
+	Latitude  *float64
+	Longitude *float64
+}
+
+// RouteLeg - ระยะทางและเวลาเดินทางโดยประมาณจากจุดก่อนหน้า
+type RouteLeg struct {
+	DistanceKm    float64
+	TravelMinutes int
+}
+
+// EstimateRouteLegs ประมาณระยะจากจุดก่อนหน้าในวันเดียวกันของแต่ละจุด
+// คืน nil ในตำแหน่งของจุดแรกของวัน หรือเมื่อจุดใดจุดหนึ่งไม่มีพิกัด
+func EstimateRouteLegs(points []RoutePoint) []*RouteLeg {
+	legs := make([]*RouteLeg, len(points))
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1], points[i]
+		if prev.Day != cur.Day || prev.Latitude == nil || prev.Longitude == nil ||
+			cur.Latitude == nil || cur.Longitude == nil {
+			continue
+		}
+		km := DistanceKm(*prev.Latitude, *prev.Longitude, *cur.Latitude, *cur.Longitude) * roadDistanceFactor
+		legs[i] = &RouteLeg{
+			DistanceKm:    math.Round(km*10) / 10,
+			TravelMinutes: int(math.Ceil(km / averageTravelSpeedKmh * 60)),
+		}
+	}
+	return legs
+}
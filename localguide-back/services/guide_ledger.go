@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"localguide-back/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// บัญชีในสมุดรายได้ของไกด์ ทุกรายการย้ายเงินจากบัญชีหนึ่งไปอีกบัญชีหนึ่ง ยอดรวมทุกบัญชีจึงเป็นศูนย์เสมอ
+const (
+	LedgerAccountTraveller = "traveller" // เงินที่นักท่องเที่ยวจ่ายเข้ามา (ยอดติดลบ = ยอดที่เก็บได้)
+	LedgerAccountPending   = "pending"   // ระบบถือไว้ รอจ่ายให้ไกด์
+	LedgerAccountReleased  = "released"  // จ่ายให้ไกด์แล้ว
+	LedgerAccountRefunded  = "refunded"  // คืนให้นักท่องเที่ยวแล้ว
+)
+
+// LedgerEntry - รายการในสมุดรายได้ สร้างจาก TripPayment (รับเงิน) และ PaymentRelease ที่ processed แล้ว (จ่ายไกด์/คืนเงิน)
+type LedgerEntry struct {
+	Date             time.Time `json:"date"`
+	Type             string    `json:"type"` // booking_paid, guide_release, refund
+	TripBookingID    uint      `json:"trip_booking_id"`
+	TripPaymentID    uint      `json:"trip_payment_id"`
+	PaymentReleaseID uint      `json:"payment_release_id,omitempty"`
+	ReleaseType      string    `json:"release_type,omitempty"`
+	From             string    `json:"from"`
+	To               string    `json:"to"`
+	AmountCents      int64     `json:"-"`
+	Amount           float64   `json:"amount"`
+	Reference        string    `json:"reference"` // PaymentNumber หรือ TransactionRef ของ release
+}
+
+// LedgerTotals - ยอดรวมของรายการชุดหนึ่ง
+type LedgerTotals struct {
+	Collected float64 `json:"collected"`
+	Pending   float64 `json:"pending"`
+	Released  float64 `json:"released"`
+	Refunded  float64 `json:"refunded"`
+}
+
+func centsToAmount(cents int64) float64 {
+	return float64(cents) / 100
+}
+
+// BuildGuideLedger สร้างสมุดรายได้ของไกด์ เรียงตามวันที่
+func BuildGuideLedger(db *gorm.DB, guideID uint) ([]LedgerEntry, error) {
+	statuses := make([]string, 0, len(collectedPaymentStatuses))
+	for status := range collectedPaymentStatuses {
+		statuses = append(statuses, status)
+	}
+
+	var payments []models.TripPayment
+	if err := db.Joins("JOIN trip_bookings ON trip_bookings.id = trip_payments.trip_booking_id").
+		Where("trip_bookings.guide_id = ? AND trip_payments.status IN ?", guideID, statuses).
+		Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+	if len(payments) == 0 {
+		return []LedgerEntry{}, nil
+	}
+
+	paymentIDs := make([]uint, len(payments))
+	bookingOf := make(map[uint]uint, len(payments))
+	entries := make([]LedgerEntry, 0, len(payments)*3)
+	for i, p := range payments {
+		paymentIDs[i] = p.ID
+		bookingOf[p.ID] = p.TripBookingID
+
+		date := p.UpdatedAt
+		if p.PaidAt != nil {
+			date = *p.PaidAt
+		}
+		cents := toCents(p.TotalAmount)
+		entries = append(entries, LedgerEntry{
+			Date:          date,
+			Type:          "booking_paid",
+			TripBookingID: p.TripBookingID,
+			TripPaymentID: p.ID,
+			From:          LedgerAccountTraveller,
+			To:            LedgerAccountPending,
+			AmountCents:   cents,
+			Amount:        centsToAmount(cents),
+			Reference:     p.PaymentNumber,
+		})
+	}
+
+	var releases []models.PaymentRelease
+	if err := db.Where("trip_payment_id IN ? AND status = ?", paymentIDs, "processed").
+		Where("(recipient_type = ? AND recipient_id = ?) OR recipient_type = ?", "guide", guideID, "user").
+		Find(&releases).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payment releases: %w", err)
+	}
+
+	for _, r := range releases {
+		date := r.ScheduledAt
+		if r.ProcessedAt != nil {
+			date = *r.ProcessedAt
+		}
+		entry := LedgerEntry{
+			Date:             date,
+			Type:             "guide_release",
+			TripBookingID:    bookingOf[r.TripPaymentID],
+			TripPaymentID:    r.TripPaymentID,
+			PaymentReleaseID: r.ID,
+			ReleaseType:      r.ReleaseType,
+			From:             LedgerAccountPending,
+			To:               LedgerAccountReleased,
+			AmountCents:      toCents(r.Amount),
+			Amount:           centsToAmount(toCents(r.Amount)),
+			Reference:        r.TransactionRef,
+		}
+		if r.RecipientType == "user" {
+			entry.Type = "refund"
+			entry.To = LedgerAccountRefunded
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.Before(entries[j].Date)
+		}
+		if entries[i].TripPaymentID != entries[j].TripPaymentID {
+			return entries[i].TripPaymentID < entries[j].TripPaymentID
+		}
+		return entries[i].PaymentReleaseID < entries[j].PaymentReleaseID
+	})
+
+	return entries, nil
+}
+
+// LedgerBalances คืนยอดคงเหลือของแต่ละบัญชี (หน่วยสตางค์)
+func LedgerBalances(entries []LedgerEntry) map[string]int64 {
+	balances := map[string]int64{
+		LedgerAccountTraveller: 0,
+		LedgerAccountPending:   0,
+		LedgerAccountReleased:  0,
+		LedgerAccountRefunded:  0,
+	}
+	for _, e := range entries {
+		balances[e.From] -= e.AmountCents
+		balances[e.To] += e.AmountCents
+	}
+	return balances
+}
+
+// SummarizeLedger สรุปยอดของรายการชุดหนึ่ง
+func SummarizeLedger(entries []LedgerEntry) LedgerTotals {
+	b := LedgerBalances(entries)
+	return LedgerTotals{
+		Collected: centsToAmount(-b[LedgerAccountTraveller]),
+		Pending:   centsToAmount(b[LedgerAccountPending]),
+		Released:  centsToAmount(b[LedgerAccountReleased]),
+		Refunded:  centsToAmount(b[LedgerAccountRefunded]),
+	}
+}
+
+// FilterLedger คืนรายการในช่วง [from, to)
+func FilterLedger(entries []LedgerEntry, from, to time.Time) []LedgerEntry {
+	out := make([]LedgerEntry, 0)
+	for _, e := range entries {
+		if (from.IsZero() || !e.Date.Before(from)) && (to.IsZero() || e.Date.Before(to)) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// EarningsStatement - รายงานรายได้ประจำเดือน ยอดยกมา + รับเข้า - จ่ายไกด์ - คืนเงิน = ยอดยกไป
+type EarningsStatement struct {
+	GuideID        uint          `json:"guide_id"`
+	Month          string        `json:"month"` // YYYY-MM
+	OpeningPending float64       `json:"opening_pending"`
+	Collected      float64       `json:"collected"`
+	Released       float64       `json:"released"`
+	Refunded       float64       `json:"refunded"`
+	ClosingPending float64       `json:"closing_pending"`
+	Entries        []LedgerEntry `json:"entries"`
+}
+
+// BuildEarningsStatement สร้าง statement ของเดือนที่ month อยู่ (ใช้ location ของ month)
+func BuildEarningsStatement(guideID uint, entries []LedgerEntry, month time.Time) *EarningsStatement {
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
+	end := start.AddDate(0, 1, 0)
+
+	opening := LedgerBalances(FilterLedger(entries, time.Time{}, start))
+	inMonth := FilterLedger(entries, start, end)
+	moved := LedgerBalances(inMonth)
+
+	return &EarningsStatement{
+		GuideID:        guideID,
+		Month:          start.Format("2006-01"),
+		OpeningPending: centsToAmount(opening[LedgerAccountPending]),
+		Collected:      centsToAmount(-moved[LedgerAccountTraveller]),
+		Released:       centsToAmount(moved[LedgerAccountReleased]),
+		Refunded:       centsToAmount(moved[LedgerAccountRefunded]),
+		ClosingPending: centsToAmount(opening[LedgerAccountPending] + moved[LedgerAccountPending]),
+		Entries:        inMonth,
+	}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// signedAmount - ผลต่อยอด pending ของรายการ (รับเข้า +, จ่ายออก -)
+func signedAmount(e LedgerEntry) float64 {
+	if e.To == LedgerAccountPending {
+		return e.Amount
+	}
+	return -e.Amount
+}
+
+// CSV ส่งออก statement เป็น CSV หนึ่งแถวต่อรายการ ตามด้วยสรุปยอด
+func (s *EarningsStatement) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	w.Write([]string{"date", "type", "release_type", "trip_booking_id", "payment_release_id", "reference", "amount", "pending_balance"})
+	balance := toCents(s.OpeningPending)
+	for _, e := range s.Entries {
+		balance += toCents(signedAmount(e))
+		releaseID := ""
+		if e.PaymentReleaseID != 0 {
+			releaseID = strconv.Itoa(int(e.PaymentReleaseID))
+		}
+		w.Write([]string{
+			e.Date.Format(time.RFC3339),
+			e.Type,
+			e.ReleaseType,
+			strconv.Itoa(int(e.TripBookingID)),
+			releaseID,
+			e.Reference,
+			formatAmount(signedAmount(e)),
+			formatAmount(centsToAmount(balance)),
+		})
+	}
+
+	w.Write([]string{})
+	w.Write([]string{"opening_pending", formatAmount(s.OpeningPending)})
+	w.Write([]string{"collected", formatAmount(s.Collected)})
+	w.Write([]string{"released", formatAmount(s.Released)})
+	w.Write([]string{"refunded", formatAmount(s.Refunded)})
+	w.Write([]string{"closing_pending", formatAmount(s.ClosingPending)})
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// PDF ส่งออก statement เป็น PDF แบบตัวอักษรล้วน
+func (s *EarningsStatement) PDF() []byte {
+	lines := []string{
+		fmt.Sprintf("Guide #%d - earnings statement %s", s.GuideID, s.Month),
+		"",
+		fmt.Sprintf("%-10s  %-13s  %-8s  %-8s  %-22s  %12s", "Date", "Type", "Booking", "Release", "Reference", "Amount"),
+	}
+	for _, e := range s.Entries {
+		releaseID := "-"
+		if e.PaymentReleaseID != 0 {
+			releaseID = strconv.Itoa(int(e.PaymentReleaseID))
+		}
+		lines = append(lines, fmt.Sprintf("%-10s  %-13s  %-8d  %-8s  %-22.22s  %12s",
+			e.Date.Format("2006-01-02"), e.Type, e.TripBookingID, releaseID, e.Reference, formatAmount(signedAmount(e))))
+	}
+	lines = append(lines,
+		"",
+		fmt.Sprintf("%-20s %12s THB", "Opening pending", formatAmount(s.OpeningPending)),
+		fmt.Sprintf("%-20s %12s THB", "Collected", formatAmount(s.Collected)),
+		fmt.Sprintf("%-20s %12s THB", "Released to guide", formatAmount(s.Released)),
+		fmt.Sprintf("%-20s %12s THB", "Refunded", formatAmount(s.Refunded)),
+		fmt.Sprintf("%-20s %12s THB", "Closing pending", formatAmount(s.ClosingPending)),
+	)
+	return RenderTextPDF(lines)
+}
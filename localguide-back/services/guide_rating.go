@@ -0,0 +1,117 @@
+package services
+
+import (
+	"localguide-back/models"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultRatingPriorMean   = 4.0
+	defaultRatingPriorWeight = 10
+)
+
+// BayesianScore - ค่าเฉลี่ยที่ถ่วงด้วยรีวิวสมมติ weight รีวิวที่ได้คะแนน mean
+// ไกด์ที่มีรีวิวน้อยจะได้คะแนนใกล้ mean ส่วนไกด์ที่มีรีวิวมากจะใกล้ค่าเฉลี่ยจริง
+func BayesianScore(average float64, count int, mean, weight float64) float64 {
+	if count == 0 && weight == 0 {
+		return 0
+	}
+	return (weight*mean + average*float64(count)) / (weight + float64(count))
+}
+
+func roundRating(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
+
+// RecomputeGuideRating คำนวณคะแนนรวมของไกด์จากรีวิวที่ไม่ถูกลบ/ซ่อน แล้วบันทึกทั้ง GuideRatingSummary และ Guide.Rating
+// ควรเรียกใน transaction เดียวกับการแก้ไขรีวิว ค่าเฉลี่ยตั้งต้นปรับได้ด้วย RATING_PRIOR_MEAN และ RATING_PRIOR_WEIGHT
+func RecomputeGuideRating(tx *gorm.DB, guideID uint) (*models.GuideRatingSummary, error) {
+	var agg struct {
+		ReviewCount          int
+		AverageRating        float64
+		AverageService       float64
+		AverageKnowledge     float64
+		AverageCommunication float64
+		AveragePunctuality   float64
+		FiveStars            int
+		FourStars            int
+		ThreeStars           int
+		TwoStars             int
+		OneStar              int
+	}
+	if err := tx.Model(&models.TripReview{}).
+		Where("guide_id = ? AND is_hidden = ?", guideID, false).
+		Select("COUNT(*) AS review_count, " +
+			"COALESCE(AVG(rating), 0) AS average_rating, " +
+			"COALESCE(AVG(service_rating), 0) AS average_service, " +
+			"COALESCE(AVG(knowledge_rating), 0) AS average_knowledge, " +
+			"COALESCE(AVG(communication_rating), 0) AS average_communication, " +
+			"COALESCE(AVG(punctuality_rating), 0) AS average_punctuality, " +
+			"COALESCE(SUM(CASE WHEN rating >= 4.5 THEN 1 ELSE 0 END), 0) AS five_stars, " +
+			"COALESCE(SUM(CASE WHEN rating >= 3.5 AND rating < 4.5 THEN 1 ELSE 0 END), 0) AS four_stars, " +
+			"COALESCE(SUM(CASE WHEN rating >= 2.5 AND rating < 3.5 THEN 1 ELSE 0 END), 0) AS three_stars, " +
+			"COALESCE(SUM(CASE WHEN rating >= 1.5 AND rating < 2.5 THEN 1 ELSE 0 END), 0) AS two_stars, " +
+			"COALESCE(SUM(CASE WHEN rating < 1.5 THEN 1 ELSE 0 END), 0) AS one_star").
+		Scan(&agg).Error; err != nil {
+		return nil, err
+	}
+
+	summary := models.GuideRatingSummary{
+		GuideID:              guideID,
+		ReviewCount:          agg.ReviewCount,
+		AverageRating:        roundRating(agg.AverageRating),
+		AverageService:       roundRating(agg.AverageService),
+		AverageKnowledge:     roundRating(agg.AverageKnowledge),
+		AverageCommunication: roundRating(agg.AverageCommunication),
+		AveragePunctuality:   roundRating(agg.AveragePunctuality),
+		FiveStars:            agg.FiveStars,
+		FourStars:            agg.FourStars,
+		ThreeStars:           agg.ThreeStars,
+		TwoStars:             agg.TwoStars,
+		OneStar:              agg.OneStar,
+		UpdatedAt:            time.Now(),
+	}
+	mean, weight := RatingPrior()
+	summary.BayesianScore = roundRating(BayesianScore(agg.AverageRating, agg.ReviewCount, mean, weight))
+
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "guide_id"}},
+		UpdateAll: true,
+	}).Create(&summary).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&models.Guide{}).Where("id = ?", guideID).
+		Update("rating", summary.AverageRating).Error; err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// RatingPrior - ค่าเฉลี่ยตั้งต้นและน้ำหนักที่ใช้ (ไกด์ที่ยังไม่มีรีวิวได้คะแนนเท่า mean)
+func RatingPrior() (mean, weight float64) {
+	return envRate("RATING_PRIOR_MEAN", defaultRatingPriorMean), envRate("RATING_PRIOR_WEIGHT", defaultRatingPriorWeight)
+}
+
+// BackfillGuideRatings คำนวณคะแนนรวมให้ไกด์ที่มีรีวิวแต่ยังไม่มี GuideRatingSummary (ข้อมูลก่อนมีตารางนี้)
+func BackfillGuideRatings(db *gorm.DB) error {
+	var guideIDs []uint
+	if err := db.Model(&models.TripReview{}).
+		Where("guide_id NOT IN (?)", db.Model(&models.GuideRatingSummary{}).Select("guide_id")).
+		Distinct().Pluck("guide_id", &guideIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range guideIDs {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			_, err := RecomputeGuideRating(tx, id)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+const (
+	imageJPEGQuality = 85
+	// imageMaxDimension - ด้านยาวสูงสุดของไฟล์หลัก (รูปที่ใหญ่กว่านี้ถูกย่อลง)
+	imageMaxDimension = 2560
+	// imageMaxPixels - กันไฟล์ที่บีบอัดเล็กแต่ขยายเป็นภาพขนาดมหาศาล (decompression bomb)
+	imageMaxPixels = 40_000_000
+)
+
+// ErrInvalidImage - ไฟล์ไม่ใช่รูปที่ถอดรหัสได้ (รองรับ JPEG, PNG, GIF)
+var ErrInvalidImage = errors.New("file is not a supported image (jpeg, png or gif)")
+
+// ImageVariantSpec - ขนาดรูปย่อ กำหนดด้านยาวสูงสุด (ไม่ขยายรูปที่เล็กกว่า)
+type ImageVariantSpec struct {
+	Name    string
+	MaxSize int
+}
+
+// ImageVariantSpecs - รูปย่อที่สร้างให้ทุกรูปที่ผ่าน pipeline
+var ImageVariantSpecs = []ImageVariantSpec{
+	{Name: "thumbnail", MaxSize: 200},
+	{Name: "medium", MaxSize: 800},
+	{Name: "large", MaxSize: 1600},
+}
+
+// EncodedImage - รูปที่เข้ารหัส JPEG แล้วพร้อมขนาด
+type EncodedImage struct {
+	Name   string // ชื่อ variant (ว่าง = ไฟล์หลัก)
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// ProcessedImage - ผลของ ProcessImage: ไฟล์หลักและรูปย่อตามลำดับ ImageVariantSpecs
+type ProcessedImage struct {
+	EncodedImage
+	Variants []EncodedImage
+}
+
+// ProcessImage ถอดรหัสรูป หมุนตาม EXIF orientation แล้วเข้ารหัสใหม่เป็น JPEG
+// การเข้ารหัสใหม่ไม่คัดลอก metadata ใดๆ (EXIF, GPS, ICC, comment) จากไฟล์ต้นฉบับ
+// ส่วนโปร่งใสของ PNG/GIF จะถูกเติมพื้นขาว
+func ProcessImage(data []byte) (*ProcessedImage, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > imageMaxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels is too large", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrInvalidImage
+	}
+
+	img := flattenImage(src)
+	if format == "jpeg" {
+		img = applyOrientation(img, jpegOrientation(data))
+	}
+	img = fitImage(img, imageMaxDimension)
+
+	main, err := encodeJPEG("", img)
+	if err != nil {
+		return nil, err
+	}
+	result := &ProcessedImage{EncodedImage: main}
+	for _, spec := range ImageVariantSpecs {
+		variant, err := encodeJPEG(spec.Name, fitImage(img, spec.MaxSize))
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, variant)
+	}
+	return result, nil
+}
+
+func encodeJPEG(name string, img *image.RGBA) (EncodedImage, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: imageJPEGQuality}); err != nil {
+		return EncodedImage{}, fmt.Errorf("failed to encode image: %w", err)
+	}
+	b := img.Bounds()
+	return EncodedImage{Name: name, Data: buf.Bytes(), Width: b.Dx(), Height: b.Dy()}, nil
+}
+
+// flattenImage แปลงเป็น RGBA เริ่มที่ (0,0) บนพื้นขาว
+func flattenImage(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Over)
+	return dst
+}
+
+// jpegOrientation อ่านค่า Orientation (tag 0x0112) จาก EXIF ใน APP1 คืน 1 ถ้าไม่มีหรืออ่านไม่ได้
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		if marker == 0xD8 || marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7) {
+			i += 2
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 { // เริ่มข้อมูลภาพแล้ว ไม่มี EXIF หลังจากนี้
+			return 1
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2 : i+4]))
+		if length < 2 || i+2+length > len(data) {
+			return 1
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return 1
+}
+
+// exifOrientation อ่าน Orientation จาก IFD0 ของ TIFF header
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(tiff[4:8]))
+	if ifd < 8 || ifd+2 > len(tiff) {
+		return 1
+	}
+	entries := int(order.Uint16(tiff[ifd : ifd+2]))
+	for n := 0; n < entries; n++ {
+		entry := ifd + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			v := int(order.Uint16(tiff[entry+8 : entry+10]))
+			if v >= 1 && v <= 8 {
+				return v
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// applyOrientation หมุน/กลับรูปตามค่า EXIF orientation (1 = ไม่ต้องทำอะไร)
+func applyOrientation(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			si := src.PixOffset(sx, sy)
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[si:si+4])
+		}
+	}
+	return dst
+}
+
+// fitImage ย่อรูปให้ด้านยาวไม่เกิน maxSize โดยคงสัดส่วน (รูปที่เล็กกว่าคืนตัวเดิม)
+func fitImage(src *image.RGBA, maxSize int) *image.RGBA {
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	if w <= maxSize && h <= maxSize {
+		return src
+	}
+	dw, dh := maxSize, h*maxSize/w
+	if h > w {
+		dw, dh = w*maxSize/h, maxSize
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+	// ย่อครึ่งทีละขั้นก่อน (เฉลี่ย 2x2) แล้วจึง bilinear เพื่อไม่ให้รายละเอียดหายเป็นจุดๆ
+	img := src
+	for img.Bounds().Dx() >= dw*2 && img.Bounds().Dy() >= dh*2 {
+		img = halveImage(img)
+	}
+	return resizeBilinear(img, dw, dh)
+}
+
+func halveImage(src *image.RGBA) *image.RGBA {
+	w, h := src.Bounds().Dx()/2, src.Bounds().Dy()/2
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a := src.PixOffset(2*x, 2*y)
+			b := src.PixOffset(2*x+1, 2*y)
+			c := src.PixOffset(2*x, 2*y+1)
+			d := src.PixOffset(2*x+1, 2*y+1)
+			o := dst.PixOffset(x, y)
+			for i := 0; i < 4; i++ {
+				sum := int(src.Pix[a+i]) + int(src.Pix[b+i]) + int(src.Pix[c+i]) + int(src.Pix[d+i])
+				dst.Pix[o+i] = uint8((sum + 2) / 4)
+			}
+		}
+	}
+	return dst
+}
+
+func resizeBilinear(src *image.RGBA, dw, dh int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw == dw && sh == dh {
+		return src
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		fy := (float64(y)+0.5)*float64(sh)/float64(dh) - 0.5
+		y0, wy := clampFloor(fy, sh)
+		y1 := min(y0+1, sh-1)
+		for x := 0; x < dw; x++ {
+			fx := (float64(x)+0.5)*float64(sw)/float64(dw) - 0.5
+			x0, wx := clampFloor(fx, sw)
+			x1 := min(x0+1, sw-1)
+			p00, p10 := src.PixOffset(x0, y0), src.PixOffset(x1, y0)
+			p01, p11 := src.PixOffset(x0, y1), src.PixOffset(x1, y1)
+			o := dst.PixOffset(x, y)
+			for i := 0; i < 4; i++ {
+				top := float64(src.Pix[p00+i])*(1-wx) + float64(src.Pix[p10+i])*wx
+				bottom := float64(src.Pix[p01+i])*(1-wx) + float64(src.Pix[p11+i])*wx
+				dst.Pix[o+i] = uint8(top*(1-wy) + bottom*wy + 0.5)
+			}
+		}
+	}
+	return dst
+}
+
+// clampFloor คืนพิกัดจำนวนเต็มที่อยู่ในรูปและน้ำหนักส่วนเศษ
+func clampFloor(f float64, size int) (int, float64) {
+	if f <= 0 {
+		return 0, 0
+	}
+	i := int(f)
+	if i >= size-1 {
+		return size - 1, 0
+	}
+	return i, f - float64(i)
+}
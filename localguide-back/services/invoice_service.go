@@ -0,0 +1,254 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultVATRate         = 7.0
+	defaultWithholdingRate = 3.0
+	defaultPlatformName    = "LocalGuide"
+)
+
+// envRate - อ่านอัตรา % จาก env (ไม่ตั้ง/ผิดรูปแบบ = ค่าเริ่มต้น, 0 = ไม่คิด)
+func envRate(key string, fallback float64) float64 {
+	if v, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && v >= 0 {
+		return v
+	}
+	return fallback
+}
+
+// invoiceAmounts แยก VAT ออกจากยอดที่รวม VAT แล้ว และคิดภาษีหัก ณ ที่จ่ายจากยอดก่อน VAT (หน่วยสตางค์)
+func invoiceAmounts(totalCents int64, vatRate, withholdingRate float64) (net, vat, withholding int64) {
+	vat = int64(math.Round(float64(totalCents) * vatRate / (100 + vatRate)))
+	net = totalCents - vat
+	withholding = int64(math.Round(float64(net) * withholdingRate / 100))
+	return net, vat, withholding
+}
+
+func fullName(user models.User) string {
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
+
+// IssueInvoices ออกใบกำกับภาษี/ใบเสร็จของ booking ที่ชำระเงินแล้ว และใบลดหนี้ของทุก refund ที่ยังไม่มีใบลดหนี้
+// เรียกซ้ำได้ (ออกเฉพาะเอกสารที่ยังไม่มี) คืนเอกสารทั้งหมดของ booking เรียงตามเลขที่ ถ้ายังไม่ได้ชำระเงินคืน slice ว่าง
+func IssueInvoices(db *gorm.DB, bookingID uint, now time.Time) ([]models.Invoice, error) {
+	var documents []models.Invoice
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// ล็อกแถว payment ก่อนตรวจว่ามีเอกสารแล้วหรือยัง การเรียกพร้อมกัน (confirm กับ webhook) จะรอกันแทนที่จะออกใบกำกับภาษีซ้ำ
+		var payment models.TripPayment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("trip_booking_id = ?", bookingID).Order("id DESC").First(&payment).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil
+			}
+			return fmt.Errorf("failed to get payment: %w", err)
+		}
+		if !collectedPaymentStatuses[payment.Status] {
+			return nil
+		}
+
+		var invoice models.Invoice
+		err := tx.Where("trip_booking_id = ? AND document_type = ?", bookingID, "invoice").First(&invoice).Error
+		if err == gorm.ErrRecordNotFound {
+			created, err := createInvoice(tx, &payment, now)
+			if err != nil {
+				return err
+			}
+			invoice = *created
+		} else if err != nil {
+			return fmt.Errorf("failed to get invoice: %w", err)
+		}
+
+		var refunds []models.PaymentRelease
+		if err := tx.Where("trip_payment_id = ? AND release_type = ? AND recipient_type = ? AND status = ?", payment.ID, "refund", "user", "processed").
+			Where("id NOT IN (?)", tx.Model(&models.Invoice{}).Select("payment_release_id").Where("payment_release_id IS NOT NULL")).
+			Order("processed_at ASC, id ASC").
+			Find(&refunds).Error; err != nil {
+			return fmt.Errorf("failed to get refunds: %w", err)
+		}
+		for i := range refunds {
+			if _, err := createCreditNote(tx, &invoice, &refunds[i], now); err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("trip_booking_id = ?", bookingID).Order("id ASC").Find(&documents).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if documents == nil {
+		documents = []models.Invoice{}
+	}
+	return documents, nil
+}
+
+func createInvoice(tx *gorm.DB, payment *models.TripPayment, now time.Time) (*models.Invoice, error) {
+	var booking models.TripBooking
+	if err := tx.Preload("User").Preload("Guide.User").First(&booking, payment.TripBookingID).Error; err != nil {
+		return nil, fmt.Errorf("booking not found: %w", err)
+	}
+
+	number, err := NextDocumentNumber(tx, "INV", now)
+	if err != nil {
+		return nil, err
+	}
+
+	buyerName := booking.BillingName
+	if buyerName == "" {
+		buyerName = fullName(booking.User)
+	}
+	withholdingRate := 0.0
+	if booking.BillingTaxID != "" {
+		withholdingRate = envRate("WITHHOLDING_RATE", defaultWithholdingRate)
+	}
+	vatRate := envRate("VAT_RATE", defaultVATRate)
+
+	sellerName := os.Getenv("PLATFORM_NAME")
+	if sellerName == "" {
+		sellerName = defaultPlatformName
+	}
+
+	days := booking.Days
+	if days < 1 {
+		days = 1
+	}
+	totalCents := toCents(payment.TotalAmount)
+	net, vat, withholding := invoiceAmounts(totalCents, vatRate, withholdingRate)
+
+	invoice := models.Invoice{
+		TripBookingID:     booking.ID,
+		TripPaymentID:     payment.ID,
+		DocumentType:      "invoice",
+		Number:            number,
+		IssuedAt:          now,
+		Description:       fmt.Sprintf("Local guide service, booking #%d (%s, %d day(s))", booking.ID, booking.StartDate.Format("2006-01-02"), days),
+		Amount:            centsToAmount(totalCents),
+		NetAmount:         centsToAmount(net),
+		VATRate:           vatRate,
+		VATAmount:         centsToAmount(vat),
+		WithholdingRate:   withholdingRate,
+		WithholdingAmount: centsToAmount(withholding),
+		SellerName:        sellerName,
+		SellerTaxID:       os.Getenv("PLATFORM_TAX_ID"),
+		SellerAddress:     os.Getenv("PLATFORM_ADDRESS"),
+		GuideID:           booking.GuideID,
+		GuideName:         fullName(booking.Guide.User),
+		BuyerName:         buyerName,
+		BuyerTaxID:        booking.BillingTaxID,
+		BuyerAddress:      booking.BillingAddress,
+	}
+	if err := tx.Create(&invoice).Error; err != nil {
+		return nil, fmt.Errorf("failed to create invoice: %w", err)
+	}
+	return &invoice, nil
+}
+
+// createCreditNote ออกใบลดหนี้ของ refund ใช้อัตราภาษีและรายละเอียดคู่ค้าเดียวกับใบกำกับภาษีที่อ้างอิง
+func createCreditNote(tx *gorm.DB, invoice *models.Invoice, refund *models.PaymentRelease, now time.Time) (*models.Invoice, error) {
+	number, err := NextDocumentNumber(tx, "CN", now)
+	if err != nil {
+		return nil, err
+	}
+
+	issuedAt := now
+	if refund.ProcessedAt != nil {
+		issuedAt = *refund.ProcessedAt
+	}
+	totalCents := toCents(refund.Amount)
+	net, vat, withholding := invoiceAmounts(totalCents, invoice.VATRate, invoice.WithholdingRate)
+
+	note := *invoice
+	note.Model = gorm.Model{}
+	note.DocumentType = "credit_note"
+	note.Number = number
+	note.InvoiceID = &invoice.ID
+	note.PaymentReleaseID = &refund.ID
+	note.IssuedAt = issuedAt
+	note.Description = fmt.Sprintf("Refund for booking #%d (%s)", invoice.TripBookingID, refund.Reason)
+	note.Amount = centsToAmount(totalCents)
+	note.NetAmount = centsToAmount(net)
+	note.VATAmount = centsToAmount(vat)
+	note.WithholdingAmount = centsToAmount(withholding)
+	if err := tx.Create(&note).Error; err != nil {
+		return nil, fmt.Errorf("failed to create credit note: %w", err)
+	}
+	return &note, nil
+}
+
+// RenderInvoicePDF สร้าง PDF ของเอกสาร related คือใบลดหนี้ของใบกำกับภาษี หรือใบกำกับภาษีที่ใบลดหนี้อ้างอิง
+func RenderInvoicePDF(doc *models.Invoice, related []models.Invoice) []byte {
+	title := "TAX INVOICE / RECEIPT"
+	if doc.DocumentType == "credit_note" {
+		title = "CREDIT NOTE"
+	}
+
+	row := func(label string, amount float64) string {
+		return fmt.Sprintf("%-44s %14s THB", label, formatAmount(amount))
+	}
+
+	lines := []string{
+		title,
+		"",
+		"No.: " + doc.Number,
+		"Date: " + doc.IssuedAt.Format("2006-01-02"),
+		"",
+		"Seller: " + doc.SellerName,
+	}
+	if doc.SellerTaxID != "" {
+		lines = append(lines, "Tax ID: "+doc.SellerTaxID)
+	}
+	if doc.SellerAddress != "" {
+		lines = append(lines, "Address: "+doc.SellerAddress)
+	}
+	lines = append(lines, fmt.Sprintf("Service provided by guide #%d %s", doc.GuideID, doc.GuideName), "", "Buyer: "+doc.BuyerName)
+	if doc.BuyerTaxID != "" {
+		lines = append(lines, "Tax ID: "+doc.BuyerTaxID)
+	}
+	if doc.BuyerAddress != "" {
+		lines = append(lines, "Address: "+doc.BuyerAddress)
+	}
+
+	if doc.DocumentType == "credit_note" && len(related) > 0 {
+		lines = append(lines, "", fmt.Sprintf("Reference: tax invoice %s (%s THB)", related[0].Number, formatAmount(related[0].Amount)))
+	}
+
+	lines = append(lines, "", doc.Description, "")
+	if doc.VATRate > 0 {
+		lines = append(lines,
+			row("Amount before VAT", doc.NetAmount),
+			row(fmt.Sprintf("VAT %s%%", strconv.FormatFloat(doc.VATRate, 'f', -1, 64)), doc.VATAmount),
+		)
+	}
+	lines = append(lines, row("Total", doc.Amount))
+	if doc.WithholdingRate > 0 {
+		lines = append(lines,
+			row(fmt.Sprintf("Withholding tax %s%% (deducted by buyer)", strconv.FormatFloat(doc.WithholdingRate, 'f', -1, 64)), doc.WithholdingAmount),
+			row("Net after withholding", doc.Amount-doc.WithholdingAmount),
+		)
+	}
+
+	if doc.DocumentType == "invoice" {
+		lines = append(lines, "", "Paid in full by card. This document serves as a receipt.")
+		if len(related) > 0 {
+			lines = append(lines, "", "Credit notes:")
+			credited := int64(0)
+			for _, note := range related {
+				credited += toCents(note.Amount)
+				lines = append(lines, fmt.Sprintf("  %-18s %s  %14s THB", note.Number, note.IssuedAt.Format("2006-01-02"), formatAmount(-note.Amount)))
+			}
+			lines = append(lines, row("Net amount after credit notes", centsToAmount(toCents(doc.Amount)-credited)))
+		}
+	}
+
+	return RenderTextPDF(lines)
+}
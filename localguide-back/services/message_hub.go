@@ -0,0 +1,59 @@
+package services
+
+import "sync"
+
+// MessageEvent - event ที่ส่งให้ผู้ที่เปิด WebSocket ของห้องสนทนาอยู่
+type MessageEvent struct {
+	Type           string      `json:"type"` // message, read
+	ConversationID uint        `json:"conversation_id"`
+	Payload        interface{} `json:"payload"`
+}
+
+// MessageHub กระจาย event ของห้องสนทนาไปยัง subscriber ภายใน process นี้
+type MessageHub struct {
+	mu   sync.RWMutex
+	subs map[uint]map[chan MessageEvent]struct{}
+}
+
+func NewMessageHub() *MessageHub {
+	return &MessageHub{subs: make(map[uint]map[chan MessageEvent]struct{})}
+}
+
+// Subscribe รับ event ของห้อง คืน channel และฟังก์ชันสำหรับยกเลิก
+func (h *MessageHub) Subscribe(conversationID uint) (<-chan MessageEvent, func()) {
+	ch := make(chan MessageEvent, 16)
+
+	h.mu.Lock()
+	if h.subs[conversationID] == nil {
+		h.subs[conversationID] = make(map[chan MessageEvent]struct{})
+	}
+	h.subs[conversationID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.subs[conversationID], ch)
+			if len(h.subs[conversationID]) == 0 {
+				delete(h.subs, conversationID)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish ส่ง event ให้ทุก subscriber ของห้อง (subscriber ที่รับไม่ทันจะถูกข้าม ใช้ REST ดึงย้อนหลังได้)
+func (h *MessageHub) Publish(conversationID uint, event MessageEvent) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	event.ConversationID = conversationID
+	for ch := range h.subs[conversationID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
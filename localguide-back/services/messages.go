@@ -0,0 +1,557 @@
+package services
+
+// DefaultLanguage - ภาษาของ response เมื่อ client ไม่ได้ระบุ (?lang, ภาษาที่ผู้ใช้ตั้งไว้ หรือ Accept-Language)
+const DefaultLanguage = "en"
+
+// SupportedLanguages - ภาษาที่มีข้อความและคำแปลในระบบ
+var SupportedLanguages = []string{"en", "th"}
+
+// IsSupportedLanguage - มีข้อความของภาษานี้หรือไม่
+func IsSupportedLanguage(lang string) bool {
+	for _, l := range SupportedLanguages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// HasMessage - รหัสนี้อยู่ใน catalogue หรือไม่
+func HasMessage(code string) bool {
+	_, ok := messageCatalogue[code]
+	return ok
+}
+
+// Message - ข้อความของรหัสตามภาษา (ไม่มีภาษานั้นใช้ DefaultLanguage, ไม่รู้จักรหัสคืนค่าเดิม)
+func Message(code, lang string) string {
+	byLang, ok := messageCatalogue[code]
+	if !ok {
+		return code
+	}
+	if text, ok := byLang[lang]; ok {
+		return text
+	}
+	return byLang[DefaultLanguage]
+}
+
+// messageCatalogue - ข้อความ error ของ API ตามรหัส แยกตามภาษา (en, th)
+// handler ตอบ {"error": "<รหัส>"} แล้ว middleware.Localize แปลงเป็นข้อความตามภาษาของ request
+var messageCatalogue = map[string]map[string]string{
+	"access_log_get_failed":                   {"en": "Failed to get access log", "th": "ดึงประวัติการเข้าถึงไม่สำเร็จ"},
+	"admin_required":                          {"en": "Admin access required", "th": "ต้องเป็นผู้ดูแลระบบ"},
+	"already_checked_in":                      {"en": "You have already checked in for this day", "th": "คุณเช็กอินวันนี้ไปแล้ว"},
+	"already_guide":                           {"en": "You are already a guide", "th": "คุณเป็นไกด์อยู่แล้ว"},
+	"already_organiser":                       {"en": "You are already the organiser of this booking", "th": "คุณเป็นผู้จัดทริปของการจองนี้อยู่แล้ว"},
+	"already_participant":                     {"en": "You are already part of this booking", "th": "คุณอยู่ในการจองนี้แล้ว"},
+	"already_reviewed":                        {"en": "You have already reviewed this trip", "th": "คุณรีวิวทริปนี้ไปแล้ว"},
+	"assigned_to_invalid":                     {"en": "assigned_to must be me, unassigned or a user ID", "th": "assigned_to ต้องเป็น me, unassigned หรือรหัสผู้ใช้"},
+	"assignee_not_admin":                      {"en": "Assignee must be an admin", "th": "ผู้รับผิดชอบต้องเป็นผู้ดูแลระบบ"},
+	"attraction_categories_get_failed":        {"en": "Failed to retrieve attraction categories", "th": "ดึงประเภทสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"attraction_category_create_failed":       {"en": "Failed to create attraction category", "th": "เพิ่มประเภทสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"attraction_category_delete_failed":       {"en": "Failed to delete attraction category", "th": "ลบประเภทสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"attraction_category_id_invalid":          {"en": "Attraction category ID must be a positive integer", "th": "รหัสประเภทสถานที่ท่องเที่ยวต้องเป็นจำนวนเต็มบวก"},
+	"attraction_category_in_use":              {"en": "Attraction category is still in use; merge it into another entry instead", "th": "ประเภทนี้ยังถูกใช้งานอยู่ กรุณารวมเข้ากับรายการอื่นแทน"},
+	"attraction_category_merge_failed":        {"en": "Failed to merge attraction category", "th": "รวมประเภทสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"attraction_category_name_required":       {"en": "Attraction category name is required", "th": "กรุณาระบุชื่อประเภทสถานที่ท่องเที่ยว"},
+	"attraction_category_name_taken":          {"en": "An attraction category with this name already exists", "th": "มีประเภทสถานที่ท่องเที่ยวชื่อนี้อยู่แล้ว"},
+	"attraction_category_name_too_long":       {"en": "Attraction category name must be at most 100 characters", "th": "ชื่อประเภทสถานที่ท่องเที่ยวต้องยาวไม่เกิน 100 ตัวอักษร"},
+	"attraction_category_not_found":           {"en": "Attraction category not found", "th": "ไม่พบประเภทสถานที่ท่องเที่ยว"},
+	"attraction_category_update_failed":       {"en": "Failed to update attraction category", "th": "แก้ไขประเภทสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"attraction_export_failed":                {"en": "Failed to export tourist attractions", "th": "ส่งออกสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"attraction_id_invalid":                   {"en": "Attraction ID must be a positive integer", "th": "รหัสสถานที่ท่องเที่ยวต้องเป็นจำนวนเต็มบวก"},
+	"attraction_image_update_failed":          {"en": "Failed to update attraction image", "th": "เปลี่ยนรูปสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"attraction_import_failed":                {"en": "Failed to import tourist attractions", "th": "นำเข้าสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"attraction_name_taken":                   {"en": "An attraction with this name already exists in the province", "th": "มีสถานที่ท่องเที่ยวชื่อนี้ในจังหวัดนี้อยู่แล้ว"},
+	"attractions_get_failed":                  {"en": "Failed to retrieve attractions", "th": "ดึงสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"auth_user_create_failed":                 {"en": "Failed to create auth user", "th": "สร้างบัญชีผู้ใช้ไม่สำเร็จ"},
+	"auth_user_not_found":                     {"en": "AuthUser not found", "th": "ไม่พบบัญชีผู้ใช้"},
+	"authorization_header_required":           {"en": "Authorization header required", "th": "ต้องส่ง Authorization header"},
+	"avatar_clear_failed":                     {"en": "Failed to clear avatar field", "th": "ลบรูปโปรไฟล์ไม่สำเร็จ"},
+	"avatar_update_failed":                    {"en": "Failed to update user avatar", "th": "เปลี่ยนรูปโปรไฟล์ไม่สำเร็จ"},
+	"billing_update_failed":                   {"en": "Failed to update billing details", "th": "แก้ไขข้อมูลใบกำกับภาษีไม่สำเร็จ"},
+	"booking_create_failed":                   {"en": "Failed to create booking", "th": "สร้างการจองไม่สำเร็จ"},
+	"booking_get_failed":                      {"en": "Failed to get booking", "th": "ดึงข้อมูลการจองไม่สำเร็จ"},
+	"booking_id_invalid":                      {"en": "Booking ID must be a positive integer", "th": "รหัสการจองต้องเป็นจำนวนเต็มบวก"},
+	"booking_not_found":                       {"en": "Booking not found", "th": "ไม่พบการจอง"},
+	"booking_not_paid":                        {"en": "Booking has not been paid yet", "th": "การจองนี้ยังไม่ได้ชำระเงิน"},
+	"booking_not_paid_or_started":             {"en": "Booking must be paid and not yet started", "th": "การจองต้องชำระเงินแล้วและยังไม่เริ่มทริป"},
+	"booking_not_split":                       {"en": "Booking does not use split payment", "th": "การจองนี้ไม่ได้แบ่งจ่าย"},
+	"booking_payment_required_for_arrival":    {"en": "Booking must be paid before confirming guide arrival", "th": "ต้องชำระเงินก่อนยืนยันว่าไกด์มาถึง"},
+	"booking_status_update_failed":            {"en": "Failed to update booking status", "th": "อัปเดตสถานะการจองไม่สำเร็จ"},
+	"booking_update_failed":                   {"en": "Failed to update booking", "th": "แก้ไขการจองไม่สำเร็จ"},
+	"booking_view_own_only":                   {"en": "You can only view your own bookings", "th": "ดูได้เฉพาะการจองของตัวเอง"},
+	"bookings_fetch_failed":                   {"en": "Failed to fetch bookings", "th": "ดึงข้อมูลการจองไม่สำเร็จ"},
+	"bookings_get_failed":                     {"en": "Failed to get bookings", "th": "ดึงรายการจองไม่สำเร็จ"},
+	"case_notes_get_failed":                   {"en": "Failed to retrieve case notes", "th": "ดึงบันทึกเคสไม่สำเร็จ"},
+	"case_resolve_failed":                     {"en": "Failed to resolve case", "th": "ปิดเคสไม่สำเร็จ"},
+	"catalogue_merge_self":                    {"en": "Cannot merge an entry into itself", "th": "ไม่สามารถรวมรายการเข้ากับตัวเองได้"},
+	"category_id_required":                    {"en": "category_id is required", "th": "กรุณาระบุ category_id"},
+	"check_in_before_start":                   {"en": "Cannot check in before trip start date", "th": "ยังเช็กอินไม่ได้ก่อนวันเริ่มทริป"},
+	"check_in_failed":                         {"en": "Failed to check in", "th": "เช็กอินไม่สำเร็จ"},
+	"check_in_future_day":                     {"en": "Cannot check in for a future trip day", "th": "ยังเช็กอินวันที่ยังมาไม่ถึงไม่ได้"},
+	"check_in_get_failed":                     {"en": "Failed to get check-in", "th": "ดึงข้อมูลเช็กอินไม่สำเร็จ"},
+	"check_in_rate_limited":                   {"en": "Too many failed check-in attempts, please try again later", "th": "กรอกรหัสผิดหลายครั้งเกินไป กรุณาลองใหม่ภายหลัง"},
+	"check_in_record_failed":                  {"en": "Failed to record check-in", "th": "บันทึกการเช็กอินไม่สำเร็จ"},
+	"check_in_save_failed":                    {"en": "Failed to save check-in", "th": "บันทึกการเช็กอินไม่สำเร็จ"},
+	"check_ins_get_failed":                    {"en": "Failed to get check-ins", "th": "ดึงรายการเช็กอินไม่สำเร็จ"},
+	"city_too_long":                           {"en": "City must be at most 100 characters", "th": "เมืองต้องยาวไม่เกิน 100 ตัวอักษร"},
+	"company_billing_incomplete":              {"en": "Company billing requires name, tax ID and address", "th": "ใบกำกับภาษีนิติบุคคลต้องมีชื่อ เลขประจำตัวผู้เสียภาษี และที่อยู่"},
+	"conversation_create_failed":              {"en": "Failed to create conversation", "th": "สร้างบทสนทนาไม่สำเร็จ"},
+	"conversation_get_failed":                 {"en": "Failed to get conversation", "th": "ดึงบทสนทนาไม่สำเร็จ"},
+	"conversation_id_invalid":                 {"en": "Invalid conversation ID", "th": "รหัสบทสนทนาไม่ถูกต้อง"},
+	"conversation_not_found":                  {"en": "Conversation not found", "th": "ไม่พบบทสนทนา"},
+	"conversations_get_failed":                {"en": "Failed to get conversations", "th": "ดึงรายการบทสนทนาไม่สำเร็จ"},
+	"coordinates_incomplete":                  {"en": "Latitude and longitude must be sent together", "th": "ต้องส่งละติจูดและลองจิจูดพร้อมกัน"},
+	"coordinates_invalid":                     {"en": "Invalid coordinates", "th": "พิกัดไม่ถูกต้อง"},
+	"coordinates_not_paired":                  {"en": "latitude and longitude must be provided together", "th": "ต้องส่ง latitude และ longitude พร้อมกัน"},
+	"credentials_invalid":                     {"en": "Invalid credentials", "th": "อีเมลหรือรหัสผ่านไม่ถูกต้อง"},
+	"daily_payout_multi_day_only":             {"en": "Daily payout is only available for multi-day trips", "th": "การจ่ายเงินรายวันใช้ได้เฉพาะทริปหลายวัน"},
+	"day_outside_trip":                        {"en": "Day number is outside the trip window", "th": "วันที่ระบุอยู่นอกช่วงทริป"},
+	"description_empty":                       {"en": "Description cannot be empty", "th": "รายละเอียดต้องไม่ว่าง"},
+	"description_too_long":                    {"en": "Description must be at most 2000 characters", "th": "รายละเอียดต้องยาวไม่เกิน 2000 ตัวอักษร"},
+	"dispute_admin_only":                      {"en": "Only admin can resolve disputes", "th": "เฉพาะผู้ดูแลระบบเท่านั้นที่ตัดสินข้อพิพาทได้"},
+	"dispute_closed":                          {"en": "The dispute is not accepting statements", "th": "ข้อพิพาทนี้ปิดรับคำชี้แจงแล้ว"},
+	"dispute_deadline_passed":                 {"en": "The deadline to dispute this report has passed", "th": "เลยกำหนดเวลาโต้แย้งรายงานนี้แล้ว"},
+	"dispute_decision_invalid":                {"en": "Invalid decision. Must be 'guide_wins', 'user_wins', or 'split_cost'", "th": "ผลการตัดสินต้องเป็น 'guide_wins', 'user_wins' หรือ 'split_cost'"},
+	"dispute_decision_record_failed":          {"en": "Failed to record dispute decision", "th": "บันทึกผลการตัดสินข้อพิพาทไม่สำเร็จ"},
+	"dispute_own_only":                        {"en": "You can only dispute reports for your own bookings", "th": "โต้แย้งได้เฉพาะรายงานของการจองของตัวเอง"},
+	"dispute_reason_required":                 {"en": "Please describe why you dispute the no-show report", "th": "กรุณาอธิบายเหตุผลที่โต้แย้งรายงานการไม่มาตามนัด"},
+	"dispute_report_create_failed":            {"en": "Failed to create dispute report", "th": "สร้างรายงานข้อพิพาทไม่สำเร็จ"},
+	"district_too_long":                       {"en": "District must be at most 100 characters", "th": "อำเภอต้องยาวไม่เกิน 100 ตัวอักษร"},
+	"document_not_found":                      {"en": "Document not found", "th": "ไม่พบเอกสาร"},
+	"early_completion_already_agreed":         {"en": "Early completion already agreed", "th": "ตกลงจบทริปก่อนกำหนดไปแล้ว"},
+	"early_completion_needs_agreement":        {"en": "The other party must agree to your early completion request", "th": "อีกฝ่ายต้องยืนยันคำขอจบทริปก่อนกำหนดของคุณ"},
+	"early_completion_not_needed":             {"en": "Trip end date has arrived, early completion is not needed", "th": "ถึงวันสิ้นสุดทริปแล้ว ไม่จำเป็นต้องขอจบก่อนกำหนด"},
+	"earnings_get_failed":                     {"en": "Failed to get earnings", "th": "ดึงข้อมูลรายได้ไม่สำเร็จ"},
+	"email_already_invited":                   {"en": "This email has already been invited", "th": "อีเมลนี้ได้รับคำเชิญแล้ว"},
+	"email_invalid":                           {"en": "Invalid email format", "th": "รูปแบบอีเมลไม่ถูกต้อง"},
+	"email_required":                          {"en": "A valid email is required", "th": "กรุณาระบุอีเมลที่ถูกต้อง"},
+	"email_send_failed":                       {"en": "Failed to send email", "th": "ส่งอีเมลไม่สำเร็จ"},
+	"email_taken":                             {"en": "Email already exists", "th": "อีเมลนี้ถูกใช้แล้ว"},
+	"end_before_start":                        {"en": "End date must be after start date", "th": "วันสิ้นสุดต้องอยู่หลังวันเริ่มต้น"},
+	"end_date_format_invalid":                 {"en": "Invalid end_date format (use DD/MM/YYYY)", "th": "รูปแบบ end_date ไม่ถูกต้อง (ใช้ DD/MM/YYYY)"},
+	"evidence_too_many":                       {"en": "A report can have at most 10 evidence files", "th": "รายงานมีหลักฐานได้ไม่เกิน 10 ไฟล์"},
+	"evidence_url_invalid":                    {"en": "Evidence must be uploaded files or http(s) URLs", "th": "หลักฐานต้องเป็นไฟล์ที่อัปโหลดหรือ URL แบบ http(s)"},
+	"evidence_url_forbidden":                  {"en": "Evidence files must be uploaded by you or already attached to this booking", "th": "ไฟล์หลักฐานต้องอัปโหลดโดยคุณหรือแนบไว้แล้วใน booking นี้"},
+	"expires_at_format_invalid":               {"en": "Invalid expires_at format (use DD/MM/YYYY)", "th": "รูปแบบ expires_at ไม่ถูกต้อง (ใช้ DD/MM/YYYY)"},
+	"export_format_invalid":                   {"en": "Format must be csv or geojson", "th": "รูปแบบไฟล์ต้องเป็น csv หรือ geojson"},
+	"file_access_check_failed":                {"en": "Failed to check file access", "th": "ตรวจสิทธิ์เข้าถึงไฟล์ไม่สำเร็จ"},
+	"file_access_denied":                      {"en": "You do not have access to this file", "th": "คุณไม่มีสิทธิ์เข้าถึงไฟล์นี้"},
+	"file_link_invalid":                       {"en": "Invalid or expired file link", "th": "ลิงก์ไฟล์ไม่ถูกต้องหรือหมดอายุ"},
+	"file_not_found":                          {"en": "File not found", "th": "ไม่พบไฟล์"},
+	"file_read_failed":                        {"en": "Failed to read file", "th": "อ่านไฟล์ไม่สำเร็จ"},
+	"file_required":                           {"en": "file is required", "th": "กรุณาแนบไฟล์"},
+	"file_save_failed":                        {"en": "Failed to save file", "th": "บันทึกไฟล์ไม่สำเร็จ"},
+	"file_upload_missing":                     {"en": "No file uploaded", "th": "ไม่พบไฟล์ที่อัปโหลด"},
+	"financial_report_failed":                 {"en": "Failed to build financial report", "th": "สร้างรายงานการเงินไม่สำเร็จ"},
+	"financial_summary_failed":                {"en": "Failed to build financial summary", "th": "สร้างสรุปการเงินไม่สำเร็จ"},
+	"flag_reason_invalid":                     {"en": "Reason must be one of spam, offensive, fake, personal_info, off_topic, other", "th": "เหตุผลต้องเป็น spam, offensive, fake, personal_info, off_topic หรือ other"},
+	"frame_invalid":                           {"en": "Invalid frame", "th": "ข้อมูลที่ส่งผ่าน WebSocket ไม่ถูกต้อง"},
+	"from_date_invalid":                       {"en": "from must be YYYY-MM-DD", "th": "from ต้องอยู่ในรูปแบบ YYYY-MM-DD"},
+	"group_by_invalid":                        {"en": "group_by must be province or guide", "th": "group_by ต้องเป็น province หรือ guide"},
+	"group_size_limit_reached":                {"en": "Group size limit reached", "th": "จำนวนผู้ร่วมทริปเต็มแล้ว"},
+	"guide_application_create_failed":         {"en": "Failed to create guide application", "th": "ส่งใบสมัครไกด์ไม่สำเร็จ"},
+	"guide_application_pending":               {"en": "You already have a pending guide application", "th": "คุณมีใบสมัครไกด์ที่รอพิจารณาอยู่แล้ว"},
+	"guide_attraction_link_failed":            {"en": "Failed to associate guide with tourist attraction", "th": "บันทึกสถานที่ท่องเที่ยวของไกด์ไม่สำเร็จ"},
+	"guide_certification_create_failed":       {"en": "Failed to create guide certification", "th": "บันทึกใบอนุญาตไกด์ไม่สำเร็จ"},
+	"guide_create_failed":                     {"en": "Failed to create guide", "th": "สร้างโปรไฟล์ไกด์ไม่สำเร็จ"},
+	"guide_exists":                            {"en": "Guide already exists", "th": "มีโปรไฟล์ไกด์อยู่แล้ว"},
+	"guide_fields_required":                   {"en": "Missing required fields (bio, description, provinceId, languageIds, certificationNumber)", "th": "กรุณากรอกข้อมูลให้ครบ (bio, description, provinceId, languageIds, certificationNumber)"},
+	"guide_id_invalid":                        {"en": "Invalid guide ID", "th": "รหัสไกด์ไม่ถูกต้อง"},
+	"guide_language_link_failed":              {"en": "Failed to associate guide with language", "th": "บันทึกภาษาของไกด์ไม่สำเร็จ"},
+	"guide_no_show_before_start":              {"en": "Cannot report guide no-show before trip start date", "th": "รายงานไกด์ไม่มาตามนัดก่อนวันเริ่มทริปไม่ได้"},
+	"guide_no_show_own_only":                  {"en": "You can only report guide no-show for your own bookings", "th": "รายงานไกด์ไม่มาตามนัดได้เฉพาะการจองของตัวเอง"},
+	"guide_no_show_report_create_failed":      {"en": "Failed to create guide no-show report", "th": "สร้างรายงานไกด์ไม่มาตามนัดไม่สำเร็จ"},
+	"guide_no_show_report_failed":             {"en": "Failed to report guide no-show", "th": "รายงานไกด์ไม่มาตามนัดไม่สำเร็จ"},
+	"guide_no_show_status_invalid":            {"en": "Invalid booking status for guide no-show report", "th": "สถานะการจองไม่สามารถรายงานไกด์ไม่มาตามนัดได้"},
+	"guide_not_found":                         {"en": "Guide not found", "th": "ไม่พบไกด์"},
+	"guide_notify_failed":                     {"en": "Failed to notify guide", "th": "แจ้งเตือนไกด์ไม่สำเร็จ"},
+	"guide_only_browse_trips":                 {"en": "Only guides can browse trip requirements", "th": "เฉพาะไกด์เท่านั้นที่ดูโพสต์ทริปได้"},
+	"guide_only_check_in":                     {"en": "Only the guide of this booking can check in", "th": "เฉพาะไกด์ของการจองนี้เท่านั้นที่เช็กอินได้"},
+	"guide_only_create_offers":                {"en": "Only guides can create offers", "th": "เฉพาะไกด์เท่านั้นที่สร้างข้อเสนอได้"},
+	"guide_only_manage_offers":                {"en": "Only guides can manage offers", "th": "เฉพาะไกด์เท่านั้นที่จัดการข้อเสนอได้"},
+	"guide_only_respond_reviews":              {"en": "Only guides can respond to reviews", "th": "เฉพาะไกด์เท่านั้นที่ตอบรีวิวได้"},
+	"guide_only_view_offers":                  {"en": "Only guides can view their offers", "th": "เฉพาะไกด์เท่านั้นที่ดูข้อเสนอของตนได้"},
+	"guide_payment_release_create_failed":     {"en": "Failed to create guide payment release", "th": "สร้างรายการจ่ายเงินให้ไกด์ไม่สำเร็จ"},
+	"guide_percent_invalid":                   {"en": "guide_percent must be between 0 and 100", "th": "guide_percent ต้องอยู่ระหว่าง 0 ถึง 100"},
+	"guide_profile_not_found":                 {"en": "Guide profile not found", "th": "ไม่พบโปรไฟล์ไกด์"},
+	"guide_required":                          {"en": "Guide access required", "th": "ต้องเป็นไกด์"},
+	"guide_sort_invalid":                      {"en": "Sort must be score, rating, reviews or newest", "th": "การเรียงลำดับต้องเป็น score, rating, reviews หรือ newest"},
+	"guide_status_update_failed":              {"en": "Failed to update guide status", "th": "อัปเดตสถานะไกด์ไม่สำเร็จ"},
+	"guide_update_failed":                     {"en": "Failed to update guide", "th": "แก้ไขโปรไฟล์ไกด์ไม่สำเร็จ"},
+	"guide_verification_not_found":            {"en": "Guide verification not found", "th": "ไม่พบใบสมัครไกด์"},
+	"guides_get_failed":                       {"en": "Failed to retrieve guides", "th": "ดึงรายชื่อไกด์ไม่สำเร็จ"},
+	"import_has_invalid_rows":                 {"en": "Fix the invalid rows before applying the import", "th": "กรุณาแก้แถวที่ไม่ถูกต้องก่อนนำเข้า"},
+	"invalid_request":                         {"en": "Invalid request", "th": "คำขอไม่ถูกต้อง"},
+	"invalid_request_body":                    {"en": "Invalid request body", "th": "ข้อมูลที่ส่งมาไม่ถูกต้อง"},
+	"invalid_request_data":                    {"en": "Invalid request data", "th": "ข้อมูลที่ส่งมาไม่ถูกต้อง"},
+	"invitation_already_answered":             {"en": "Invitation has already been answered", "th": "ตอบรับคำเชิญนี้ไปแล้ว"},
+	"invitation_email_mismatch":               {"en": "This invitation was sent to a different email address", "th": "คำเชิญนี้ส่งถึงอีเมลอื่น"},
+	"invitation_not_found":                    {"en": "Invitation not found", "th": "ไม่พบคำเชิญ"},
+	"invitation_update_failed":                {"en": "Failed to update invitation", "th": "อัปเดตคำเชิญไม่สำเร็จ"},
+	"invitations_get_failed":                  {"en": "Failed to get invitations", "th": "ดึงคำเชิญไม่สำเร็จ"},
+	"invoice_already_issued":                  {"en": "Invoice has already been issued for this booking", "th": "ออกใบกำกับภาษีสำหรับการจองนี้แล้ว"},
+	"invoice_issue_failed":                    {"en": "Failed to issue invoice", "th": "ออกใบกำกับภาษีไม่สำเร็จ"},
+	"issue_not_open":                          {"en": "Only open issues can be ignored", "th": "ละเว้นได้เฉพาะรายการที่ยังเปิดอยู่"},
+	"issue_not_resyncable":                    {"en": "Issue type cannot be resynced", "th": "รายการประเภทนี้ซิงก์ใหม่ไม่ได้"},
+	"issue_not_resyncable_open":               {"en": "Only open issues marked resyncable can be resynced", "th": "ซิงก์ใหม่ได้เฉพาะรายการที่เปิดอยู่และซิงก์ได้"},
+	"issue_update_failed":                     {"en": "Failed to update issue", "th": "อัปเดตรายการไม่สำเร็จ"},
+	"itinerary_too_many_stops":                {"en": "An itinerary can have at most 50 stops", "th": "กำหนดการมีจุดแวะได้ไม่เกิน 50 จุด"},
+	"language_create_failed":                  {"en": "Failed to create language", "th": "เพิ่มภาษาไม่สำเร็จ"},
+	"language_delete_failed":                  {"en": "Failed to delete language", "th": "ลบภาษาไม่สำเร็จ"},
+	"language_id_invalid":                     {"en": "Language ID must be a positive integer", "th": "รหัสภาษาต้องเป็นจำนวนเต็มบวก"},
+	"language_in_use":                         {"en": "Language is still in use; merge it into another entry instead", "th": "ภาษานี้ยังถูกใช้งานอยู่ กรุณารวมเข้ากับรายการอื่นแทน"},
+	"language_invalid":                        {"en": "Language must be 'th' or 'en'", "th": "ภาษาต้องเป็น 'th' หรือ 'en'"},
+	"language_merge_failed":                   {"en": "Failed to merge language", "th": "รวมภาษาไม่สำเร็จ"},
+	"language_name_required":                  {"en": "Language name is required", "th": "กรุณาระบุชื่อภาษา"},
+	"language_name_taken":                     {"en": "A language with this name already exists", "th": "มีภาษาชื่อนี้อยู่แล้ว"},
+	"language_name_too_long":                  {"en": "Language name must be at most 100 characters", "th": "ชื่อภาษาต้องยาวไม่เกิน 100 ตัวอักษร"},
+	"language_not_found":                      {"en": "Language not found", "th": "ไม่พบภาษา"},
+	"language_update_failed":                  {"en": "Failed to update language", "th": "แก้ไขภาษาไม่สำเร็จ"},
+	"languages_get_failed":                    {"en": "Failed to retrieve languages", "th": "ดึงรายการภาษาไม่สำเร็จ"},
+	"licence_document_invalid":                {"en": "Licence document must be a file you uploaded with purpose licence_document", "th": "สำเนาใบอนุญาตต้องเป็นไฟล์ที่คุณอัปโหลดด้วย purpose licence_document"},
+	"limit_invalid":                           {"en": "limit must be between 1 and 200", "th": "limit ต้องอยู่ระหว่าง 1 ถึง 200"},
+	"meeting_point_locked":                    {"en": "Meeting point can only be changed before the trip starts", "th": "เปลี่ยนจุดนัดพบได้เฉพาะก่อนเริ่มทริป"},
+	"meeting_point_too_far":                   {"en": "You are too far from the meeting point", "th": "คุณอยู่ห่างจากจุดนัดพบเกินไป"},
+	"meeting_point_update_failed":             {"en": "Failed to update meeting point", "th": "แก้ไขจุดนัดพบไม่สำเร็จ"},
+	"message_attachment_not_uploaded":         {"en": "Attachments must be uploaded through /uploads first", "th": "ต้องอัปโหลดไฟล์แนบผ่าน /uploads ก่อน"},
+	"message_attachment_forbidden":            {"en": "Attachments must be uploaded by you or already shared in this conversation", "th": "ไฟล์แนบต้องอัปโหลดโดยคุณหรือเคยแนบไว้แล้วในห้องนี้"},
+	"message_empty":                           {"en": "Message must have a body or an attachment", "th": "ข้อความต้องมีเนื้อหาหรือไฟล์แนบ"},
+	"message_send_failed":                     {"en": "Failed to send message", "th": "ส่งข้อความไม่สำเร็จ"},
+	"message_too_long":                        {"en": "Message is too long", "th": "ข้อความยาวเกินไป"},
+	"message_too_many_attachments":            {"en": "Too many attachments", "th": "ไฟล์แนบมากเกินไป"},
+	"messages_get_failed":                     {"en": "Failed to get messages", "th": "ดึงข้อความไม่สำเร็จ"},
+	"messages_mark_read_failed":               {"en": "Failed to mark messages as read", "th": "ทำเครื่องหมายว่าอ่านแล้วไม่สำเร็จ"},
+	"month_invalid":                           {"en": "Month must be YYYY-MM", "th": "เดือนต้องอยู่ในรูปแบบ YYYY-MM"},
+	"name_required":                           {"en": "First name and last name are required", "th": "กรุณาระบุชื่อและนามสกุล"},
+	"near_coordinates_required":               {"en": "Latitude and longitude are required", "th": "กรุณาระบุละติจูดและลองจิจูด"},
+	"near_radius_out_of_range":                {"en": "Radius must be between 0 and 200 km", "th": "รัศมีต้องมากกว่า 0 และไม่เกิน 200 กม."},
+	"no_active_dispute":                       {"en": "No active dispute to resolve", "th": "ไม่มีข้อพิพาทที่รอการตัดสิน"},
+	"no_active_no_show_report":                {"en": "No active no-show report to dispute", "th": "ไม่มีรายงานการไม่มาตามนัดที่โต้แย้งได้"},
+	"no_avatar":                               {"en": "No avatar to delete", "th": "ไม่มีรูปโปรไฟล์ให้ลบ"},
+	"no_early_completion_request":             {"en": "No early completion request to agree to", "th": "ไม่มีคำขอจบทริปก่อนกำหนดให้ยืนยัน"},
+	"no_outstanding_share":                    {"en": "You have no outstanding share", "th": "คุณไม่มียอดค้างชำระ"},
+	"no_show_already_handled":                 {"en": "No-show already handled", "th": "จัดการกรณีไม่มาตามนัดไปแล้ว"},
+	"no_show_before_start":                    {"en": "Cannot report no-show before trip start date", "th": "รายงานการไม่มาตามนัดก่อนวันเริ่มทริปไม่ได้"},
+	"no_show_confirm_before_start":            {"en": "Cannot confirm no-show before trip start date", "th": "ยืนยันการไม่มาตามนัดก่อนวันเริ่มทริปไม่ได้"},
+	"no_show_confirm_failed":                  {"en": "Failed to confirm no-show", "th": "ยืนยันการไม่มาตามนัดไม่สำเร็จ"},
+	"no_show_confirm_own_only":                {"en": "You can only confirm no-show for your own bookings", "th": "ยืนยันการไม่มาตามนัดได้เฉพาะการจองของตัวเอง"},
+	"no_show_confirm_status_invalid":          {"en": "Invalid booking status for no-show confirmation", "th": "สถานะการจองไม่สามารถยืนยันการไม่มาตามนัดได้"},
+	"no_show_dispute_not_found":               {"en": "No no-show dispute for this booking", "th": "ไม่มีข้อพิพาทการไม่มาตามนัดของการจองนี้"},
+	"no_show_own_only":                        {"en": "You can only report no-show for your own bookings", "th": "รายงานการไม่มาตามนัดได้เฉพาะการจองของตัวเอง"},
+	"no_show_payment_failed":                  {"en": "Failed to process no-show payment", "th": "จัดการการชำระเงินกรณีไม่มาตามนัดไม่สำเร็จ"},
+	"no_show_report_create_failed":            {"en": "Failed to create no-show report", "th": "สร้างรายงานการไม่มาตามนัดไม่สำเร็จ"},
+	"no_show_status_invalid":                  {"en": "Invalid booking status for no-show report", "th": "สถานะการจองไม่สามารถรายงานการไม่มาตามนัดได้"},
+	"not_in_conversation":                     {"en": "You are not part of this conversation", "th": "คุณไม่ได้อยู่ในบทสนทนานี้"},
+	"not_participant":                         {"en": "You are not a participant of this booking", "th": "คุณไม่ได้อยู่ในการจองนี้"},
+	"note_add_failed":                         {"en": "Failed to add note", "th": "เพิ่มบันทึกไม่สำเร็จ"},
+	"note_length_invalid":                     {"en": "Note must be between 1 and 5000 characters", "th": "บันทึกต้องมีความยาว 1 ถึง 5000 ตัวอักษร"},
+	"nothing_to_pay":                          {"en": "Nothing left to pay", "th": "ไม่มียอดค้างชำระ"},
+	"notification_count_failed":               {"en": "Failed to count notifications", "th": "นับการแจ้งเตือนไม่สำเร็จ"},
+	"notification_id_invalid":                 {"en": "Invalid notification ID", "th": "รหัสการแจ้งเตือนไม่ถูกต้อง"},
+	"notification_not_found":                  {"en": "Notification not found", "th": "ไม่พบการแจ้งเตือน"},
+	"notification_update_failed":              {"en": "Failed to update notification", "th": "อัปเดตการแจ้งเตือนไม่สำเร็จ"},
+	"notifications_get_failed":                {"en": "Failed to get notifications", "th": "ดึงการแจ้งเตือนไม่สำเร็จ"},
+	"notifications_update_failed":             {"en": "Failed to update notifications", "th": "อัปเดตการแจ้งเตือนไม่สำเร็จ"},
+	"oauth_code_missing":                      {"en": "No code provided", "th": "ไม่ได้รับ code จากผู้ให้บริการเข้าสู่ระบบ"},
+	"offer_accept_failed":                     {"en": "Failed to accept offer", "th": "ยอมรับข้อเสนอไม่สำเร็จ"},
+	"offer_accept_own_trip_only":              {"en": "You can only accept offers for your own trip requirements", "th": "ยอมรับข้อเสนอได้เฉพาะโพสต์ทริปของตัวเอง"},
+	"offer_acceptance_failed":                 {"en": "Failed to complete offer acceptance", "th": "ยอมรับข้อเสนอไม่สำเร็จ"},
+	"offer_already_made":                      {"en": "You have already made an offer for this trip requirement", "th": "คุณส่งข้อเสนอให้โพสต์ทริปนี้ไปแล้ว"},
+	"offer_count_failed":                      {"en": "Failed to count remaining offers", "th": "นับข้อเสนอที่เหลือไม่สำเร็จ"},
+	"offer_create_failed":                     {"en": "Failed to create offer", "th": "สร้างข้อเสนอไม่สำเร็จ"},
+	"offer_from_template_failed":              {"en": "Failed to create offer from template", "th": "สร้างข้อเสนอจากเทมเพลตไม่สำเร็จ"},
+	"offer_get_failed":                        {"en": "Failed to get offer", "th": "ดึงข้อเสนอไม่สำเร็จ"},
+	"offer_id_invalid":                        {"en": "Invalid offer ID", "th": "รหัสข้อเสนอไม่ถูกต้อง"},
+	"offer_id_not_positive":                   {"en": "Offer ID must be greater than 0", "th": "รหัสข้อเสนอต้องมากกว่า 0"},
+	"offer_manage_own_only":                   {"en": "You can only manage your own offers", "th": "จัดการได้เฉพาะข้อเสนอของตัวเอง"},
+	"offer_not_available":                     {"en": "Offer is no longer available for acceptance", "th": "ข้อเสนอนี้ไม่สามารถยอมรับได้แล้ว"},
+	"offer_not_draft":                         {"en": "Only draft offers can be sent", "th": "ส่งได้เฉพาะข้อเสนอที่เป็นร่าง"},
+	"offer_not_editable":                      {"en": "Offer can only be edited while in draft, sent or negotiating status", "th": "แก้ไขข้อเสนอได้เฉพาะสถานะ draft, sent หรือ negotiating"},
+	"offer_not_found":                         {"en": "Offer not found", "th": "ไม่พบข้อเสนอ"},
+	"offer_not_pending":                       {"en": "Only pending offers can be rejected", "th": "ปฏิเสธได้เฉพาะข้อเสนอที่รอพิจารณา"},
+	"offer_not_withdrawable":                  {"en": "Only draft, sent or negotiating offers can be withdrawn", "th": "ถอนได้เฉพาะข้อเสนอสถานะ draft, sent หรือ negotiating"},
+	"offer_or_booking_required":               {"en": "Provide either trip_offer_id or trip_booking_id", "th": "กรุณาระบุ trip_offer_id หรือ trip_booking_id"},
+	"offer_reject_failed":                     {"en": "Failed to reject offer", "th": "ปฏิเสธข้อเสนอไม่สำเร็จ"},
+	"offer_reject_own_trip_only":              {"en": "You can only reject offers for your own trip requirements", "th": "ปฏิเสธข้อเสนอได้เฉพาะโพสต์ทริปของตัวเอง"},
+	"offer_send_failed":                       {"en": "Failed to send offer", "th": "ส่งข้อเสนอไม่สำเร็จ"},
+	"offer_stops_update_failed":               {"en": "Failed to update offer stops", "th": "แก้ไขจุดแวะของข้อเสนอไม่สำเร็จ"},
+	"offer_update_failed":                     {"en": "Failed to update offer", "th": "แก้ไขข้อเสนอไม่สำเร็จ"},
+	"offer_withdraw_failed":                   {"en": "Failed to withdraw offer", "th": "ถอนข้อเสนอไม่สำเร็จ"},
+	"offers_get_failed":                       {"en": "Failed to get offers", "th": "ดึงรายการข้อเสนอไม่สำเร็จ"},
+	"organiser_not_removable":                 {"en": "The organiser cannot be removed", "th": "นำผู้จัดทริปออกไม่ได้"},
+	"organiser_notify_failed":                 {"en": "Failed to notify organiser", "th": "แจ้งเตือนผู้จัดทริปไม่สำเร็จ"},
+	"organiser_only":                          {"en": "Only the organiser can manage this booking", "th": "เฉพาะผู้จัดทริปเท่านั้นที่จัดการการจองนี้ได้"},
+	"organiser_only_cover_shares":             {"en": "Only the organiser can cover the remaining shares", "th": "เฉพาะผู้จัดทริปเท่านั้นที่จ่ายส่วนที่เหลือแทนได้"},
+	"organiser_participant_create_failed":     {"en": "Failed to create organiser participant", "th": "เพิ่มผู้จัดทริปไม่สำเร็จ"},
+	"organiser_share_already_paid":            {"en": "The organiser has already paid their share and cannot take over another share", "th": "ผู้จัดทริปจ่ายส่วนแบ่งของตัวเองแล้ว รับส่วนแบ่งเพิ่มไม่ได้"},
+	"other_offers_get_failed":                 {"en": "Failed to get other offers", "th": "ดึงข้อเสนออื่นไม่สำเร็จ"},
+	"other_offers_reject_failed":              {"en": "Failed to reject other offers", "th": "ปฏิเสธข้อเสนออื่นไม่สำเร็จ"},
+	"other_party_notify_failed":               {"en": "Failed to notify the other party", "th": "แจ้งเตือนอีกฝ่ายไม่สำเร็จ"},
+	"outbox_entry_not_failed":                 {"en": "Only failed entries can be retried", "th": "ลองใหม่ได้เฉพาะรายการที่ล้มเหลว"},
+	"outbox_entry_not_found":                  {"en": "Outbox entry not found", "th": "ไม่พบรายการในคิว"},
+	"outbox_retry_failed":                     {"en": "Failed to retry outbox entry", "th": "ลองคำสั่งในคิวใหม่ไม่สำเร็จ"},
+	"outcome_invalid":                         {"en": "Outcome must be none, refund, release or split", "th": "ผลลัพธ์ต้องเป็น none, refund, release หรือ split"},
+	"own_data_only":                           {"en": "Access denied: You can only access your own data", "th": "ไม่มีสิทธิ์เข้าถึง: ดูได้เฉพาะข้อมูลของตัวเอง"},
+	"participant_id_invalid":                  {"en": "Invalid participant ID", "th": "รหัสผู้ร่วมทริปไม่ถูกต้อง"},
+	"participant_invite_closed":               {"en": "Participants can only be invited before the trip starts", "th": "เชิญผู้ร่วมทริปได้เฉพาะก่อนเริ่มทริป"},
+	"participant_invite_failed":               {"en": "Failed to invite participant", "th": "เชิญผู้ร่วมทริปไม่สำเร็จ"},
+	"participant_not_found":                   {"en": "Participant not found", "th": "ไม่พบผู้ร่วมทริป"},
+	"participant_notify_failed":               {"en": "Failed to notify participant", "th": "แจ้งเตือนผู้ร่วมทริปไม่สำเร็จ"},
+	"participant_remove_failed":               {"en": "Failed to remove participant", "th": "นำผู้ร่วมทริปออกไม่สำเร็จ"},
+	"participant_share_exists":                {"en": "Participant already has a payment share", "th": "ผู้ร่วมทริปคนนี้มีส่วนแบ่งการจ่ายแล้ว"},
+	"participants_get_failed":                 {"en": "Failed to get participants", "th": "ดึงรายชื่อผู้ร่วมทริปไม่สำเร็จ"},
+	"password_hash_failed":                    {"en": "Password hash failed", "th": "เข้ารหัสรหัสผ่านไม่สำเร็จ"},
+	"password_too_short":                      {"en": "Password must be at least 8 characters", "th": "รหัสผ่านต้องมีอย่างน้อย 8 ตัวอักษร"},
+	"password_update_failed":                  {"en": "Failed to update password", "th": "เปลี่ยนรหัสผ่านไม่สำเร็จ"},
+	"pay_your_share":                          {"en": "This booking uses split payment, pay your share instead", "th": "การจองนี้แบ่งจ่าย กรุณาชำระส่วนของคุณแทน"},
+	"payment_already_completed":               {"en": "Payment already completed", "th": "ชำระเงินเรียบร้อยแล้ว"},
+	"payment_already_refunded":                {"en": "Payment already refunded or partially refunded", "th": "มีการคืนเงินแล้วทั้งหมดหรือบางส่วน"},
+	"payment_already_started":                 {"en": "Payment has already been started for this booking", "th": "เริ่มชำระเงินสำหรับการจองนี้แล้ว"},
+	"payment_failure_process_failed":          {"en": "Failed to process payment failure", "th": "จัดการการชำระเงินที่ล้มเหลวไม่สำเร็จ"},
+	"payment_fully_refunded":                  {"en": "Payment already fully refunded", "th": "คืนเงินครบแล้ว"},
+	"payment_get_failed":                      {"en": "Failed to get payment", "th": "ดึงข้อมูลการชำระเงินไม่สำเร็จ"},
+	"payment_intent_create_failed":            {"en": "Failed to create payment intent", "th": "สร้างรายการชำระเงินไม่สำเร็จ"},
+	"payment_intent_parse_failed":             {"en": "Failed to parse payment intent", "th": "อ่านข้อมูล payment intent ไม่สำเร็จ"},
+	"payment_intent_save_failed":              {"en": "Failed to save payment intent", "th": "บันทึก payment intent ไม่สำเร็จ"},
+	"payment_not_completed":                   {"en": "Payment not completed", "th": "การชำระเงินยังไม่สำเร็จ"},
+	"payment_not_found":                       {"en": "Payment not found", "th": "ไม่พบการชำระเงิน"},
+	"payment_not_succeeded":                   {"en": "Payment has not succeeded on Stripe", "th": "การชำระเงินบน Stripe ยังไม่สำเร็จ"},
+	"payment_outbox_get_failed":               {"en": "Failed to retrieve payment outbox", "th": "ดึงคิวคำสั่งชำระเงินไม่สำเร็จ"},
+	"payment_recheck_failed":                  {"en": "Failed to re-check payment", "th": "ตรวจสอบการชำระเงินซ้ำไม่สำเร็จ"},
+	"payment_record_create_failed":            {"en": "Failed to create payment record", "th": "สร้างรายการชำระเงินไม่สำเร็จ"},
+	"payment_record_not_found":                {"en": "Payment record not found", "th": "ไม่พบรายการชำระเงิน"},
+	"payment_release_create_failed":           {"en": "Failed to create payment release", "th": "สร้างรายการจ่ายเงินไม่สำเร็จ"},
+	"payment_settle_failed":                   {"en": "Cannot settle payment", "th": "ไม่สามารถจัดการยอดเงินได้"},
+	"payment_status_update_failed":            {"en": "Failed to update payment status", "th": "อัปเดตสถานะการชำระเงินไม่สำเร็จ"},
+	"payment_success_process_failed":          {"en": "Failed to process payment success", "th": "จัดการการชำระเงินที่สำเร็จไม่สำเร็จ"},
+	"payment_update_failed":                   {"en": "Failed to update payment", "th": "อัปเดตการชำระเงินไม่สำเร็จ"},
+	"payments_get_failed":                     {"en": "Failed to retrieve payments", "th": "ดึงรายการชำระเงินไม่สำเร็จ"},
+	"payout_freeze_unchanged":                 {"en": "Payout freeze is already in this state", "th": "สถานะการระงับจ่ายเงินเป็นแบบนี้อยู่แล้ว"},
+	"payout_freeze_update_failed":             {"en": "Failed to update payout freeze", "th": "อัปเดตการระงับจ่ายเงินไม่สำเร็จ"},
+	"period_invalid":                          {"en": "period must be day, week or month", "th": "period ต้องเป็น day, week หรือ month"},
+	"phone_invalid":                           {"en": "Invalid phone number", "th": "เบอร์โทรศัพท์ไม่ถูกต้อง"},
+	"preference_invalid":                      {"en": "Each preference needs a type and a channel of 'in_app' or 'email'", "th": "การตั้งค่าแต่ละรายการต้องมีประเภทและช่องทาง 'in_app' หรือ 'email'"},
+	"preferences_get_failed":                  {"en": "Failed to get preferences", "th": "ดึงการตั้งค่าไม่สำเร็จ"},
+	"preferences_update_failed":               {"en": "Failed to update preferences", "th": "บันทึกการตั้งค่าไม่สำเร็จ"},
+	"price_out_of_range":                      {"en": "Price is outside the requested range", "th": "ราคาอยู่นอกช่วงที่ลูกค้ากำหนด"},
+	"price_range_invalid":                     {"en": "Max price must be greater than or equal to min price", "th": "ราคาสูงสุดต้องไม่น้อยกว่าราคาต่ำสุด"},
+	"profile_update_failed":                   {"en": "Failed to update profile", "th": "แก้ไขโปรไฟล์ไม่สำเร็จ"},
+	"province_create_failed":                  {"en": "Failed to create province", "th": "เพิ่มจังหวัดไม่สำเร็จ"},
+	"province_delete_failed":                  {"en": "Failed to delete province", "th": "ลบจังหวัดไม่สำเร็จ"},
+	"province_get_failed":                     {"en": "Failed to retrieve province", "th": "ดึงข้อมูลจังหวัดไม่สำเร็จ"},
+	"province_has_no_attractions":             {"en": "No attractions found for this province", "th": "ไม่พบสถานที่ท่องเที่ยวในจังหวัดนี้"},
+	"province_id_invalid":                     {"en": "Invalid province ID", "th": "รหัสจังหวัดไม่ถูกต้อง"},
+	"province_id_not_positive":                {"en": "Province ID must be greater than 0", "th": "รหัสจังหวัดต้องมากกว่า 0"},
+	"province_id_required":                    {"en": "province_id is required", "th": "กรุณาระบุ province_id"},
+	"province_in_use":                         {"en": "Province is still in use; merge it into another entry instead", "th": "จังหวัดนี้ยังถูกใช้งานอยู่ กรุณารวมเข้ากับรายการอื่นแทน"},
+	"province_merge_failed":                   {"en": "Failed to merge province", "th": "รวมจังหวัดไม่สำเร็จ"},
+	"province_name_required":                  {"en": "Province name is required", "th": "กรุณาระบุชื่อจังหวัด"},
+	"province_name_taken":                     {"en": "A province with this name already exists", "th": "มีจังหวัดชื่อนี้อยู่แล้ว"},
+	"province_name_too_long":                  {"en": "Province name must be at most 100 characters", "th": "ชื่อจังหวัดต้องยาวไม่เกิน 100 ตัวอักษร"},
+	"province_not_found":                      {"en": "Province not found", "th": "ไม่พบจังหวัด"},
+	"province_update_failed":                  {"en": "Failed to update province", "th": "แก้ไขจังหวัดไม่สำเร็จ"},
+	"provinces_get_failed":                    {"en": "Failed to retrieve provinces", "th": "ดึงรายชื่อจังหวัดไม่สำเร็จ"},
+	"provinces_not_found":                     {"en": "No provinces found", "th": "ไม่พบจังหวัด"},
+	"quotation_accept_failed":                 {"en": "Failed to accept quotation", "th": "ยอมรับใบเสนอราคาไม่สำเร็จ"},
+	"quotation_create_failed":                 {"en": "Failed to create quotation", "th": "สร้างใบเสนอราคาไม่สำเร็จ"},
+	"quotation_get_failed":                    {"en": "Failed to get offer quotation", "th": "ดึงใบเสนอราคาไม่สำเร็จ"},
+	"quotation_supersede_failed":              {"en": "Failed to update previous quotation", "th": "อัปเดตใบเสนอราคาเดิมไม่สำเร็จ"},
+	"rating_invalid":                          {"en": "Rating must be between 0 and 5", "th": "คะแนนต้องอยู่ระหว่าง 0 ถึง 5"},
+	"ratings_invalid":                         {"en": "Ratings must be between 1 and 5", "th": "คะแนนต้องอยู่ระหว่าง 1 ถึง 5"},
+	"rationale_required":                      {"en": "A written rationale (reason) is required", "th": "กรุณาระบุเหตุผลประกอบการตัดสิน"},
+	"reason_required":                         {"en": "Reason is required", "th": "กรุณาระบุเหตุผล"},
+	"recipient_type_invalid":                  {"en": "recipient_type must be 'guide' or 'user'", "th": "recipient_type ต้องเป็น 'guide' หรือ 'user'"},
+	"reconciliation_issue_not_found":          {"en": "Reconciliation issue not found", "th": "ไม่พบรายการที่ไม่ตรงกับ Stripe"},
+	"reconciliation_issues_get_failed":        {"en": "Failed to retrieve reconciliation issues", "th": "ดึงรายการที่ไม่ตรงกับ Stripe ไม่สำเร็จ"},
+	"reconciliation_run_failed":               {"en": "Failed to run reconciliation", "th": "ตรวจสอบยอดกับ Stripe ไม่สำเร็จ"},
+	"records_update_failed":                   {"en": "Failed to update records", "th": "อัปเดตข้อมูลไม่สำเร็จ"},
+	"references_check_failed":                 {"en": "Failed to check references", "th": "ตรวจข้อมูลที่อ้างถึงไม่สำเร็จ"},
+	"refund_check_failed":                     {"en": "Failed to check refundable amount", "th": "ตรวจสอบยอดที่คืนได้ไม่สำเร็จ"},
+	"refund_record_create_failed":             {"en": "Failed to create refund record", "th": "สร้างรายการคืนเงินไม่สำเร็จ"},
+	"refund_record_failed":                    {"en": "Failed to record refund", "th": "บันทึกการคืนเงินไม่สำเร็จ"},
+	"region_invalid":                          {"en": "Region is required and must be at most 100 characters", "th": "กรุณาระบุภาค (ไม่เกิน 100 ตัวอักษร)"},
+	"rejection_failed":                        {"en": "Failed to complete rejection", "th": "ปฏิเสธไม่สำเร็จ"},
+	"related_report_id_invalid":               {"en": "Invalid related report ID", "th": "รหัสรายงานที่เกี่ยวข้องไม่ถูกต้อง"},
+	"related_report_not_found":                {"en": "Related report not found", "th": "ไม่พบรายงานที่เกี่ยวข้อง"},
+	"report_close_status_invalid":             {"en": "Status must be resolved or dismissed", "th": "สถานะต้องเป็น resolved หรือ dismissed"},
+	"report_create_failed":                    {"en": "Failed to create report", "th": "สร้างรายงานไม่สำเร็จ"},
+	"report_format_invalid":                   {"en": "format must be json, csv or pdf", "th": "format ต้องเป็น json, csv หรือ pdf"},
+	"report_id_invalid":                       {"en": "Invalid report ID", "th": "รหัสรายงานไม่ถูกต้อง"},
+	"report_link_other_booking":               {"en": "Only reports on the same booking can be linked", "th": "เชื่อมได้เฉพาะรายงานของการจองเดียวกัน"},
+	"report_link_self":                        {"en": "A report cannot be linked to itself", "th": "ไม่สามารถเชื่อมรายงานกับตัวเองได้"},
+	"report_sort_invalid":                     {"en": "Sort must be sla, newest or oldest", "th": "การเรียงลำดับต้องเป็น sla, newest หรือ oldest"},
+	"report_status_filter_invalid":            {"en": "Status must be open, pending, investigating, resolved or dismissed", "th": "สถานะต้องเป็น open, pending, investigating, resolved หรือ dismissed"},
+	"report_status_invalid":                   {"en": "Status must be pending, investigating, resolved or dismissed", "th": "สถานะต้องเป็น pending, investigating, resolved หรือ dismissed"},
+	"report_type_invalid":                     {"en": "Report type must be safety_issue, inappropriate_behavior, payment_issue or other", "th": "ประเภทรายงานต้องเป็น safety_issue, inappropriate_behavior, payment_issue หรือ other"},
+	"reports_already_linked":                  {"en": "Reports are already linked", "th": "รายงานเชื่อมกันอยู่แล้ว"},
+	"reports_get_failed":                      {"en": "Failed to retrieve reports", "th": "ดึงรายงานไม่สำเร็จ"},
+	"reports_link_failed":                     {"en": "Failed to link reports", "th": "เชื่อมรายงานไม่สำเร็จ"},
+	"reports_not_linked":                      {"en": "Reports are not linked", "th": "รายงานไม่ได้เชื่อมกัน"},
+	"reports_unlink_failed":                   {"en": "Failed to unlink reports", "th": "ยกเลิกการเชื่อมรายงานไม่สำเร็จ"},
+	"request_body_parse_failed":               {"en": "Failed to parse request body", "th": "อ่านข้อมูลที่ส่งมาไม่สำเร็จ"},
+	"require_id_invalid":                      {"en": "Invalid require ID", "th": "รหัสโพสต์ทริปไม่ถูกต้อง"},
+	"require_id_not_positive":                 {"en": "Require ID must be greater than 0", "th": "รหัสโพสต์ทริปต้องมากกว่า 0"},
+	"reset_token_create_failed":               {"en": "Failed to create reset token", "th": "สร้างลิงก์รีเซ็ตรหัสผ่านไม่สำเร็จ"},
+	"resolution_reason_required":              {"en": "Resolution reason is required", "th": "กรุณาระบุเหตุผลในการปิดเรื่อง"},
+	"response_save_failed":                    {"en": "Failed to save response", "th": "บันทึกการตอบกลับไม่สำเร็จ"},
+	"review_already_flagged":                  {"en": "You have already flagged this review", "th": "คุณรายงานรีวิวนี้ไปแล้ว"},
+	"review_already_hidden":                   {"en": "Review is already hidden", "th": "รีวิวถูกซ่อนอยู่แล้ว"},
+	"review_caption_too_long":                 {"en": "Image captions must be at most 300 characters", "th": "คำบรรยายรูปต้องยาวไม่เกิน 300 ตัวอักษร"},
+	"review_comment_too_long":                 {"en": "Comment must be at most 2000 characters", "th": "ความคิดเห็นต้องยาวไม่เกิน 2000 ตัวอักษร"},
+	"review_create_failed":                    {"en": "Failed to create review", "th": "สร้างรีวิวไม่สำเร็จ"},
+	"review_delete_failed":                    {"en": "Failed to delete review", "th": "ลบรีวิวไม่สำเร็จ"},
+	"review_delete_own_only":                  {"en": "You can only delete your own reviews", "th": "ลบได้เฉพาะรีวิวของตัวเอง"},
+	"review_edit_own_only":                    {"en": "You can only edit your own reviews", "th": "แก้ไขได้เฉพาะรีวิวของตัวเอง"},
+	"review_flag_failed":                      {"en": "Failed to flag review", "th": "รายงานรีวิวไม่สำเร็จ"},
+	"review_flag_own":                         {"en": "You cannot flag your own review", "th": "รายงานรีวิวของตัวเองไม่ได้"},
+	"review_forbidden":                        {"en": "You are not authorized to review this booking", "th": "คุณไม่มีสิทธิ์รีวิวการจองนี้"},
+	"review_helpful_own":                      {"en": "You cannot mark your own review as helpful", "th": "กดว่ามีประโยชน์ให้รีวิวของตัวเองไม่ได้"},
+	"review_id_invalid":                       {"en": "Invalid review ID", "th": "รหัสรีวิวไม่ถูกต้อง"},
+	"review_image_not_owned":                  {"en": "Images must be your own review image uploads", "th": "รูปต้องเป็นรูปรีวิวที่คุณอัปโหลดเอง"},
+	"review_image_url_invalid":                {"en": "Images must be uploaded files or http(s) URLs", "th": "รูปต้องเป็นไฟล์ที่อัปโหลดหรือ URL แบบ http(s)"},
+	"review_images_invalid":                   {"en": "Images must be a JSON array of URLs or {url, caption} objects", "th": "รูปต้องเป็น JSON array ของ URL หรือ {url, caption}"},
+	"review_images_too_many":                  {"en": "A review can have at most 5 images", "th": "รีวิวมีรูปได้ไม่เกิน 5 รูป"},
+	"review_moderate_failed":                  {"en": "Failed to moderate review", "th": "ตรวจสอบรีวิวไม่สำเร็จ"},
+	"review_not_found":                        {"en": "Review not found", "th": "ไม่พบรีวิว"},
+	"review_not_hidden_or_flagged":            {"en": "Review is not hidden or flagged", "th": "รีวิวนี้ไม่ได้ถูกซ่อนหรือถูกรายงาน"},
+	"review_respond_own_only":                 {"en": "You can only respond to your own reviews", "th": "ตอบได้เฉพาะรีวิวของตัวเอง"},
+	"review_status_filter_invalid":            {"en": "Status must be flagged, hidden or all", "th": "สถานะต้องเป็น flagged, hidden หรือ all"},
+	"review_trip_not_completed":               {"en": "Can only review completed trips", "th": "รีวิวได้เฉพาะทริปที่เสร็จสิ้นแล้ว"},
+	"review_update_failed":                    {"en": "Failed to update review", "th": "แก้ไขรีวิวไม่สำเร็จ"},
+	"reviews_fetch_failed":                    {"en": "Failed to fetch reviews", "th": "ดึงรีวิวไม่สำเร็จ"},
+	"reviews_get_failed":                      {"en": "Failed to retrieve reviews", "th": "ดึงรีวิวไม่สำเร็จ"},
+	"severity_invalid":                        {"en": "Severity must be low, medium, high or critical", "th": "ระดับความรุนแรงต้องเป็น low, medium, high หรือ critical"},
+	"share_amount_negative":                   {"en": "Share amount must not be negative", "th": "ยอดส่วนแบ่งต้องไม่ติดลบ"},
+	"share_participant_unknown":               {"en": "Share refers to an unknown participant", "th": "ส่วนแบ่งอ้างถึงผู้ร่วมทริปที่ไม่มีอยู่"},
+	"share_payment_not_found":                 {"en": "Share payment not found", "th": "ไม่พบการชำระส่วนแบ่ง"},
+	"share_payment_record_failed":             {"en": "Failed to record share payment", "th": "บันทึกการชำระส่วนแบ่งไม่สำเร็จ"},
+	"share_payment_in_progress":               {"en": "A payment for this share is already in progress", "th": "มีการชำระส่วนแบ่งนี้กำลังดำเนินการอยู่"},
+	"share_reassign_failed":                   {"en": "Failed to move the share to the organiser", "th": "ย้ายส่วนแบ่งไปให้ผู้จัดทริปไม่สำเร็จ"},
+	"shares_save_failed":                      {"en": "Failed to save shares", "th": "บันทึกส่วนแบ่งการจ่ายไม่สำเร็จ"},
+	"shares_total_mismatch":                   {"en": "Shares must add up to the booking total", "th": "ผลรวมส่วนแบ่งต้องเท่ากับยอดการจอง"},
+	"signature_invalid":                       {"en": "Invalid signature", "th": "ลายเซ็นไม่ถูกต้อง"},
+	"split_payment_exists":                    {"en": "Split payment is already set up", "th": "ตั้งค่าการแบ่งจ่ายไว้แล้ว"},
+	"split_payment_locked":                    {"en": "Split payment can only be set up before payment", "th": "ตั้งค่าการแบ่งจ่ายได้เฉพาะก่อนชำระเงิน"},
+	"split_payment_setup_failed":              {"en": "Failed to set up split payment", "th": "ตั้งค่าการแบ่งจ่ายไม่สำเร็จ"},
+	"split_requires_co_traveller":             {"en": "Invite at least one co-traveller before splitting payment", "th": "ต้องเชิญผู้ร่วมทริปอย่างน้อยหนึ่งคนก่อนแบ่งจ่าย"},
+	"start_code_generate_failed":              {"en": "Failed to generate start code", "th": "สร้างรหัสเริ่มทริปไม่สำเร็จ"},
+	"start_code_invalid":                      {"en": "Invalid start code", "th": "รหัสเริ่มทริปไม่ถูกต้อง"},
+	"start_code_required":                     {"en": "Start code is required", "th": "กรุณากรอกรหัสเริ่มทริป"},
+	"start_code_unavailable":                  {"en": "Start code is only available for paid bookings that have not started", "th": "รหัสเริ่มทริปใช้ได้เฉพาะการจองที่ชำระเงินแล้วและยังไม่เริ่ม"},
+	"start_date_format_invalid":               {"en": "Invalid start_date format (use DD/MM/YYYY)", "th": "รูปแบบ start_date ไม่ถูกต้อง (ใช้ DD/MM/YYYY)"},
+	"statement_add_failed":                    {"en": "Failed to add statement", "th": "เพิ่มคำชี้แจงไม่สำเร็จ"},
+	"statement_build_failed":                  {"en": "Failed to build statement", "th": "สร้าง statement ไม่สำเร็จ"},
+	"statement_length_invalid":                {"en": "Statement must be between 1 and 5000 characters", "th": "คำชี้แจงต้องมีความยาว 1 ถึง 5000 ตัวอักษร"},
+	"statement_limit_reached":                 {"en": "You have reached the maximum number of statements for this dispute", "th": "คุณส่งคำชี้แจงในข้อพิพาทนี้ครบจำนวนสูงสุดแล้ว"},
+	"stop_coordinates_incomplete":             {"en": "Stop latitude and longitude must be provided together", "th": "ต้องส่งละติจูดและลองจิจูดของจุดแวะพร้อมกัน"},
+	"stop_coordinates_invalid":                {"en": "Invalid stop coordinates", "th": "พิกัดของจุดแวะไม่ถูกต้อง"},
+	"stop_day_invalid":                        {"en": "Stop day must be between 1 and 30", "th": "วันของจุดแวะต้องอยู่ระหว่าง 1 ถึง 30"},
+	"stop_name_required":                      {"en": "Each stop needs a name or attraction_id", "th": "จุดแวะแต่ละจุดต้องมีชื่อหรือ attraction_id"},
+	"stop_name_too_long":                      {"en": "Stop name must be at most 200 characters", "th": "ชื่อจุดแวะต้องยาวไม่เกิน 200 ตัวอักษร"},
+	"stripe_account_invalid":                  {"en": "Invalid Stripe account ID", "th": "รหัสบัญชี Stripe ไม่ถูกต้อง"},
+	"stripe_payment_get_failed":               {"en": "Failed to retrieve payment from Stripe", "th": "ดึงข้อมูลการชำระเงินจาก Stripe ไม่สำเร็จ"},
+	"stripe_refund_missing":                   {"en": "Refund no longer exists on Stripe", "th": "ไม่พบรายการคืนเงินนี้บน Stripe แล้ว"},
+	"target_id_required":                      {"en": "target_id is required", "th": "กรุณาระบุ target_id"},
+	"template_create_failed":                  {"en": "Failed to create template", "th": "สร้างเทมเพลตไม่สำเร็จ"},
+	"template_delete_failed":                  {"en": "Failed to delete template", "th": "ลบเทมเพลตไม่สำเร็จ"},
+	"template_fields_required":                {"en": "Missing required fields (name, title, description)", "th": "กรุณากรอกข้อมูลให้ครบ (name, title, description)"},
+	"template_get_failed":                     {"en": "Failed to get template", "th": "ดึงเทมเพลตไม่สำเร็จ"},
+	"template_id_invalid":                     {"en": "Invalid template ID", "th": "รหัสเทมเพลตไม่ถูกต้อง"},
+	"template_not_found":                      {"en": "Template not found", "th": "ไม่พบเทมเพลต"},
+	"template_price_negative":                 {"en": "Default price must not be negative", "th": "ราคาเริ่มต้นต้องไม่ติดลบ"},
+	"template_update_failed":                  {"en": "Failed to update template", "th": "แก้ไขเทมเพลตไม่สำเร็จ"},
+	"templates_get_failed":                    {"en": "Failed to get templates", "th": "ดึงรายการเทมเพลตไม่สำเร็จ"},
+	"title_description_required":              {"en": "Title and description are required", "th": "กรุณาระบุหัวข้อและรายละเอียด"},
+	"title_empty":                             {"en": "Title cannot be empty", "th": "หัวข้อต้องไม่ว่าง"},
+	"title_too_long":                          {"en": "Title must be at most 200 characters", "th": "หัวข้อต้องยาวไม่เกิน 200 ตัวอักษร"},
+	"to_date_invalid":                         {"en": "to must be YYYY-MM-DD", "th": "to ต้องอยู่ในรูปแบบ YYYY-MM-DD"},
+	"token_claims_invalid":                    {"en": "Invalid token claims", "th": "ข้อมูลใน token ไม่ถูกต้อง"},
+	"token_creation_failed":                   {"en": "Token creation failed", "th": "สร้าง token ไม่สำเร็จ"},
+	"token_exchange_failed":                   {"en": "Failed to exchange token", "th": "แลกเปลี่ยน token ไม่สำเร็จ"},
+	"token_format_invalid":                    {"en": "Invalid token format", "th": "รูปแบบ token ไม่ถูกต้อง"},
+	"token_generation_failed":                 {"en": "Token generation failed", "th": "สร้าง token ไม่สำเร็จ"},
+	"token_invalid":                           {"en": "Invalid token", "th": "token ไม่ถูกต้อง"},
+	"token_invalid_or_expired":                {"en": "Invalid or expired token", "th": "token ไม่ถูกต้องหรือหมดอายุ"},
+	"token_mark_used_failed":                  {"en": "Failed to mark token as used", "th": "อัปเดตสถานะ token ไม่สำเร็จ"},
+	"token_missing_claims":                    {"en": "Missing user or role information in token", "th": "token ไม่มีข้อมูลผู้ใช้หรือบทบาท"},
+	"token_required":                          {"en": "Token required", "th": "ต้องส่ง token"},
+	"tourist_attraction_create_failed":        {"en": "Failed to create tourist attraction", "th": "เพิ่มสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"tourist_attraction_delete_failed":        {"en": "Failed to delete tourist attraction", "th": "ลบสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"tourist_attraction_id_invalid":           {"en": "Tourist attraction ID must be a positive integer", "th": "รหัสสถานที่ท่องเที่ยวต้องเป็นจำนวนเต็มบวก"},
+	"tourist_attraction_in_use":               {"en": "Tourist attraction is still in use; merge it into another entry instead", "th": "สถานที่ท่องเที่ยวนี้ยังถูกใช้งานอยู่ กรุณารวมเข้ากับรายการอื่นแทน"},
+	"tourist_attraction_merge_failed":         {"en": "Failed to merge tourist attraction", "th": "รวมสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"tourist_attraction_name_required":        {"en": "Tourist attraction name is required", "th": "กรุณาระบุชื่อสถานที่ท่องเที่ยว"},
+	"tourist_attraction_name_too_long":        {"en": "Tourist attraction name must be at most 100 characters", "th": "ชื่อสถานที่ท่องเที่ยวต้องยาวไม่เกิน 100 ตัวอักษร"},
+	"tourist_attraction_not_found":            {"en": "Tourist attraction not found", "th": "ไม่พบสถานที่ท่องเที่ยว"},
+	"tourist_attraction_update_failed":        {"en": "Failed to update tourist attraction", "th": "แก้ไขสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"tourist_attractions_get_failed":          {"en": "Failed to retrieve tourist attractions", "th": "ดึงสถานที่ท่องเที่ยวไม่สำเร็จ"},
+	"transaction_commit_failed":               {"en": "Failed to commit transaction", "th": "บันทึกข้อมูลไม่สำเร็จ"},
+	"transaction_failed":                      {"en": "Transaction failed", "th": "ทำรายการไม่สำเร็จ"},
+	"translation_field_invalid":               {"en": "This field cannot be translated", "th": "field นี้แปลไม่ได้"},
+	"translation_id_invalid":                  {"en": "ID must be a positive integer", "th": "รหัสต้องเป็นจำนวนเต็มบวก"},
+	"translation_target_not_found":            {"en": "Item to translate not found", "th": "ไม่พบรายการที่ต้องการแปล"},
+	"translation_too_long":                    {"en": "Translations must be at most 5000 characters", "th": "คำแปลต้องยาวไม่เกิน 5000 ตัวอักษร"},
+	"translation_type_invalid":                {"en": "Type must be province, attraction, category, language, role or guide", "th": "ประเภทต้องเป็น province, attraction, category, language, role หรือ guide"},
+	"translations_get_failed":                 {"en": "Failed to get translations", "th": "ดึงคำแปลไม่สำเร็จ"},
+	"translations_update_failed":              {"en": "Failed to update translations", "th": "บันทึกคำแปลไม่สำเร็จ"},
+	"traveller_notify_failed":                 {"en": "Failed to notify traveller", "th": "แจ้งเตือนนักท่องเที่ยวไม่สำเร็จ"},
+	"traveller_only_billing":                  {"en": "Only the traveller can set billing details", "th": "เฉพาะนักท่องเที่ยวเท่านั้นที่ตั้งข้อมูลใบกำกับภาษีได้"},
+	"traveller_only_confirm_arrival":          {"en": "Only the traveller can confirm guide arrival", "th": "เฉพาะนักท่องเที่ยวเท่านั้นที่ยืนยันว่าไกด์มาถึงได้"},
+	"traveller_only_confirm_completion":       {"en": "Only the traveller can confirm trip completion", "th": "เฉพาะนักท่องเที่ยวเท่านั้นที่ยืนยันการจบทริปได้"},
+	"traveller_only_meeting_point":            {"en": "Only the traveller can set the meeting point", "th": "เฉพาะนักท่องเที่ยวเท่านั้นที่ตั้งจุดนัดพบได้"},
+	"traveller_only_start_code":               {"en": "Only the traveller can view the start code", "th": "เฉพาะนักท่องเที่ยวเท่านั้นที่ดูรหัสเริ่มทริปได้"},
+	"trip_already_started":                    {"en": "The trip has already started", "th": "ทริปเริ่มไปแล้ว"},
+	"trip_end_not_reached":                    {"en": "Trip cannot be completed before its end date unless early completion is agreed", "th": "จบทริปก่อนวันสิ้นสุดไม่ได้ เว้นแต่ทั้งสองฝ่ายตกลงจบก่อนกำหนด"},
+	"trip_id_invalid":                         {"en": "Invalid trip ID", "th": "รหัสทริปไม่ถูกต้อง"},
+	"trip_not_started_check_in":               {"en": "Trip must be started before daily check-in", "th": "ต้องเริ่มทริปก่อนเช็กอินรายวัน"},
+	"trip_not_started_completion":             {"en": "Trip must be started before completion", "th": "ต้องเริ่มทริปก่อนจึงจะจบทริปได้"},
+	"trip_not_started_early_completion":       {"en": "Trip must be started before requesting early completion", "th": "ต้องเริ่มทริปก่อนขอจบทริปก่อนกำหนด"},
+	"trip_report_assign_failed":               {"en": "Failed to assign trip report", "th": "มอบหมายรายงานไม่สำเร็จ"},
+	"trip_report_closed":                      {"en": "Trip report is already closed", "th": "รายงานนี้ปิดแล้ว"},
+	"trip_report_duplicate":                   {"en": "You already have an open report of this type for this booking", "th": "คุณมีรายงานประเภทนี้ของการจองนี้ที่ยังเปิดอยู่แล้ว"},
+	"trip_report_not_found":                   {"en": "Trip report not found", "th": "ไม่พบรายงานเหตุการณ์"},
+	"trip_report_update_failed":               {"en": "Failed to update trip report", "th": "อัปเดตรายงานเหตุการณ์ไม่สำเร็จ"},
+	"trip_reports_get_failed":                 {"en": "Failed to retrieve trip reports", "th": "ดึงรายงานเหตุการณ์ไม่สำเร็จ"},
+	"trip_require_id_invalid":                 {"en": "Invalid trip require ID", "th": "รหัสโพสต์ทริปไม่ถูกต้อง"},
+	"trip_require_id_required":                {"en": "trip_require_id is required", "th": "กรุณาระบุ trip_require_id"},
+	"trip_requirement_closed":                 {"en": "Trip requirement is no longer accepting offers", "th": "โพสต์ทริปนี้ไม่รับข้อเสนอแล้ว"},
+	"trip_requirement_create_failed":          {"en": "Failed to create trip requirement", "th": "สร้างโพสต์ทริปไม่สำเร็จ"},
+	"trip_requirement_delete_failed":          {"en": "Failed to delete trip requirement", "th": "ลบโพสต์ทริปไม่สำเร็จ"},
+	"trip_requirement_find_failed":            {"en": "Failed to find trip requirement", "th": "ค้นหาโพสต์ทริปไม่สำเร็จ"},
+	"trip_requirement_get_failed":             {"en": "Failed to get trip requirement", "th": "ดึงโพสต์ทริปไม่สำเร็จ"},
+	"trip_requirement_not_deletable":          {"en": "Cannot delete a trip requirement that is assigned or completed", "th": "ไม่สามารถลบโพสต์ทริปที่มีไกด์รับงานแล้วหรือเสร็จสิ้นแล้ว"},
+	"trip_requirement_not_found":              {"en": "Trip requirement not found", "th": "ไม่พบโพสต์ทริป"},
+	"trip_requirement_not_found_or_forbidden": {"en": "Trip requirement not found or you don't have permission", "th": "ไม่พบโพสต์ทริปหรือคุณไม่มีสิทธิ์"},
+	"trip_requirement_retrieve_failed":        {"en": "Failed to retrieve trip requirement", "th": "ดึงโพสต์ทริปไม่สำเร็จ"},
+	"trip_requirement_status_update_failed":   {"en": "Failed to update trip requirement status", "th": "อัปเดตสถานะโพสต์ทริปไม่สำเร็จ"},
+	"trip_requirement_update_failed":          {"en": "Failed to update trip requirement", "th": "แก้ไขโพสต์ทริปไม่สำเร็จ"},
+	"trip_requirements_get_failed":            {"en": "Failed to retrieve trip requirements", "th": "ดึงรายการโพสต์ทริปไม่สำเร็จ"},
+	"trip_requirements_not_found":             {"en": "No trip requirements found for this trip ID", "th": "ไม่พบโพสต์ทริปของรหัสนี้"},
+	"unauthorized":                            {"en": "Unauthorized", "th": "กรุณาเข้าสู่ระบบ"},
+	"upload_empty":                            {"en": "Uploaded file is empty", "th": "ไฟล์ที่อัปโหลดว่างเปล่า"},
+	"upload_id_invalid":                       {"en": "Upload ID must be a positive integer", "th": "รหัสไฟล์ต้องเป็นจำนวนเต็มบวก"},
+	"upload_image_invalid":                    {"en": "File is not a supported image (JPEG, PNG or GIF)", "th": "ไฟล์ไม่ใช่รูปที่รองรับ (JPEG, PNG หรือ GIF)"},
+	"upload_purpose_unknown":                  {"en": "Unknown upload purpose", "th": "ไม่รู้จักประเภทการอัปโหลด"},
+	"upload_too_large":                        {"en": "Uploaded file is too large", "th": "ไฟล์ที่อัปโหลดมีขนาดใหญ่เกินไป"},
+	"upload_type_not_allowed":                 {"en": "File type is not allowed", "th": "ไม่รองรับไฟล์ประเภทนี้"},
+	"uploaded_file_open_failed":               {"en": "Failed to open uploaded file", "th": "เปิดไฟล์ที่อัปโหลดไม่สำเร็จ"},
+	"user_create_failed":                      {"en": "Failed to create user", "th": "สร้างผู้ใช้ไม่สำเร็จ"},
+	"user_details_get_failed":                 {"en": "Failed to get user details", "th": "ดึงข้อมูลผู้ใช้ไม่สำเร็จ"},
+	"user_email_get_failed":                   {"en": "Failed to get user email", "th": "ดึงอีเมลผู้ใช้ไม่สำเร็จ"},
+	"user_id_required":                        {"en": "User ID parameter required", "th": "ต้องระบุรหัสผู้ใช้"},
+	"user_info_get_failed":                    {"en": "Failed to get user info", "th": "ดึงข้อมูลผู้ใช้ไม่สำเร็จ"},
+	"user_info_invalid":                       {"en": "Invalid user information", "th": "ข้อมูลผู้ใช้ไม่ถูกต้อง"},
+	"user_info_not_found":                     {"en": "User information not found", "th": "ไม่พบข้อมูลผู้ใช้"},
+	"user_info_parse_failed":                  {"en": "Failed to parse user info", "th": "อ่านข้อมูลผู้ใช้ไม่สำเร็จ"},
+	"user_not_found":                          {"en": "User not found", "th": "ไม่พบผู้ใช้"},
+	"user_refund_record_create_failed":        {"en": "Failed to create user refund record", "th": "สร้างรายการคืนเงินให้ผู้ใช้ไม่สำเร็จ"},
+	"user_reload_failed":                      {"en": "Failed to reload user data", "th": "โหลดข้อมูลผู้ใช้ใหม่ไม่สำเร็จ"},
+	"user_required":                           {"en": "User access required", "th": "ต้องเป็นผู้ใช้ทั่วไป"},
+	"user_role_get_failed":                    {"en": "Failed to get user role", "th": "ดึงบทบาทผู้ใช้ไม่สำเร็จ"},
+	"user_role_not_found":                     {"en": "User role not found", "th": "ไม่พบบทบาทผู้ใช้"},
+	"user_role_update_failed":                 {"en": "Failed to update user role", "th": "เปลี่ยนบทบาทผู้ใช้ไม่สำเร็จ"},
+	"user_update_failed":                      {"en": "Failed to update user", "th": "แก้ไขผู้ใช้ไม่สำเร็จ"},
+	"verification_guide_update_failed":        {"en": "Failed to update guide ID in verification", "th": "อัปเดตใบสมัครไกด์ไม่สำเร็จ"},
+	"verification_requests_get_failed":        {"en": "Failed to retrieve verification requests", "th": "ดึงคำขอยืนยันตัวตนไม่สำเร็จ"},
+}
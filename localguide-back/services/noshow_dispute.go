@@ -0,0 +1,226 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultNoShowResponseHours = 24
+	defaultNoShowEvidenceHours = 72
+
+	// DisputeDeadlineLayout - รูปแบบเวลาที่แสดงในข้อความแจ้งเตือน
+	DisputeDeadlineLayout = "2006-01-02 15:04"
+)
+
+func envHours(key string, fallback int) time.Duration {
+	hours := fallback
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		hours = v
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// NoShowResponseWindow - เวลาที่ user มีให้ยืนยันหรือคัดค้านหลังไกด์รีพอร์ต (NOSHOW_RESPONSE_HOURS, ค่าเริ่มต้น 24)
+func NoShowResponseWindow() time.Duration {
+	return envHours("NOSHOW_RESPONSE_HOURS", defaultNoShowResponseHours)
+}
+
+// NoShowEvidenceWindow - เวลาที่ทั้งสองฝ่ายส่งคำชี้แจง/หลักฐานได้หลัง user คัดค้าน (NOSHOW_EVIDENCE_HOURS, ค่าเริ่มต้น 72)
+func NoShowEvidenceWindow() time.Duration {
+	return envHours("NOSHOW_EVIDENCE_HOURS", defaultNoShowEvidenceHours)
+}
+
+// SettleNoShowDecision จัดการเงินตามคำตัดสิน no-show ด้วยเงินที่ยังค้างในระบบ
+// guide_wins = ไกด์ได้ครึ่งหนึ่งคืน user ครึ่งหนึ่ง, user_wins = คืน user ทั้งหมด, split_cost = ไกด์ 25% คืน user 75%
+func SettleNoShowDecision(tx *gorm.DB, booking *models.TripBooking, decision, reason string, now time.Time) (*CaseSettlement, error) {
+	var (
+		settlement *CaseSettlement
+		err        error
+		status     string
+	)
+	switch decision {
+	case "guide_wins":
+		settlement, err = SettleCase(tx, booking, "split", 0.5, reason, now)
+		status = "no_show_confirmed"
+	case "user_wins":
+		settlement, err = SettleCase(tx, booking, "refund", 0, reason, now)
+		status = "cancelled"
+	case "split_cost":
+		settlement, err = SettleCase(tx, booking, "split", 0.25, reason, now)
+		status = "no_show_split"
+	default:
+		return nil, fmt.Errorf("unknown no-show decision %q", decision)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"status": status, "cancellation_reason": reason}
+	if status == "cancelled" {
+		updates["cancelled_at"] = &now
+	}
+	if err := tx.Model(booking).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update booking: %w", err)
+	}
+	return settlement, nil
+}
+
+// RecordNoShowDecision บันทึกคำตัดสินพร้อมเหตุผลลงใน dispute และปิดรีพอร์ตที่ยังค้างของ booking
+// booking ที่ไม่มี dispute (รีพอร์ตก่อนมีไทม์ไลน์) จะปิดเฉพาะรีพอร์ต
+func RecordNoShowDecision(tx *gorm.DB, bookingID uint, decision, source, rationale string, decidedBy *uint, now time.Time) error {
+	if err := tx.Model(&models.NoShowDispute{}).Where("trip_booking_id = ?", bookingID).Updates(map[string]interface{}{
+		"stage":           "decided",
+		"decision":        decision,
+		"decision_source": source,
+		"rationale":       rationale,
+		"decided_by":      decidedBy,
+		"decided_at":      &now,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to record decision: %w", err)
+	}
+
+	return tx.Model(&models.TripReport{}).
+		Where("trip_booking_id = ? AND report_type IN ? AND status IN ?", bookingID, []string{"user_no_show", "dispute_no_show"}, OpenReportStatuses).
+		Updates(map[string]interface{}{
+			"status":      "resolved",
+			"resolved_at": &now,
+			"admin_notes": rationale,
+		}).Error
+}
+
+// NoShowDisputeService ปิดขั้นตอนของ dispute ที่เลยกำหนด:
+// user ไม่ตอบภายในเวลา = ยอมรับว่าไม่มา (guide_wins), ไกด์ไม่ชี้แจงหลัง user คัดค้าน = คืนเงิน user (user_wins),
+// ทั้งสองฝ่ายชี้แจงแล้ว = รอ admin ตัดสิน
+type NoShowDisputeService struct {
+	db *gorm.DB
+}
+
+func NewNoShowDisputeService(db *gorm.DB) *NoShowDisputeService {
+	return &NoShowDisputeService{db: db}
+}
+
+// Start รัน job ทุก interval จนกว่าโปรแกรมจะปิด
+func (s *NoShowDisputeService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.RunOnce(time.Now()); err != nil {
+				log.Printf("no-show dispute deadlines failed: %v", err)
+			} else if n > 0 {
+				log.Printf("no-show dispute deadlines: advanced %d dispute(s)", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// RunOnce จัดการ dispute ที่เลยกำหนดทั้งหมด คืนจำนวน dispute ที่เปลี่ยนขั้นตอน
+func (s *NoShowDisputeService) RunOnce(now time.Time) (int, error) {
+	var disputes []models.NoShowDispute
+	if err := s.db.Where("(stage = ? AND respond_by < ?) OR (stage = ? AND evidence_due_at < ?)",
+		"awaiting_traveller", now, "evidence", now).Find(&disputes).Error; err != nil {
+		return 0, fmt.Errorf("failed to get disputes: %w", err)
+	}
+
+	advanced := 0
+	for i := range disputes {
+		ok, err := s.advance(&disputes[i], now)
+		if err != nil {
+			log.Printf("no-show dispute #%d: %v", disputes[i].ID, err)
+			continue
+		}
+		if ok {
+			advanced++
+		}
+	}
+	return advanced, nil
+}
+
+// advance คืน false ถ้า dispute เปลี่ยนขั้นตอนไปแล้ว (เช่น user ตอบกลับ admin ตัดสิน หรือ job อีกตัวทำไปก่อน)
+func (s *NoShowDisputeService) advance(dispute *models.NoShowDispute, now time.Time) (bool, error) {
+	advanced := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		guideResponded := false
+		if dispute.Stage == "evidence" {
+			var guideStatements int64
+			if err := tx.Model(&models.DisputeStatement{}).
+				Where("no_show_dispute_id = ? AND party = ? AND created_at >= ?", dispute.ID, "guide", dispute.DisputedAt).
+				Count(&guideStatements).Error; err != nil {
+				return fmt.Errorf("failed to count guide statements: %w", err)
+			}
+			guideResponded = guideStatements > 0
+		}
+
+		// จองขั้นตอนถัดไปก่อน ถ้าขั้นตอนเปลี่ยนไปแล้วระหว่างที่ job อ่านรายการ ข้าม dispute นี้
+		next := "decided"
+		if guideResponded {
+			next = "awaiting_decision"
+		}
+		claim := tx.Model(&models.NoShowDispute{}).
+			Where("id = ? AND stage = ?", dispute.ID, dispute.Stage).
+			Update("stage", next)
+		if claim.Error != nil {
+			return fmt.Errorf("failed to update dispute stage: %w", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			return nil
+		}
+		advanced = true
+
+		var booking models.TripBooking
+		if err := tx.First(&booking, dispute.TripBookingID).Error; err != nil {
+			return fmt.Errorf("booking not found: %w", err)
+		}
+		notifier := NewNotificationService(tx)
+
+		var decision, source, rationale string
+		if dispute.Stage == "awaiting_traveller" {
+			decision, source = "guide_wins", "traveller_no_response"
+			rationale = "The traveller did not confirm or dispute the no-show report by " + dispute.RespondBy.Format(DisputeDeadlineLayout) + "."
+		} else {
+			if guideResponded {
+				var adminIDs []uint
+				tx.Model(&models.User{}).Where("role_id = ?", 3).Pluck("id", &adminIDs)
+				for _, id := range adminIDs {
+					if _, err := notifier.Send(id, "dispute_awaiting_decision", map[string]interface{}{"BookingID": booking.ID}, "trip_booking", booking.ID); err != nil {
+						return fmt.Errorf("failed to notify admin: %w", err)
+					}
+				}
+				return nil
+			}
+			decision, source = "user_wins", "guide_no_response"
+			rationale = "The guide did not respond to the traveller's dispute by " + dispute.EvidenceDueAt.Format(DisputeDeadlineLayout) + "."
+		}
+
+		if _, err := SettleNoShowDecision(tx, &booking, decision, "dispute_"+source, now); err != nil {
+			return err
+		}
+		if err := RecordNoShowDecision(tx, booking.ID, decision, source, rationale, nil, now); err != nil {
+			return err
+		}
+
+		data := map[string]interface{}{"BookingID": booking.ID, "Decision": decision, "Rationale": rationale}
+		if _, err := notifier.Send(booking.UserID, "dispute_resolved", data, "trip_booking", booking.ID); err != nil {
+			return fmt.Errorf("failed to notify user: %w", err)
+		}
+		var guide models.Guide
+		if err := tx.Select("id", "user_id").First(&guide, booking.GuideID).Error; err == nil {
+			if _, err := notifier.Send(guide.UserID, "dispute_resolved", data, "trip_booking", booking.ID); err != nil {
+				return fmt.Errorf("failed to notify guide: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return advanced, nil
+}
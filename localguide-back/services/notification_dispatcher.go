@@ -0,0 +1,113 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultOutboxMaxAttempts = 5
+	outboxBatchSize          = 100
+	outboxRetryBase          = time.Minute
+)
+
+// NotificationChannel ช่องทางส่งข้อความออกนอกระบบ เพิ่มช่องทางใหม่ได้โดย implement interface นี้
+// แล้วส่งเข้า NewNotificationDispatcher และเพิ่มชื่อใน OutboundChannels
+type NotificationChannel interface {
+	Name() string
+	Deliver(entry *models.NotificationOutbox) error
+}
+
+// EmailChannel ส่ง outbox ช่องทาง email ผ่าน EmailSender
+type EmailChannel struct {
+	sender EmailSender
+}
+
+func NewEmailChannel(sender EmailSender) *EmailChannel {
+	return &EmailChannel{sender: sender}
+}
+
+func (c *EmailChannel) Name() string {
+	return "email"
+}
+
+func (c *EmailChannel) Deliver(entry *models.NotificationOutbox) error {
+	return c.sender.Send(entry.Recipient, entry.Subject, entry.Body)
+}
+
+// NotificationDispatcher ส่งข้อความใน outbox ที่ถึงเวลา ส่งไม่สำเร็จจะลองใหม่แบบ backoff จนครบ MaxAttempts
+type NotificationDispatcher struct {
+	db       *gorm.DB
+	channels map[string]NotificationChannel
+}
+
+func NewNotificationDispatcher(db *gorm.DB, channels ...NotificationChannel) *NotificationDispatcher {
+	d := &NotificationDispatcher{db: db, channels: make(map[string]NotificationChannel)}
+	for _, ch := range channels {
+		d.channels[ch.Name()] = ch
+	}
+	return d
+}
+
+// Start รัน dispatcher ทุก interval จนกว่าโปรแกรมจะปิด
+func (d *NotificationDispatcher) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if _, err := d.RunOnce(time.Now()); err != nil {
+				log.Printf("notification dispatch failed: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// RunOnce ส่งข้อความที่ถึงเวลาทั้งหมด คืนจำนวนที่ส่งสำเร็จ
+func (d *NotificationDispatcher) RunOnce(now time.Time) (int, error) {
+	var entries []models.NotificationOutbox
+	if err := d.db.Where("status = ? AND next_attempt_at <= ?", "pending", now).
+		Order("id ASC").
+		Limit(outboxBatchSize).
+		Find(&entries).Error; err != nil {
+		return 0, fmt.Errorf("failed to get outbox: %w", err)
+	}
+
+	sent := 0
+	for i := range entries {
+		entry := &entries[i]
+
+		var err error
+		if ch, ok := d.channels[entry.Channel]; ok {
+			err = ch.Deliver(entry)
+		} else {
+			err = fmt.Errorf("no channel registered for %q", entry.Channel)
+		}
+
+		entry.Attempts++
+		if err == nil {
+			entry.Status = "sent"
+			entry.SentAt = &now
+			entry.LastError = ""
+			sent++
+		} else {
+			entry.LastError = err.Error()
+			if entry.Attempts >= entry.MaxAttempts {
+				entry.Status = "failed"
+				log.Printf("notification outbox #%d failed after %d attempts: %v", entry.ID, entry.Attempts, err)
+			} else {
+				entry.NextAttemptAt = now.Add(outboxRetryBase * time.Duration(1<<(entry.Attempts-1)))
+			}
+		}
+
+		if err := d.db.Save(entry).Error; err != nil {
+			return sent, fmt.Errorf("failed to update outbox #%d: %w", entry.ID, err)
+		}
+	}
+
+	return sent, nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"localguide-back/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboundChannels - ช่องทางภายนอกที่ Notify จะเข้าคิว outbox ให้ (dispatcher ต้องมี NotificationChannel ชื่อเดียวกัน)
+var OutboundChannels = []string{"email"}
+
+type NotificationService struct {
+	db *gorm.DB
+}
+
+// NewNotificationService สร้าง service โดยใช้ db ที่ส่งเข้ามา (ส่ง tx มาได้เพื่อให้อยู่ใน transaction เดียวกัน)
+func NewNotificationService(db *gorm.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// Enabled - ผู้ใช้เปิดช่องทางนี้สำหรับประเภทนี้หรือไม่ (ค่าของประเภทนั้นมีผลเหนือ "*", ไม่ตั้งค่า = เปิด)
+func (s *NotificationService) Enabled(userID uint, notifType, channel string) bool {
+	var prefs []models.NotificationPreference
+	s.db.Where("user_id = ? AND channel = ? AND type IN ?", userID, channel, []string{notifType, "*"}).Find(&prefs)
+
+	enabled := true
+	for _, p := range prefs {
+		if p.Type == notifType {
+			return p.Enabled
+		}
+		enabled = p.Enabled
+	}
+	return enabled
+}
+
+// Send สร้างการแจ้งเตือนจาก template ตามภาษาของผู้ใช้
+func (s *NotificationService) Send(userID uint, notifType string, data map[string]interface{}, relatedType string, relatedID uint) (*models.Notification, error) {
+	var lang string
+	s.db.Model(&models.User{}).Select("language").Where("id = ?", userID).Scan(&lang)
+
+	title, message, err := RenderNotification(notifType, lang, data)
+	if err != nil {
+		return nil, err
+	}
+	return s.Notify(userID, notifType, title, message, relatedType, relatedID)
+}
+
+// Notify บันทึกการแจ้งเตือนลง feed ในแอป และเข้าคิวส่งช่องทางอื่นที่ผู้ใช้เปิดไว้
+// ถ้าผู้ใช้ปิดการแจ้งเตือนในแอปจะคืน nil
+func (s *NotificationService) Notify(userID uint, notifType, title, message, relatedType string, relatedID uint) (*models.Notification, error) {
+	var notification *models.Notification
+	if s.Enabled(userID, notifType, "in_app") {
+		notification = &models.Notification{
+			UserID:      userID,
+			Type:        notifType,
+			Title:       title,
+			Message:     message,
+			RelatedType: relatedType,
+			RelatedID:   relatedID,
+		}
+		if err := s.db.Create(notification).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	for _, channel := range OutboundChannels {
+		if !s.Enabled(userID, notifType, channel) {
+			continue
+		}
+		if err := s.enqueue(channel, userID, notification, title, message); err != nil {
+			return nil, err
+		}
+	}
+
+	return notification, nil
+}
+
+func (s *NotificationService) enqueue(channel string, userID uint, notification *models.Notification, title, message string) error {
+	switch channel {
+	case "email":
+		if !EmailConfigured() {
+			return nil
+		}
+		var email string
+		s.db.Table("auth_users").
+			Select("auth_users.email").
+			Joins("JOIN users ON users.auth_user_id = auth_users.id").
+			Where("users.id = ?", userID).
+			Scan(&email)
+		if email == "" {
+			return nil
+		}
+		body := fmt.Sprintf(`
+        <h2>%s</h2>
+        <p>%s</p>
+        <a href="%s">LocalGuide</a>
+    `, html.EscapeString(title), html.EscapeString(message), FrontendURL("/notifications"))
+
+		entry := s.newOutbox(channel, email, title, body)
+		entry.UserID = &userID
+		if notification != nil {
+			entry.NotificationID = &notification.ID
+		}
+		return s.db.Create(&entry).Error
+	default:
+		return fmt.Errorf("unknown notification channel %q", channel)
+	}
+}
+
+// QueueEmail เข้าคิวอีเมลที่ไม่ได้มาจากการแจ้งเตือน เช่น รีเซ็ตรหัสผ่าน หรือคำเชิญร่วมทริป
+// คืน false ถ้าไม่ได้ตั้งค่า SMTP
+func (s *NotificationService) QueueEmail(to, subject, htmlBody string) (bool, error) {
+	if !EmailConfigured() {
+		return false, nil
+	}
+	entry := s.newOutbox("email", to, subject, htmlBody)
+	if err := s.db.Create(&entry).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *NotificationService) newOutbox(channel, recipient, subject, body string) models.NotificationOutbox {
+	return models.NotificationOutbox{
+		Channel:       channel,
+		Recipient:     recipient,
+		Subject:       subject,
+		Body:          body,
+		Status:        "pending",
+		MaxAttempts:   defaultOutboxMaxAttempts,
+		NextAttemptAt: time.Now(),
+	}
+}
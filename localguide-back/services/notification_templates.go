@@ -0,0 +1,153 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+const defaultNotificationLanguage = "th"
+
+type notificationTemplate struct {
+	Title   string
+	Message string
+}
+
+// notificationTemplates - ข้อความแจ้งเตือนตามประเภท แยกตามภาษา (th, en)
+// data ที่ใช้ได้ดูจากจุดที่เรียก NotificationService.Send
+var notificationTemplates = map[string]map[string]notificationTemplate{
+	"offer_received": {
+		"th": {"มีข้อเสนอใหม่", `คุณได้รับข้อเสนอ "{{.OfferTitle}}" สำหรับทริป "{{.TripTitle}}"`},
+		"en": {"New offer", `You have received a new offer "{{.OfferTitle}}" for your trip "{{.TripTitle}}".`},
+	},
+	"offer_withdrawn": {
+		"th": {"ไกด์ถอนข้อเสนอ", `ไกด์ได้ถอนข้อเสนอ "{{.OfferTitle}}" สำหรับทริป "{{.TripTitle}}"{{if .Reason}} เหตุผล: {{.Reason}}{{end}}`},
+		"en": {"Offer withdrawn", `A guide has withdrawn their offer "{{.OfferTitle}}" for your trip "{{.TripTitle}}".{{if .Reason}} Reason: {{.Reason}}{{end}}`},
+	},
+	"offer_accepted": {
+		"th": {"ข้อเสนอได้รับการยอมรับ", `ลูกค้ายอมรับข้อเสนอ "{{.OfferTitle}}" แล้ว (booking #{{.BookingID}}) รอการชำระเงิน`},
+		"en": {"Offer accepted", `Your offer "{{.OfferTitle}}" has been accepted (booking #{{.BookingID}}). Waiting for payment.`},
+	},
+	"payment_succeeded": {
+		"th": {"ชำระเงินสำเร็จ", `ชำระเงิน {{printf "%.2f" .Amount}} บาท สำหรับ booking #{{.BookingID}} สำเร็จแล้ว`},
+		"en": {"Payment successful", `Payment of {{printf "%.2f" .Amount}} THB for booking #{{.BookingID}} was successful.`},
+	},
+	"booking_paid": {
+		"th": {"ลูกค้าชำระเงินแล้ว", `booking #{{.BookingID}} ชำระเงินเรียบร้อยแล้ว เตรียมพบลูกค้าในวันที่ {{.StartDate}}`},
+		"en": {"Booking paid", `Booking #{{.BookingID}} has been paid. Your trip starts on {{.StartDate}}.`},
+	},
+	"user_no_show_reported": {
+		"th": {"ไกด์รายงานว่าคุณไม่มาตามนัด", `ไกด์รายงานว่าคุณไม่มาตามนัดใน booking #{{.BookingID}} หากไม่ถูกต้องสามารถโต้แย้งได้`},
+		"en": {"No-show reported", `Your guide reported that you did not show up for booking #{{.BookingID}}. You can dispute this report.`},
+	},
+	"no_show_disputed": {
+		"th": {"ลูกค้าโต้แย้งรายงานไม่มาตามนัด", `ลูกค้าโต้แย้งรายงานไม่มาตามนัดของ booking #{{.BookingID}} กรุณาส่งคำชี้แจงและหลักฐานภายใน {{.Deadline}} มิฉะนั้นระบบจะคืนเงินให้ลูกค้าเต็มจำนวน`},
+		"en": {"No-show report disputed", `The traveller disputed your no-show report for booking #{{.BookingID}}. Submit your statement and evidence by {{.Deadline}}, otherwise the traveller will be refunded in full.`},
+	},
+	"dispute_statement_added": {
+		"th": {"มีคำชี้แจงใหม่ในข้อพิพาท", `อีกฝ่ายได้ส่งคำชี้แจงใน dispute ของ booking #{{.BookingID}} คุณส่งคำชี้แจงเพิ่มเติมได้ถึง {{.Deadline}}`},
+		"en": {"New dispute statement", `The other party added a statement to the dispute for booking #{{.BookingID}}. You can respond until {{.Deadline}}.`},
+	},
+	"dispute_awaiting_decision": {
+		"th": {"ข้อพิพาทรอการตัดสิน", `ข้อพิพาท no-show ของ booking #{{.BookingID}} ปิดรับหลักฐานแล้ว รอ admin ตัดสิน`},
+		"en": {"Dispute awaiting decision", `The no-show dispute for booking #{{.BookingID}} has closed for evidence and needs an admin decision.`},
+	},
+	"guide_no_show_reported": {
+		"th": {"ลูกค้ารายงานว่าคุณไม่มาตามนัด", `ลูกค้ารายงานว่าคุณไม่มาตามนัดใน booking #{{.BookingID}}`},
+		"en": {"No-show reported", `The traveller reported that you did not show up for booking #{{.BookingID}}.`},
+	},
+	"dispute_resolved": {
+		"th": {"ข้อพิพาทได้รับการตัดสินแล้ว", `ข้อพิพาทของ booking #{{.BookingID}} ได้รับการตัดสินแล้ว ({{.Decision}}){{if .Rationale}} เหตุผล: {{.Rationale}}{{end}}`},
+		"en": {"Dispute resolved", `The dispute for booking #{{.BookingID}} has been resolved ({{.Decision}}).{{if .Rationale}} Rationale: {{.Rationale}}{{end}}`},
+	},
+	"report_critical": {
+		"th": {"รีพอร์ตร้ายแรงต้องตรวจสอบทันที", `มีรีพอร์ต {{.ReportType}} ระดับ critical ใน booking #{{.BookingID}}: {{.Title}}{{if .PayoutsFrozen}} (ระงับการจ่ายไกด์แล้ว){{end}}`},
+		"en": {"Critical report needs attention", `A critical {{.ReportType}} report was filed for booking #{{.BookingID}}: {{.Title}}{{if .PayoutsFrozen}} (guide payouts frozen){{end}}`},
+	},
+	"report_assigned": {
+		"th": {"คุณได้รับมอบหมายเคสใหม่", `เคส #{{.ReportID}} ({{.Severity}}) ของ booking #{{.BookingID}}: {{.Title}}`},
+		"en": {"Case assigned to you", `Case #{{.ReportID}} ({{.Severity}}) for booking #{{.BookingID}}: {{.Title}}`},
+	},
+	"report_updated": {
+		"th": {"รีพอร์ตของคุณมีความคืบหน้า", `รีพอร์ต "{{.Title}}" ของ booking #{{.BookingID}} เปลี่ยนสถานะเป็น {{.Status}}`},
+		"en": {"Report updated", `Your report "{{.Title}}" for booking #{{.BookingID}} is now {{.Status}}.`},
+	},
+	"review_posted": {
+		"th": {"มีรีวิวใหม่", `คุณได้รับรีวิว {{.Rating}} ดาวจาก booking #{{.BookingID}}`},
+		"en": {"New review", `You received a {{.Rating}}-star review for booking #{{.BookingID}}.`},
+	},
+	"review_hidden": {
+		"th": {"รีวิวถูกซ่อน", `รีวิวของคุณสำหรับ booking #{{.BookingID}} ถูกซ่อนโดยผู้ดูแล เหตุผล: {{.Reason}}`},
+		"en": {"Review hidden", `Your review for booking #{{.BookingID}} has been hidden by a moderator. Reason: {{.Reason}}`},
+	},
+	"review_restored": {
+		"th": {"รีวิวแสดงอีกครั้ง", `รีวิวของคุณสำหรับ booking #{{.BookingID}} แสดงอีกครั้งแล้ว`},
+		"en": {"Review restored", `Your review for booking #{{.BookingID}} is visible again.`},
+	},
+	"booking_invite": {
+		"th": {"คำเชิญร่วมทริป", `คุณได้รับเชิญให้ร่วมเดินทางใน booking #{{.BookingID}}`},
+		"en": {"Trip invitation", `You have been invited to join trip booking #{{.BookingID}}.`},
+	},
+	"booking_invite_accepted": {
+		"th": {"ตอบรับคำเชิญแล้ว", `{{.Email}} ตอบรับคำเชิญร่วมทริป booking #{{.BookingID}}`},
+		"en": {"Invitation accepted", `{{.Email}} accepted your invitation for booking #{{.BookingID}}.`},
+	},
+	"booking_invite_declined": {
+		"th": {"ปฏิเสธคำเชิญ", `{{.Email}} ปฏิเสธคำเชิญร่วมทริป booking #{{.BookingID}}`},
+		"en": {"Invitation declined", `{{.Email}} declined your invitation for booking #{{.BookingID}}.`},
+	},
+	"early_completion_requested": {
+		"th": {"ขอจบทริปก่อนกำหนด", `อีกฝ่ายขอจบทริป booking #{{.BookingID}} ก่อนกำหนด{{if .Reason}} เหตุผล: {{.Reason}}{{end}}`},
+		"en": {"Early completion requested", `The other party has asked to end booking #{{.BookingID}} early.{{if .Reason}} Reason: {{.Reason}}{{end}}`},
+	},
+	"early_completion_agreed": {
+		"th": {"ยอมรับการจบทริปก่อนกำหนด", `อีกฝ่ายยอมรับการจบทริป booking #{{.BookingID}} ก่อนกำหนดแล้ว`},
+		"en": {"Early completion agreed", `Early completion of booking #{{.BookingID}} has been agreed.`},
+	},
+	"guide_checked_in": {
+		"th": {"ทริปเริ่มแล้ว", `ไกด์เช็คอินสำหรับ booking #{{.BookingID}} แล้ว ทริปได้เริ่มต้นขึ้น`},
+		"en": {"Trip started", `Your guide has checked in for booking #{{.BookingID}}. The trip has started.`},
+	},
+	"trip_auto_completed": {
+		"th": {`{{if .Guide}}โอนเงินให้ไกด์แล้ว{{else}}ทริปจบอัตโนมัติ{{end}}`, `{{if .Guide}}booking #{{.BookingID}} จบอัตโนมัติและโอนเงิน {{printf "%.2f" .Amount}} บาทให้คุณแล้ว{{else}}booking #{{.BookingID}} จบอัตโนมัติเนื่องจากไม่ได้ยืนยันหลังจบทริป{{end}}`},
+		"en": {`{{if .Guide}}Payout released{{else}}Trip completed automatically{{end}}`, `{{if .Guide}}Booking #{{.BookingID}} was completed automatically and {{printf "%.2f" .Amount}} THB has been released to you.{{else}}Booking #{{.BookingID}} was completed automatically because it was not confirmed after the trip ended.{{end}}`},
+	},
+	"new_message": {
+		"th": {"ข้อความใหม่", `คุณมีข้อความใหม่`},
+		"en": {"New message", `You have a new message.`},
+	},
+}
+
+// RenderNotification สร้างหัวข้อและข้อความของการแจ้งเตือนตามภาษา (ไม่มีภาษาที่ขอจะใช้ภาษาไทย)
+func RenderNotification(notifType, lang string, data map[string]interface{}) (string, string, error) {
+	byLang, ok := notificationTemplates[notifType]
+	if !ok {
+		return "", "", fmt.Errorf("no template for notification type %q", notifType)
+	}
+	tmpl, ok := byLang[lang]
+	if !ok {
+		tmpl = byLang[defaultNotificationLanguage]
+	}
+
+	title, err := renderTemplateString(tmpl.Title, data)
+	if err != nil {
+		return "", "", err
+	}
+	message, err := renderTemplateString(tmpl.Message, data)
+	if err != nil {
+		return "", "", err
+	}
+	return title, message, nil
+}
+
+func renderTemplateString(text string, data map[string]interface{}) (string, error) {
+	t, err := template.New("").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
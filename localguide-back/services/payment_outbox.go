@@ -0,0 +1,368 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	paymentOutboxMaxAttempts = 8
+	paymentOutboxBatchSize   = 50
+	paymentOutboxRetryBase   = time.Minute
+)
+
+// PaymentGateway - การเรียก Stripe ที่ PaymentOutboxWorker ใช้ (StripeService implement ไว้ แยกเป็น interface เพื่อทดสอบ)
+type PaymentGateway interface {
+	CreateRefund(paymentIntentID string, amount int64, idempotencyKey string) (string, error)
+	CreateTransfer(destination string, amount int64, transferGroup, idempotencyKey string) (string, error)
+}
+
+func toCents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+const manualRefundNote = "manual refund required"
+
+// QueueRefund บันทึก release คืนเงินให้ user เป็น pending พร้อมคำสั่งคืนเงินใน outbox (ต้องเรียกภายใน tx เดียวกับการเปลี่ยนสถานะ booking/payment)
+// booking แยกจ่ายจะแบ่งคืนเข้า PaymentIntent ของผู้ร่วมเดินทางแต่ละคนตามสัดส่วนที่จ่าย (หนึ่งคำสั่งต่อคน)
+// ถ้าหา PaymentIntent ที่คืนเงินได้ไม่ครบ จะไม่เข้าคิว release ค้างเป็น pending และหมายเหตุให้ admin คืนเงินเอง
+func QueueRefund(tx *gorm.DB, payment *models.TripPayment, release *models.PaymentRelease) (*models.PaymentOutbox, error) {
+	release.Status = "pending"
+	release.ProcessedAt = nil
+
+	var shares []refundShare
+	var manualReason string
+	if payment.PaymentMethod == "stripe_split" {
+		var err error
+		shares, manualReason, err = splitRefundShares(tx, payment, toCents(release.Amount))
+		if err != nil {
+			return nil, err
+		}
+	} else if payment.StripePaymentIntentID == "" {
+		manualReason = "payment has no Stripe PaymentIntent"
+	}
+
+	if manualReason != "" {
+		note := manualRefundNote + ": " + manualReason
+		release.Notes = appendNote(release.Notes, note)
+		payment.Notes = appendNote(payment.Notes, note)
+		if err := tx.Create(release).Error; err != nil {
+			return nil, fmt.Errorf("failed to create refund record: %w", err)
+		}
+		if err := tx.Model(&models.TripPayment{}).Where("id = ?", payment.ID).Update("notes", payment.Notes).Error; err != nil {
+			return nil, fmt.Errorf("failed to flag payment for manual refund: %w", err)
+		}
+		log.Printf("refund release #%d of payment #%d needs manual handling: %s", release.ID, payment.ID, manualReason)
+		return nil, nil
+	}
+
+	if err := tx.Create(release).Error; err != nil {
+		return nil, fmt.Errorf("failed to create refund record: %w", err)
+	}
+
+	if shares == nil {
+		return queuePaymentAction(tx, payment, release, "refund", payment.StripePaymentIntentID)
+	}
+
+	var first *models.PaymentOutbox
+	for _, share := range shares {
+		entry, err := queueShareRefund(tx, payment, release, share)
+		if err != nil {
+			return nil, err
+		}
+		if first == nil {
+			first = entry
+		}
+	}
+	return first, nil
+}
+
+// QueueOverpaymentRefund บันทึก release คืนยอดที่เก็บเกินเข้า PaymentIntent ของผู้ร่วมเดินทางคนเดียว
+// (release_type overpayment_refund ไม่นับเป็นการคืนเงินของ booking และไม่ออกใบลดหนี้)
+func QueueOverpaymentRefund(tx *gorm.DB, payment *models.TripPayment, participant *models.TripBookingParticipant, release *models.PaymentRelease) (*models.PaymentOutbox, error) {
+	release.ReleaseType = "overpayment_refund"
+	release.Status = "pending"
+	release.ProcessedAt = nil
+	if err := tx.Create(release).Error; err != nil {
+		return nil, fmt.Errorf("failed to create refund record: %w", err)
+	}
+	return queueShareRefund(tx, payment, release, refundShare{
+		ParticipantID:   participant.ID,
+		PaymentIntentID: participant.StripePaymentIntentID,
+		Cents:           toCents(release.Amount),
+	})
+}
+
+func queueShareRefund(tx *gorm.DB, payment *models.TripPayment, release *models.PaymentRelease, share refundShare) (*models.PaymentOutbox, error) {
+	participantID := share.ParticipantID
+	entry := models.PaymentOutbox{
+		TripPaymentID:            payment.ID,
+		PaymentReleaseID:         release.ID,
+		TripBookingParticipantID: &participantID,
+		Action:                   "refund",
+		AmountCents:              share.Cents,
+		Target:                   share.PaymentIntentID,
+		IdempotencyKey:           fmt.Sprintf("refund-release-%d-participant-%d", release.ID, participantID),
+		Status:                   "pending",
+		NextAttemptAt:            time.Now(),
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue refund: %w", err)
+	}
+	return &entry, nil
+}
+
+type refundShare struct {
+	ParticipantID   uint
+	PaymentIntentID string
+	Cents           int64
+}
+
+// splitRefundShares แบ่งเงินคืน (สตางค์) ตามสัดส่วนที่ผู้ร่วมเดินทางแต่ละคนจ่าย เศษสตางค์ให้คนแรกที่ยังคืนได้
+// คืนเหตุผลแทนถ้าแบ่งอัตโนมัติไม่ได้
+func splitRefundShares(tx *gorm.DB, payment *models.TripPayment, refundCents int64) ([]refundShare, string, error) {
+	var participants []models.TripBookingParticipant
+	if err := tx.Where("trip_booking_id = ? AND share_status = ?", payment.TripBookingID, "paid").
+		Order("id ASC").Find(&participants).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to get paid shares: %w", err)
+	}
+
+	paid := make([]int64, len(participants))
+	var total int64
+	for i, p := range participants {
+		if p.StripePaymentIntentID == "" {
+			return nil, fmt.Sprintf("share #%d has no PaymentIntent", p.ID), nil
+		}
+		amount := p.PaidAmount
+		if amount <= 0 && !p.CoversRemainder {
+			amount = p.ShareAmount
+		}
+		if amount <= 0 {
+			return nil, fmt.Sprintf("paid amount of share #%d is unknown", p.ID), nil
+		}
+		paid[i] = toCents(amount)
+		total += paid[i]
+	}
+	if total == 0 {
+		return nil, "no paid shares to refund", nil
+	}
+	if refundCents > total {
+		return nil, fmt.Sprintf("refund %d exceeds paid shares %d", refundCents, total), nil
+	}
+
+	shares := make([]refundShare, len(participants))
+	var assigned int64
+	for i, p := range participants {
+		cents := refundCents * paid[i] / total
+		shares[i] = refundShare{ParticipantID: p.ID, PaymentIntentID: p.StripePaymentIntentID, Cents: cents}
+		assigned += cents
+	}
+	for i := 0; assigned < refundCents && i < len(shares); i++ {
+		extra := paid[i] - shares[i].Cents
+		if extra > refundCents-assigned {
+			extra = refundCents - assigned
+		}
+		shares[i].Cents += extra
+		assigned += extra
+	}
+
+	result := shares[:0]
+	for _, share := range shares {
+		if share.Cents > 0 {
+			result = append(result, share)
+		}
+	}
+	return result, "", nil
+}
+
+func appendNote(notes, note string) string {
+	if notes == "" {
+		return note
+	}
+	return notes + " | " + note
+}
+
+// QueueGuidePayout บันทึก release ให้ไกด์ (ทุกการจ่ายไกด์ต้องผ่านฟังก์ชันนี้ภายใน tx เดียวกับการเปลี่ยนสถานะ)
+// ถ้าไกด์มีบัญชี Stripe Connect จะเป็น pending และเข้าคิวโอนเงิน worker จะเปลี่ยนเป็น processed เมื่อโอนสำเร็จ
+// ถ้าไม่มี (จ่ายนอกระบบ) จะบันทึกเป็น processed ทันที
+func QueueGuidePayout(tx *gorm.DB, payment *models.TripPayment, release *models.PaymentRelease) (*models.PaymentOutbox, error) {
+	var guide models.Guide
+	if err := tx.Select("id", "stripe_account_id").First(&guide, release.RecipientID).Error; err != nil {
+		return nil, fmt.Errorf("guide not found: %w", err)
+	}
+
+	if guide.StripeAccountID == "" {
+		if release.ProcessedAt == nil {
+			now := time.Now()
+			release.ProcessedAt = &now
+		}
+		release.Status = "processed"
+		if err := HoldIfPayoutsFrozen(tx, release); err != nil {
+			return nil, err
+		}
+		if err := tx.Create(release).Error; err != nil {
+			return nil, fmt.Errorf("failed to create guide payment release: %w", err)
+		}
+		return nil, nil
+	}
+
+	release.Status = "pending"
+	release.ProcessedAt = nil
+	if err := tx.Create(release).Error; err != nil {
+		return nil, fmt.Errorf("failed to create guide payment release: %w", err)
+	}
+
+	return queuePaymentAction(tx, payment, release, "transfer", guide.StripeAccountID)
+}
+
+func queuePaymentAction(tx *gorm.DB, payment *models.TripPayment, release *models.PaymentRelease, action, target string) (*models.PaymentOutbox, error) {
+	entry := models.PaymentOutbox{
+		TripPaymentID:    payment.ID,
+		PaymentReleaseID: release.ID,
+		Action:           action,
+		AmountCents:      toCents(release.Amount),
+		Target:           target,
+		IdempotencyKey:   fmt.Sprintf("%s-release-%d", action, release.ID),
+		Status:           "pending",
+		NextAttemptAt:    time.Now(),
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to queue %s: %w", action, err)
+	}
+	return &entry, nil
+}
+
+// PaymentOutboxWorker ทำคำสั่งใน PaymentOutbox กับ Stripe แล้วอัปเดตผลกลับไปที่ PaymentRelease
+// ใช้ idempotency key เดิมทุกครั้งที่ลองใหม่ จึงไม่คืนเงิน/โอนเงินซ้ำแม้ process ตายหลังเรียก Stripe สำเร็จ
+type PaymentOutboxWorker struct {
+	db      *gorm.DB
+	gateway PaymentGateway
+}
+
+func NewPaymentOutboxWorker(db *gorm.DB, gateway PaymentGateway) *PaymentOutboxWorker {
+	return &PaymentOutboxWorker{db: db, gateway: gateway}
+}
+
+// Start รัน worker ทุก interval จนกว่าโปรแกรมจะปิด
+func (w *PaymentOutboxWorker) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := w.RunOnce(time.Now()); err != nil {
+				log.Printf("payment outbox failed: %v", err)
+			} else if n > 0 {
+				log.Printf("payment outbox: completed %d action(s)", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// RunOnce ทำคำสั่งที่ถึงเวลาทั้งหมด คืนจำนวนที่สำเร็จ (ข้ามการโอนเงินของ payment ที่ถูกระงับการจ่าย)
+func (w *PaymentOutboxWorker) RunOnce(now time.Time) (int, error) {
+	var entries []models.PaymentOutbox
+	if err := w.db.Where("status = ? AND next_attempt_at <= ?", "pending", now).
+		Where("NOT (action = ? AND trip_payment_id IN (?))", "transfer",
+			w.db.Model(&models.TripPayment{}).Select("id").Where("payouts_frozen = ?", true)).
+		Order("id ASC").
+		Limit(paymentOutboxBatchSize).
+		Find(&entries).Error; err != nil {
+		return 0, fmt.Errorf("failed to get payment outbox: %w", err)
+	}
+
+	succeeded := 0
+	for i := range entries {
+		ok, err := w.process(&entries[i], now)
+		if err != nil {
+			return succeeded, err
+		}
+		if ok {
+			succeeded++
+		}
+	}
+	return succeeded, nil
+}
+
+func (w *PaymentOutboxWorker) process(entry *models.PaymentOutbox, now time.Time) (bool, error) {
+	var ref string
+	var callErr error
+	switch entry.Action {
+	case "refund":
+		ref, callErr = w.gateway.CreateRefund(entry.Target, entry.AmountCents, entry.IdempotencyKey)
+	case "transfer":
+		group := fmt.Sprintf("payment-%d", entry.TripPaymentID)
+		ref, callErr = w.gateway.CreateTransfer(entry.Target, entry.AmountCents, group, entry.IdempotencyKey)
+	default:
+		callErr = fmt.Errorf("unknown payment action %q", entry.Action)
+	}
+
+	entry.Attempts++
+	err := w.db.Transaction(func(tx *gorm.DB) error {
+		if callErr == nil {
+			entry.Status = "succeeded"
+			entry.ExternalRef = ref
+			entry.LastError = ""
+			entry.ProcessedAt = &now
+			if err := tx.Save(entry).Error; err != nil {
+				return err
+			}
+
+			// คืนเงินแบบแบ่งหลายคำสั่ง release จะ processed เมื่อทุกคำสั่งสำเร็จ
+			var parts []models.PaymentOutbox
+			if err := tx.Where("payment_release_id = ?", entry.PaymentReleaseID).Order("id ASC").Find(&parts).Error; err != nil {
+				return err
+			}
+			refs := make([]string, 0, len(parts))
+			for _, part := range parts {
+				if part.Status != "succeeded" {
+					return nil
+				}
+				refs = append(refs, part.ExternalRef)
+			}
+			return tx.Model(&models.PaymentRelease{}).Where("id = ?", entry.PaymentReleaseID).Updates(map[string]interface{}{
+				"status":          "processed",
+				"processed_at":    &now,
+				"transaction_ref": strings.Join(refs, ","),
+			}).Error
+		}
+
+		entry.LastError = callErr.Error()
+		if entry.Attempts < paymentOutboxMaxAttempts {
+			entry.NextAttemptAt = now.Add(paymentOutboxRetryBase * time.Duration(1<<(entry.Attempts-1)))
+			return tx.Save(entry).Error
+		}
+
+		// ลองครบแล้ว ให้ admin ตรวจสอบ
+		entry.Status = "failed"
+		if err := tx.Save(entry).Error; err != nil {
+			return err
+		}
+		log.Printf("payment outbox #%d (%s) failed after %d attempts: %v", entry.ID, entry.Action, entry.Attempts, callErr)
+		return tx.Model(&models.PaymentRelease{}).Where("id = ?", entry.PaymentReleaseID).Updates(map[string]interface{}{
+			"status": "failed",
+			"notes":  gorm.Expr("notes || ?", " | "+entry.Action+" failed: "+callErr.Error()),
+		}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record result of payment outbox #%d: %w", entry.ID, err)
+	}
+
+	// คืนเงินสำเร็จ -> ออกใบลดหนี้
+	if callErr == nil && entry.Action == "refund" {
+		var payment models.TripPayment
+		if err := w.db.Select("id", "trip_booking_id").First(&payment, entry.TripPaymentID).Error; err == nil {
+			if _, err := IssueInvoices(w.db, payment.TripBookingID, now); err != nil {
+				log.Printf("failed to issue credit note for payment outbox #%d: %v", entry.ID, err)
+			}
+		}
+	}
+
+	return callErr == nil, nil
+}
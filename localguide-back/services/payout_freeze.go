@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const payoutHeldNote = "held: payouts frozen"
+
+// FreezePayouts ระงับการจ่ายไกด์ของ payment รายการจ่ายไกด์ที่เกิดขึ้นหลังจากนี้จะค้างเป็น pending
+// และคำสั่งโอนเงินใน outbox จะไม่ถูกทำจนกว่าจะ UnfreezePayouts
+func FreezePayouts(tx *gorm.DB, paymentID uint, reason string, now time.Time) error {
+	return tx.Model(&models.TripPayment{}).Where("id = ?", paymentID).Updates(map[string]interface{}{
+		"payouts_frozen":        true,
+		"payouts_frozen_reason": reason,
+		"payouts_frozen_at":     &now,
+	}).Error
+}
+
+// UnfreezePayouts ยกเลิกการระงับ รายการจ่ายนอกระบบที่ค้างไว้จะถูกบันทึกว่าจ่ายแล้ว ส่วนที่โอนผ่าน Stripe outbox จะทำต่อเอง
+// คืนจำนวนรายการที่ปล่อย
+func UnfreezePayouts(tx *gorm.DB, paymentID uint, now time.Time) (int64, error) {
+	if err := tx.Model(&models.TripPayment{}).Where("id = ?", paymentID).Updates(map[string]interface{}{
+		"payouts_frozen":        false,
+		"payouts_frozen_reason": "",
+		"payouts_frozen_at":     nil,
+	}).Error; err != nil {
+		return 0, err
+	}
+
+	result := tx.Model(&models.PaymentRelease{}).
+		Where("trip_payment_id = ? AND recipient_type = ? AND status = ? AND notes LIKE ?", paymentID, "guide", "pending", "%"+payoutHeldNote+"%").
+		Where("id NOT IN (?)", tx.Model(&models.PaymentOutbox{}).Select("payment_release_id")).
+		Updates(map[string]interface{}{
+			"status":       "processed",
+			"processed_at": &now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// HoldIfPayoutsFrozen เปลี่ยนรายการจ่ายไกด์ที่กำลังจะบันทึกเป็น pending ถ้า payment ถูกระงับการจ่าย
+func HoldIfPayoutsFrozen(tx *gorm.DB, release *models.PaymentRelease) error {
+	if release.RecipientType != "guide" {
+		return nil
+	}
+	var frozen []bool
+	if err := tx.Model(&models.TripPayment{}).Where("id = ?", release.TripPaymentID).Pluck("payouts_frozen", &frozen).Error; err != nil {
+		return fmt.Errorf("failed to check payout freeze: %w", err)
+	}
+	if len(frozen) == 0 || !frozen[0] {
+		return nil
+	}
+
+	release.Status = "pending"
+	release.ProcessedAt = nil
+	if release.Notes == "" {
+		release.Notes = payoutHeldNote
+	} else {
+		release.Notes += " | " + payoutHeldNote
+	}
+	return nil
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"localguide-back/models"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// PrivateFileURLPrefix - URL ของไฟล์ส่วนตัว เปิดได้เมื่อมีลายเซ็น (ดู SignedURL) ไม่ได้ชี้ไปที่ storage โดยตรง
+	PrivateFileURLPrefix = "/api/files/"
+	// privateKeyPrefix - key ของไฟล์ส่วนตัวใน storage (static route และ bucket policy ต้องไม่เปิด prefix นี้)
+	privateKeyPrefix = "private/"
+
+	defaultSignedFileURLMinutes = 5
+)
+
+// privateUploadPurposes - วัตถุประสงค์ที่เก็บเป็นไฟล์ส่วนตัว
+var privateUploadPurposes = map[string]bool{"evidence": true, "licence_document": true}
+
+// identityDocumentPurposes - เอกสารยืนยันตัวตน ต้องบันทึกการเข้าถึงทุกครั้ง
+var identityDocumentPurposes = map[string]bool{"licence_document": true}
+
+// IsIdentityDocument - ไฟล์เป็นเอกสารยืนยันตัวตนหรือไม่
+func IsIdentityDocument(upload *models.Upload) bool {
+	return identityDocumentPurposes[upload.Purpose]
+}
+
+// SignedFileURLTTL - อายุของ signed URL (FILE_URL_TTL_MINUTES, ค่าเริ่มต้น 5 นาที)
+func SignedFileURLTTL() time.Duration {
+	minutes := defaultSignedFileURLMinutes
+	if v, err := strconv.Atoi(os.Getenv("FILE_URL_TTL_MINUTES")); err == nil && v > 0 {
+		minutes = v
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+func (s *UploadService) fileSignature(key string, userID uint, expires int64) string {
+	mac := hmac.New(sha256.New, s.SigningKey)
+	fmt.Fprintf(mac, "%s|%d|%d", key, userID, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedURL สร้าง URL ชั่วคราวของไฟล์ส่วนตัวสำหรับผู้ใช้ที่ตรวจสิทธิ์แล้ว (ผูกกับ userID เพื่อบันทึกว่าใครเปิด)
+func (s *UploadService) SignedURL(upload *models.Upload, userID uint, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+	return fmt.Sprintf("%s%s?expires=%d&uid=%d&sig=%s", PrivateFileURLPrefix, upload.Key, expires, userID,
+		s.fileSignature(upload.Key, userID, expires))
+}
+
+// VerifySignedURL ตรวจลายเซ็นและเวลาหมดอายุของ signed URL
+func (s *UploadService) VerifySignedURL(key string, userID uint, expires int64, sig string, now time.Time) bool {
+	if len(s.SigningKey) == 0 || now.Unix() > expires {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(s.fileSignature(key, userID, expires)))
+}
+
+// FindPrivateByKey หาไฟล์ส่วนตัวจาก key ใน signed URL
+func (s *UploadService) FindPrivateByKey(key string) (*models.Upload, error) {
+	var upload models.Upload
+	if err := s.db.Where("key = ? AND private = ?", key, true).First(&upload).Error; err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// CanAccess - ผู้ใช้เปิดไฟล์นี้ได้หรือไม่: เจ้าของ, admin, คู่สนทนาของข้อความที่แนบไฟล์
+// หรือ user/ไกด์ของ booking ที่มีรีพอร์ตหรือคำชี้แจงใน dispute อ้างถึงไฟล์
+// เอกสารยืนยันตัวตนเปิดได้เฉพาะเจ้าของและ admin
+func (s *UploadService) CanAccess(upload *models.Upload, userID uint) (bool, error) {
+	if !upload.Private || upload.OwnerID == userID {
+		return true, nil
+	}
+	var user models.User
+	if err := s.db.Select("id", "role_id").First(&user, userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	if user.RoleID == 3 {
+		return true, nil
+	}
+	if IsIdentityDocument(upload) {
+		return false, nil
+	}
+
+	var conversations int64
+	if err := s.db.Model(&models.MessageAttachment{}).
+		Joins("JOIN messages ON messages.id = message_attachments.message_id").
+		Joins("JOIN conversations ON conversations.id = messages.conversation_id").
+		Where("message_attachments.url = ? AND (conversations.user_id = ? OR conversations.guide_user_id = ?)", upload.URL, userID, userID).
+		Count(&conversations).Error; err != nil {
+		return false, err
+	}
+	if conversations > 0 {
+		return true, nil
+	}
+
+	bookingIDs, err := s.evidenceBookingIDs(upload.URL)
+	if err != nil {
+		return false, err
+	}
+	if len(bookingIDs) == 0 {
+		return false, nil
+	}
+
+	var count int64
+	err = s.db.Model(&models.TripBooking{}).
+		Joins("JOIN guides ON guides.id = trip_bookings.guide_id").
+		Where("trip_bookings.id IN ? AND (trip_bookings.user_id = ? OR guides.user_id = ?)", bookingIDs, userID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// evidenceBookingIDs - booking ที่มีรีพอร์ตหรือคำชี้แจงใน dispute อ้างถึงไฟล์
+func (s *UploadService) evidenceBookingIDs(url string) ([]uint, error) {
+	pattern := "%" + url + "%"
+	var bookingIDs []uint
+	if err := s.db.Model(&models.TripReport{}).Where("evidence LIKE ?", pattern).
+		Pluck("trip_booking_id", &bookingIDs).Error; err != nil {
+		return nil, err
+	}
+	var disputeBookingIDs []uint
+	if err := s.db.Model(&models.DisputeStatement{}).
+		Joins("JOIN no_show_disputes ON no_show_disputes.id = dispute_statements.no_show_dispute_id").
+		Where("dispute_statements.evidence LIKE ?", pattern).
+		Pluck("no_show_disputes.trip_booking_id", &disputeBookingIDs).Error; err != nil {
+		return nil, err
+	}
+	return append(bookingIDs, disputeBookingIDs...), nil
+}
+
+// CanAttach - ผู้ใช้แนบ URL นี้ในข้อความของห้อง conversationID หรือหลักฐานของ booking bookingID ได้หรือไม่ (0 = ไม่ใช่บริบทนั้น)
+// การแนบไฟล์ทำให้คู่สนทนา/คู่กรณีเปิดไฟล์ได้ (ดู CanAccess) ไฟล์ส่วนตัวจึงต้องเป็นของผู้แนบเอง
+// หรือถูกแนบไว้แล้วในห้องหรือ booking เดียวกัน URL ที่ไม่ใช่ไฟล์ส่วนตัวแนบได้เสมอ
+func (s *UploadService) CanAttach(url string, userID, conversationID, bookingID uint) (bool, error) {
+	if !strings.HasPrefix(url, PrivateFileURLPrefix) {
+		return true, nil
+	}
+	upload, err := s.FindByURL(url)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	if !upload.Private || upload.OwnerID == userID {
+		return true, nil
+	}
+	if IsIdentityDocument(upload) {
+		return false, nil
+	}
+
+	if conversationID != 0 {
+		var count int64
+		if err := s.db.Model(&models.MessageAttachment{}).
+			Joins("JOIN messages ON messages.id = message_attachments.message_id").
+			Where("message_attachments.url = ? AND messages.conversation_id = ?", url, conversationID).
+			Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	if bookingID != 0 {
+		bookingIDs, err := s.evidenceBookingIDs(url)
+		if err != nil {
+			return false, err
+		}
+		for _, id := range bookingIDs {
+			if id == bookingID {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// privateUploadSources - คอลัมน์ที่เก็บ URL ของไฟล์ส่วนตัวกับคอลัมน์ผู้อัปโหลด ใช้หาไฟล์เดิมที่ยังไม่มี record ใน uploads
+var privateUploadSources = []struct {
+	uploadReference
+	Purpose string
+	Owner   string
+}{
+	{uploadReference{Table: "guide_vertifications", Column: "licence_document"}, "licence_document", "user_id"},
+	{uploadReference{Table: "trip_reports", Column: "evidence", JSON: true}, "evidence", "reporter_id"},
+	{uploadReference{Table: "dispute_statements", Column: "evidence", JSON: true}, "evidence", "author_id"},
+}
+
+// MigratePrivateUploads ย้ายหลักฐานและเอกสารยืนยันตัวตนที่อัปโหลดก่อนมีไฟล์ส่วนตัว (ยังเปิดได้จาก storage โดยตรง)
+// ไปไว้ใต้ private/ และเปลี่ยน URL ในทุกที่ที่อ้างถึง ไฟล์ที่ย้ายไม่สำเร็จจะลองใหม่ในการรันครั้งถัดไป คืนจำนวนไฟล์ที่ย้าย
+func (s *UploadService) MigratePrivateUploads() (int, error) {
+	if err := s.registerLegacyPrivateFiles(); err != nil {
+		return 0, err
+	}
+
+	var purposes []string
+	for purpose := range privateUploadPurposes {
+		purposes = append(purposes, purpose)
+	}
+	var uploads []models.Upload
+	if err := s.db.Where("purpose IN ? AND private = ?", purposes, false).Order("id").Find(&uploads).Error; err != nil {
+		return 0, fmt.Errorf("failed to get public uploads: %w", err)
+	}
+	moved := 0
+	for i := range uploads {
+		if err := s.makePrivate(&uploads[i]); err != nil {
+			log.Printf("upload #%d: failed to make private: %v", uploads[i].ID, err)
+			continue
+		}
+		moved++
+	}
+	return moved, nil
+}
+
+// registerLegacyPrivateFiles สร้าง record ใน uploads ให้ไฟล์หลักฐาน/เอกสารที่อัปโหลดก่อนมีตาราง uploads
+// (เจ้าของคือผู้สร้างข้อมูลที่อ้างถึงไฟล์) เพื่อให้ย้ายและเปิดผ่าน signed URL ได้
+func (s *UploadService) registerLegacyPrivateFiles() error {
+	for _, source := range privateUploadSources {
+		if !s.db.Migrator().HasTable(source.Table) {
+			continue
+		}
+		var rows []struct {
+			Owner uint
+			Value string
+		}
+		if err := s.db.Table(source.Table).
+			Select(source.Owner+" AS owner, "+source.Column+" AS value").
+			Where(source.Column+" LIKE ?", "%"+s.storage.URL("")+"%").
+			Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to read %s.%s: %w", source.Table, source.Column, err)
+		}
+		for _, row := range rows {
+			urls := []string{row.Value}
+			if source.JSON {
+				if err := json.Unmarshal([]byte(row.Value), &urls); err != nil {
+					urls = []string{row.Value}
+				}
+			}
+			for _, url := range urls {
+				if !strings.HasPrefix(url, s.storage.URL("")) || strings.Contains(url, "..") {
+					continue
+				}
+				var count int64
+				if err := s.db.Model(&models.Upload{}).Where("url = ?", url).Count(&count).Error; err != nil {
+					return err
+				}
+				if count > 0 {
+					continue
+				}
+				upload := models.Upload{
+					OwnerID: row.Owner,
+					Purpose: source.Purpose,
+					Backend: s.storage.Name(),
+					Key:     strings.TrimPrefix(url, s.storage.URL("")),
+					URL:     url,
+				}
+				if err := s.db.Create(&upload).Error; err != nil {
+					return fmt.Errorf("failed to register %s: %w", url, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// makePrivate คัดลอกไฟล์ไปไว้ใต้ private/ เปลี่ยน URL ใน uploads และข้อมูลที่อ้างถึง (uploadReferences) แล้วลบไฟล์เดิม
+func (s *UploadService) makePrivate(upload *models.Upload) error {
+	oldKey, oldURL := upload.Key, upload.URL
+	newKey := privateKeyPrefix + oldKey
+	newURL := PrivateFileURLPrefix + newKey
+
+	r, err := s.storage.Get(oldKey)
+	if err != nil {
+		return fmt.Errorf("failed to open stored file: %w", err)
+	}
+	data, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read stored file: %w", err)
+	}
+	contentType := upload.ContentType
+	if contentType == "" {
+		contentType = strings.TrimSpace(strings.Split(http.DetectContentType(data), ";")[0])
+	}
+	if err := s.storage.Put(newKey, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return fmt.Errorf("failed to store private copy: %w", err)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(upload).Updates(map[string]interface{}{
+			"key":          newKey,
+			"url":          newURL,
+			"private":      true,
+			"content_type": contentType,
+			"size":         int64(len(data)),
+		}).Error; err != nil {
+			return err
+		}
+		for _, ref := range uploadReferences {
+			if !tx.Migrator().HasTable(ref.Table) {
+				continue
+			}
+			q := tx.Table(ref.Table)
+			var err error
+			if ref.JSON {
+				err = q.Where(ref.Column+" LIKE ?", "%"+oldURL+"%").
+					Update(ref.Column, gorm.Expr("REPLACE("+ref.Column+", ?, ?)", oldURL, newURL)).Error
+			} else {
+				err = q.Where(ref.Column+" = ?", oldURL).Update(ref.Column, newURL).Error
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update %s.%s: %w", ref.Table, ref.Column, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.storage.Delete(newKey)
+		return err
+	}
+	if err := s.storage.Delete(oldKey); err != nil {
+		log.Printf("upload #%d: failed to delete public copy %s: %v", upload.ID, oldKey, err)
+	}
+	return nil
+}
+
+// LogFileAccess บันทึกการเข้าถึงเอกสารยืนยันตัวตน (ไฟล์ประเภทอื่นไม่บันทึก)
+func (s *UploadService) LogFileAccess(upload *models.Upload, userID uint, action, ip, userAgent string) error {
+	if !IsIdentityDocument(upload) {
+		return nil
+	}
+	return s.db.Create(&models.FileAccessLog{
+		UploadID:  upload.ID,
+		UserID:    userID,
+		Action:    action,
+		IPAddress: ip,
+		UserAgent: userAgent,
+	}).Error
+}
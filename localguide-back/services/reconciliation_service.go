@@ -0,0 +1,374 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PaymentLookup - อ่านข้อมูล PaymentIntent จาก Stripe (StripeService implement ไว้ แยกเป็น interface เพื่อทดสอบ)
+type PaymentLookup interface {
+	GetPaymentSnapshot(paymentIntentID string) (*PaymentSnapshot, error)
+}
+
+// สถานะ TripPayment ที่แปลว่าเก็บเงินได้แล้ว
+var collectedPaymentStatuses = map[string]bool{
+	"paid":               true,
+	"first_released":     true,
+	"fully_released":     true,
+	"partially_refunded": true,
+	"refunded":           true,
+}
+
+// ReconciliationSummary - ผลของการ reconcile หนึ่งรอบ
+type ReconciliationSummary struct {
+	Checked  int `json:"checked"`
+	Open     int `json:"open"`
+	Resolved int `json:"resolved"`
+}
+
+// ReconciliationService เทียบ TripPayment/PaymentRelease กับ PaymentIntent ใน Stripe แล้วบันทึกสิ่งที่ไม่ตรงเป็น ReconciliationIssue
+type ReconciliationService struct {
+	db     *gorm.DB
+	lookup PaymentLookup
+}
+
+func NewReconciliationService(db *gorm.DB, lookup PaymentLookup) *ReconciliationService {
+	return &ReconciliationService{db: db, lookup: lookup}
+}
+
+// Start รัน reconciliation ทุก interval จนกว่าโปรแกรมจะปิด
+func (s *ReconciliationService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if summary, err := s.RunOnce(time.Now()); err != nil {
+				log.Printf("stripe reconciliation failed: %v", err)
+			} else if summary.Open > 0 {
+				log.Printf("stripe reconciliation: %d payment(s) checked, %d open issue(s)", summary.Checked, summary.Open)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// RunOnce ตรวจทุก TripPayment ที่มี PaymentIntent ของ Stripe และทุก payment แบบแยกจ่าย (ตรวจจาก PaymentIntent ของผู้ร่วมเดินทาง)
+func (s *ReconciliationService) RunOnce(now time.Time) (*ReconciliationSummary, error) {
+	var payments []models.TripPayment
+	if err := s.db.Where("stripe_payment_intent_id LIKE ? OR payment_method = ?", "pi_%", "stripe_split").Order("id ASC").Find(&payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get payments: %w", err)
+	}
+
+	summary := &ReconciliationSummary{}
+	for i := range payments {
+		open, resolved, err := s.ReconcilePayment(&payments[i], now)
+		if err != nil {
+			return summary, err
+		}
+		summary.Checked++
+		summary.Open += open
+		summary.Resolved += resolved
+	}
+	return summary, nil
+}
+
+// ReconcilePayment ตรวจ payment เดียว คืนจำนวน issue ที่ยังเปิดอยู่ และจำนวนที่ปิดอัตโนมัติในรอบนี้
+func (s *ReconciliationService) ReconcilePayment(payment *models.TripPayment, now time.Time) (int, int, error) {
+	var found []models.ReconciliationIssue
+	snapshot, err := PaymentSnapshotFor(s.db, s.lookup, payment)
+	if err != nil {
+		found = []models.ReconciliationIssue{{IssueType: "stripe_error", Details: err.Error()}}
+	} else {
+		found = s.compare(payment, snapshot)
+	}
+
+	var open, resolved int
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var existing []models.ReconciliationIssue
+		if err := tx.Where("trip_payment_id = ? AND status = ?", payment.ID, "open").Find(&existing).Error; err != nil {
+			return err
+		}
+		byKey := make(map[string]*models.ReconciliationIssue, len(existing))
+		for i := range existing {
+			byKey[existing[i].IssueType+"|"+existing[i].ExternalRef] = &existing[i]
+		}
+
+		// admin ignore ไว้แล้ว ไม่ต้องเปิดใหม่
+		var ignored []models.ReconciliationIssue
+		if err := tx.Where("trip_payment_id = ? AND status = ?", payment.ID, "ignored").Find(&ignored).Error; err != nil {
+			return err
+		}
+		ignoredKeys := make(map[string]bool, len(ignored))
+		for _, issue := range ignored {
+			ignoredKeys[issue.IssueType+"|"+issue.ExternalRef] = true
+		}
+
+		for _, issue := range found {
+			key := issue.IssueType + "|" + issue.ExternalRef
+			if ignoredKeys[key] {
+				continue
+			}
+			open++
+			if current, ok := byKey[key]; ok {
+				delete(byKey, key)
+				if err := tx.Model(current).Updates(map[string]interface{}{
+					"local_value":     issue.LocalValue,
+					"stripe_value":    issue.StripeValue,
+					"details":         issue.Details,
+					"resyncable":      issue.Resyncable,
+					"last_checked_at": now,
+				}).Error; err != nil {
+					return err
+				}
+				continue
+			}
+
+			issue.TripPaymentID = payment.ID
+			issue.StripePaymentIntentID = payment.StripePaymentIntentID
+			issue.Status = "open"
+			issue.DetectedAt = now
+			issue.LastCheckedAt = now
+			if err := tx.Create(&issue).Error; err != nil {
+				return err
+			}
+		}
+
+		// ไม่เจอแล้วในรอบนี้ -> ปิดอัตโนมัติ
+		for _, stale := range byKey {
+			if err := tx.Model(stale).Updates(map[string]interface{}{
+				"status":          "resolved",
+				"resolved_at":     &now,
+				"last_checked_at": now,
+			}).Error; err != nil {
+				return err
+			}
+			resolved++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to record reconciliation of payment #%d: %w", payment.ID, err)
+	}
+
+	return open, resolved, nil
+}
+
+// PaymentSnapshotFor ดึงข้อมูล Stripe ของ payment
+// payment แยกจ่ายรวมจาก PaymentIntent ของทุกส่วนแบ่งที่ต้องจ่าย (ส่วนแบ่งที่ผู้จองจ่ายแทนแล้วนับรวมใน PaymentIntent ของผู้จอง)
+// และหักยอดที่คืนเพราะเก็บเกินออก จึงเทียบกับยอดของ booking ได้ตรง
+func PaymentSnapshotFor(db *gorm.DB, lookup PaymentLookup, payment *models.TripPayment) (*PaymentSnapshot, error) {
+	if payment.PaymentMethod != "stripe_split" {
+		return lookup.GetPaymentSnapshot(payment.StripePaymentIntentID)
+	}
+
+	var participants []models.TripBookingParticipant
+	if err := db.Where("trip_booking_id = ? AND share_status IN ?", payment.TripBookingID, []string{"pending", "paid"}).
+		Order("id ASC").Find(&participants).Error; err != nil {
+		return nil, fmt.Errorf("failed to get participant shares: %w", err)
+	}
+
+	var overpayments []models.PaymentRelease
+	if err := db.Where("trip_payment_id = ? AND release_type = ? AND status <> ?", payment.ID, "overpayment_refund", "failed").
+		Find(&overpayments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get overpayment refunds: %w", err)
+	}
+	overpaymentRefs := make(map[string]bool)
+	combined := &PaymentSnapshot{Status: "succeeded"}
+	for _, r := range overpayments {
+		combined.CapturedCents -= toCents(r.Amount)
+		for _, ref := range strings.Split(r.TransactionRef, ",") {
+			overpaymentRefs[ref] = true
+		}
+	}
+
+	unpaid := ""
+	covered := false
+	for _, p := range participants {
+		if p.StripePaymentIntentID == "" {
+			if unpaid == "" {
+				unpaid = "requires_payment_method"
+			}
+			continue
+		}
+		snapshot, err := lookup.GetPaymentSnapshot(p.StripePaymentIntentID)
+		if err != nil {
+			return nil, err
+		}
+		combined.AmountCents += snapshot.AmountCents
+		if snapshot.Status == "succeeded" {
+			combined.CapturedCents += snapshot.CapturedCents
+			covered = covered || p.CoversRemainder
+		} else if unpaid == "" {
+			unpaid = snapshot.Status
+		}
+		for _, r := range snapshot.Refunds {
+			if overpaymentRefs[r.ID] {
+				continue
+			}
+			combined.RefundedCents += r.AmountCents
+			combined.Refunds = append(combined.Refunds, r)
+		}
+	}
+
+	switch {
+	case len(participants) == 0:
+		combined.Status = "requires_payment_method"
+	case unpaid != "" && !covered:
+		combined.Status = unpaid
+	}
+	if combined.CapturedCents < 0 {
+		combined.CapturedCents = 0
+	}
+	return combined, nil
+}
+
+func formatCents(cents int64) string {
+	return fmt.Sprintf("%.2f", float64(cents)/100)
+}
+
+func (s *ReconciliationService) compare(payment *models.TripPayment, snapshot *PaymentSnapshot) []models.ReconciliationIssue {
+	var issues []models.ReconciliationIssue
+	succeeded := snapshot.Status == "succeeded"
+	collected := collectedPaymentStatuses[payment.Status]
+
+	// สถานะ
+	if succeeded && !collected {
+		issues = append(issues, models.ReconciliationIssue{
+			IssueType:   "status_mismatch",
+			LocalValue:  payment.Status,
+			StripeValue: snapshot.Status,
+			Details:     "Payment succeeded on Stripe but is not recorded as paid (missed webhook?)",
+			Resyncable:  payment.Status == "pending" || payment.Status == "failed",
+		})
+	} else if !succeeded && collected {
+		issues = append(issues, models.ReconciliationIssue{
+			IssueType:   "status_mismatch",
+			LocalValue:  payment.Status,
+			StripeValue: snapshot.Status,
+			Details:     "Payment is recorded as paid but the PaymentIntent has not succeeded on Stripe",
+		})
+	}
+
+	// ยอดที่เก็บได้
+	if succeeded && snapshot.CapturedCents != toCents(payment.TotalAmount) {
+		issues = append(issues, models.ReconciliationIssue{
+			IssueType:   "amount_mismatch",
+			LocalValue:  fmt.Sprintf("%.2f", payment.TotalAmount),
+			StripeValue: formatCents(snapshot.CapturedCents),
+			Details:     "Captured amount on Stripe differs from the payment total",
+		})
+	}
+
+	// การคืนเงิน
+	var releases []models.PaymentRelease
+	s.db.Where("trip_payment_id = ? AND release_type = ? AND status = ?", payment.ID, "refund", "processed").Find(&releases)
+	var localRefunded int64
+	localRefs := make(map[string]bool)
+	for _, r := range releases {
+		localRefunded += toCents(r.Amount)
+		for _, ref := range transactionRefs(r) {
+			localRefs[ref] = true
+		}
+	}
+
+	// มีคำสั่งคืนเงินค้างใน outbox อยู่ worker จะบันทึกผลเอง ห้าม resync ซ้อน
+	var pendingRefunds int64
+	s.db.Model(&models.PaymentOutbox{}).Where("trip_payment_id = ? AND action = ? AND status = ?", payment.ID, "refund", "pending").Count(&pendingRefunds)
+
+	var stripeRefunded int64
+	stripeRefs := make(map[string]bool)
+	perRefund := 0
+	for _, r := range snapshot.Refunds {
+		if r.Status == "failed" || r.Status == "canceled" {
+			continue
+		}
+		stripeRefunded += r.AmountCents
+		stripeRefs[r.ID] = true
+		if !localRefs[r.ID] {
+			details := "Refund exists on Stripe but has no PaymentRelease record"
+			if pendingRefunds > 0 {
+				details += "; a queued refund for this payment is still pending"
+			}
+			issues = append(issues, models.ReconciliationIssue{
+				IssueType:   "unrecorded_refund",
+				ExternalRef: r.ID,
+				StripeValue: formatCents(r.AmountCents),
+				Details:     details,
+				Resyncable:  pendingRefunds == 0,
+			})
+			perRefund++
+		}
+	}
+	for _, r := range releases {
+		for _, ref := range transactionRefs(r) {
+			if stripeRefs[ref] || !strings.HasPrefix(ref, "re_") {
+				continue
+			}
+			issues = append(issues, models.ReconciliationIssue{
+				IssueType:   "missing_stripe_refund",
+				ExternalRef: ref,
+				LocalValue:  fmt.Sprintf("%.2f", r.Amount),
+				Details:     fmt.Sprintf("PaymentRelease #%d records a refund that Stripe does not have", r.ID),
+			})
+			perRefund++
+		}
+	}
+
+	if perRefund == 0 && localRefunded != stripeRefunded {
+		issues = append(issues, models.ReconciliationIssue{
+			IssueType:   "refund_total_mismatch",
+			LocalValue:  formatCents(localRefunded),
+			StripeValue: formatCents(stripeRefunded),
+			Details:     "Total refunded differs between PaymentRelease records and Stripe",
+		})
+	}
+
+	return issues
+}
+
+// transactionRefs - refund ID ของ release (คืนเงิน booking แยกจ่ายเก็บหลาย ID คั่นด้วย comma)
+func transactionRefs(release models.PaymentRelease) []string {
+	if release.TransactionRef == "" {
+		return nil
+	}
+	return strings.Split(release.TransactionRef, ",")
+}
+
+// RecordStripeRefund บันทึก refund ที่มีใน Stripe แต่ไม่มีในระบบ เป็น PaymentRelease และปรับยอดคืนเงินของ payment
+func RecordStripeRefund(tx *gorm.DB, payment *models.TripPayment, booking *models.TripBooking, refund RefundSnapshot, now time.Time) (*models.PaymentRelease, error) {
+	amount := float64(refund.AmountCents) / 100
+	release := models.PaymentRelease{
+		TripPaymentID:  payment.ID,
+		ReleaseType:    "refund",
+		Amount:         amount,
+		RecipientType:  "user",
+		RecipientID:    booking.UserID,
+		Reason:         "reconciliation",
+		ScheduledAt:    now,
+		ProcessedAt:    &now,
+		Status:         "processed",
+		TransactionRef: refund.ID,
+		Notes:          "Recorded from Stripe by reconciliation",
+	}
+	if err := tx.Create(&release).Error; err != nil {
+		return nil, fmt.Errorf("failed to create refund record: %w", err)
+	}
+
+	payment.RefundAmount += amount
+	payment.RefundedAt = &now
+	if toCents(payment.RefundAmount) >= toCents(payment.TotalAmount) {
+		payment.Status = "refunded"
+	} else if payment.Status == "paid" {
+		payment.Status = "partially_refunded"
+	}
+	if err := tx.Save(payment).Error; err != nil {
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	return &release, nil
+}
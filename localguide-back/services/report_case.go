@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"localguide-back/models"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReportSLA - เวลาที่ admin ต้องรับเรื่อง (ReviewedAt) และปิดเคส (ResolvedAt) นับจากวันที่รีพอร์ต
+type ReportSLA struct {
+	Response   time.Duration
+	Resolution time.Duration
+}
+
+var reportSLAs = map[string]ReportSLA{
+	"critical": {Response: time.Hour, Resolution: 24 * time.Hour},
+	"high":     {Response: 4 * time.Hour, Resolution: 72 * time.Hour},
+	"medium":   {Response: 24 * time.Hour, Resolution: 7 * 24 * time.Hour},
+	"low":      {Response: 72 * time.Hour, Resolution: 14 * 24 * time.Hour},
+}
+
+// OpenReportStatuses - สถานะของเคสที่ยังไม่ปิด
+var OpenReportStatuses = []string{"pending", "investigating"}
+
+// SLAFor คืน SLA ของ severity (ค่าที่ไม่รู้จักใช้ของ medium)
+func SLAFor(severity string) ReportSLA {
+	if sla, ok := reportSLAs[severity]; ok {
+		return sla
+	}
+	return reportSLAs["medium"]
+}
+
+// CaseSLAStatus - สถานะ SLA ของเคส ณ เวลาที่ดู
+type CaseSLAStatus struct {
+	AgeHours           float64   `json:"age_hours"`
+	ResponseDueAt      time.Time `json:"response_due_at"`
+	ResolutionDueAt    time.Time `json:"resolution_due_at"`
+	ResponseBreached   bool      `json:"response_breached"`
+	ResolutionBreached bool      `json:"resolution_breached"`
+}
+
+// CaseSLA คำนวณกำหนดเวลาและการเกิน SLA ของรีพอร์ต เคสที่ปิดแล้วเทียบกับเวลาที่ปิด
+func CaseSLA(report *models.TripReport, now time.Time) CaseSLAStatus {
+	sla := SLAFor(report.Severity)
+	status := CaseSLAStatus{
+		ResponseDueAt:   report.CreatedAt.Add(sla.Response),
+		ResolutionDueAt: report.CreatedAt.Add(sla.Resolution),
+	}
+
+	end := now
+	if report.ResolvedAt != nil {
+		end = *report.ResolvedAt
+	}
+	status.AgeHours = math.Round(end.Sub(report.CreatedAt).Hours()*10) / 10
+
+	responded := end
+	if report.ReviewedAt != nil {
+		responded = *report.ReviewedAt
+	}
+	status.ResponseBreached = responded.After(status.ResponseDueAt)
+	status.ResolutionBreached = end.After(status.ResolutionDueAt)
+	return status
+}
+
+// OverdueReportCondition - เงื่อนไข SQL ของเคสที่ยังไม่ปิดและเกินกำหนดปิดเคสแล้ว
+func OverdueReportCondition(now time.Time) (string, []interface{}) {
+	cond := "CASE severity"
+	args := []interface{}{}
+	for _, severity := range []string{"critical", "high", "low"} {
+		cond += " WHEN ? THEN ?"
+		args = append(args, severity, now.Add(-reportSLAs[severity].Resolution))
+	}
+	cond += " ELSE ? END"
+	args = append(args, now.Add(-reportSLAs["medium"].Resolution))
+	return "status IN ? AND created_at < " + cond, append([]interface{}{OpenReportStatuses}, args...)
+}
+
+// ErrNothingToSettle - payment ไม่มีเงินค้างในระบบให้จ่าย/คืนแล้ว
+var ErrNothingToSettle = errors.New("payment has no funds left to settle")
+
+// ErrPaymentNotCollected - payment ยังไม่ได้รับเงินจาก user
+var ErrPaymentNotCollected = errors.New("payment has not been collected")
+
+// CaseSettlement - รายการเงินที่เกิดจากการตัดสินเคส
+type CaseSettlement struct {
+	Escrow       float64                `json:"escrow"`
+	GuideRelease *models.PaymentRelease `json:"guide_release,omitempty"`
+	UserRefund   *models.PaymentRelease `json:"user_refund,omitempty"`
+}
+
+// PaymentEscrow เงินของ payment ที่ยังไม่ได้ตั้งรายการจ่ายไกด์/คืนเงิน (นับรายการที่ค้างหรือล้มเหลวด้วย เพราะยังจะถูกทำต่อ)
+func PaymentEscrow(tx *gorm.DB, payment *models.TripPayment) (float64, error) {
+	var allocated float64
+	if err := tx.Model(&models.PaymentRelease{}).
+		Where("trip_payment_id = ?", payment.ID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&allocated).Error; err != nil {
+		return 0, fmt.Errorf("failed to sum payment releases: %w", err)
+	}
+	return centsToAmount(toCents(payment.TotalAmount) - toCents(allocated)), nil
+}
+
+// SettleCase จัดการเงินที่เหลือของ booking ตามผลการตัดสินเคส (ต้องเรียกภายใน tx)
+//   - refund: คืนเงินที่เหลือทั้งหมดให้ user
+//   - release: จ่ายเงินที่เหลือทั้งหมดให้ไกด์
+//   - split: จ่ายไกด์ตาม guideShare (0-1) ที่เหลือคืน user
+//
+// booking ที่ยังไม่จบจะถูกปิด (refund/split = cancelled, release = trip_completed) เพื่อไม่ให้มีการจ่ายงวดถัดไปซ้ำ
+func SettleCase(tx *gorm.DB, booking *models.TripBooking, outcome string, guideShare float64, reason string, now time.Time) (*CaseSettlement, error) {
+	switch outcome {
+	case "refund":
+		guideShare = 0
+	case "release":
+		guideShare = 1
+	case "split":
+		if guideShare < 0 || guideShare > 1 {
+			return nil, fmt.Errorf("guide share must be between 0 and 1")
+		}
+	default:
+		return nil, fmt.Errorf("unknown settlement outcome %q", outcome)
+	}
+
+	var payment models.TripPayment
+	if err := tx.Where("trip_booking_id = ?", booking.ID).First(&payment).Error; err != nil {
+		return nil, fmt.Errorf("payment not found: %w", err)
+	}
+	if !collectedPaymentStatuses[payment.Status] {
+		return nil, fmt.Errorf("%w (status %s)", ErrPaymentNotCollected, payment.Status)
+	}
+
+	escrow, err := PaymentEscrow(tx, &payment)
+	if err != nil {
+		return nil, err
+	}
+	if escrow <= 0 {
+		return nil, ErrNothingToSettle
+	}
+
+	settlement := &CaseSettlement{Escrow: escrow}
+	guideCents := int64(math.Round(float64(toCents(escrow)) * guideShare))
+	userCents := toCents(escrow) - guideCents
+	releaseReason := "admin_case_" + outcome
+
+	if guideCents > 0 {
+		release := models.PaymentRelease{
+			TripPaymentID: payment.ID,
+			ReleaseType:   "second_payment",
+			Amount:        centsToAmount(guideCents),
+			RecipientType: "guide",
+			RecipientID:   booking.GuideID,
+			Reason:        releaseReason,
+			ScheduledAt:   now,
+			ProcessedAt:   &now,
+			Notes:         reason,
+		}
+		if _, err := QueueGuidePayout(tx, &payment, &release); err != nil {
+			return nil, err
+		}
+		settlement.GuideRelease = &release
+	}
+
+	if userCents > 0 {
+		refund := models.PaymentRelease{
+			TripPaymentID: payment.ID,
+			ReleaseType:   "refund",
+			Amount:        centsToAmount(userCents),
+			RecipientType: "user",
+			RecipientID:   booking.UserID,
+			Reason:        releaseReason,
+			ScheduledAt:   now,
+			Notes:         reason,
+		}
+		if _, err := QueueRefund(tx, &payment, &refund); err != nil {
+			return nil, err
+		}
+		settlement.UserRefund = &refund
+
+		payment.RefundAmount = centsToAmount(toCents(payment.RefundAmount) + userCents)
+		payment.RefundedAt = &now
+		payment.RefundReason = releaseReason
+	}
+
+	switch {
+	case toCents(payment.RefundAmount) >= toCents(payment.TotalAmount):
+		payment.Status = "refunded"
+	case payment.RefundAmount > 0:
+		payment.Status = "partially_refunded"
+	default:
+		payment.Status = "fully_released"
+		payment.SecondReleasedAt = &now
+	}
+	if err := tx.Save(&payment).Error; err != nil {
+		return nil, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	switch booking.Status {
+	case "paid", "trip_started":
+		updates := map[string]interface{}{"status": "cancelled", "cancelled_at": &now, "cancellation_reason": releaseReason}
+		if outcome == "release" {
+			updates = map[string]interface{}{"status": "trip_completed", "trip_completed_at": &now}
+		}
+		if err := tx.Model(booking).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update booking: %w", err)
+		}
+	}
+
+	return settlement, nil
+}
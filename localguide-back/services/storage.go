@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrStorageNotFound - ไม่มีไฟล์ตาม key ใน storage
+var ErrStorageNotFound = errors.New("stored file not found")
+
+// Storage ที่เก็บไฟล์ที่อัปโหลด (แยกเป็น interface เพื่อสลับ local disk / S3 และใช้ตัวปลอมตอนทดสอบ)
+// key เป็น path แบบ "avatars/<random>.png" เสมอ ไม่มี "/" นำหน้า
+type Storage interface {
+	Put(key string, r io.Reader, size int64, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	// URL ที่ client ใช้เปิดไฟล์ URL("") คือ prefix ของไฟล์ทั้งหมดใน storage นี้
+	URL(key string) string
+	Name() string
+}
+
+func validStorageKey(key string) bool {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return false
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// LocalStorage เก็บไฟล์บน disk ใต้ Dir และเปิดผ่าน static route BaseURL (ค่าเริ่มต้น ./uploads, /uploads)
+// ไฟล์ส่วนตัว (key ขึ้นต้นด้วย private/) เก็บแยกไว้ใต้ PrivateDir ซึ่งอยู่นอก Dir
+// static route จึงเปิดไม่ได้ไม่ว่าจะเขียน path แบบไหน (//, %70rivate, ./, ../)
+type LocalStorage struct {
+	Dir        string
+	PrivateDir string
+	BaseURL    string
+}
+
+// NewLocalStorage - PrivateDir เป็นโฟลเดอร์ข้างๆ Dir ชื่อ private-<ชื่อ Dir> เช่น ./uploads → ./private-uploads
+func NewLocalStorage(dir, baseURL string) *LocalStorage {
+	dir = filepath.Clean(dir)
+	return &LocalStorage{
+		Dir:        dir,
+		PrivateDir: filepath.Join(filepath.Dir(dir), "private-"+filepath.Base(dir)),
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (s *LocalStorage) Name() string { return "local" }
+
+func (s *LocalStorage) path(key string) (string, error) {
+	if !validStorageKey(key) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	if rest := strings.TrimPrefix(key, privateKeyPrefix); rest != key {
+		return filepath.Join(s.PrivateDir, filepath.FromSlash(rest)), nil
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(key)), nil
+}
+
+// MovePrivateFiles ย้ายไฟล์ส่วนตัวที่เคยเก็บไว้ใต้ Dir/private (เปิดได้ผ่าน static route) ไปไว้ใต้ PrivateDir
+// คืนจำนวนไฟล์ที่ย้าย
+func (s *LocalStorage) MovePrivateFiles() (int, error) {
+	legacy := filepath.Join(s.Dir, strings.TrimSuffix(privateKeyPrefix, "/"))
+	if _, err := os.Stat(legacy); os.IsNotExist(err) {
+		return 0, nil
+	}
+	moved := 0
+	err := filepath.Walk(legacy, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(legacy, p)
+		if err != nil {
+			return err
+		}
+		dest := filepath.Join(s.PrivateDir, rel)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("failed to create private upload directory: %w", err)
+		}
+		if err := os.Rename(p, dest); err != nil {
+			return fmt.Errorf("failed to move %s: %w", rel, err)
+		}
+		moved++
+		return nil
+	})
+	if err != nil {
+		return moved, err
+	}
+	return moved, os.RemoveAll(legacy)
+}
+
+// Put เขียนลงไฟล์ชั่วคราวก่อนแล้ว rename เพื่อไม่ให้มีไฟล์ครึ่งๆ กลางๆ
+func (s *LocalStorage) Put(key string, r io.Reader, size int64, contentType string) error {
+	dest, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return os.Rename(tmp.Name(), dest)
+}
+
+func (s *LocalStorage) Get(key string) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if os.IsNotExist(err) {
+		return nil, ErrStorageNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStorage) Delete(key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStorage) URL(key string) string {
+	return s.BaseURL + "/" + key
+}
+
+// NewStorageFromEnv เลือก backend จาก STORAGE_BACKEND (local ค่าเริ่มต้น, s3)
+// local: UPLOAD_DIR (./uploads) เปิดผ่าน /uploads ไฟล์ส่วนตัวอยู่ที่ PRIVATE_UPLOAD_DIR (./private-uploads)
+// s3: S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_ACCESS_KEY, S3_SECRET_KEY, S3_PUBLIC_URL (ไม่บังคับ เช่น CDN)
+func NewStorageFromEnv() Storage {
+	if strings.EqualFold(os.Getenv("STORAGE_BACKEND"), "s3") {
+		return NewS3Storage(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			PublicURL: os.Getenv("S3_PUBLIC_URL"),
+		})
+	}
+	dir := os.Getenv("UPLOAD_DIR")
+	if dir == "" {
+		dir = "./uploads"
+	}
+	storage := NewLocalStorage(dir, "/uploads")
+	if privateDir := os.Getenv("PRIVATE_UPLOAD_DIR"); privateDir != "" {
+		storage.PrivateDir = privateDir
+	}
+	return storage
+}
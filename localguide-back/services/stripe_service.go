@@ -3,10 +3,12 @@ package services
 import (
 	"fmt"
 	"localguide-back/models"
+	"math"
 
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/paymentintent"
 	"github.com/stripe/stripe-go/v76/refund"
+	"github.com/stripe/stripe-go/v76/transfer"
 )
 
 type StripeService struct{}
@@ -41,6 +43,53 @@ func (s *StripeService) CreatePaymentIntent(booking *models.TripBooking, userEma
 	return pi, nil
 }
 
+// CreateSharePaymentIntent สร้าง PaymentIntent สำหรับส่วนแบ่งของผู้ร่วมเดินทางหนึ่งคน (กรณีแยกจ่าย)
+func (s *StripeService) CreateSharePaymentIntent(booking *models.TripBooking, participant *models.TripBookingParticipant, amount float64, payerEmail string) (*stripe.PaymentIntent, error) {
+	amountInCents := int64(math.Round(amount * 100))
+
+	params := &stripe.PaymentIntentParams{
+		Amount:   stripe.Int64(amountInCents),
+		Currency: stripe.String("thb"),
+		PaymentMethodTypes: stripe.StringSlice([]string{"card", "promptpay"}),
+		Metadata: map[string]string{
+			"booking_id":     fmt.Sprintf("%d", booking.ID),
+			"guide_id":       fmt.Sprintf("%d", booking.GuideID),
+			"participant_id": fmt.Sprintf("%d", participant.ID),
+			"user_email":     payerEmail,
+		},
+		Description: stripe.String(fmt.Sprintf("Share payment for trip booking #%d", booking.ID)),
+	}
+
+	pi, err := paymentintent.New(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment intent: %w", err)
+	}
+
+	return pi, nil
+}
+
+// PaymentIntentAdjuster - ปรับยอดหรือยกเลิก PaymentIntent ที่ยังไม่ชำระ (StripeService implement ไว้ แยกเป็น interface เพื่อทดสอบ)
+type PaymentIntentAdjuster interface {
+	UpdatePaymentIntentAmount(paymentIntentID string, amount int64) error
+	CancelPaymentIntent(paymentIntentID string) error
+}
+
+// UpdatePaymentIntentAmount เปลี่ยนยอดของ PaymentIntent ที่ยังไม่ชำระ (หน่วยสตางค์)
+func (s *StripeService) UpdatePaymentIntentAmount(paymentIntentID string, amount int64) error {
+	if _, err := paymentintent.Update(paymentIntentID, &stripe.PaymentIntentParams{Amount: stripe.Int64(amount)}); err != nil {
+		return fmt.Errorf("failed to update payment intent: %w", err)
+	}
+	return nil
+}
+
+// CancelPaymentIntent ยกเลิก PaymentIntent ที่ยังไม่ชำระ
+func (s *StripeService) CancelPaymentIntent(paymentIntentID string) error {
+	if _, err := paymentintent.Cancel(paymentIntentID, nil); err != nil {
+		return fmt.Errorf("failed to cancel payment intent: %w", err)
+	}
+	return nil
+}
+
 // ConfirmPayment ยืนยันการชำระเงิน
 func (s *StripeService) ConfirmPayment(paymentIntentID string) (*stripe.PaymentIntent, error) {
 	pi, err := paymentintent.Get(paymentIntentID, nil)
@@ -55,33 +104,39 @@ func (s *StripeService) ConfirmPayment(paymentIntentID string) (*stripe.PaymentI
 	return pi, nil
 }
 
-// RefundPayment คืนเงิน
-func (s *StripeService) RefundPayment(paymentIntentID string, amount int64, reason string) (*stripe.Refund, error) {
+// CreateRefund คืนเงินด้วย idempotency key (เรียกซ้ำด้วย key เดิม Stripe จะคืนผลเดิม ไม่คืนเงินซ้ำ)
+func (s *StripeService) CreateRefund(paymentIntentID string, amount int64, idempotencyKey string) (string, error) {
 	params := &stripe.RefundParams{
 		PaymentIntent: stripe.String(paymentIntentID),
 		Amount:        stripe.Int64(amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
 	}
+	params.SetIdempotencyKey(idempotencyKey)
 
-	// Map internal reasons to Stripe-supported refund reasons.
-	// Stripe only accepts: duplicate, fraudulent, requested_by_customer
-	switch reason {
-	case "duplicate":
-		params.Reason = stripe.String(string(stripe.RefundReasonDuplicate))
-	case "fraudulent":
-		params.Reason = stripe.String(string(stripe.RefundReasonFraudulent))
-	case "requested_by_customer":
-		params.Reason = stripe.String(string(stripe.RefundReasonRequestedByCustomer))
-	default:
-		// For any internal/custom reason, default to requested_by_customer
-		params.Reason = stripe.String(string(stripe.RefundReasonRequestedByCustomer))
+	ref, err := refund.New(params)
+	if err != nil {
+		return "", fmt.Errorf("failed to create refund: %w", err)
 	}
 
-	ref, err := refund.New(params)
+	return ref.ID, nil
+}
+
+// CreateTransfer โอนเงินให้บัญชี Stripe Connect ของไกด์ ด้วย idempotency key
+func (s *StripeService) CreateTransfer(destination string, amount int64, transferGroup, idempotencyKey string) (string, error) {
+	params := &stripe.TransferParams{
+		Amount:        stripe.Int64(amount),
+		Currency:      stripe.String("thb"),
+		Destination:   stripe.String(destination),
+		TransferGroup: stripe.String(transferGroup),
+	}
+	params.SetIdempotencyKey(idempotencyKey)
+
+	tr, err := transfer.New(params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create refund: %w", err)
+		return "", fmt.Errorf("failed to create transfer: %w", err)
 	}
 
-	return ref, nil
+	return tr.ID, nil
 }
 
 // GetPaymentIntent ดึงข้อมูล PaymentIntent
@@ -114,3 +169,44 @@ func (s *StripeService) GetRefundableAmountCents(paymentIntentID string) (int64,
 	}
 	return remaining, nil
 }
+
+// PaymentSnapshot - ข้อมูลของ PaymentIntent ฝั่ง Stripe ที่ใช้เทียบกับข้อมูลในระบบ
+type PaymentSnapshot struct {
+	Status        string
+	AmountCents   int64 // ยอดที่ตั้งไว้
+	CapturedCents int64 // ยอดที่เก็บเงินได้จริง
+	RefundedCents int64
+	Refunds       []RefundSnapshot
+}
+
+type RefundSnapshot struct {
+	ID          string
+	AmountCents int64
+	Status      string
+}
+
+// GetPaymentSnapshot ดึงสถานะ ยอดเงิน และรายการคืนเงินของ PaymentIntent
+func (s *StripeService) GetPaymentSnapshot(paymentIntentID string) (*PaymentSnapshot, error) {
+	params := &stripe.PaymentIntentParams{}
+	params.AddExpand("latest_charge.refunds")
+	pi, err := paymentintent.Get(paymentIntentID, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment intent: %w", err)
+	}
+
+	snapshot := &PaymentSnapshot{
+		Status:        string(pi.Status),
+		AmountCents:   pi.Amount,
+		CapturedCents: pi.AmountReceived,
+	}
+	if ch := pi.LatestCharge; ch != nil {
+		snapshot.RefundedCents = ch.AmountRefunded
+		if ch.Refunds != nil {
+			for _, r := range ch.Refunds.Data {
+				snapshot.Refunds = append(snapshot.Refunds, RefundSnapshot{ID: r.ID, AmountCents: r.Amount, Status: string(r.Status)})
+			}
+		}
+	}
+
+	return snapshot, nil
+}
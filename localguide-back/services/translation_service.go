@@ -0,0 +1,230 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"localguide-back/models"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownTranslationType  = errors.New("unknown translation type")
+	ErrUnknownTranslationField = errors.New("field cannot be translated")
+	ErrUnsupportedLanguage     = errors.New("unsupported language")
+	ErrTranslationTooLong      = errors.New("translation is too long")
+)
+
+// maxTranslationLength - ความยาวสูงสุดของคำแปลหนึ่งข้อความ (ตัวอักษร)
+const maxTranslationLength = 5000
+
+// translatableEntity - ข้อมูลที่แปลได้: model และ field (ชื่อใน API → ชื่อ field ของ struct)
+type translatableEntity struct {
+	Model  interface{}
+	Fields map[string]string
+}
+
+// translatableEntities - ชนิดข้อมูลที่มีคำแปล ชื่อชนิดตรงกับ kind ของ CatalogueService
+var translatableEntities = map[string]translatableEntity{
+	"province":   {&models.Province{}, map[string]string{"name": "Name"}},
+	"attraction": {&models.TouristAttraction{}, map[string]string{"name": "Name", "description": "Description"}},
+	"category":   {&models.AttractionCategory{}, map[string]string{"name": "Name", "description": "Description"}},
+	"language":   {&models.Language{}, map[string]string{"name": "Name"}},
+	"role":       {&models.Role{}, map[string]string{"name": "Name"}},
+	"guide":      {&models.Guide{}, map[string]string{"bio": "Bio", "description": "Description"}},
+}
+
+// translatableTypes - struct type → ชื่อชนิด ใช้ตอนไล่หา entity ใน response
+var translatableTypes = func() map[reflect.Type]string {
+	types := map[reflect.Type]string{}
+	for name, entity := range translatableEntities {
+		types[reflect.TypeOf(entity.Model).Elem()] = name
+	}
+	return types
+}()
+
+// TranslationFields - field ที่แปลได้ของชนิดนี้ (nil = ไม่รู้จักชนิด)
+func TranslationFields(entityType string) []string {
+	entity, ok := translatableEntities[entityType]
+	if !ok {
+		return nil
+	}
+	fields := make([]string, 0, len(entity.Fields))
+	for field := range entity.Fields {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// TranslationService จัดการคำแปลของ catalogue และโปรไฟล์ไกด์ และแปลข้อมูลใน response ตามภาษา
+type TranslationService struct {
+	db *gorm.DB
+}
+
+func NewTranslationService(db *gorm.DB) *TranslationService {
+	return &TranslationService{db: db}
+}
+
+// Get คืนคำแปลทั้งหมดของรายการ แยกตามภาษาแล้วตาม field
+// คืน gorm.ErrRecordNotFound ถ้าไม่มีรายการนี้
+func (s *TranslationService) Get(entityType string, id uint) (map[string]map[string]string, error) {
+	entity, ok := translatableEntities[entityType]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTranslationType, entityType)
+	}
+	model := reflect.New(reflect.TypeOf(entity.Model).Elem()).Interface()
+	if err := s.db.Select("id").First(model, id).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []models.Translation
+	if err := s.db.Where("entity_type = ? AND entity_id = ?", entityType, id).Order("language, field").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	out := map[string]map[string]string{}
+	for _, row := range rows {
+		if out[row.Language] == nil {
+			out[row.Language] = map[string]string{}
+		}
+		out[row.Language][row.Field] = row.Value
+	}
+	return out, nil
+}
+
+// Set บันทึกคำแปลของภาษาเดียว field ที่ส่งค่าว่างจะลบคำแปลนั้น (กลับไปใช้ค่าเริ่มต้น)
+// field ที่ไม่ได้ส่งมาไม่เปลี่ยน คืน gorm.ErrRecordNotFound ถ้าไม่มีรายการนี้
+func (s *TranslationService) Set(entityType string, id uint, lang string, fields map[string]string) error {
+	entity, ok := translatableEntities[entityType]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownTranslationType, entityType)
+	}
+	if !IsSupportedLanguage(lang) {
+		return fmt.Errorf("%w %q", ErrUnsupportedLanguage, lang)
+	}
+	for field, value := range fields {
+		if _, ok := entity.Fields[field]; !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownTranslationField, field)
+		}
+		if utf8.RuneCountInString(value) > maxTranslationLength {
+			return fmt.Errorf("%w: %s", ErrTranslationTooLong, field)
+		}
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		model := reflect.New(reflect.TypeOf(entity.Model).Elem()).Interface()
+		if err := tx.Select("id").First(model, id).Error; err != nil {
+			return err
+		}
+		for field, value := range fields {
+			// ลบถาวรเพราะ unique index รวมแถวที่ถูก soft delete ด้วย
+			if err := tx.Unscoped().Where("entity_type = ? AND entity_id = ? AND field = ? AND language = ?", entityType, id, field, lang).
+				Delete(&models.Translation{}).Error; err != nil {
+				return err
+			}
+			value = strings.TrimSpace(value)
+			if value == "" {
+				continue
+			}
+			if err := tx.Create(&models.Translation{
+				EntityType: entityType,
+				EntityID:   id,
+				Field:      field,
+				Language:   lang,
+				Value:      value,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deleteTranslations - ลบคำแปลทั้งหมดของรายการ (ใช้ตอนลบ/รวมรายการใน catalogue)
+func deleteTranslations(tx *gorm.DB, entityType string, id uint) error {
+	if !tx.Migrator().HasTable(&models.Translation{}) {
+		return nil
+	}
+	return tx.Unscoped().Where("entity_type = ? AND entity_id = ?", entityType, id).Delete(&models.Translation{}).Error
+}
+
+// maxLocalizeDepth - ความลึกสูงสุดที่ Localize ไล่เข้าไปใน struct (กันวนไม่รู้จบ)
+const maxLocalizeDepth = 8
+
+type localizeTarget struct {
+	value  reflect.Value
+	fields map[string]string
+}
+
+// Localize แทนที่ข้อความใน v (pointer ไปยัง struct หรือ slice) ด้วยคำแปลภาษา lang
+// ไล่หา Province, TouristAttraction, AttractionCategory, Language, Role และ Guide ทั้งที่ซ้อนอยู่ใน struct อื่น
+// รายการที่ไม่มีคำแปลใช้ค่าเดิม
+func (s *TranslationService) Localize(lang string, v interface{}) error {
+	targets := map[string]map[uint][]localizeTarget{}
+	collectTranslatable(reflect.ValueOf(v), 0, targets)
+
+	for entityType, byID := range targets {
+		ids := make([]uint, 0, len(byID))
+		for id := range byID {
+			ids = append(ids, id)
+		}
+		var rows []models.Translation
+		if err := s.db.Where("entity_type = ? AND language = ? AND entity_id IN ?", entityType, lang, ids).Find(&rows).Error; err != nil {
+			return err
+		}
+		for _, row := range rows {
+			for _, target := range byID[row.EntityID] {
+				name, ok := target.fields[row.Field]
+				if !ok {
+					continue
+				}
+				if f := target.value.FieldByName(name); f.CanSet() && f.Kind() == reflect.String {
+					f.SetString(row.Value)
+				}
+			}
+		}
+	}
+	// TouristAttraction.Category เป็นสำเนาชื่อประเภท ให้ตรงกับชื่อประเภทที่แปลแล้ว
+	for _, list := range targets["attraction"] {
+		for _, target := range list {
+			if a, ok := target.value.Addr().Interface().(*models.TouristAttraction); ok && a.AttractionCategory != nil {
+				a.Category = a.AttractionCategory.Name
+			}
+		}
+	}
+	return nil
+}
+
+func collectTranslatable(v reflect.Value, depth int, targets map[string]map[uint][]localizeTarget) {
+	if depth > maxLocalizeDepth || !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			collectTranslatable(v.Elem(), depth+1, targets)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			collectTranslatable(v.Index(i), depth+1, targets)
+		}
+	case reflect.Struct:
+		if entityType, ok := translatableTypes[v.Type()]; ok && v.CanSet() {
+			if id, ok := v.FieldByName("ID").Interface().(uint); ok && id > 0 {
+				if targets[entityType] == nil {
+					targets[entityType] = map[uint][]localizeTarget{}
+				}
+				targets[entityType][id] = append(targets[entityType][id], localizeTarget{v, translatableEntities[entityType].Fields})
+			}
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				collectTranslatable(v.Field(i), depth+1, targets)
+			}
+		}
+	}
+}
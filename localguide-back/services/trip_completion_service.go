@@ -0,0 +1,188 @@
+package services
+
+import (
+	"fmt"
+	"localguide-back/models"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const defaultAutoCompleteHours = 72
+
+// TripCompletionService จบทริปและจ่ายเงินให้ไกด์อัตโนมัติเมื่อ user ไม่ยืนยันภายในเวลาที่กำหนดหลังวันสุดท้ายของทริป
+type TripCompletionService struct {
+	db    *gorm.DB
+	delay time.Duration
+}
+
+// NewTripCompletionService อ่านจำนวนชั่วโมงหลังจบทริปจาก TRIP_AUTO_COMPLETE_HOURS (ค่าเริ่มต้น 72)
+func NewTripCompletionService(db *gorm.DB) *TripCompletionService {
+	hours := defaultAutoCompleteHours
+	if v, err := strconv.Atoi(os.Getenv("TRIP_AUTO_COMPLETE_HOURS")); err == nil && v >= 0 {
+		hours = v
+	}
+	return &TripCompletionService{db: db, delay: time.Duration(hours) * time.Hour}
+}
+
+// Start รัน job ทุก interval จนกว่าโปรแกรมจะปิด
+func (s *TripCompletionService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.RunOnce(time.Now()); err != nil {
+				log.Printf("trip auto-completion failed: %v", err)
+			} else if n > 0 {
+				log.Printf("trip auto-completion: completed %d booking(s)", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// tripEndsAt - เวลาที่ทริปจบ (สิ้นวันสุดท้ายของทริป)
+func tripEndsAt(booking *models.TripBooking) time.Time {
+	end := booking.StartDate
+	if booking.EndDate != nil {
+		end = *booking.EndDate
+	} else if booking.Days > 1 {
+		end = booking.StartDate.AddDate(0, 0, booking.Days-1)
+	}
+	y, m, d := end.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, end.Location()).AddDate(0, 0, 1)
+}
+
+// RunOnce จบทริปที่เลยกำหนดทั้งหมด คืนจำนวน booking ที่จบอัตโนมัติ
+// เฉพาะทริปที่เริ่มแล้ว (trip_started) หรือ paid ที่ไกด์เช็คอินเริ่มทริปผ่านแล้ว
+// booking paid ที่ไม่มีหลักฐานว่าไกด์มา ปล่อยให้ขั้นตอน no-show จัดการ ไม่จ่ายไกด์อัตโนมัติ
+func (s *TripCompletionService) RunOnce(now time.Time) (int, error) {
+	var bookings []models.TripBooking
+	if err := s.db.Where("start_date <= ?", now.Add(-s.delay)).
+		Where("status = ? OR (status = ? AND id IN (?))", "trip_started", "paid",
+			s.db.Model(&models.TripStartCheckIn{}).Select("trip_booking_id").Where("status = ?", "accepted")).
+		Find(&bookings).Error; err != nil {
+		return 0, fmt.Errorf("failed to get bookings: %w", err)
+	}
+
+	completed := 0
+	for i := range bookings {
+		booking := &bookings[i]
+		if now.Before(tripEndsAt(booking).Add(s.delay)) {
+			continue
+		}
+
+		// มี report ที่ยังไม่ปิด ให้ admin ตัดสินก่อน
+		var openReports int64
+		s.db.Model(&models.TripReport{}).
+			Where("trip_booking_id = ? AND status IN ?", booking.ID, []string{"pending", "investigating"}).
+			Count(&openReports)
+		if openReports > 0 {
+			continue
+		}
+
+		ok, err := s.completeBooking(booking, now)
+		if err != nil {
+			log.Printf("trip auto-completion: booking #%d: %v", booking.ID, err)
+			continue
+		}
+		if ok {
+			completed++
+		}
+	}
+
+	return completed, nil
+}
+
+func (s *TripCompletionService) completeBooking(booking *models.TripBooking, now time.Time) (bool, error) {
+	tx := s.db.Begin()
+	defer tx.Rollback()
+
+	var payment models.TripPayment
+	if err := tx.Where("trip_booking_id = ?", booking.ID).First(&payment).Error; err != nil {
+		return false, fmt.Errorf("payment not found: %w", err)
+	}
+	if payment.Status != "paid" && payment.Status != "first_released" {
+		return false, nil
+	}
+
+	notes := fmt.Sprintf("Auto-completed %d hours after trip end without traveller confirmation", int(s.delay.Hours()))
+	var released float64
+
+	// user ไม่ได้ยืนยันว่าไกด์มาแต่ไกด์เช็คอินเริ่มทริปแล้ว -> จ่ายงวดแรกด้วย
+	if payment.Status == "paid" {
+		first := models.PaymentRelease{
+			TripPaymentID: payment.ID,
+			ReleaseType:   "first_payment",
+			Amount:        payment.FirstPayment,
+			RecipientType: "guide",
+			RecipientID:   booking.GuideID,
+			Reason:        "auto_completed",
+			ScheduledAt:   now,
+			Notes:         notes,
+		}
+		if _, err := QueueGuidePayout(tx, &payment, &first); err != nil {
+			return false, fmt.Errorf("failed to create first release: %w", err)
+		}
+		payment.FirstReleasedAt = &now
+		released += first.Amount
+	}
+
+	// งวดที่สอง หักส่วนที่จ่ายรายวันไปแล้ว
+	var dailyReleased float64
+	tx.Model(&models.PaymentRelease{}).
+		Where("trip_payment_id = ? AND release_type = ? AND status IN ?", payment.ID, "daily_payment", []string{"processed", "pending"}).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&dailyReleased)
+	second := models.PaymentRelease{
+		TripPaymentID: payment.ID,
+		ReleaseType:   "second_payment",
+		Amount:        math.Round((payment.SecondPayment-dailyReleased)*100) / 100,
+		RecipientType: "guide",
+		RecipientID:   booking.GuideID,
+		Reason:        "auto_completed",
+		ScheduledAt:   now,
+		Notes:         notes,
+	}
+	if _, err := QueueGuidePayout(tx, &payment, &second); err != nil {
+		return false, fmt.Errorf("failed to create second release: %w", err)
+	}
+	released += second.Amount
+
+	payment.Status = "fully_released"
+	payment.SecondReleasedAt = &now
+	if err := tx.Save(&payment).Error; err != nil {
+		return false, fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	if err := tx.Model(booking).Updates(map[string]interface{}{
+		"status":            "trip_completed",
+		"trip_completed_at": &now,
+		"auto_completed_at": &now,
+	}).Error; err != nil {
+		return false, fmt.Errorf("failed to update booking: %w", err)
+	}
+
+	// แจ้งทั้งสองฝ่าย
+	notifier := NewNotificationService(tx)
+	data := map[string]interface{}{"BookingID": booking.ID, "Amount": released}
+	if _, err := notifier.Send(booking.UserID, "trip_auto_completed", data, "trip_booking", booking.ID); err != nil {
+		return false, fmt.Errorf("failed to notify user: %w", err)
+	}
+	var guide models.Guide
+	if err := tx.Select("id", "user_id").First(&guide, booking.GuideID).Error; err == nil {
+		data["Guide"] = true
+		if _, err := notifier.Send(guide.UserID, "trip_auto_completed", data, "trip_booking", booking.ID); err != nil {
+			return false, fmt.Errorf("failed to notify guide: %w", err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"localguide-back/models"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	uploadSniffLength      = 512
+	uploadCleanupBatchSize = 200
+	defaultUploadOrphanAge = 24 * time.Hour
+)
+
+var (
+	ErrUnknownUploadPurpose = errors.New("unknown upload purpose")
+	ErrUploadEmpty          = errors.New("uploaded file is empty")
+	ErrUploadTooLarge       = errors.New("uploaded file is too large")
+	ErrUploadTypeNotAllowed = errors.New("file type is not allowed")
+)
+
+// UploadPolicy - ประเภทไฟล์ (ตรวจจากเนื้อไฟล์) และขนาดสูงสุดของแต่ละวัตถุประสงค์
+type UploadPolicy struct {
+	Dir          string            // โฟลเดอร์ใน storage
+	MaxSize      int64             // bytes
+	ContentTypes map[string]string // content type -> นามสกุลไฟล์
+	ProcessImage bool              // ผ่าน ProcessImage: ลบ metadata, เข้ารหัสเป็น JPEG และสร้างรูปย่อ
+}
+
+var (
+	// uploadProcessedImageTypes - รูปที่ถอดรหัสได้ด้วย ProcessImage (ผลลัพธ์เป็น JPEG เสมอ)
+	uploadProcessedImageTypes = map[string]string{"image/jpeg": ".jpg", "image/png": ".jpg", "image/gif": ".jpg"}
+
+	uploadPolicies = map[string]UploadPolicy{
+		"avatar":           {Dir: "avatars", MaxSize: 5 << 20, ContentTypes: uploadProcessedImageTypes, ProcessImage: true},
+		"review_image":     {Dir: "reviews", MaxSize: 10 << 20, ContentTypes: uploadProcessedImageTypes, ProcessImage: true},
+		"attraction_image": {Dir: "attractions", MaxSize: 15 << 20, ContentTypes: uploadProcessedImageTypes, ProcessImage: true},
+		"evidence": {Dir: "evidence", MaxSize: 20 << 20, ContentTypes: map[string]string{
+			"image/jpeg": ".jpg", "image/png": ".png", "image/webp": ".webp", "application/pdf": ".pdf", "video/mp4": ".mp4",
+		}},
+		"licence_document": {Dir: "licences", MaxSize: 10 << 20, ContentTypes: map[string]string{
+			"image/jpeg": ".jpg", "image/png": ".png", "application/pdf": ".pdf",
+		}},
+		"message_attachment": {Dir: "attachments", MaxSize: 10 << 20, ContentTypes: map[string]string{
+			"image/jpeg": ".jpg", "image/png": ".png", "image/webp": ".webp", "application/pdf": ".pdf",
+		}},
+	}
+)
+
+// uploadReference - คอลัมน์ที่เก็บ URL ของไฟล์ (JSON = เก็บเป็น JSON array/ข้อความที่มี URL อยู่ข้างใน)
+type uploadReference struct {
+	Table  string
+	Column string
+	JSON   bool
+}
+
+// uploadReferences - ที่ที่อ้างถึงไฟล์ ใช้ตัดสินว่าไฟล์ไหนเป็น orphan (เพิ่มตรงนี้เมื่อมีคอลัมน์ใหม่ที่เก็บ URL ไฟล์)
+var uploadReferences = []uploadReference{
+	{Table: "users", Column: "avatar"},
+	{Table: "guide_vertifications", Column: "licence_document"},
+	{Table: "message_attachments", Column: "url"},
+	{Table: "trip_reports", Column: "evidence", JSON: true},
+	{Table: "dispute_statements", Column: "evidence", JSON: true},
+	{Table: "trip_review_images", Column: "url"},
+	{Table: "tourist_attractions", Column: "image_url"},
+}
+
+// UploadService ตรวจและบันทึกไฟล์ลง Storage พร้อมเก็บข้อมูลเจ้าของในตาราง uploads
+type UploadService struct {
+	db      *gorm.DB
+	storage Storage
+	// OrphanAge - ไฟล์ที่ไม่มีข้อมูลใดอ้างถึงนานกว่านี้จะถูกลบ
+	OrphanAge time.Duration
+	// SigningKey - กุญแจลงชื่อ signed URL ของไฟล์ส่วนตัว (ว่าง = signed URL ใช้ไม่ได้)
+	SigningKey []byte
+}
+
+func NewUploadService(db *gorm.DB, storage Storage) *UploadService {
+	return &UploadService{db: db, storage: storage, OrphanAge: defaultUploadOrphanAge}
+}
+
+func randomUploadName() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Save ตรวจประเภท/ขนาดไฟล์ตาม purpose แล้วบันทึกด้วย key แบบสุ่ม (ไม่ใช้ชื่อไฟล์ของ client)
+// รูปของ purpose ที่ตั้ง ProcessImage จะถูกเข้ารหัสใหม่และบันทึกรูปย่อใน Variants
+// error ที่เป็นความผิดของ client ห่อ ErrUnknownUploadPurpose, ErrUploadEmpty, ErrUploadTooLarge,
+// ErrUploadTypeNotAllowed หรือ ErrInvalidImage
+func (s *UploadService) Save(ownerID uint, purpose, originalName string, r io.Reader, size int64) (*models.Upload, error) {
+	policy, ok := uploadPolicies[purpose]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownUploadPurpose, purpose)
+	}
+	if size <= 0 {
+		return nil, ErrUploadEmpty
+	}
+	if size > policy.MaxSize {
+		return nil, fmt.Errorf("%w (max %d MB for %s)", ErrUploadTooLarge, policy.MaxSize>>20, purpose)
+	}
+
+	head := make([]byte, uploadSniffLength)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	head = head[:n]
+	contentType := strings.TrimSpace(strings.Split(http.DetectContentType(head), ";")[0])
+	ext, ok := policy.ContentTypes[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w for %s (%s)", ErrUploadTypeNotAllowed, purpose, contentType)
+	}
+
+	name, err := randomUploadName()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate file name: %w", err)
+	}
+	private := privateUploadPurposes[purpose]
+	key := policy.Dir + "/" + name + ext
+	url := s.storage.URL(key)
+	if private {
+		key = privateKeyPrefix + key
+		url = PrivateFileURLPrefix + key
+	}
+	body := io.LimitReader(io.MultiReader(bytes.NewReader(head), r), size)
+	if policy.ProcessImage {
+		return s.saveImage(ownerID, purpose, originalName, name, policy, private, body)
+	}
+	if err := s.storage.Put(key, body, size, contentType); err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+
+	upload := models.Upload{
+		OwnerID:      ownerID,
+		Purpose:      purpose,
+		Backend:      s.storage.Name(),
+		Key:          key,
+		URL:          url,
+		ContentType:  contentType,
+		Size:         size,
+		OriginalName: originalName,
+		Private:      private,
+	}
+	if err := s.db.Create(&upload).Error; err != nil {
+		s.storage.Delete(key)
+		return nil, fmt.Errorf("failed to save upload record: %w", err)
+	}
+	return &upload, nil
+}
+
+// saveImage ประมวลผลรูปแล้วบันทึกไฟล์หลักและรูปย่อ (<name>_<variant>.jpg) ลบไฟล์ที่บันทึกไปแล้วถ้าขั้นใดล้มเหลว
+func (s *UploadService) saveImage(ownerID uint, purpose, originalName, name string, policy UploadPolicy, private bool, body io.Reader) (*models.Upload, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	processed, err := ProcessImage(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []string
+	put := func(suffix string, img EncodedImage) (string, string, error) {
+		key := policy.Dir + "/" + name + suffix + ".jpg"
+		url := s.storage.URL(key)
+		if private {
+			key = privateKeyPrefix + key
+			url = PrivateFileURLPrefix + key
+		}
+		if err := s.storage.Put(key, bytes.NewReader(img.Data), int64(len(img.Data)), "image/jpeg"); err != nil {
+			return "", "", err
+		}
+		stored = append(stored, key)
+		return key, url, nil
+	}
+	cleanup := func() {
+		for _, key := range stored {
+			s.storage.Delete(key)
+		}
+	}
+
+	key, url, err := put("", processed.EncodedImage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store file: %w", err)
+	}
+	upload := models.Upload{
+		OwnerID:      ownerID,
+		Purpose:      purpose,
+		Backend:      s.storage.Name(),
+		Key:          key,
+		URL:          url,
+		ContentType:  "image/jpeg",
+		Size:         int64(len(processed.Data)),
+		OriginalName: originalName,
+		Private:      private,
+		Width:        processed.Width,
+		Height:       processed.Height,
+	}
+	for _, v := range processed.Variants {
+		vkey, vurl, err := put("_"+v.Name, v)
+		if err != nil {
+			cleanup()
+			return nil, fmt.Errorf("failed to store %s variant: %w", v.Name, err)
+		}
+		upload.Variants = append(upload.Variants, models.ImageVariant{
+			Variant: v.Name,
+			Key:     vkey,
+			URL:     vurl,
+			Width:   v.Width,
+			Height:  v.Height,
+			Size:    int64(len(v.Data)),
+		})
+	}
+	if err := s.db.Create(&upload).Error; err != nil {
+		cleanup()
+		return nil, fmt.Errorf("failed to save upload record: %w", err)
+	}
+	return &upload, nil
+}
+
+// FindByURL หาไฟล์จาก URL ที่ client ส่งมา
+func (s *UploadService) FindByURL(url string) (*models.Upload, error) {
+	var upload models.Upload
+	if err := s.db.Where("url = ?", url).First(&upload).Error; err != nil {
+		return nil, err
+	}
+	return &upload, nil
+}
+
+// Delete ลบไฟล์และรูปย่อออกจาก storage และลบ record
+func (s *UploadService) Delete(upload *models.Upload) error {
+	var variants []models.ImageVariant
+	if err := s.db.Where("upload_id = ?", upload.ID).Find(&variants).Error; err != nil {
+		return fmt.Errorf("failed to get image variants: %w", err)
+	}
+	for _, v := range variants {
+		if err := s.storage.Delete(v.Key); err != nil {
+			return fmt.Errorf("failed to delete %s variant: %w", v.Variant, err)
+		}
+	}
+	if err := s.storage.Delete(upload.Key); err != nil {
+		return fmt.Errorf("failed to delete stored file: %w", err)
+	}
+	if err := s.db.Unscoped().Where("upload_id = ?", upload.ID).Delete(&models.ImageVariant{}).Error; err != nil {
+		return err
+	}
+	return s.db.Unscoped().Delete(upload).Error
+}
+
+// DeleteByURL ลบไฟล์ตาม URL ไฟล์เก่าที่อัปโหลดก่อนมีตาราง uploads จะลบจาก storage ตาม path ของ URL
+func (s *UploadService) DeleteByURL(url string) error {
+	upload, err := s.FindByURL(url)
+	if err == nil {
+		return s.Delete(upload)
+	}
+	if err != gorm.ErrRecordNotFound {
+		return err
+	}
+	prefix := s.storage.URL("")
+	if !strings.HasPrefix(url, prefix) {
+		return nil
+	}
+	return s.storage.Delete(strings.TrimPrefix(url, prefix))
+}
+
+// IsStoredURL - URL ชี้ไปที่ไฟล์ใน storage นี้ (หรือไฟล์ส่วนตัว) หรือไม่
+func (s *UploadService) IsStoredURL(url string) bool {
+	return (strings.HasPrefix(url, s.storage.URL("")) || strings.HasPrefix(url, PrivateFileURLPrefix)) && !strings.Contains(url, "..")
+}
+
+// Open เปิดเนื้อไฟล์จาก storage (ผู้เรียกต้องปิด)
+func (s *UploadService) Open(upload *models.Upload) (io.ReadCloser, error) {
+	return s.storage.Get(upload.Key)
+}
+
+// referenced - มีข้อมูลใดอ้างถึง URL นี้หรือไม่ (query ผิดพลาดถือว่ายังใช้อยู่ เพื่อไม่ลบไฟล์ผิด)
+func (s *UploadService) referenced(url string) bool {
+	for _, ref := range uploadReferences {
+		if !s.db.Migrator().HasTable(ref.Table) {
+			continue
+		}
+		q := s.db.Table(ref.Table)
+		if ref.JSON {
+			q = q.Where(ref.Column+" LIKE ?", "%"+url+"%")
+		} else {
+			q = q.Where(ref.Column+" = ?", url)
+		}
+		var count int64
+		if err := q.Count(&count).Error; err != nil || count > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Start รัน job ลบไฟล์ orphan ทุก interval จนกว่าโปรแกรมจะปิด
+func (s *UploadService) Start(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if n, err := s.RunOnce(time.Now()); err != nil {
+				log.Printf("upload cleanup failed: %v", err)
+			} else if n > 0 {
+				log.Printf("upload cleanup: removed %d orphaned file(s)", n)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// RunOnce ลบไฟล์ที่อัปโหลดก่อน now-OrphanAge และไม่มีข้อมูลใดอ้างถึง คืนจำนวนไฟล์ที่ลบ
+func (s *UploadService) RunOnce(now time.Time) (int, error) {
+	removed := 0
+	var lastID uint
+	for {
+		var candidates []models.Upload
+		if err := s.db.Where("created_at < ? AND id > ?", now.Add(-s.OrphanAge), lastID).
+			Order("id").Limit(uploadCleanupBatchSize).Find(&candidates).Error; err != nil {
+			return removed, fmt.Errorf("failed to get uploads: %w", err)
+		}
+
+		for i := range candidates {
+			lastID = candidates[i].ID
+			if s.referenced(candidates[i].URL) {
+				continue
+			}
+			if err := s.Delete(&candidates[i]); err != nil {
+				log.Printf("upload #%d: %v", candidates[i].ID, err)
+				continue
+			}
+			removed++
+		}
+		if len(candidates) < uploadCleanupBatchSize {
+			return removed, nil
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/migrations"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttractionImportExport(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.AttractionCategory{})
+	db.Create(&models.Province{Name: "เชียงใหม่", Region: "ภาคเหนือ"})
+	db.Create(&models.Province{Name: "Krabi", Region: "ภาคใต้"})
+	db.Create(&models.AttractionCategory{Name: "วัด"})
+
+	app.Post("/admin/attractions/import", controllers.AdminImportAttractions)
+	app.Get("/admin/attractions/export", controllers.AdminExportAttractions)
+
+	importFile := func(query, filename, content string) (*http.Response, map[string]interface{}) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", filename)
+		fw.Write([]byte(content))
+		mw.Close()
+		r := httptest.NewRequest("POST", "/admin/attractions/import"+query, &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		resp, err := app.Test(r)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	summary := func(out map[string]interface{}) [4]float64 {
+		r := out["result"].(map[string]interface{})
+		return [4]float64{r["new"].(float64), r["changed"].(float64), r["unchanged"].(float64), r["invalid"].(float64)}
+	}
+	count := func() int64 {
+		var n int64
+		db.Model(&models.TouristAttraction{}).Count(&n)
+		return n
+	}
+
+	csvFile := "\ufeffName,Province,Category,District,Description,Rating,Latitude,Longitude,Notes\n" +
+		"วัดพระธาตุดอยสุเทพ,จังหวัดเชียงใหม่,วัด,เมืองเชียงใหม่,วัดบนดอยสุเทพ,4.9,18.8048,98.9217,ขึ้นบันได 306 ขั้น\n" +
+		"Railay Beach,krabi,ชายหาด,,,4.7,8.0110,98.8380,\n" +
+		"\n" +
+		"วัดเจดีย์หลวง,เชียงใหม่,วัด,,,,,,\n"
+
+	t.Run("Dry run returns a diff and writes nothing", func(t *testing.T) {
+		resp, out := importFile("", "attractions.csv", csvFile)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, [4]float64{3, 0, 0, 0}, summary(out))
+		result := out["result"].(map[string]interface{})
+		assert.Equal(t, false, result["applied"])
+		assert.Equal(t, []interface{}{"ชายหาด"}, result["new_categories"])
+		assert.Equal(t, []interface{}{"notes"}, result["ignored_columns"])
+		assert.Equal(t, float64(5), result["rows"].([]interface{})[2].(map[string]interface{})["line"])
+		assert.Equal(t, int64(0), count())
+		var categories int64
+		db.Model(&models.AttractionCategory{}).Count(&categories)
+		assert.Equal(t, int64(1), categories)
+	})
+
+	t.Run("Invalid rows block apply", func(t *testing.T) {
+		bad := csvFile + "ดอยอินทนนท์,ลำพูน,ธรรมชาติ,,,,,,\n" +
+			"วัดเจดีย์หลวง,เชียงใหม่,วัด,,,,,,\n" +
+			"น้ำตกแม่สา,เชียงใหม่,ธรรมชาติ,,,6,,,\n" +
+			"ม่อนแจ่ม,เชียงใหม่,ธรรมชาติ,,,,18.9,,\n"
+		resp, out := importFile("?apply=true", "attractions.csv", bad)
+		assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+		assert.Equal(t, [4]float64{3, 0, 0, 4}, summary(out))
+		var errs []string
+		for _, row := range out["result"].(map[string]interface{})["rows"].([]interface{}) {
+			if e, ok := row.(map[string]interface{})["error"]; ok {
+				errs = append(errs, e.(string))
+			}
+		}
+		assert.Equal(t, []string{
+			`province "ลำพูน" not found`,
+			"duplicate of line 5",
+			"rating must be a number between 0 and 5",
+			"latitude and longitude must be provided together",
+		}, errs)
+		assert.Equal(t, int64(0), count())
+	})
+
+	t.Run("Apply upserts and re-importing is a no-op", func(t *testing.T) {
+		resp, out := importFile("?apply=true", "attractions.csv", csvFile)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, true, out["result"].(map[string]interface{})["applied"])
+		assert.Equal(t, int64(3), count())
+
+		var railay models.TouristAttraction
+		db.Preload("Province").Preload("AttractionCategory").Where("name = ?", "Railay Beach").First(&railay)
+		assert.Equal(t, "Krabi", railay.Province.Name)
+		assert.Equal(t, "ชายหาด", railay.AttractionCategory.Name)
+		assert.InDelta(t, 8.011, *railay.Latitude, 1e-9)
+
+		resp, out = importFile("?apply=true", "attractions.csv", csvFile)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, [4]float64{0, 0, 3, 0}, summary(out))
+		assert.Equal(t, int64(3), count())
+	})
+
+	t.Run("Changed rows list their field changes and only touch given columns", func(t *testing.T) {
+		edited := "name,province,description,latitude,longitude\n" +
+			"วัดพระธาตุดอยสุเทพ,เชียงใหม่,วัดคู่เมืองเชียงใหม่,,\n" +
+			"RAILAY BEACH,Krabi,,8.0110,98.8380\n"
+		resp, out := importFile("?apply=true", "edited.csv", edited)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, [4]float64{0, 2, 0, 0}, summary(out))
+		rows := out["result"].(map[string]interface{})["rows"].([]interface{})
+		changes := rows[0].(map[string]interface{})["changes"].([]interface{})
+		assert.Len(t, changes, 3)
+		assert.Equal(t, "description", changes[0].(map[string]interface{})["field"])
+		assert.Equal(t, "วัดบนดอยสุเทพ", changes[0].(map[string]interface{})["from"])
+
+		var doiSuthep models.TouristAttraction
+		db.Where("name = ?", "วัดพระธาตุดอยสุเทพ").First(&doiSuthep)
+		assert.Nil(t, doiSuthep.Latitude)
+		assert.Equal(t, 4.9, doiSuthep.Rating)
+		assert.Equal(t, "เมืองเชียงใหม่", doiSuthep.District)
+
+		var railay models.TouristAttraction
+		db.Where("LOWER(name) = ?", "railay beach").First(&railay)
+		assert.Equal(t, "RAILAY BEACH", railay.Name)
+	})
+
+	t.Run("Exports round-trip without changes", func(t *testing.T) {
+		for _, format := range []string{"csv", "geojson"} {
+			resp, err := app.Test(httptest.NewRequest("GET", "/admin/attractions/export?format="+format, nil))
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Contains(t, resp.Header.Get("Content-Disposition"), "."+format)
+			exported, _ := io.ReadAll(resp.Body)
+
+			resp, out := importFile("", "attractions."+format, string(exported))
+			assert.Equal(t, http.StatusOK, resp.StatusCode, format)
+			assert.Equal(t, [4]float64{0, 0, 3, 0}, summary(out), format)
+		}
+
+		resp, _ := app.Test(httptest.NewRequest("GET", "/admin/attractions/export?format=xlsx", nil))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("GeoJSON geometry provides coordinates", func(t *testing.T) {
+		file, err := services.ParseAttractionsGeoJSON(strings.NewReader(`{"type":"FeatureCollection","features":[
+			{"type":"Feature","geometry":{"type":"Point","coordinates":[98.9217,18.8048]},"properties":{"name":"วัดพระธาตุดอยสุเทพ","province":"เชียงใหม่","rating":4.9,"osm_id":1}},
+			{"type":"Feature","geometry":{"type":"LineString","coordinates":[[1,2],[3,4]]},"properties":{"name":"x","province":"Krabi","category":"วัด"}}
+		]}`))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"osm_id"}, file.IgnoredColumns)
+		assert.Equal(t, "18.8048", file.Records[0].Fields["latitude"])
+
+		result, err := services.NewAttractionImportService(db).Import(file, false)
+		assert.NoError(t, err)
+		assert.Equal(t, "changed", result.Rows[0].Action)
+		assert.Equal(t, "invalid", result.Rows[1].Action)
+		assert.Equal(t, "geometry must be a Point", result.Rows[1].Error)
+	})
+
+	t.Run("Unsupported files are rejected", func(t *testing.T) {
+		resp, _ := importFile("", "attractions.xlsx", "x")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = importFile("", "attractions.csv", "title,province\nx,y\n")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
+
+func TestSeedTouristAttractionsMatchesProvincesByName(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.AttractionCategory{})
+	assert.NoError(t, migrations.SeedProvinces(db))
+	assert.NoError(t, migrations.SeedTouristAttractions(db))
+
+	var whiteTemple models.TouristAttraction
+	assert.NoError(t, db.Preload("Province").Preload("AttractionCategory").Where("name = ?", "วัดร่องขุ่น").First(&whiteTemple).Error)
+	assert.Equal(t, "เชียงราย", whiteTemple.Province.Name)
+	assert.Equal(t, "วัด", whiteTemple.AttractionCategory.Name)
+
+	var n int64
+	db.Model(&models.TouristAttraction{}).Count(&n)
+	assert.Equal(t, int64(32), n)
+	// เรียกซ้ำไม่เพิ่มข้อมูล
+	assert.NoError(t, migrations.SeedTouristAttractions(db))
+	db.Model(&models.TouristAttraction{}).Count(&n)
+	assert.Equal(t, int64(32), n)
+}
@@ -0,0 +1,237 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/migrations"
+	"localguide-back/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalogueAdmin(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.AuthUser{}, &models.User{}, &models.Language{}, &models.AttractionCategory{}, &models.Guide{}, &models.GuideVertification{}, &models.TripRequire{}, &models.Upload{}, &models.ImageVariant{})
+
+	app.Get("/attractions", controllers.GetTouristAttractions)
+	app.Post("/admin/provinces", controllers.AdminCreateProvince)
+	app.Put("/admin/provinces/:id", controllers.AdminUpdateProvince)
+	app.Delete("/admin/provinces/:id", controllers.AdminDeleteProvince)
+	app.Post("/admin/provinces/:id/merge", controllers.AdminMergeProvince)
+	app.Post("/admin/attractions", controllers.AdminCreateAttraction)
+	app.Put("/admin/attractions/:id", controllers.AdminUpdateAttraction)
+	app.Delete("/admin/attractions/:id", controllers.AdminDeleteAttraction)
+	app.Get("/admin/attractions/:id/impact", controllers.AdminGetAttractionImpact)
+	app.Post("/admin/attractions/:id/merge", controllers.AdminMergeAttraction)
+	app.Post("/admin/attraction-categories", controllers.AdminCreateAttractionCategory)
+	app.Put("/admin/attraction-categories/:id", controllers.AdminUpdateAttractionCategory)
+	app.Delete("/admin/attraction-categories/:id", controllers.AdminDeleteAttractionCategory)
+	app.Post("/admin/languages", controllers.AdminCreateLanguage)
+	app.Delete("/admin/languages/:id", controllers.AdminDeleteLanguage)
+	app.Post("/admin/languages/:id/merge", controllers.AdminMergeLanguage)
+
+	do := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	id := func(out map[string]interface{}, key string) uint {
+		return uint(out[key].(map[string]interface{})["ID"].(float64))
+	}
+	path := func(prefix string, id uint) string {
+		return prefix + "/" + strconv.Itoa(int(id))
+	}
+
+	authUser := models.AuthUser{Email: "guide@example.com", Password: "hash"}
+	db.Create(&authUser)
+	guideUser := models.User{AuthUserID: authUser.ID, FirstName: "Guide", LastName: "One", RoleID: 2}
+	db.Create(&guideUser)
+
+	var provinceID, templeID uint
+	t.Run("Provinces are validated and unique", func(t *testing.T) {
+		resp, _ := do("POST", "/admin/provinces", map[string]string{"name": "  ", "region": "ภาคเหนือ"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out := do("POST", "/admin/provinces", map[string]string{"name": " เชียงใหม่ ", "region": "ภาคเหนือ"})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		provinceID = id(out, "province")
+		assert.Equal(t, "เชียงใหม่", out["province"].(map[string]interface{})["Name"])
+
+		resp, _ = do("POST", "/admin/provinces", map[string]string{"name": "เชียงใหม่", "region": "ภาคเหนือ"})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp, out = do("PUT", path("/admin/provinces", provinceID), map[string]string{"region": "North"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "เชียงใหม่", out["province"].(map[string]interface{})["Name"])
+		assert.Equal(t, "North", out["province"].(map[string]interface{})["Region"])
+	})
+
+	t.Run("Attractions need a province and a category from the table", func(t *testing.T) {
+		resp, out := do("POST", "/admin/attraction-categories", map[string]string{"name": "วัด"})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		templeID = id(out, "category")
+		resp, _ = do("POST", "/admin/attraction-categories", map[string]string{"name": "วัด"})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		resp, _ = do("POST", "/admin/attractions", map[string]interface{}{"name": "Wat Phra Singh", "province_id": provinceID})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", "/admin/attractions", map[string]interface{}{"name": "Wat Phra Singh", "province_id": 999, "category_id": templeID})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", "/admin/attractions", map[string]interface{}{"name": "Wat Phra Singh", "province_id": provinceID, "category_id": templeID, "rating": 6})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out = do("POST", "/admin/attractions", map[string]interface{}{"name": "Wat Phra Singh", "province_id": provinceID, "category_id": templeID, "city": "เชียงใหม่"})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		attraction := out["attraction"].(map[string]interface{})
+		assert.Equal(t, "วัด", attraction["Category"])
+		assert.Equal(t, "เชียงใหม่", attraction["Province"].(map[string]interface{})["Name"])
+
+		resp, _ = do("POST", "/admin/attractions", map[string]interface{}{"name": "wat phra singh", "province_id": provinceID, "category_id": templeID})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		// แก้ชื่อประเภท ชื่อในสถานที่ท่องเที่ยวเปลี่ยนตาม
+		resp, _ = do("PUT", path("/admin/attraction-categories", templeID), map[string]string{"name": "Temple"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp, _ = do("DELETE", path("/admin/attraction-categories", templeID), nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		_, out = do("GET", "/attractions?category_id="+strconv.Itoa(int(templeID)), nil)
+		listed := out["attractions"].([]interface{})
+		if assert.Len(t, listed, 1) {
+			assert.Equal(t, "Temple", listed[0].(map[string]interface{})["Category"])
+			assert.Equal(t, "Temple", listed[0].(map[string]interface{})["AttractionCategory"].(map[string]interface{})["Name"])
+		}
+	})
+
+	t.Run("Referenced entries cannot be deleted and are merged instead", func(t *testing.T) {
+		newAttraction := func(name string) models.TouristAttraction {
+			a := models.TouristAttraction{Name: name, ProvinceID: provinceID, Category: "Temple", CategoryID: &templeID}
+			db.Create(&a)
+			return a
+		}
+		duplicate := newAttraction("Doi Suthep")
+		canonical := newAttraction("Wat Phra That Doi Suthep")
+		unused := newAttraction("Old entry")
+		thai := models.Language{Name: "Thai"}
+		db.Create(&thai)
+		thaiDup := models.Language{Name: "ภาษาไทย"}
+		db.Create(&thaiDup)
+
+		guide := models.Guide{UserID: guideUser.ID, ProvinceID: provinceID, Description: "desc", Available: true}
+		db.Create(&guide)
+		db.Model(&guide).Association("TouristAttraction").Append(&duplicate, &canonical)
+		db.Model(&guide).Association("Language").Append(&thaiDup)
+		verification := models.GuideVertification{UserID: guideUser.ID, Status: "pending", VerificationDate: time.Now(), ProvinceID: provinceID}
+		db.Create(&verification)
+		db.Model(&verification).Association("Attraction").Append(&duplicate)
+
+		resp, out := do("GET", path("/admin/attractions", duplicate.ID)+"/impact", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, false, out["deletable"])
+		assert.Equal(t, 1.0, out["impact"].(map[string]interface{})["guides"])
+		assert.Equal(t, 1.0, out["impact"].(map[string]interface{})["verifications"])
+
+		resp, out = do("DELETE", path("/admin/attractions", duplicate.ID), nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.NotNil(t, out["impact"])
+
+		resp, _ = do("POST", path("/admin/attractions", duplicate.ID)+"/merge", map[string]uint{"target_id": duplicate.ID})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", path("/admin/attractions", duplicate.ID)+"/merge", map[string]uint{"target_id": canonical.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// ไกด์ผูกกับทั้งสองรายการอยู่แล้ว เหลือแถวเดียว ใบสมัครย้ายไปที่รายการหลัก
+		var attractions []models.TouristAttraction
+		db.Model(&guide).Association("TouristAttraction").Find(&attractions)
+		if assert.Len(t, attractions, 1) {
+			assert.Equal(t, canonical.ID, attractions[0].ID)
+		}
+		db.Model(&verification).Association("Attraction").Find(&attractions)
+		if assert.Len(t, attractions, 1) {
+			assert.Equal(t, canonical.ID, attractions[0].ID)
+		}
+		resp, _ = do("DELETE", path("/admin/attractions", duplicate.ID), nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		resp, _ = do("DELETE", path("/admin/attractions", unused.ID), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var remaining int64
+		db.Model(&models.TouristAttraction{}).Where("province_id = ?", provinceID).Count(&remaining)
+		assert.Equal(t, int64(2), remaining)
+
+		resp, _ = do("DELETE", path("/admin/languages", thaiDup.ID), nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		resp, _ = do("POST", path("/admin/languages", thaiDup.ID)+"/merge", map[string]uint{"target_id": thai.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var languages []models.Language
+		db.Model(&guide).Association("Language").Find(&languages)
+		if assert.Len(t, languages, 1) {
+			assert.Equal(t, thai.ID, languages[0].ID)
+		}
+		resp, _ = do("POST", "/admin/languages", map[string]string{"name": "thai"})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		// จังหวัดที่มีไกด์ลบไม่ได้ รวมเข้ากับจังหวัดอื่นได้
+		resp, _ = do("DELETE", path("/admin/provinces", provinceID), nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		other := models.Province{Name: "Chiang Mai", Region: "North"}
+		db.Create(&other)
+		resp, out = do("POST", path("/admin/provinces", provinceID)+"/merge", map[string]uint{"target_id": other.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		moved := out["moved"].(map[string]interface{})
+		assert.Equal(t, 1.0, moved["guides"])
+		assert.Equal(t, 1.0, moved["verifications"])
+		db.First(&guide, guide.ID)
+		assert.Equal(t, other.ID, guide.ProvinceID)
+	})
+
+	t.Run("Free-text categories are migrated to the table", func(t *testing.T) {
+		province := models.Province{Name: "น่าน", Region: "ภาคเหนือ"}
+		db.Create(&province)
+		db.Create(&models.TouristAttraction{Name: "Wat Phumin", ProvinceID: province.ID, Category: "temple"})
+		db.Create(&models.TouristAttraction{Name: "Doi Samer Dao", ProvinceID: province.ID, Category: "ธรรมชาติ"})
+		db.Create(&models.TouristAttraction{Name: "Sao Din", ProvinceID: province.ID, Category: "ธรรมชาติ"})
+
+		assert.NoError(t, migrations.MigrateAttractionCategories(db))
+		assert.NoError(t, migrations.MigrateAttractionCategories(db))
+
+		var nature models.AttractionCategory
+		assert.NoError(t, db.Where("name = ?", "ธรรมชาติ").First(&nature).Error)
+		var count int64
+		db.Model(&models.TouristAttraction{}).Where("category_id = ?", nature.ID).Count(&count)
+		assert.Equal(t, int64(2), count)
+		// "temple" ตรงกับประเภท "Temple" ที่มีอยู่แล้ว
+		var wat models.TouristAttraction
+		db.Where("name = ?", "Wat Phumin").First(&wat)
+		if assert.NotNil(t, wat.CategoryID) {
+			assert.Equal(t, templeID, *wat.CategoryID)
+		}
+		assert.Equal(t, "Temple", wat.Category)
+		db.Model(&models.AttractionCategory{}).Count(&count)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Seeds do not bring back deleted entries", func(t *testing.T) {
+		migrations.SeedLanguages(db)
+		var count int64
+		db.Model(&models.Language{}).Where("name = ?", "English").Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
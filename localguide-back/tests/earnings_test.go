@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuideEarnings(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripPayment{}, &models.PaymentRelease{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	newGuide := func(email string) (models.User, models.Guide) {
+		auth := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&auth)
+		u := models.User{AuthUserID: auth.ID, FirstName: "Guide", LastName: email, RoleID: 2}
+		db.Create(&u)
+		g := models.Guide{UserID: u.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+		db.Create(&g)
+		return u, g
+	}
+	// ไกด์คนแรกมี ID ต่างจาก user ID เพื่อให้เห็นว่าใช้ Guide.ID
+	_, other := newGuide("other@example.com")
+	guideUser, guide := newGuide("guide@example.com")
+
+	at := func(month time.Month, day int) *time.Time {
+		d := time.Date(2026, month, day, 10, 0, 0, 0, time.Local)
+		return &d
+	}
+	newPayment := func(g models.Guide, amount float64, paidAt *time.Time, number string) models.TripPayment {
+		booking := models.TripBooking{TripOfferID: 1, UserID: user.ID, GuideID: g.ID, StartDate: *paidAt, TotalAmount: amount, Status: "paid", PaymentStatus: "paid"}
+		db.Create(&booking)
+		payment := models.TripPayment{TripBookingID: booking.ID, PaymentNumber: number, TransactionID: "TXN-" + number, StripePaymentIntentID: "pi_" + number, TotalAmount: amount, FirstPayment: amount / 2, SecondPayment: amount / 2, PaymentMethod: "stripe_card", Status: "paid", PaidAt: paidAt}
+		db.Create(&payment)
+		return payment
+	}
+	release := func(p models.TripPayment, releaseType, recipientType string, recipientID uint, amount float64, processedAt *time.Time, status, ref string) {
+		db.Create(&models.PaymentRelease{TripPaymentID: p.ID, ReleaseType: releaseType, Amount: amount, RecipientType: recipientType, RecipientID: recipientID, Reason: "test", ScheduledAt: *at(1, 1), ProcessedAt: processedAt, Status: status, TransactionRef: ref})
+	}
+
+	september := newPayment(guide, 2000, at(9, 15), "PAY-1")
+	release(september, "first_payment", "guide", guide.ID, 1000, at(9, 20), "processed", "tr_1")
+	release(september, "refund", "user", user.ID, 500, at(10, 5), "processed", "re_1")
+
+	october := newPayment(guide, 3000, at(10, 10), "PAY-2")
+	release(october, "first_payment", "guide", guide.ID, 1500, nil, "pending", "")
+
+	// ไม่ใช่ของไกด์คนนี้
+	otherPayment := newPayment(other, 9999, at(10, 11), "PAY-3")
+	release(otherPayment, "first_payment", "guide", other.ID, 5000, at(10, 12), "processed", "tr_other")
+
+	actor := guideUser.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Get("/guides/me/earnings", as(controllers.GetMyEarnings))
+	app.Get("/guides/me/earnings/statements/:month", as(controllers.GetMyEarningsStatement))
+
+	get := func(url string) (*http.Response, []byte) {
+		resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	t.Run("Earnings totals by booking and period", func(t *testing.T) {
+		resp, body := get("/guides/me/earnings")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var out map[string]interface{}
+		json.Unmarshal(body, &out)
+		totals := out["totals"].(map[string]interface{})
+		assert.Equal(t, 5000.0, totals["collected"])
+		assert.Equal(t, 3500.0, totals["pending"])
+		assert.Equal(t, 1000.0, totals["released"])
+		assert.Equal(t, 500.0, totals["refunded"])
+		assert.Len(t, out["by_booking"], 2)
+		assert.Len(t, out["by_period"], 2)
+		assert.Len(t, out["entries"], 4)
+
+		_, body = get("/guides/me/earnings?from=2026-10-01&to=2026-10-31")
+		json.Unmarshal(body, &out)
+		period := out["period_totals"].(map[string]interface{})
+		assert.Equal(t, 3000.0, period["collected"])
+		assert.Equal(t, 500.0, period["refunded"])
+		assert.Len(t, out["entries"], 2)
+	})
+
+	t.Run("Monthly statement reconciles with payment releases", func(t *testing.T) {
+		_, body := get("/guides/me/earnings/statements/2026-10")
+		var statement map[string]interface{}
+		json.Unmarshal(body, &statement)
+		assert.Equal(t, 1000.0, statement["opening_pending"])
+		assert.Equal(t, 3000.0, statement["collected"])
+		assert.Equal(t, 0.0, statement["released"])
+		assert.Equal(t, 500.0, statement["refunded"])
+		assert.Equal(t, 3500.0, statement["closing_pending"])
+
+		resp, body := get("/guides/me/earnings/statements/2026-10?format=csv")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), "earnings-2026-10.csv")
+		csv := string(body)
+		assert.Contains(t, csv, "refund,refund,")
+		assert.Contains(t, csv, ",re_1,-500.00,500.00")
+		assert.Contains(t, csv, "PAY-2,3000.00,3500.00")
+		assert.Contains(t, csv, "closing_pending,3500.00")
+		assert.NotContains(t, csv, "tr_other")
+
+		resp, body = get("/guides/me/earnings/statements/2026-09?format=pdf")
+		assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(string(body), "%PDF-1.4"))
+		assert.Contains(t, string(body), "tr_1")
+		assert.True(t, strings.HasSuffix(string(body), "%%EOF\n"))
+
+		resp, _ = get("/guides/me/earnings/statements/october")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Non-guides are rejected", func(t *testing.T) {
+		actor = user.ID
+		defer func() { actor = guideUser.ID }()
+		resp, _ := get("/guides/me/earnings")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFinancialReport(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripBooking{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.TripReport{})
+
+	bangkok := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&bangkok)
+	chiangMai := models.Province{Name: "Chiang Mai", Region: "North"}
+	db.Create(&chiangMai)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	newGuide := func(email string, province models.Province) models.Guide {
+		auth := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&auth)
+		u := models.User{AuthUserID: auth.ID, FirstName: "Guide", LastName: email, RoleID: 2}
+		db.Create(&u)
+		g := models.Guide{UserID: u.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+		db.Create(&g)
+		return g
+	}
+	bangkokGuide := newGuide("bkk@example.com", bangkok)
+	northGuide := newGuide("cnx@example.com", chiangMai)
+
+	at := func(month time.Month, day int) *time.Time {
+		d := time.Date(2026, month, day, 10, 0, 0, 0, time.UTC)
+		return &d
+	}
+	newPayment := func(g models.Guide, province models.Province, amount float64, paidAt *time.Time, status, number string) models.TripPayment {
+		tr := models.TripRequire{UserID: user.ID, ProvinceID: province.ID, Title: "Trip " + number, Description: "Test", MinPrice: 100, MaxPrice: 10000, StartDate: *paidAt, EndDate: *paidAt, Days: 1, Status: "assigned", GroupSize: 1}
+		db.Create(&tr)
+		offer := models.TripOffer{TripRequireID: tr.ID, GuideID: g.ID, Title: "Offer " + number, Description: "desc", Status: "accepted"}
+		db.Create(&offer)
+		booking := models.TripBooking{TripOfferID: offer.ID, UserID: user.ID, GuideID: g.ID, StartDate: *paidAt, TotalAmount: amount, Status: "paid", PaymentStatus: "paid"}
+		db.Create(&booking)
+		payment := models.TripPayment{TripBookingID: booking.ID, PaymentNumber: number, TransactionID: "TXN-" + number, StripePaymentIntentID: "pi_" + number, TotalAmount: amount, FirstPayment: amount / 2, SecondPayment: amount / 2, PaymentMethod: "stripe_card", Status: status, PaidAt: paidAt}
+		db.Create(&payment)
+		return payment
+	}
+	release := func(p models.TripPayment, releaseType, recipientType string, amount float64, processedAt *time.Time, status string) {
+		db.Create(&models.PaymentRelease{TripPaymentID: p.ID, ReleaseType: releaseType, Amount: amount, RecipientType: recipientType, RecipientID: 1, Reason: "test", ScheduledAt: *at(1, 1), ProcessedAt: processedAt, Status: status})
+	}
+	report := func(p models.TripPayment, reportType string) {
+		db.Create(&models.TripReport{TripBookingID: p.TripBookingID, ReporterID: user.ID, ReportedUserID: user.ID, ReportType: reportType, Title: reportType, Description: "desc"})
+	}
+
+	// กันยายน: จ่ายไกด์ครบ 1800 จาก 2000 แพลตฟอร์มเหลือ 200
+	settled := newPayment(bangkokGuide, bangkok, 2000, at(9, 10), "fully_released", "PAY-1")
+	release(settled, "first_payment", "guide", 900, at(9, 12), "processed")
+	release(settled, "second_payment", "guide", 900, at(10, 2), "processed")
+
+	// ตุลาคม: คืนเงินบางส่วน 1000 จ่ายไกด์ 1500 แพลตฟอร์มเหลือ 500 และมี no-show
+	refunded := newPayment(bangkokGuide, bangkok, 3000, at(10, 5), "partially_refunded", "PAY-2")
+	release(refunded, "refund", "user", 1000, at(10, 8), "processed")
+	release(refunded, "first_payment", "guide", 1500, at(10, 9), "processed")
+	report(refunded, "user_no_show")
+	report(refunded, "dispute_no_show")
+
+	// ตุลาคม: ยังอยู่ใน escrow (จ่ายไปแล้ว 400 ยังค้างอีกรายการ)
+	escrow := newPayment(northGuide, chiangMai, 1000, at(10, 20), "first_released", "PAY-3")
+	release(escrow, "first_payment", "guide", 400, at(10, 21), "processed")
+	release(escrow, "second_payment", "guide", 600, nil, "pending")
+	report(escrow, "safety_issue")
+
+	// ยังไม่ชำระ ไม่นับ
+	newPayment(northGuide, chiangMai, 5000, at(10, 22), "pending", "PAY-4")
+
+	app.Get("/admin/reports/financial", controllers.GetFinancialReport)
+	app.Get("/admin/reports/financial/summary", controllers.GetFinancialSummary)
+
+	get := func(url string) (*http.Response, []byte) {
+		resp, err := app.Test(httptest.NewRequest("GET", url, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	t.Run("Monthly report splits GMV, refunds, payouts and revenue", func(t *testing.T) {
+		resp, body := get("/admin/reports/financial")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var out struct {
+			Rows  []map[string]interface{} `json:"rows"`
+			Total int                      `json:"total"`
+		}
+		json.Unmarshal(body, &out)
+		assert.Equal(t, 2, out.Total)
+		if assert.Len(t, out.Rows, 2) {
+			october, september := out.Rows[0], out.Rows[1]
+			assert.Equal(t, "2026-10", october["period"])
+			assert.Equal(t, 2.0, october["bookings"])
+			assert.Equal(t, 4000.0, october["gmv"])
+			assert.Equal(t, 1000.0, october["refunds"])
+			assert.Equal(t, 2800.0, october["guide_payouts"])
+			assert.Equal(t, 700.0, october["platform_revenue"])
+
+			assert.Equal(t, "2026-09", september["period"])
+			assert.Equal(t, 2000.0, september["gmv"])
+			assert.Equal(t, 900.0, september["guide_payouts"])
+			assert.Equal(t, 0.0, september["platform_revenue"])
+		}
+
+		// หน้า 2 ขนาด 1
+		_, body = get("/admin/reports/financial?limit=1&page=2")
+		json.Unmarshal(body, &out)
+		assert.Equal(t, 2, out.Total)
+		if assert.Len(t, out.Rows, 1) {
+			assert.Equal(t, "2026-09", out.Rows[0]["period"])
+		}
+	})
+
+	t.Run("Group by province and filter by guide and date", func(t *testing.T) {
+		_, body := get("/admin/reports/financial?period=day&group_by=province&from=2026-10-20&to=2026-10-21")
+		var out struct {
+			Rows []map[string]interface{} `json:"rows"`
+		}
+		json.Unmarshal(body, &out)
+		if assert.Len(t, out.Rows, 2) {
+			assert.Equal(t, "2026-10-21", out.Rows[0]["period"])
+			assert.Equal(t, 400.0, out.Rows[0]["guide_payouts"])
+			assert.Equal(t, "2026-10-20", out.Rows[1]["period"])
+			assert.Equal(t, "Chiang Mai", out.Rows[1]["group_name"])
+			assert.Equal(t, 1000.0, out.Rows[1]["gmv"])
+		}
+
+		_, body = get("/admin/reports/financial?group_by=guide&guide_id=" + strconv.Itoa(int(northGuide.ID)))
+		json.Unmarshal(body, &out)
+		if assert.Len(t, out.Rows, 1) {
+			assert.Equal(t, float64(northGuide.ID), out.Rows[0]["group_id"])
+			assert.Equal(t, 1000.0, out.Rows[0]["gmv"])
+		}
+
+		resp, _ := get("/admin/reports/financial?period=year")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Summary balances GMV with escrow and rates", func(t *testing.T) {
+		_, body := get("/admin/reports/financial/summary")
+		var out struct {
+			Summary map[string]float64 `json:"summary"`
+		}
+		json.Unmarshal(body, &out)
+		s := out.Summary
+		assert.Equal(t, 3.0, s["bookings"])
+		assert.Equal(t, 6000.0, s["gmv"])
+		assert.Equal(t, 1000.0, s["refunds"])
+		assert.Equal(t, 3700.0, s["guide_payouts"])
+		assert.Equal(t, 700.0, s["platform_revenue"])
+		assert.Equal(t, 600.0, s["outstanding_escrow"])
+		// GMV = คืนเงิน + จ่ายไกด์ + รายได้ + escrow
+		assert.Equal(t, s["gmv"], s["refunds"]+s["guide_payouts"]+s["platform_revenue"]+s["outstanding_escrow"])
+		assert.Equal(t, 2.0, s["disputed_bookings"])
+		assert.Equal(t, 1.0, s["no_show_bookings"])
+		assert.InDelta(t, 2.0/3.0, s["dispute_rate"], 0.0001)
+		assert.InDelta(t, 1.0/3.0, s["no_show_rate"], 0.0001)
+
+		_, body = get("/admin/reports/financial/summary?province_id=" + strconv.Itoa(int(chiangMai.ID)))
+		json.Unmarshal(body, &out)
+		assert.Equal(t, 1000.0, out.Summary["gmv"])
+		assert.Equal(t, 600.0, out.Summary["outstanding_escrow"])
+	})
+
+	t.Run("CSV export", func(t *testing.T) {
+		resp, body := get("/admin/reports/financial?group_by=province&format=csv")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, resp.Header.Get("Content-Type"), "text/csv")
+		assert.Contains(t, resp.Header.Get("Content-Disposition"), "attachment")
+
+		records, err := csv.NewReader(strings.NewReader(string(body))).ReadAll()
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"period", "group_id", "group_name", "bookings", "gmv", "refunds", "guide_payouts", "platform_revenue"}, records[0])
+		assert.Len(t, records, 4)
+		assert.Contains(t, string(body), "Chiang Mai")
+
+		resp, body = get("/admin/reports/financial/summary?format=csv")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Contains(t, string(body), "outstanding_escrow,600.00")
+	})
+}
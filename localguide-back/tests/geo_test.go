@@ -0,0 +1,268 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func floatPtr(v float64) *float64 { return &v }
+
+func TestGeoHelpers(t *testing.T) {
+	t.Run("Route legs skip day changes and stops without coordinates", func(t *testing.T) {
+		legs := services.EstimateRouteLegs([]services.RoutePoint{
+			{Day: 1, Latitude: floatPtr(13.7500), Longitude: floatPtr(100.4913)},
+			{Day: 1, Latitude: floatPtr(13.7437), Longitude: floatPtr(100.4889)},
+			{Day: 1},
+			{Day: 2, Latitude: floatPtr(14.3559), Longitude: floatPtr(100.5581)},
+			{Day: 2, Latitude: floatPtr(14.3559), Longitude: floatPtr(100.5581)},
+		})
+		assert.Nil(t, legs[0])
+		assert.NotNil(t, legs[1])
+		assert.InDelta(t, 1.0, legs[1].DistanceKm, 0.15) // ~0.75 กม. เส้นตรง x 1.3
+		assert.Equal(t, 2, legs[1].TravelMinutes)
+		assert.Nil(t, legs[2])
+		assert.Nil(t, legs[3])
+		assert.Equal(t, 0.0, legs[4].DistanceKm)
+	})
+
+	t.Run("Bounding box covers the radius", func(t *testing.T) {
+		minLat, maxLat, minLng, maxLng := services.BoundingBox(13.75, 100.49, 10)
+		assert.InDelta(t, 10, services.DistanceKm(13.75, 100.49, maxLat, 100.49), 0.01)
+		assert.InDelta(t, 10, services.DistanceKm(13.75, 100.49, minLat, 100.49), 0.01)
+		assert.Greater(t, services.DistanceKm(13.75, 100.49, 13.75, maxLng), 9.99)
+		assert.Greater(t, services.DistanceKm(13.75, 100.49, 13.75, minLng), 9.99)
+
+		_, _, minLng, maxLng = services.BoundingBox(89.99, 0, 10)
+		assert.Equal(t, -180.0, minLng)
+		assert.Equal(t, 180.0, maxLng)
+	})
+}
+
+func TestNearbyAttractionsAndOfferStops(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.AttractionCategory{}, &models.Language{}, &models.GuideCertification{},
+		&models.Guide{}, &models.GuideRatingSummary{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripOfferQuotation{}, &models.TripOfferStop{},
+		&models.DocumentSequence{}, &models.Notification{}, &models.Upload{}, &models.ImageVariant{})
+
+	province := models.Province{Name: "กรุงเทพมหานคร", Region: "ภาคกลาง"}
+	db.Create(&province)
+	category := models.AttractionCategory{Name: "วัด"}
+	db.Create(&category)
+	newAttraction := func(name string, lat, lng *float64) models.TouristAttraction {
+		a := models.TouristAttraction{Name: name, ProvinceID: province.ID, Category: category.Name, CategoryID: &category.ID, Latitude: lat, Longitude: lng}
+		db.Create(&a)
+		return a
+	}
+	palace := newAttraction("พระบรมมหาราชวัง", floatPtr(13.7500), floatPtr(100.4913))
+	watArun := newAttraction("วัดอรุณราชวราราม", floatPtr(13.7437), floatPtr(100.4889))
+	ayutthaya := newAttraction("อุทยานประวัติศาสตร์พระนครศรีอยุธยา", floatPtr(14.3559), floatPtr(100.5581))
+	newAttraction("ตลาดน้ำ (ไม่มีพิกัด)", nil, nil)
+
+	roleGuide := models.Role{Name: "guide"}
+	db.Create(&roleGuide)
+	newGuide := func(email string, attractions ...models.TouristAttraction) (models.User, models.Guide) {
+		authUser := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&authUser)
+		user := models.User{AuthUserID: authUser.ID, FirstName: "Guide", LastName: email, RoleID: roleGuide.ID}
+		db.Create(&user)
+		guide := models.Guide{UserID: user.ID, ProvinceID: province.ID, Description: "guide", Available: true, TouristAttraction: attractions}
+		db.Create(&guide)
+		return user, guide
+	}
+	guideUser, cityGuide := newGuide("city@example.com", palace, watArun)
+	_, oldCapitalGuide := newGuide("ayutthaya@example.com", ayutthaya)
+
+	app.Get("/attractions/nearby", controllers.GetNearbyAttractions)
+	app.Get("/guides", controllers.GetGuides)
+	app.Put("/admin/attractions/:id", controllers.AdminUpdateAttraction)
+	app.Post("/trip-offers", func(c *fiber.Ctx) error {
+		c.Locals("user_id", guideUser.ID)
+		return controllers.CreateTripOffer(c)
+	})
+	app.Put("/trip-offers/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", guideUser.ID)
+		return controllers.UpdateTripOffer(c)
+	})
+	app.Get("/trip-offers/:id", controllers.GetTripOfferByID)
+
+	do := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	names := func(items []interface{}, key string) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.(map[string]interface{})[key].(string))
+		}
+		return out
+	}
+
+	t.Run("Nearby attractions are sorted by distance", func(t *testing.T) {
+		resp, out := do("GET", "/attractions/nearby?lat=13.7510&lng=100.4920", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		attractions := out["attractions"].([]interface{})
+		assert.Equal(t, []string{palace.Name, watArun.Name}, names(attractions, "Name"))
+		assert.Less(t, attractions[0].(map[string]interface{})["distance_km"].(float64), 0.2)
+		assert.Equal(t, 10.0, out["radius_km"])
+
+		_, out = do("GET", "/attractions/nearby?lat=13.7510&lng=100.4920&radius=100", nil)
+		assert.Len(t, out["attractions"].([]interface{}), 3)
+
+		_, out = do("GET", "/attractions/nearby?lat=13.7510&lng=100.4920&radius=100&limit=1", nil)
+		assert.Equal(t, []string{palace.Name}, names(out["attractions"].([]interface{}), "Name"))
+	})
+
+	t.Run("Nearby attractions validate the query", func(t *testing.T) {
+		for q, code := range map[string]string{
+			"lat=13.75":                 "near_coordinates_required",
+			"lat=abc&lng=100":           "near_coordinates_required",
+			"lat=91&lng=100":            "coordinates_invalid",
+			"lat=13&lng=100&radius=0":   "near_radius_out_of_range",
+			"lat=13&lng=100&radius=500": "near_radius_out_of_range",
+			"lat=13&lng=100&limit=0":    "limit_invalid",
+		} {
+			resp, out := do("GET", "/attractions/nearby?"+q, nil)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, q)
+			assert.Equal(t, code, out["code"], q)
+		}
+	})
+
+	t.Run("Admin sets and clears attraction coordinates", func(t *testing.T) {
+		path := "/admin/attractions/" + strconv.Itoa(int(palace.ID))
+		resp, _ := do("PUT", path, map[string]interface{}{"latitude": 13.75})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("PUT", path, map[string]interface{}{"latitude": 13.75, "longitude": 200})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out := do("PUT", path, map[string]interface{}{"clear_location": true})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Nil(t, out["attraction"].(map[string]interface{})["Latitude"])
+
+		resp, out = do("PUT", path, map[string]interface{}{"latitude": 13.7500, "longitude": 100.4913})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 13.75, out["attraction"].(map[string]interface{})["Latitude"])
+	})
+
+	t.Run("Guides can be filtered by attractions within a radius", func(t *testing.T) {
+		_, out := do("GET", "/guides?lat=13.7510&lng=100.4920&within_km=5", nil)
+		guides := out["guides"].([]interface{})
+		assert.Len(t, guides, 1)
+		assert.Equal(t, float64(cityGuide.ID), guides[0].(map[string]interface{})["ID"])
+
+		_, out = do("GET", "/guides?lat=14.36&lng=100.56&within_km=5", nil)
+		guides = out["guides"].([]interface{})
+		assert.Len(t, guides, 1)
+		assert.Equal(t, float64(oldCapitalGuide.ID), guides[0].(map[string]interface{})["ID"])
+
+		_, out = do("GET", "/guides?lat=18.80&lng=98.92&within_km=5", nil)
+		assert.Empty(t, out["guides"])
+
+		resp, out := do("GET", "/guides?within_km=5", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "near_coordinates_required", out["code"])
+	})
+
+	authTraveller := models.AuthUser{Email: "traveller@example.com", Password: "hash"}
+	db.Create(&authTraveller)
+	traveller := models.User{AuthUserID: authTraveller.ID, FirstName: "Trav", LastName: "Eller"}
+	db.Create(&traveller)
+	tripRequire := models.TripRequire{UserID: traveller.ID, ProvinceID: province.ID, Title: "Bangkok and Ayutthaya", Description: "2 days",
+		MinPrice: 1000, MaxPrice: 5000, StartDate: time.Now().AddDate(0, 0, 7), EndDate: time.Now().AddDate(0, 0, 8), Days: 2, Status: "open", GroupSize: 2}
+	db.Create(&tripRequire)
+
+	stopsOf := func(out map[string]interface{}, key string) []map[string]interface{} {
+		var stops []map[string]interface{}
+		for _, s := range out[key].(map[string]interface{})["Stops"].([]interface{}) {
+			stops = append(stops, s.(map[string]interface{}))
+		}
+		return stops
+	}
+
+	var offerID uint
+	t.Run("Offer stops get distances between consecutive stops", func(t *testing.T) {
+		resp, _ := do("POST", "/trip-offers", map[string]interface{}{
+			"trip_require_id": tripRequire.ID, "title": "Temples", "description": "desc", "total_price": 2000,
+			"stops": []map[string]interface{}{{"name": "Nowhere", "latitude": 13.7}},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out := do("POST", "/trip-offers", map[string]interface{}{
+			"trip_require_id": tripRequire.ID, "title": "Temples", "description": "desc", "total_price": 2000,
+			"stops": []map[string]interface{}{
+				{"day": 2, "attraction_id": ayutthaya.ID},
+				{"day": 1, "attraction_id": palace.ID},
+				{"day": 1, "attraction_id": watArun.ID, "note": "ข้ามเรือ"},
+				{"day": 1, "name": "ร้านอาหารริมน้ำ"},
+			},
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		offerID = uint(out["offer"].(map[string]interface{})["ID"].(float64))
+
+		_, out = do("GET", "/trip-offers/"+strconv.Itoa(int(offerID)), nil)
+		stops := stopsOf(out, "data")
+		assert.Equal(t, []string{palace.Name, watArun.Name, "ร้านอาหารริมน้ำ", ayutthaya.Name}, func() []string {
+			var n []string
+			for _, s := range stops {
+				n = append(n, s["Name"].(string))
+			}
+			return n
+		}())
+		assert.Nil(t, stops[0]["DistanceFromPreviousKm"])
+		assert.InDelta(t, 1.0, stops[1]["DistanceFromPreviousKm"].(float64), 0.15)
+		assert.Nil(t, stops[2]["DistanceFromPreviousKm"]) // ไม่มีพิกัด
+		assert.Nil(t, stops[3]["DistanceFromPreviousKm"]) // วันใหม่
+		assert.Equal(t, 2.0, stops[3]["Day"])
+		assert.Equal(t, 0.0, stops[3]["Position"])
+		assert.Equal(t, stops[1]["DistanceFromPreviousKm"], out["data"].(map[string]interface{})["EstimatedDistanceKm"])
+	})
+
+	t.Run("Updating stops replaces them and recomputes the total", func(t *testing.T) {
+		path := "/trip-offers/" + strconv.Itoa(int(offerID))
+		resp, out := do("PUT", path, map[string]interface{}{
+			"stops": []map[string]interface{}{
+				{"day": 1, "attraction_id": palace.ID},
+				{"day": 1, "attraction_id": ayutthaya.ID},
+			},
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		stops := stopsOf(out, "offer")
+		assert.Len(t, stops, 2)
+		assert.InDelta(t, 88, stops[1]["DistanceFromPreviousKm"].(float64), 5) // ~68 กม. เส้นตรง
+		assert.Greater(t, stops[1]["TravelMinutesFromPrevious"].(float64), 120.0)
+
+		resp, out = do("PUT", path, map[string]interface{}{"title": "Temples only"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Len(t, stopsOf(out, "offer"), 2)
+
+		resp, out = do("PUT", path, map[string]interface{}{"stops": []interface{}{}})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Empty(t, stopsOf(out, "offer"))
+		assert.Equal(t, 0.0, out["offer"].(map[string]interface{})["EstimatedDistanceKm"])
+	})
+}
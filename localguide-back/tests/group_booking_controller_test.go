@@ -0,0 +1,409 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+func TestGroupBookings(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripOfferQuotation{}, &models.TripOfferStop{}, &models.TripBooking{}, &models.TripBookingParticipant{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.PaymentOutbox{}, &models.DocumentSequence{}, &models.Invoice{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	newUser := func(email, first string, roleID uint) models.User {
+		authUser := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&authUser)
+		user := models.User{AuthUserID: authUser.ID, FirstName: first, LastName: "Test", RoleID: roleID}
+		db.Create(&user)
+		return user
+	}
+	organiser := newUser("organiser@example.com", "Org", 1)
+	friend := newUser("friend@example.com", "Friend", 1)
+	stranger := newUser("stranger@example.com", "Stranger", 1)
+	userGuide := newUser("guide@example.com", "Guide", 2)
+	guide := models.Guide{UserID: userGuide.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	tripRequire := models.TripRequire{
+		UserID:      organiser.ID,
+		ProvinceID:  province.ID,
+		Title:       "Group trip",
+		Description: "desc",
+		MinPrice:    1000,
+		MaxPrice:    3000,
+		StartDate:   time.Now().AddDate(0, 0, 7),
+		EndDate:     time.Now().AddDate(0, 0, 7),
+		Days:        1,
+		Status:      "assigned",
+		GroupSize:   2,
+	}
+	db.Create(&tripRequire)
+	offer := models.TripOffer{TripRequireID: tripRequire.ID, GuideID: guide.ID, Title: "Offer", Description: "desc", Status: "accepted"}
+	db.Create(&offer)
+	booking := models.TripBooking{
+		TripOfferID:   offer.ID,
+		UserID:        organiser.ID,
+		GuideID:       guide.ID,
+		StartDate:     tripRequire.StartDate,
+		TotalAmount:   2001,
+		Status:        "pending_payment",
+		PaymentStatus: "pending",
+	}
+	db.Create(&booking)
+	bookingPath := "/trip-bookings/" + strconv.Itoa(int(booking.ID))
+
+	// ผู้ใช้ที่ทำ request ปัจจุบัน
+	actor := organiser.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Post("/trip-bookings/:id/participants", as(controllers.InviteBookingParticipant))
+	app.Get("/trip-bookings/:id/participants", as(controllers.GetBookingParticipants))
+	app.Get("/booking-invites", as(controllers.GetMyBookingInvites))
+	app.Post("/booking-invites/:token/accept", as(controllers.AcceptBookingInvite))
+	app.Post("/booking-invites/:token/decline", as(controllers.DeclineBookingInvite))
+	app.Delete("/trip-bookings/:id/participants/:participantId", as(controllers.RemoveBookingParticipant))
+	app.Post("/trip-bookings/:id/split-payment", as(controllers.SetupSplitPayment))
+	app.Post("/trip-bookings/:id/shares/pay", as(controllers.CreateSharePayment))
+	app.Post("/trip-bookings/:id/payment", as(controllers.CreateTripPayment))
+	app.Post("/webhook/stripe", controllers.StripeWebhook)
+
+	doJSON := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	// ส่ง webhook ที่ sign แล้ว (ไม่ต้องต่อ Stripe จริง)
+	secret := "whsec_test"
+	os.Setenv("STRIPE_WEBHOOK_SECRET", secret)
+	defer os.Unsetenv("STRIPE_WEBHOOK_SECRET")
+	sendPaymentSucceededAmount := func(paymentIntentID string, amountCents int64) int {
+		object := map[string]interface{}{"id": paymentIntentID, "object": "payment_intent", "status": "succeeded"}
+		if amountCents > 0 {
+			object["amount"] = amountCents
+		}
+		payload, _ := json.Marshal(map[string]interface{}{
+			"id":          "evt_" + paymentIntentID,
+			"object":      "event",
+			"type":        "payment_intent.succeeded",
+			"api_version": stripe.APIVersion,
+			"data": map[string]interface{}{
+				"object": object,
+			},
+		})
+		signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{Payload: payload, Secret: secret})
+		req := httptest.NewRequest("POST", "/webhook/stripe", bytes.NewBuffer(payload))
+		req.Header.Set("Stripe-Signature", signed.Header)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp.StatusCode
+	}
+	sendPaymentSucceeded := func(paymentIntentID string) int {
+		return sendPaymentSucceededAmount(paymentIntentID, 0)
+	}
+
+	t.Run("Invite co-traveller within group size", func(t *testing.T) {
+		actor = organiser.ID
+		resp, _ := doJSON("POST", bookingPath+"/participants", map[string]interface{}{
+			"email":      "Friend@example.com",
+			"first_name": "Friend",
+			"phone":      "0812345678",
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		// organiser + friend = group size 2 แล้ว
+		resp, _ = doJSON("POST", bookingPath+"/participants", map[string]interface{}{"email": "extra@example.com"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var notifications int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", friend.ID, "booking_invite").Count(&notifications)
+		assert.Equal(t, int64(1), notifications)
+
+		// คนนอกดูรายชื่อไม่ได้
+		actor = stranger.ID
+		resp, _ = doJSON("GET", bookingPath+"/participants", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// ไกด์เห็นข้อมูลติดต่อ
+		actor = userGuide.ID
+		resp, out := doJSON("GET", bookingPath+"/participants", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		participants := out["participants"].([]interface{})
+		assert.Len(t, participants, 2)
+		assert.Equal(t, "0812345678", participants[1].(map[string]interface{})["Phone"])
+	})
+
+	t.Run("Accept invite links account", func(t *testing.T) {
+		actor = friend.ID
+		_, out := doJSON("GET", "/booking-invites", nil)
+		invites := out["invites"].([]interface{})
+		if !assert.Len(t, invites, 1) {
+			return
+		}
+		token := invites[0].(map[string]interface{})["token"].(string)
+
+		resp, _ := doJSON("POST", "/booking-invites/"+token+"/accept", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var participant models.TripBookingParticipant
+		db.Where("trip_booking_id = ? AND email = ?", booking.ID, "friend@example.com").First(&participant)
+		assert.Equal(t, "accepted", participant.InviteStatus)
+		if assert.NotNil(t, participant.UserID) {
+			assert.Equal(t, friend.ID, *participant.UserID)
+		}
+	})
+
+	t.Run("Invite token cannot be used by another account", func(t *testing.T) {
+		b := models.TripBooking{TripOfferID: offer.ID, UserID: organiser.ID, GuideID: guide.ID, StartDate: tripRequire.StartDate, TotalAmount: 1000, Status: "pending_payment", PaymentStatus: "pending"}
+		db.Create(&b)
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, Email: "friend@example.com", InviteToken: "tok-bound", InviteStatus: "invited"})
+
+		actor = stranger.ID
+		resp, out := doJSON("POST", "/booking-invites/tok-bound/accept", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		assert.Equal(t, "invitation_email_mismatch", out["code"])
+		resp, _ = doJSON("POST", "/booking-invites/tok-bound/decline", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		var participant models.TripBookingParticipant
+		db.Where("invite_token = ?", "tok-bound").First(&participant)
+		assert.Equal(t, "invited", participant.InviteStatus)
+		assert.Nil(t, participant.UserID)
+	})
+
+	t.Run("Split payment divides total and settles per share", func(t *testing.T) {
+		actor = friend.ID
+		resp, _ := doJSON("POST", bookingPath+"/split-payment", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		actor = organiser.ID
+		resp, _ = doJSON("POST", bookingPath+"/split-payment", map[string]interface{}{
+			"shares": []map[string]interface{}{{"participant_id": 1, "amount": 100.0}},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = doJSON("POST", bookingPath+"/split-payment", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var shares []models.TripBookingParticipant
+		db.Where("trip_booking_id = ?", booking.ID).Order("is_organizer DESC").Find(&shares)
+		assert.Equal(t, 1000.5, shares[0].ShareAmount)
+		assert.Equal(t, 1000.5, shares[1].ShareAmount)
+		assert.Equal(t, "pending", shares[1].ShareStatus)
+
+		// จ่ายแบบเต็มจำนวนไม่ได้แล้ว
+		resp, _ = doJSON("POST", bookingPath+"/payment", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		// คนนอกจ่ายส่วนแบ่งไม่ได้
+		actor = stranger.ID
+		resp, _ = doJSON("POST", bookingPath+"/shares/pay", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// จำลองว่าแต่ละคนสร้าง PaymentIntent แล้ว
+		db.Model(&shares[0]).Update("stripe_payment_intent_id", "pi_organiser")
+		db.Model(&shares[1]).Update("stripe_payment_intent_id", "pi_friend")
+
+		assert.Equal(t, http.StatusOK, sendPaymentSucceeded("pi_friend"))
+		db.First(&booking, booking.ID)
+		assert.Equal(t, "pending_payment", booking.Status)
+
+		assert.Equal(t, http.StatusOK, sendPaymentSucceeded("pi_organiser"))
+		db.First(&booking, booking.ID)
+		assert.Equal(t, "paid", booking.Status)
+		assert.Equal(t, "paid", booking.PaymentStatus)
+
+		var payment models.TripPayment
+		db.Where("trip_booking_id = ?", booking.ID).First(&payment)
+		assert.Equal(t, "paid", payment.Status)
+		assert.Equal(t, 2001.0, payment.TotalAmount)
+		assert.Equal(t, 2001.0, payment.FirstPayment+payment.SecondPayment)
+		assert.Empty(t, payment.StripePaymentIntentID)
+
+		db.Where("trip_booking_id = ?", booking.ID).Order("is_organizer DESC").Find(&shares)
+		assert.Equal(t, 1000.5, shares[0].PaidAmount)
+		assert.Equal(t, 1000.5, shares[1].PaidAmount)
+	})
+
+	t.Run("Split payment halves are rounded to satang", func(t *testing.T) {
+		b := models.TripBooking{TripOfferID: offer.ID, UserID: organiser.ID, GuideID: guide.ID, StartDate: tripRequire.StartDate, TotalAmount: 1000.05, Status: "pending_payment", PaymentStatus: "pending"}
+		db.Create(&b)
+		orgID, friendID := organiser.ID, friend.ID
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, UserID: &orgID, Email: "organiser@example.com", IsOrganizer: true, InviteToken: "tok-round-org", InviteStatus: "accepted"})
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, UserID: &friendID, Email: "friend@example.com", InviteToken: "tok-round-friend", InviteStatus: "accepted"})
+
+		actor = organiser.ID
+		resp, _ := doJSON("POST", "/trip-bookings/"+strconv.Itoa(int(b.ID))+"/split-payment", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var payment models.TripPayment
+		db.Where("trip_booking_id = ?", b.ID).First(&payment)
+		assert.Equal(t, 500.03, payment.FirstPayment)
+		assert.Equal(t, 500.02, payment.SecondPayment)
+		assert.Empty(t, payment.StripePaymentIntentID)
+	})
+
+	t.Run("Covering payment is adjusted and over-collection refunded", func(t *testing.T) {
+		adjuster := &fakeIntentAdjuster{}
+		controllers.SharePaymentIntents = adjuster
+		defer func() { controllers.SharePaymentIntents = services.NewStripeService() }()
+
+		b := models.TripBooking{TripOfferID: offer.ID, UserID: organiser.ID, GuideID: guide.ID, StartDate: tripRequire.StartDate, TotalAmount: 1000, Status: "pending_payment", PaymentStatus: "pending", SplitPayment: true}
+		db.Create(&b)
+		db.Create(&models.TripPayment{TripBookingID: b.ID, PaymentNumber: "PAY-ADJUST", TransactionID: "GROUP-ADJUST", TotalAmount: 1000, FirstPayment: 500, SecondPayment: 500, PaymentMethod: "stripe_split", Status: "pending"})
+		orgID, friendID := organiser.ID, friend.ID
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, UserID: &orgID, Email: "organiser@example.com", IsOrganizer: true, InviteToken: "tok-adjust-org", InviteStatus: "accepted", ShareAmount: 500, ShareStatus: "pending", StripePaymentIntentID: "pi_adjust_cover", CoversRemainder: true})
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, UserID: &friendID, Email: "friend@example.com", InviteToken: "tok-adjust-friend", InviteStatus: "accepted", ShareAmount: 500, ShareStatus: "pending", StripePaymentIntentID: "pi_adjust_friend"})
+
+		// เพื่อนจ่ายส่วนของตัวเองก่อน -> PaymentIntent จ่ายแทนเหลือแค่ส่วนของผู้จอง
+		assert.Equal(t, http.StatusOK, sendPaymentSucceededAmount("pi_adjust_friend", 50000))
+		assert.Equal(t, int64(50000), adjuster.updates["pi_adjust_cover"])
+		db.First(&b, b.ID)
+		assert.Equal(t, "pending_payment", b.Status)
+
+		// Stripe ตัดเงินยอดเดิมไปแล้ว -> คืนส่วนเกินเข้า PaymentIntent ของผู้จอง
+		assert.Equal(t, http.StatusOK, sendPaymentSucceededAmount("pi_adjust_cover", 100000))
+		db.First(&b, b.ID)
+		assert.Equal(t, "paid", b.Status)
+
+		var cover models.TripBookingParticipant
+		db.Where("invite_token = ?", "tok-adjust-org").First(&cover)
+		assert.Equal(t, 500.0, cover.PaidAmount)
+
+		var refund models.PaymentRelease
+		assert.NoError(t, db.Where("release_type = ? AND reason = ?", "overpayment_refund", "cover_remainder_overpaid").First(&refund).Error)
+		assert.Equal(t, 500.0, refund.Amount)
+		assert.Equal(t, organiser.ID, refund.RecipientID)
+		var entry models.PaymentOutbox
+		assert.NoError(t, db.Where("payment_release_id = ?", refund.ID).First(&entry).Error)
+		assert.Equal(t, "pi_adjust_cover", entry.Target)
+		assert.Equal(t, int64(50000), entry.AmountCents)
+	})
+
+	t.Run("Declining or removing a pending share moves it to the organiser", func(t *testing.T) {
+		adjuster := &fakeIntentAdjuster{}
+		controllers.SharePaymentIntents = adjuster
+		defer func() { controllers.SharePaymentIntents = services.NewStripeService() }()
+
+		b := models.TripBooking{TripOfferID: offer.ID, UserID: organiser.ID, GuideID: guide.ID, StartDate: tripRequire.StartDate, TotalAmount: 1500, Status: "pending_payment", PaymentStatus: "pending", SplitPayment: true}
+		db.Create(&b)
+		db.Create(&models.TripPayment{TripBookingID: b.ID, PaymentNumber: "PAY-REASSIGN", TransactionID: "GROUP-REASSIGN", TotalAmount: 1500, FirstPayment: 750, SecondPayment: 750, PaymentMethod: "stripe_split", Status: "pending"})
+		orgID := organiser.ID
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, UserID: &orgID, Email: "organiser@example.com", IsOrganizer: true, InviteToken: "tok-reassign-org", InviteStatus: "accepted", ShareAmount: 500, ShareStatus: "pending", StripePaymentIntentID: "pi_reassign_org"})
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, Email: "friend@example.com", InviteToken: "tok-reassign-friend", InviteStatus: "invited", ShareAmount: 500, ShareStatus: "pending", StripePaymentIntentID: "pi_reassign_friend"})
+		removed := models.TripBookingParticipant{TripBookingID: b.ID, Email: "other@example.com", InviteToken: "tok-reassign-other", InviteStatus: "invited", ShareAmount: 500, ShareStatus: "pending"}
+		db.Create(&removed)
+
+		actor = friend.ID
+		resp, _ := doJSON("POST", "/booking-invites/tok-reassign-friend/decline", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.ElementsMatch(t, []string{"pi_reassign_friend", "pi_reassign_org"}, adjuster.cancelled)
+
+		var declined, org models.TripBookingParticipant
+		db.Where("invite_token = ?", "tok-reassign-friend").First(&declined)
+		assert.Equal(t, "declined", declined.InviteStatus)
+		assert.Equal(t, "none", declined.ShareStatus)
+		assert.Equal(t, 0.0, declined.ShareAmount)
+		assert.Empty(t, declined.StripePaymentIntentID)
+		db.Where("invite_token = ?", "tok-reassign-org").First(&org)
+		assert.Equal(t, "pending", org.ShareStatus)
+		assert.Equal(t, 1000.0, org.ShareAmount)
+		assert.Empty(t, org.StripePaymentIntentID)
+
+		actor = organiser.ID
+		resp, _ = doJSON("DELETE", "/trip-bookings/"+strconv.Itoa(int(b.ID))+"/participants/"+strconv.Itoa(int(removed.ID)), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		db.Where("invite_token = ?", "tok-reassign-org").First(&org)
+		assert.Equal(t, 1500.0, org.ShareAmount)
+
+		var remaining float64
+		db.Model(&models.TripBookingParticipant{}).Where("trip_booking_id = ? AND share_status = ?", b.ID, "pending").Select("COALESCE(SUM(share_amount), 0)").Scan(&remaining)
+		assert.Equal(t, 1500.0, remaining)
+	})
+
+	t.Run("A pending share is not moved onto an organiser who already paid", func(t *testing.T) {
+		b := models.TripBooking{TripOfferID: offer.ID, UserID: organiser.ID, GuideID: guide.ID, StartDate: tripRequire.StartDate, TotalAmount: 1000, Status: "pending_payment", PaymentStatus: "pending", SplitPayment: true}
+		db.Create(&b)
+		orgID := organiser.ID
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, UserID: &orgID, Email: "organiser@example.com", IsOrganizer: true, InviteToken: "tok-paid-org", InviteStatus: "accepted", ShareAmount: 500, ShareStatus: "paid", PaidAmount: 500})
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, Email: "friend@example.com", InviteToken: "tok-paid-friend", InviteStatus: "invited", ShareAmount: 500, ShareStatus: "pending"})
+
+		actor = friend.ID
+		resp, out := doJSON("POST", "/booking-invites/tok-paid-friend/decline", nil)
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, "organiser_share_already_paid", out["code"])
+
+		var participant models.TripBookingParticipant
+		db.Where("invite_token = ?", "tok-paid-friend").First(&participant)
+		assert.Equal(t, "invited", participant.InviteStatus)
+		assert.Equal(t, "pending", participant.ShareStatus)
+	})
+
+	t.Run("Organiser covering remainder marks pending shares covered", func(t *testing.T) {
+		b := models.TripBooking{TripOfferID: offer.ID, UserID: organiser.ID, GuideID: guide.ID, StartDate: tripRequire.StartDate, TotalAmount: 1000, Status: "pending_payment", PaymentStatus: "pending", SplitPayment: true}
+		db.Create(&b)
+		db.Create(&models.TripPayment{TripBookingID: b.ID, PaymentNumber: "PAY-COVER", TransactionID: "GROUP-COVER", TotalAmount: 1000, FirstPayment: 500, SecondPayment: 500, PaymentMethod: "stripe_split", Status: "pending"})
+		orgID := organiser.ID
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, UserID: &orgID, Email: "organiser@example.com", IsOrganizer: true, InviteToken: "tok-org", InviteStatus: "accepted", ShareAmount: 500, ShareStatus: "pending", StripePaymentIntentID: "pi_cover", CoversRemainder: true})
+		db.Create(&models.TripBookingParticipant{TripBookingID: b.ID, Email: "late@example.com", InviteToken: "tok-late", InviteStatus: "invited", ShareAmount: 500, ShareStatus: "pending"})
+
+		assert.Equal(t, http.StatusOK, sendPaymentSucceeded("pi_cover"))
+
+		var late models.TripBookingParticipant
+		db.Where("invite_token = ?", "tok-late").First(&late)
+		assert.Equal(t, "covered", late.ShareStatus)
+
+		db.First(&b, b.ID)
+		assert.Equal(t, "paid", b.Status)
+	})
+}
+
+// fakeIntentAdjuster - แทน Stripe จำยอดที่ปรับและ PaymentIntent ที่ยกเลิก
+type fakeIntentAdjuster struct {
+	updates   map[string]int64
+	cancelled []string
+}
+
+func (a *fakeIntentAdjuster) UpdatePaymentIntentAmount(paymentIntentID string, amount int64) error {
+	if a.updates == nil {
+		a.updates = map[string]int64{}
+	}
+	a.updates[paymentIntentID] = amount
+	return nil
+}
+
+func (a *fakeIntentAdjuster) CancelPaymentIntent(paymentIntentID string) error {
+	a.cancelled = append(a.cancelled, paymentIntentID)
+	return nil
+}
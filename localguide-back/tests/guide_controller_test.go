@@ -20,7 +20,7 @@ func TestGuideController(t *testing.T) {
 	app := setupTestApp()
 
 	// Migrate tables
-	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.GuideCertification{}, &models.Language{}, &models.TouristAttraction{})
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.GuideRatingSummary{}, &models.GuideCertification{}, &models.Language{}, &models.TouristAttraction{})
 
 	// Seed data
 	roleGuide := models.Role{Name: "guide"}
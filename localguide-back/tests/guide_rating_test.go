@@ -0,0 +1,172 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuideRatingAggregates(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.GuideRatingSummary{}, &models.GuideCertification{}, &models.Language{}, &models.TouristAttraction{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripBooking{}, &models.TripReview{}, &models.TripReviewImage{})
+
+	p := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&p)
+	au := models.AuthUser{Email: "u@example.com", Password: "hash"}
+	db.Create(&au)
+	user := models.User{AuthUserID: au.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	newGuide := func(email string) models.Guide {
+		a := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&a)
+		u := models.User{AuthUserID: a.ID, FirstName: "Guide", LastName: email, RoleID: 2}
+		db.Create(&u)
+		g := models.Guide{UserID: u.ID, ProvinceID: p.ID, Description: "desc", Available: true}
+		db.Create(&g)
+		return g
+	}
+	newBooking := func(g models.Guide) models.TripBooking {
+		req := models.TripRequire{UserID: user.ID, ProvinceID: p.ID, Title: "Trip", Description: "", MinPrice: 100, MaxPrice: 200, Days: 1, StartDate: time.Now(), EndDate: time.Now(), Status: "open"}
+		db.Create(&req)
+		offer := models.TripOffer{TripRequireID: req.ID, GuideID: g.ID, Title: "Offer", Description: "desc", Status: "accepted"}
+		db.Create(&offer)
+		booking := models.TripBooking{TripOfferID: offer.ID, UserID: user.ID, GuideID: g.ID, StartDate: time.Now(), TotalAmount: 1000, Status: "trip_completed"}
+		db.Create(&booking)
+		return booking
+	}
+	addReview := func(g models.Guide, rating float64) {
+		booking := newBooking(g)
+		db.Create(&models.TripReview{TripBookingID: booking.ID, UserID: user.ID, GuideID: g.ID, Rating: rating, ServiceRating: rating, KnowledgeRating: rating, CommunicationRating: rating, PunctualityRating: rating, IsVerified: true})
+	}
+
+	oneReview := newGuide("one@example.com")
+	established := newGuide("many@example.com")
+	unreviewed := newGuide("new@example.com")
+	reviewed := newGuide("crud@example.com")
+
+	addReview(oneReview, 5)
+	for i := 0; i < 200; i++ {
+		rating := 5.0
+		if i%5 == 0 {
+			rating = 4
+		}
+		addReview(established, rating)
+	}
+	assert.NoError(t, services.BackfillGuideRatings(db))
+
+	app.Get("/guides", controllers.GetGuides)
+	app.Get("/guides/:id/reviews", controllers.GetGuideReviews)
+	app.Post("/reviews", func(c *fiber.Ctx) error { c.Locals("user_id", user.ID); return controllers.CreateReview(c) })
+	app.Put("/reviews/:id", func(c *fiber.Ctx) error { c.Locals("user_id", user.ID); return controllers.UpdateReview(c) })
+	app.Delete("/reviews/:id", func(c *fiber.Ctx) error { c.Locals("user_id", user.ID); return controllers.DeleteReview(c) })
+
+	do := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	t.Run("Bayesian score ranks many good reviews above one perfect review", func(t *testing.T) {
+		var single, many models.GuideRatingSummary
+		db.First(&single, "guide_id = ?", oneReview.ID)
+		db.First(&many, "guide_id = ?", established.ID)
+		assert.Equal(t, 200, many.ReviewCount)
+		assert.InDelta(t, 4.8, many.AverageRating, 0.001)
+		assert.Equal(t, 160, many.FiveStars)
+		assert.Equal(t, 40, many.FourStars)
+		assert.InDelta(t, 5.0, single.AverageRating, 0.001)
+		assert.Greater(t, many.BayesianScore, single.BayesianScore)
+
+		_, out := do("GET", "/guides", nil)
+		guides := out["guides"].([]interface{})
+		if assert.Len(t, guides, 4) {
+			assert.Equal(t, float64(established.ID), guides[0].(map[string]interface{})["ID"])
+			assert.Equal(t, float64(oneReview.ID), guides[1].(map[string]interface{})["ID"])
+		}
+
+		_, out = do("GET", "/guides?sort=rating", nil)
+		guides = out["guides"].([]interface{})
+		assert.Equal(t, float64(oneReview.ID), guides[0].(map[string]interface{})["ID"])
+
+		resp, _ := do("GET", "/guides?sort=price", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		// ไกด์ที่ยังไม่มีรีวิวได้คะแนนตั้งต้น
+		_, out = do("GET", "/guides/"+strconv.Itoa(int(unreviewed.ID))+"/reviews", nil)
+		summary := out["summary"].(map[string]interface{})
+		assert.Equal(t, 0.0, summary["ReviewCount"])
+		assert.Equal(t, 4.0, summary["BayesianScore"])
+	})
+
+	t.Run("Sub-rating aggregates follow create, update and delete", func(t *testing.T) {
+		booking := newBooking(reviewed)
+		resp, out := do("POST", "/reviews", map[string]interface{}{
+			"trip_booking_id":      booking.ID,
+			"rating":               4,
+			"service_rating":       5,
+			"knowledge_rating":     4,
+			"communication_rating": 3,
+			"punctuality_rating":   2,
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		reviewID := uint(out["review"].(map[string]interface{})["ID"].(float64))
+
+		var summary models.GuideRatingSummary
+		db.First(&summary, "guide_id = ?", reviewed.ID)
+		assert.Equal(t, 1, summary.ReviewCount)
+		assert.InDelta(t, 5.0, summary.AverageService, 0.001)
+		assert.InDelta(t, 4.0, summary.AverageKnowledge, 0.001)
+		assert.InDelta(t, 3.0, summary.AverageCommunication, 0.001)
+		assert.InDelta(t, 2.0, summary.AveragePunctuality, 0.001)
+		assert.Equal(t, 1, summary.FourStars)
+		assert.InDelta(t, 4.0, summary.BayesianScore, 0.001)
+
+		resp, _ = do("PUT", "/reviews/"+strconv.Itoa(int(reviewID)), map[string]interface{}{
+			"rating":               1,
+			"service_rating":       1,
+			"knowledge_rating":     1,
+			"communication_rating": 1,
+			"punctuality_rating":   1,
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		db.First(&summary, "guide_id = ?", reviewed.ID)
+		assert.Equal(t, 1, summary.OneStar)
+		assert.Equal(t, 0, summary.FourStars)
+		assert.InDelta(t, (10*4.0+1)/11, summary.BayesianScore, 0.001)
+
+		_, out = do("GET", "/guides/"+strconv.Itoa(int(reviewed.ID))+"/reviews", nil)
+		assert.Equal(t, 1.0, out["summary"].(map[string]interface{})["OneStar"])
+
+		resp, _ = do("DELETE", "/reviews/"+strconv.Itoa(int(reviewID)), nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		db.First(&summary, "guide_id = ?", reviewed.ID)
+		assert.Equal(t, 0, summary.ReviewCount)
+		assert.InDelta(t, 4.0, summary.BayesianScore, 0.001)
+
+		var g models.Guide
+		db.First(&g, reviewed.ID)
+		assert.InDelta(t, 0.0, g.Rating, 0.001)
+	})
+}
@@ -0,0 +1,313 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/jpeg"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/migrations"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// jpegWithExif - JPEG ขนาด w x h ที่มี APP1 EXIF (Orientation และข้อความจำลองพิกัด GPS) ต่อจาก SOI
+func jpegWithExif(w, h int, orientation byte) []byte {
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h)), nil)
+	plain := buf.Bytes()
+
+	tiff := []byte{'I', 'I', 0x2A, 0x00, 0x08, 0x00, 0x00, 0x00, // header, IFD0 ที่ offset 8
+		0x01, 0x00, // 1 entry
+		0x12, 0x01, 0x03, 0x00, 0x01, 0x00, 0x00, 0x00, orientation, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00} // ไม่มี IFD ถัดไป
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	payload = append(payload, []byte("GPSLatitude 13.7563 GPSLongitude 100.5018")...)
+	segment := []byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}
+	segment = append(segment, payload...)
+
+	out := append([]byte{}, plain[:2]...)
+	out = append(out, segment...)
+	return append(out, plain[2:]...)
+}
+
+func TestImagePipeline(t *testing.T) {
+	t.Run("Metadata is stripped and orientation applied", func(t *testing.T) {
+		src := jpegWithExif(400, 300, 6)
+		processed, err := services.ProcessImage(src)
+		assert.NoError(t, err)
+
+		assert.False(t, bytes.Contains(processed.Data, []byte("Exif")))
+		assert.False(t, bytes.Contains(processed.Data, []byte("GPSLatitude")))
+		// orientation 6 = หมุน 90 องศา ด้านกว้าง/สูงสลับกัน
+		assert.Equal(t, 300, processed.Width)
+		assert.Equal(t, 400, processed.Height)
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(processed.Data))
+		assert.NoError(t, err)
+		assert.Equal(t, 300, cfg.Width)
+
+		sizes := map[string][2]int{}
+		for _, v := range processed.Variants {
+			assert.False(t, bytes.Contains(v.Data, []byte("Exif")))
+			sizes[v.Name] = [2]int{v.Width, v.Height}
+		}
+		assert.Equal(t, [2]int{150, 200}, sizes["thumbnail"])
+		// ไม่ขยายรูปที่เล็กกว่าขนาด variant
+		assert.Equal(t, [2]int{300, 400}, sizes["medium"])
+		assert.Equal(t, [2]int{300, 400}, sizes["large"])
+	})
+
+	t.Run("Transparent PNG is flattened onto white", func(t *testing.T) {
+		processed, err := services.ProcessImage(encodeTransparentPNG(10, 10))
+		assert.NoError(t, err)
+		img, err := jpeg.Decode(bytes.NewReader(processed.Data))
+		assert.NoError(t, err)
+		r, g, b, _ := img.At(5, 5).RGBA()
+		assert.Greater(t, r>>8, uint32(240))
+		assert.Greater(t, g>>8, uint32(240))
+		assert.Greater(t, b>>8, uint32(240))
+	})
+
+	t.Run("Files that are not images are rejected", func(t *testing.T) {
+		_, err := services.ProcessImage([]byte("not an image"))
+		assert.True(t, errors.Is(err, services.ErrInvalidImage))
+
+		// หัวไฟล์เป็น PNG แต่เนื้อไฟล์เสีย ผ่านการตรวจ content type แต่ถอดรหัสไม่ได้
+		broken := append([]byte{0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a}, bytes.Repeat([]byte{0x00}, 600)...)
+		db := setupTestDB()
+		db.AutoMigrate(&models.Upload{}, &models.ImageVariant{})
+		svc := services.NewUploadService(db, services.NewLocalStorage(t.TempDir(), "/uploads"))
+		_, err = svc.Save(1, "review_image", "broken.png", bytes.NewReader(broken), int64(len(broken)))
+		assert.True(t, errors.Is(err, services.ErrInvalidImage))
+	})
+}
+
+func encodeTransparentPNG(w, h int) []byte {
+	return encodeTestImage(image.NewNRGBA(image.Rect(0, 0, w, h)))
+}
+
+func TestReviewImages(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.AuthUser{}, &models.User{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripBooking{}, &models.TripReview{}, &models.TripReviewImage{}, &models.GuideRatingSummary{}, &models.Notification{}, &models.Upload{}, &models.ImageVariant{})
+
+	dir := t.TempDir()
+	previous := controllers.FileStorage
+	controllers.FileStorage = services.NewLocalStorage(dir, "/uploads")
+	defer func() { controllers.FileStorage = previous }()
+
+	p := models.Province{Name: "Chiang Mai", Region: "North"}
+	db.Create(&p)
+	newUser := func(email string, roleID uint) models.User {
+		au := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&au)
+		u := models.User{AuthUserID: au.ID, FirstName: "Test", LastName: email, RoleID: roleID}
+		db.Create(&u)
+		return u
+	}
+	traveller := newUser("traveller@example.com", 1)
+	other := newUser("other@example.com", 1)
+	admin := newUser("admin@example.com", 3)
+	guideUser := newUser("guide@example.com", 2)
+	guide := models.Guide{UserID: guideUser.ID, ProvinceID: p.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+	req := models.TripRequire{UserID: traveller.ID, ProvinceID: p.ID, Title: "Trip", MinPrice: 100, MaxPrice: 200, Days: 1, StartDate: time.Now(), EndDate: time.Now(), Status: "open"}
+	db.Create(&req)
+	offer := models.TripOffer{TripRequireID: req.ID, GuideID: guide.ID, Title: "Offer", Description: "desc", Status: "accepted"}
+	db.Create(&offer)
+	booking := models.TripBooking{TripOfferID: offer.ID, UserID: traveller.ID, GuideID: guide.ID, StartDate: time.Now(), TotalAmount: 1000, Status: "trip_completed"}
+	db.Create(&booking)
+
+	actor := traveller.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Post("/uploads", as(controllers.UploadFile))
+	app.Post("/reviews", as(controllers.CreateReview))
+	app.Put("/reviews/:id", as(controllers.UpdateReview))
+	app.Get("/guides/:id/reviews", controllers.GetGuideReviews)
+	app.Put("/admin/attractions/:id/image", as(controllers.UploadAttractionImage))
+
+	uploadFile := func(path, field, purpose string, content []byte) map[string]interface{} {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if purpose != "" {
+			mw.WriteField("purpose", purpose)
+		}
+		fw, _ := mw.CreateFormFile(field, "photo.png")
+		fw.Write(content)
+		mw.Close()
+		r := httptest.NewRequest("PUT", path, &body)
+		if path == "/uploads" {
+			r.Method = "POST"
+		}
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		resp, err := app.Test(r)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return out
+	}
+	do := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		r := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		r.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(r)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	ratings := func(images interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"trip_booking_id":      booking.ID,
+			"rating":               5,
+			"service_rating":       5,
+			"knowledge_rating":     5,
+			"communication_rating": 5,
+			"punctuality_rating":   5,
+			"images":               images,
+		}
+	}
+
+	var reviewID int
+	t.Run("Images are stored in order with captions and variants", func(t *testing.T) {
+		first := uploadFile("/uploads", "file", "review_image", testPNG)["url"].(string)
+		second := uploadFile("/uploads", "file", "review_image", testPNG)["url"].(string)
+		assert.True(t, strings.HasSuffix(first, ".jpg"))
+
+		actor = other.ID
+		foreign := uploadFile("/uploads", "file", "review_image", testPNG)["url"].(string)
+		actor = traveller.ID
+		resp, _ := do("POST", "/reviews", ratings([]interface{}{foreign}))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		long := strings.Repeat("ก", 301)
+		resp, _ = do("POST", "/reviews", ratings([]map[string]string{{"url": first, "caption": long}}))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out := do("POST", "/reviews", ratings([]interface{}{
+			map[string]string{"url": second, "caption": "  Doi Suthep at sunrise  "},
+			first,
+			map[string]string{"url": "https://example.com/view.jpg"},
+		}))
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		review := out["review"].(map[string]interface{})
+		reviewID = int(review["ID"].(float64))
+		images := review["Images"].([]interface{})
+		if assert.Len(t, images, 3) {
+			img := images[0].(map[string]interface{})
+			assert.Equal(t, second, img["URL"])
+			assert.Equal(t, "Doi Suthep at sunrise", img["Caption"])
+			upload := img["Upload"].(map[string]interface{})
+			assert.Equal(t, 40.0, upload["Width"])
+			assert.Len(t, upload["Variants"], 3)
+			assert.Equal(t, first, images[1].(map[string]interface{})["URL"])
+			// URL ภายนอกไม่มีไฟล์ใน uploads
+			assert.Nil(t, images[2].(map[string]interface{})["UploadID"])
+		}
+	})
+
+	t.Run("Updating a review replaces its images", func(t *testing.T) {
+		var images []models.TripReviewImage
+		db.Where("trip_review_id = ?", reviewID).Order("position").Find(&images)
+		payload := ratings([]map[string]string{{"url": images[1].URL, "caption": "Old town"}})
+		resp, _ := do("PUT", "/reviews/"+strconv.Itoa(reviewID), payload)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, out := do("GET", "/guides/"+strconv.Itoa(int(guide.ID))+"/reviews", nil)
+		listed := out["reviews"].([]interface{})[0].(map[string]interface{})["Images"].([]interface{})
+		if assert.Len(t, listed, 1) {
+			assert.Equal(t, images[1].URL, listed[0].(map[string]interface{})["URL"])
+			assert.Equal(t, "Old town", listed[0].(map[string]interface{})["Caption"])
+			assert.Equal(t, 0.0, listed[0].(map[string]interface{})["Position"])
+		}
+	})
+
+	t.Run("Legacy images column is migrated to rows", func(t *testing.T) {
+		legacy := models.TripReview{TripBookingID: booking.ID, UserID: other.ID, GuideID: guide.ID, Rating: 4, ServiceRating: 4, KnowledgeRating: 4, CommunicationRating: 4, PunctualityRating: 4}
+		db.Create(&legacy)
+		db.Exec("ALTER TABLE trip_reviews ADD COLUMN `images` text")
+		db.Exec("UPDATE trip_reviews SET images = ? WHERE id = ?", `["/uploads/a.jpg","/uploads/b.jpg"]`, legacy.ID)
+
+		assert.NoError(t, migrations.MigrateReviewImages(db))
+		assert.False(t, db.Migrator().HasColumn(&models.TripReview{}, "images"))
+		var images []models.TripReviewImage
+		db.Where("trip_review_id = ?", legacy.ID).Order("position").Find(&images)
+		if assert.Len(t, images, 2) {
+			assert.Equal(t, "/uploads/a.jpg", images[0].URL)
+			assert.Equal(t, 1, images[1].Position)
+		}
+		// เรียกซ้ำได้
+		assert.NoError(t, migrations.MigrateReviewImages(db))
+	})
+
+	t.Run("Legacy images that are not JSON fall back to a URL list and unreadable ones keep the column", func(t *testing.T) {
+		newReview := func() models.TripReview {
+			review := models.TripReview{TripBookingID: booking.ID, UserID: other.ID, GuideID: guide.ID, Rating: 5, ServiceRating: 5, KnowledgeRating: 5, CommunicationRating: 5, PunctualityRating: 5}
+			db.Create(&review)
+			return review
+		}
+		urlsOf := func(reviewID uint) []string {
+			var urls []string
+			db.Model(&models.TripReviewImage{}).Where("trip_review_id = ?", reviewID).Order("position").Pluck("url", &urls)
+			return urls
+		}
+		single, list, broken := newReview(), newReview(), newReview()
+		db.Exec("ALTER TABLE trip_reviews ADD COLUMN `images` text")
+		db.Exec("UPDATE trip_reviews SET images = ? WHERE id = ?", "/uploads/c.jpg", single.ID)
+		db.Exec("UPDATE trip_reviews SET images = ? WHERE id = ?", "/uploads/d.jpg, /uploads/e.jpg", list.ID)
+		db.Exec("UPDATE trip_reviews SET images = ? WHERE id = ?", `["/uploads/f.jpg"`, broken.ID)
+
+		assert.NoError(t, migrations.MigrateReviewImages(db))
+		assert.Equal(t, []string{"/uploads/c.jpg"}, urlsOf(single.ID))
+		assert.Equal(t, []string{"/uploads/d.jpg", "/uploads/e.jpg"}, urlsOf(list.ID))
+		assert.Empty(t, urlsOf(broken.ID))
+		assert.True(t, db.Migrator().HasColumn(&models.TripReview{}, "images"))
+
+		// แก้ข้อมูลแล้วรันใหม่ รีวิวที่ย้ายแล้วไม่ถูกย้ายซ้ำ
+		db.Exec("UPDATE trip_reviews SET images = ? WHERE id = ?", `["/uploads/f.jpg"]`, broken.ID)
+		assert.NoError(t, migrations.MigrateReviewImages(db))
+		assert.Equal(t, []string{"/uploads/d.jpg", "/uploads/e.jpg"}, urlsOf(list.ID))
+		assert.Equal(t, []string{"/uploads/f.jpg"}, urlsOf(broken.ID))
+		assert.False(t, db.Migrator().HasColumn(&models.TripReview{}, "images"))
+	})
+
+	t.Run("Admin sets an attraction image with variants", func(t *testing.T) {
+		attraction := models.TouristAttraction{Name: "Wat Phra That", ProvinceID: p.ID, Category: "วัด"}
+		db.Create(&attraction)
+
+		actor = admin.ID
+		out := uploadFile("/admin/attractions/"+strconv.Itoa(int(attraction.ID))+"/image", "image", "", testPNG)
+		updated := out["attraction"].(map[string]interface{})
+		imageURL := updated["ImageURL"].(string)
+		assert.True(t, strings.HasPrefix(imageURL, "/uploads/attractions/"))
+		assert.Len(t, updated["Image"].(map[string]interface{})["Variants"], 3)
+
+		// รูปใหม่แทนที่รูปเดิมและลบไฟล์เก่า
+		uploadFile("/admin/attractions/"+strconv.Itoa(int(attraction.ID))+"/image", "image", "", testPNG)
+		var count int64
+		db.Model(&models.Upload{}).Where("purpose = ?", "attraction_image").Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+}
@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDocumentNumbers(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.DocumentSequence{})
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.Local)
+
+	next := func() string {
+		var number string
+		db.Transaction(func(tx *gorm.DB) error {
+			number, _ = services.NextDocumentNumber(tx, "INV", now)
+			return nil
+		})
+		return number
+	}
+
+	assert.Equal(t, "INV-2026-000001", next())
+
+	// rollback คืนเลขที่จองไว้ ไม่ขาดช่วง
+	db.Transaction(func(tx *gorm.DB) error {
+		number, _ := services.NextDocumentNumber(tx, "INV", now)
+		assert.Equal(t, "INV-2026-000002", number)
+		return errors.New("rollback")
+	})
+	assert.Equal(t, "INV-2026-000002", next())
+
+	// ปีใหม่เริ่มนับใหม่
+	number, err := services.NextDocumentNumber(db, "INV", now.AddDate(1, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, "INV-2027-000001", number)
+}
+
+func TestTripBookingInvoice(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.PaymentOutbox{}, &models.DocumentSequence{}, &models.Invoice{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authGuide := models.AuthUser{Email: "guide@example.com", Password: "hash"}
+	db.Create(&authGuide)
+	userGuide := models.User{AuthUserID: authGuide.ID, FirstName: "Somchai", LastName: "Guide", RoleID: 2}
+	db.Create(&userGuide)
+	guide := models.Guide{UserID: userGuide.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	authOther := models.AuthUser{Email: "other@example.com", Password: "hash"}
+	db.Create(&authOther)
+	other := models.User{AuthUserID: authOther.ID, FirstName: "Other", LastName: "User", RoleID: 1}
+	db.Create(&other)
+
+	newBooking := func(status string) (models.TripBooking, models.TripPayment) {
+		booking := models.TripBooking{TripOfferID: 1, UserID: user.ID, GuideID: guide.ID, StartDate: time.Now(), Days: 1, TotalAmount: 2000, Status: "paid", PaymentStatus: "paid"}
+		db.Create(&booking)
+		ref := strconv.Itoa(int(booking.ID))
+		payment := models.TripPayment{TripBookingID: booking.ID, PaymentNumber: "PAY-" + ref, TransactionID: "TXN-" + ref, StripePaymentIntentID: "pi_" + ref, TotalAmount: 2000, FirstPayment: 1000, SecondPayment: 1000, PaymentMethod: "stripe_card", Status: status}
+		db.Create(&payment)
+		return booking, payment
+	}
+
+	actor := user.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Get("/trip-bookings/:id/invoice", as(controllers.GetTripBookingInvoice))
+	app.Put("/trip-bookings/:id/billing", as(controllers.UpdateTripBookingBilling))
+
+	do := func(method, url string, payload interface{}) (*http.Response, []byte) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		out, _ := io.ReadAll(resp.Body)
+		return resp, out
+	}
+	documentsOf := func(booking models.TripBooking) []models.Invoice {
+		_, body := do("GET", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/invoice?format=json", nil)
+		var out struct {
+			Documents []models.Invoice `json:"documents"`
+		}
+		json.Unmarshal(body, &out)
+		return out.Documents
+	}
+
+	t.Run("Unpaid bookings have no invoice", func(t *testing.T) {
+		booking, _ := newBooking("pending")
+		resp, _ := do("GET", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/invoice", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Invoice has VAT, guide details and a sequential number", func(t *testing.T) {
+		booking, _ := newBooking("paid")
+		year := strconv.Itoa(time.Now().Year())
+
+		docs := documentsOf(booking)
+		if !assert.Len(t, docs, 1) {
+			return
+		}
+		invoice := docs[0]
+		assert.Equal(t, "INV-"+year+"-000001", invoice.Number)
+		assert.Equal(t, 2000.0, invoice.Amount)
+		assert.Equal(t, 7.0, invoice.VATRate)
+		assert.Equal(t, 130.84, invoice.VATAmount)
+		assert.Equal(t, 1869.16, invoice.NetAmount)
+		assert.Equal(t, 0.0, invoice.WithholdingAmount)
+		assert.Equal(t, "Somchai Guide", invoice.GuideName)
+		assert.Equal(t, "John Doe", invoice.BuyerName)
+
+		// เรียกซ้ำไม่ออกใบใหม่
+		assert.Len(t, documentsOf(booking), 1)
+
+		resp, body := do("GET", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/invoice", nil)
+		assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+		assert.True(t, strings.HasPrefix(string(body), "%PDF"))
+		assert.Contains(t, string(body), "TAX INVOICE / RECEIPT")
+		assert.Contains(t, string(body), "VAT 7%")
+
+		// ออกใบกำกับแล้วแก้ข้อมูลผู้ซื้อไม่ได้
+		resp, _ = do("PUT", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/billing", map[string]interface{}{"billing_name": "ACME"})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("Company billing adds withholding tax", func(t *testing.T) {
+		booking, _ := newBooking("pending")
+		resp, _ := do("PUT", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/billing", map[string]interface{}{"billing_tax_id": "0105556000000"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = do("PUT", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/billing", map[string]interface{}{
+			"billing_name": "ACME Co., Ltd.", "billing_tax_id": "0105556000000", "billing_address": "1 Silom Rd, Bangkok",
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		db.Model(&models.TripPayment{}).Where("trip_booking_id = ?", booking.ID).Update("status", "paid")
+		docs := documentsOf(booking)
+		if assert.Len(t, docs, 1) {
+			assert.Equal(t, "ACME Co., Ltd.", docs[0].BuyerName)
+			assert.Equal(t, 3.0, docs[0].WithholdingRate)
+			assert.Equal(t, 56.07, docs[0].WithholdingAmount)
+		}
+	})
+
+	t.Run("Partial refund issues a credit note and updates the invoice", func(t *testing.T) {
+		booking, payment := newBooking("paid")
+		invoice := documentsOf(booking)[0]
+
+		db.Transaction(func(tx *gorm.DB) error {
+			_, err := services.QueueRefund(tx, &payment, &models.PaymentRelease{TripPaymentID: payment.ID, ReleaseType: "refund", Amount: 500, RecipientType: "user", RecipientID: user.ID, Reason: "user_no_show", ScheduledAt: time.Now()})
+			return err
+		})
+		done, err := services.NewPaymentOutboxWorker(db, &fakeGateway{}).RunOnce(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, done)
+
+		var notes []models.Invoice
+		db.Where("trip_booking_id = ? AND document_type = ?", booking.ID, "credit_note").Find(&notes)
+		if !assert.Len(t, notes, 1) {
+			return
+		}
+		note := notes[0]
+		assert.Equal(t, "CN-"+strconv.Itoa(time.Now().Year())+"-000001", note.Number)
+		assert.Equal(t, 500.0, note.Amount)
+		assert.Equal(t, 32.71, note.VATAmount)
+		assert.Equal(t, invoice.ID, *note.InvoiceID)
+
+		_, body := do("GET", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/invoice", nil)
+		assert.Contains(t, string(body), note.Number)
+		assert.Contains(t, string(body), "1500.00")
+
+		_, body = do("GET", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/invoice?number="+note.Number, nil)
+		assert.Contains(t, string(body), "CREDIT NOTE")
+		assert.Contains(t, string(body), invoice.Number)
+	})
+
+	t.Run("Only booking participants can download", func(t *testing.T) {
+		booking, _ := newBooking("paid")
+
+		actor = other.ID
+		resp, _ := do("GET", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/invoice", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		actor = userGuide.ID
+		resp, _ = do("GET", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/invoice", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		actor = user.ID
+	})
+}
@@ -0,0 +1,247 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// ทุกรหัสที่ controller/middleware ตอบใน "error" ต้องมีข้อความทั้งสองภาษา
+func TestMessageCatalogueCoversErrorCodes(t *testing.T) {
+	// "error": "...", socketError("...") และ validator ที่คืนรหัส (return "...", return fiber.StatusX, "...", errors.New("..."))
+	codePattern := regexp.MustCompile(`"error":\s*"([^"]+)"|socketError\("([^"]+)"\)|(?m)^\s*return (?:[\w.]+, )*"([a-z0-9]+(?:_[a-z0-9]+)+)"$|(?:errors\.New|fmt\.Errorf)\("([a-z0-9]+(?:_[a-z0-9]+)+)"\)`)
+	comment := regexp.MustCompile(`(?m)^\s*//.*$`)
+	files, _ := filepath.Glob("../controllers/*.go")
+	more, _ := filepath.Glob("../middleware/*.go")
+	files = append(files, more...)
+
+	seen := 0
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		for _, m := range codePattern.FindAllStringSubmatch(comment.ReplaceAllString(string(src), ""), -1) {
+			code := m[1] + m[2] + m[3] + m[4]
+			seen++
+			assert.True(t, services.HasMessage(code), "%s: %q is not in the message catalogue", filepath.Base(file), code)
+			assert.NotEqual(t, services.Message(code, "en"), services.Message(code, "th"), code)
+		}
+	}
+	assert.Greater(t, seen, 400)
+}
+
+func TestLocalizeErrorResponses(t *testing.T) {
+	db := setupTestDB()
+	config.DB = db
+	db.AutoMigrate(&models.AuthUser{}, &models.User{})
+	thaiUser := models.User{FirstName: "สมชาย", Language: "th"}
+	db.Create(&thaiUser)
+
+	app := setupTestApp()
+	app.Get("/bookings/:id", func(c *fiber.Ctx) error {
+		if c.Query("as") == "user" {
+			c.Locals("user_id", thaiUser.ID)
+		}
+		switch c.Params("id") {
+		case "missing":
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "booking_not_found", "booking_id": 12})
+		case "raw":
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "rating must be a number between 0 and 5"})
+		}
+		return c.JSON(fiber.Map{"error": "booking_not_found"})
+	})
+
+	get := func(url, acceptLanguage string) (*http.Response, map[string]interface{}) {
+		req := httptest.NewRequest("GET", url, nil)
+		if acceptLanguage != "" {
+			req.Header.Set("Accept-Language", acceptLanguage)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp, body
+	}
+
+	t.Run("Defaults to English with the code", func(t *testing.T) {
+		resp, body := get("/bookings/missing", "")
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+		assert.Equal(t, "Booking not found", body["error"])
+		assert.Equal(t, "booking_not_found", body["code"])
+		assert.Equal(t, float64(12), body["booking_id"])
+		assert.Equal(t, "en", resp.Header.Get("Content-Language"))
+	})
+
+	t.Run("Accept-Language picks the best supported language", func(t *testing.T) {
+		resp, body := get("/bookings/missing", "fr-FR, th-TH;q=0.8, en;q=0.5")
+		assert.Equal(t, "ไม่พบการจอง", body["error"])
+		assert.Equal(t, "th", resp.Header.Get("Content-Language"))
+
+		_, body = get("/bookings/missing", "th;q=0, en-GB")
+		assert.Equal(t, "Booking not found", body["error"])
+	})
+
+	t.Run("Query parameter wins over the header", func(t *testing.T) {
+		_, body := get("/bookings/missing?lang=en", "th")
+		assert.Equal(t, "Booking not found", body["error"])
+	})
+
+	t.Run("Logged-in user preference wins over the header", func(t *testing.T) {
+		_, body := get("/bookings/missing?as=user", "")
+		assert.Equal(t, "ไม่พบการจอง", body["error"])
+
+		_, body = get("/bookings/missing?as=user", "en-US")
+		assert.Equal(t, "ไม่พบการจอง", body["error"])
+
+		// ?lang ยังมาก่อนภาษาที่ผู้ใช้ตั้งไว้
+		_, body = get("/bookings/missing?as=user&lang=en", "")
+		assert.Equal(t, "Booking not found", body["error"])
+	})
+
+	t.Run("Unknown messages and successful responses are left alone", func(t *testing.T) {
+		_, body := get("/bookings/raw", "th")
+		assert.Equal(t, "rating must be a number between 0 and 5", body["error"])
+		assert.Nil(t, body["code"])
+
+		resp, body := get("/bookings/ok", "th")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "booking_not_found", body["error"])
+	})
+}
+
+func TestContentTranslations(t *testing.T) {
+	db := setupTestDB()
+	config.DB = db
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Guide{}, &models.GuideRatingSummary{}, &models.GuideCertification{},
+		&models.Language{}, &models.AttractionCategory{}, &models.GuideVertification{}, &models.TripRequire{})
+
+	province := models.Province{Name: "เชียงใหม่", Region: "ภาคเหนือ"}
+	db.Create(&province)
+	category := models.AttractionCategory{Name: "วัด"}
+	db.Create(&category)
+	temple := models.TouristAttraction{Name: "วัดพระธาตุดอยสุเทพ", Description: "วัดบนดอย", ProvinceID: province.ID, Category: "วัด", CategoryID: &category.ID}
+	db.Create(&temple)
+	thai := models.Language{Name: "ไทย"}
+	db.Create(&thai)
+	guideUser := models.User{FirstName: "ไกด์"}
+	db.Create(&guideUser)
+	guide := models.Guide{UserID: guideUser.ID, ProvinceID: province.ID, Bio: "ไกด์ท้องถิ่น", Description: "พาเที่ยววัด", Available: true,
+		Language: []models.Language{thai}, TouristAttraction: []models.TouristAttraction{temple}}
+	db.Create(&guide)
+
+	app := setupTestApp()
+	app.Get("/provinces", controllers.GetProvinces)
+	app.Get("/attractions", controllers.GetTouristAttractions)
+	app.Get("/guides/:id", controllers.GetGuideByID)
+	app.Get("/admin/translations/:type/:id", controllers.AdminGetTranslations)
+	app.Put("/admin/translations/:type/:id", controllers.AdminSetTranslations)
+	app.Delete("/admin/provinces/:id", controllers.AdminDeleteProvince)
+	app.Put("/guides/me/translations", func(c *fiber.Ctx) error {
+		c.Locals("user_id", guideUser.ID)
+		return controllers.SetMyGuideTranslations(c)
+	})
+
+	do := func(method, url, lang string, payload interface{}) (*http.Response, map[string]interface{}) {
+		var body []byte
+		if payload != nil {
+			body, _ = json.Marshal(payload)
+		}
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		if lang != "" {
+			req.Header.Set("Accept-Language", lang)
+		}
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	translationURL := func(kind string, id uint) string {
+		return "/admin/translations/" + kind + "/" + strconv.Itoa(int(id))
+	}
+	put := func(url string, payload interface{}) int {
+		resp, _ := do("PUT", url, "", payload)
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, put(translationURL("province", province.ID), fiber.Map{"language": "en", "fields": fiber.Map{"name": "Chiang Mai"}}))
+	assert.Equal(t, http.StatusOK, put(translationURL("attraction", temple.ID), fiber.Map{"language": "en", "fields": fiber.Map{"name": "Wat Phra That Doi Suthep"}}))
+	assert.Equal(t, http.StatusOK, put(translationURL("category", category.ID), fiber.Map{"language": "en", "fields": fiber.Map{"name": "Temple"}}))
+	assert.Equal(t, http.StatusOK, put(translationURL("language", thai.ID), fiber.Map{"language": "en", "fields": fiber.Map{"name": "Thai"}}))
+	assert.Equal(t, http.StatusOK, put("/guides/me/translations", fiber.Map{"language": "en", "bio": "Local guide"}))
+
+	t.Run("Translations replace base text for the requested language", func(t *testing.T) {
+		_, out := do("GET", "/attractions", "en-US,en;q=0.9", nil)
+		attraction := out["attractions"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "Wat Phra That Doi Suthep", attraction["Name"])
+		assert.Equal(t, "วัดบนดอย", attraction["Description"]) // ไม่มีคำแปลใช้ค่าเดิม
+		assert.Equal(t, "Chiang Mai", attraction["Province"].(map[string]interface{})["Name"])
+		assert.Equal(t, "Temple", attraction["Category"])
+
+		_, out = do("GET", "/attractions", "th", nil)
+		attraction = out["attractions"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "วัดพระธาตุดอยสุเทพ", attraction["Name"])
+		assert.Equal(t, "วัด", attraction["Category"])
+	})
+
+	t.Run("Guide profiles are localised including nested catalogue entries", func(t *testing.T) {
+		_, out := do("GET", "/guides/"+strconv.Itoa(int(guide.ID)), "en", nil)
+		g := out["guide"].(map[string]interface{})
+		assert.Equal(t, "Local guide", g["Bio"])
+		assert.Equal(t, "พาเที่ยววัด", g["Description"])
+		assert.Equal(t, "Thai", g["Language"].([]interface{})[0].(map[string]interface{})["Name"])
+		assert.Equal(t, "Chiang Mai", g["Province"].(map[string]interface{})["Name"])
+
+		_, out = do("GET", "/guides/"+strconv.Itoa(int(guide.ID)), "th", nil)
+		assert.Equal(t, "ไกด์ท้องถิ่น", out["guide"].(map[string]interface{})["Bio"])
+	})
+
+	t.Run("Empty values remove a translation", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, put(translationURL("language", thai.ID), fiber.Map{"language": "en", "fields": fiber.Map{"name": " "}}))
+		_, out := do("GET", translationURL("language", thai.ID), "", nil)
+		assert.Empty(t, out["translations"])
+		assert.Equal(t, []interface{}{"name"}, out["fields"])
+	})
+
+	t.Run("Invalid translation requests", func(t *testing.T) {
+		resp, out := do("PUT", translationURL("province", province.ID), "", fiber.Map{"language": "en", "fields": fiber.Map{"region": "North"}})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "translation_field_invalid", out["code"])
+		assert.Equal(t, []interface{}{"name"}, out["fields"])
+
+		resp, out = do("PUT", translationURL("province", province.ID), "", fiber.Map{"language": "fr", "fields": fiber.Map{"name": "x"}})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "language_invalid", out["code"])
+
+		resp, _ = do("GET", "/admin/translations/trip/1", "", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("GET", "/admin/translations/province/999", "", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Deleting a catalogue entry removes its translations", func(t *testing.T) {
+		spare := models.Province{Name: "ลำพูน", Region: "ภาคเหนือ"}
+		db.Create(&spare)
+		assert.Equal(t, http.StatusOK, put(translationURL("province", spare.ID), fiber.Map{"language": "en", "fields": fiber.Map{"name": "Lamphun"}}))
+		resp, _ := do("DELETE", "/admin/provinces/"+strconv.Itoa(int(spare.ID)), "", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var n int64
+		db.Unscoped().Model(&models.Translation{}).Where("entity_type = ? AND entity_id = ?", "province", spare.ID).Count(&n)
+		assert.Equal(t, int64(0), n)
+	})
+
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskContactDetails(t *testing.T) {
+	masked := services.MaskContactDetails("Call me at 081-234-5678 or mail john.doe@example.com")
+	assert.Equal(t, "Call me at [phone hidden] or mail [email hidden]", masked)
+
+	// ราคาและวันที่สั้นๆ ไม่ถูกปิด
+	assert.Equal(t, "Price 1,500 THB on 12/05", services.MaskContactDetails("Price 1,500 THB on 12/05"))
+}
+
+func TestMessageHub(t *testing.T) {
+	hub := services.NewMessageHub()
+	events, unsubscribe := hub.Subscribe(1)
+
+	hub.Publish(2, services.MessageEvent{Type: "message"})
+	hub.Publish(1, services.MessageEvent{Type: "read"})
+
+	select {
+	case event := <-events:
+		assert.Equal(t, "read", event.Type)
+		assert.Equal(t, uint(1), event.ConversationID)
+	case <-time.After(time.Second):
+		t.Fatal("expected event for subscribed conversation")
+	}
+
+	unsubscribe()
+	hub.Publish(1, services.MessageEvent{Type: "message"})
+}
+
+func TestMessaging(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripBooking{}, &models.Conversation{}, &models.Message{}, &models.MessageAttachment{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authGuide := models.AuthUser{Email: "guide@example.com", Password: "hash"}
+	db.Create(&authGuide)
+	userGuide := models.User{AuthUserID: authGuide.ID, FirstName: "Guide", LastName: "One", RoleID: 2}
+	db.Create(&userGuide)
+	guide := models.Guide{UserID: userGuide.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	authOther := models.AuthUser{Email: "other@example.com", Password: "hash"}
+	db.Create(&authOther)
+	other := models.User{AuthUserID: authOther.ID, FirstName: "Other", LastName: "User", RoleID: 1}
+	db.Create(&other)
+
+	now := time.Now()
+	tripRequire := models.TripRequire{UserID: user.ID, ProvinceID: province.ID, Title: "Trip", Description: "desc", MinPrice: 1000, MaxPrice: 3000, StartDate: now, EndDate: now, Days: 1}
+	db.Create(&tripRequire)
+	offer := models.TripOffer{TripRequireID: tripRequire.ID, GuideID: guide.ID, Title: "Offer", Description: "desc", Status: "sent"}
+	db.Create(&offer)
+
+	actor := user.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Post("/conversations", as(controllers.StartConversation))
+	app.Get("/conversations", as(controllers.GetConversations))
+	app.Get("/conversations/:id/messages", as(controllers.GetMessages))
+	app.Post("/conversations/:id/messages", as(controllers.SendMessage))
+	app.Put("/conversations/:id/read", as(controllers.MarkConversationRead))
+
+	doJSON := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	actor = user.ID
+	resp, out := doJSON("POST", "/conversations", map[string]interface{}{"trip_offer_id": offer.ID})
+	assert.Equal(t, http.StatusCreated, resp.StatusCode)
+	conversationID := int(out["conversation"].(map[string]interface{})["ID"].(float64))
+	path := "/conversations/" + strconv.Itoa(conversationID)
+
+	t.Run("Opening the same offer returns the existing conversation", func(t *testing.T) {
+		actor = userGuide.ID
+		resp, out := doJSON("POST", "/conversations", map[string]interface{}{"trip_offer_id": offer.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, float64(conversationID), out["conversation"].(map[string]interface{})["ID"])
+	})
+
+	t.Run("Non-members cannot read or post", func(t *testing.T) {
+		actor = other.ID
+		resp, _ := doJSON("GET", path+"/messages", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp, _ = doJSON("POST", path+"/messages", map[string]interface{}{"body": "hi"})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp, _ = doJSON("POST", "/conversations", map[string]interface{}{"trip_offer_id": offer.ID})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Attachments must come from uploads", func(t *testing.T) {
+		actor = user.ID
+		resp, _ := doJSON("POST", path+"/messages", map[string]interface{}{
+			"attachments": []map[string]string{{"url": "https://evil.example.com/x.png"}},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out := doJSON("POST", path+"/messages", map[string]interface{}{
+			"body":        "Here is my passport",
+			"attachments": []map[string]string{{"url": "/uploads/passport.jpg", "file_name": "passport.jpg"}},
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Len(t, out["message"].(map[string]interface{})["attachments"], 1)
+	})
+
+	t.Run("Contact details are masked until the booking is paid", func(t *testing.T) {
+		actor = userGuide.ID
+		resp, out := doJSON("POST", path+"/messages", map[string]interface{}{"body": "Line me: 0812345678"})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, "Line me: [phone hidden]", out["message"].(map[string]interface{})["body"])
+
+		var stored models.Message
+		db.Order("id DESC").First(&stored)
+		assert.Equal(t, "Line me: 0812345678", stored.Body)
+
+		booking := models.TripBooking{TripOfferID: offer.ID, UserID: user.ID, GuideID: guide.ID, StartDate: now, TotalAmount: 2000, Status: "paid", PaymentStatus: "paid"}
+		db.Create(&booking)
+
+		actor = user.ID
+		resp, out = doJSON("GET", path+"/messages", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, true, out["contacts_visible"])
+		messages := out["messages"].([]interface{})
+		assert.Equal(t, "Line me: 0812345678", messages[len(messages)-1].(map[string]interface{})["body"])
+	})
+
+	t.Run("Read receipts and unread counts", func(t *testing.T) {
+		actor = user.ID
+		_, out := doJSON("GET", "/conversations", nil)
+		conversations := out["conversations"].([]interface{})
+		if assert.Len(t, conversations, 1) {
+			assert.Equal(t, float64(1), conversations[0].(map[string]interface{})["unread_count"])
+		}
+
+		resp, out := doJSON("PUT", path+"/read", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, float64(1), out["marked_read"])
+
+		var guideMessage models.Message
+		db.Where("conversation_id = ? AND sender_id = ?", conversationID, userGuide.ID).First(&guideMessage)
+		assert.NotNil(t, guideMessage.ReadAt)
+
+		// ข้อความของตัวเองยังไม่ถูกอ่านโดยไกด์
+		var unreadByGuide int64
+		db.Model(&models.Message{}).Where("conversation_id = ? AND sender_id = ? AND read_at IS NULL", conversationID, user.ID).Count(&unreadByGuide)
+		assert.Equal(t, int64(1), unreadByGuide)
+	})
+
+	t.Run("Polling with after_id returns only newer messages", func(t *testing.T) {
+		actor = user.ID
+		var last models.Message
+		db.Order("id DESC").First(&last)
+
+		actor = userGuide.ID
+		doJSON("POST", path+"/messages", map[string]interface{}{"body": "See you tomorrow"})
+
+		actor = user.ID
+		_, out := doJSON("GET", path+"/messages?after_id="+strconv.Itoa(int(last.ID)), nil)
+		messages := out["messages"].([]interface{})
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, "See you tomorrow", messages[0].(map[string]interface{})["body"])
+		}
+
+		var notifications int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", user.ID, "new_message").Count(&notifications)
+		assert.Equal(t, int64(2), notifications)
+	})
+}
@@ -0,0 +1,356 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestNoShowDisputeTimeline(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.PaymentOutbox{}, &models.TripReport{}, &models.NoShowDispute{}, &models.DisputeStatement{}, &models.Notification{})
+
+	db.Create(&models.Role{Name: "user"})
+	db.Create(&models.Role{Name: "guide"})
+	db.Create(&models.Role{Name: "admin"})
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+	newUser := func(email string, roleID uint) models.User {
+		auth := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&auth)
+		u := models.User{AuthUserID: auth.ID, FirstName: "Test", LastName: email, RoleID: roleID}
+		db.Create(&u)
+		return u
+	}
+	traveller := newUser("user@example.com", 1)
+	guideUser := newUser("guide@example.com", 2)
+	admin := newUser("admin@example.com", 3)
+	stranger := newUser("stranger@example.com", 1)
+	guide := models.Guide{UserID: guideUser.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	now := time.Now()
+	newBooking := func(number string) models.TripBooking {
+		booking := models.TripBooking{TripOfferID: 1, UserID: traveller.ID, GuideID: guide.ID, StartDate: now, TotalAmount: 2000, Status: "paid", PaymentStatus: "paid"}
+		db.Create(&booking)
+		db.Create(&models.TripPayment{TripBookingID: booking.ID, PaymentNumber: number, TransactionID: "TXN-" + number, StripePaymentIntentID: "pi_" + number, TotalAmount: 2000, FirstPayment: 1000, SecondPayment: 1000, PaymentMethod: "stripe_card", Status: "paid", PaidAt: &now})
+		return booking
+	}
+
+	actor := guideUser.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Put("/trip-bookings/:id/report-user-no-show", as(controllers.ReportUserNoShow))
+	app.Put("/trip-bookings/:id/dispute-no-show", as(controllers.DisputeNoShowReport))
+	app.Get("/trip-bookings/:id/dispute", as(controllers.GetNoShowDispute))
+	app.Post("/trip-bookings/:id/dispute/statements", as(controllers.AddDisputeStatement))
+	app.Put("/admin/trip-bookings/:id/resolve-dispute", as(controllers.AdminResolveNoShowDispute))
+	app.Put("/trip-bookings/:id/confirm-user-no-show", as(controllers.ConfirmUserNoShow))
+
+	do := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	bookingPath := func(b models.TripBooking) string {
+		return "/trip-bookings/" + strconv.Itoa(int(b.ID))
+	}
+	reportNoShow := func(b models.TripBooking) {
+		actor = guideUser.ID
+		resp, _ := do("PUT", bookingPath(b)+"/report-user-no-show", map[string]interface{}{"description": "Waited an hour at the meeting point", "evidence": "/uploads/meeting.jpg"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+	countNotifications := func(userID uint, notifType string) int64 {
+		var n int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", userID, notifType).Count(&n)
+		return n
+	}
+	releases := func(b models.TripBooking) []models.PaymentRelease {
+		var list []models.PaymentRelease
+		db.Joins("JOIN trip_payments ON trip_payments.id = payment_releases.trip_payment_id").
+			Where("trip_payments.trip_booking_id = ?", b.ID).Order("payment_releases.id").Find(&list)
+		return list
+	}
+
+	t.Run("Both parties exchange statements and the case waits for an admin", func(t *testing.T) {
+		booking := newBooking("PAY-1")
+		reportNoShow(booking)
+
+		var dispute models.NoShowDispute
+		db.Where("trip_booking_id = ?", booking.ID).First(&dispute)
+		assert.Equal(t, "awaiting_traveller", dispute.Stage)
+		assert.WithinDuration(t, now.Add(24*time.Hour), dispute.RespondBy, time.Minute)
+
+		// ยังไม่คัดค้าน ส่งคำชี้แจงเพิ่มไม่ได้
+		actor = traveller.ID
+		resp, _ := do("POST", bookingPath(booking)+"/dispute/statements", map[string]interface{}{"statement": "I was there"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = do("PUT", bookingPath(booking)+"/dispute-no-show", map[string]interface{}{"description": ""})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, out := do("PUT", bookingPath(booking)+"/dispute-no-show", map[string]interface{}{
+			"description":    "I was at the meeting point, the guide was not",
+			"evidence_files": []string{"/uploads/selfie.jpg", "https://maps.example.com/timeline"},
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.NotNil(t, out["evidence_due_at"])
+		assert.Equal(t, int64(1), countNotifications(guideUser.ID, "no_show_disputed"))
+
+		var report models.TripReport
+		db.Where("trip_booking_id = ? AND report_type = ?", booking.ID, "dispute_no_show").First(&report)
+		assert.Equal(t, guideUser.ID, report.ReportedUserID)
+
+		actor = guideUser.ID
+		resp, _ = do("POST", bookingPath(booking)+"/dispute/statements", map[string]interface{}{"statement": "Here is my GPS log", "evidence": []string{"ftp://x"}})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", bookingPath(booking)+"/dispute/statements", map[string]interface{}{"statement": "Here is my GPS log", "evidence": []string{"/uploads/gps.png"}})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, int64(1), countNotifications(traveller.ID, "dispute_statement_added"))
+
+		actor = stranger.ID
+		resp, _ = do("GET", bookingPath(booking)+"/dispute", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		actor = traveller.ID
+		_, out = do("GET", bookingPath(booking)+"/dispute", nil)
+		view := out["dispute"].(map[string]interface{})
+		assert.Equal(t, "evidence", view["stage"])
+		statements := view["statements"].([]interface{})
+		if assert.Len(t, statements, 3) {
+			assert.Equal(t, "guide", statements[0].(map[string]interface{})["party"])
+			assert.Equal(t, []interface{}{"/uploads/meeting.jpg"}, statements[0].(map[string]interface{})["evidence"])
+			assert.Equal(t, "traveller", statements[1].(map[string]interface{})["party"])
+			assert.Len(t, statements[1].(map[string]interface{})["evidence"], 2)
+			assert.Equal(t, "guide", statements[2].(map[string]interface{})["party"])
+		}
+		assert.Nil(t, view["decision"])
+
+		// ปิดรับหลักฐาน -> รอ admin ตัดสิน
+		n, err := services.NewNoShowDisputeService(db).RunOnce(now.Add(73 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		db.First(&dispute, dispute.ID)
+		assert.Equal(t, "awaiting_decision", dispute.Stage)
+		assert.Equal(t, int64(1), countNotifications(admin.ID, "dispute_awaiting_decision"))
+		assert.Empty(t, releases(booking))
+
+		// admin ต้องเขียนเหตุผลของคำตัดสิน
+		actor = admin.ID
+		resp, _ = do("PUT", "/admin"+bookingPath(booking)+"/resolve-dispute", map[string]interface{}{"decision": "user_wins", "reason": "  "})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Traveller who misses the deadline gets the no-show confirmed", func(t *testing.T) {
+		booking := newBooking("PAY-2")
+		reportNoShow(booking)
+
+		n, err := services.NewNoShowDisputeService(db).RunOnce(now.Add(25 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		list := releases(booking)
+		if assert.Len(t, list, 2) {
+			assert.Equal(t, "guide", list[0].RecipientType)
+			assert.Equal(t, 1000.0, list[0].Amount)
+			assert.Equal(t, "user", list[1].RecipientType)
+			assert.Equal(t, 1000.0, list[1].Amount)
+		}
+		var b models.TripBooking
+		db.First(&b, booking.ID)
+		assert.Equal(t, "no_show_confirmed", b.Status)
+
+		actor = guideUser.ID
+		_, out := do("GET", bookingPath(booking)+"/dispute", nil)
+		view := out["dispute"].(map[string]interface{})
+		assert.Equal(t, "decided", view["stage"])
+		decision := view["decision"].(map[string]interface{})
+		assert.Equal(t, "guide_wins", decision["decision"])
+		assert.Equal(t, "traveller_no_response", decision["source"])
+		assert.NotEmpty(t, decision["rationale"])
+
+		var open int64
+		db.Model(&models.TripReport{}).Where("trip_booking_id = ? AND status = ?", booking.ID, "pending").Count(&open)
+		assert.Equal(t, int64(0), open)
+
+		// เลยกำหนดแล้วคัดค้านไม่ได้
+		actor = traveller.ID
+		resp, _ := do("PUT", bookingPath(booking)+"/dispute-no-show", map[string]interface{}{"description": "Too late"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Guide who ignores the dispute loses by default", func(t *testing.T) {
+		booking := newBooking("PAY-3")
+		reportNoShow(booking)
+
+		actor = traveller.ID
+		resp, _ := do("PUT", bookingPath(booking)+"/dispute-no-show", map[string]interface{}{"description": "I was there"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// ยังไม่ถึงกำหนด ไม่มีอะไรเปลี่ยน
+		n, err := services.NewNoShowDisputeService(db).RunOnce(now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		n, err = services.NewNoShowDisputeService(db).RunOnce(now.Add(73 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		list := releases(booking)
+		if assert.Len(t, list, 1) {
+			assert.Equal(t, "user", list[0].RecipientType)
+			assert.Equal(t, 2000.0, list[0].Amount)
+		}
+		var b models.TripBooking
+		db.First(&b, booking.ID)
+		assert.Equal(t, "cancelled", b.Status)
+
+		var dispute models.NoShowDispute
+		db.Where("trip_booking_id = ?", booking.ID).First(&dispute)
+		assert.Equal(t, "user_wins", dispute.Decision)
+		assert.Equal(t, "guide_no_response", dispute.DecisionSource)
+		var resolved int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ? AND related_id = ?", traveller.ID, "dispute_resolved", booking.ID).Count(&resolved)
+		assert.Equal(t, int64(1), resolved)
+	})
+
+	t.Run("Admin decision settles the remaining escrow and records the decision in one transaction", func(t *testing.T) {
+		booking := newBooking("PAY-4")
+		reportNoShow(booking)
+		actor = traveller.ID
+		resp, _ := do("PUT", bookingPath(booking)+"/dispute-no-show", map[string]interface{}{"description": "I was there"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// คืนเงินที่ยังรออยู่ใน outbox ต้องถูกหักออกก่อนแบ่งเงิน
+		var payment models.TripPayment
+		db.Where("trip_booking_id = ?", booking.ID).First(&payment)
+		queued := models.PaymentRelease{TripPaymentID: payment.ID, ReleaseType: "refund", Amount: 400, RecipientType: "user", RecipientID: traveller.ID, Reason: "goodwill", ScheduledAt: now}
+		_, err := services.QueueRefund(db, &payment, &queued)
+		assert.NoError(t, err)
+
+		actor = admin.ID
+		resp, out := do("PUT", "/admin"+bookingPath(booking)+"/resolve-dispute", map[string]interface{}{"decision": "split_cost", "reason": "Both sides were late"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "split_cost", out["decision"])
+
+		list := releases(booking)
+		if assert.Len(t, list, 3) {
+			assert.Equal(t, "guide", list[1].RecipientType)
+			assert.Equal(t, 400.0, list[1].Amount)
+			assert.Equal(t, "user", list[2].RecipientType)
+			assert.Equal(t, 1200.0, list[2].Amount)
+		}
+		var b models.TripBooking
+		db.First(&b, booking.ID)
+		assert.Equal(t, "no_show_split", b.Status)
+		var dispute models.NoShowDispute
+		db.Where("trip_booking_id = ?", booking.ID).First(&dispute)
+		assert.Equal(t, "decided", dispute.Stage)
+		assert.Equal(t, "admin", dispute.DecisionSource)
+		assert.Equal(t, "Both sides were late", dispute.Rationale)
+		var open int64
+		db.Model(&models.TripReport{}).Where("trip_booking_id = ? AND status = ?", booking.ID, "pending").Count(&open)
+		assert.Equal(t, int64(0), open)
+		var resolved int64
+		db.Model(&models.Notification{}).Where("type = ? AND related_id = ?", "dispute_resolved", booking.ID).Count(&resolved)
+		assert.Equal(t, int64(2), resolved)
+
+		// ตัดสินซ้ำไม่ตั้งรายการเงินเพิ่ม
+		resp, _ = do("PUT", "/admin"+bookingPath(booking)+"/resolve-dispute", map[string]interface{}{"decision": "user_wins", "reason": "Changed my mind"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Len(t, releases(booking), 3)
+	})
+
+	t.Run("A failed admin decision leaves the dispute open", func(t *testing.T) {
+		booking := newBooking("PAY-5")
+		reportNoShow(booking)
+		// เงินถูกตั้งจ่าย/คืนครบแล้ว ไม่มีอะไรให้แบ่ง
+		db.Model(&models.TripPayment{}).Where("trip_booking_id = ?", booking.ID).Update("total_amount", 0)
+
+		actor = admin.ID
+		resp, out := do("PUT", "/admin"+bookingPath(booking)+"/resolve-dispute", map[string]interface{}{"decision": "guide_wins", "reason": "GPS log"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		assert.Equal(t, "payment_fully_refunded", out["code"])
+
+		var b models.TripBooking
+		db.First(&b, booking.ID)
+		assert.Equal(t, "user_no_show_reported", b.Status)
+		var dispute models.NoShowDispute
+		db.Where("trip_booking_id = ?", booking.ID).First(&dispute)
+		assert.Equal(t, "awaiting_traveller", dispute.Stage)
+		var open int64
+		db.Model(&models.TripReport{}).Where("trip_booking_id = ? AND status = ?", booking.ID, "pending").Count(&open)
+		assert.Equal(t, int64(1), open)
+		var resolved int64
+		db.Model(&models.Notification{}).Where("type = ? AND related_id = ?", "dispute_resolved", booking.ID).Count(&resolved)
+		assert.Equal(t, int64(0), resolved)
+	})
+
+	t.Run("Confirming a no-show twice pays out once", func(t *testing.T) {
+		booking := newBooking("PAY-6")
+		reportNoShow(booking)
+
+		actor = traveller.ID
+		resp, _ := do("PUT", bookingPath(booking)+"/confirm-user-no-show", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp, _ = do("PUT", bookingPath(booking)+"/confirm-user-no-show", nil)
+		assert.NotEqual(t, http.StatusOK, resp.StatusCode)
+		assert.Len(t, releases(booking), 2)
+
+		var b models.TripBooking
+		db.First(&b, booking.ID)
+		assert.Equal(t, "user_no_show_confirmed", b.Status)
+	})
+
+	t.Run("Deadline job skips a dispute that changed stage after it was listed", func(t *testing.T) {
+		booking := newBooking("PAY-7")
+		reportNoShow(booking)
+
+		// user คัดค้านหลังจาก job อ่านรายการ dispute ที่เลยกำหนดไปแล้ว
+		fired := false
+		db.Callback().Query().After("gorm:query").Register("test:traveller_disputed", func(d *gorm.DB) {
+			if d.Statement.Table == "no_show_disputes" && !fired {
+				fired = true
+				d.Session(&gorm.Session{NewDB: true}).Exec("UPDATE no_show_disputes SET stage = ?, disputed_at = ? WHERE stage = ?", "evidence", now, "awaiting_traveller")
+			}
+		})
+		defer db.Callback().Query().Remove("test:traveller_disputed")
+
+		n, err := services.NewNoShowDisputeService(db).RunOnce(now.Add(25 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		assert.Empty(t, releases(booking))
+
+		var dispute models.NoShowDispute
+		db.Where("trip_booking_id = ?", booking.ID).First(&dispute)
+		assert.Equal(t, "evidence", dispute.Stage)
+		var b models.TripBooking
+		db.First(&b, booking.ID)
+		assert.Equal(t, "user_no_show_reported", b.Status)
+	})
+}
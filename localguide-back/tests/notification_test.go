@@ -0,0 +1,304 @@
+package tests
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSMTPServer - SMTP server จำลองสำหรับทดสอบ เก็บข้อความที่ได้รับไว้ใน channel
+func fakeSMTPServer(t *testing.T) (string, int, <-chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+				reply("220 localhost fake SMTP")
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					cmd := strings.ToUpper(strings.TrimSpace(line))
+					switch {
+					case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+						reply("250 localhost")
+					case strings.HasPrefix(cmd, "DATA"):
+						reply("354 end with .")
+						var data strings.Builder
+						for {
+							l, err := r.ReadString('\n')
+							if err != nil {
+								return
+							}
+							if l == ".\r\n" {
+								break
+							}
+							data.WriteString(l)
+						}
+						received <- data.String()
+						reply("250 OK")
+					case strings.HasPrefix(cmd, "QUIT"):
+						reply("221 bye")
+						return
+					default:
+						reply("250 OK")
+					}
+				}
+			}(conn)
+		}
+	}()
+
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, received
+}
+
+type failingChannel struct{}
+
+func (failingChannel) Name() string { return "email" }
+
+func (failingChannel) Deliver(entry *models.NotificationOutbox) error {
+	return errors.New("smtp unavailable")
+}
+
+func TestRenderNotification(t *testing.T) {
+	data := map[string]interface{}{"BookingID": 7, "Amount": 1500.0}
+
+	title, message, err := services.RenderNotification("payment_succeeded", "en", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "Payment successful", title)
+	assert.Equal(t, "Payment of 1500.00 THB for booking #7 was successful.", message)
+
+	// ภาษาที่ไม่รองรับใช้ภาษาไทย
+	title, _, err = services.RenderNotification("payment_succeeded", "jp", data)
+	assert.NoError(t, err)
+	assert.Equal(t, "ชำระเงินสำเร็จ", title)
+
+	_, _, err = services.RenderNotification("unknown_type", "en", data)
+	assert.Error(t, err)
+}
+
+func TestNotificationService(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Notification{}, &models.NotificationPreference{}, &models.NotificationOutbox{})
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1, Language: "en"}
+	db.Create(&user)
+
+	host, port, received := fakeSMTPServer(t)
+	os.Setenv("SMTP_HOST", host)
+	defer os.Unsetenv("SMTP_HOST")
+
+	notifier := services.NewNotificationService(db)
+
+	t.Run("Notification is stored in the feed and queued for email", func(t *testing.T) {
+		notification, err := notifier.Send(user.ID, "review_posted", map[string]interface{}{"BookingID": 3, "Rating": 5}, "trip_review", 1)
+		assert.NoError(t, err)
+		if assert.NotNil(t, notification) {
+			assert.Equal(t, "New review", notification.Title)
+		}
+
+		var outbox models.NotificationOutbox
+		assert.NoError(t, db.Where("user_id = ?", user.ID).First(&outbox).Error)
+		assert.Equal(t, "user@example.com", outbox.Recipient)
+		assert.Equal(t, "pending", outbox.Status)
+
+		sender := &services.SMTPSender{Host: host, Port: port, From: "no-reply@example.com"}
+		sent, err := services.NewNotificationDispatcher(db, services.NewEmailChannel(sender)).RunOnce(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		select {
+		case msg := <-received:
+			assert.Contains(t, msg, "To: user@example.com")
+			assert.Contains(t, msg, "Subject: New review")
+		case <-time.After(2 * time.Second):
+			t.Fatal("fake SMTP server did not receive the email")
+		}
+
+		db.First(&outbox, outbox.ID)
+		assert.Equal(t, "sent", outbox.Status)
+		assert.NotNil(t, outbox.SentAt)
+	})
+
+	t.Run("Preferences disable channels", func(t *testing.T) {
+		db.Create(&models.NotificationPreference{UserID: user.ID, Type: "*", Channel: "email", Enabled: false})
+		db.Create(&models.NotificationPreference{UserID: user.ID, Type: "new_message", Channel: "in_app", Enabled: false})
+		// เปิดเฉพาะประเภทนี้ มีผลเหนือ "*"
+		db.Create(&models.NotificationPreference{UserID: user.ID, Type: "dispute_resolved", Channel: "email", Enabled: true})
+
+		notification, err := notifier.Send(user.ID, "new_message", nil, "conversation", 1)
+		assert.NoError(t, err)
+		assert.Nil(t, notification)
+
+		var pending int64
+		db.Model(&models.NotificationOutbox{}).Where("status = ?", "pending").Count(&pending)
+		assert.Equal(t, int64(0), pending)
+
+		_, err = notifier.Send(user.ID, "dispute_resolved", map[string]interface{}{"BookingID": 3, "Decision": "user_wins"}, "trip_booking", 3)
+		assert.NoError(t, err)
+		db.Model(&models.NotificationOutbox{}).Where("status = ?", "pending").Count(&pending)
+		assert.Equal(t, int64(1), pending)
+	})
+
+	t.Run("Failed deliveries are retried then marked failed", func(t *testing.T) {
+		db.Model(&models.NotificationOutbox{}).Where("status = ?", "pending").Update("max_attempts", 2)
+
+		dispatcher := services.NewNotificationDispatcher(db, failingChannel{})
+		now := time.Now()
+		sent, err := dispatcher.RunOnce(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, sent)
+
+		var outbox models.NotificationOutbox
+		db.Where("status = ?", "pending").First(&outbox)
+		assert.Equal(t, 1, outbox.Attempts)
+		assert.Equal(t, "smtp unavailable", outbox.LastError)
+		assert.True(t, outbox.NextAttemptAt.After(now))
+
+		// ยังไม่ถึงเวลาลองใหม่
+		dispatcher.RunOnce(now)
+		db.First(&outbox, outbox.ID)
+		assert.Equal(t, 1, outbox.Attempts)
+
+		dispatcher.RunOnce(now.Add(time.Hour))
+		db.First(&outbox, outbox.ID)
+		assert.Equal(t, 2, outbox.Attempts)
+		assert.Equal(t, "failed", outbox.Status)
+	})
+}
+
+func TestNotificationFeed(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Notification{}, &models.NotificationPreference{}, &models.NotificationOutbox{})
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authOther := models.AuthUser{Email: "other@example.com", Password: "hash"}
+	db.Create(&authOther)
+	other := models.User{AuthUserID: authOther.ID, FirstName: "Other", LastName: "User", RoleID: 1}
+	db.Create(&other)
+
+	notifier := services.NewNotificationService(db)
+	for i := 1; i <= 3; i++ {
+		notifier.Send(user.ID, "booking_invite", map[string]interface{}{"BookingID": i}, "trip_booking", uint(i))
+	}
+	otherNotification, _ := notifier.Send(other.ID, "booking_invite", map[string]interface{}{"BookingID": 9}, "trip_booking", 9)
+
+	actor := user.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Get("/notifications", as(controllers.GetNotifications))
+	app.Get("/notifications/unread-count", as(controllers.GetUnreadNotificationCount))
+	app.Put("/notifications/read-all", as(controllers.MarkAllNotificationsRead))
+	app.Put("/notifications/:id/read", as(controllers.MarkNotificationRead))
+	app.Get("/notifications/preferences", as(controllers.GetNotificationPreferences))
+	app.Put("/notifications/preferences", as(controllers.UpdateNotificationPreferences))
+
+	doJSON := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	t.Run("Feed is in Thai by default with unread count", func(t *testing.T) {
+		resp, out := doJSON("GET", "/notifications?limit=2", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, float64(3), out["unread_count"])
+		assert.Equal(t, float64(3), out["total"])
+		items := out["notifications"].([]interface{})
+		if assert.Len(t, items, 2) {
+			assert.Equal(t, "คำเชิญร่วมทริป", items[0].(map[string]interface{})["Title"])
+		}
+	})
+
+	t.Run("Mark one and all as read", func(t *testing.T) {
+		var first models.Notification
+		db.Where("user_id = ?", user.ID).Order("id").First(&first)
+
+		resp, _ := doJSON("PUT", "/notifications/"+strconv.Itoa(int(first.ID))+"/read", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		_, out := doJSON("GET", "/notifications/unread-count", nil)
+		assert.Equal(t, float64(2), out["unread_count"])
+
+		_, out = doJSON("GET", "/notifications?unread=true", nil)
+		assert.Len(t, out["notifications"], 2)
+
+		// อ่านของคนอื่นไม่ได้
+		resp, _ = doJSON("PUT", "/notifications/"+strconv.Itoa(int(otherNotification.ID))+"/read", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		_, out = doJSON("PUT", "/notifications/read-all", nil)
+		assert.Equal(t, float64(2), out["marked_read"])
+
+		_, out = doJSON("GET", "/notifications/unread-count", nil)
+		assert.Equal(t, float64(0), out["unread_count"])
+	})
+
+	t.Run("Update language and channel preferences", func(t *testing.T) {
+		resp, _ := doJSON("PUT", "/notifications/preferences", map[string]interface{}{
+			"preferences": []map[string]interface{}{{"type": "*", "channel": "sms", "enabled": false}},
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out := doJSON("PUT", "/notifications/preferences", map[string]interface{}{
+			"language":    "en",
+			"preferences": []map[string]interface{}{{"type": "*", "channel": "email", "enabled": false}},
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "en", out["language"])
+		assert.Len(t, out["preferences"], 1)
+
+		notification, err := notifier.Send(user.ID, "booking_invite", map[string]interface{}{"BookingID": 4}, "trip_booking", 4)
+		assert.NoError(t, err)
+		assert.Equal(t, "Trip invitation", notification.Title)
+	})
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDraftOffersAndTemplates(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripOfferQuotation{}, &models.TripOfferStop{}, &models.TripOfferTemplate{}, &models.DocumentSequence{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authGuide := models.AuthUser{Email: "guide@example.com", Password: "hash"}
+	db.Create(&authGuide)
+	userGuide := models.User{AuthUserID: authGuide.ID, FirstName: "Guide", LastName: "One", RoleID: 2}
+	db.Create(&userGuide)
+	guide := models.Guide{UserID: userGuide.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	newTripRequire := func(title string) models.TripRequire {
+		tr := models.TripRequire{
+			UserID:      user.ID,
+			ProvinceID:  province.ID,
+			Title:       title,
+			Description: "Test",
+			MinPrice:    1000,
+			MaxPrice:    2000,
+			StartDate:   time.Now().AddDate(0, 0, 7),
+			EndDate:     time.Now().AddDate(0, 0, 10),
+			Days:        3,
+			Status:      "open",
+			GroupSize:   1,
+		}
+		db.Create(&tr)
+		return tr
+	}
+
+	asGuide := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", userGuide.ID)
+			return h(c)
+		}
+	}
+	app.Post("/trip-offers", asGuide(controllers.CreateTripOffer))
+	app.Put("/trip-offers/:id/send", asGuide(controllers.SendTripOffer))
+	app.Put("/trip-offers/:id", asGuide(controllers.UpdateTripOffer))
+	app.Get("/trip-requires/:id/offers", controllers.GetTripOffers)
+	app.Post("/offer-templates", asGuide(controllers.CreateOfferTemplate))
+	app.Get("/offer-templates", asGuide(controllers.GetOfferTemplates))
+	app.Post("/offer-templates/:id/instantiate", asGuide(controllers.InstantiateOfferTemplate))
+
+	doJSON := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	t.Run("Draft offer skips price check until sent", func(t *testing.T) {
+		tr := newTripRequire("Draft")
+
+		resp, out := doJSON("POST", "/trip-offers", map[string]interface{}{
+			"trip_require_id": tr.ID,
+			"title":           "Draft offer",
+			"description":     "desc",
+			"total_price":     5000.0,
+			"save_as_draft":   true,
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		offerID := uint(out["offer"].(map[string]interface{})["ID"].(float64))
+
+		var offer models.TripOffer
+		db.First(&offer, offerID)
+		assert.Equal(t, "draft", offer.Status)
+		assert.Nil(t, offer.SentAt)
+
+		// traveller ไม่เห็น draft
+		_, list := doJSON("GET", "/trip-requires/"+strconv.Itoa(int(tr.ID))+"/offers", nil)
+		assert.Len(t, list["offers"], 0)
+
+		// ส่งไม่ได้เพราะราคาเกินช่วง
+		resp, _ = doJSON("PUT", "/trip-offers/"+strconv.Itoa(int(offerID))+"/send", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		// แก้ราคาแล้วส่งได้
+		resp, _ = doJSON("PUT", "/trip-offers/"+strconv.Itoa(int(offerID)), map[string]interface{}{"total_price": 1500.0})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		resp, _ = doJSON("PUT", "/trip-offers/"+strconv.Itoa(int(offerID))+"/send", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		db.First(&offer, offerID)
+		assert.Equal(t, "sent", offer.Status)
+		assert.NotNil(t, offer.SentAt)
+
+		var updatedTR models.TripRequire
+		db.First(&updatedTR, tr.ID)
+		assert.Equal(t, "in_review", updatedTR.Status)
+
+		var notifications int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", user.ID, "offer_received").Count(&notifications)
+		assert.Equal(t, int64(1), notifications)
+	})
+
+	t.Run("Create template and instantiate against trip require", func(t *testing.T) {
+		resp, out := doJSON("POST", "/offer-templates", map[string]interface{}{
+			"name":              "City tour",
+			"title":             "Old town walking tour",
+			"description":       "Temples and markets",
+			"itinerary":         "Day 1: Temples",
+			"included_services": "Water",
+			"default_price":     1200.0,
+			"price_breakdown":   "Guide fee: 1200",
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		templateID := uint(out["template"].(map[string]interface{})["ID"].(float64))
+
+		_, list := doJSON("GET", "/offer-templates", nil)
+		assert.Len(t, list["templates"], 1)
+
+		tr := newTripRequire("Template")
+		resp, _ = doJSON("POST", "/offer-templates/"+strconv.Itoa(int(templateID))+"/instantiate", map[string]interface{}{
+			"trip_require_id": tr.ID,
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		var offer models.TripOffer
+		db.Where("trip_require_id = ? AND guide_id = ?", tr.ID, guide.ID).First(&offer)
+		assert.Equal(t, "draft", offer.Status)
+		assert.Equal(t, "Old town walking tour", offer.Title)
+		assert.Equal(t, "Day 1: Temples", offer.Itinerary)
+
+		var quotation models.TripOfferQuotation
+		db.Where("trip_offer_id = ?", offer.ID).First(&quotation)
+		assert.Equal(t, 1200.0, quotation.TotalPrice)
+		assert.Equal(t, "Guide fee: 1200", quotation.PriceBreakdown)
+	})
+
+	t.Run("Instantiate and send applies price range", func(t *testing.T) {
+		template := models.TripOfferTemplate{GuideID: guide.ID, Name: "Cheap", Title: "Cheap tour", Description: "desc", DefaultPrice: 500}
+		db.Create(&template)
+
+		tr := newTripRequire("Send Template")
+		resp, _ := doJSON("POST", "/offer-templates/"+strconv.Itoa(int(template.ID))+"/instantiate", map[string]interface{}{
+			"trip_require_id": tr.ID,
+			"send":            true,
+		})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var count int64
+		db.Model(&models.TripOffer{}).Where("trip_require_id = ?", tr.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+}
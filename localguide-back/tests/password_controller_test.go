@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForgotPassword(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.AuthUser{}, &models.PasswordReset{}, &models.NotificationOutbox{})
+	db.Create(&models.AuthUser{Email: "user@example.com", Password: "hash"})
+
+	app.Post("/auth/forgot-password", controllers.ForgotPassword)
+
+	forgot := func(email string) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(map[string]interface{}{"email": email})
+		req := httptest.NewRequest("POST", "/auth/forgot-password", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	t.Run("Reset email is queued when SMTP is configured", func(t *testing.T) {
+		os.Setenv("SMTP_HOST", "localhost")
+		defer os.Unsetenv("SMTP_HOST")
+
+		resp, _ := forgot("user@example.com")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var queued int64
+		db.Model(&models.NotificationOutbox{}).Where("channel = ? AND recipient = ?", "email", "user@example.com").Count(&queued)
+		assert.Equal(t, int64(1), queued)
+	})
+
+	t.Run("Reset email that cannot be queued is reported as a failure", func(t *testing.T) {
+		os.Unsetenv("SMTP_HOST")
+
+		resp, out := forgot("user@example.com")
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+		assert.Equal(t, "email_send_failed", out["code"])
+	})
+}
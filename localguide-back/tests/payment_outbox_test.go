@@ -0,0 +1,340 @@
+package tests
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/migrations"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeGateway - แทน Stripe จำ idempotency key ที่เคยเห็นเพื่อตรวจว่าไม่คืนเงินซ้ำ
+type fakeGateway struct {
+	failures int
+	calls    []string
+	refunds  map[string]string
+}
+
+func (g *fakeGateway) result(kind, key string) (string, error) {
+	g.calls = append(g.calls, key)
+	if g.failures > 0 {
+		g.failures--
+		return "", errors.New("stripe unavailable")
+	}
+	if g.refunds == nil {
+		g.refunds = map[string]string{}
+	}
+	if ref, ok := g.refunds[key]; ok {
+		return ref, nil
+	}
+	ref := kind + "_" + strconv.Itoa(len(g.refunds)+1)
+	g.refunds[key] = ref
+	return ref, nil
+}
+
+func (g *fakeGateway) CreateRefund(paymentIntentID string, amount int64, idempotencyKey string) (string, error) {
+	return g.result("re", idempotencyKey)
+}
+
+func (g *fakeGateway) CreateTransfer(destination string, amount int64, transferGroup, idempotencyKey string) (string, error) {
+	return g.result("tr", idempotencyKey)
+}
+
+func TestPaymentOutbox(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.PaymentOutbox{}, &models.TripBookingParticipant{}, &models.TripReport{}, &models.NoShowDispute{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authGuide := models.AuthUser{Email: "guide@example.com", Password: "hash"}
+	db.Create(&authGuide)
+	userGuide := models.User{AuthUserID: authGuide.ID, FirstName: "Guide", LastName: "One", RoleID: 2}
+	db.Create(&userGuide)
+	guide := models.Guide{UserID: userGuide.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	app.Put("/trip-bookings/:id/confirm-user-no-show", func(c *fiber.Ctx) error {
+		c.Locals("user_id", user.ID)
+		return controllers.ConfirmUserNoShow(c)
+	})
+
+	newReportedBooking := func() (models.TripBooking, models.TripPayment) {
+		booking := models.TripBooking{TripOfferID: 1, UserID: user.ID, GuideID: guide.ID, StartDate: time.Now(), TotalAmount: 2000, Status: "user_no_show_reported", PaymentStatus: "paid"}
+		db.Create(&booking)
+		ref := strconv.Itoa(int(booking.ID))
+		payment := models.TripPayment{TripBookingID: booking.ID, PaymentNumber: "PAY-" + ref, TransactionID: "TXN-" + ref, StripePaymentIntentID: "pi_" + ref, TotalAmount: 2000, FirstPayment: 1000, SecondPayment: 1000, PaymentMethod: "stripe_card", Status: "paid"}
+		db.Create(&payment)
+		return booking, payment
+	}
+
+	confirm := func(booking models.TripBooking) *http.Response {
+		req := httptest.NewRequest("PUT", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/confirm-user-no-show", nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		return resp
+	}
+
+	t.Run("Refund is queued with the booking change and settled by the worker", func(t *testing.T) {
+		booking, payment := newReportedBooking()
+
+		resp := confirm(booking)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		db.First(&booking, booking.ID)
+		assert.Equal(t, "user_no_show_confirmed", booking.Status)
+
+		var refund models.PaymentRelease
+		db.Where("trip_payment_id = ? AND release_type = ?", payment.ID, "refund").First(&refund)
+		assert.Equal(t, "pending", refund.Status)
+		assert.Empty(t, refund.TransactionRef)
+
+		// ไกด์ไม่มีบัญชี Stripe -> บันทึกเป็น processed (จ่ายนอกระบบ) ไม่มีคำสั่งโอนเงิน
+		var guideRelease models.PaymentRelease
+		db.Where("trip_payment_id = ? AND recipient_type = ?", payment.ID, "guide").First(&guideRelease)
+		assert.Equal(t, "processed", guideRelease.Status)
+
+		var entries []models.PaymentOutbox
+		db.Where("trip_payment_id = ?", payment.ID).Find(&entries)
+		if assert.Len(t, entries, 1) {
+			assert.Equal(t, "refund", entries[0].Action)
+			assert.Equal(t, int64(100000), entries[0].AmountCents)
+			assert.Equal(t, payment.StripePaymentIntentID, entries[0].Target)
+		}
+
+		// Stripe ล่มครั้งแรก ลองใหม่ด้วย key เดิม
+		gateway := &fakeGateway{failures: 1}
+		worker := services.NewPaymentOutboxWorker(db, gateway)
+		now := time.Now()
+		done, err := worker.RunOnce(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, done)
+
+		done, err = worker.RunOnce(now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, done)
+		if assert.Len(t, gateway.calls, 2) {
+			assert.Equal(t, gateway.calls[0], gateway.calls[1])
+		}
+
+		db.First(&refund, refund.ID)
+		assert.Equal(t, "processed", refund.Status)
+		assert.Equal(t, "re_1", refund.TransactionRef)
+		assert.NotNil(t, refund.ProcessedAt)
+
+		// ทำซ้ำไม่เรียก Stripe อีก
+		done, _ = worker.RunOnce(now.Add(2 * time.Hour))
+		assert.Equal(t, 0, done)
+		assert.Len(t, gateway.calls, 2)
+
+		// เรียก API ซ้ำไม่สร้างคำสั่งคืนเงินใหม่
+		resp = confirm(booking)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		var count int64
+		db.Model(&models.PaymentOutbox{}).Where("trip_payment_id = ?", payment.ID).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("Guides with a Stripe account are paid by transfer", func(t *testing.T) {
+		db.Model(&guide).Update("stripe_account_id", "acct_123")
+		defer db.Model(&guide).Update("stripe_account_id", "")
+
+		booking, payment := newReportedBooking()
+		resp := confirm(booking)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var transfer models.PaymentOutbox
+		assert.NoError(t, db.Where("trip_payment_id = ? AND action = ?", payment.ID, "transfer").First(&transfer).Error)
+		assert.Equal(t, "acct_123", transfer.Target)
+
+		done, err := services.NewPaymentOutboxWorker(db, &fakeGateway{}).RunOnce(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, done)
+
+		var guideRelease models.PaymentRelease
+		db.First(&guideRelease, transfer.PaymentReleaseID)
+		assert.Equal(t, "processed", guideRelease.Status)
+		assert.Equal(t, "tr_1", guideRelease.TransactionRef)
+	})
+
+	t.Run("Trip payouts to guides with a Stripe account wait for the transfer", func(t *testing.T) {
+		db.Model(&guide).Update("stripe_account_id", "acct_123")
+		defer db.Model(&guide).Update("stripe_account_id", "")
+
+		app.Put("/trip-bookings/:id/confirm-arrival", func(c *fiber.Ctx) error {
+			c.Locals("user_id", user.ID)
+			return controllers.ConfirmGuideArrival(c)
+		})
+		app.Put("/trip-bookings/:id/confirm-complete", func(c *fiber.Ctx) error {
+			c.Locals("user_id", user.ID)
+			return controllers.ConfirmTripComplete(c)
+		})
+
+		booking, payment := newReportedBooking()
+		db.Model(&booking).Update("status", "paid")
+		for _, action := range []string{"confirm-arrival", "confirm-complete"} {
+			req := httptest.NewRequest("PUT", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/"+action, nil)
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode, action)
+		}
+
+		var releases []models.PaymentRelease
+		db.Where("trip_payment_id = ? AND recipient_type = ?", payment.ID, "guide").Order("id").Find(&releases)
+		if assert.Len(t, releases, 2) {
+			for _, r := range releases {
+				assert.Equal(t, "pending", r.Status)
+				assert.Nil(t, r.ProcessedAt)
+			}
+		}
+
+		var transfers int64
+		db.Model(&models.PaymentOutbox{}).Where("trip_payment_id = ? AND action = ? AND target = ?", payment.ID, "transfer", "acct_123").Count(&transfers)
+		assert.Equal(t, int64(2), transfers)
+
+		done, err := services.NewPaymentOutboxWorker(db, &fakeGateway{}).RunOnce(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, done)
+		db.Where("trip_payment_id = ? AND recipient_type = ?", payment.ID, "guide").Order("id").Find(&releases)
+		for _, r := range releases {
+			assert.Equal(t, "processed", r.Status)
+			assert.NotEmpty(t, r.TransactionRef)
+		}
+	})
+
+	t.Run("Legacy unique release index is relaxed", func(t *testing.T) {
+		db.Migrator().DropIndex(&models.PaymentOutbox{}, "idx_payment_outboxes_payment_release_id")
+		db.Exec("CREATE UNIQUE INDEX idx_payment_outboxes_payment_release_id ON payment_outboxes (payment_release_id)")
+
+		assert.NoError(t, migrations.RelaxPaymentOutboxReleaseIndex(db))
+
+		indexes, err := db.Migrator().GetIndexes(&models.PaymentOutbox{})
+		assert.NoError(t, err)
+		found := false
+		for _, index := range indexes {
+			if index.Name() == "idx_payment_outboxes_payment_release_id" {
+				found = true
+				unique, _ := index.Unique()
+				assert.False(t, unique)
+			}
+		}
+		assert.True(t, found)
+	})
+
+	t.Run("Split-payment refunds are spread over each share's PaymentIntent", func(t *testing.T) {
+		booking, payment := newReportedBooking()
+		db.Model(&payment).Updates(map[string]interface{}{"payment_method": "stripe_split", "stripe_payment_intent_id": ""})
+		db.Create(&models.TripBookingParticipant{TripBookingID: booking.ID, Email: "a@example.com", InviteToken: "split-a-" + strconv.Itoa(int(booking.ID)), ShareAmount: 1200, ShareStatus: "paid", PaidAmount: 1200, StripePaymentIntentID: "pi_share_a"})
+		db.Create(&models.TripBookingParticipant{TripBookingID: booking.ID, Email: "b@example.com", InviteToken: "split-b-" + strconv.Itoa(int(booking.ID)), ShareAmount: 800, ShareStatus: "paid", StripePaymentIntentID: "pi_share_b"})
+
+		resp := confirm(booking)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var entries []models.PaymentOutbox
+		db.Where("trip_payment_id = ? AND action = ?", payment.ID, "refund").Order("id").Find(&entries)
+		if assert.Len(t, entries, 2) {
+			assert.Equal(t, "pi_share_a", entries[0].Target)
+			assert.Equal(t, int64(60000), entries[0].AmountCents)
+			assert.Equal(t, "pi_share_b", entries[1].Target)
+			assert.Equal(t, int64(40000), entries[1].AmountCents)
+			assert.NotEqual(t, entries[0].IdempotencyKey, entries[1].IdempotencyKey)
+			assert.Equal(t, entries[0].PaymentReleaseID, entries[1].PaymentReleaseID)
+		}
+
+		// สำเร็จคำสั่งเดียว release ยังไม่ processed
+		worker := services.NewPaymentOutboxWorker(db, &fakeGateway{})
+		db.Model(&entries[1]).Update("next_attempt_at", time.Now().Add(time.Hour))
+		done, err := worker.RunOnce(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, done)
+		var refund models.PaymentRelease
+		db.First(&refund, entries[0].PaymentReleaseID)
+		assert.Equal(t, "pending", refund.Status)
+
+		done, err = worker.RunOnce(time.Now().Add(2 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, done)
+		db.First(&refund, refund.ID)
+		assert.Equal(t, "processed", refund.Status)
+		assert.Equal(t, "re_1,re_2", refund.TransactionRef)
+	})
+
+	t.Run("Split-payment refunds with unknown shares are left for manual handling", func(t *testing.T) {
+		booking, payment := newReportedBooking()
+		db.Model(&payment).Updates(map[string]interface{}{"payment_method": "stripe_split", "stripe_payment_intent_id": ""})
+		db.Create(&models.TripBookingParticipant{TripBookingID: booking.ID, Email: "c@example.com", InviteToken: "split-c-" + strconv.Itoa(int(booking.ID)), ShareAmount: 1000, ShareStatus: "paid", CoversRemainder: true, StripePaymentIntentID: "pi_share_c"})
+
+		resp := confirm(booking)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var count int64
+		db.Model(&models.PaymentOutbox{}).Where("trip_payment_id = ? AND action = ?", payment.ID, "refund").Count(&count)
+		assert.Equal(t, int64(0), count)
+
+		var refund models.PaymentRelease
+		db.Where("trip_payment_id = ? AND release_type = ?", payment.ID, "refund").First(&refund)
+		assert.Equal(t, "pending", refund.Status)
+		assert.Contains(t, refund.Notes, "manual refund required")
+		db.First(&payment, payment.ID)
+		assert.Contains(t, payment.Notes, "manual refund required")
+	})
+
+	t.Run("Failures past the retry limit mark the release failed", func(t *testing.T) {
+		booking, payment := newReportedBooking()
+		confirm(booking)
+
+		worker := services.NewPaymentOutboxWorker(db, &fakeGateway{failures: 100})
+		now := time.Now()
+		for i := 0; i < 10; i++ {
+			worker.RunOnce(now.Add(time.Duration(i) * 24 * time.Hour))
+		}
+
+		var entry models.PaymentOutbox
+		db.Where("trip_payment_id = ?", payment.ID).First(&entry)
+		assert.Equal(t, "failed", entry.Status)
+		assert.Equal(t, "stripe unavailable", entry.LastError)
+
+		var refund models.PaymentRelease
+		db.First(&refund, entry.PaymentReleaseID)
+		assert.Equal(t, "failed", refund.Status)
+		assert.Contains(t, refund.Notes, "refund failed")
+	})
+
+	t.Run("DB failure rolls back the booking change", func(t *testing.T) {
+		booking, payment := newReportedBooking()
+
+		db.Migrator().DropTable(&models.PaymentOutbox{})
+		defer db.AutoMigrate(&models.PaymentOutbox{})
+
+		resp := confirm(booking)
+		assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+		db.First(&booking, booking.ID)
+		assert.Equal(t, "user_no_show_reported", booking.Status)
+		db.First(&payment, payment.ID)
+		assert.Equal(t, "paid", payment.Status)
+
+		var releases int64
+		db.Model(&models.PaymentRelease{}).Where("trip_payment_id = ?", payment.ID).Count(&releases)
+		assert.Equal(t, int64(0), releases)
+	})
+}
@@ -0,0 +1,390 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrivateFiles(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	config.JWTSecret = []byte("test-secret")
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripReport{}, &models.NoShowDispute{}, &models.DisputeStatement{}, &models.Conversation{}, &models.Message{}, &models.MessageAttachment{}, &models.Upload{}, &models.ImageVariant{}, &models.FileAccessLog{}, &models.Notification{}, &models.GuideVertification{})
+
+	dir := t.TempDir()
+	previous := controllers.FileStorage
+	storage := services.NewLocalStorage(dir, "/uploads")
+	controllers.FileStorage = storage
+	defer func() { controllers.FileStorage = previous }()
+
+	db.Create(&models.Role{Name: "user"})
+	db.Create(&models.Role{Name: "guide"})
+	db.Create(&models.Role{Name: "admin"})
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+	newUser := func(email string, roleID uint) models.User {
+		auth := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&auth)
+		u := models.User{AuthUserID: auth.ID, FirstName: "Test", LastName: email, RoleID: roleID}
+		db.Create(&u)
+		return u
+	}
+	traveller := newUser("user@example.com", 1)
+	guideUser := newUser("guide@example.com", 2)
+	admin := newUser("admin@example.com", 3)
+	stranger := newUser("stranger@example.com", 1)
+	guide := models.Guide{UserID: guideUser.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+	booking := models.TripBooking{TripOfferID: 1, UserID: traveller.ID, GuideID: guide.ID, StartDate: time.Now(), TotalAmount: 2000, Status: "paid"}
+	db.Create(&booking)
+
+	actor := traveller.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Post("/api/uploads", as(controllers.UploadFile))
+	app.Get("/api/uploads/access", as(controllers.GetUploadAccess))
+	app.Get("/api/uploads/:id/download", as(controllers.DownloadUpload))
+	app.Get("/api/files/*", controllers.ServeSignedFile)
+	app.Static("/uploads", storage.Dir)
+	app.Get("/api/admin/uploads/:id/access-log", controllers.AdminGetFileAccessLog)
+	app.Post("/api/trip-bookings/:id/reports", as(controllers.CreateTripReport))
+	app.Post("/api/conversations/:id/messages", as(controllers.SendMessage))
+
+	get := func(path string) (*http.Response, []byte) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+	upload := func(purpose string, content []byte) map[string]interface{} {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("purpose", purpose)
+		fw, _ := mw.CreateFormFile("file", "scan.pdf")
+		fw.Write(content)
+		mw.Close()
+		req := httptest.NewRequest("POST", "/api/uploads", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return out
+	}
+	access := func(fileURL string) (int, string) {
+		resp, body := get("/api/uploads/access?url=" + url.QueryEscape(fileURL))
+		var out map[string]interface{}
+		json.Unmarshal(body, &out)
+		signed, _ := out["url"].(string)
+		return resp.StatusCode, signed
+	}
+	accessLogs := func(uploadID uint) []models.FileAccessLog {
+		var logs []models.FileAccessLog
+		db.Where("upload_id = ?", uploadID).Order("id").Find(&logs)
+		return logs
+	}
+
+	t.Run("Evidence is private and shared with the other booking party", func(t *testing.T) {
+		actor = traveller.ID
+		out := upload("evidence", testPDF)
+		evidenceURL := out["url"].(string)
+		uploadID := uint(out["id"].(float64))
+		assert.True(t, strings.HasPrefix(evidenceURL, "/api/files/private/evidence/"))
+		_, err := os.Stat(filepath.Join(storage.PrivateDir, strings.TrimPrefix(evidenceURL, "/api/files/private/")))
+		assert.NoError(t, err)
+
+		// ไฟล์ส่วนตัวอยู่นอกโฟลเดอร์ static เขียน path แบบไหนก็เปิดตรงๆ ไม่ได้
+		name := strings.TrimPrefix(evidenceURL, "/api/files/private/")
+		for _, path := range []string{
+			"/uploads/private/" + name,
+			"/uploads//private/" + name,
+			"/uploads/%70rivate/" + name,
+			"/uploads/./private/" + name,
+			"/uploads/x/../private/" + name,
+			"/uploads/%2e/private/" + name,
+			"/uploads/" + name,
+		} {
+			resp, body := get(path)
+			assert.NotEqual(t, http.StatusOK, resp.StatusCode, path)
+			assert.NotEqual(t, testPDF, body, path)
+		}
+
+		// ยังไม่ได้แนบในรีพอร์ต ไกด์ยังเปิดไม่ได้
+		actor = guideUser.ID
+		status, _ := access(evidenceURL)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		db.Create(&models.TripReport{TripBookingID: booking.ID, ReporterID: traveller.ID, ReportedUserID: guideUser.ID, ReportType: "other", Title: "Issue", Description: "d", Evidence: `["` + evidenceURL + `"]`})
+
+		status, signed := access(evidenceURL)
+		assert.Equal(t, http.StatusOK, status)
+		assert.True(t, strings.HasPrefix(signed, evidenceURL+"?expires="))
+
+		resp, body := get(signed)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, testPDF, body)
+		assert.Equal(t, "application/pdf", resp.Header.Get("Content-Type"))
+		assert.Equal(t, "private, no-store", resp.Header.Get("Cache-Control"))
+
+		// ใช้ลิงก์ที่ไม่มีลายเซ็น หรือแก้ uid ไม่ได้
+		resp, _ = get(evidenceURL)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+		resp, _ = get(strings.Replace(signed, "uid="+strconv.Itoa(int(guideUser.ID)), "uid="+strconv.Itoa(int(stranger.ID)), 1))
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		svc := services.NewUploadService(db, controllers.FileStorage)
+		svc.SigningKey = config.JWTSecret
+		var record models.Upload
+		db.First(&record, uploadID)
+		resp, _ = get(svc.SignedURL(&record, guideUser.ID, time.Now().Add(-time.Minute)))
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		actor = stranger.ID
+		status, _ = access(evidenceURL)
+		assert.Equal(t, http.StatusForbidden, status)
+		resp, _ = get("/api/uploads/" + strconv.Itoa(int(uploadID)) + "/download")
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		actor = traveller.ID
+		resp, body = get("/api/uploads/" + strconv.Itoa(int(uploadID)) + "/download")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, testPDF, body)
+
+		// หลักฐานทั่วไปไม่ต้องบันทึกการเข้าถึง
+		assert.Empty(t, accessLogs(uploadID))
+	})
+
+	t.Run("Evidence attached to a dispute statement is shared too", func(t *testing.T) {
+		actor = guideUser.ID
+		evidenceURL := upload("evidence", testPDF)["url"].(string)
+		dispute := models.NoShowDispute{TripBookingID: booking.ID, Stage: "evidence", RespondBy: time.Now()}
+		db.Create(&dispute)
+		db.Create(&models.DisputeStatement{NoShowDisputeID: dispute.ID, AuthorID: guideUser.ID, Party: "guide", Body: "GPS log", Evidence: `["` + evidenceURL + `"]`})
+
+		actor = traveller.ID
+		status, _ := access(evidenceURL)
+		assert.Equal(t, http.StatusOK, status)
+		actor = stranger.ID
+		status, _ = access(evidenceURL)
+		assert.Equal(t, http.StatusForbidden, status)
+	})
+
+	t.Run("Licence documents are owner and admin only and every access is logged", func(t *testing.T) {
+		actor = guideUser.ID
+		out := upload("licence_document", testPDF)
+		licenceURL := out["url"].(string)
+		uploadID := uint(out["id"].(float64))
+
+		actor = traveller.ID
+		status, _ := access(licenceURL)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		actor = admin.ID
+		status, signed := access(licenceURL)
+		assert.Equal(t, http.StatusOK, status)
+		resp, body := get(signed)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, testPDF, body)
+
+		actor = guideUser.ID
+		resp, _ = get("/api/uploads/" + strconv.Itoa(int(uploadID)) + "/download")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		logs := accessLogs(uploadID)
+		if assert.Len(t, logs, 3) {
+			assert.Equal(t, "sign_url", logs[0].Action)
+			assert.Equal(t, admin.ID, logs[0].UserID)
+			assert.Equal(t, "signed_download", logs[1].Action)
+			assert.Equal(t, admin.ID, logs[1].UserID)
+			assert.Equal(t, "download", logs[2].Action)
+			assert.Equal(t, guideUser.ID, logs[2].UserID)
+		}
+
+		resp, body = get("/api/admin/uploads/" + strconv.Itoa(int(uploadID)) + "/access-log")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var logOut map[string]interface{}
+		json.Unmarshal(body, &logOut)
+		assert.Len(t, logOut["logs"], 3)
+	})
+
+	t.Run("Private files are attached only by their owner or where they are already shared", func(t *testing.T) {
+		post := func(path string, payload interface{}) (int, string) {
+			raw, _ := json.Marshal(payload)
+			req := httptest.NewRequest("POST", path, bytes.NewReader(raw))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			var out map[string]interface{}
+			json.NewDecoder(resp.Body).Decode(&out)
+			code, _ := out["code"].(string)
+			return resp.StatusCode, code
+		}
+		report := func(bookingID uint, evidence ...string) (int, string) {
+			return post("/api/trip-bookings/"+strconv.Itoa(int(bookingID))+"/reports", fiber.Map{
+				"report_type": "inappropriate_behavior", "title": "Issue", "description": "d", "evidence": evidence,
+			})
+		}
+
+		actor = traveller.ID
+		evidenceURL := upload("evidence", testPDF)["url"].(string)
+
+		// คนอื่นเอา URL ไปแนบในรีพอร์ตของ booking ตัวเองหรือในห้องแชทเพื่อเปิดไฟล์ไม่ได้
+		strangerBooking := models.TripBooking{TripOfferID: 2, UserID: stranger.ID, GuideID: guide.ID, StartDate: time.Now(), TotalAmount: 1000, Status: "paid"}
+		db.Create(&strangerBooking)
+		actor = stranger.ID
+		status, code := report(strangerBooking.ID, evidenceURL)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "evidence_url_forbidden", code)
+
+		conversation := models.Conversation{UserID: stranger.ID, GuideUserID: guideUser.ID}
+		db.Create(&conversation)
+		status, code = post("/api/conversations/"+strconv.Itoa(int(conversation.ID))+"/messages", fiber.Map{
+			"attachments": []fiber.Map{{"url": evidenceURL, "file_name": "scan.pdf"}},
+		})
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "message_attachment_forbidden", code)
+		status, _ = access(evidenceURL)
+		assert.Equal(t, http.StatusForbidden, status)
+
+		// เจ้าของแนบได้ และคู่กรณีอ้างถึงหลักฐานที่แนบไว้แล้วใน booking เดียวกันได้
+		actor = traveller.ID
+		status, _ = report(booking.ID, evidenceURL)
+		assert.Equal(t, http.StatusCreated, status)
+		actor = guideUser.ID
+		status, _ = report(booking.ID, evidenceURL)
+		assert.Equal(t, http.StatusCreated, status)
+		status, code = report(strangerBooking.ID, evidenceURL)
+		assert.Equal(t, http.StatusBadRequest, status)
+		assert.Equal(t, "evidence_url_forbidden", code)
+	})
+
+	t.Run("Existing evidence and licence files are moved to private storage", func(t *testing.T) {
+		write := func(key string) string {
+			assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, key)), 0755))
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, key), testPDF, 0644))
+			return "/uploads/" + key
+		}
+		// ไฟล์จาก typed upload ที่เก็บแบบเปิด กับไฟล์เก่าที่ไม่มี record ใน uploads
+		typedURL := write("evidence/typed.pdf")
+		typed := models.Upload{OwnerID: traveller.ID, Purpose: "evidence", Backend: "local", Key: "evidence/typed.pdf", URL: typedURL, ContentType: "application/pdf", Size: int64(len(testPDF))}
+		db.Create(&typed)
+		legacyURL := write("1700000000.pdf")
+		licenceURL := write("1700000001.pdf")
+		external := "https://example.com/photo.jpg"
+
+		tripReport := models.TripReport{TripBookingID: booking.ID, ReporterID: traveller.ID, ReportedUserID: guideUser.ID, ReportType: "other", Title: "Old", Description: "d", Evidence: `["` + typedURL + `","` + legacyURL + `","` + external + `"]`}
+		db.Create(&tripReport)
+		verification := models.GuideVertification{UserID: guideUser.ID, VerificationDate: time.Now(), LicenceDocument: licenceURL}
+		db.Create(&verification)
+
+		svc := services.NewUploadService(db, controllers.FileStorage)
+		moved, err := svc.MigratePrivateUploads()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, moved)
+
+		db.First(&tripReport, tripReport.ID)
+		assert.Equal(t, `["/api/files/private/evidence/typed.pdf","/api/files/private/1700000000.pdf","`+external+`"]`, tripReport.Evidence)
+		db.First(&verification, verification.ID)
+		assert.Equal(t, "/api/files/private/1700000001.pdf", verification.LicenceDocument)
+
+		var legacy models.Upload
+		assert.NoError(t, db.Where("url = ?", "/api/files/private/1700000000.pdf").First(&legacy).Error)
+		assert.True(t, legacy.Private)
+		assert.Equal(t, traveller.ID, legacy.OwnerID)
+		assert.Equal(t, "application/pdf", legacy.ContentType)
+		var licence models.Upload
+		assert.NoError(t, db.Where("url = ?", verification.LicenceDocument).First(&licence).Error)
+		assert.Equal(t, "licence_document", licence.Purpose)
+		assert.Equal(t, guideUser.ID, licence.OwnerID)
+
+		for _, key := range []string{"evidence/typed.pdf", "1700000000.pdf", "1700000001.pdf"} {
+			_, err := os.Stat(filepath.Join(dir, key))
+			assert.True(t, os.IsNotExist(err), key)
+			_, err = os.Stat(filepath.Join(storage.PrivateDir, key))
+			assert.NoError(t, err, key)
+		}
+
+		// ไกด์ของ booking ยังเปิดหลักฐานได้ผ่าน signed URL
+		actor = guideUser.ID
+		status, signed := access("/api/files/private/1700000000.pdf")
+		assert.Equal(t, http.StatusOK, status)
+		resp, body := get(signed)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, testPDF, body)
+
+		moved, err = svc.MigratePrivateUploads()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, moved)
+	})
+
+	t.Run("Private files stored under the static directory are moved out of it", func(t *testing.T) {
+		legacy := filepath.Join(dir, "private", "licences", "old.pdf")
+		assert.NoError(t, os.MkdirAll(filepath.Dir(legacy), 0755))
+		assert.NoError(t, os.WriteFile(legacy, testPDF, 0644))
+
+		moved, err := storage.MovePrivateFiles()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, moved)
+		_, err = os.Stat(filepath.Join(dir, "private"))
+		assert.True(t, os.IsNotExist(err))
+		r, err := storage.Get("private/licences/old.pdf")
+		if assert.NoError(t, err) {
+			data, _ := io.ReadAll(r)
+			r.Close()
+			assert.Equal(t, testPDF, data)
+		}
+
+		moved, err = storage.MovePrivateFiles()
+		assert.NoError(t, err)
+		assert.Equal(t, 0, moved)
+	})
+
+	t.Run("Public uploads keep their direct URL", func(t *testing.T) {
+		actor = traveller.ID
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("purpose", "review_image")
+		fw, _ := mw.CreateFormFile("file", "photo.png")
+		fw.Write(testPNG)
+		mw.Close()
+		req := httptest.NewRequest("POST", "/api/uploads", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		resp, _ := app.Test(req)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		photoURL := out["url"].(string)
+		assert.True(t, strings.HasPrefix(photoURL, "/uploads/reviews/"))
+
+		actor = stranger.ID
+		status, direct := access(photoURL)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, photoURL, direct)
+	})
+}
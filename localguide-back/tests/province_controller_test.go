@@ -8,6 +8,7 @@ import (
 
 	"localguide-back/config"
 	"localguide-back/controllers"
+	"localguide-back/middleware"
 	"localguide-back/models"
 
 	"github.com/gofiber/fiber/v2"
@@ -22,7 +23,7 @@ func setupTestDB() *gorm.DB {
 		panic("Failed to connect to test database")
 	}
 	// migrate minimal models used by tested controllers
-	db.AutoMigrate(&models.Province{}, &models.TouristAttraction{})
+	db.AutoMigrate(&models.Province{}, &models.TouristAttraction{}, &models.Translation{})
 	return db
 }
 
@@ -34,6 +35,7 @@ func setupTestApp() *fiber.App {
 		}
 		return c.Status(code).JSON(fiber.Map{"error": err.Error()})
 	}})
+	app.Use(middleware.Localize())
 	return app
 }
 
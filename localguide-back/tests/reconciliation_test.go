@@ -0,0 +1,239 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeLookup - แทน Stripe คืน snapshot ตาม PaymentIntent ID
+type fakeLookup map[string]*services.PaymentSnapshot
+
+func (f fakeLookup) GetPaymentSnapshot(paymentIntentID string) (*services.PaymentSnapshot, error) {
+	if snapshot, ok := f[paymentIntentID]; ok {
+		return snapshot, nil
+	}
+	return nil, errors.New("no such payment_intent")
+}
+
+func TestStripeReconciliation(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripPayment{}, &models.TripBookingParticipant{}, &models.PaymentRelease{}, &models.PaymentOutbox{}, &models.ReconciliationIssue{}, &models.Notification{}, &models.NotificationPreference{}, &models.NotificationOutbox{})
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authAdmin := models.AuthUser{Email: "admin@example.com", Password: "hash"}
+	db.Create(&authAdmin)
+	admin := models.User{AuthUserID: authAdmin.ID, FirstName: "Admin", LastName: "One", RoleID: 3}
+	db.Create(&admin)
+
+	newPayment := func(status string) (models.TripBooking, models.TripPayment) {
+		paymentStatus := "paid"
+		if status == "pending" {
+			paymentStatus = "pending"
+		}
+		booking := models.TripBooking{TripOfferID: 1, UserID: user.ID, GuideID: 1, StartDate: time.Now(), TotalAmount: 2000, Status: paymentStatus, PaymentStatus: paymentStatus}
+		db.Create(&booking)
+		ref := strconv.Itoa(int(booking.ID))
+		payment := models.TripPayment{TripBookingID: booking.ID, PaymentNumber: "PAY-" + ref, TransactionID: "TXN-" + ref, StripePaymentIntentID: "pi_" + ref, TotalAmount: 2000, FirstPayment: 1000, SecondPayment: 1000, PaymentMethod: "stripe_card", Status: status}
+		db.Create(&payment)
+		return booking, payment
+	}
+
+	lookup := fakeLookup{}
+	controllers.ReconciliationLookup = lookup
+	defer func() { controllers.ReconciliationLookup = services.NewStripeService() }()
+	service := services.NewReconciliationService(db, lookup)
+
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", admin.ID)
+			return h(c)
+		}
+	}
+	app.Get("/admin/reconciliation", as(controllers.GetReconciliationIssues))
+	app.Post("/admin/reconciliation/:id/resync", as(controllers.ResyncReconciliationIssue))
+	app.Put("/admin/reconciliation/:id/ignore", as(controllers.IgnoreReconciliationIssue))
+
+	do := func(method, url string) (*http.Response, map[string]interface{}) {
+		resp, err := app.Test(httptest.NewRequest(method, url, nil))
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	issuesOf := func(payment models.TripPayment, status string) []models.ReconciliationIssue {
+		var issues []models.ReconciliationIssue
+		db.Where("trip_payment_id = ? AND status = ?", payment.ID, status).Order("id").Find(&issues)
+		return issues
+	}
+
+	t.Run("Matching payments produce no issues", func(t *testing.T) {
+		_, payment := newPayment("paid")
+		lookup[payment.StripePaymentIntentID] = &services.PaymentSnapshot{Status: "succeeded", AmountCents: 200000, CapturedCents: 200000}
+
+		_, _, err := service.ReconcilePayment(&payment, time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, issuesOf(payment, "open"))
+	})
+
+	t.Run("Split payments are reconciled from participant PaymentIntents", func(t *testing.T) {
+		booking, payment := newPayment("paid")
+		db.Model(&payment).Updates(map[string]interface{}{"payment_method": "stripe_split", "stripe_payment_intent_id": ""})
+		db.First(&payment, payment.ID)
+		db.Create(&models.TripBookingParticipant{TripBookingID: booking.ID, Email: "a@example.com", InviteToken: "rec-a", ShareAmount: 1200, ShareStatus: "paid", PaidAmount: 1200, StripePaymentIntentID: "pi_split_a"})
+		db.Create(&models.TripBookingParticipant{TripBookingID: booking.ID, Email: "b@example.com", InviteToken: "rec-b", ShareAmount: 800, ShareStatus: "paid", PaidAmount: 800, StripePaymentIntentID: "pi_split_b"})
+		lookup["pi_split_a"] = &services.PaymentSnapshot{Status: "succeeded", AmountCents: 120000, CapturedCents: 120000}
+		lookup["pi_split_b"] = &services.PaymentSnapshot{Status: "succeeded", AmountCents: 80000, CapturedCents: 80000}
+
+		_, _, err := service.ReconcilePayment(&payment, time.Now())
+		assert.NoError(t, err)
+		assert.Empty(t, issuesOf(payment, "open"))
+
+		// refund ที่ทำใน Stripe โดยตรงบน PaymentIntent ของผู้ร่วมเดินทาง
+		lookup["pi_split_b"] = &services.PaymentSnapshot{Status: "succeeded", AmountCents: 80000, CapturedCents: 80000, RefundedCents: 10000,
+			Refunds: []services.RefundSnapshot{{ID: "re_split_b", AmountCents: 10000, Status: "succeeded"}}}
+		_, _, err = service.ReconcilePayment(&payment, time.Now())
+		assert.NoError(t, err)
+		issues := issuesOf(payment, "open")
+		if assert.Len(t, issues, 1) {
+			assert.Equal(t, "unrecorded_refund", issues[0].IssueType)
+			assert.Equal(t, "re_split_b", issues[0].ExternalRef)
+		}
+
+		// release ที่คืนเงินหลาย PaymentIntent เก็บหลาย refund ID
+		db.Create(&models.PaymentRelease{TripPaymentID: payment.ID, ReleaseType: "refund", Amount: 100, RecipientType: "user", RecipientID: user.ID, ScheduledAt: time.Now(), Status: "processed", TransactionRef: "re_split_b"})
+		_, resolved, err := service.ReconcilePayment(&payment, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, resolved)
+		assert.Empty(t, issuesOf(payment, "open"))
+	})
+
+	t.Run("Missed webhook is flagged once and resynced", func(t *testing.T) {
+		booking, payment := newPayment("pending")
+		lookup[payment.StripePaymentIntentID] = &services.PaymentSnapshot{Status: "succeeded", AmountCents: 200000, CapturedCents: 200000}
+
+		summary, err := service.RunOnce(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, summary.Open)
+
+		// รอบถัดไปไม่สร้างซ้ำ
+		service.RunOnce(time.Now().Add(time.Hour))
+		issues := issuesOf(payment, "open")
+		if !assert.Len(t, issues, 1) {
+			return
+		}
+		assert.Equal(t, "status_mismatch", issues[0].IssueType)
+		assert.True(t, issues[0].Resyncable)
+
+		resp, out := do("GET", "/admin/reconciliation")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, float64(1), out["open_count"])
+
+		resp, _ = do("POST", "/admin/reconciliation/"+strconv.Itoa(int(issues[0].ID))+"/resync")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		db.First(&payment, payment.ID)
+		assert.Equal(t, "paid", payment.Status)
+		db.First(&booking, booking.ID)
+		assert.Equal(t, "paid", booking.PaymentStatus)
+
+		var issue models.ReconciliationIssue
+		db.First(&issue, issues[0].ID)
+		assert.Equal(t, "resynced", issue.Status)
+		if assert.NotNil(t, issue.ResolvedBy) {
+			assert.Equal(t, admin.ID, *issue.ResolvedBy)
+		}
+	})
+
+	t.Run("Refund made directly on Stripe is recorded by resync", func(t *testing.T) {
+		_, payment := newPayment("paid")
+		lookup[payment.StripePaymentIntentID] = &services.PaymentSnapshot{
+			Status: "succeeded", AmountCents: 200000, CapturedCents: 200000, RefundedCents: 50000,
+			Refunds: []services.RefundSnapshot{{ID: "re_dashboard", AmountCents: 50000, Status: "succeeded"}},
+		}
+
+		service.ReconcilePayment(&payment, time.Now())
+		issues := issuesOf(payment, "open")
+		if !assert.Len(t, issues, 1) {
+			return
+		}
+		assert.Equal(t, "unrecorded_refund", issues[0].IssueType)
+		assert.Equal(t, "re_dashboard", issues[0].ExternalRef)
+
+		resp, _ := do("POST", "/admin/reconciliation/"+strconv.Itoa(int(issues[0].ID))+"/resync")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var release models.PaymentRelease
+		assert.NoError(t, db.Where("transaction_ref = ?", "re_dashboard").First(&release).Error)
+		assert.Equal(t, 500.0, release.Amount)
+		assert.Equal(t, "processed", release.Status)
+
+		db.First(&payment, payment.ID)
+		assert.Equal(t, "partially_refunded", payment.Status)
+		assert.Equal(t, 500.0, payment.RefundAmount)
+
+		// ตรวจซ้ำแล้วตรงกัน
+		service.ReconcilePayment(&payment, time.Now())
+		assert.Empty(t, issuesOf(payment, "open"))
+	})
+
+	t.Run("Unsafe mismatches cannot be resynced and close when fixed", func(t *testing.T) {
+		_, payment := newPayment("paid")
+		db.Create(&models.PaymentRelease{TripPaymentID: payment.ID, ReleaseType: "refund", Amount: 300, RecipientType: "user", RecipientID: user.ID, ScheduledAt: time.Now(), Status: "processed", TransactionRef: "re_local"})
+		lookup[payment.StripePaymentIntentID] = &services.PaymentSnapshot{Status: "succeeded", AmountCents: 200000, CapturedCents: 150000}
+
+		service.ReconcilePayment(&payment, time.Now())
+		issues := issuesOf(payment, "open")
+		if !assert.Len(t, issues, 2) {
+			return
+		}
+		assert.Equal(t, "amount_mismatch", issues[0].IssueType)
+		assert.Equal(t, "missing_stripe_refund", issues[1].IssueType)
+
+		resp, _ := do("POST", "/admin/reconciliation/"+strconv.Itoa(int(issues[0].ID))+"/resync")
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = do("PUT", "/admin/reconciliation/"+strconv.Itoa(int(issues[1].ID))+"/ignore")
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// Stripe แก้แล้ว -> ปิดอัตโนมัติ ส่วนที่ ignore ไม่ถูกเปิดใหม่
+		lookup[payment.StripePaymentIntentID].CapturedCents = 200000
+		_, resolved, err := service.ReconcilePayment(&payment, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, resolved)
+		assert.Empty(t, issuesOf(payment, "open"))
+		assert.Len(t, issuesOf(payment, "ignored"), 1)
+	})
+
+	t.Run("Stripe errors are recorded", func(t *testing.T) {
+		_, payment := newPayment("paid")
+
+		service.ReconcilePayment(&payment, time.Now())
+		issues := issuesOf(payment, "open")
+		if assert.Len(t, issues, 1) {
+			assert.Equal(t, "stripe_error", issues[0].IssueType)
+			assert.False(t, issues[0].Resyncable)
+		}
+	})
+}
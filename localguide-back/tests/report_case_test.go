@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportCaseQueue(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.PaymentOutbox{}, &models.TripReport{}, &models.TripReportNote{}, &models.TripReportLink{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+	newUser := func(email string, roleID uint) models.User {
+		auth := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&auth)
+		u := models.User{AuthUserID: auth.ID, FirstName: "Test", LastName: email, RoleID: roleID}
+		db.Create(&u)
+		return u
+	}
+	traveller := newUser("user@example.com", 1)
+	guideUser := newUser("guide@example.com", 2)
+	admin := newUser("admin@example.com", 3)
+	otherAdmin := newUser("admin2@example.com", 3)
+	guide := models.Guide{UserID: guideUser.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	now := time.Now()
+	newBooking := func(number string) models.TripBooking {
+		booking := models.TripBooking{TripOfferID: 1, UserID: traveller.ID, GuideID: guide.ID, StartDate: now, TotalAmount: 2000, Status: "trip_started", PaymentStatus: "paid"}
+		db.Create(&booking)
+		db.Create(&models.TripPayment{TripBookingID: booking.ID, PaymentNumber: number, TransactionID: "TXN-" + number, StripePaymentIntentID: "pi_" + number, TotalAmount: 2000, FirstPayment: 1000, SecondPayment: 1000, PaymentMethod: "stripe_card", Status: "paid", PaidAt: &now})
+		return booking
+	}
+	newReport := func(booking models.TripBooking, reporter models.User, reportType, severity string, age time.Duration) models.TripReport {
+		reported := guideUser.ID
+		if reporter.ID == guideUser.ID {
+			reported = traveller.ID
+		}
+		r := models.TripReport{TripBookingID: booking.ID, ReporterID: reporter.ID, ReportedUserID: reported, ReportType: reportType, Title: reportType, Description: "desc", Severity: severity, Status: "pending"}
+		db.Create(&r)
+		db.Model(&r).Update("created_at", now.Add(-age))
+		return r
+	}
+
+	booking := newBooking("PAY-1")
+	otherBooking := newBooking("PAY-2")
+	critical := newReport(booking, traveller, "safety_issue", "critical", 30*time.Hour)
+	counter := newReport(booking, guideUser, "inappropriate_behavior", "medium", 2*time.Hour)
+	low := newReport(otherBooking, traveller, "other", "low", time.Hour)
+
+	actor := admin.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Get("/admin/trip-reports", as(controllers.GetAllTripReports))
+	app.Get("/admin/trip-reports/:id", as(controllers.GetTripReportCase))
+	app.Put("/admin/trip-reports/:id", as(controllers.HandleTripReport))
+	app.Put("/admin/trip-reports/:id/assign", as(controllers.AssignTripReport))
+	app.Post("/admin/trip-reports/:id/notes", as(controllers.AddTripReportNote))
+	app.Post("/admin/trip-reports/:id/links", as(controllers.LinkTripReports))
+	app.Delete("/admin/trip-reports/:id/links/:relatedId", as(controllers.UnlinkTripReports))
+	app.Post("/admin/trip-reports/:id/resolve", as(controllers.ResolveTripReportCase))
+
+	do := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	casePath := func(r models.TripReport) string {
+		return "/admin/trip-reports/" + strconv.Itoa(int(r.ID))
+	}
+	reportIDs := func(out map[string]interface{}) []float64 {
+		ids := []float64{}
+		for _, item := range out["reports"].([]interface{}) {
+			ids = append(ids, item.(map[string]interface{})["report"].(map[string]interface{})["ID"].(float64))
+		}
+		return ids
+	}
+
+	t.Run("Queue filters and SLA ordering", func(t *testing.T) {
+		_, out := do("GET", "/admin/trip-reports", nil)
+		assert.Equal(t, []float64{float64(critical.ID), float64(counter.ID), float64(low.ID)}, reportIDs(out))
+		assert.Equal(t, 3.0, out["total"])
+		sla := out["reports"].([]interface{})[0].(map[string]interface{})["sla"].(map[string]interface{})
+		assert.Equal(t, true, sla["response_breached"])
+		assert.Equal(t, true, sla["resolution_breached"])
+
+		_, out = do("GET", "/admin/trip-reports?overdue=true", nil)
+		assert.Equal(t, []float64{float64(critical.ID)}, reportIDs(out))
+
+		_, out = do("GET", "/admin/trip-reports?severity=medium,low&sort=oldest", nil)
+		assert.Equal(t, []float64{float64(counter.ID), float64(low.ID)}, reportIDs(out))
+
+		_, out = do("GET", "/admin/trip-reports?min_age_hours=2&type=safety_issue,inappropriate_behavior", nil)
+		assert.Equal(t, []float64{float64(critical.ID), float64(counter.ID)}, reportIDs(out))
+
+		_, out = do("GET", "/admin/trip-reports?booking_id="+strconv.Itoa(int(otherBooking.ID)), nil)
+		assert.Equal(t, []float64{float64(low.ID)}, reportIDs(out))
+
+		resp, _ := do("GET", "/admin/trip-reports?status=closed", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("GET", "/admin/trip-reports?severity=urgent", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Handling a report validates status and records the reviewer", func(t *testing.T) {
+		resp, _ := do("PUT", casePath(low), map[string]interface{}{"status": "done"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		actor = otherAdmin.ID
+		resp, _ = do("PUT", casePath(low), map[string]interface{}{"status": "investigating"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var saved models.TripReport
+		db.First(&saved, low.ID)
+		assert.Equal(t, "investigating", saved.Status)
+		if assert.NotNil(t, saved.ReviewedBy) && assert.NotNil(t, saved.AssignedTo) {
+			assert.Equal(t, otherAdmin.ID, *saved.ReviewedBy)
+			assert.Equal(t, otherAdmin.ID, *saved.AssignedTo)
+		}
+		assert.NotNil(t, saved.ReviewedAt)
+		actor = admin.ID
+	})
+
+	t.Run("Assign case to an admin", func(t *testing.T) {
+		resp, _ := do("PUT", casePath(critical)+"/assign", map[string]interface{}{"admin_id": traveller.ID})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = do("PUT", casePath(critical)+"/assign", map[string]interface{}{"admin_id": otherAdmin.ID})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var notified int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", otherAdmin.ID, "report_assigned").Count(&notified)
+		assert.Equal(t, int64(1), notified)
+
+		_, out := do("GET", "/admin/trip-reports?assigned_to="+strconv.Itoa(int(otherAdmin.ID)), nil)
+		assert.ElementsMatch(t, []float64{float64(critical.ID), float64(low.ID)}, reportIDs(out))
+		_, out = do("GET", "/admin/trip-reports?assigned_to=unassigned", nil)
+		assert.Equal(t, []float64{float64(counter.ID)}, reportIDs(out))
+	})
+
+	t.Run("Notes and links appear in the case detail", func(t *testing.T) {
+		resp, _ := do("POST", casePath(critical)+"/notes", map[string]interface{}{"body": "  "})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", casePath(critical)+"/notes", map[string]interface{}{"body": "Called the traveller"})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		resp, _ = do("POST", casePath(critical)+"/links", map[string]interface{}{"related_report_id": low.ID})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", casePath(counter)+"/links", map[string]interface{}{"related_report_id": critical.ID})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp, _ = do("POST", casePath(critical)+"/links", map[string]interface{}{"related_report_id": counter.ID})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		_, out := do("GET", casePath(critical), nil)
+		assert.Len(t, out["notes"], 1)
+		linked := out["linked_reports"].([]interface{})
+		if assert.Len(t, linked, 1) {
+			assert.Equal(t, float64(counter.ID), linked[0].(map[string]interface{})["ID"])
+		}
+		assert.Equal(t, 2000.0, out["escrow"])
+
+		resp, _ = do("DELETE", casePath(critical)+"/links/"+strconv.Itoa(int(low.ID)), nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	})
+
+	t.Run("Resolve with split settles the payment and closes linked reports", func(t *testing.T) {
+		resp, _ := do("POST", casePath(critical)+"/resolve", map[string]interface{}{"outcome": "split"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out := do("POST", casePath(critical)+"/resolve", map[string]interface{}{
+			"outcome":       "split",
+			"guide_percent": 25,
+			"reason":        "Guide partly at fault",
+			"admin_notes":   "We refunded 75% of your payment",
+		})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.ElementsMatch(t, []interface{}{float64(critical.ID), float64(counter.ID)}, out["closed_reports"])
+
+		var releases []models.PaymentRelease
+		db.Joins("JOIN trip_payments ON trip_payments.id = payment_releases.trip_payment_id").
+			Where("trip_payments.trip_booking_id = ?", booking.ID).Order("payment_releases.id").Find(&releases)
+		if assert.Len(t, releases, 2) {
+			assert.Equal(t, "guide", releases[0].RecipientType)
+			assert.Equal(t, 500.0, releases[0].Amount)
+			assert.Equal(t, "processed", releases[0].Status)
+			assert.Equal(t, "user", releases[1].RecipientType)
+			assert.Equal(t, 1500.0, releases[1].Amount)
+			assert.Equal(t, "pending", releases[1].Status)
+		}
+		var outbox int64
+		db.Model(&models.PaymentOutbox{}).Where("action = ?", "refund").Count(&outbox)
+		assert.Equal(t, int64(1), outbox)
+
+		var payment models.TripPayment
+		db.Where("trip_booking_id = ?", booking.ID).First(&payment)
+		assert.Equal(t, "partially_refunded", payment.Status)
+		assert.Equal(t, 1500.0, payment.RefundAmount)
+		var b models.TripBooking
+		db.First(&b, booking.ID)
+		assert.Equal(t, "cancelled", b.Status)
+
+		var closed models.TripReport
+		db.First(&closed, counter.ID)
+		assert.Equal(t, "resolved", closed.Status)
+		assert.Equal(t, "We refunded 75% of your payment", closed.AdminNotes)
+		assert.Equal(t, `["case_split"]`, closed.Actions)
+
+		var notified int64
+		db.Model(&models.Notification{}).Where("type = ?", "dispute_resolved").Count(&notified)
+		assert.Equal(t, int64(2), notified)
+
+		resp, _ = do("POST", casePath(critical)+"/resolve", map[string]interface{}{"outcome": "refund", "reason": "again"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Resolve refuses to settle a payment with nothing left", func(t *testing.T) {
+		var payment models.TripPayment
+		db.Where("trip_booking_id = ?", otherBooking.ID).First(&payment)
+		db.Create(&models.PaymentRelease{TripPaymentID: payment.ID, ReleaseType: "refund", Amount: 2000, RecipientType: "user", RecipientID: traveller.ID, Reason: "test", ScheduledAt: now, Status: "processed"})
+		resp, _ := do("POST", casePath(low)+"/resolve", map[string]interface{}{"outcome": "release", "reason": "Guide did the trip"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var saved models.TripReport
+		db.First(&saved, low.ID)
+		assert.Equal(t, "investigating", saved.Status)
+
+		resp, _ = do("POST", casePath(low)+"/resolve", map[string]interface{}{"status": "dismissed", "reason": "Not actionable"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		db.First(&saved, low.ID)
+		assert.Equal(t, "dismissed", saved.Status)
+		assert.NotNil(t, saved.ResolvedAt)
+	})
+}
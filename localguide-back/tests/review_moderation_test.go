@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReviewModeration(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.AuthUser{}, &models.User{}, &models.Guide{}, &models.Province{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripBooking{}, &models.TripReview{}, &models.TripReviewImage{}, &models.GuideRatingSummary{}, &models.ReviewHelpfulVote{}, &models.ReviewFlag{}, &models.Notification{})
+
+	p := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&p)
+	newUser := func(email string) models.User {
+		au := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&au)
+		u := models.User{AuthUserID: au.ID, FirstName: "Test", LastName: email, RoleID: 1}
+		db.Create(&u)
+		return u
+	}
+	author := newUser("author@example.com")
+	other := newUser("other@example.com")
+	reader := newUser("reader@example.com")
+	admin := newUser("admin@example.com")
+	guideUser := newUser("guide@example.com")
+	guide := models.Guide{UserID: guideUser.ID, ProvinceID: p.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	newReview := func(u models.User, rating float64, verified bool) models.TripReview {
+		req := models.TripRequire{UserID: u.ID, ProvinceID: p.ID, Title: "Trip", Description: "", MinPrice: 100, MaxPrice: 200, Days: 1, StartDate: time.Now(), EndDate: time.Now(), Status: "open"}
+		db.Create(&req)
+		offer := models.TripOffer{TripRequireID: req.ID, GuideID: guide.ID, Title: "Offer", Description: "desc", Status: "accepted"}
+		db.Create(&offer)
+		booking := models.TripBooking{TripOfferID: offer.ID, UserID: u.ID, GuideID: guide.ID, StartDate: time.Now(), TotalAmount: 1000, Status: "trip_completed"}
+		db.Create(&booking)
+		review := models.TripReview{TripBookingID: booking.ID, UserID: u.ID, GuideID: guide.ID, Rating: rating, ServiceRating: rating, KnowledgeRating: rating, CommunicationRating: rating, PunctualityRating: rating, IsVerified: true}
+		db.Create(&review)
+		if !verified {
+			db.Model(&review).Update("is_verified", false)
+		}
+		return review
+	}
+	good := newReview(author, 5, true)
+	bad := newReview(other, 1, true)
+	unverified := newReview(reader, 4, false)
+
+	actor := reader.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Post("/reviews", as(controllers.CreateReview))
+	app.Get("/guides/:id/reviews", controllers.GetGuideReviews)
+	app.Post("/reviews/:id/helpful", as(controllers.MarkReviewHelpful))
+	app.Delete("/reviews/:id/helpful", as(controllers.UnmarkReviewHelpful))
+	app.Post("/reviews/:id/flag", as(controllers.FlagReview))
+	app.Get("/admin/reviews/moderation", as(controllers.GetReviewModerationQueue))
+	app.Put("/admin/reviews/:id/hide", as(controllers.HideReview))
+	app.Put("/admin/reviews/:id/restore", as(controllers.RestoreReview))
+
+	do := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	reviewPath := func(r models.TripReview) string {
+		return "/reviews/" + strconv.Itoa(int(r.ID))
+	}
+
+	t.Run("Helpful is one vote per user and can be undone", func(t *testing.T) {
+		actor = reader.ID
+		_, out := do("POST", reviewPath(good)+"/helpful", nil)
+		assert.Equal(t, 1.0, out["helpful_count"])
+		_, out = do("POST", reviewPath(good)+"/helpful", nil)
+		assert.Equal(t, 1.0, out["helpful_count"])
+
+		actor = other.ID
+		_, out = do("POST", reviewPath(good)+"/helpful", nil)
+		assert.Equal(t, 2.0, out["helpful_count"])
+
+		actor = reader.ID
+		resp, out := do("DELETE", reviewPath(good)+"/helpful", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1.0, out["helpful_count"])
+
+		var review models.TripReview
+		db.First(&review, good.ID)
+		assert.Equal(t, 1, review.HelpfulCount)
+
+		// กด helpful รีวิวตัวเองไม่ได้
+		actor = author.ID
+		resp, _ = do("POST", reviewPath(good)+"/helpful", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Flag review once and list in moderation queue", func(t *testing.T) {
+		actor = guideUser.ID
+		resp, _ := do("POST", reviewPath(bad)+"/flag", map[string]interface{}{"reason": "fake", "details": "Never met this traveller"})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		resp, _ = do("POST", reviewPath(bad)+"/flag", map[string]interface{}{"reason": "fake"})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+
+		actor = reader.ID
+		resp, _ = do("POST", reviewPath(bad)+"/flag", map[string]interface{}{"reason": "rude"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", reviewPath(bad)+"/flag", map[string]interface{}{"reason": "offensive"})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+
+		actor = admin.ID
+		_, out := do("GET", "/admin/reviews/moderation", nil)
+		items := out["reviews"].([]interface{})
+		if assert.Len(t, items, 1) {
+			item := items[0].(map[string]interface{})
+			assert.Equal(t, float64(bad.ID), item["review"].(map[string]interface{})["ID"])
+			assert.Len(t, item["flags"], 2)
+		}
+	})
+
+	t.Run("Hide and restore recompute guide rating", func(t *testing.T) {
+		db.Model(&guide).Update("rating", 10.0/3.0)
+
+		actor = admin.ID
+		resp, _ := do("PUT", "/admin"+reviewPath(bad)+"/hide", map[string]interface{}{})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("PUT", "/admin"+reviewPath(bad)+"/hide", map[string]interface{}{"reason": "Fake review"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var g models.Guide
+		db.First(&g, guide.ID)
+		assert.InDelta(t, 4.5, g.Rating, 0.001)
+
+		var upheld int64
+		db.Model(&models.ReviewFlag{}).Where("trip_review_id = ? AND status = ?", bad.ID, "upheld").Count(&upheld)
+		assert.Equal(t, int64(2), upheld)
+
+		var notifications int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", other.ID, "review_hidden").Count(&notifications)
+		assert.Equal(t, int64(1), notifications)
+
+		// รีวิวที่ถูกซ่อนไม่แสดง และกด helpful/แจ้งไม่ได้
+		_, out := do("GET", "/guides/"+strconv.Itoa(int(guide.ID))+"/reviews", nil)
+		assert.Len(t, out["reviews"], 2)
+		assert.Equal(t, 2.0, out["stats"].(map[string]interface{})["TotalReviews"])
+		actor = reader.ID
+		resp, _ = do("POST", reviewPath(bad)+"/helpful", nil)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		actor = admin.ID
+		_, out = do("GET", "/admin/reviews/moderation?status=hidden", nil)
+		assert.Len(t, out["reviews"], 1)
+
+		resp, _ = do("PUT", "/admin"+reviewPath(bad)+"/restore", map[string]interface{}{"reason": "Booking verified"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		db.First(&g, guide.ID)
+		assert.InDelta(t, 10.0/3.0, g.Rating, 0.001)
+	})
+
+	t.Run("Verified-only display", func(t *testing.T) {
+		_, out := do("GET", "/guides/"+strconv.Itoa(int(guide.ID))+"/reviews?verified_only=true", nil)
+		reviews := out["reviews"].([]interface{})
+		assert.Len(t, reviews, 2)
+		for _, r := range reviews {
+			assert.NotEqual(t, float64(unverified.ID), r.(map[string]interface{})["ID"])
+		}
+	})
+
+	t.Run("Review images must be a list of URLs", func(t *testing.T) {
+		actor = reader.ID
+		req := models.TripRequire{UserID: reader.ID, ProvinceID: p.ID, Title: "Trip", Description: "", MinPrice: 100, MaxPrice: 200, Days: 1, StartDate: time.Now(), EndDate: time.Now(), Status: "open"}
+		db.Create(&req)
+		offer := models.TripOffer{TripRequireID: req.ID, GuideID: guide.ID, Title: "Offer", Description: "desc", Status: "accepted"}
+		db.Create(&offer)
+		booking := models.TripBooking{TripOfferID: offer.ID, UserID: reader.ID, GuideID: guide.ID, StartDate: time.Now(), TotalAmount: 1000, Status: "trip_completed"}
+		db.Create(&booking)
+
+		payload := map[string]interface{}{
+			"trip_booking_id":      booking.ID,
+			"rating":               4,
+			"service_rating":       4,
+			"knowledge_rating":     4,
+			"communication_rating": 4,
+			"punctuality_rating":   4,
+			"images":               "javascript:alert(1)",
+		}
+		resp, _ := do("POST", "/reviews", payload)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		payload["images"] = `["/uploads/1.jpg"]`
+		payload["comment"] = "  Great trip  "
+		resp, out := do("POST", "/reviews", payload)
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		review := out["review"].(map[string]interface{})
+		assert.Equal(t, "Great trip", review["Comment"])
+		// รูปแบบเดิม (ข้อความ JSON) ยังรับได้ และเก็บเป็นรายการรูป
+		images := review["Images"].([]interface{})
+		if assert.Len(t, images, 1) {
+			assert.Equal(t, "/uploads/1.jpg", images[0].(map[string]interface{})["URL"])
+			assert.Equal(t, 0.0, images[0].(map[string]interface{})["Position"])
+		}
+	})
+}
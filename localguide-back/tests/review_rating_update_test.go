@@ -29,7 +29,7 @@ func TestGuideRatingUpdatesOnReviewCRUD(t *testing.T) {
 	app.Delete("/reviews/:id", func(c *fiber.Ctx) error { c.Locals("user_id", uint(1)); return controllers.DeleteReview(c) })
 
 	// migrate
-	db.AutoMigrate(&models.AuthUser{}, &models.User{}, &models.Guide{}, &models.Province{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripBooking{}, &models.TripReview{})
+	db.AutoMigrate(&models.AuthUser{}, &models.User{}, &models.Guide{}, &models.Province{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripBooking{}, &models.TripReview{}, &models.TripReviewImage{}, &models.GuideRatingSummary{})
 
 	// Seed province, users, guide, booking
 	p := models.Province{Name: "Bangkok", Region: "Central"}
@@ -22,7 +22,7 @@ func TestTripBookingController(t *testing.T) {
 	app := setupTestApp()
 
 	// Migrate tables
-	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripOfferQuotation{}, &models.TripBooking{}, &models.TripPayment{}, &models.TripReview{}, &models.TripReport{})
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripOfferQuotation{}, &models.TripOfferStop{}, &models.TripBooking{}, &models.TripPayment{}, &models.TripReview{}, &models.TripReviewImage{}, &models.TripReport{})
 
 	// Seed data
 	roleCustomer := models.Role{Name: "customer"}
@@ -151,7 +151,7 @@ func TestTripBookingController(t *testing.T) {
 
 		var response map[string]interface{}
 		json.NewDecoder(resp.Body).Decode(&response)
-		assert.Contains(t, response["error"], "You can only view your own bookings")
+		assert.Equal(t, "booking_view_own_only", response["code"])
 	})
 
 	t.Run("Create Trip Payment - Success (Mock Stripe)", func(t *testing.T) {
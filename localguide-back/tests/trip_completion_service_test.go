@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTripAutoCompletion(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.TripStartCheckIn{}, &models.TripReport{}, &models.Notification{})
+
+	os.Setenv("TRIP_AUTO_COMPLETE_HOURS", "24")
+	defer os.Unsetenv("TRIP_AUTO_COMPLETE_HOURS")
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authGuide := models.AuthUser{Email: "guide@example.com", Password: "hash"}
+	db.Create(&authGuide)
+	userGuide := models.User{AuthUserID: authGuide.ID, FirstName: "Guide", LastName: "One", RoleID: 2}
+	db.Create(&userGuide)
+	guide := models.Guide{UserID: userGuide.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	newBooking := func(status string, start time.Time, days int, paymentStatus string) (models.TripBooking, models.TripPayment) {
+		end := start.AddDate(0, 0, days-1)
+		booking := models.TripBooking{TripOfferID: 1, UserID: user.ID, GuideID: guide.ID, StartDate: start, EndDate: &end, Days: days, TotalAmount: 2000, Status: status, PaymentStatus: "paid"}
+		db.Create(&booking)
+		ref := strconv.Itoa(int(booking.ID))
+		payment := models.TripPayment{TripBookingID: booking.ID, PaymentNumber: "PAY-" + ref, TransactionID: "TXN-" + ref, StripePaymentIntentID: "pi_" + ref, TotalAmount: 2000, FirstPayment: 1000, SecondPayment: 1000, PaymentMethod: "stripe_card", Status: paymentStatus}
+		db.Create(&payment)
+		return booking, payment
+	}
+
+	now := time.Now()
+	started, startedPayment := newBooking("trip_started", now.AddDate(0, 0, -5), 2, "first_released")
+	neverConfirmed, neverPayment := newBooking("paid", now.AddDate(0, 0, -4), 1, "paid")
+	db.Create(&models.TripStartCheckIn{TripBookingID: neverConfirmed.ID, GuideID: guide.ID, CodeValid: true, Status: "accepted"})
+	noShow, noShowPayment := newBooking("paid", now.AddDate(0, 0, -4), 1, "paid")
+	db.Create(&models.TripStartCheckIn{TripBookingID: noShow.ID, GuideID: guide.ID, Status: "rejected", FailureReason: "invalid_code"})
+	recent, _ := newBooking("trip_started", now.AddDate(0, 0, -1), 2, "first_released")
+	reported, _ := newBooking("trip_started", now.AddDate(0, 0, -6), 1, "first_released")
+	db.Create(&models.TripReport{TripBookingID: reported.ID, ReporterID: user.ID, ReportedUserID: userGuide.ID, ReportType: "other", Title: "Issue", Description: "desc", Status: "pending"})
+
+	completed, err := services.NewTripCompletionService(db).RunOnce(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, completed)
+
+	t.Run("Started trip releases second payment", func(t *testing.T) {
+		db.First(&started, started.ID)
+		assert.Equal(t, "trip_completed", started.Status)
+		assert.NotNil(t, started.AutoCompletedAt)
+
+		var releases []models.PaymentRelease
+		db.Where("trip_payment_id = ?", startedPayment.ID).Find(&releases)
+		if assert.Len(t, releases, 1) {
+			assert.Equal(t, "second_payment", releases[0].ReleaseType)
+			assert.Equal(t, "auto_completed", releases[0].Reason)
+			assert.Equal(t, 1000.0, releases[0].Amount)
+		}
+
+		db.First(&startedPayment, startedPayment.ID)
+		assert.Equal(t, "fully_released", startedPayment.Status)
+	})
+
+	t.Run("Unconfirmed arrival after guide check-in releases both payments", func(t *testing.T) {
+		db.First(&neverConfirmed, neverConfirmed.ID)
+		assert.Equal(t, "trip_completed", neverConfirmed.Status)
+
+		var count int64
+		db.Model(&models.PaymentRelease{}).Where("trip_payment_id = ? AND reason = ?", neverPayment.ID, "auto_completed").Count(&count)
+		assert.Equal(t, int64(2), count)
+	})
+
+	t.Run("Paid trips without a guide check-in are left for no-show handling", func(t *testing.T) {
+		db.First(&noShow, noShow.ID)
+		assert.Equal(t, "paid", noShow.Status)
+		assert.Nil(t, noShow.AutoCompletedAt)
+
+		var count int64
+		db.Model(&models.PaymentRelease{}).Where("trip_payment_id = ?", noShowPayment.ID).Count(&count)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("Recent and reported trips are left alone", func(t *testing.T) {
+		db.First(&recent, recent.ID)
+		assert.Equal(t, "trip_started", recent.Status)
+		db.First(&reported, reported.ID)
+		assert.Equal(t, "trip_started", reported.Status)
+	})
+
+	t.Run("Both parties are notified", func(t *testing.T) {
+		var userNotes, guideNotes int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", user.ID, "trip_auto_completed").Count(&userNotes)
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", userGuide.ID, "trip_auto_completed").Count(&guideNotes)
+		assert.Equal(t, int64(2), userNotes)
+		assert.Equal(t, int64(2), guideNotes)
+	})
+
+	t.Run("Running again is a no-op", func(t *testing.T) {
+		completed, err := services.NewTripCompletionService(db).RunOnce(now)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, completed)
+	})
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiDayTrips(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripOfferQuotation{}, &models.TripOfferStop{}, &models.TripBooking{}, &models.TripDayCheckIn{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authGuide := models.AuthUser{Email: "guide@example.com", Password: "hash"}
+	db.Create(&authGuide)
+	userGuide := models.User{AuthUserID: authGuide.ID, FirstName: "Guide", LastName: "One", RoleID: 2}
+	db.Create(&userGuide)
+	guide := models.Guide{UserID: userGuide.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	// ผู้ใช้ที่ทำ request ปัจจุบัน (สลับระหว่าง user กับไกด์ได้)
+	actor := user.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Put("/trip-offers/:id/accept", as(controllers.AcceptTripOffer))
+	app.Put("/trip-bookings/:id/confirm-trip-complete", as(controllers.ConfirmTripComplete))
+	app.Post("/trip-bookings/:id/check-ins", as(controllers.CheckInTripDay))
+	app.Put("/trip-bookings/:id/request-early-completion", as(controllers.RequestEarlyCompletion))
+	app.Put("/trip-bookings/:id/agree-early-completion", as(controllers.AgreeEarlyCompletion))
+
+	doJSON := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	// สร้าง booking ที่เริ่มทริปแล้ว พร้อม payment ที่จ่ายงวดแรกแล้ว
+	newStartedBooking := func(start time.Time, days int, dailyPayout bool) (models.TripBooking, models.TripPayment) {
+		end := start.AddDate(0, 0, days-1)
+		now := time.Now()
+		booking := models.TripBooking{
+			TripOfferID:   1,
+			UserID:        user.ID,
+			GuideID:       guide.ID,
+			StartDate:     start,
+			EndDate:       &end,
+			Days:          days,
+			DailyPayout:   dailyPayout,
+			TotalAmount:   1800,
+			Status:        "trip_started",
+			PaymentStatus: "paid",
+			TripStartedAt: &now,
+		}
+		db.Create(&booking)
+
+		payment := models.TripPayment{
+			TripBookingID:         booking.ID,
+			PaymentNumber:         "PAY-" + strconv.Itoa(int(booking.ID)),
+			TotalAmount:           1800,
+			FirstPayment:          900,
+			SecondPayment:         900,
+			PaymentMethod:         "stripe_card",
+			TransactionID:         "TXN-" + strconv.Itoa(int(booking.ID)),
+			StripePaymentIntentID: "pi_" + strconv.Itoa(int(booking.ID)),
+			Status:                "first_released",
+		}
+		db.Create(&payment)
+		return booking, payment
+	}
+
+	t.Run("Accept offer copies trip window to booking", func(t *testing.T) {
+		tripRequire := models.TripRequire{
+			UserID:      user.ID,
+			ProvinceID:  province.ID,
+			Title:       "Three days",
+			Description: "desc",
+			MinPrice:    1000,
+			MaxPrice:    2000,
+			StartDate:   time.Now().AddDate(0, 0, 7),
+			EndDate:     time.Now().AddDate(0, 0, 9),
+			Days:        3,
+			Status:      "in_review",
+			GroupSize:   1,
+		}
+		db.Create(&tripRequire)
+
+		now := time.Now()
+		offer := models.TripOffer{TripRequireID: tripRequire.ID, GuideID: guide.ID, Title: "Offer", Description: "desc", Status: "sent", SentAt: &now}
+		db.Create(&offer)
+		db.Create(&models.TripOfferQuotation{TripOfferID: offer.ID, Version: 1, TotalPrice: 1500, Status: "sent"})
+
+		actor = user.ID
+		resp, _ := doJSON("PUT", "/trip-offers/"+strconv.Itoa(int(offer.ID))+"/accept", map[string]interface{}{"daily_payout": true})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var booking models.TripBooking
+		db.Where("trip_offer_id = ?", offer.ID).First(&booking)
+		assert.Equal(t, 3, booking.Days)
+		assert.True(t, booking.DailyPayout)
+		if assert.NotNil(t, booking.EndDate) {
+			assert.Equal(t, tripRequire.EndDate.Format("2006-01-02"), booking.EndDate.Format("2006-01-02"))
+		}
+	})
+
+	t.Run("Completion before end date requires agreed early completion", func(t *testing.T) {
+		booking, _ := newStartedBooking(time.Now(), 3, false)
+		id := strconv.Itoa(int(booking.ID))
+
+		actor = user.ID
+		resp, _ := doJSON("PUT", "/trip-bookings/"+id+"/confirm-trip-complete", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = doJSON("PUT", "/trip-bookings/"+id+"/request-early-completion", map[string]interface{}{"reason": "Weather"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// คนที่ขอเองยอมรับไม่ได้
+		resp, _ = doJSON("PUT", "/trip-bookings/"+id+"/agree-early-completion", nil)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		var notifications int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", userGuide.ID, "early_completion_requested").Count(&notifications)
+		assert.Equal(t, int64(1), notifications)
+
+		actor = userGuide.ID
+		resp, _ = doJSON("PUT", "/trip-bookings/"+id+"/agree-early-completion", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// ไกด์ยืนยันจบทริปแทน user ไม่ได้
+		resp, _ = doJSON("PUT", "/trip-bookings/"+id+"/confirm-trip-complete", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		actor = user.ID
+		resp, _ = doJSON("PUT", "/trip-bookings/"+id+"/confirm-trip-complete", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		db.First(&booking, booking.ID)
+		assert.Equal(t, "trip_completed", booking.Status)
+	})
+
+	t.Run("Daily check-ins release proportional payout", func(t *testing.T) {
+		booking, payment := newStartedBooking(time.Now().AddDate(0, 0, -2), 3, true)
+		id := strconv.Itoa(int(booking.ID))
+
+		// วันในอนาคตเช็คอินไม่ได้
+		actor = user.ID
+		resp, _ := doJSON("POST", "/trip-bookings/"+id+"/check-ins", map[string]interface{}{"day_number": 4})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		for _, day := range []int{1, 2} {
+			actor = user.ID
+			resp, _ = doJSON("POST", "/trip-bookings/"+id+"/check-ins", map[string]interface{}{"day_number": day})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			actor = userGuide.ID
+			resp, _ = doJSON("POST", "/trip-bookings/"+id+"/check-ins", map[string]interface{}{"day_number": day})
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		}
+
+		var checkIn models.TripDayCheckIn
+		db.Where("trip_booking_id = ? AND day_number = ?", booking.ID, 1).First(&checkIn)
+		assert.NotNil(t, checkIn.CompletedAt)
+		assert.NotNil(t, checkIn.PaymentReleaseID)
+
+		var dailyReleases []models.PaymentRelease
+		db.Where("trip_payment_id = ? AND release_type = ?", payment.ID, "daily_payment").Find(&dailyReleases)
+		assert.Len(t, dailyReleases, 2)
+		assert.Equal(t, 300.0, dailyReleases[0].Amount)
+
+		// วันสุดท้ายถึงแล้ว ยืนยันจบทริปได้ และจ่ายเฉพาะส่วนที่เหลือ
+		actor = user.ID
+		resp, _ = doJSON("PUT", "/trip-bookings/"+id+"/confirm-trip-complete", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var final models.PaymentRelease
+		db.Where("trip_payment_id = ? AND release_type = ?", payment.ID, "second_payment").First(&final)
+		assert.Equal(t, 300.0, final.Amount)
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 	"time"
 
@@ -23,7 +24,7 @@ func TestTripOfferController(t *testing.T) {
 	app := setupTestApp()
 
 	// Migrate tables
-	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripOfferQuotation{}, &models.TripBooking{})
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripRequire{}, &models.TripOffer{}, &models.TripOfferQuotation{}, &models.TripOfferStop{}, &models.TripBooking{}, &models.DocumentSequence{}, &models.Notification{})
 
 	// Seed data
 	roleCustomer := models.Role{Name: "customer"}
@@ -77,6 +78,61 @@ func TestTripOfferController(t *testing.T) {
 		c.Locals("user_id", user.ID)
 		return controllers.RejectTripOffer(c)
 	})
+	app.Put("/trip-offers/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userGuide.ID)
+		return controllers.UpdateTripOffer(c)
+	})
+	app.Delete("/trip-offers/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userGuide.ID)
+		return controllers.WithdrawTripOffer(c)
+	})
+
+	// ไกด์อีกคนสำหรับทดสอบสิทธิ์
+	authOther := models.AuthUser{Email: "other@example.com", Password: "hash"}
+	db.Create(&authOther)
+	userOther := models.User{AuthUserID: authOther.ID, FirstName: "Other", LastName: "Guide", RoleID: roleGuide.ID}
+	db.Create(&userOther)
+	otherGuide := models.Guide{UserID: userOther.ID, ProvinceID: province.ID, Description: "Another guide", Available: true}
+	db.Create(&otherGuide)
+	app.Put("/other/trip-offers/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userOther.ID)
+		return controllers.UpdateTripOffer(c)
+	})
+	app.Delete("/other/trip-offers/:id", func(c *fiber.Ctx) error {
+		c.Locals("user_id", userOther.ID)
+		return controllers.WithdrawTripOffer(c)
+	})
+
+	// createSentOffer สร้าง TripRequire ที่มี offer ของ guide หนึ่งรายการ
+	createSentOffer := func(title string) (models.TripRequire, models.TripOffer) {
+		tr := models.TripRequire{
+			UserID:      user.ID,
+			ProvinceID:  province.ID,
+			Title:       title,
+			Description: "Test",
+			MinPrice:    1000,
+			MaxPrice:    2000,
+			StartDate:   time.Now().AddDate(0, 0, 7),
+			EndDate:     time.Now().AddDate(0, 0, 10),
+			Days:        3,
+			Status:      "in_review",
+			GroupSize:   1,
+		}
+		db.Create(&tr)
+
+		now := time.Now()
+		offer := models.TripOffer{
+			TripRequireID: tr.ID,
+			GuideID:       guide.ID,
+			Title:         title + " Offer",
+			Description:   "desc",
+			Status:        "sent",
+			SentAt:        &now,
+		}
+		db.Create(&offer)
+		db.Create(&models.TripOfferQuotation{TripOfferID: offer.ID, Version: 1, TotalPrice: 1500, Status: "sent", SentAt: &now})
+		return tr, offer
+	}
 
 	t.Run("Create Trip Offer - Success", func(t *testing.T) {
 		payload := map[string]interface{}{
@@ -140,7 +196,7 @@ func TestTripOfferController(t *testing.T) {
 
 		var response map[string]interface{}
 		json.NewDecoder(resp.Body).Decode(&response)
-		assert.Contains(t, response["error"], "Price is outside the requested range")
+		assert.Equal(t, "price_out_of_range", response["code"])
 	})
 
 	t.Run("Get Trip Offers - Success", func(t *testing.T) {
@@ -263,4 +319,131 @@ func TestTripOfferController(t *testing.T) {
 		db.First(&updatedOffer, offer.ID)
 		assert.Equal(t, "rejected", updatedOffer.Status)
 	})
+
+	t.Run("Update Trip Offer - Whitelisted fields and new quotation version", func(t *testing.T) {
+		_, offer := createSentOffer("Update")
+
+		payload := map[string]interface{}{
+			"title":           "Updated title",
+			"total_price":     1800.0,
+			"price_breakdown": "Guide fee: 1800",
+			"guide_id":        otherGuide.ID,
+			"status":          "accepted",
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest("PUT", "/trip-offers/"+strconv.Itoa(int(offer.ID)), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var updated models.TripOffer
+		db.First(&updated, offer.ID)
+		assert.Equal(t, "Updated title", updated.Title)
+		assert.Equal(t, guide.ID, updated.GuideID)
+		assert.Equal(t, "sent", updated.Status)
+
+		var quotations []models.TripOfferQuotation
+		db.Where("trip_offer_id = ?", offer.ID).Order("version ASC").Find(&quotations)
+		assert.Len(t, quotations, 2)
+		assert.Equal(t, "superseded", quotations[0].Status)
+		assert.Equal(t, 2, quotations[1].Version)
+		assert.Equal(t, 1800.0, quotations[1].TotalPrice)
+		assert.Equal(t, "sent", quotations[1].Status)
+	})
+
+	t.Run("Update Trip Offer - Not Owner", func(t *testing.T) {
+		_, offer := createSentOffer("Not Owner")
+
+		body, _ := json.Marshal(map[string]interface{}{"title": "Hijacked"})
+		req := httptest.NewRequest("PUT", "/other/trip-offers/"+strconv.Itoa(int(offer.ID)), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Update Trip Offer - Not Editable Status", func(t *testing.T) {
+		_, offer := createSentOffer("Accepted")
+		db.Model(&offer).Update("status", "accepted")
+
+		body, _ := json.Marshal(map[string]interface{}{"title": "Too late"})
+		req := httptest.NewRequest("PUT", "/trip-offers/"+strconv.Itoa(int(offer.ID)), bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Withdraw Trip Offer - Last live offer reopens trip require", func(t *testing.T) {
+		tr, offer := createSentOffer("Withdraw")
+
+		req := httptest.NewRequest("DELETE", "/trip-offers/"+strconv.Itoa(int(offer.ID)), nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var updated models.TripOffer
+		db.First(&updated, offer.ID)
+		assert.Equal(t, "withdrawn", updated.Status)
+		assert.NotNil(t, updated.WithdrawnAt)
+
+		var updatedTR models.TripRequire
+		db.First(&updatedTR, tr.ID)
+		assert.Equal(t, "open", updatedTR.Status)
+
+		var notifications int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ? AND related_id = ?", user.ID, "offer_withdrawn", offer.ID).Count(&notifications)
+		assert.Equal(t, int64(1), notifications)
+	})
+
+	t.Run("Create Trip Offer - Re-offer after withdrawal", func(t *testing.T) {
+		tr, offer := createSentOffer("Re-offer")
+		payload := map[string]interface{}{
+			"trip_require_id": tr.ID,
+			"title":           "Second Offer",
+			"description":     "Revised plan",
+			"total_price":     1800.0,
+		}
+		post := func() int {
+			body, _ := json.Marshal(payload)
+			req := httptest.NewRequest("POST", "/trip-offers", bytes.NewBuffer(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			assert.NoError(t, err)
+			return resp.StatusCode
+		}
+
+		// ยังมี offer ที่ส่งอยู่ เสนอซ้ำไม่ได้
+		assert.Equal(t, http.StatusConflict, post())
+
+		req := httptest.NewRequest("DELETE", "/trip-offers/"+strconv.Itoa(int(offer.ID)), nil)
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		assert.Equal(t, http.StatusCreated, post())
+
+		var live int64
+		db.Model(&models.TripOffer{}).Where("trip_require_id = ? AND guide_id = ? AND status <> ?", tr.ID, guide.ID, "withdrawn").Count(&live)
+		assert.Equal(t, int64(1), live)
+	})
+
+	t.Run("Withdraw Trip Offer - Not Owner", func(t *testing.T) {
+		_, offer := createSentOffer("Withdraw Not Owner")
+
+		req := httptest.NewRequest("DELETE", "/other/trip-offers/"+strconv.Itoa(int(offer.ID)), nil)
+		resp, err := app.Test(req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		var updated models.TripOffer
+		db.First(&updated, offer.ID)
+		assert.Equal(t, "sent", updated.Status)
+	})
 }
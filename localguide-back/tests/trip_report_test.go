@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTripReports(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.PaymentOutbox{}, &models.TripReport{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	newUser := func(email string, roleID uint) models.User {
+		auth := models.AuthUser{Email: email, Password: "hash"}
+		db.Create(&auth)
+		u := models.User{AuthUserID: auth.ID, FirstName: "Test", LastName: email, RoleID: roleID}
+		db.Create(&u)
+		return u
+	}
+	traveller := newUser("user@example.com", 1)
+	stranger := newUser("stranger@example.com", 1)
+	admin := newUser("admin@example.com", 3)
+	guideUser := newUser("guide@example.com", 2)
+	guide := models.Guide{UserID: guideUser.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+	stripeGuideUser := newUser("stripe-guide@example.com", 2)
+	stripeGuide := models.Guide{UserID: stripeGuideUser.ID, ProvinceID: province.ID, Description: "desc", Available: true, StripeAccountID: "acct_123"}
+	db.Create(&stripeGuide)
+
+	now := time.Now()
+	newBooking := func(g models.Guide, number string) (models.TripBooking, models.TripPayment) {
+		booking := models.TripBooking{TripOfferID: 1, UserID: traveller.ID, GuideID: g.ID, StartDate: now, TotalAmount: 2000, Status: "trip_started", PaymentStatus: "paid"}
+		db.Create(&booking)
+		payment := models.TripPayment{TripBookingID: booking.ID, PaymentNumber: number, TransactionID: "TXN-" + number, StripePaymentIntentID: "pi_" + number, TotalAmount: 2000, FirstPayment: 1000, SecondPayment: 1000, PaymentMethod: "stripe_card", Status: "paid", PaidAt: &now}
+		db.Create(&payment)
+		return booking, payment
+	}
+	booking, payment := newBooking(guide, "PAY-1")
+
+	actor := traveller.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Post("/trip-bookings/:id/reports", as(controllers.CreateTripReport))
+	app.Get("/trip-bookings/:id/reports", as(controllers.GetTripBookingReports))
+	app.Get("/trip-reports/mine", as(controllers.GetMyTripReports))
+	app.Put("/admin/trip-reports/:id", as(controllers.HandleTripReport))
+	app.Put("/admin/payments/:id/payout-freeze", as(controllers.SetPaymentPayoutFreeze))
+
+	do := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+	reportsPath := func(b models.TripBooking) string {
+		return "/trip-bookings/" + strconv.Itoa(int(b.ID)) + "/reports"
+	}
+	adminNotifications := func() int64 {
+		var n int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", admin.ID, "report_critical").Count(&n)
+		return n
+	}
+
+	t.Run("Validation and participant check", func(t *testing.T) {
+		actor = traveller.ID
+		resp, _ := do("POST", reportsPath(booking), map[string]interface{}{"report_type": "user_no_show", "title": "x", "description": "y"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", reportsPath(booking), map[string]interface{}{"report_type": "other", "title": "x", "description": "y", "evidence": []string{"javascript:alert(1)"}})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("POST", reportsPath(booking), map[string]interface{}{"report_type": "other", "title": "", "description": "y"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		actor = stranger.ID
+		resp, _ = do("POST", reportsPath(booking), map[string]interface{}{"report_type": "other", "title": "x", "description": "y"})
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+
+	t.Run("Guide report is triaged to the minimum severity of its type", func(t *testing.T) {
+		actor = guideUser.ID
+		resp, out := do("POST", reportsPath(booking), map[string]interface{}{
+			"report_type": "inappropriate_behavior",
+			"title":       "Rude traveller",
+			"description": "Shouted at staff",
+			"severity":    "low",
+			"evidence":    []string{"/uploads/1.jpg"},
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		report := out["report"].(map[string]interface{})
+		assert.Equal(t, "medium", report["severity"])
+		assert.Equal(t, []interface{}{"/uploads/1.jpg"}, report["evidence"])
+		assert.Equal(t, false, out["payouts_frozen"])
+
+		var saved models.TripReport
+		db.First(&saved, uint(report["id"].(float64)))
+		assert.Equal(t, traveller.ID, saved.ReportedUserID)
+		assert.Equal(t, int64(0), adminNotifications())
+
+		resp, _ = do("POST", reportsPath(booking), map[string]interface{}{"report_type": "inappropriate_behavior", "title": "Again", "description": "Again"})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	})
+
+	t.Run("Critical safety report pages admins and freezes payouts", func(t *testing.T) {
+		actor = traveller.ID
+		resp, out := do("POST", reportsPath(booking), map[string]interface{}{
+			"report_type": "safety_issue",
+			"title":       "Unsafe driving",
+			"description": "Guide drove recklessly",
+			"severity":    "critical",
+		})
+		assert.Equal(t, http.StatusCreated, resp.StatusCode)
+		assert.Equal(t, true, out["payouts_frozen"])
+		assert.Equal(t, "critical", out["report"].(map[string]interface{})["severity"])
+		assert.Equal(t, int64(1), adminNotifications())
+
+		db.First(&payment, payment.ID)
+		assert.True(t, payment.PayoutsFrozen)
+
+		// จ่ายไกด์ระหว่างระงับ -> ค้างเป็น pending
+		release := models.PaymentRelease{TripPaymentID: payment.ID, ReleaseType: "first_payment", Amount: 1000, RecipientType: "guide", RecipientID: guide.ID, Reason: "trip_started", ScheduledAt: now}
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			_, err := services.QueueGuidePayout(tx, &payment, &release)
+			return err
+		}))
+		db.First(&release, release.ID)
+		assert.Equal(t, "pending", release.Status)
+
+		// admin ยกเลิกการระงับ -> จ่ายรายการที่ค้าง
+		actor = admin.ID
+		resp, out = do("PUT", "/admin/payments/"+strconv.Itoa(int(payment.ID))+"/payout-freeze", map[string]interface{}{"frozen": false})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, 1.0, out["released_payouts"])
+		db.First(&release, release.ID)
+		assert.Equal(t, "processed", release.Status)
+		assert.NotNil(t, release.ProcessedAt)
+	})
+
+	t.Run("Frozen payment skips Stripe transfers until unfrozen", func(t *testing.T) {
+		stripeBooking, stripePayment := newBooking(stripeGuide, "PAY-2")
+		_ = stripeBooking
+
+		actor = admin.ID
+		resp, _ := do("PUT", "/admin/payments/"+strconv.Itoa(int(stripePayment.ID))+"/payout-freeze", map[string]interface{}{"frozen": true})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		resp, _ = do("PUT", "/admin/payments/"+strconv.Itoa(int(stripePayment.ID))+"/payout-freeze", map[string]interface{}{"frozen": true, "reason": "Investigating"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		release := models.PaymentRelease{TripPaymentID: stripePayment.ID, ReleaseType: "first_payment", Amount: 1000, RecipientType: "guide", RecipientID: stripeGuide.ID, Reason: "trip_started", ScheduledAt: now}
+		assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+			_, err := services.QueueGuidePayout(tx, &stripePayment, &release)
+			return err
+		}))
+
+		gateway := &fakeGateway{}
+		worker := services.NewPaymentOutboxWorker(db, gateway)
+		n, err := worker.RunOnce(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+		assert.Len(t, gateway.calls, 0)
+
+		resp, _ = do("PUT", "/admin/payments/"+strconv.Itoa(int(stripePayment.ID))+"/payout-freeze", map[string]interface{}{"frozen": false})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		n, err = worker.RunOnce(time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+		db.First(&release, release.ID)
+		assert.Equal(t, "processed", release.Status)
+	})
+
+	t.Run("Reporter sees status and admin response", func(t *testing.T) {
+		var report models.TripReport
+		db.Where("reporter_id = ? AND report_type = ?", traveller.ID, "safety_issue").First(&report)
+
+		actor = admin.ID
+		resp, _ := do("PUT", "/admin/trip-reports/"+strconv.Itoa(int(report.ID)), map[string]interface{}{"status": "investigating", "admin_notes": "We are contacting the guide"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		var notified int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", traveller.ID, "report_updated").Count(&notified)
+		assert.Equal(t, int64(1), notified)
+
+		actor = traveller.ID
+		_, out := do("GET", "/trip-reports/mine", nil)
+		reports := out["reports"].([]interface{})
+		if assert.Len(t, reports, 1) {
+			view := reports[0].(map[string]interface{})
+			assert.Equal(t, "investigating", view["status"])
+			assert.Equal(t, "We are contacting the guide", view["admin_response"])
+		}
+
+		// แต่ละฝ่ายเห็นเฉพาะรีพอร์ตของตัวเอง
+		actor = guideUser.ID
+		_, out = do("GET", reportsPath(booking), nil)
+		reports = out["reports"].([]interface{})
+		if assert.Len(t, reports, 1) {
+			assert.Equal(t, "inappropriate_behavior", reports[0].(map[string]interface{})["report_type"])
+		}
+	})
+}
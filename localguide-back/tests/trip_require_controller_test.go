@@ -99,7 +99,7 @@ func TestTripRequireController(t *testing.T) {
 
 		var response map[string]interface{}
 		json.NewDecoder(resp.Body).Decode(&response)
-		assert.Contains(t, response["error"], "Max price must be greater than or equal to min price")
+		assert.Equal(t, "price_range_invalid", response["code"])
 	})
 
 	t.Run("Create Trip Require - Invalid Date Format", func(t *testing.T) {
@@ -124,7 +124,7 @@ func TestTripRequireController(t *testing.T) {
 
 		var response map[string]interface{}
 		json.NewDecoder(resp.Body).Decode(&response)
-		assert.Contains(t, response["error"], "Invalid start_date format")
+		assert.Equal(t, "start_date_format_invalid", response["code"])
 	})
 
 	t.Run("Get Trip Requires - Success", func(t *testing.T) {
@@ -207,6 +207,7 @@ func TestTripRequireController(t *testing.T) {
 
 		var response map[string]interface{}
 		json.NewDecoder(resp.Body).Decode(&response)
-		assert.Contains(t, response["error"], "Cannot delete trip requirement with status")
+		assert.Equal(t, "trip_requirement_not_deletable", response["code"])
+		assert.Equal(t, "assigned", response["status"])
 	})
 }
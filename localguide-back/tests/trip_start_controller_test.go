@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestGuideStartCheckIn(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Province{}, &models.Guide{}, &models.TripBooking{}, &models.TripStartCheckIn{}, &models.TripPayment{}, &models.PaymentRelease{}, &models.Notification{})
+
+	province := models.Province{Name: "Bangkok", Region: "Central"}
+	db.Create(&province)
+
+	authUser := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&authUser)
+	user := models.User{AuthUserID: authUser.ID, FirstName: "John", LastName: "Doe", RoleID: 1}
+	db.Create(&user)
+
+	authGuide := models.AuthUser{Email: "guide@example.com", Password: "hash"}
+	db.Create(&authGuide)
+	userGuide := models.User{AuthUserID: authGuide.ID, FirstName: "Guide", LastName: "One", RoleID: 2}
+	db.Create(&userGuide)
+	guide := models.Guide{UserID: userGuide.ID, ProvinceID: province.ID, Description: "desc", Available: true}
+	db.Create(&guide)
+
+	actor := user.ID
+	as := func(h fiber.Handler) fiber.Handler {
+		return func(c *fiber.Ctx) error {
+			c.Locals("user_id", actor)
+			return h(c)
+		}
+	}
+	app.Get("/trip-bookings/:id/start-code", as(controllers.GetTripStartCode))
+	app.Put("/trip-bookings/:id/meeting-point", as(controllers.SetMeetingPoint))
+	app.Post("/trip-bookings/:id/guide-check-in", as(controllers.GuideCheckIn))
+	app.Put("/trip-bookings/:id/confirm-guide-arrival", as(controllers.ConfirmGuideArrival))
+
+	doJSON := func(method, url string, payload interface{}) (*http.Response, map[string]interface{}) {
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	newPaidBooking := func(start time.Time) (models.TripBooking, models.TripPayment) {
+		booking := models.TripBooking{TripOfferID: 1, UserID: user.ID, GuideID: guide.ID, StartDate: start, TotalAmount: 2000, Status: "paid", PaymentStatus: "paid"}
+		db.Create(&booking)
+		ref := strconv.Itoa(int(booking.ID))
+		payment := models.TripPayment{TripBookingID: booking.ID, PaymentNumber: "PAY-" + ref, TransactionID: "TXN-" + ref, StripePaymentIntentID: "pi_" + ref, TotalAmount: 2000, FirstPayment: 1000, SecondPayment: 1000, PaymentMethod: "stripe_card", Status: "paid"}
+		db.Create(&payment)
+		return booking, payment
+	}
+
+	t.Run("Guide checks in with code near meeting point", func(t *testing.T) {
+		booking, payment := newPaidBooking(time.Now())
+		path := "/trip-bookings/" + strconv.Itoa(int(booking.ID))
+
+		actor = user.ID
+		resp, _ := doJSON("PUT", path+"/meeting-point", map[string]interface{}{"latitude": 13.7500, "longitude": 100.4913, "note": "Wat Pho gate"})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		// ไกด์ดูรหัสไม่ได้
+		actor = userGuide.ID
+		resp, _ = doJSON("GET", path+"/start-code", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		actor = user.ID
+		resp, out := doJSON("GET", path+"/start-code", nil)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		code := out["start_code"].(string)
+		assert.Len(t, code, 6)
+
+		// ไกด์ยืนยันแทน user ไม่ได้
+		actor = userGuide.ID
+		resp, _ = doJSON("PUT", path+"/confirm-guide-arrival", nil)
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+		// ห่างจากจุดนัดพบเกินไป (~1.5 km)
+		resp, _ = doJSON("POST", path+"/guide-check-in", map[string]interface{}{"code": code, "latitude": 13.7640, "longitude": 100.4913})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = doJSON("POST", path+"/guide-check-in", map[string]interface{}{"code": code, "latitude": 13.7502, "longitude": 100.4915})
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		db.First(&booking, booking.ID)
+		assert.Equal(t, "trip_started", booking.Status)
+		assert.NotNil(t, booking.StartCodeUsedAt)
+
+		var release models.PaymentRelease
+		db.Where("trip_payment_id = ?", payment.ID).First(&release)
+		assert.Equal(t, "first_payment", release.ReleaseType)
+		assert.Equal(t, "guide_check_in", release.Reason)
+
+		var checkIns []models.TripStartCheckIn
+		db.Where("trip_booking_id = ?", booking.ID).Order("id").Find(&checkIns)
+		if assert.Len(t, checkIns, 2) {
+			assert.Equal(t, "outside_meeting_radius", checkIns[0].FailureReason)
+			assert.Equal(t, "accepted", checkIns[1].Status)
+			assert.NotNil(t, checkIns[1].DistanceMeters)
+		}
+
+		var notifications int64
+		db.Model(&models.Notification{}).Where("user_id = ? AND type = ?", user.ID, "guide_checked_in").Count(&notifications)
+		assert.Equal(t, int64(1), notifications)
+	})
+
+	t.Run("Check-in that races an arrival confirmation does not release the first payment twice", func(t *testing.T) {
+		booking, payment := newPaidBooking(time.Now())
+		path := "/trip-bookings/" + strconv.Itoa(int(booking.ID))
+		actor = user.ID
+		_, out := doJSON("GET", path+"/start-code", nil)
+		code := out["start_code"].(string)
+
+		// ลูกค้ายืนยันว่าไกด์มาแล้ว (commit) หลังจากที่การเช็คอินอ่าน booking ที่ยังเป็น paid ไปแล้ว
+		db.Callback().Create().Before("gorm:create").Register("test:arrival_confirmed", func(d *gorm.DB) {
+			if d.Statement.Table == "trip_start_check_ins" {
+				d.Session(&gorm.Session{NewDB: true}).Exec("UPDATE trip_bookings SET status = ? WHERE id = ?", "trip_started", booking.ID)
+			}
+		})
+		defer db.Callback().Create().Remove("test:arrival_confirmed")
+
+		actor = userGuide.ID
+		resp, out := doJSON("POST", path+"/guide-check-in", map[string]interface{}{"code": code})
+		assert.Equal(t, http.StatusConflict, resp.StatusCode)
+		assert.Equal(t, "trip_already_started", out["code"])
+
+		var releases int64
+		db.Model(&models.PaymentRelease{}).Where("trip_payment_id = ?", payment.ID).Count(&releases)
+		assert.Equal(t, int64(0), releases)
+	})
+
+	t.Run("Wrong codes are recorded and rate limited", func(t *testing.T) {
+		booking, _ := newPaidBooking(time.Now())
+		path := "/trip-bookings/" + strconv.Itoa(int(booking.ID))
+
+		actor = userGuide.ID
+		for i := 0; i < 5; i++ {
+			resp, _ := doJSON("POST", path+"/guide-check-in", map[string]interface{}{"code": "000000x"})
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		}
+		resp, _ := doJSON("POST", path+"/guide-check-in", map[string]interface{}{"code": "000000x"})
+		assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+
+		var rejected int64
+		db.Model(&models.TripStartCheckIn{}).Where("trip_booking_id = ? AND failure_reason = ?", booking.ID, "invalid_code").Count(&rejected)
+		assert.Equal(t, int64(5), rejected)
+	})
+
+	t.Run("Check-in before start date is rejected", func(t *testing.T) {
+		booking, _ := newPaidBooking(time.Now().AddDate(0, 0, 3))
+
+		actor = userGuide.ID
+		resp, _ := doJSON("POST", "/trip-bookings/"+strconv.Itoa(int(booking.ID))+"/guide-check-in", map[string]interface{}{"code": "123456"})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+}
@@ -0,0 +1,282 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"localguide-back/config"
+	"localguide-back/controllers"
+	"localguide-back/models"
+	"localguide-back/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testPNG = encodeTestPNG(40, 30)
+	testPDF = []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+)
+
+// encodeTestPNG - รูป PNG ไล่สีขนาด w x h
+func encodeTestPNG(w, h int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 255 / w), G: uint8(y * 255 / h), B: 128, A: 255})
+		}
+	}
+	return encodeTestImage(img)
+}
+
+func encodeTestImage(img image.Image) []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+func TestUploadService(t *testing.T) {
+	// Setup DB and App
+	db := setupTestDB()
+	config.DB = db
+	app := setupTestApp()
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Upload{}, &models.ImageVariant{}, &models.MessageAttachment{}, &models.TripReport{})
+
+	dir := t.TempDir()
+	previous := controllers.FileStorage
+	controllers.FileStorage = services.NewLocalStorage(dir, "/uploads")
+	defer func() { controllers.FileStorage = previous }()
+
+	db.Create(&models.Role{Name: "user"})
+	auth := models.AuthUser{Email: "user@example.com", Password: "hash"}
+	db.Create(&auth)
+	user := models.User{AuthUserID: auth.ID, FirstName: "Test", LastName: "User", RoleID: 1}
+	db.Create(&user)
+
+	app.Post("/uploads", func(c *fiber.Ctx) error {
+		c.Locals("user_id", user.ID)
+		return controllers.UploadFile(c)
+	})
+
+	upload := func(purpose, filename string, content []byte) (*http.Response, map[string]interface{}) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if purpose != "" {
+			mw.WriteField("purpose", purpose)
+		}
+		fw, _ := mw.CreateFormFile("file", filename)
+		fw.Write(content)
+		mw.Close()
+		req := httptest.NewRequest("POST", "/uploads", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		resp, err := app.Test(req)
+		assert.NoError(t, err)
+		var out map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&out)
+		return resp, out
+	}
+
+	t.Run("Stores files under random keys with ownership metadata", func(t *testing.T) {
+		resp, first := upload("message_attachment", "photo.png", testPNG)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		_, second := upload("message_attachment", "photo.png", testPNG)
+
+		url := first["url"].(string)
+		assert.True(t, strings.HasPrefix(url, "/uploads/attachments/"))
+		assert.True(t, strings.HasSuffix(url, ".png"))
+		assert.NotEqual(t, url, second["url"])
+		assert.Equal(t, "image/png", first["content_type"])
+
+		stored, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(url, "/uploads/")))
+		assert.NoError(t, err)
+		assert.Equal(t, testPNG, stored)
+
+		var record models.Upload
+		db.Where("url = ?", url).First(&record)
+		assert.Equal(t, user.ID, record.OwnerID)
+		assert.Equal(t, "message_attachment", record.Purpose)
+		assert.Equal(t, "local", record.Backend)
+		assert.Equal(t, "photo.png", record.OriginalName)
+		assert.Equal(t, int64(len(testPNG)), record.Size)
+	})
+
+	t.Run("Type is checked from the content, not the file name", func(t *testing.T) {
+		resp, _ := upload("avatar", "avatar.png", []byte("<html><script>alert(1)</script></html>"))
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, _ = upload("avatar", "scan.pdf", testPDF)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+		resp, out := upload("licence_document", "scan.jpg", testPDF)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.True(t, strings.HasPrefix(out["url"].(string), "/api/files/private/licences/"))
+		assert.True(t, strings.HasSuffix(out["url"].(string), ".pdf"))
+
+		// ไม่ระบุ purpose = evidence
+		resp, out = upload("", "meeting.pdf", testPDF)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, "evidence", out["purpose"])
+
+		resp, _ = upload("passport", "a.png", testPNG)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	})
+
+	t.Run("Size limit is enforced per purpose", func(t *testing.T) {
+		svc := services.NewUploadService(db, controllers.FileStorage)
+		big := append(append([]byte{}, testPNG...), make([]byte, 6<<20)...)
+
+		_, err := svc.Save(user.ID, "avatar", "big.png", bytes.NewReader(big), int64(len(big)))
+		assert.True(t, errors.Is(err, services.ErrUploadTooLarge))
+
+		record, err := svc.Save(user.ID, "evidence", "big.png", bytes.NewReader(big), int64(len(big)))
+		assert.NoError(t, err)
+		// หลักฐานเป็นไฟล์ส่วนตัว อยู่ใต้ PrivateDir ไม่ใช่ dir
+		info, err := os.Stat(filepath.Join(controllers.FileStorage.(*services.LocalStorage).PrivateDir, strings.TrimPrefix(record.Key, "private/")))
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(len(big)), info.Size())
+		}
+
+		_, err = svc.Save(user.ID, "evidence", "empty.png", bytes.NewReader(nil), 0)
+		assert.True(t, errors.Is(err, services.ErrUploadEmpty))
+	})
+
+	t.Run("Orphan cleanup removes only unreferenced old files", func(t *testing.T) {
+		db.Exec("DELETE FROM uploads")
+		svc := services.NewUploadService(db, controllers.FileStorage)
+		save := func(purpose string) *models.Upload {
+			record, err := svc.Save(user.ID, purpose, "f.png", bytes.NewReader(testPNG), int64(len(testPNG)))
+			assert.NoError(t, err)
+			return record
+		}
+		avatar := save("avatar")
+		attachment := save("message_attachment")
+		evidence := save("evidence")
+		orphan := save("review_image")
+
+		db.Model(&user).Update("avatar", avatar.URL)
+		db.Create(&models.MessageAttachment{MessageID: 1, URL: attachment.URL, FileName: "f.png"})
+		db.Create(&models.TripReport{TripBookingID: 1, ReporterID: user.ID, ReportedUserID: user.ID, ReportType: "other", Title: "t", Evidence: `["` + evidence.URL + `"]`, Status: "pending"})
+
+		// ยังไม่เกินอายุ ไม่ลบ
+		n, err := svc.RunOnce(time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		n, err = svc.RunOnce(time.Now().Add(25 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		var remaining int64
+		db.Model(&models.Upload{}).Count(&remaining)
+		assert.Equal(t, int64(3), remaining)
+		_, err = os.Stat(filepath.Join(dir, orphan.Key))
+		assert.True(t, os.IsNotExist(err))
+		for _, v := range orphan.Variants {
+			_, err = os.Stat(filepath.Join(dir, v.Key))
+			assert.True(t, os.IsNotExist(err))
+		}
+		_, err = os.Stat(filepath.Join(dir, avatar.Key))
+		assert.NoError(t, err)
+		var variants int64
+		db.Model(&models.ImageVariant{}).Count(&variants)
+		assert.Equal(t, int64(3), variants)
+	})
+}
+
+// fakeS3 - S3 API แบบย่อ (PUT/GET/DELETE object แบบ path-style) สำหรับทดสอบแทน MinIO
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=test-key/") || !strings.Contains(auth, "/ap-southeast-1/s3/aws4_request") ||
+		r.Header.Get("X-Amz-Date") == "" || r.Header.Get("X-Amz-Content-Sha256") == "" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = body
+		f.types[r.URL.Path] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(body)
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestS3Storage(t *testing.T) {
+	db := setupTestDB()
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Upload{}, &models.ImageVariant{})
+
+	fake := &fakeS3{objects: map[string][]byte{}, types: map[string]string{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	storage := services.NewS3Storage(services.S3Config{
+		Endpoint:  server.URL,
+		Region:    "ap-southeast-1",
+		Bucket:    "localguide",
+		AccessKey: "test-key",
+		SecretKey: "test-secret",
+	})
+	svc := services.NewUploadService(db, storage)
+
+	record, err := svc.Save(1, "review_image", "photo.png", bytes.NewReader(testPNG), int64(len(testPNG)))
+	assert.NoError(t, err)
+	assert.Equal(t, "s3", record.Backend)
+	assert.True(t, strings.HasPrefix(record.Key, "reviews/"))
+	assert.Equal(t, server.URL+"/localguide/"+record.Key, record.URL)
+	assert.True(t, svc.IsStoredURL(record.URL))
+	assert.Equal(t, "image/jpeg", fake.types["/localguide/"+record.Key])
+	// ไฟล์หลัก + รูปย่อ 3 ขนาด
+	assert.Len(t, fake.objects, 4)
+
+	body, err := storage.Get(record.Key)
+	if assert.NoError(t, err) {
+		content, _ := io.ReadAll(body)
+		body.Close()
+		assert.Equal(t, fake.objects["/localguide/"+record.Key], content)
+	}
+
+	assert.NoError(t, svc.DeleteByURL(record.URL))
+	_, err = storage.Get(record.Key)
+	assert.Equal(t, services.ErrStorageNotFound, err)
+	assert.Empty(t, fake.objects)
+	var count int64
+	db.Model(&models.Upload{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	// ลงชื่อผิด = error จาก storage
+	bad := services.NewS3Storage(services.S3Config{Endpoint: server.URL, Region: "us-east-1", Bucket: "localguide", AccessKey: "other", SecretKey: "x"})
+	assert.Error(t, bad.Put("evidence/a.pdf", bytes.NewReader(testPDF), int64(len(testPDF)), "application/pdf"))
+
+	public := services.NewS3Storage(services.S3Config{Endpoint: server.URL, Bucket: "localguide", PublicURL: "https://cdn.example.com/"})
+	assert.Equal(t, "https://cdn.example.com/avatars/a.png", public.URL("avatars/a.png"))
+}
@@ -33,7 +33,7 @@ func TestUserProfile_GetUpdateAvatarFlow(t *testing.T) {
 	app.Delete("/me/avatar", func(c *fiber.Ctx) error { c.Locals("user_id", uint(1)); return controllers.DeleteProfileAvatar(c) })
 
 	// Migrate tables
-	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{})
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Upload{}, &models.ImageVariant{})
 
 	// Seed role, user
 	role := models.Role{Name: "customer"}
@@ -92,9 +92,8 @@ func TestUserProfile_GetUpdateAvatarFlow(t *testing.T) {
 	var body bytes.Buffer
 	mw := multipart.NewWriter(&body)
 	fw, _ := mw.CreateFormFile("avatar", "avatar.png")
-	// minimal PNG header bytes + padding
-	img := append([]byte{0x89, 0x50, 0x4e, 0x47, 0x0d, 0x0a, 0x1a, 0x0a}, bytes.Repeat([]byte{0x00}, 1024)...)
-	fw.Write(img)
+	// PNG จริง เพราะรูปโปรไฟล์ถูกถอดรหัสและเข้ารหัสใหม่
+	fw.Write(testPNG)
 	mw.Close()
 	reqUpA := httptest.NewRequest("POST", "/me/avatar", &body)
 	reqUpA.Header.Set("Content-Type", mw.FormDataContentType())
@@ -105,6 +104,8 @@ func TestUserProfile_GetUpdateAvatarFlow(t *testing.T) {
 	json.NewDecoder(respA.Body).Decode(&upA)
 	avatarURL, _ := upA["avatar_url"].(string)
 	assert.True(t, strings.HasPrefix(avatarURL, "/uploads/avatars/"))
+	assert.True(t, strings.HasSuffix(avatarURL, ".jpg"))
+	assert.Len(t, upA["avatar_variants"], 3)
 
 	// Check DB and file exists
 	db.First(&u, user.ID)
@@ -137,7 +138,7 @@ func TestUserProfile_ErrorCases(t *testing.T) {
 	app.Post("/me/avatar", func(c *fiber.Ctx) error { c.Locals("user_id", uint(1)); return controllers.UploadProfileAvatar(c) })
 
 	// Migrate and seed minimal
-	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{})
+	db.AutoMigrate(&models.Role{}, &models.AuthUser{}, &models.User{}, &models.Upload{}, &models.ImageVariant{})
 	role := models.Role{Name: "customer"}
 	db.Create(&role)
 	auth := models.AuthUser{Email: "e@e.com", Password: "x"}